@@ -1,253 +1,184 @@
 package main
 
 import (
-	"bytes"
-	"encoding/gob"
-	"encoding/json"
+	"context"
+	"errors"
+	"flag"
 	"fmt"
-	"io/ioutil"
-	"net"
+	"io"
+	"log/slog"
 	"os"
-	"strconv"
+	"os/signal"
+	"strings"
 	"time"
 
-	"github.com/DistributedClocks/tracing"
+	"nimgame/pkg/client"
+	"nimgame/pkg/configfile"
+	"nimgame/pkg/nim"
+	"nimgame/pkg/nimerr"
 )
 
-/* Config struct */
-
-type ClientConfig struct {
-	ClientAddress        string
-	NimServerAddress     string
-	TracingServerAddress string
-	Secret               []byte
-	TracingIdentity      string
-}
-
-/* Tracing structs */
-
-type GameStart struct {
-	Seed int8
-}
-
-type ClientMove StateMoveMessage
-
-type ServerMoveReceive StateMoveMessage
-
-type GameComplete struct {
-	Winner string
+func main() {
+	// loadConfig reports its own errors
+	var result client.Result
+	flags, config, err := loadConfig(os.Args[1:], os.Stderr, os.Getenv)
+	if err == nil {
+		if result, err = run(flags, config); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		}
+	}
+	os.Exit(exitCode(result, err))
 }
 
-/* Message structs */
-
-type StateMoveMessage struct {
-	GameState []uint8
-	MoveRow   int8
-	MoveCount int8
+// exitCode is the status to exit with after a run; see client.ExitCode.
+func exitCode(result client.Result, err error) int {
+	if errors.Is(err, flag.ErrHelp) {
+		return 0
+	}
+	return client.ExitCode(result, err)
 }
 
-func main() {
-	if len(os.Args) != 2 {
-		fmt.Println("Usage: client.go [seed]")
-		return
+// run does what flags ask with config, which is nil with -init-config,
+// -simulate and -replay-local, and returns the result of the game if one
+// was played.
+func run(flags *clientFlags, config *client.ClientConfig) (client.Result, error) {
+	if flags.initConfig != "" {
+		if err := configfile.Write(flags.initConfig, []byte(client.ExampleConfig), flags.force); err != nil {
+			return client.Result{}, fmt.Errorf("writing config: %w", err)
+		}
+		fmt.Printf("wrote example config to %v\n", flags.initConfig)
+		return client.Result{}, nil
+	}
+	if flags.simulate {
+		return client.Result{}, runSimulation(flags, os.Stdout)
+	}
+	if flags.replayLocal != "" {
+		return client.Result{}, runReplayLocal(flags.replayLocal, os.Stdout)
+	}
+	if flags.validateOnly {
+		fmt.Println("config OK")
+		return client.Result{}, nil
+	}
+	seed := flags.seed
+	initLogger(flags.level(config))
+	if flags.replay != "" {
+		return runReplay(flags, config, os.Stdout)
 	}
-	arg, err := strconv.Atoi(os.Args[1])
-	CheckErr(err, "Provided seed could not be converted to integer", arg)
-	seed := int8(arg)
-
-	config := ReadConfig("config/client_config.json")
-
-	// now connect to it
-	tracer := tracing.NewTracer(tracing.TracerConfig{
-		ServerAddress:  config.TracingServerAddress,
-		TracerIdentity: config.TracingIdentity,
-		Secret:         config.Secret,
-	})
-	defer tracer.Close()
-
-	trace := tracer.CreateTrace()
-	trace.RecordAction(
-		GameStart{
-			Seed: seed,
-		})
-
-	local_ip_port := config.ClientAddress
-	remote_ip_port := config.NimServerAddress
-
-	laddr, err := net.ResolveUDPAddr("udp", local_ip_port)
-	CheckErr(err, "Error converting UDP address: %v\n", err)
-	raddr, err := net.ResolveUDPAddr("udp", remote_ip_port)
-	CheckErr(err, "Error converting UDP address: %v\n", err)
-
-	// setup UDP connection
-	conn, err := net.DialUDP("udp", laddr, raddr)
-	CheckErr(err, "Couldn't connect to the server", config.NimServerAddress)
-	defer conn.Close()
-
-	// get board state
-	sendMove := StateMoveMessage{nil, -1, seed}
-	var recvMove StateMoveMessage
-	for {
-		// send start packet
-		traceAndSend(&sendMove, trace, conn)
 
-		// get server response
-		if recvAndTrace(&recvMove, trace, conn) != nil {
-			continue
+	if flags.strategySeed == 0 {
+		flags.strategySeed = time.Now().UnixNano()
+	}
+	var strategy nim.Strategy
+	if flags.strategy == "interactive" {
+		strategy = newInteractiveStrategy(os.Stdin, os.Stdout, !flags.noHints)
+	} else {
+		var err error
+		if strategy, err = nim.NewStrategy(flags.strategy, flags.strategySeed); err != nil {
+			return client.Result{}, nimerr.Wrap(nimerr.ErrConfig, err)
 		}
-		break
-
 	}
-	state := make([]uint8, len(recvMove.GameState))
-	copy(state, recvMove.GameState)
-
-	// main loop
-	for {
-		// make move and update state
-		sendMove = decideMove(state)
-		copy(state, sendMove.GameState)
-		for {
-			// send my move
-			traceAndSend(&sendMove, trace, conn)
-
-			// if I won, stop
-			if isWinState(state) {
-				trace.RecordAction(GameComplete{"client"})
-				os.Exit(0)
-			}
 
-			// get server response
-			if recvAndTrace(&recvMove, trace, conn) != nil {
-				fmt.Fprintln(os.Stderr, "saw timeout or corrupt packet")
-				continue
-			} else if !isValidSuccessor(state, &recvMove) {
-				fmt.Fprintln(os.Stderr, "saw invalid/duplicate (but not corrupt) packet")
-				fmt.Fprintln(os.Stderr, "state = ", state, " received = ", recvMove.GameState)
-				continue
+	var results GameResultStore
+	if config.GameResultsFile != "" {
+		results = fileResultStore{path: config.GameResultsFile}
+		if config.AutoEscalate && flags.difficulty == "" {
+			difficulty, err := nextDifficulty(results, config.EscalationThreshold, seed&1)
+			if err != nil {
+				slog.Warn("couldn't read past game results", "err", err)
 			}
-			break
-		}
-		copy(state, recvMove.GameState)
-		// if server won, stop
-		if isWinState(state) {
-			trace.RecordAction(GameComplete{"server"})
-			os.Exit(0)
+			seed = seedWithDifficulty(seed, difficulty)
 		}
 	}
-}
 
-func decideMove(state []uint8) StateMoveMessage {
-	// winning nim strategy as described by https://en.wikipedia.org/wiki/Nim
-	var nimSum uint8
-	for _, elm := range state {
-		nimSum ^= elm
+	if flags.games > 1 || flags.parallel > 1 {
+		return client.Result{}, runTournament(flags, config, strategy, seed, results)
+	}
+	if flags.match > 0 {
+		return runMatch(flags, config, strategy, seed, results)
 	}
 
-	if nimSum != 0 {
-		for idx, elm := range state {
-			if elm >= elm^nimSum {
-				reduceBy := elm - (elm ^ nimSum)
-				newState := make([]uint8, len(state))
-				copy(newState, state)
-				newState[idx] -= reduceBy
-				return StateMoveMessage{newState, int8(idx), int8(reduceBy)}
-			}
+	var resume *client.ResumeState
+	if flags.resume != "" {
+		var err error
+		if resume, err = client.LoadResumeState(flags.resume); err != nil {
+			return client.Result{}, nimerr.Wrap(nimerr.ErrConfig, err)
 		}
-	} else {
-		for idx, elm := range state {
-			if elm != 0 {
-				newState := make([]uint8, len(state))
-				copy(newState, state)
-				newState[idx] -= 1
-				return StateMoveMessage{newState, int8(idx), 1}
+		if resume != nil {
+			seed = resume.Seed
+			if !flags.quiet {
+				fmt.Printf("resuming game %d from %v\n", seed, flags.resume)
 			}
+		} else if !flags.set["seed"] {
+			return client.Result{}, nimerr.Wrap(nimerr.ErrConfig, fmt.Errorf("no game to resume in %v and no seed given", flags.resume))
 		}
 	}
 
-	fmt.Fprintln(os.Stderr, "move decision strategy failed")
-	fmt.Fprintln(os.Stderr, "state = ", state)
-	os.Exit(1)
-	return StateMoveMessage{}
-}
-
-func isWinState(state []uint8) bool {
-	for _, elm := range state {
-		if elm != 0 {
-			return false
-		}
+	opts := []client.Option{client.WithSeed(seed), client.WithStrategyName(flags.strategy), client.WithMoveDelay(flags.moveDelay), flags.conditions()}
+	if flags.resume != "" {
+		opts = append(opts, client.WithResume(flags.resume, resume))
 	}
-	return true
-}
-
-func isValidSuccessor(state []uint8, move *StateMoveMessage) bool {
-	for idx, elm := range state {
-		if idx == int(move.MoveRow) {
-			if elm-uint8(move.MoveCount) != move.GameState[idx] {
-				return false
-			}
-		} else {
-			if elm != move.GameState[idx] {
-				return false
-			}
+	if !flags.quiet && flags.strategy != "interactive" {
+		// the interactive strategy draws the board itself
+		opts = append(opts, client.WithMoveHook(printMoves(os.Stdout)))
+	}
+	if flags.printStats {
+		opts = append(opts, client.WithNetworkStats())
+	}
+	if flags.recordPath != "" {
+		f, err := os.Create(flags.recordPath)
+		if err != nil {
+			return client.Result{}, fmt.Errorf("creating game record: %w", err)
 		}
+		defer f.Close()
+		servers := strings.Join(config.ServerAddresses(), ",")
+		opts = append(opts, client.WithRecorder(client.NewGameRecorder(f, seed, config.TracingIdentity, servers)))
 	}
-
-	return true
-}
-
-func traceAndSend(move *StateMoveMessage, trace *tracing.Trace, conn net.Conn) {
-	trace.RecordAction(ClientMove(*move))
-	conn.Write(encode(move))
-	// assume it went through, if it didn't, we'll just retry after a timeout
-}
-
-func recvAndTrace(move *StateMoveMessage, trace *tracing.Trace, conn net.Conn) error {
-	recvBuf := make([]byte, 1024)
-
-	conn.SetReadDeadline(time.Now().Add(time.Duration(1) * time.Second))
-	len, err := conn.Read(recvBuf)
-	if err != nil {
-		return err
+	var transcript *client.Transcript
+	if flags.transcript != "" {
+		transcript = client.NewTranscript(seed)
+		opts = append(opts, client.WithTranscript(transcript))
 	}
-	decoded, err := decode(recvBuf, len)
+	sess, err := client.NewSession(*config, strategy, opts...)
 	if err != nil {
-		return err
+		return client.Result{}, err
 	}
-	*move = decoded
-	trace.RecordAction(ServerMoveReceive(*move))
-	return nil
-}
-
-func encode(move *StateMoveMessage) []byte {
-	var buf bytes.Buffer
-	gob.NewEncoder(&buf).Encode(move)
-	return buf.Bytes()
-}
-
-func decode(buf []byte, len int) (StateMoveMessage, error) {
-	var decoded StateMoveMessage
-	err := gob.NewDecoder(bytes.NewBuffer(buf[0:len])).Decode(&decoded)
-	if err != nil {
-		return StateMoveMessage{}, err
+	defer sess.Close()
+
+	// an interrupt abandons the game
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	result, err := sess.Play(ctx)
+	if flags.printStats {
+		fmt.Printf("Network: %v\n", sess.NetworkStats())
+	}
+	printResult(os.Stdout, result, flags.quiet)
+	if flags.summaryPath != "" {
+		if werr := writeSummary(flags.summaryPath, result); werr != nil {
+			err = errors.Join(err, werr)
+		}
 	}
-	return decoded, nil
+	if transcript != nil {
+		if werr := transcript.WriteFile(flags.transcript); werr != nil {
+			err = errors.Join(err, werr)
+		}
+	}
+	if result.Winner == "" {
+		return result, fmt.Errorf("game aborted: %w", err)
+	}
+	if results != nil {
+		if err := results.Add(GameResult{Win: result.Winner == "client", Difficulty: seed & 1}); err != nil {
+			slog.Warn("couldn't save game result", "err", err)
+		}
+	}
+	// the game finished, but its record may not have been written
+	return result, err
 }
 
-func ReadConfig(filepath string) *ClientConfig {
-	configFile := filepath
-	configData, err := ioutil.ReadFile(configFile)
-	CheckErr(err, "reading config file")
-
-	config := new(ClientConfig)
-	err = json.Unmarshal(configData, config)
-	CheckErr(err, "parsing config data")
-
-	return config
+func initLogger(level slog.Level) {
+	slog.SetDefault(newLogger(os.Stderr, level))
 }
 
-func CheckErr(err error, errfmsg string, fargs ...interface{}) {
-	if err != nil {
-		fmt.Fprintf(os.Stderr, errfmsg, fargs...)
-		os.Exit(1)
-	}
+// newLogger logs to w at level.
+func newLogger(w io.Writer, level slog.Level) *slog.Logger {
+	return slog.New(slog.NewTextHandler(w, &slog.HandlerOptions{Level: level}))
 }
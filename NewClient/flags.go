@@ -0,0 +1,353 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"nimgame/pkg/client"
+	"nimgame/pkg/configfile"
+	"nimgame/pkg/envconfig"
+	"nimgame/pkg/netcond"
+	"nimgame/pkg/nim"
+	"nimgame/pkg/nimerr"
+)
+
+// configName is the config file looked for when -config isn't given; see
+// configfile.Locate.
+const configName = "client_config.json"
+
+// clientFlags is the parsed command line. Settings that also live in
+// ClientConfig override the config file and environment only when given.
+type clientFlags struct {
+	seed         int8
+	configPath   string
+	recordPath   string
+	summaryPath  string
+	transcript   string
+	games        int
+	parallel     int
+	match        int // best of, with -match; zero plays games
+	strategy     string
+	strategySeed int64
+	noHints      bool
+	validateOnly bool
+	printStats   bool
+	initConfig   string
+	force        bool
+	resume       string
+	replay       string // transcript to replay against the server
+	replayLocal  string // transcript to replay offline
+	moveDelay    time.Duration
+
+	// -inject-* degrade the network to the server, in the -inject-dir
+	// directions, for trying the client against a poor one
+	inject    netcond.Config
+	injectDir string
+
+	// -simulate plays seeds seedLo up to, but not including, seedHi
+	simulate       bool
+	serverStrategy string
+	seedLo, seedHi int64
+
+	server      string
+	local       string
+	timeout     time.Duration
+	verbose     bool // -v: retransmissions, timeouts and rejected replies
+	packets     bool // -vv: every packet as well
+	quiet       bool // only the winner
+	logLevel    string
+	difficulty  string
+	verifyBoard bool
+	set         map[string]bool // flags given on the command line
+}
+
+// parseFlags parses args, which exclude the program name. The seed may be
+// given as -seed or, as it used to be, as the only positional argument.
+// Usage is written to output on error.
+func parseFlags(args []string, output io.Writer) (*clientFlags, error) {
+	f := &clientFlags{set: make(map[string]bool)}
+	fs := flag.NewFlagSet("client", flag.ContinueOnError)
+	fs.SetOutput(output)
+	seed := fs.Int("seed", 0, "game `seed`, -128 to 127; odd seeds play the hard server")
+	fs.StringVar(&f.configPath, "config", "", "read the client config from `path` rather than searching for "+configName)
+	fs.StringVar(&f.recordPath, "record", "", "write a PGN-style record of the game to `path`")
+	fs.StringVar(&f.summaryPath, "summary-out", "", "write the end-of-game summary to `path` as JSON")
+	fs.StringVar(&f.transcript, "transcript", "", "write a JSON transcript of every packet and move to `path` at the end of the game")
+	fs.IntVar(&f.games, "games", 1, "play `n` games in a row with consecutive seeds from -seed and report the record; exits 0 unless one is aborted")
+	fs.IntVar(&f.match, "match", 0, "play a best-of-`n` match, n odd, against the server, which opens every other game, printing the score after each")
+	fs.IntVar(&f.parallel, "parallel", 1, "play `k` games at once, each from its own local port, reporting them as -games does")
+	fs.StringVar(&f.strategy, "strategy", "optimal", "how to pick moves: "+strings.Join(nim.StrategyNames, "|")+"|interactive")
+	human := fs.Bool("human", false, "pick moves yourself, as -strategy interactive")
+	fs.StringVar(&f.difficulty, "difficulty", "", "easy|hard: play the seed's easy (even) or hard (odd) neighbour, whatever AutoEscalate would pick")
+	fs.Int64Var(&f.strategySeed, "strategy-seed", 0, "seed for the random strategy; 0 seeds from the clock")
+	fs.BoolVar(&f.noHints, "no-hints", false, "disable the hint command in interactive play")
+	fs.BoolVar(&f.printStats, "print-stats", false, "log RTT and loss every 10 moves and print them after the game")
+	fs.BoolVar(&f.validateOnly, "validate-config", false, "check the config and exit without playing")
+	fs.StringVar(&f.initConfig, "init-config", "", "write an example config to `path` and exit")
+	fs.BoolVar(&f.force, "force", false, "let -init-config overwrite an existing file")
+	fs.StringVar(&f.resume, "resume", "", "save the game to `path` after every move, resuming the game saved there, if any, rather than starting one for -seed")
+	fs.StringVar(&f.replay, "replay", "", "replay the client's moves from the transcript at `path` against the server, reporting where its replies first differ from the recording")
+	fs.StringVar(&f.replayLocal, "replay-local", "", "replay both sides of the transcript at `path` offline, drawing the board after each move")
+	fs.DurationVar(&f.moveDelay, "move-delay", 0, "wait this long before each of our moves, to make games watchable")
+	fs.Float64Var(&f.inject.Loss, "inject-loss", 0, "drop this fraction of packets, 0 to 1")
+	fs.Float64Var(&f.inject.Duplicate, "inject-dup", 0, "deliver this fraction of packets twice, 0 to 1")
+	fs.DurationVar(&f.inject.Delay, "inject-delay", 0, "delay packets by up to this long")
+	fs.DurationVar(&f.inject.DelayMean, "inject-delay-mean", 0, "delay packets by a normally distributed time with this mean, instead of -inject-delay")
+	fs.DurationVar(&f.inject.DelayStddev, "inject-delay-stddev", 0, "standard deviation of the -inject-delay-mean delays")
+	fs.Float64Var(&f.inject.Reorder, "inject-reorder", 0, "hold back this fraction of packets until the next has overtaken them, 0 to 1")
+	fs.Int64Var(&f.inject.Seed, "inject-seed", 1, "seed picking the packets -inject-* affect, the same seed affecting the same packets")
+	fs.StringVar(&f.injectDir, "inject-dir", "both", "out|in|both: degrade packets sent to the server, received from it, or both")
+	fs.BoolVar(&f.simulate, "simulate", false, "play -games games in-process, with no server, and print win rates by seed")
+	fs.StringVar(&f.serverStrategy, "server-strategy", "", "the server's strategy with -simulate: "+strings.Join(nim.StrategyNames, "|")+"; by default optimal on odd seeds and basic on even, as the server plays")
+	seedRange := fs.String("seed-range", "-128:128", "seeds `lo:hi`, from lo up to but not including hi, that -simulate plays in turn; one game each unless -games is given")
+	fs.StringVar(&f.server, "server", "", "nim server `address`, overriding NimServerAddresses")
+	fs.StringVar(&f.local, "local", "", "local UDP `address`, overriding ClientAddress")
+	fs.DurationVar(&f.timeout, "timeout", 0, "abandon the game after this long, overriding MaxGameDurationSeconds")
+	fs.BoolVar(&f.verbose, "v", false, "log retransmissions, timeouts and rejected replies, at debug level, overriding LogLevel")
+	fs.BoolVar(&f.verbose, "verbose", false, "same as -v")
+	fs.BoolVar(&f.packets, "vv", false, "log every packet as well as what -v does")
+	fs.BoolVar(&f.quiet, "quiet", false, "print only the winner, logging only errors")
+	fs.StringVar(&f.logLevel, "log-level", "", "debug, info, warn or error, overriding LogLevel")
+	fs.BoolVar(&f.verifyBoard, "verify-board", false, "check the initial board matches the seed, overriding VerifyInitialBoard")
+	fs.Usage = func() {
+		fmt.Fprintln(output, "Usage: client [flags] [seed]")
+		fs.PrintDefaults()
+		fmt.Fprint(output, "\n"+client.ExitUsage)
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	fs.Visit(func(fl *flag.Flag) { f.set[fl.Name] = true })
+
+	usageErr := func(format string, args ...interface{}) error {
+		err := fmt.Errorf(format, args...)
+		fmt.Fprintln(output, err)
+		fs.Usage()
+		return err
+	}
+	switch {
+	case fs.NArg() > 1:
+		return nil, usageErr("too many arguments: %v", fs.Args())
+	case fs.NArg() == 1 && f.set["seed"]:
+		return nil, usageErr("seed given both as -seed and as an argument")
+	case fs.NArg() == 1:
+		n, err := strconv.Atoi(fs.Arg(0))
+		if err != nil {
+			return nil, usageErr("seed %q is not an integer", fs.Arg(0))
+		}
+		*seed = n
+		f.set["seed"] = true
+	case !f.set["seed"] && !f.validateOnly && f.initConfig == "" && !f.simulate && f.resume == "" && f.replay == "" && f.replayLocal == "":
+		return nil, usageErr("no seed given")
+	}
+	if *seed < math.MinInt8 || *seed > math.MaxInt8 {
+		return nil, usageErr("seed %d is out of range -128 to 127", *seed)
+	}
+	f.seed = int8(*seed)
+	switch f.difficulty {
+	case "":
+	case "easy":
+		f.seed = seedWithDifficulty(f.seed, 0)
+	case "hard":
+		f.seed = seedWithDifficulty(f.seed, 1)
+	default:
+		return nil, usageErr("-difficulty %q is neither easy nor hard", f.difficulty)
+	}
+	if *human {
+		if f.set["strategy"] && f.strategy != "interactive" {
+			return nil, usageErr("-human can't be used with -strategy %v", f.strategy)
+		}
+		f.strategy = "interactive"
+	}
+	levels := 0
+	for _, name := range []string{"quiet", "v", "verbose", "vv", "log-level"} {
+		if f.set[name] {
+			levels++
+		}
+	}
+	if levels > 1 {
+		return nil, usageErr("only one of -quiet, -v, -vv and -log-level can be given")
+	}
+	if f.set["timeout"] && f.timeout <= 0 {
+		return nil, usageErr("-timeout must be positive")
+	}
+	if f.moveDelay < 0 {
+		return nil, usageErr("-move-delay can't be negative")
+	}
+	if err := f.inject.Validate(); err != nil {
+		return nil, usageErr("-inject-*: %v", err)
+	}
+	switch f.injectDir {
+	case "out", "in", "both":
+	default:
+		return nil, usageErr("-inject-dir %q is not out, in or both", f.injectDir)
+	}
+	switch {
+	case f.games < 1:
+		return nil, usageErr("-games must be at least 1")
+	case f.parallel < 1:
+		return nil, usageErr("-parallel must be at least 1")
+	case f.games > 1 && f.parallel > 1:
+		return nil, usageErr("-games and -parallel can't be used together")
+	case (f.games > 1 || f.parallel > 1) && (f.recordPath != "" || f.transcript != "" || f.printStats):
+		return nil, usageErr("-record, -transcript and -print-stats can't be used with -games or -parallel")
+	case (f.games > 1 || f.parallel > 1 || f.simulate) && f.resume != "":
+		return nil, usageErr("-resume can't be used with -games, -parallel or -simulate")
+	case f.replay != "" && f.replayLocal != "":
+		return nil, usageErr("-replay and -replay-local can't be used together")
+	case (f.replay != "" || f.replayLocal != "") && (f.games > 1 || f.parallel > 1 || f.simulate || f.resume != "" || f.set["strategy"] || *human):
+		return nil, usageErr("-replay and -replay-local play the recorded moves, so can't be used with -games, -parallel, -simulate, -resume, -strategy or -human")
+	case f.parallel > 1 && f.strategy == "interactive":
+		return nil, usageErr("-parallel can't be used with the interactive strategy")
+	case f.set["match"] && (f.match < 1 || f.match > client.MaxMatchGames || f.match%2 == 0):
+		return nil, usageErr("-match %d is not an odd number from 1 to %d", f.match, client.MaxMatchGames)
+	case f.match > 0 && (f.games > 1 || f.parallel > 1 || f.simulate || f.resume != "" || f.replay != "" || f.replayLocal != ""):
+		return nil, usageErr("-match can't be used with -games, -parallel, -simulate, -resume, -replay or -replay-local")
+	case f.match > 0 && (f.recordPath != "" || f.transcript != "" || f.printStats || f.summaryPath != ""):
+		return nil, usageErr("-record, -transcript, -print-stats and -summary-out can't be used with -match")
+	}
+	if f.simulate {
+		var err error
+		if f.seedLo, f.seedHi, err = parseSeedRange(*seedRange); err != nil {
+			return nil, usageErr("-seed-range: %v", err)
+		}
+		switch {
+		case f.strategy == "interactive":
+			return nil, usageErr("-simulate can't be used with the interactive strategy")
+		case f.parallel > 1 || f.recordPath != "" || f.transcript != "" || f.printStats || f.summaryPath != "" || f.validateOnly || f.moveDelay > 0:
+			return nil, usageErr("-parallel, -record, -transcript, -print-stats, -summary-out, -move-delay and -validate-config can't be used with -simulate")
+		case f.inject.Enabled():
+			return nil, usageErr("-inject-* need a network, so can't be used with -simulate")
+		}
+	} else if f.set["server-strategy"] || f.set["seed-range"] {
+		return nil, usageErr("-server-strategy and -seed-range need -simulate")
+	}
+	return f, nil
+}
+
+// maxSeedRange is the most seeds -seed-range can cover, each of which
+// -simulate keeps a board for.
+const maxSeedRange = 1 << 20
+
+// parseSeedRange parses "lo:hi" into lo and hi, where lo < hi and the range
+// is no longer than maxSeedRange.
+func parseSeedRange(s string) (lo, hi int64, err error) {
+	los, his, ok := strings.Cut(s, ":")
+	if !ok {
+		return 0, 0, fmt.Errorf("%q is not lo:hi", s)
+	}
+	if lo, err = strconv.ParseInt(los, 10, 64); err == nil {
+		hi, err = strconv.ParseInt(his, 10, 64)
+	}
+	if err != nil {
+		return 0, 0, fmt.Errorf("%q is not lo:hi: %w", s, err)
+	}
+	if lo >= hi {
+		return 0, 0, fmt.Errorf("%q is empty", s)
+	}
+	if hi-lo < 0 || hi-lo > maxSeedRange { // the first overflows
+		return 0, 0, fmt.Errorf("%q covers more than %d seeds", s, maxSeedRange)
+	}
+	return lo, hi, nil
+}
+
+// conditions degrades the network as the -inject-* flags say. Packets
+// coming in are picked from the seed after -inject-seed, so as not to
+// mirror those going out.
+func (f *clientFlags) conditions() client.Option {
+	var out, in netcond.Config
+	if f.injectDir != "in" {
+		out = f.inject
+	}
+	if f.injectDir != "out" {
+		in = f.inject
+		in.Seed++
+	}
+	return client.WithNetworkConditions(out, in)
+}
+
+// apply overrides config with the flags that were given.
+func (f *clientFlags) apply(config *client.ClientConfig) {
+	if f.set["server"] {
+		config.NimServerAddresses = []string{f.server}
+	}
+	if f.set["local"] {
+		config.ClientAddress = f.local
+	}
+	if f.set["timeout"] {
+		config.MaxGameDurationSeconds = int(math.Ceil(f.timeout.Seconds()))
+	}
+	switch {
+	case f.quiet:
+		config.LogLevel = "error"
+	case f.verbose || f.packets:
+		config.LogLevel = "debug"
+	}
+	if f.set["log-level"] {
+		config.LogLevel = f.logLevel
+	}
+	if f.set["verify-board"] {
+		config.VerifyInitialBoard = f.verifyBoard
+	}
+}
+
+// level is the level to log at: config's LogLevel, which the flags
+// have already overridden, or client.LevelPacket with -vv.
+func (f *clientFlags) level(config *client.ClientConfig) slog.Level {
+	if f.packets {
+		return client.LevelPacket
+	}
+	var level slog.Level
+	level.UnmarshalText([]byte(config.LogLevel)) // checked by client.ValidateConfig
+	return level
+}
+
+// loadConfig parses the command line and builds the config from the file
+// it names or finds, the NIM_* environment variables (see package
+// envconfig), then the flags, each overriding the last, and validates the
+// result. Errors are written to output as well as returned, and are
+// nimerr.ErrConfig. With -init-config, -simulate or -replay-local there is
+// no config to load and it is nil.
+func loadConfig(args []string, output io.Writer, getenv func(string) string) (*clientFlags, *client.ClientConfig, error) {
+	f, config, err := readConfig(args, output, getenv)
+	return f, config, nimerr.Wrap(nimerr.ErrConfig, err)
+}
+
+func readConfig(args []string, output io.Writer, getenv func(string) string) (*clientFlags, *client.ClientConfig, error) {
+	f, err := parseFlags(args, output)
+	if err != nil {
+		return nil, nil, err
+	}
+	if f.initConfig != "" || f.simulate || f.replayLocal != "" {
+		return f, nil, nil
+	}
+	path, err := configfile.Locate(f.configPath, configName, getenv)
+	if err != nil {
+		fmt.Fprintln(output, err)
+		return nil, nil, err
+	}
+	config, err := client.ReadConfig(path)
+	if err != nil {
+		fmt.Fprintln(output, err)
+		return nil, nil, err
+	}
+	if err := envconfig.Apply(config, "NIM_", getenv); err != nil {
+		err = fmt.Errorf("invalid environment:\n%w", err)
+		fmt.Fprintln(output, err)
+		return nil, nil, err
+	}
+	f.apply(config)
+	if err := client.ValidateConfig(config); err != nil {
+		err = fmt.Errorf("invalid config %v:\n%w", path, err)
+		fmt.Fprintln(output, err)
+		return nil, nil, err
+	}
+	return f, config, nil
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"nimgame/pkg/client"
+	"nimgame/pkg/nimerr"
+)
+
+func writeTestConfig(t *testing.T, config string) string {
+	path := filepath.Join(t.TempDir(), "client_config.json")
+	if err := os.WriteFile(path, []byte(config), 0644); err != nil {
+		t.Fatalf("writing config: %v\n", err)
+	}
+	return path
+}
+
+func TestConfigPrecedence(t *testing.T) {
+	path := writeTestConfig(t, `{
+		"ClientAddress": "127.0.0.1:1000",
+		"NimServerAddresses": ["127.0.0.1:2000"],
+		"LogLevel": "warn",
+		"MaxGameDurationSeconds": 60
+	}`)
+	tests := []struct {
+		name    string
+		args    []string
+		env     map[string]string
+		local   string
+		servers []string
+		level   string
+	}{
+		{"file", []string{"-config", path, "3"}, nil,
+			"127.0.0.1:1000", []string{"127.0.0.1:2000"}, "warn"},
+		{"env over file", []string{"-config", path, "3"},
+			map[string]string{"NIM_CLIENT_ADDRESS": "127.0.0.1:1001", "NIM_SERVER_ADDRESS": "127.0.0.1:2001", "NIM_LOG_LEVEL": "info"},
+			"127.0.0.1:1001", []string{"127.0.0.1:2001"}, "info"},
+		{"flag over env", []string{"-config", path, "-local", "127.0.0.1:1002", "-server", "127.0.0.1:2002", "-verbose", "3"},
+			map[string]string{"NIM_CLIENT_ADDRESS": "127.0.0.1:1001", "NIM_SERVER_ADDRESS": "127.0.0.1:2001", "NIM_LOG_LEVEL": "info"},
+			"127.0.0.1:1002", []string{"127.0.0.1:2002"}, "debug"},
+		{"flag over file", []string{"-config", path, "-server", "127.0.0.1:2002", "3"}, nil,
+			"127.0.0.1:1000", []string{"127.0.0.1:2002"}, "warn"},
+		{"log level flag", []string{"-config", path, "-log-level", "error", "3"},
+			map[string]string{"NIM_LOG_LEVEL": "info"},
+			"127.0.0.1:1000", []string{"127.0.0.1:2000"}, "error"},
+	}
+	for _, test := range tests {
+		_, config, err := loadConfig(test.args, io.Discard, func(k string) string { return test.env[k] })
+		if err != nil {
+			t.Fatalf("%v: %v\n", test.name, err)
+		}
+		if config.ClientAddress != test.local || !reflect.DeepEqual(config.NimServerAddresses, test.servers) || config.LogLevel != test.level {
+			t.Errorf("%v: got local %v, servers %v, level %v\n", test.name, config.ClientAddress, config.NimServerAddresses, config.LogLevel)
+		}
+	}
+}
+
+func TestVerifyBoardFlag(t *testing.T) {
+	path := writeTestConfig(t, `{
+		"ClientAddress": "127.0.0.1:1000",
+		"NimServerAddresses": ["127.0.0.1:2000"],
+		"VerifyInitialBoard": true
+	}`)
+	noEnv := func(string) string { return "" }
+	for _, test := range []struct {
+		args   []string
+		verify bool
+	}{
+		{[]string{"-config", path, "3"}, true},
+		{[]string{"-config", path, "-verify-board=false", "3"}, false},
+	} {
+		_, config, err := loadConfig(test.args, io.Discard, noEnv)
+		if err != nil {
+			t.Fatalf("%v: %v\n", test.args, err)
+		}
+		if config.VerifyInitialBoard != test.verify {
+			t.Errorf("%v: VerifyInitialBoard = %v, want %v\n", test.args, config.VerifyInitialBoard, test.verify)
+		}
+	}
+}
+
+func TestTimeoutFlag(t *testing.T) {
+	path := writeTestConfig(t, `{"ClientAddress": "127.0.0.1:1000", "NimServerAddresses": ["127.0.0.1:2000"], "MaxGameDurationSeconds": 60}`)
+	_, config, err := loadConfig([]string{"-config", path, "-timeout", "1500ms", "-seed", "3"}, io.Discard, func(string) string { return "" })
+	if err != nil {
+		t.Fatalf("loading config: %v\n", err)
+	}
+	if config.MaxGameDurationSeconds != 2 {
+		t.Errorf("expected -timeout 1500ms to round up to 2s, got %d\n", config.MaxGameDurationSeconds)
+	}
+}
+
+func TestParseSeed(t *testing.T) {
+	tests := []struct {
+		args []string
+		seed int8
+		ok   bool
+	}{
+		{[]string{"5"}, 5, true},
+		{[]string{"-seed", "-7"}, -7, true},
+		{[]string{"-strategy", "basic", "9"}, 9, true},
+		{[]string{}, 0, false},
+		{[]string{"-seed", "1", "2"}, 0, false},
+		{[]string{"1", "2"}, 0, false},
+		{[]string{"x"}, 0, false},
+		{[]string{"200"}, 0, false},
+		{[]string{"-timeout", "-1s", "1"}, 0, false},
+		{[]string{"-resume", "state.json"}, 0, true},
+		{[]string{"-resume", "state.json", "-games", "2", "1"}, 0, false},
+		{[]string{"-move-delay", "750ms", "3"}, 3, true},
+		{[]string{"-move-delay", "-1s", "3"}, 0, false},
+		{[]string{"-difficulty", "hard", "4"}, 5, true},
+		{[]string{"-difficulty", "easy", "-seed", "-127"}, -128, true},
+		{[]string{"-difficulty", "medium", "4"}, 0, false},
+		{[]string{"-verbose", "-log-level", "warn", "4"}, 0, false},
+		{[]string{"-quiet", "-vv", "4"}, 0, false},
+		{[]string{"-replay", "game.json"}, 0, true},
+		{[]string{"-replay", "game.json", "-replay-local", "game.json"}, 0, false},
+		{[]string{"-replay-local", "game.json", "-strategy", "random"}, 0, false},
+		{[]string{"-v", "4"}, 4, true},
+		{[]string{"-inject-loss", "0.1", "-inject-dup", "0.1", "-inject-delay", "5ms", "-inject-reorder", "0.1", "4"}, 4, true},
+		{[]string{"-inject-loss", "1.5", "4"}, 0, false},
+		{[]string{"-inject-delay", "-5ms", "4"}, 0, false},
+		{[]string{"-inject-delay-mean", "50ms", "-inject-delay-stddev", "10ms", "4"}, 4, true},
+		{[]string{"-inject-delay", "5ms", "-inject-delay-mean", "50ms", "4"}, 0, false},
+		{[]string{"-inject-dir", "sideways", "4"}, 0, false},
+		{[]string{"-inject-loss", "0.1", "-simulate"}, 0, false},
+	}
+	for _, test := range tests {
+		f, err := parseFlags(test.args, io.Discard)
+		if (err == nil) != test.ok {
+			t.Errorf("parseFlags(%v): unexpected error %v\n", test.args, err)
+			continue
+		}
+		if err == nil && f.seed != test.seed {
+			t.Errorf("parseFlags(%v): seed %d, want %d\n", test.args, f.seed, test.seed)
+		}
+	}
+}
+
+func TestHumanFlag(t *testing.T) {
+	f, err := parseFlags([]string{"-human", "3"}, io.Discard)
+	if err != nil {
+		t.Fatalf("-human: %v\n", err)
+	}
+	if f.strategy != "interactive" {
+		t.Errorf("-human: strategy %v, expected interactive\n", f.strategy)
+	}
+	if _, err := parseFlags([]string{"-human", "-strategy", "random", "3"}, io.Discard); err == nil {
+		t.Errorf("expected -human to clash with -strategy random\n")
+	}
+}
+
+func TestConfigEnvErrors(t *testing.T) {
+	path := writeTestConfig(t, `{}`)
+	env := map[string]string{"NIM_MAX_RETRIES": "lots"}
+	_, _, err := loadConfig([]string{"-config", path, "1"}, io.Discard, func(k string) string { return env[k] })
+	if err == nil || !strings.Contains(err.Error(), "NIM_MAX_RETRIES") {
+		t.Errorf("expected an error naming NIM_MAX_RETRIES, got %v\n", err)
+	}
+}
+
+func TestValidateConfigFlag(t *testing.T) {
+	path := writeTestConfig(t, `{"ClientAddress": "127.0.0.1:0", "NimServerAddresses": ["127.0.0.1:1"]}`)
+	f, _, err := loadConfig([]string{"-config", path, "-validate-config"}, io.Discard, func(string) string { return "" })
+	if err != nil || !f.validateOnly {
+		t.Errorf("-validate-config without a seed: %v\n", err)
+	}
+}
+
+func TestInitConfigFlag(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "client_config.json")
+	f, config, err := loadConfig([]string{"-init-config", path}, io.Discard, func(string) string { return "" })
+	if err != nil || config != nil || f.initConfig != path {
+		t.Errorf("-init-config without a seed or config: got %v, %v\n", config, err)
+	}
+}
+
+func TestExitCode(t *testing.T) {
+	noEnv := func(string) string { return "" }
+	_, _, helpErr := loadConfig([]string{"-h"}, io.Discard, noEnv)
+	_, _, configErr := loadConfig([]string{"-config", filepath.Join(t.TempDir(), "missing.json"), "1"}, io.Discard, noEnv)
+	_, _, usageErr := loadConfig([]string{"1", "2"}, io.Discard, noEnv)
+	tests := []struct {
+		err  error
+		code int
+	}{
+		{nil, 0},
+		{helpErr, 0},
+		{configErr, client.ExitConfig},
+		{usageErr, client.ExitConfig},
+		{fmt.Errorf("game aborted: %w", client.ErrNoReply), client.ExitAborted},
+		{fmt.Errorf("writing game record: %w", os.ErrClosed), client.ExitError},
+	}
+	for _, test := range tests {
+		if code := exitCode(client.Result{}, test.err); code != test.code {
+			t.Errorf("exitCode(%v) = %d, want %d\n", test.err, code, test.code)
+		}
+	}
+	if !errors.Is(configErr, nimerr.ErrConfig) || !errors.Is(configErr, fs.ErrNotExist) {
+		t.Errorf("missing config file should be a config error: %v\n", configErr)
+	}
+}
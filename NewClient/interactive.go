@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"nimgame/pkg/nim"
+)
+
+// interactiveStrategy asks a human for each move. Typing "hint" at the
+// prompt prints the optimal move unless hints are disabled.
+type interactiveStrategy struct {
+	in    *bufio.Scanner
+	out   io.Writer
+	hints bool
+	last  []uint8 // the board as our previous move left it
+}
+
+func newInteractiveStrategy(in io.Reader, out io.Writer, hints bool) *interactiveStrategy {
+	return &interactiveStrategy{in: bufio.NewScanner(in), out: out, hints: hints}
+}
+
+// Move prompts until the player enters a legal move, returning row -1 if
+// the input runs out.
+func (s *interactiveStrategy) Move(board []uint8) (int, uint8) {
+	prompt := "your move (row count)"
+	if s.hints {
+		prompt += ", or hint"
+	}
+	fmt.Fprint(s.out, nim.RenderBoard(board, serverMove(s.last, board)))
+	for {
+		fmt.Fprintf(s.out, "%s: ", prompt)
+		if !s.in.Scan() {
+			return -1, 0
+		}
+		line := strings.TrimSpace(s.in.Text())
+		if line == "hint" {
+			if s.hints {
+				fmt.Fprintln(s.out, hint(board))
+			} else {
+				fmt.Fprintln(s.out, "hints are disabled")
+			}
+			continue
+		}
+		var row int
+		var count uint8
+		if _, err := fmt.Sscan(line, &row, &count); err != nil {
+			fmt.Fprintln(s.out, "enter a row and a number of coins, e.g. 0 2")
+			continue
+		}
+		if row < 0 || row >= len(board) || count == 0 || count > board[row] {
+			fmt.Fprintf(s.out, "can't take %d from row %d\n", count, row)
+			continue
+		}
+		s.last = append(s.last[:0], board...)
+		s.last[row] -= count
+		return row, count
+	}
+}
+
+// serverMove works out the move that took before to after, if it was one.
+func serverMove(before, after []uint8) *nim.Move {
+	if len(before) != len(after) {
+		return nil
+	}
+	var move *nim.Move
+	for i := range before {
+		if before[i] == after[i] {
+			continue
+		}
+		if move != nil || before[i] < after[i] {
+			return nil
+		}
+		move = &nim.Move{Row: i, Count: before[i] - after[i]}
+	}
+	return move
+}
+
+// hint describes the optimal move on board and whether the player to move
+// can force a win from it.
+func hint(board []uint8) string {
+	row, count := nim.Optimal{}.Move(board)
+	if nim.NimSum(board) != 0 {
+		return fmt.Sprintf("hint: take %d from row %d; this position is winning", count, row)
+	}
+	return fmt.Sprintf("hint: take %d from row %d; this position is losing against perfect play", count, row)
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"nimgame/pkg/nim"
+)
+
+func TestHintMatchesBestMove(t *testing.T) {
+	boards := [][]uint8{
+		{3, 4, 5},
+		{1, 2, 3},
+		{7, 0, 2, 9},
+		{10, 10},
+		{0, 0, 1},
+	}
+	for _, b := range boards {
+		row, count := nim.Optimal{}.Move(b)
+		h := hint(b)
+		if want := fmt.Sprintf("take %d from row %d;", count, row); !strings.Contains(h, want) {
+			t.Errorf("hint for %v = %q, expected it to suggest %q\n", b, h, want)
+		}
+		if winning := nim.NimSum(b) != 0; strings.Contains(h, "is winning") != winning {
+			t.Errorf("hint for %v = %q, winning position: %v\n", b, h, winning)
+		}
+	}
+}
+
+func TestInteractiveMove(t *testing.T) {
+	var out bytes.Buffer
+	s := newInteractiveStrategy(strings.NewReader("hint\n5 1\n0 9\nfoo\n1 2\n"), &out, true)
+	row, count := s.Move([]uint8{3, 4, 5})
+	if row != 1 || count != 2 {
+		t.Errorf("expected move (1, 2), got (%d, %d)\n", row, count)
+	}
+	if !strings.Contains(out.String(), hint([]uint8{3, 4, 5})) {
+		t.Errorf("hint not printed:\n%s\n", out.String())
+	}
+
+	// the server's reply is drawn against the board our move left
+	out.Reset()
+	s.in = bufio.NewScanner(strings.NewReader("2 3\n"))
+	s.Move([]uint8{3, 2, 3})
+	if want := nim.RenderBoard([]uint8{3, 2, 3}, &nim.Move{Row: 2, Count: 2}); !strings.HasPrefix(out.String(), want) {
+		t.Errorf("expected the board drawn as\n%s\ngot\n%s\n", want, out.String())
+	}
+
+	out.Reset()
+	s = newInteractiveStrategy(strings.NewReader("hint\n"), &out, false)
+	if row, _ := s.Move([]uint8{3, 4, 5}); row != -1 {
+		t.Errorf("expected no move once input ran out, got row %d\n", row)
+	}
+	if strings.Contains(out.String(), "hint:") {
+		t.Errorf("hint printed with hints disabled:\n%s\n", out.String())
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+
+	"nimgame/pkg/client"
+	"nimgame/pkg/nim"
+)
+
+// runMatch plays a best-of-flags.match match from seed against the server,
+// printing the score after each game and the match's winner at the end.
+// Each game finished is saved to results. The result's Winner is the
+// match's, which the exit status reports.
+func runMatch(flags *clientFlags, config *client.ClientConfig, strategy nim.Strategy, seed int8, results GameResultStore) (client.Result, error) {
+	opts := []client.Option{client.WithSeed(seed), client.WithStrategyName(flags.strategy), client.WithMoveDelay(flags.moveDelay), flags.conditions()}
+	if !flags.quiet && flags.strategy != "interactive" {
+		opts = append(opts, client.WithMoveHook(printMoves(os.Stdout)))
+	}
+	sess, err := client.NewSession(*config, strategy, opts...)
+	if err != nil {
+		return client.Result{}, err
+	}
+	defer sess.Close()
+
+	// an interrupt abandons the match
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	played := 0
+	m, err := sess.PlayMatch(ctx, flags.match, func(game client.TournamentGame, score client.MatchScore) {
+		played++
+		if !flags.quiet {
+			printMatchGame(os.Stdout, played, game, score)
+		}
+		if results == nil {
+			return
+		}
+		if err := results.Add(GameResult{Win: game.Result.Winner == "client", Difficulty: game.Seed & 1}); err != nil {
+			slog.Warn("couldn't save game result", "err", err)
+		}
+	})
+	if err != nil {
+		return client.Result{}, fmt.Errorf("match abandoned after %d games, at %v: %w", len(m.Games), m.Score, err)
+	}
+	printMatch(os.Stdout, m)
+	return client.Result{Winner: m.Score.Winner()}, nil
+}
+
+// printMatchGame writes the running score after game number n of a match.
+func printMatchGame(w io.Writer, n int, game client.TournamentGame, score client.MatchScore) {
+	fmt.Fprintf(w, "Game %d (seed %d) won by %v in %d moves: %v\n", n, game.Seed, game.Result.Winner, game.Result.Moves, score)
+}
+
+// printMatch writes who won the match, and by what score.
+func printMatch(w io.Writer, m client.MatchResult) {
+	fmt.Fprintf(w, "Match won by %v, best of %d: %v\n", m.Score.Winner(), m.Score.Games, m.Score)
+}
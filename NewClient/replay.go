@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+
+	"nimgame/pkg/client"
+	"nimgame/pkg/nim"
+	"nimgame/pkg/nimerr"
+)
+
+// runReplay replays the client's moves from the -replay transcript against
+// config's servers. A divergence is returned with no result, so the exit
+// status reports it whoever won.
+func runReplay(flags *clientFlags, config *client.ClientConfig, w io.Writer) (client.Result, error) {
+	transcript, err := client.ReadTranscript(flags.replay)
+	if err != nil {
+		return client.Result{}, nimerr.Wrap(nimerr.ErrConfig, err)
+	}
+	opts := []client.Option{flags.conditions()}
+	if !flags.quiet {
+		opts = append(opts, client.WithMoveHook(printMoves(w)))
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	result, err := client.Replay(ctx, *config, transcript, opts...)
+	if err != nil {
+		return client.Result{}, fmt.Errorf("replaying %v: %w", flags.replay, err)
+	}
+	printResult(w, result, flags.quiet)
+	fmt.Fprintf(w, "The server's replies matched all %d recorded moves\n", len(transcript.Moves))
+	return result, nil
+}
+
+// runReplayLocal draws the game in the transcript at path move by move,
+// checking each move is legal on the board before it.
+func runReplayLocal(path string, w io.Writer) error {
+	transcript, err := client.ReadTranscript(path)
+	if err != nil {
+		return nimerr.Wrap(nimerr.ErrConfig, err)
+	}
+	board := boardBytes(transcript.InitialBoard)
+	fmt.Fprintf(w, "Seed %d\n%v", transcript.Seed, nim.RenderBoard(board, nil))
+	for _, move := range transcript.Moves {
+		after := boardBytes(move.Board)
+		if !nim.ValidMove(board, after, move.MoveRow, move.MoveCount) {
+			return nimerr.Wrap(nimerr.ErrGameState, fmt.Errorf("%v: move %d, %v taking %d from row %d, can't go from %v to %v",
+				path, move.Number, move.Player, move.MoveCount, move.MoveRow, board, after))
+		}
+		board = after
+		fmt.Fprintf(w, "\n%d. %v took %d from row %d\n%v", move.Number, move.Player, move.MoveCount, move.MoveRow,
+			nim.RenderBoard(board, &nim.Move{Row: move.MoveRow, Count: uint8(move.MoveCount)}))
+	}
+	fmt.Fprintln(w)
+	printWinner(w, client.Result{Winner: transcript.Result.Winner})
+	return nil
+}
+
+func boardBytes(board []int) []uint8 {
+	b := make([]uint8, len(board))
+	for i, coins := range board {
+		b[i] = uint8(coins)
+	}
+	return b
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"nimgame/pkg/client"
+	"nimgame/pkg/nim"
+	"nimgame/pkg/nimerr"
+)
+
+// recordGame plays optimally against a scripted server on board and writes
+// the game's transcript, returning its path.
+func recordGame(t *testing.T, board []uint8) string {
+	config := client.ClientConfig{ClientAddress: "127.0.0.1:0", NimServerAddresses: []string{scriptedServer(t, board)}, RetryBaseMs: 20, RetryCapMs: 40}
+	transcript := client.NewTranscript(3)
+	sess, err := client.NewSession(config, nim.Optimal{}, client.WithSeed(3), client.WithTranscript(transcript))
+	if err != nil {
+		t.Fatalf("creating session: %v\n", err)
+	}
+	defer sess.Close()
+	if _, err := sess.Play(context.Background()); err != nil {
+		t.Fatalf("recording game: %v\n", err)
+	}
+	path := filepath.Join(t.TempDir(), "transcript.json")
+	if err := transcript.WriteFile(path); err != nil {
+		t.Fatalf("writing transcript: %v\n", err)
+	}
+	return path
+}
+
+func TestReplayFlag(t *testing.T) {
+	path := recordGame(t, []uint8{3, 4, 5})
+	for _, test := range []struct {
+		board    []uint8
+		diverged bool
+	}{
+		{[]uint8{3, 4, 5}, false},
+		{[]uint8{3, 4, 6}, true},
+	} {
+		flags, err := parseFlags([]string{"-replay", path}, io.Discard)
+		if err != nil {
+			t.Fatalf("parsing flags: %v\n", err)
+		}
+		config := &client.ClientConfig{ClientAddress: "127.0.0.1:0", NimServerAddresses: []string{scriptedServer(t, test.board)}, RetryBaseMs: 20, RetryCapMs: 40}
+		var out bytes.Buffer
+		result, err := runReplay(flags, config, &out)
+		if diverged := errors.Is(err, client.ErrTranscriptDiverged); diverged != test.diverged {
+			t.Errorf("replay against %v: divergence %v, expected %v: %v\n", test.board, diverged, test.diverged, err)
+		}
+		if test.diverged && exitCode(result, err) == client.ExitClientWon {
+			t.Errorf("replay against %v: divergence exits 0\n", test.board)
+		}
+		if !test.diverged && !strings.Contains(out.String(), "matched all") {
+			t.Errorf("replay against %v: no report of the match:\n%s", test.board, out.String())
+		}
+	}
+}
+
+func TestReplayLocal(t *testing.T) {
+	transcript := client.NewTranscript(3)
+	transcript.InitialBoard = []int{1, 2}
+	transcript.Moves = []client.TranscriptMove{
+		{Number: 1, Player: "client", MoveRow: 1, MoveCount: 1, Board: []int{1, 1}},
+		{Number: 2, Player: "server", MoveRow: 0, MoveCount: 1, Board: []int{0, 1}},
+		{Number: 3, Player: "client", MoveRow: 1, MoveCount: 1, Board: []int{0, 0}},
+	}
+	transcript.Result.Winner = "client"
+	path := filepath.Join(t.TempDir(), "transcript.json")
+	transcript.WriteFile(path)
+
+	var out bytes.Buffer
+	if err := runReplayLocal(path, &out); err != nil {
+		t.Fatalf("replaying: %v\n", err)
+	}
+	for _, want := range []string{"Seed 3\n0 ●\n1 ●●\n", "2. server took 1 from row 0\n0 ○\n1 ●\n", "Winner: client"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("replay is missing %q:\n%s", want, out.String())
+		}
+	}
+
+	transcript.Moves[1].Board = []int{0, 2} // puts a coin back
+	transcript.WriteFile(path)
+	if err := runReplayLocal(path, io.Discard); !errors.Is(err, nimerr.ErrGameState) {
+		t.Errorf("expected an illegal move to be reported, got %v\n", err)
+	}
+}
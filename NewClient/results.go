@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"log/slog"
+	"os"
+)
+
+// Difficulties the server derives from a GameStart seed's parity.
+const (
+	difficultyEasy int8 = 0
+	difficultyHard int8 = 1
+)
+
+// escalationWindow is how many recent games the win rate is taken over.
+const escalationWindow = 10
+
+// GameResult is the outcome of one finished game.
+type GameResult struct {
+	Win        bool // the client won
+	Difficulty int8
+}
+
+// GameResultStore remembers the outcomes of past games.
+type GameResultStore interface {
+	// Recent returns up to the last n results, oldest first.
+	Recent(n int) ([]GameResult, error)
+	Add(result GameResult) error
+}
+
+// fileResultStore keeps results as JSON lines in a file, so they outlive
+// the client process.
+type fileResultStore struct {
+	path string
+}
+
+func (s fileResultStore) Recent(n int) ([]GameResult, error) {
+	f, err := os.Open(s.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var results []GameResult
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var r GameResult
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			return nil, err
+		}
+		results = append(results, r)
+	}
+	if len(results) > n {
+		results = results[len(results)-n:]
+	}
+	return results, scanner.Err()
+}
+
+func (s fileResultStore) Add(result GameResult) error {
+	f, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(result)
+	if err != nil {
+		f.Close()
+		return err
+	}
+	if _, err = f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// nextDifficulty picks the difficulty of the next game from recent results:
+// hard once the win rate over the last escalationWindow games exceeds
+// threshold, easy once it drops below 1-threshold, and otherwise whatever
+// the last game was played at. fallback is used when there is no history.
+func nextDifficulty(store GameResultStore, threshold float64, fallback int8) (int8, error) {
+	results, err := store.Recent(escalationWindow)
+	if err != nil || len(results) == 0 {
+		return fallback, err
+	}
+	wins := 0
+	for _, r := range results {
+		if r.Win {
+			wins++
+		}
+	}
+	rate := float64(wins) / float64(len(results))
+
+	last := results[len(results)-1].Difficulty
+	next := last
+	if rate > threshold {
+		next = difficultyHard
+	} else if rate < 1-threshold {
+		next = difficultyEasy
+	}
+	if next != last {
+		slog.Info("changing difficulty", "from", last, "to", next, "winRate", rate)
+	}
+	return next, nil
+}
+
+// seedWithDifficulty adjusts seed so the server plays at difficulty.
+func seedWithDifficulty(seed, difficulty int8) int8 {
+	return seed&^1 | difficulty
+}
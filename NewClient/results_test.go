@@ -0,0 +1,78 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+// memResultStore is a GameResultStore held in memory.
+type memResultStore struct {
+	results []GameResult
+}
+
+func (s *memResultStore) Recent(n int) ([]GameResult, error) {
+	if len(s.results) > n {
+		return s.results[len(s.results)-n:], nil
+	}
+	return s.results, nil
+}
+
+func (s *memResultStore) Add(result GameResult) error {
+	s.results = append(s.results, result)
+	return nil
+}
+
+func TestEscalateAfterWinningStreak(t *testing.T) {
+	store := &memResultStore{}
+	for i := 0; i < 10; i++ {
+		store.Add(GameResult{Win: i < 8, Difficulty: difficultyEasy})
+	}
+	difficulty, err := nextDifficulty(store, 0.7, difficultyEasy)
+	if err != nil {
+		t.Fatalf("nextDifficulty: %v\n", err)
+	}
+	if difficulty != difficultyHard {
+		t.Errorf("8 wins in 10 should escalate to hard, got %d\n", difficulty)
+	}
+	if seed := seedWithDifficulty(4, difficulty); seed != 5 {
+		t.Errorf("expected seed 4 to become 5 for a hard game, got %d\n", seed)
+	}
+}
+
+func TestDifficultyHysteresis(t *testing.T) {
+	tests := []struct {
+		wins, last, want int8
+	}{
+		{2, difficultyHard, difficultyEasy}, // below 1-threshold
+		{5, difficultyHard, difficultyHard}, // in between, stay put
+		{5, difficultyEasy, difficultyEasy},
+	}
+	for _, test := range tests {
+		store := &memResultStore{}
+		for i := int8(0); i < 10; i++ {
+			store.Add(GameResult{Win: i < test.wins, Difficulty: test.last})
+		}
+		if got, _ := nextDifficulty(store, 0.7, difficultyEasy); got != test.want {
+			t.Errorf("%d wins at difficulty %d: got %d, want %d\n", test.wins, test.last, got, test.want)
+		}
+	}
+}
+
+func TestFileResultStore(t *testing.T) {
+	store := fileResultStore{path: filepath.Join(t.TempDir(), "results.jsonl")}
+	if results, err := store.Recent(10); err != nil || len(results) != 0 {
+		t.Fatalf("expected no results yet, got %v, %v\n", results, err)
+	}
+	for i := 0; i < 12; i++ {
+		if err := store.Add(GameResult{Win: i%3 == 0, Difficulty: int8(i % 2)}); err != nil {
+			t.Fatalf("adding result: %v\n", err)
+		}
+	}
+	results, err := store.Recent(10)
+	if err != nil {
+		t.Fatalf("reading results: %v\n", err)
+	}
+	if len(results) != 10 || results[0] != (GameResult{Win: false, Difficulty: 0}) || results[9] != (GameResult{Win: false, Difficulty: 1}) {
+		t.Errorf("unexpected last 10 results: %v\n", results)
+	}
+}
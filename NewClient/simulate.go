@@ -0,0 +1,138 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"nimgame/pkg/nim"
+	"nimgame/pkg/nimerr"
+)
+
+// simulation is the record of games played in-process by -simulate.
+type simulation struct {
+	games      int
+	clientWins int
+	seeds      []seedRecord // in seed order, those played at least once
+}
+
+// seedRecord is how the games on one seed's board went.
+type seedRecord struct {
+	seed       int64
+	games      int
+	clientWins int
+}
+
+// serverStrategy returns the strategy the server plays on seed's board.
+// Without a name it picks the way the server does: the best move on odd
+// seeds and a single coin on even ones, which nim.Optimal and nim.Basic
+// play exactly.
+func serverStrategy(name string, strategySeed int64) (func(seed int64) nim.Strategy, error) {
+	if name == "" {
+		return func(seed int64) nim.Strategy {
+			if seed&1 == 1 {
+				return nim.Optimal{}
+			}
+			return nim.Basic{}
+		}, nil
+	}
+	strategy, err := nim.NewStrategy(name, strategySeed)
+	if err != nil {
+		return nil, err
+	}
+	return func(int64) nim.Strategy { return strategy }, nil
+}
+
+// simulate plays games games with nim.SimulateGame, game i on the board
+// for seed lo+i%(hi-lo), the client moving first as it does against a
+// server.
+func simulate(games int, lo, hi int64, client nim.Strategy, server func(seed int64) nim.Strategy) simulation {
+	seeds := make([]seedRecord, hi-lo)
+	boards := make([][]uint8, hi-lo)
+	sim := simulation{games: games}
+	for i := 0; i < games; i++ {
+		n := int64(i) % (hi - lo)
+		seed := lo + n
+		if boards[n] == nil {
+			boards[n] = nim.GenerateBoard(seed)
+		}
+		seeds[n].seed = seed
+		seeds[n].games++
+		if nim.SimulateGame(boards[n], client, server(seed)) {
+			seeds[n].clientWins++
+			sim.clientWins++
+		}
+	}
+	for _, record := range seeds {
+		if record.games > 0 {
+			sim.seeds = append(sim.seeds, record)
+		}
+	}
+	return sim
+}
+
+// runSimulation plays the games -simulate asks for and prints how each
+// seed went.
+func runSimulation(flags *clientFlags, w io.Writer) error {
+	if flags.strategySeed == 0 {
+		flags.strategySeed = time.Now().UnixNano()
+	}
+	client, err := nim.NewStrategy(flags.strategy, flags.strategySeed)
+	if err != nil {
+		return nimerr.Wrap(nimerr.ErrConfig, err)
+	}
+	// seeded apart from the client's, in case both play randomly
+	server, err := serverStrategy(flags.serverStrategy, flags.strategySeed+1)
+	if err != nil {
+		return nimerr.Wrap(nimerr.ErrConfig, err)
+	}
+	games := flags.games
+	if !flags.set["games"] {
+		games = int(flags.seedHi - flags.seedLo)
+	}
+	serverName := flags.serverStrategy
+	if serverName == "" {
+		serverName = "by seed"
+	}
+	printSimulation(w, simulate(games, flags.seedLo, flags.seedHi, client, server), flags.strategy, serverName)
+	return nil
+}
+
+func printSimulation(w io.Writer, sim simulation, clientName, serverName string) {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "Seed\tGames\tClient wins\tWin rate")
+	for _, record := range sim.seeds {
+		fmt.Fprintf(tw, "%d\t%d\t%d\t%.1f%%\n", record.seed, record.games, record.clientWins, percent(record.clientWins, record.games))
+	}
+	tw.Flush()
+	client := fmt.Sprintf("client (%v)", clientName)
+	server := fmt.Sprintf("server (%v)", serverName)
+	fmt.Fprintf(w, "%d games: %v won %.1f%%, %v won %.1f%%\n", sim.games,
+		client, percent(sim.clientWins, sim.games), server, percent(sim.games-sim.clientWins, sim.games))
+
+	// the seeds on which the side that usually loses won at least once
+	weaker, won := server, func(r seedRecord) bool { return r.clientWins < r.games }
+	if sim.clientWins*2 < sim.games {
+		weaker, won = client, func(r seedRecord) bool { return r.clientWins > 0 }
+	}
+	var seeds []string
+	for _, record := range sim.seeds {
+		if won(record) {
+			seeds = append(seeds, strconv.FormatInt(record.seed, 10))
+		}
+	}
+	if len(seeds) == 0 {
+		seeds = []string{"none"}
+	}
+	fmt.Fprintf(w, "Seeds won by the %v: %v\n", weaker, strings.Join(seeds, " "))
+}
+
+func percent(n, of int) float64 {
+	if of == 0 {
+		return 0
+	}
+	return 100 * float64(n) / float64(of)
+}
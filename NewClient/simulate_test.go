@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"testing"
+
+	"nimgame/pkg/nim"
+)
+
+func TestSimulate(t *testing.T) {
+	byParity, _ := serverStrategy("", 0)
+	optimal, _ := serverStrategy("optimal", 0)
+
+	// every generated board has a non-zero nim sum, so playing first and
+	// perfectly always wins
+	sim := simulate(512, -128, 128, nim.Optimal{}, optimal)
+	if sim.games != 512 || sim.clientWins != 512 || len(sim.seeds) != 256 || sim.seeds[0].games != 2 {
+		t.Errorf("expected the optimal client to win all 512 games on 256 seeds, got %d of %d on %d\n",
+			sim.clientWins, sim.games, len(sim.seeds))
+	}
+
+	sim = simulate(1000, 0, 1000, nim.Basic{}, byParity)
+	wins := 0
+	for _, record := range sim.seeds {
+		want := nim.SimulateGame(nim.GenerateBoard(record.seed), nim.Basic{}, byParity(record.seed))
+		if (record.clientWins == 1) != want || record.games != 1 {
+			t.Errorf("seed %d: recorded %d wins in %d games, expected the client to win: %v\n", record.seed, record.clientWins, record.games, want)
+		}
+		wins += record.clientWins
+	}
+	if wins != sim.clientWins {
+		t.Errorf("seeds add up to %d client wins, total is %d\n", wins, sim.clientWins)
+	}
+}
+
+func TestRunSimulation(t *testing.T) {
+	flags, err := parseFlags([]string{"-simulate", "-strategy", "basic", "-server-strategy", "optimal", "-seed-range", "0:8"}, &bytes.Buffer{})
+	if err != nil {
+		t.Fatalf("parsing flags: %v\n", err)
+	}
+	var out bytes.Buffer
+	if err := runSimulation(flags, &out); err != nil {
+		t.Fatalf("simulating: %v\n", err)
+	}
+	var won []string
+	for seed := int64(0); seed < 8; seed++ {
+		if nim.SimulateGame(nim.GenerateBoard(seed), nim.Basic{}, nim.Optimal{}) {
+			won = append(won, strconv.FormatInt(seed, 10))
+		}
+	}
+	if len(won) == 0 {
+		won = []string{"none"}
+	}
+	want := "Seeds won by the client (basic): " + strings.Join(won, " ") + "\n"
+	if !strings.Contains(out.String(), "8 games: client (basic) won") || !strings.HasSuffix(out.String(), want) {
+		t.Errorf("unexpected output:\n%v\nexpected it to end with %q\n", out.String(), want)
+	}
+}
+
+func TestSimulateFlags(t *testing.T) {
+	bad := [][]string{
+		{"-simulate", "-seed-range", "5:5"},
+		{"-simulate", "-seed-range", "5"},
+		{"-simulate", "-seed-range", "0:10000000"},
+		{"-simulate", "-parallel", "4"},
+		{"-simulate", "-strategy", "interactive"},
+		{"-seed-range", "0:10", "3"},
+		{"-server-strategy", "basic", "3"},
+	}
+	for _, args := range bad {
+		if _, err := parseFlags(args, &bytes.Buffer{}); err == nil {
+			t.Errorf("%v: expected a usage error\n", args)
+		}
+	}
+	flags, err := parseFlags([]string{"-simulate"}, &bytes.Buffer{})
+	if err != nil || flags.seedLo != -128 || flags.seedHi != 128 {
+		t.Errorf("expected -simulate to default to every int8 seed, got %+v (%v)\n", flags, err)
+	}
+}
+
+// BenchmarkSimulate10k plays 10,000 games, the client playing randomly.
+func BenchmarkSimulate10k(b *testing.B) {
+	client, _ := nim.NewStrategy("random", 1)
+	server, _ := serverStrategy("", 0)
+	for i := 0; i < b.N; i++ {
+		simulate(10000, 0, 1000, client, server)
+	}
+}
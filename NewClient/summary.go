@@ -0,0 +1,81 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"nimgame/pkg/client"
+	"nimgame/pkg/nim"
+)
+
+// printResult writes the end of the game: with quiet only the winner,
+// otherwise the whole summary.
+func printResult(w io.Writer, result client.Result, quiet bool) {
+	if quiet {
+		printWinner(w, result)
+		return
+	}
+	printSummary(w, result)
+}
+
+func printWinner(w io.Writer, result client.Result) {
+	winner := result.Winner
+	if winner == "" {
+		winner = "none (game aborted)"
+	}
+	fmt.Fprintf(w, "Winner: %v\n", winner)
+}
+
+// printSummary writes the end-of-game summary for people to read.
+func printSummary(w io.Writer, result client.Result) {
+	printWinner(w, result)
+	fmt.Fprintf(w, "Moves: %d by the client, %d by the server\n", result.ClientMoves, result.ServerMoves)
+	fmt.Fprintf(w, "Retransmissions: %d, timeouts: %d, invalid packets: %d\n",
+		result.Retransmissions, result.Timeouts, result.InvalidPackets)
+	fmt.Fprintf(w, "RTT: mean %v, p50 %v, p95 %v, p99 %v, max %v\n",
+		result.MeanRTT, result.P50RTT, result.P95RTT, result.P99RTT, result.MaxRTT)
+	if predicted := result.SpeculativeHits + result.SpeculativeMisses; predicted > 0 {
+		fmt.Fprintf(w, "Speculation: %d of %d replies predicted, %v of move decisions hidden\n",
+			result.SpeculativeHits, predicted, result.SpeculativeSaved)
+	}
+	if result.TimeControl > 0 {
+		fmt.Fprintf(w, "Clock: client %v, server %v left of %v\n", result.ClientClock, result.ServerClock, result.TimeControl)
+	}
+	switch result.Forfeit {
+	case client.ForfeitTimeout:
+		fmt.Fprintln(w, "Forfeited: the client ran out of time")
+	case client.ForfeitMoveTTL:
+		fmt.Fprintln(w, "Forfeited: the client took too long over a move")
+	}
+	fmt.Fprintf(w, "Duration: %v\n", result.Duration)
+}
+
+// printMoves returns a move hook writing each move to w as a one-liner
+// with the board it leaves, and under time control the clocks.
+func printMoves(w io.Writer) func(client.MoveEvent) {
+	return func(e client.MoveEvent) {
+		var clocks string
+		if e.ClientClock > 0 || e.ServerClock > 0 {
+			clocks = fmt.Sprintf(" [client %v, server %v]", e.ClientClock, e.ServerClock)
+		}
+		fmt.Fprintf(w, "%-6v took %d from row %d: %v%v\n", e.Player, e.Count, e.Row, nim.RenderBoardLine(e.Board), clocks)
+	}
+}
+
+// writeSummary writes result to path as client.Summary JSON.
+func writeSummary(path string, result client.Result) error {
+	return writeJSON(path, result.Summary())
+}
+
+func writeJSON(path string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "    ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("writing summary: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"encoding/json"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"nimgame/pkg/client"
+	"nimgame/pkg/nim"
+)
+
+func TestSummaryOutput(t *testing.T) {
+	result := client.Result{Winner: "client", Moves: 5, ClientMoves: 3, ServerMoves: 2, Retransmissions: 1, Duration: time.Second}
+
+	var out bytes.Buffer
+	printSummary(&out, result)
+	for _, want := range []string{"Winner: client", "3 by the client, 2 by the server", "Retransmissions: 1"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("summary is missing %q:\n%s", want, out.String())
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), "summary.json")
+	if err := writeSummary(path, result); err != nil {
+		t.Fatalf("writing summary: %v\n", err)
+	}
+	data, _ := os.ReadFile(path)
+	var summary client.Summary
+	if err := json.Unmarshal(data, &summary); err != nil {
+		t.Fatalf("reading summary back: %v\n", err)
+	}
+	if summary != result.Summary() {
+		t.Errorf("wrote %+v, read back %+v\n", result.Summary(), summary)
+	}
+}
+
+// scriptedServer plays board against the client, taking one coin each
+// move and ignoring the first packet of the client's first move, so the
+// game includes a retransmission.
+func scriptedServer(t *testing.T, board []uint8) string {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatalf("starting scripted server: %v\n", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	go func() {
+		buf := make([]byte, 1024)
+		dropped := false
+		for {
+			n, raddr, err := conn.ReadFromUDP(buf)
+			if err != nil {
+				return
+			}
+			var move client.StateMoveMessage
+			if gob.NewDecoder(bytes.NewReader(buf[:n])).Decode(&move) != nil {
+				continue
+			}
+			reply := client.StateMoveMessage{GameState: board, MoveRow: -1, MoveCount: move.MoveCount}
+			if move.GameState != nil {
+				if !dropped {
+					dropped = true
+					continue
+				}
+				reply = client.StateMoveMessage{GameState: nil, MoveRow: -2, MoveCount: -2}
+				for i, coins := range move.GameState {
+					if coins > 0 {
+						move.GameState[i]--
+						reply = client.StateMoveMessage{GameState: move.GameState, MoveRow: int8(i), MoveCount: 1}
+						break
+					}
+				}
+			}
+			var out bytes.Buffer
+			gob.NewEncoder(&out).Encode(&reply)
+			conn.WriteToUDP(out.Bytes(), raddr)
+		}
+	}()
+	return conn.LocalAddr().String()
+}
+
+func TestOutputLevels(t *testing.T) {
+	tests := []struct {
+		flag            string
+		stdout, without []string
+		logs, nologs    []string
+	}{
+		{"-quiet", []string{"Winner: client\n"}, []string{"took", "Moves:"}, nil, []string{"msg="}},
+		{"", []string{"client took", "server took", "●", "Winner: client", "Retransmissions: 1"}, nil,
+			nil, []string{"msg=retransmitting", "msg=sent"}},
+		{"-v", []string{"client took"}, nil, []string{"msg=retransmitting", "msg=\"no reply from server\""}, []string{"msg=sent"}},
+		{"-vv", []string{"client took"}, nil, []string{"msg=retransmitting", "msg=sent", "msg=received"}, nil},
+	}
+	for _, test := range tests {
+		args := []string{"3"}
+		if test.flag != "" {
+			args = append([]string{test.flag}, args...)
+		}
+		flags, err := parseFlags(args, io.Discard)
+		if err != nil {
+			t.Fatalf("%v: %v\n", args, err)
+		}
+		config := &client.ClientConfig{
+			ClientAddress:      "127.0.0.1:0",
+			NimServerAddresses: []string{scriptedServer(t, []uint8{3, 4, 5})},
+			LogLevel:           "info",
+			RetryBaseMs:        20,
+			RetryCapMs:         40,
+		}
+		flags.apply(config)
+
+		var stdout, logs bytes.Buffer
+		opts := []client.Option{client.WithSeed(flags.seed), client.WithLogger(newLogger(&logs, flags.level(config)))}
+		if !flags.quiet {
+			opts = append(opts, client.WithMoveHook(printMoves(&stdout)))
+		}
+		sess, err := client.NewSession(*config, nim.Optimal{}, opts...)
+		if err != nil {
+			t.Fatalf("%v: %v\n", args, err)
+		}
+		result, err := sess.Play(context.Background())
+		sess.Close()
+		if err != nil {
+			t.Fatalf("%v: game failed: %v\n", args, err)
+		}
+		printResult(&stdout, result, flags.quiet)
+
+		check := func(name, out string, want []string, present bool) {
+			for _, s := range want {
+				if strings.Contains(out, s) != present {
+					verb := map[bool]string{true: "is missing", false: "shouldn't have"}[present]
+					t.Errorf("%q: %v %v %q:\n%s", test.flag, name, verb, s, out)
+				}
+			}
+		}
+		check("output", stdout.String(), test.stdout, true)
+		check("output", stdout.String(), test.without, false)
+		check("log", logs.String(), test.logs, true)
+		check("log", logs.String(), test.nologs, false)
+	}
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"os/signal"
+	"sync"
+	"text/tabwriter"
+
+	"nimgame/pkg/client"
+	"nimgame/pkg/nim"
+	"nimgame/pkg/nimerr"
+)
+
+// runTournament plays flags.games games from seed, or flags.parallel at
+// once, prints the record and writes it as JSON to -summary-out, or after
+// the record if that isn't given. The error is the last abort's if any game
+// was aborted.
+func runTournament(flags *clientFlags, config *client.ClientConfig, strategy nim.Strategy, seed int8, results GameResultStore) error {
+	// an interrupt abandons the games in progress and any after them
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	var t client.TournamentResult
+	games := flags.games
+	if flags.parallel > 1 {
+		games = flags.parallel
+		t = client.PlayParallel(ctx, games, seed, parallelSession(flags, config))
+	} else {
+		gameConfig := freePort(config)
+		t = client.PlayTournament(ctx, games, seed, func(game int, seed int8) (*client.Session, error) {
+			return client.NewSession(gameConfig, strategy, client.WithSeed(seed), client.WithStrategyName(flags.strategy), client.WithMoveDelay(flags.moveDelay), flags.conditions())
+		})
+	}
+
+	if results != nil {
+		for _, game := range t.Games {
+			if game.Result.Winner == "" {
+				continue
+			}
+			if err := results.Add(GameResult{Win: game.Result.Winner == "client", Difficulty: game.Seed & 1}); err != nil {
+				slog.Warn("couldn't save game result", "err", err)
+			}
+		}
+	}
+
+	printTournament(os.Stdout, t)
+	var werr error
+	if flags.summaryPath != "" {
+		werr = writeJSON(flags.summaryPath, t.Summary())
+	} else {
+		data, _ := json.MarshalIndent(t.Summary(), "", "    ")
+		fmt.Printf("%s\n", data)
+	}
+	return errors.Join(tournamentErr(ctx, t, games), werr)
+}
+
+// parallelSession returns sessions for games played at once. Each game has
+// its own strategy, seeded from -strategy-seed plus the game's index, and
+// local port, and prefixes its log lines with its index.
+func parallelSession(flags *clientFlags, config *client.ClientConfig) func(game int, seed int8) (*client.Session, error) {
+	var logMu sync.Mutex
+	return func(game int, seed int8) (*client.Session, error) {
+		strategy, err := nim.NewStrategy(flags.strategy, flags.strategySeed+int64(game))
+		if err != nil {
+			return nil, nimerr.Wrap(nimerr.ErrConfig, err)
+		}
+		gameConfig := freePort(config)
+		log := newLogger(&prefixWriter{prefix: fmt.Sprintf("[game %d] ", game+1), mu: &logMu, w: os.Stderr}, flags.level(config))
+		return client.NewSession(gameConfig, strategy, client.WithSeed(seed), client.WithStrategyName(flags.strategy), client.WithMoveDelay(flags.moveDelay), flags.conditions(), client.WithLogger(log))
+	}
+}
+
+// freePort returns config playing from a free port on ClientAddress's
+// host, so that games at once don't collide on the port and games in turn
+// don't get stray replies meant for the last.
+func freePort(config *client.ClientConfig) client.ClientConfig {
+	gameConfig := *config
+	if gameConfig.ClientAddress != "" {
+		host, _, _ := net.SplitHostPort(config.ClientAddress) // checked by client.ValidateConfig
+		gameConfig.ClientAddress = net.JoinHostPort(host, "0")
+	}
+	return gameConfig
+}
+
+// prefixWriter prefixes each write, a whole log line, before passing it to
+// w, one at a time.
+type prefixWriter struct {
+	prefix string
+	mu     *sync.Mutex
+	w      io.Writer
+}
+
+func (p *prefixWriter) Write(b []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, err := io.WriteString(p.w, p.prefix); err != nil {
+		return 0, err
+	}
+	return p.w.Write(b)
+}
+
+// tournamentErr is why the tournament didn't play every game to the end,
+// or nil if it did.
+func tournamentErr(ctx context.Context, t client.TournamentResult, games int) error {
+	if ctx.Err() != nil {
+		return fmt.Errorf("tournament stopped after %d of %d games: %w", len(t.Games), games, client.ErrCanceled)
+	}
+	if t.Aborts == 0 {
+		return nil
+	}
+	var last error
+	for _, game := range t.Games {
+		if game.Err != nil {
+			last = game.Err
+		}
+	}
+	return fmt.Errorf("%d of %d games aborted, the last with: %w", t.Aborts, len(t.Games), last)
+}
+
+// printTournament writes a table of the games played and the record.
+func printTournament(w io.Writer, t client.TournamentResult) {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "Game\tSeed\tWinner\tMoves\tDuration")
+	for i, game := range t.Games {
+		winner := game.Result.Winner
+		if winner == "" {
+			winner = "aborted: " + game.Err.Error()
+		}
+		fmt.Fprintf(tw, "%d\t%d\t%v\t%d\t%v\n", i+1, game.Seed, winner, game.Result.Moves, game.Result.Duration)
+	}
+	tw.Flush()
+	fmt.Fprintf(w, "Record: %d won, %d lost, %d aborted\n", t.Wins, t.Losses, t.Aborts)
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"nimgame/pkg/client"
+)
+
+func TestTournamentOutput(t *testing.T) {
+	result := client.TournamentResult{
+		Games: []client.TournamentGame{
+			{Seed: 3, Result: client.Result{Winner: "client", Moves: 7, Duration: time.Second}},
+			{Seed: 4, Err: client.ErrNoReply},
+		},
+		Wins:   1,
+		Aborts: 1,
+	}
+
+	var out bytes.Buffer
+	printTournament(&out, result)
+	for _, want := range []string{"Game", "client", "aborted: server stopped responding", "Record: 1 won, 0 lost, 1 aborted"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("table is missing %q:\n%s", want, out.String())
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), "tournament.json")
+	if err := writeJSON(path, result.Summary()); err != nil {
+		t.Fatalf("writing summary: %v\n", err)
+	}
+	data, _ := os.ReadFile(path)
+	var summary client.TournamentSummary
+	if err := json.Unmarshal(data, &summary); err != nil {
+		t.Fatalf("reading summary back: %v\n", err)
+	}
+	if summary.Wins != 1 || summary.Aborts != 1 || len(summary.Games) != 2 || summary.Games[1].Error == "" {
+		t.Errorf("unexpected summary read back: %+v\n", summary)
+	}
+}
+
+func TestParallelLogPrefix(t *testing.T) {
+	var out bytes.Buffer
+	var mu sync.Mutex
+	first := newLogger(&prefixWriter{prefix: "[game 1] ", mu: &mu, w: &out}, slog.LevelInfo)
+	second := newLogger(&prefixWriter{prefix: "[game 2] ", mu: &mu, w: &out}, slog.LevelInfo)
+	first.Info("one")
+	second.Warn("two")
+	first.Debug("hidden")
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[0], "[game 1] ") || !strings.Contains(lines[0], "msg=one") ||
+		!strings.HasPrefix(lines[1], "[game 2] ") || !strings.Contains(lines[1], "msg=two") {
+		t.Errorf("expected one prefixed line per game, got:\n%s", out.String())
+	}
+}
+
+func TestGamesFlag(t *testing.T) {
+	tests := []struct {
+		args []string
+		ok   bool
+	}{
+		{[]string{"-games", "10", "1"}, true},
+		{[]string{"-games", "0", "1"}, false},
+		{[]string{"-games", "2", "-record", "game.pgn", "1"}, false},
+		{[]string{"-games", "2", "-print-stats", "1"}, false},
+		{[]string{"-record", "game.pgn", "1"}, true},
+		{[]string{"-parallel", "20", "1"}, true},
+		{[]string{"-parallel", "0", "1"}, false},
+		{[]string{"-parallel", "2", "-games", "2", "1"}, false},
+		{[]string{"-parallel", "2", "-record", "game.pgn", "1"}, false},
+		{[]string{"-games", "2", "-transcript", "game.json", "1"}, false},
+		{[]string{"-transcript", "game.json", "1"}, true},
+		{[]string{"-parallel", "2", "-strategy", "interactive", "1"}, false},
+		{[]string{"-match", "5", "1"}, true},
+		{[]string{"-match", "4", "1"}, false},
+		{[]string{"-match", "0", "1"}, false},
+		{[]string{"-match", "3", "-games", "2", "1"}, false},
+		{[]string{"-match", "3", "-summary-out", "match.json", "1"}, false},
+	}
+	for _, test := range tests {
+		_, err := parseFlags(test.args, io.Discard)
+		if (err == nil) != test.ok {
+			t.Errorf("parseFlags(%v): unexpected error %v\n", test.args, err)
+		}
+	}
+}
@@ -5,10 +5,17 @@ import (
 	"encoding/gob"
 	"encoding/json"
 	"errors"
+	"flag"
 	"fmt"
 	"github.com/DistributedClocks/tracing"
+	"io"
 	"io/ioutil"
+	"math"
 	"net"
+	"nimgame/pkg/configfile"
+	"nimgame/pkg/nim"
+	"nimgame/pkg/nimerr"
+	"nimgame/pkg/udpnet"
 	"os"
 	"strconv"
 )
@@ -45,16 +52,90 @@ type StateMoveMessage struct {
 	MoveCount int8
 }
 
+// clientFlags is the parsed command line.
+type clientFlags struct {
+	seed       int8
+	configPath string
+}
+
+// parseFlags parses args, which exclude the program name. The seed may be
+// given as -seed or, as it used to be, as the only positional argument.
+// Usage is written to output on error.
+func parseFlags(args []string, output io.Writer) (*clientFlags, error) {
+	f := &clientFlags{}
+	fs := flag.NewFlagSet("client", flag.ContinueOnError)
+	fs.SetOutput(output)
+	seed := fs.Int("seed", 0, "game `seed`, -128 to 127; odd seeds play the hard server")
+	fs.StringVar(&f.configPath, "config", "", "read the client config from `path` rather than searching for client_config.json")
+	fs.Usage = func() {
+		fmt.Fprintln(output, "Usage: client [flags] [seed]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	seedGiven := false
+	fs.Visit(func(fl *flag.Flag) { seedGiven = seedGiven || fl.Name == "seed" })
+
+	usageErr := func(format string, args ...interface{}) error {
+		err := fmt.Errorf(format, args...)
+		fmt.Fprintln(output, err)
+		fs.Usage()
+		return err
+	}
+	switch {
+	case fs.NArg() > 1:
+		return nil, usageErr("too many arguments: %v", fs.Args())
+	case fs.NArg() == 1 && seedGiven:
+		return nil, usageErr("seed given both as -seed and as an argument")
+	case fs.NArg() == 1:
+		n, err := strconv.Atoi(fs.Arg(0))
+		if err != nil {
+			return nil, usageErr("seed %q is not an integer", fs.Arg(0))
+		}
+		*seed = n
+	case !seedGiven:
+		return nil, usageErr("no seed given")
+	}
+	if *seed < math.MinInt8 || *seed > math.MaxInt8 {
+		return nil, usageErr("seed %d is out of range -128 to 127", *seed)
+	}
+	f.seed = int8(*seed)
+	return f, nil
+}
+
 func main() {
-	if len(os.Args) != 2 {
-		fmt.Println("Usage: client.go [seed]")
+	flags, err := parseFlags(os.Args[1:], os.Stderr)
+	if errors.Is(err, flag.ErrHelp) {
 		return
+	} else if err != nil {
+		os.Exit(2)
 	}
-	arg, err := strconv.Atoi(os.Args[1])
-	CheckErr(err, "Provided seed could not be converted to integer", arg)
-	seed := int8(arg)
+	if err := run(flags); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		if errors.Is(err, nimerr.ErrConfig) {
+			os.Exit(2)
+		}
+		os.Exit(1)
+	}
+}
 
-	config := ReadConfig("../config/client_config.json")
+// run plays the game flags ask for.
+func run(flags *clientFlags) error {
+	seed := flags.seed
+	path, err := configfile.Locate(flags.configPath, "client_config.json", os.Getenv)
+	if err != nil {
+		return nimerr.Wrap(nimerr.ErrConfig, err)
+	}
+	config, err := ReadConfig(path)
+	if err != nil {
+		return err
+	}
+	// this client records every game, and the tracing library exits when
+	// it can't dial the tracing server
+	if config.TracingServerAddress == "" {
+		return nimerr.Wrap(nimerr.ErrConfig, errors.New("TracingServerAddress is empty; this client records every game to a tracing server"))
+	}
 	tracer := tracing.NewTracer(tracing.TracerConfig{
 		ServerAddress:  config.TracingServerAddress,
 		TracerIdentity: config.TracingIdentity,
@@ -68,46 +149,85 @@ func main() {
 			Seed: seed,
 		})
 
-	buf := make([]byte, 5000)
-	bufOut := make([]byte, 5000)
-
-	remoteadrr, err := net.ResolveUDPAddr("udp", config.NimServerAddress)
-	CheckErr(err, "Error in resolving server address", remoteadrr)
+	network := udpnet.Network(config.NimServerAddress, false)
+	remoteadrr, err := net.ResolveUDPAddr(network, config.NimServerAddress)
+	if err != nil {
+		return nimerr.Wrap(nimerr.ErrConfig, fmt.Errorf("resolving server address: %w", err))
+	}
 
-	laddr, err := net.ResolveUDPAddr("udp", config.ClientAddress)
-	CheckErr(err, "Error in resolving local addr", laddr)
+	laddr, err := net.ResolveUDPAddr(network, config.ClientAddress)
+	if err != nil {
+		return nimerr.Wrap(nimerr.ErrConfig, fmt.Errorf("resolving local addr: %w", err))
+	}
 
-	conn, err := net.DialUDP("udp", laddr, remoteadrr)
-	CheckErr(err, "Error in connecting to server", conn)
+	conn, err := net.DialUDP(network, laddr, remoteadrr)
+	if err != nil {
+		return nimerr.Wrap(nimerr.ErrTransport, fmt.Errorf("connecting to server: %w", err))
+	}
 
 	defer conn.Close()
 
-	bufOut, err = Marshal(ClientMove{nil, -1, seed})
-	CheckErr(err, "Error in marshalling the server message", bufOut)
+	return playGame(conn, trace, seed)
+}
+
+// actionRecorder is the part of the tracing API playGame uses.
+type actionRecorder interface {
+	RecordAction(action interface{})
+}
+
+// playGame plays the game for seed with the server at the other end of conn.
+func playGame(conn *net.UDPConn, trace actionRecorder, seed int8) error {
+	buf := make([]byte, 5000)
+	bufOut := make([]byte, 5000)
+
+	// the board after our last move, which the server's reply must follow
+	// from; nil until the game's board arrives
+	var lastBoard []uint8
+
+	bufOut, err := Marshal(ClientMove{nil, -1, seed})
+	if err != nil {
+		return nimerr.Wrap(nimerr.ErrProtocol, fmt.Errorf("marshalling the message: %w", err))
+	}
 
 	trace.RecordAction(ClientMove{nil, -1, seed})
 
 	_, err = conn.Write(bufOut)
-	CheckErr(err, "Error in sending message to server")
+	if err != nil {
+		return nimerr.Wrap(nimerr.ErrTransport, fmt.Errorf("sending message to server: %w", err))
+	}
 
 	for {
 
 		// Reading message send from the server
 		n, _, err := conn.ReadFromUDP(buf)
-		CheckErr(err, "Error in reading from bufIn")
+		if err != nil {
+			return nimerr.Wrap(nimerr.ErrTransport, fmt.Errorf("reading from server: %w", err))
+		}
 
 		ServerMove := StateMoveMessage{}
 		err = Unmarshal(buf[:n], &ServerMove)
-		CheckErr(err, "Error in Unmarshalling the server message")
+		if err != nil {
+			return nimerr.Wrap(nimerr.ErrProtocol, fmt.Errorf("unmarshalling the server message: %w", err))
+		}
+		// a duplicated or delayed reply doesn't follow from our last move,
+		// and answering it would move twice from a stale board
+		if !freshReply(lastBoard, &ServerMove) {
+			fmt.Fprintf(os.Stderr, "Dropping duplicate server reply: %v\n", ServerMove)
+			continue
+		}
 		trace.RecordAction(ServerMoveReceive(ServerMove))
 
 		// Sending message to server on when server start their first move
 		if ServerMove.GameState == nil && ServerMove.MoveRow == -1 {
 			bufOut, err = Marshal(ClientMove{nil, -1, seed})
-			CheckErr(err, "Error in marshalling the message", bufOut)
+			if err != nil {
+				return nimerr.Wrap(nimerr.ErrProtocol, fmt.Errorf("marshalling the message: %w", err))
+			}
 
 			_, err = conn.Write(bufOut)
-			CheckErr(err, "Error is sending message to server")
+			if err != nil {
+				return nimerr.Wrap(nimerr.ErrTransport, fmt.Errorf("sending message to server: %w", err))
+			}
 
 			trace.RecordAction(ClientMove{nil, -1, seed})
 
@@ -116,18 +236,23 @@ func main() {
 			state := nimsum(ServerMove.GameState)
 			if state {
 				trace.RecordAction(GameComplete{Winner: "Server"})
-				break
+				return nil
 			}
 
 			newMove := play(ServerMove)
+			lastBoard = newMove.GameState
 
 			trace.RecordAction(ClientMove(newMove))
 
 			bufOut, err := Marshal(newMove)
+			if err != nil {
+				return nimerr.Wrap(nimerr.ErrProtocol, fmt.Errorf("marshalling the message: %w", err))
+			}
 
 			_, err = conn.Write(bufOut)
-
-			CheckErr(err, "Error in sending message to server")
+			if err != nil {
+				return nimerr.Wrap(nimerr.ErrTransport, fmt.Errorf("sending message to server: %w", err))
+			}
 
 		}
 
@@ -135,23 +260,20 @@ func main() {
 
 }
 
-func ReadConfig(filepath string) *ClientConfig {
+func ReadConfig(filepath string) (*ClientConfig, error) {
 	configFile := filepath
 	configData, err := ioutil.ReadFile(configFile)
-	CheckErr(err, "reading config file")
+	if err != nil {
+		return nil, nimerr.Wrap(nimerr.ErrConfig, fmt.Errorf("reading config file: %w", err))
+	}
 
 	config := new(ClientConfig)
 	err = json.Unmarshal(configData, config)
-	CheckErr(err, "parsing config data")
-
-	return config
-}
-
-func CheckErr(err error, errfmsg string, fargs ...interface{}) {
 	if err != nil {
-		fmt.Fprintf(os.Stderr, errfmsg, fargs...)
-		os.Exit(1)
+		return nil, nimerr.Wrap(nimerr.ErrConfig, fmt.Errorf("parsing config data: %w", err))
 	}
+
+	return config, nil
 }
 
 func Unmarshal(input []byte, move interface{}) error {
@@ -192,6 +314,18 @@ func normalmove(board []uint8) (*StateMoveMessage, error) {
 	return nil, errors.New("no move to make")
 }
 
+// freshReply reports whether reply, from the server, follows from state,
+// the board after our last move. Messages without a board always do.
+func freshReply(state []uint8, reply *StateMoveMessage) bool {
+	if reply.GameState == nil {
+		return true
+	}
+	if state == nil {
+		return reply.MoveRow == -1 // the game's board
+	}
+	return nim.ValidMove(state, reply.GameState, int(reply.MoveRow), int(reply.MoveCount))
+}
+
 func nimsum(move []uint8) bool {
 	state := false
 	count := 0
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"nimgame/internal/wiredump"
+	"nimgame/pkg/nim"
+	"nimgame/pkg/nimserver"
+)
+
+type recordingTrace struct {
+	actions []interface{}
+}
+
+func (r *recordingTrace) RecordAction(action interface{}) {
+	r.actions = append(r.actions, action)
+}
+
+// duplicatingServer plays board taking a coin at a time, sending each reply
+// twice, and reports the first client move that isn't valid, if any, once
+// the board is empty.
+func duplicatingServer(t *testing.T, board []uint8) (*net.UDPAddr, <-chan error) {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatalf("listening: %v\n", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	done := make(chan error, 1)
+	go func() {
+		buf := make([]byte, 5000)
+		for {
+			n, raddr, err := conn.ReadFromUDP(buf)
+			if err != nil {
+				done <- err
+				return
+			}
+			var move StateMoveMessage
+			if err := Unmarshal(buf[:n], &move); err != nil {
+				done <- err
+				return
+			}
+			var reply StateMoveMessage
+			if move.GameState == nil && move.MoveRow == -1 {
+				reply = StateMoveMessage{append([]uint8(nil), board...), -1, move.MoveCount}
+			} else if !nim.ValidMove(board, move.GameState, int(move.MoveRow), int(move.MoveCount)) {
+				done <- fmt.Errorf("invalid move %v on %v", move, board)
+				return
+			} else {
+				next, err := normalmove(move.GameState)
+				if err != nil {
+					done <- err
+					return
+				}
+				reply = *next
+			}
+			board = append([]uint8(nil), reply.GameState...)
+			packet, _ := Marshal(reply)
+			conn.WriteToUDP(packet, raddr)
+			conn.WriteToUDP(packet, raddr)
+			if nimsum(board) {
+				done <- nil
+				return
+			}
+		}
+	}()
+	return conn.LocalAddr().(*net.UDPAddr), done
+}
+
+// TestDuplicateReplies plays a game in which every server reply arrives
+// twice; answering the copies would move from a stale board.
+func TestDuplicateReplies(t *testing.T) {
+	raddr, serverDone := duplicatingServer(t, []uint8{2, 2}) // the server takes the last coin
+	conn, err := net.DialUDP("udp", nil, raddr)
+	if err != nil {
+		t.Fatalf("dialing server: %v\n", err)
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	trace := &recordingTrace{}
+	if err := playGame(conn, trace, 3); err != nil {
+		t.Fatalf("game failed: %v\n", err)
+	}
+	if err := <-serverDone; err != nil {
+		t.Fatalf("server: %v\n", err)
+	}
+	received := 0
+	for _, action := range trace.actions {
+		if _, ok := action.(ServerMoveReceive); ok {
+			received++
+		}
+	}
+	// the board, and the server's two moves
+	if received != 3 {
+		t.Errorf("expected 3 server replies traced, got %d: %v\n", received, trace.actions)
+	}
+	if last := trace.actions[len(trace.actions)-1]; last != (GameComplete{Winner: "Server"}) {
+		t.Errorf("expected the game to end won by the server, got %v\n", last)
+	}
+}
+
+// readWireDump reads a canonical packet the server's tests keep in their
+// legacy wire dumps, from before any field was added.
+func readWireDump(t *testing.T, name string) []byte {
+	packet, err := wiredump.Read(filepath.Join("pkg", "nimserver", "testdata", "wire", "legacy", name+".hex"))
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	return packet
+}
+
+// TestWireCompatibility checks this client still writes the packets the
+// server's tests decode, byte for byte, and reads the server's.
+func TestWireCompatibility(t *testing.T) {
+	sent := map[string]ClientMove{
+		"legacy_game_start": {nil, -1, 5},
+		"legacy_move":       {[]uint8{2, 3, 4}, 0, 1},
+	}
+	for name, move := range sent {
+		packet, err := Marshal(move)
+		if err != nil {
+			t.Fatalf("%v: marshalling %v: %v\n", name, move, err)
+		}
+		if want := readWireDump(t, name); !bytes.Equal(packet, want) {
+			t.Errorf("%v: encoded as %x, expected %x\n", name, packet, want)
+		}
+	}
+
+	received := map[string]StateMoveMessage{
+		"server_board": {[]uint8{3, 3, 4}, -1, 5},
+		"server_move":  {[]uint8{1, 3, 4}, 0, 1},
+		"concede":      {nil, -2, -2},
+	}
+	for name, want := range received {
+		var got StateMoveMessage
+		if err := Unmarshal(readWireDump(t, name), &got); err != nil {
+			t.Errorf("%v: decoding: %v\n", name, err)
+		} else if !reflect.DeepEqual(got, want) {
+			t.Errorf("%v decoded to %v, expected %v\n", name, got, want)
+		}
+	}
+}
+
+// TestAgainstServer plays a game with the nim server, which must still
+// understand this client's packets and send ones it understands.
+func TestAgainstServer(t *testing.T) {
+	server, err := nimserver.New(nimserver.WithListenAddress("127.0.0.1:0"))
+	if err != nil {
+		t.Fatalf("starting server: %v\n", err)
+	}
+	done := make(chan error, 1)
+	go func() { done <- server.Run(context.Background()) }()
+	defer func() {
+		server.Shutdown(context.Background())
+		<-done
+	}()
+
+	conn, err := net.DialUDP("udp", nil, server.Addr().(*net.UDPAddr))
+	if err != nil {
+		t.Fatalf("dialing server: %v\n", err)
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	// an odd seed plays the hard server, which always beats taking a coin
+	// at a time
+	trace := &recordingTrace{}
+	if err := playGame(conn, trace, 3); err != nil {
+		t.Fatalf("game failed: %v\n", err)
+	}
+	if last := trace.actions[len(trace.actions)-1]; last != (GameComplete{Winner: "Server"}) {
+		t.Errorf("expected the game to end won by the server, got %v\n", last)
+	}
+	if stats := server.Stats(); stats.GamesStarted != 1 || stats.InvalidMoves != 0 {
+		t.Errorf("server stats are %+v, expected one game and no invalid moves\n", stats)
+	}
+}
+
+func TestFreshReply(t *testing.T) {
+	tests := []struct {
+		state []uint8
+		reply StateMoveMessage
+		want  bool
+	}{
+		{nil, StateMoveMessage{[]uint8{2, 2}, -1, 3}, true},
+		{nil, StateMoveMessage{nil, -1, 3}, true},
+		{[]uint8{1, 2}, StateMoveMessage{[]uint8{0, 2}, 0, 1}, true},
+		{[]uint8{1, 2}, StateMoveMessage{[]uint8{2, 2}, -1, 3}, false}, // the board again
+		{[]uint8{0, 1}, StateMoveMessage{[]uint8{0, 2}, 0, 1}, false},  // a move we already answered
+	}
+	for _, test := range tests {
+		if got := freshReply(test.state, &test.reply); got != test.want {
+			t.Errorf("freshReply(%v, %v) = %v, expected %v\n", test.state, test.reply, got, test.want)
+		}
+	}
+}
+
+func TestParseFlags(t *testing.T) {
+	tests := []struct {
+		args   []string
+		seed   int8
+		config string
+		ok     bool
+	}{
+		{[]string{"5"}, 5, "", true},
+		{[]string{"-seed", "-7", "-config", "c.json"}, -7, "c.json", true},
+		{[]string{"-config", "c.json", "9"}, 9, "c.json", true},
+		{[]string{}, 0, "", false},
+		{[]string{"-seed", "1", "2"}, 0, "", false},
+		{[]string{"x"}, 0, "", false},
+		{[]string{"200"}, 0, "", false},
+	}
+	for _, test := range tests {
+		f, err := parseFlags(test.args, io.Discard)
+		if (err == nil) != test.ok {
+			t.Errorf("parseFlags(%v): unexpected error %v\n", test.args, err)
+			continue
+		}
+		if err == nil && (f.seed != test.seed || f.configPath != test.config) {
+			t.Errorf("parseFlags(%v): seed %d, config %q, want %d, %q\n", test.args, f.seed, f.configPath, test.seed, test.config)
+		}
+	}
+}
@@ -0,0 +1,338 @@
+package main
+
+import (
+	crand "crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+
+	"nimgame/pkg/nim"
+)
+
+// GameSession is one game played over the API. The player always moves
+// first; the server answers each move at once, so it is only ever the
+// player's turn until the game ends.
+type GameSession struct {
+	ID     string
+	Board  []uint8
+	Seed   int8
+	Winner string // "player" or "server" once the game is over
+	Moves  int    // moves by both sides
+	server nim.Strategy
+
+	lastActive time.Time // created or last moved on, see evict
+
+	// spectators' event streams, closed when the game ends
+	watchers map[chan moveEvent]bool
+}
+
+// turn is whose move it is, or "" once the game is over.
+func (g *GameSession) turn() string {
+	if g.Winner != "" {
+		return ""
+	}
+	return "player"
+}
+
+// gameState is the body of every successful response.
+type gameState struct {
+	GameID     string    `json:"game_id"`
+	Board      []int     `json:"board"` // []uint8 would encode as base64
+	Turn       string    `json:"turn,omitempty"`
+	Winner     string    `json:"winner,omitempty"`
+	Moves      int       `json:"moves"`
+	ServerMove *moveBody `json:"server_move,omitempty"`
+}
+
+type moveBody struct {
+	Row   int `json:"row"`
+	Count int `json:"count"`
+}
+
+type newGameBody struct {
+	Seed *int8 `json:"seed"` // odd seeds play the optimal server; random if unset
+}
+
+// moveEvent is a move by either side, as sent to spectators.
+type moveEvent struct {
+	Player    string `json:"player"` // "player" or "server"
+	MoveRow   int    `json:"move_row"`
+	MoveCount int    `json:"move_count"`
+	GameState []int  `json:"game_state"` // after the move
+}
+
+type errorBody struct {
+	Error string `json:"error"`
+}
+
+var (
+	errNoGame   = errors.New("no such game")
+	errGameOver = errors.New("game is over")
+)
+
+// gameTTL is how long a game is kept after it was created or last moved
+// on, finished or not, so abandoned games don't pile up.
+const gameTTL = time.Hour
+
+// api serves games from memory, dropping each gameTTL after its last move.
+type api struct {
+	mu    sync.Mutex
+	games map[string]*GameSession
+	now   func() time.Time
+}
+
+func newAPI() *api {
+	return &api{games: make(map[string]*GameSession), now: time.Now}
+}
+
+// evict drops the games idle for longer than gameTTL, ending their
+// spectators' streams. The caller holds a.mu.
+func (a *api) evict() {
+	now := a.now()
+	for id, game := range a.games {
+		if now.Sub(game.lastActive) > gameTTL {
+			for events := range game.watchers {
+				game.unwatch(events)
+			}
+			delete(a.games, id)
+		}
+	}
+}
+
+// handler routes the API's endpoints.
+func (a *api) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /game", a.createGame)
+	mux.HandleFunc("GET /game/{id}", a.getGame)
+	mux.HandleFunc("POST /game/{id}/move", a.move)
+	mux.HandleFunc("GET /game/{id}/watch", a.watch)
+	return mux
+}
+
+func (a *api) createGame(w http.ResponseWriter, r *http.Request) {
+	var body newGameBody
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("parsing body: %w", err))
+			return
+		}
+	}
+	seed := int8(rand.Intn(256) - 128)
+	if body.Seed != nil {
+		seed = *body.Seed
+	}
+	var server nim.Strategy = nim.Basic{}
+	if seed&1 == 1 {
+		server = nim.Optimal{}
+	}
+	game := &GameSession{ID: newGameID(), Board: nim.GenerateBoard(int64(seed)), Seed: seed, server: server}
+
+	a.mu.Lock()
+	a.evict()
+	game.lastActive = a.now()
+	a.games[game.ID] = game
+	state := game.state(nil)
+	a.mu.Unlock()
+	writeJSON(w, http.StatusOK, state)
+}
+
+func (a *api) getGame(w http.ResponseWriter, r *http.Request) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	game, ok := a.games[r.PathValue("id")]
+	if !ok {
+		writeError(w, http.StatusNotFound, errNoGame)
+		return
+	}
+	writeJSON(w, http.StatusOK, game.state(nil))
+}
+
+func (a *api) move(w http.ResponseWriter, r *http.Request) {
+	var body moveBody
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("parsing body: %w", err))
+		return
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	game, ok := a.games[r.PathValue("id")]
+	if !ok {
+		writeError(w, http.StatusNotFound, errNoGame)
+		return
+	}
+	if game.Winner != "" {
+		writeError(w, http.StatusConflict, errGameOver)
+		return
+	}
+	if err := game.take(body.Row, body.Count); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	game.lastActive = a.now()
+	game.publish("player", body.Row, body.Count)
+	if isEmpty(game.Board) {
+		game.end("player")
+		writeJSON(w, http.StatusOK, game.state(nil))
+		return
+	}
+
+	row, count := game.server.Move(game.Board)
+	game.take(row, int(count))
+	game.publish("server", row, int(count))
+	if isEmpty(game.Board) {
+		game.end("server")
+	}
+	writeJSON(w, http.StatusOK, game.state(&moveBody{Row: row, Count: int(count)}))
+}
+
+// watch streams the game's moves as server-sent events until it ends or
+// the spectator disconnects.
+func (a *api) watch(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, errors.New("streaming unsupported"))
+		return
+	}
+	a.mu.Lock()
+	game, ok := a.games[r.PathValue("id")]
+	if !ok {
+		a.mu.Unlock()
+		writeError(w, http.StatusNotFound, errNoGame)
+		return
+	}
+	if game.Winner != "" {
+		a.mu.Unlock()
+		writeError(w, http.StatusConflict, errGameOver)
+		return
+	}
+	events := game.watch()
+	a.mu.Unlock()
+	defer func() {
+		a.mu.Lock()
+		game.unwatch(events)
+		a.mu.Unlock()
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			data, _ := json.Marshal(event)
+			if _, err := fmt.Fprintf(w, "data: %s\n\n", data); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// watcherBuffer is how many events a spectator may fall behind by before
+// it is dropped, so a slow one never holds up the game.
+const watcherBuffer = 16
+
+// watch returns a new spectator's event stream. The caller must hold the
+// api's lock, as for the other GameSession methods.
+func (g *GameSession) watch() chan moveEvent {
+	if g.watchers == nil {
+		g.watchers = make(map[chan moveEvent]bool)
+	}
+	events := make(chan moveEvent, watcherBuffer)
+	g.watchers[events] = true
+	return events
+}
+
+// unwatch drops a spectator, if it hasn't been already.
+func (g *GameSession) unwatch(events chan moveEvent) {
+	if g.watchers[events] {
+		delete(g.watchers, events)
+		close(events)
+	}
+}
+
+// publish sends a move to every spectator, dropping any that have fallen
+// too far behind.
+func (g *GameSession) publish(player string, row, count int) {
+	event := moveEvent{Player: player, MoveRow: row, MoveCount: count, GameState: g.state(nil).Board}
+	for events := range g.watchers {
+		select {
+		case events <- event:
+		default:
+			g.unwatch(events)
+		}
+	}
+}
+
+// end records the winner and ends every spectator's stream.
+func (g *GameSession) end(winner string) {
+	g.Winner = winner
+	for events := range g.watchers {
+		g.unwatch(events)
+	}
+}
+
+// take removes count coins from row.
+func (g *GameSession) take(row, count int) error {
+	if row < 0 || row >= len(g.Board) {
+		return fmt.Errorf("row %d is not on the board, which has %d rows", row, len(g.Board))
+	}
+	if count < 1 || count > int(g.Board[row]) {
+		return fmt.Errorf("can't take %d from row %d, which has %d", count, row, g.Board[row])
+	}
+	g.Board[row] -= uint8(count)
+	g.Moves++
+	return nil
+}
+
+func (g *GameSession) state(serverMove *moveBody) gameState {
+	board := make([]int, len(g.Board))
+	for i, coins := range g.Board {
+		board[i] = int(coins)
+	}
+	return gameState{
+		GameID:     g.ID,
+		Board:      board,
+		Turn:       g.turn(),
+		Winner:     g.Winner,
+		Moves:      g.Moves,
+		ServerMove: serverMove,
+	}
+}
+
+func isEmpty(board []uint8) bool {
+	for _, coins := range board {
+		if coins != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func newGameID() string {
+	id := make([]byte, 8)
+	crand.Read(id)
+	return hex.EncodeToString(id)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, errorBody{Error: err.Error()})
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"nimgame/pkg/nim"
+)
+
+// do sends body as JSON and decodes the reply into out, returning the status.
+func do(t *testing.T, method, url string, body, out interface{}) int {
+	t.Helper()
+	var buf bytes.Buffer
+	if body != nil {
+		json.NewEncoder(&buf).Encode(body)
+	}
+	req, err := http.NewRequest(method, url, &buf)
+	if err != nil {
+		t.Fatalf("building request: %v\n", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s: %v\n", method, url, err)
+	}
+	defer resp.Body.Close()
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		t.Fatalf("decoding reply to %s %s: %v\n", method, url, err)
+	}
+	return resp.StatusCode
+}
+
+func toBoard(ints []int) []uint8 {
+	board := make([]uint8, len(ints))
+	for i, coins := range ints {
+		board[i] = uint8(coins)
+	}
+	return board
+}
+
+// TestPlayGame plays optimally against the easy server until it wins.
+func TestPlayGame(t *testing.T) {
+	ts := httptest.NewServer(newAPI().handler())
+	defer ts.Close()
+
+	var state gameState
+	seed := int8(4)
+	if status := do(t, "POST", ts.URL+"/game", newGameBody{Seed: &seed}, &state); status != http.StatusOK {
+		t.Fatalf("creating a game returned %d\n", status)
+	}
+	if state.GameID == "" || !bytes.Equal(toBoard(state.Board), nim.GenerateBoard(4)) || state.Turn != "player" {
+		t.Fatalf("unexpected new game %+v\n", state)
+	}
+	gameURL := ts.URL + "/game/" + state.GameID
+
+	for moves := 0; state.Winner == ""; moves++ {
+		if moves > 1000 {
+			t.Fatalf("game never finished: %+v\n", state)
+		}
+		row, count := nim.Optimal{}.Move(toBoard(state.Board))
+		if status := do(t, "POST", gameURL+"/move", moveBody{Row: row, Count: int(count)}, &state); status != http.StatusOK {
+			t.Fatalf("move %d,%d returned %d\n", row, count, status)
+		}
+	}
+	if state.Winner != "player" {
+		t.Errorf("expected the optimal player to beat the easy server, got %+v\n", state)
+	}
+
+	var got gameState
+	if status := do(t, "GET", gameURL, nil, &got); status != http.StatusOK {
+		t.Fatalf("getting the game returned %d\n", status)
+	}
+	if got.Winner != "player" || got.Turn != "" || got.Moves != state.Moves {
+		t.Errorf("expected the finished game, got %+v\n", got)
+	}
+
+	var reply errorBody
+	if status := do(t, "POST", gameURL+"/move", moveBody{Row: 0, Count: 1}, &reply); status != http.StatusConflict {
+		t.Errorf("expected a move after the game ended to return %d, got %d\n", http.StatusConflict, status)
+	}
+}
+
+func TestAPIErrors(t *testing.T) {
+	ts := httptest.NewServer(newAPI().handler())
+	defer ts.Close()
+
+	var state gameState
+	seed := int8(4)
+	do(t, "POST", ts.URL+"/game", newGameBody{Seed: &seed}, &state)
+	gameURL := ts.URL + "/game/" + state.GameID
+
+	tests := []struct {
+		name   string
+		method string
+		url    string
+		body   interface{}
+		status int
+	}{
+		{"unknown game", "GET", ts.URL + "/game/nope", nil, http.StatusNotFound},
+		{"move in unknown game", "POST", ts.URL + "/game/nope/move", moveBody{Row: 0, Count: 1}, http.StatusNotFound},
+		{"row off the board", "POST", gameURL + "/move", moveBody{Row: len(state.Board), Count: 1}, http.StatusBadRequest},
+		{"take nothing", "POST", gameURL + "/move", moveBody{Row: 0, Count: 0}, http.StatusBadRequest},
+		{"take too many", "POST", gameURL + "/move", moveBody{Row: 0, Count: 256}, http.StatusBadRequest},
+	}
+	for _, test := range tests {
+		var reply errorBody
+		if status := do(t, test.method, test.url, test.body, &reply); status != test.status || reply.Error == "" {
+			t.Errorf("%s: expected %d with an error, got %d %+v\n", test.name, test.status, status, reply)
+		}
+	}
+}
+
+// TestEvictIdleGames leaves one game idle past gameTTL while another is
+// played, then starts a third, which drops only the idle one.
+func TestEvictIdleGames(t *testing.T) {
+	a := newAPI()
+	now := time.Unix(0, 0)
+	a.now = func() time.Time { return now }
+	ts := httptest.NewServer(a.handler())
+	defer ts.Close()
+
+	var idle, played gameState
+	seed := int8(4)
+	do(t, "POST", ts.URL+"/game", newGameBody{Seed: &seed}, &idle)
+	do(t, "POST", ts.URL+"/game", newGameBody{Seed: &seed}, &played)
+	now = now.Add(gameTTL / 2)
+	row, count := nim.Optimal{}.Move(toBoard(played.Board))
+	do(t, "POST", ts.URL+"/game/"+played.GameID+"/move", moveBody{Row: row, Count: int(count)}, &played)
+	now = now.Add(gameTTL/2 + time.Second)
+	do(t, "POST", ts.URL+"/game", newGameBody{Seed: &seed}, &gameState{})
+
+	var reply errorBody
+	if status := do(t, "GET", ts.URL+"/game/"+idle.GameID, nil, &reply); status != http.StatusNotFound {
+		t.Errorf("idle game answered %d, expected it dropped\n", status)
+	}
+	if status := do(t, "GET", ts.URL+"/game/"+played.GameID, nil, &gameState{}); status != http.StatusOK {
+		t.Errorf("game moved on within gameTTL answered %d\n", status)
+	}
+	if len(a.games) != 2 {
+		t.Errorf("%d games kept, expected 2\n", len(a.games))
+	}
+}
+
+func TestWatchGame(t *testing.T) {
+	ts := httptest.NewServer(newAPI().handler())
+	defer ts.Close()
+
+	var state gameState
+	seed := int8(4)
+	do(t, "POST", ts.URL+"/game", newGameBody{Seed: &seed}, &state)
+	gameURL := ts.URL + "/game/" + state.GameID
+
+	resp, err := http.Get(gameURL + "/watch")
+	if err != nil {
+		t.Fatalf("watching the game: %v\n", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("expected an event stream, got %d %v\n", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+
+	// the player's move and the server's reply
+	row, count := nim.Optimal{}.Move(toBoard(state.Board))
+	var after gameState
+	do(t, "POST", gameURL+"/move", moveBody{Row: row, Count: int(count)}, &after)
+	board := toBoard(state.Board)
+	board[row] -= count
+	want := []moveEvent{
+		{Player: "player", MoveRow: row, MoveCount: int(count), GameState: toInts(board)},
+		{Player: "server", MoveRow: after.ServerMove.Row, MoveCount: after.ServerMove.Count, GameState: after.Board},
+	}
+
+	lines := bufio.NewScanner(resp.Body)
+	for i, w := range want {
+		var data string
+		for lines.Scan() && lines.Text() != "" {
+			data = strings.TrimPrefix(lines.Text(), "data: ")
+		}
+		var got moveEvent
+		if err := json.Unmarshal([]byte(data), &got); err != nil {
+			t.Fatalf("event %d: decoding %q: %v\n", i, data, err)
+		}
+		if !reflect.DeepEqual(got, w) {
+			t.Errorf("event %d: expected %+v, got %+v\n", i, w, got)
+		}
+	}
+}
+
+func toInts(board []uint8) []int {
+	ints := make([]int, len(board))
+	for i, coins := range board {
+		ints[i] = int(coins)
+	}
+	return ints
+}
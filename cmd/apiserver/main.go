@@ -0,0 +1,56 @@
+// Command apiserver serves games of nim over HTTP. The player always moves
+// first and the server replies within the same request:
+//
+//	POST /game               {"seed": n} (optional) -> {game_id, board, ...}
+//	POST /game/{id}/move     {"row": r, "count": c} -> new board and the server's move
+//	GET  /game/{id}          -> current board, whose turn it is and the winner
+//	GET  /game/{id}/watch    -> server-sent events, one per move by either side
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// shutdownTimeout bounds how long in-flight requests get to finish.
+const shutdownTimeout = 5 * time.Second
+
+func main() {
+	if err := run(os.Args[1:]); err != nil && !errors.Is(err, flag.ErrHelp) {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string) error {
+	fs := flag.NewFlagSet("apiserver", flag.ContinueOnError)
+	listen := fs.String("listen", "127.0.0.1:41610", "HTTP `address` to serve the API on")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	srv := &http.Server{Addr: *listen, Handler: newAPI().handler()}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	errc := make(chan error, 1)
+	go func() { errc <- srv.ListenAndServe() }()
+	slog.Info("serving API", "addr", *listen)
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+	slog.Info("shutting down")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	return srv.Shutdown(shutdownCtx)
+}
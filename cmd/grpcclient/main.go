@@ -0,0 +1,71 @@
+// Command grpcclient plays a game of nim against a server's NimGame gRPC
+// service, which listens on its GRPCAddress, moving as nim.Optimal does and
+// printing every move.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"nimgame/pkg/nim"
+	"nimgame/pkg/nimpb"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+func main() {
+	if err := run(os.Args[1:]); err != nil && !errors.Is(err, flag.ErrHelp) {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string) error {
+	fs := flag.NewFlagSet("grpcclient", flag.ContinueOnError)
+	server := fs.String("server", "127.0.0.1:41620", "gRPC `address` of the server")
+	seed := fs.Int("seed", 4, "`seed` of the board to play")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	conn, err := grpc.NewClient(*server, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return fmt.Errorf("dialing %v: %w", *server, err)
+	}
+	defer conn.Close()
+	client := nimpb.NewNimGameClient(conn)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	state, err := client.StartGame(ctx, &nimpb.StartGameRequest{Seed: int32(*seed)})
+	if err != nil {
+		return fmt.Errorf("starting game: %w", err)
+	}
+	fmt.Printf("game %v: board %v\n", state.GameId, state.GameState)
+	for state.Winner == "" {
+		board := append([]uint8(nil), state.GameState...)
+		row, count := nim.Optimal{}.Move(board)
+		board[row] -= count
+		fmt.Printf("client takes %v from row %v: %v\n", count, row, board)
+		state, err = client.MakeMove(ctx, &nimpb.MoveRequest{
+			GameId:    state.GameId,
+			GameState: board,
+			MoveRow:   int32(row),
+			MoveCount: int32(count),
+		})
+		if err != nil {
+			return fmt.Errorf("moving: %w", err)
+		}
+		if state.MoveRow >= 0 {
+			fmt.Printf("server takes %v from row %v: %v\n", state.MoveCount, state.MoveRow, state.GameState)
+		}
+	}
+	fmt.Printf("%v wins\n", state.Winner)
+	return nil
+}
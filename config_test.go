@@ -0,0 +1,50 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/DistributedClocks/tracing"
+
+	"nimgame/pkg/client"
+	"nimgame/pkg/configfile"
+	"nimgame/pkg/nimserver"
+)
+
+// TestShippedConfigs checks every config in config/ reads and validates as
+// the binary using it would, so they start on the repository's defaults.
+func TestShippedConfigs(t *testing.T) {
+	validate := map[string]func(path string) error{
+		"server_config.json": func(path string) error {
+			config, err := nimserver.ReadConfig(path)
+			if err != nil {
+				return err
+			}
+			return nimserver.ValidateConfig(config)
+		},
+		"client_config.json": func(path string) error {
+			config, err := client.ReadConfig(path)
+			if err != nil {
+				return err
+			}
+			return client.ValidateConfig(config)
+		},
+		"tracing_server_config.json": func(path string) error {
+			return configfile.Read(path, &tracing.TracingServerConfig{})
+		},
+	}
+	paths, err := filepath.Glob(filepath.Join("config", "*"))
+	if err != nil || len(paths) == 0 {
+		t.Fatalf("no configs in config/: %v\n", err)
+	}
+	for _, path := range paths {
+		check, ok := validate[filepath.Base(path)]
+		if !ok {
+			t.Errorf("%v: no check for this config; add one\n", path)
+			continue
+		}
+		if err := check(path); err != nil {
+			t.Errorf("%v: %v\n", path, err)
+		}
+	}
+}
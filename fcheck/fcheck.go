@@ -0,0 +1,244 @@
+// Package fcheck detects failure of a remote node with UDP heartbeats.
+//
+// The monitored node runs a Responder, which acks every heartbeat it
+// receives. The monitoring node runs a Monitor, which sends heartbeats at an
+// interval that tracks the measured round-trip time and reports a failure
+// once LostMsgsThresh heartbeats in a row go unacknowledged.
+package fcheck
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"math/rand"
+	"net"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"nimgame/pkg/udpnet"
+)
+
+// Heartbeat sent by the Monitor.
+type HBeatMessage struct {
+	EpochNonce uint64 // identifies this Monitor instance
+	SeqNum     uint64 // identifies this heartbeat
+}
+
+// Ack sent by the Responder, echoing the heartbeat it answers.
+type AckMessage struct {
+	HBEatEpochNonce uint64
+	HBEatSeqNum     uint64
+}
+
+// FailureDetected is delivered once the remote node is deemed failed.
+type FailureDetected struct {
+	UDPIpPort string    // the responder that failed
+	Timestamp time.Time // when the failure was detected
+}
+
+const (
+	defaultInitialRTT  = 3 * time.Second
+	defaultMinInterval = 100 * time.Millisecond
+)
+
+// Config parameterises a Monitor.
+type Config struct {
+	LocalAddr      string // heartbeats are sent from here; empty picks a free port
+	RemoteAddr     string // the Responder to monitor
+	LostMsgsThresh uint8  // consecutive lost heartbeats that mean failure
+	EpochNonce     uint64 // zero picks a random nonce
+
+	InitialRTT  time.Duration // RTT estimate before any ack arrives, default 3s
+	MinInterval time.Duration // floor on the heartbeat interval, default 100ms
+}
+
+// Monitor sends heartbeats to a Responder until it fails or Stop is called.
+type Monitor struct {
+	config Config
+	conn   *net.UDPConn
+	notify chan FailureDetected
+	done   chan struct{}
+	rtt    atomic.Int64
+}
+
+// StartMonitor starts monitoring config.RemoteAddr.
+func StartMonitor(config Config) (*Monitor, error) {
+	if config.LostMsgsThresh == 0 {
+		return nil, errors.New("fcheck: LostMsgsThresh must be positive")
+	}
+	if config.EpochNonce == 0 {
+		config.EpochNonce = rand.Uint64()
+	}
+	if config.InitialRTT <= 0 {
+		config.InitialRTT = defaultInitialRTT
+	}
+	if config.MinInterval <= 0 {
+		config.MinInterval = defaultMinInterval
+	}
+
+	network := udpnet.Network(config.RemoteAddr, false)
+	var laddr *net.UDPAddr
+	if config.LocalAddr != "" {
+		var err error
+		if laddr, err = net.ResolveUDPAddr(network, config.LocalAddr); err != nil {
+			return nil, err
+		}
+	}
+	raddr, err := net.ResolveUDPAddr(network, config.RemoteAddr)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := net.DialUDP(network, laddr, raddr)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &Monitor{
+		config: config,
+		conn:   conn,
+		notify: make(chan FailureDetected, 1),
+		done:   make(chan struct{}),
+	}
+	m.rtt.Store(int64(config.InitialRTT))
+	go m.run()
+	return m, nil
+}
+
+// Failed receives a FailureDetected if the remote node fails, and is closed
+// once the monitor stops either way.
+func (m *Monitor) Failed() <-chan FailureDetected {
+	return m.notify
+}
+
+// RTT returns the current round-trip time estimate.
+func (m *Monitor) RTT() time.Duration {
+	return time.Duration(m.rtt.Load())
+}
+
+// Stop ends monitoring and releases the local port.
+func (m *Monitor) Stop() {
+	m.conn.Close()
+	<-m.done
+}
+
+func (m *Monitor) run() {
+	defer close(m.done)
+	defer close(m.notify)
+
+	sent := make(map[uint64]time.Time) // unacknowledged heartbeats
+	buf := make([]byte, 1024)
+	var lost uint8
+	for seq := uint64(1); ; seq++ {
+		interval := m.RTT()
+		if interval < m.config.MinInterval {
+			interval = m.config.MinInterval
+		}
+		start := time.Now()
+		sent[seq] = start
+		if _, err := m.conn.Write(encode(HBeatMessage{m.config.EpochNonce, seq})); errors.Is(err, net.ErrClosed) {
+			return
+		}
+
+		acked := false
+		m.conn.SetReadDeadline(start.Add(interval))
+		for {
+			n, err := m.conn.Read(buf)
+			if errors.Is(err, net.ErrClosed) {
+				return
+			} else if errors.Is(err, os.ErrDeadlineExceeded) {
+				break
+			} else if err != nil {
+				// e.g. ICMP port unreachable; count it as a lost heartbeat
+				continue
+			}
+			var ack AckMessage
+			if decode(buf[:n], &ack) != nil || ack.HBEatEpochNonce != m.config.EpochNonce {
+				continue
+			}
+			if sentAt, ok := sent[ack.HBEatSeqNum]; ok {
+				m.rtt.Store(int64((m.RTT() + time.Since(sentAt)) / 2))
+				delete(sent, ack.HBEatSeqNum)
+				acked = true
+			}
+		}
+
+		if acked {
+			lost = 0
+			sent = make(map[uint64]time.Time)
+			continue
+		}
+		lost++
+		if lost >= m.config.LostMsgsThresh {
+			m.notify <- FailureDetected{UDPIpPort: m.config.RemoteAddr, Timestamp: time.Now()}
+			m.conn.Close()
+			return
+		}
+	}
+}
+
+// Responder acks heartbeats arriving on its address.
+type Responder struct {
+	conn   *net.UDPConn
+	paused atomic.Bool
+	done   chan struct{}
+}
+
+// StartResponder answers heartbeats on addr until Close is called.
+func StartResponder(addr string) (*Responder, error) {
+	network := udpnet.Network(addr, false)
+	laddr, err := net.ResolveUDPAddr(network, addr)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := net.ListenUDP(network, laddr)
+	if err != nil {
+		return nil, err
+	}
+	r := &Responder{conn: conn, done: make(chan struct{})}
+	go r.run()
+	return r, nil
+}
+
+// Addr returns the address heartbeats should be sent to.
+func (r *Responder) Addr() string {
+	return r.conn.LocalAddr().String()
+}
+
+// SetPaused stops (or resumes) acking, so that monitors see a failure.
+func (r *Responder) SetPaused(paused bool) {
+	r.paused.Store(paused)
+}
+
+func (r *Responder) Close() {
+	r.conn.Close()
+	<-r.done
+}
+
+func (r *Responder) run() {
+	defer close(r.done)
+	buf := make([]byte, 1024)
+	for {
+		n, raddr, err := r.conn.ReadFromUDP(buf)
+		if errors.Is(err, net.ErrClosed) {
+			return
+		} else if err != nil {
+			continue
+		}
+		var hbeat HBeatMessage
+		if r.paused.Load() || decode(buf[:n], &hbeat) != nil {
+			continue
+		}
+		r.conn.WriteToUDP(encode(AckMessage{hbeat.EpochNonce, hbeat.SeqNum}), raddr)
+	}
+}
+
+func encode(msg interface{}) []byte {
+	var buf bytes.Buffer
+	gob.NewEncoder(&buf).Encode(msg)
+	return buf.Bytes()
+}
+
+func decode(packet []byte, msg interface{}) error {
+	return gob.NewDecoder(bytes.NewBuffer(packet)).Decode(msg)
+}
@@ -0,0 +1,86 @@
+package fcheck
+
+import (
+	"testing"
+	"time"
+)
+
+func startPair(t *testing.T, thresh uint8) (*Responder, *Monitor) {
+	responder, err := StartResponder("127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("starting responder: %v\n", err)
+	}
+	t.Cleanup(responder.Close)
+
+	monitor, err := StartMonitor(Config{
+		LocalAddr:      "127.0.0.1:0",
+		RemoteAddr:     responder.Addr(),
+		LostMsgsThresh: thresh,
+		InitialRTT:     200 * time.Millisecond,
+		MinInterval:    10 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("starting monitor: %v\n", err)
+	}
+	t.Cleanup(monitor.Stop)
+	return responder, monitor
+}
+
+func TestMonitorHealthyResponder(t *testing.T) {
+	_, monitor := startPair(t, 3)
+
+	select {
+	case f := <-monitor.Failed():
+		t.Fatalf("healthy responder reported failed: %v\n", f)
+	case <-time.After(500 * time.Millisecond):
+	}
+	// the interval adapts down from the initial estimate once acks arrive
+	if rtt := monitor.RTT(); rtt >= 200*time.Millisecond {
+		t.Errorf("RTT estimate should have adapted, still %v\n", rtt)
+	}
+}
+
+func TestMonitorDetectsPausedResponder(t *testing.T) {
+	responder, monitor := startPair(t, 3)
+	time.Sleep(300 * time.Millisecond)
+
+	responder.SetPaused(true)
+	paused := time.Now()
+	select {
+	case f := <-monitor.Failed():
+		if f.UDPIpPort != responder.Addr() {
+			t.Errorf("failure reported for %v, expected %v\n", f.UDPIpPort, responder.Addr())
+		}
+		if elapsed := f.Timestamp.Sub(paused); elapsed > time.Second {
+			t.Errorf("detection took %v\n", elapsed)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatalf("paused responder was never detected\n")
+	}
+}
+
+func TestMonitorNoResponder(t *testing.T) {
+	responder, err := StartResponder("127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("starting responder: %v\n", err)
+	}
+	addr := responder.Addr()
+	responder.Close()
+
+	monitor, err := StartMonitor(Config{RemoteAddr: addr, LostMsgsThresh: 2, InitialRTT: 20 * time.Millisecond, MinInterval: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("starting monitor: %v\n", err)
+	}
+	defer monitor.Stop()
+	select {
+	case <-monitor.Failed():
+	case <-time.After(2 * time.Second):
+		t.Fatalf("missing responder was never detected\n")
+	}
+}
+
+func TestMonitorRejectsZeroThreshold(t *testing.T) {
+	if _, err := StartMonitor(Config{RemoteAddr: "127.0.0.1:1"}); err == nil {
+		t.Errorf("expected an error for a zero LostMsgsThresh\n")
+	}
+}
@@ -0,0 +1,68 @@
+// Package wiredump reads and writes the dumps of canonical packets the
+// tests of the nim binaries check the wire protocol against.
+//
+// A dump is the packet in hex, 64 digits to a line, after comment lines
+// starting with # that describe the message it encodes.
+package wiredump
+
+import (
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Read returns the packet dumped at path.
+func Read(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading wire dump: %w", err)
+	}
+	var digits strings.Builder
+	for _, line := range strings.Split(string(data), "\n") {
+		if !strings.HasPrefix(line, "#") {
+			digits.WriteString(strings.TrimSpace(line))
+		}
+	}
+	packet, err := hex.DecodeString(digits.String())
+	if err != nil {
+		return nil, fmt.Errorf("decoding wire dump %v: %w", path, err)
+	}
+	return packet, nil
+}
+
+// Comment returns the comment the packet dumped at path is under, without
+// its #, its lines joined by newlines.
+func Comment(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("reading wire dump: %w", err)
+	}
+	var comment []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if text, ok := strings.CutPrefix(line, "#"); ok {
+			comment = append(comment, strings.TrimPrefix(text, " "))
+		}
+	}
+	return strings.Join(comment, "\n"), nil
+}
+
+// Write dumps packet to path under a comment, making its directory if
+// need be.
+func Write(path, comment string, packet []byte) error {
+	var dump strings.Builder
+	fmt.Fprintf(&dump, "# %v\n", comment)
+	for line := hex.EncodeToString(packet); line != ""; {
+		n := min(len(line), 64)
+		dump.WriteString(line[:n] + "\n")
+		line = line[n:]
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("making wire dump directory: %w", err)
+	}
+	if err := os.WriteFile(path, []byte(dump.String()), 0644); err != nil {
+		return fmt.Errorf("writing wire dump: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,32 @@
+package wiredump
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWriteRead(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "v1", "packet.hex")
+	packet := bytes.Repeat([]byte{0xff, 0x01, 0x80}, 30) // longer than a line
+	if err := Write(path, "{MoveRow:-1}", packet); err != nil {
+		t.Fatalf("writing %v: %v\n", path, err)
+	}
+	got, err := Read(path)
+	if err != nil {
+		t.Fatalf("reading %v: %v\n", path, err)
+	}
+	if !bytes.Equal(got, packet) {
+		t.Errorf("read back %x, expected %x\n", got, packet)
+	}
+	if comment, err := Comment(path); err != nil || comment != "{MoveRow:-1}" {
+		t.Errorf("read back comment %q (%v), expected {MoveRow:-1}\n", comment, err)
+	}
+}
+
+func TestReadMissing(t *testing.T) {
+	if _, err := Read(filepath.Join(t.TempDir(), "missing.hex")); err == nil || !strings.Contains(err.Error(), "reading wire dump") {
+		t.Errorf("reading a missing dump returned %v, expected a read error\n", err)
+	}
+}
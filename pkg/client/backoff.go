@@ -0,0 +1,81 @@
+package client
+
+import (
+	"math"
+	"math/rand"
+	"time"
+)
+
+// Retransmission defaults, used when the matching ClientConfig field is zero.
+const (
+	defaultRetryBase       = 1 * time.Second
+	defaultRetryMultiplier = 2.0
+	defaultRetryCap        = 8 * time.Second
+)
+
+// clock is the time source for read deadlines, so tests can pin "now".
+type clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// Backoff produces retransmission timeouts growing from Base by Multiplier
+// per attempt, capped at Cap. Each timeout is jittered into [d/2, d] so that
+// many clients started together do not retransmit in lockstep.
+type Backoff struct {
+	Base       time.Duration
+	Multiplier float64
+	Cap        time.Duration
+
+	attempt int
+	rng     *rand.Rand
+}
+
+func NewBackoff(config *ClientConfig, rng *rand.Rand) *Backoff {
+	b := &Backoff{
+		Base:       time.Duration(config.RetryBaseMs) * time.Millisecond,
+		Multiplier: config.RetryMultiplier,
+		Cap:        time.Duration(config.RetryCapMs) * time.Millisecond,
+		rng:        rng,
+	}
+	if b.Base <= 0 {
+		b.Base = defaultRetryBase
+	}
+	if b.Multiplier < 1 {
+		b.Multiplier = defaultRetryMultiplier
+	}
+	if b.Cap <= 0 {
+		b.Cap = defaultRetryCap
+	}
+	if b.Cap < b.Base {
+		b.Cap = b.Base
+	}
+	return b
+}
+
+// Next returns how long to wait for a reply to the next transmission.
+func (b *Backoff) Next() time.Duration {
+	d := float64(b.Base) * math.Pow(b.Multiplier, float64(b.attempt))
+	if d > float64(b.Cap) {
+		d = float64(b.Cap)
+	}
+	b.attempt++
+
+	half := int64(d) / 2
+	return time.Duration(half + b.rng.Int63n(int64(d)-half+1))
+}
+
+// Attempt returns the number of timeouts handed out since the last Reset.
+func (b *Backoff) Attempt() int {
+	return b.attempt
+}
+
+// Reset starts the schedule over from Base.
+func (b *Backoff) Reset() {
+	b.attempt = 0
+}
@@ -0,0 +1,108 @@
+package client
+
+import (
+	"context"
+	"math/rand"
+	"net"
+	"os"
+	"testing"
+	"time"
+)
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+// fakeConn times out the first `timeouts` reads, recording the deadline of
+// each one, and then answers with reply.
+type fakeConn struct {
+	net.Conn
+	timeouts  int
+	reply     StateMoveMessage
+	deadlines []time.Time
+	writes    int
+}
+
+func (c *fakeConn) Write(b []byte) (int, error) {
+	c.writes++
+	return len(b), nil
+}
+
+func (c *fakeConn) Close() error {
+	return nil
+}
+
+func (c *fakeConn) SetReadDeadline(t time.Time) error {
+	c.deadlines = append(c.deadlines, t)
+	return nil
+}
+
+func (c *fakeConn) Read(b []byte) (int, error) {
+	if c.timeouts > 0 {
+		c.timeouts--
+		return 0, os.ErrDeadlineExceeded
+	}
+	return copy(b, encode(&c.reply)), nil
+}
+
+func checkWait(t *testing.T, attempt int, wait, ceiling time.Duration) {
+	if wait < ceiling/2 || wait > ceiling {
+		t.Errorf("attempt %d: wait %v outside [%v, %v]\n", attempt, wait, ceiling/2, ceiling)
+	}
+}
+
+func TestBackoffSchedule(t *testing.T) {
+	config := &ClientConfig{RetryBaseMs: 100, RetryMultiplier: 2, RetryCapMs: 1000}
+	b := NewBackoff(config, rand.New(rand.NewSource(1)))
+
+	ceilings := []time.Duration{100, 200, 400, 800, 1000, 1000, 1000}
+	for i, c := range ceilings {
+		checkWait(t, i+1, b.Next(), c*time.Millisecond)
+	}
+
+	b.Reset()
+	checkWait(t, 1, b.Next(), 100*time.Millisecond)
+}
+
+func TestBackoffDefaults(t *testing.T) {
+	b := NewBackoff(&ClientConfig{}, rand.New(rand.NewSource(1)))
+	if b.Base != defaultRetryBase || b.Multiplier != defaultRetryMultiplier || b.Cap != defaultRetryCap {
+		t.Errorf("unexpected defaults: %+v\n", b)
+	}
+}
+
+func TestSendAndAwaitRetrySchedule(t *testing.T) {
+	clk := &fakeClock{now: time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)}
+	conn := &fakeConn{timeouts: 4, reply: StateMoveMessage{GameState: []uint8{1, 2, 3}, MoveRow: -1, MoveCount: 7}}
+	config := &ClientConfig{RetryBaseMs: 50, RetryMultiplier: 3, RetryCapMs: 1000}
+	retry := NewBackoff(config, rand.New(rand.NewSource(42)))
+	sess := &Session{config: config, conn: conn, trace: nopRecorder{}, retry: retry, clk: clk}
+
+	var reply StateMoveMessage
+	send := StateMoveMessage{GameState: nil, MoveRow: -1, MoveCount: 7}
+	accept := func(*StateMoveMessage) (bool, error) { return true, nil }
+	if err := sess.sendAndAwait(context.Background(), &send, &reply, accept); err != nil {
+		t.Fatalf("unexpected error: %v\n", err)
+	}
+
+	if conn.writes != 5 {
+		t.Errorf("expected 5 transmissions, got %d\n", conn.writes)
+	}
+	ceilings := []time.Duration{50, 150, 450, 1000, 1000}
+	if len(conn.deadlines) != len(ceilings) {
+		t.Fatalf("expected %d reads, got %d\n", len(ceilings), len(conn.deadlines))
+	}
+	for i, c := range ceilings {
+		checkWait(t, i+1, conn.deadlines[i].Sub(clk.now), c*time.Millisecond)
+	}
+	if retry.Attempt() != 0 {
+		t.Errorf("backoff should reset after a reply, attempt = %d\n", retry.Attempt())
+	}
+	if len(reply.GameState) != 3 || reply.MoveCount != 7 {
+		t.Errorf("unexpected reply: %v\n", reply)
+	}
+}
@@ -0,0 +1,101 @@
+package client
+
+import (
+	"log/slog"
+	"time"
+)
+
+// Circuit breaker states.
+const (
+	circuitClosed   = "closed"
+	circuitOpen     = "open"
+	circuitHalfOpen = "half-open"
+)
+
+// CircuitBreaker stops the client hammering a server that keeps failing.
+// After Threshold consecutive failures (timeouts or rejected replies) it
+// opens and no moves are sent for BackoffPeriod, though late replies are
+// still read. It then half-opens to let one probe through, closing again
+// if the probe is answered and reopening if not.
+//
+// A nil *CircuitBreaker is always closed.
+type CircuitBreaker struct {
+	Threshold     int
+	BackoffPeriod time.Duration
+	State         string
+
+	failures int
+	openedAt time.Time
+	log      *slog.Logger // nil logs to the default logger
+}
+
+// NewCircuitBreaker returns the breaker config asks for, or nil if
+// CircuitBreakerThreshold is zero.
+func NewCircuitBreaker(config *ClientConfig) *CircuitBreaker {
+	if config.CircuitBreakerThreshold <= 0 {
+		return nil
+	}
+	return &CircuitBreaker{
+		Threshold:     config.CircuitBreakerThreshold,
+		BackoffPeriod: time.Duration(config.CircuitBreakerBackoffMs) * time.Millisecond,
+		State:         circuitClosed,
+	}
+}
+
+// Allow reports whether a move may be sent at now, half-opening the breaker
+// once BackoffPeriod has passed.
+func (b *CircuitBreaker) Allow(now time.Time) bool {
+	if b == nil {
+		return true
+	}
+	if b.State == circuitOpen {
+		if now.Before(b.ReopensAt()) {
+			return false
+		}
+		b.setState(circuitHalfOpen)
+	}
+	return true
+}
+
+// ReopensAt is when an open breaker will let a probe through.
+func (b *CircuitBreaker) ReopensAt() time.Time {
+	return b.openedAt.Add(b.BackoffPeriod)
+}
+
+// Success records an accepted reply, closing the breaker.
+func (b *CircuitBreaker) Success() {
+	if b == nil {
+		return
+	}
+	b.failures = 0
+	b.setState(circuitClosed)
+}
+
+// Failure records a send that went unanswered or drew a rejected reply,
+// noticed at now.
+func (b *CircuitBreaker) Failure(now time.Time) {
+	if b == nil {
+		return
+	}
+	b.failures++
+	if b.State == circuitHalfOpen || b.failures >= b.Threshold {
+		b.openedAt = now
+		b.setState(circuitOpen)
+	}
+}
+
+// Reset closes the breaker and forgets past failures, for a new server.
+func (b *CircuitBreaker) Reset() {
+	if b == nil {
+		return
+	}
+	b.failures = 0
+	b.State = circuitClosed
+}
+
+func (b *CircuitBreaker) setState(state string) {
+	if b.State != state {
+		logOrDefault(b.log).Info("circuit breaker "+state, "failures", b.failures)
+		b.State = state
+	}
+}
@@ -0,0 +1,99 @@
+package client
+
+import (
+	"context"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreakerStates(t *testing.T) {
+	b := NewCircuitBreaker(&ClientConfig{CircuitBreakerThreshold: 3, CircuitBreakerBackoffMs: 1000})
+	now := time.Unix(0, 0)
+	for i := 0; i < 2; i++ {
+		b.Failure(now)
+	}
+	b.Success()
+	for i := 0; i < 2; i++ {
+		b.Failure(now)
+	}
+	if b.State != circuitClosed || !b.Allow(now) {
+		t.Fatalf("breaker opened before 3 consecutive failures: %v\n", b.State)
+	}
+	b.Failure(now)
+	if b.State != circuitOpen || b.Allow(now.Add(999*time.Millisecond)) {
+		t.Fatalf("breaker should be open for 1s after 3 failures: %v\n", b.State)
+	}
+	if !b.Allow(now.Add(time.Second)) || b.State != circuitHalfOpen {
+		t.Fatalf("breaker should half-open after 1s: %v\n", b.State)
+	}
+	// a failed probe reopens it straight away
+	b.Failure(now.Add(2 * time.Second))
+	if b.State != circuitOpen || b.Allow(now.Add(2500*time.Millisecond)) {
+		t.Fatalf("failed probe should reopen the breaker: %v\n", b.State)
+	}
+	if !b.Allow(now.Add(3*time.Second)) || b.State != circuitHalfOpen {
+		t.Fatalf("breaker should half-open again: %v\n", b.State)
+	}
+	b.Success()
+	if b.State != circuitClosed {
+		t.Errorf("answered probe should close the breaker: %v\n", b.State)
+	}
+
+	if NewCircuitBreaker(&ClientConfig{}) != nil {
+		t.Errorf("zero threshold should disable the breaker\n")
+	}
+}
+
+// waitingConn moves clk to the read deadline whenever a read times out, as
+// if it had waited for it.
+type waitingConn struct {
+	*fakeConn
+	clk *fakeClock
+}
+
+func (c waitingConn) Read(b []byte) (int, error) {
+	n, err := c.fakeConn.Read(b)
+	if err != nil {
+		c.clk.now = c.deadlines[len(c.deadlines)-1]
+	}
+	return n, err
+}
+
+func TestCircuitBreakerStopsSends(t *testing.T) {
+	clk := &fakeClock{now: time.Unix(0, 0)}
+	// five timeouts open the breaker, the read while it's open times out
+	// too, and the probe after it is answered
+	conn := &fakeConn{timeouts: 6, reply: StateMoveMessage{GameState: []uint8{1, 2, 3}, MoveRow: -1, MoveCount: 7}}
+	config := &ClientConfig{RetryBaseMs: 100, RetryCapMs: 100, CircuitBreakerThreshold: 5, CircuitBreakerBackoffMs: 5000}
+	breaker := NewCircuitBreaker(config)
+	sess := &Session{
+		config:  config,
+		conn:    waitingConn{conn, clk},
+		trace:   nopRecorder{},
+		retry:   NewBackoff(config, rand.New(rand.NewSource(1))),
+		breaker: breaker,
+		clk:     clk,
+	}
+
+	var reply StateMoveMessage
+	send := StateMoveMessage{GameState: nil, MoveRow: -1, MoveCount: 7}
+	if err := sess.sendAndAwait(context.Background(), &send, &reply, func(*StateMoveMessage) (bool, error) { return true, nil }); err != nil {
+		t.Fatalf("unexpected error: %v\n", err)
+	}
+
+	if len(conn.deadlines) != 7 {
+		t.Fatalf("expected 7 reads, got %d\n", len(conn.deadlines))
+	}
+	opened := conn.deadlines[4]
+	if held := conn.deadlines[5]; !held.Equal(opened.Add(5 * time.Second)) {
+		t.Errorf("read while open should wait out the 5s backoff, waited until %v after opening\n", held.Sub(opened))
+	}
+	// nothing is sent while the breaker is open, then one probe
+	if conn.writes != 6 {
+		t.Errorf("expected 5 sends before opening and 1 probe, got %d\n", conn.writes)
+	}
+	if breaker.State != circuitClosed {
+		t.Errorf("answered probe should close the breaker, got %v\n", breaker.State)
+	}
+}
@@ -0,0 +1,434 @@
+// Package client plays games against the nim servers. It is the engine
+// of the client binary and can be embedded in other programs, such as bot
+// tournament runners:
+//
+//	sess, err := client.NewSession(config, nim.Optimal{}, client.WithSeed(7))
+//	if err != nil {
+//		return err
+//	}
+//	defer sess.Close()
+//	result, err := sess.Play(ctx)
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"math"
+	"math/rand"
+	"net"
+	"time"
+
+	"nimgame/pkg/nim"
+	"nimgame/pkg/nimerr"
+
+	"github.com/DistributedClocks/tracing"
+)
+
+/* Config struct */
+
+type ClientConfig struct {
+	// the local UDP address to play from; empty, or port 0, lets the OS
+	// pick a free port, so that clients can run side by side
+	ClientAddress      string
+	NimServerAddresses []string `env:"SERVER_ADDRESS"` // tried in order, failing over to the next
+	NimServerAddress   string   // deprecated single-server form of NimServerAddresses
+	// addresses in IPv6 notation, "[::1]:41600" say, are played over IPv6;
+	// IPv6 does so for host names too, rather than IPv4
+	IPv6 bool
+
+	// moves are traced to TracingServerAddress as TracingIdentity when set;
+	// empty disables tracing
+	TracingServerAddress string
+	Secret               []byte
+	TracingIdentity      string
+	LogLevel             string
+
+	// give up after MaxRetries retransmissions of one message (zero means
+	// the default) or once the game has run for MaxGameDurationSeconds
+	// (zero means no limit). How long each transmission waits for a reply is
+	// set by the backoff fields below.
+	MaxRetries             int
+	MaxGameDurationSeconds int
+
+	// heartbeat monitoring of the nim servers, enabled by a non-zero
+	// FCheckLostMsgsThresh; FCheckServerAddresses[i] is the heartbeat
+	// address of NimServerAddresses[i]
+	FCheckHbeatLocalAddr  string
+	FCheckLostMsgsThresh  uint8
+	FCheckServerAddresses []string
+
+	// "rle" run-length encodes boards in our moves; empty sends them as-is
+	CompressionMode string
+
+	// play over a QUIC stream, which the server must be serving, rather than
+	// bare UDP packets
+	QuicEnabled bool
+
+	// "tcp" plays over TCP, to a server with a TCPAddress on the same
+	// host:port as NimServerAddresses, and "dtls" over DTLS to its
+	// DTLSAddress; "udp" or empty plays over UDP, or QUIC. With TCPFallback
+	// a game that can't be played over UDP, its socket failing or the
+	// server never answering, switches to TCP rather than failing over to
+	// the next server.
+	Transport   string
+	TCPFallback bool
+
+	// over DTLS the server's certificate is verified against the PEM
+	// certificates in DTLSCAFile; without one, both sides are
+	// authenticated by a key derived from DTLSPSK, the server's DTLSPSK
+	DTLSCAFile string
+	DTLSPSK    []byte
+
+	// fraction (0-1) of actions recorded in the trace; unset records all
+	TracingSampleRate *float64
+
+	// game outcomes are appended to GameResultsFile when set. With
+	// AutoEscalate the seed is nudged to a hard game once the recent win
+	// rate exceeds EscalationThreshold, and back to easy below
+	// 1-EscalationThreshold; the server picks difficulty from seed parity.
+	GameResultsFile     string
+	AutoEscalate        bool
+	EscalationThreshold float64
+
+	// stop sending for CircuitBreakerBackoffMs after CircuitBreakerThreshold
+	// consecutive timeouts or rejected replies; a zero threshold disables
+	CircuitBreakerThreshold int
+	CircuitBreakerBackoffMs int
+
+	// retransmission backoff; zero values fall back to the defaults in backoff.go
+	RetryBaseMs     int
+	RetryMultiplier float64
+	RetryCapMs      int
+
+	// check the server's initial board is the one nim.GenerateBoard makes
+	// for the seed, or makes in BoardProfile, BoardGuarantee or Variant;
+	// leave off against servers playing other boards, such as from a
+	// dataset
+	VerifyInitialBoard bool
+
+	// while waiting for each reply, predict the server's move, taking it to
+	// play its best, and decide our answer to it; a wrong prediction is
+	// corrected from the reply. Stateful strategies such as nim.Random see
+	// the extra moves they are asked for.
+	SpeculativeUpdate bool
+
+	// play misère nim, where whoever takes the last coin loses. The
+	// server doesn't know the rules changed, so only the winner reported
+	// here changes, not how either side plays.
+	MisereMode bool
+
+	// "lasker" asks the server for Lasker's nim, in which a move may split a
+	// heap in two, played by strategies with a LaskerMove and by the others
+	// only taking coins; "wythoff" asks for Wythoff's game, on two heaps,
+	// in which a move may take as many coins from both, played likewise by
+	// strategies with a WythoffMove. The game is nim if the server won't.
+	// Empty plays nim.
+	Variant string
+
+	// asks the server for boards of one shape, one of nim.BoardProfiles:
+	// "uniform", "skewed", "single", "endgame" or "custom" with the
+	// server's weights; empty leaves it to the server
+	BoardProfile string
+
+	// asks the server for a board won with perfect play by one player, one
+	// of nim.BoardGuarantees: "first", the client, "second", the server, or
+	// "random", either; empty leaves it to the server
+	BoardGuarantee string
+
+	// asks the server for time control: each side has TimeControlSeconds
+	// on a chess clock for the whole game, and the client loses once its
+	// clock runs out; zero plays without
+	TimeControlSeconds int
+}
+
+/* Tracing structs */
+
+type GameStart struct {
+	Seed     int8
+	Strategy string
+}
+
+type ClientMove StateMoveMessage
+
+type ServerMoveReceive StateMoveMessage
+
+type GameComplete struct {
+	Winner string
+}
+
+// MatchComplete is recorded once a match PlayMatch plays is decided, with
+// the final score the server sent.
+type MatchComplete struct {
+	Games      int8
+	ClientWins int8
+	ServerWins int8
+	Winner     string
+}
+
+type GameAborted struct {
+	Reason string
+}
+
+type NimServerFailed struct {
+	NimServerAddress string
+}
+
+type NewNimServer struct {
+	NimServerAddress string
+}
+
+type AllNimServersDown struct {
+}
+
+// InitialBoardMismatch is recorded when VerifyInitialBoard is set and the
+// server starts the game on a board other than the seed's.
+type InitialBoardMismatch struct {
+	Seed     int8
+	Expected []uint8
+	Received []uint8
+}
+
+// ServerCheatDetected is recorded when the server keeps replying with
+// boards that no legal move reaches from Board.
+type ServerCheatDetected struct {
+	Board     []uint8 // after the client's move
+	Received  []uint8
+	MoveRow   int8
+	MoveCount int8
+}
+
+/* Message structs */
+
+type StateMoveMessage struct {
+	GameState         []uint8
+	MoveRow           int8
+	MoveCount         int8
+	TracingServerAddr string
+	Token             tracing.TracingToken
+	RLEEncoded        bool     // GameState is run-length encoded, see nim.RLEEncode
+	MerkleRoot        [32]byte // nim.ComputeMerkleRoot of the decoded GameState; zero if not sent
+	GameID            string   // the server's name for the game, sent back to resume it
+	Variant           string   // nim.LaskerVariant or nim.WythoffVariant, asking for and playing it
+	BoardProfile      string   // one of nim.BoardProfiles, asking for boards of its shape at GameStart
+	BoardGuarantee    string   // one of nim.BoardGuarantees, asking for a board won by that player
+	MatchGames        int8     // best of, asking for a match at GameStart; see PlayMatch
+	MatchClientWins   int8     // the match's score so far, in replies during one
+	MatchServerWins   int8
+	TimeControl       int32 // ms on each side's clock, asking for time control at GameStart
+	ClientClock       int32 // ms left on each side's clock, in replies under time control
+	ServerClock       int32
+	Seq               uint32 // numbers each reply under time control; a move carries back its reply's
+	DryRun            bool   // in every reply of a server keeping nothing of the game
+}
+
+// errBadStrategy is returned when the strategy makes an illegal move, or
+// none at all.
+var errBadStrategy = nimerr.New(nimerr.ErrGameState, "move decision strategy failed")
+
+// decideMove returns the move strategy makes on state.
+func decideMove(strategy Strategy, state []uint8) (StateMoveMessage, error) {
+	row, count := strategy.Move(state)
+	if row < 0 || row >= len(state) || count == 0 || count > state[row] {
+		return StateMoveMessage{}, fmt.Errorf("%w: took %d from row %d of %v", errBadStrategy, count, row, state)
+	}
+	newState := make([]uint8, len(state))
+	copy(newState, state)
+	newState[row] -= count
+	return StateMoveMessage{GameState: newState, MoveRow: int8(row), MoveCount: int8(count)}, nil
+}
+
+// decideLaskerMove returns the move strategy makes on state in Lasker's
+// nim: a split is sent with the row split and a count of zero.
+func decideLaskerMove(strategy nim.LaskerStrategy, state []uint8) (StateMoveMessage, error) {
+	after := strategy.LaskerMove(state)
+	if !nim.ValidLaskerMove(state, after) {
+		return StateMoveMessage{}, fmt.Errorf("%w: moved %v to %v", errBadStrategy, state, after)
+	}
+	row, count := nim.LaskerStep(state, after)
+	return StateMoveMessage{GameState: after, MoveRow: int8(row), MoveCount: int8(count)}, nil
+}
+
+// decideWythoffMove returns the move strategy makes on state in Wythoff's
+// game: taking from both heaps is sent with nim.WythoffBothRows as the row.
+func decideWythoffMove(strategy nim.WythoffStrategy, state []uint8) (StateMoveMessage, error) {
+	after := strategy.WythoffMove(state)
+	if !nim.ValidWythoffMove(state, after) {
+		return StateMoveMessage{}, fmt.Errorf("%w: moved %v to %v", errBadStrategy, state, after)
+	}
+	row, count := nim.WythoffStep(state, after)
+	return StateMoveMessage{GameState: after, MoveRow: int8(row), MoveCount: int8(count)}, nil
+}
+
+func isWinState(state []uint8) bool {
+	for _, elm := range state {
+		if elm != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// isValidSuccessor reports whether move takes state to its GameState by
+// removing MoveCount coins from row MoveRow. Malformed replies (wrong board
+// length, out-of-range row, non-positive or oversized count) are invalid.
+func isValidSuccessor(state []uint8, move *StateMoveMessage) bool {
+	return nim.ValidMove(state, move.GameState, int(move.MoveRow), int(move.MoveCount))
+}
+
+// isConcession reports whether move is the server's {nil, -2, -2} admission
+// of defeat.
+func isConcession(move *StateMoveMessage) bool {
+	return move.GameState == nil && move.MoveRow == -2 && move.MoveCount == -2
+}
+
+// drainingMoveRow marks a draining server's refusal of a GameStart: it
+// plays on the games it has, but starts no more.
+const drainingMoveRow = -4
+
+// actionRecorder is the part of the tracing API used on the send/receive path.
+type actionRecorder interface {
+	RecordAction(record interface{})
+	GenerateToken() tracing.TracingToken
+	ReceiveToken(token tracing.TracingToken)
+}
+
+// nopRecorder records nothing, for when tracing is disabled.
+type nopRecorder struct{}
+
+func (nopRecorder) RecordAction(interface{}) {}
+
+func (nopRecorder) GenerateToken() tracing.TracingToken { return nil }
+
+func (nopRecorder) ReceiveToken(tracing.TracingToken) {}
+
+const tracingDialTimeout = 5 * time.Second
+
+// gameTrace keeps the game's trace current as tokens come back from the server.
+// Only a sampleRate fraction of actions are recorded; tokens always are.
+type gameTrace struct {
+	tracer     *tracing.Tracer
+	trace      *tracing.Trace
+	sampleRate float64
+	rng        *rand.Rand
+}
+
+// newGameTrace connects to config's tracing server and starts a trace.
+func newGameTrace(config *ClientConfig, log *slog.Logger) (*gameTrace, error) {
+	// tracing.NewTracer exits the program if it can't connect, so check
+	// the server is there first
+	conn, err := net.DialTimeout("tcp", config.TracingServerAddress, tracingDialTimeout)
+	if err != nil {
+		return nil, nimerr.Wrap(nimerr.ErrTransport, fmt.Errorf("connecting to tracing server: %w", err))
+	}
+	conn.Close()
+	tracer := tracing.NewTracer(tracing.TracerConfig{
+		ServerAddress:  config.TracingServerAddress,
+		TracerIdentity: config.TracingIdentity,
+		Secret:         config.Secret,
+	})
+
+	t := &gameTrace{tracer: tracer, trace: tracer.CreateTrace(), sampleRate: 1, rng: rand.New(rand.NewSource(time.Now().UnixNano()))}
+	if config.TracingSampleRate != nil {
+		t.sampleRate = *config.TracingSampleRate
+	}
+	if t.sampleRate < 1 {
+		log.Warn("tracing is sampled", "rate", t.sampleRate)
+	}
+	return t, nil
+}
+
+func (t *gameTrace) RecordAction(record interface{}) {
+	if t.rng.Float64() < t.sampleRate {
+		t.trace.RecordAction(record)
+	}
+}
+
+func (t *gameTrace) GenerateToken() tracing.TracingToken {
+	return t.trace.GenerateToken()
+}
+
+func (t *gameTrace) ReceiveToken(token tracing.TracingToken) {
+	if token != nil {
+		t.trace = t.tracer.ReceiveToken(token)
+	}
+}
+
+func traceAndSend(move *StateMoveMessage, trace actionRecorder, conn net.Conn, compression string) {
+	trace.RecordAction(ClientMove(*move))
+	move.Token = trace.GenerateToken()
+	wire := *move
+	if compression == "rle" && wire.GameState != nil {
+		wire.GameState = nim.RLEEncode(wire.GameState)
+		wire.RLEEncoded = true
+	}
+	conn.Write(encode(&wire))
+	// assume it went through, if it didn't, we'll just retry after a timeout
+}
+
+// recvAndTrace reads one move from conn, waiting until deadline or until ctx
+// is done, whichever comes first.
+func recvAndTrace(ctx context.Context, move *StateMoveMessage, trace actionRecorder, conn net.Conn, deadline time.Time) error {
+	recvBuf := make([]byte, 1024)
+
+	conn.SetReadDeadline(deadline)
+	// set after the deadline above, so a cancellation always cuts the read short
+	stop := context.AfterFunc(ctx, func() { conn.SetReadDeadline(time.Now()) })
+	defer stop()
+	len, err := conn.Read(recvBuf)
+	if err != nil {
+		return err
+	}
+	decoded, err := decode(recvBuf, len)
+	if err != nil {
+		return err
+	}
+	*move = decoded
+	trace.ReceiveToken(move.Token)
+	trace.RecordAction(ServerMoveReceive(*move))
+	return nil
+}
+
+func encode(move *StateMoveMessage) []byte {
+	var buf bytes.Buffer
+	gob.NewEncoder(&buf).Encode(move)
+	return buf.Bytes()
+}
+
+// maxBoardRows is the most rows a board can have and still have a MoveRow
+// for each.
+const maxBoardRows = math.MaxInt8 + 1
+
+// errBadMerkleRoot rejects a move whose board doesn't match its MerkleRoot.
+var errBadMerkleRoot = nimerr.New(nimerr.ErrProtocol, "board doesn't match its Merkle root")
+
+// decode reads a move from the first len bytes of buf. Errors are
+// nimerr.ErrProtocol.
+func decode(buf []byte, len int) (StateMoveMessage, error) {
+	var decoded StateMoveMessage
+	err := gob.NewDecoder(bytes.NewBuffer(buf[0:len])).Decode(&decoded)
+	if err != nil {
+		return StateMoveMessage{}, nimerr.Wrap(nimerr.ErrProtocol, err)
+	}
+	if decoded.RLEEncoded {
+		if decoded.GameState, err = nim.RLEDecode(decoded.GameState, maxBoardRows); err != nil {
+			return StateMoveMessage{}, nimerr.Wrap(nimerr.ErrProtocol, err)
+		}
+		decoded.RLEEncoded = false
+	}
+	if decoded.MerkleRoot != ([32]byte{}) && !nim.VerifyMerkleRoot(decoded.GameState, decoded.MerkleRoot) {
+		return StateMoveMessage{}, fmt.Errorf("%w: state %v, root %v", errBadMerkleRoot, decoded.GameState, hex.EncodeToString(decoded.MerkleRoot[:]))
+	}
+	return decoded, nil
+}
+
+// ServerAddresses returns the nim servers to try, in order.
+func (config *ClientConfig) ServerAddresses() []string {
+	if len(config.NimServerAddresses) == 0 && config.NimServerAddress != "" {
+		return []string{config.NimServerAddress}
+	}
+	return config.NimServerAddresses
+}
@@ -0,0 +1,134 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"nimgame/pkg/nim"
+	"nimgame/pkg/nimerr"
+)
+
+func TestIsValidSuccessor(t *testing.T) {
+	state := []uint8{3, 4, 5}
+	cases := []struct {
+		name  string
+		move  StateMoveMessage
+		valid bool
+	}{
+		{"valid", StateMoveMessage{GameState: []uint8{3, 1, 5}, MoveRow: 1, MoveCount: 3}, true},
+		{"empties row", StateMoveMessage{GameState: []uint8{3, 4, 0}, MoveRow: 2, MoveCount: 5}, true},
+		{"nil board", StateMoveMessage{GameState: nil, MoveRow: 0, MoveCount: 1}, false},
+		{"short board", StateMoveMessage{GameState: []uint8{2, 4}, MoveRow: 0, MoveCount: 1}, false},
+		{"long board", StateMoveMessage{GameState: []uint8{2, 4, 5, 1}, MoveRow: 0, MoveCount: 1}, false},
+		{"negative row", StateMoveMessage{GameState: []uint8{3, 4, 5}, MoveRow: -1, MoveCount: 1}, false},
+		{"row past end", StateMoveMessage{GameState: []uint8{3, 4, 5}, MoveRow: 3, MoveCount: 1}, false},
+		{"row far past end", StateMoveMessage{GameState: []uint8{3, 4, 5}, MoveRow: 127, MoveCount: 1}, false},
+		{"zero count", StateMoveMessage{GameState: []uint8{3, 4, 5}, MoveRow: 0, MoveCount: 0}, false},
+		{"negative count", StateMoveMessage{GameState: []uint8{4, 4, 5}, MoveRow: 0, MoveCount: -1}, false},
+		{"count exceeds row", StateMoveMessage{GameState: []uint8{3, 4, 251}, MoveRow: 2, MoveCount: 10}, false},
+		// 5-6 wraps to 255 in uint8 arithmetic
+		{"underflow by one", StateMoveMessage{GameState: []uint8{3, 4, 255}, MoveRow: 2, MoveCount: 6}, false},
+		// a count of -1 is 255 as a uint8, and 5-255 wraps to 6
+		{"adds a coin by underflow", StateMoveMessage{GameState: []uint8{3, 4, 6}, MoveRow: 2, MoveCount: -1}, false},
+		{"row grows", StateMoveMessage{GameState: []uint8{3, 5, 5}, MoveRow: 1, MoveCount: 1}, false},
+		{"wrong delta", StateMoveMessage{GameState: []uint8{3, 2, 5}, MoveRow: 1, MoveCount: 1}, false},
+		{"other row changed", StateMoveMessage{GameState: []uint8{2, 3, 5}, MoveRow: 1, MoveCount: 1}, false},
+	}
+	for _, c := range cases {
+		if got := isValidSuccessor(state, &c.move); got != c.valid {
+			t.Errorf("%s: isValidSuccessor(%v, %v) = %v, want %v\n", c.name, state, c.move, got, c.valid)
+		}
+	}
+}
+
+func TestDecodeChecksMerkleRoot(t *testing.T) {
+	board := []uint8{5, 5, 5, 2, 2, 7}
+	move := StateMoveMessage{GameState: board, MoveRow: -1, MoveCount: 3, MerkleRoot: nim.ComputeMerkleRoot(board)}
+	buf := encode(&move)
+	if _, err := decode(buf, len(buf)); err != nil {
+		t.Errorf("valid root rejected: %v\n", err)
+	}
+
+	move.GameState = []uint8{5, 5, 5, 2, 2, 6}
+	buf = encode(&move)
+	if _, err := decode(buf, len(buf)); !errors.Is(err, errBadMerkleRoot) || !errors.Is(err, nimerr.ErrProtocol) {
+		t.Errorf("tampered board: got error %v, expected errBadMerkleRoot\n", err)
+	}
+
+	// servers that don't send a root aren't checked
+	move.MerkleRoot = [32]byte{}
+	buf = encode(&move)
+	if _, err := decode(buf, len(buf)); err != nil {
+		t.Errorf("move without a root rejected: %v\n", err)
+	}
+
+	if _, err := decode([]byte("garbage"), 7); !errors.Is(err, nimerr.ErrProtocol) {
+		t.Errorf("undecodable packet: got error %v, expected a protocol error\n", err)
+	}
+}
+
+func TestServerCheatDetected(t *testing.T) {
+	h := &harnessServer{Board: []uint8{3, 4, 5}, Cheat: true}
+	sess := newTestSession(t, &ClientConfig{}, h.start(t))
+	trace := &recordingRecorder{}
+	sess.trace = trace
+
+	_, err := sess.Play(context.Background())
+	if !errors.Is(err, ErrServerCheating) || !errors.Is(err, nimerr.ErrProtocol) {
+		t.Fatalf("expected ErrServerCheating, got %v\n", err)
+	}
+	if code := ExitCode(Result{}, err); code != ExitProtocol {
+		t.Errorf("expected exit code %d, got %d\n", ExitProtocol, code)
+	}
+	// GameStart and one send per illegal reply, without waiting out MaxRetries
+	if received, _ := h.counts(); received != 1+cheatThreshold {
+		t.Errorf("expected %d packets before giving up, server saw %d\n", 1+cheatThreshold, received)
+	}
+	var cheat *ServerCheatDetected
+	for _, action := range trace.actions {
+		if c, ok := action.(ServerCheatDetected); ok {
+			cheat = &c
+		}
+	}
+	// the harness echoes the client's board back as its own move
+	if cheat == nil || !bytes.Equal(cheat.Board, cheat.Received) || cheat.MoveRow != 0 || cheat.MoveCount != 1 {
+		t.Errorf("expected a ServerCheatDetected action with both boards, got %v\n", trace.actions)
+	}
+}
+
+func TestVerifyInitialBoard(t *testing.T) {
+	addr := (&harnessServer{Board: nim.GenerateBoard(9)}).start(t)
+	tests := []struct {
+		seed   int8
+		verify bool
+		err    error
+	}{
+		{9, true, nil},
+		{10, true, ErrBoardMismatch},
+		{10, false, nil}, // as against a server playing a dataset
+	}
+	for _, test := range tests {
+		sess := newTestSession(t, &ClientConfig{VerifyInitialBoard: test.verify}, addr)
+		sess.seed = test.seed
+		trace := &recordingRecorder{}
+		sess.trace = trace
+
+		_, err := sess.Play(context.Background())
+		if !errors.Is(err, test.err) || (test.err == nil && err != nil) {
+			t.Errorf("seed %d, verify %v: expected %v, got %v\n", test.seed, test.verify, test.err, err)
+		}
+		var mismatch *InitialBoardMismatch
+		for _, action := range trace.actions {
+			if m, ok := action.(InitialBoardMismatch); ok {
+				mismatch = &m
+			}
+		}
+		if (mismatch != nil) != (test.err != nil) {
+			t.Errorf("seed %d, verify %v: unexpected mismatch action %+v\n", test.seed, test.verify, mismatch)
+		} else if mismatch != nil && (!bytes.Equal(mismatch.Expected, nim.GenerateBoard(10)) || !bytes.Equal(mismatch.Received, nim.GenerateBoard(9))) {
+			t.Errorf("mismatch action has the wrong boards: %+v\n", mismatch)
+		}
+	}
+}
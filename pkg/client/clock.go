@@ -0,0 +1,50 @@
+package client
+
+import (
+	"math"
+	"time"
+)
+
+// forfeitMoveRow marks the server's notice that the client lost the game
+// by forfeit: its clock ran out, or it took longer than the server's
+// MoveTTL over a move.
+const forfeitMoveRow = -12
+
+// maxTimeControlSeconds is the longest TimeControlSeconds, the most
+// milliseconds a message carries.
+const maxTimeControlSeconds = math.MaxInt32 / 1000
+
+// Why the server declared a game forfeited, as Result.Forfeit has it.
+const (
+	ForfeitTimeout = "timeout"  // the client's clock ran out
+	ForfeitMoveTTL = "move_ttl" // the client took longer than the server's MoveTTL over a move
+)
+
+// isForfeit reports whether move is the server's notice that the client
+// forfeited the game.
+func isForfeit(move *StateMoveMessage) bool {
+	return move.GameState == nil && move.MoveRow == forfeitMoveRow
+}
+
+// forfeited ends the game lost by the notice, returning the winner.
+func (s *Session) forfeited(notice *StateMoveMessage) string {
+	s.result.Forfeit = ForfeitMoveTTL
+	if notice.TimeControl > 0 && notice.ClientClock == 0 {
+		s.result.Forfeit = ForfeitTimeout
+	}
+	s.logger().Warn("server declared the game forfeited", "reason", s.result.Forfeit)
+	return "server"
+}
+
+// readClocks keeps the clocks a reply under time control reports, and its
+// Seq for the next move to carry back, so the server tells the move from a
+// retransmitted copy of the one before.
+func (s *Session) readClocks(reply *StateMoveMessage) {
+	if reply.TimeControl <= 0 {
+		return
+	}
+	s.seq = reply.Seq
+	s.result.TimeControl = time.Duration(reply.TimeControl) * time.Millisecond
+	s.result.ClientClock = time.Duration(reply.ClientClock) * time.Millisecond
+	s.result.ServerClock = time.Duration(reply.ServerClock) * time.Millisecond
+}
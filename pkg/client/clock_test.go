@@ -0,0 +1,39 @@
+package client
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"nimgame/pkg/nimserver"
+)
+
+// TestTimeControl plays a game with a minute on each clock, which the
+// client wins, and one whose second move comes after its one second clock
+// has run out.
+func TestTimeControl(t *testing.T) {
+	server := startServer(t, &nimserver.ServerConfig{}, "127.0.0.1:0")
+	sess := newTestSession(t, &ClientConfig{TimeControlSeconds: 60}, server.Addr().(*net.UDPAddr))
+	sess.seed = 3
+	var events []MoveEvent
+	WithMoveHook(func(e MoveEvent) { events = append(events, e) })(sess)
+	result, err := sess.Play(context.Background())
+	if err != nil || result.Winner != "client" || result.Forfeit != "" {
+		t.Fatalf("game ended with %+v, %v, expected the client to win\n", result, err)
+	}
+	if result.TimeControl != time.Minute || result.ClientClock <= 0 || result.ClientClock > time.Minute || result.ServerClock <= 0 {
+		t.Errorf("clocks at the end are %+v\n", result)
+	}
+	if e := events[len(events)-1]; e.ClientClock != result.ClientClock || e.ServerClock != result.ServerClock {
+		t.Errorf("last move event %+v doesn't show the clocks at the end\n", e)
+	}
+
+	sess = newTestSession(t, &ClientConfig{TimeControlSeconds: 1}, server.Addr().(*net.UDPAddr))
+	sess.seed = 5
+	sess.moveDelay = 1200 * time.Millisecond
+	result, err = sess.Play(context.Background())
+	if err != nil || result.Winner != "server" || result.Forfeit != ForfeitTimeout || result.ClientClock != 0 {
+		t.Errorf("slow game ended with %+v, %v, expected the client to lose on time\n", result, err)
+	}
+}
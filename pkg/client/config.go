@@ -0,0 +1,154 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+
+	"nimgame/pkg/configfile"
+	"nimgame/pkg/nim"
+	"nimgame/pkg/nimerr"
+	"nimgame/pkg/udpnet"
+)
+
+// ReadConfig reads the config file at path, rejecting fields ClientConfig
+// doesn't have so typos don't go unnoticed. Errors are nimerr.ErrConfig.
+func ReadConfig(path string) (*ClientConfig, error) {
+	config := new(ClientConfig)
+	if err := configfile.Read(path, config); err != nil {
+		return nil, nimerr.Wrap(nimerr.ErrConfig, err)
+	}
+	return config, nil
+}
+
+// ValidateConfig reports every setting in config that would stop the
+// client from playing, naming the field and its value. The error is
+// nimerr.ErrConfig.
+func ValidateConfig(config *ClientConfig) error {
+	var errs []error
+	checkAddr := func(field, network, addr string) {
+		var err error
+		if network == "udp" {
+			_, err = udpnet.ResolveAddr(addr, config.IPv6)
+		} else {
+			_, err = net.ResolveTCPAddr(network, addr)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%v %q is not a valid host:port address: %v", field, addr, err))
+		}
+	}
+	checkRange := func(field string, value, min, max float64) {
+		if value < min || value > max {
+			errs = append(errs, fmt.Errorf("%v %v is outside %v to %v", field, strconv.FormatFloat(value, 'g', -1, 64), min, max))
+		}
+	}
+
+	if config.ClientAddress != "" { // empty binds a free port
+		checkAddr("ClientAddress", "udp", config.ClientAddress)
+	}
+	servers := config.ServerAddresses()
+	if len(servers) == 0 {
+		errs = append(errs, errors.New("NimServerAddresses is empty; set it in the config file or with -server"))
+	}
+	for _, addr := range servers {
+		checkAddr("NimServerAddresses", "udp", addr)
+		// one socket, bound to ClientAddress, plays with every server
+		if config.ClientAddress != "" && config.Transport != "tcp" && udpnet.Network(addr, config.IPv6) != udpnet.Network(config.ClientAddress, config.IPv6) {
+			errs = append(errs, fmt.Errorf("NimServerAddresses %q and ClientAddress %q aren't both IPv4 or both IPv6", addr, config.ClientAddress))
+		}
+	}
+	if config.TracingServerAddress != "" {
+		checkAddr("TracingServerAddress", "tcp", config.TracingServerAddress)
+		if len(config.Secret) == 0 {
+			errs = append(errs, errors.New("Secret is empty; it must match the tracing server's Secret"))
+		}
+	}
+	if config.LogLevel != "" {
+		var level slog.Level
+		if err := level.UnmarshalText([]byte(config.LogLevel)); err != nil {
+			errs = append(errs, fmt.Errorf("LogLevel %q is not one of debug, info, warn or error", config.LogLevel))
+		}
+	}
+
+	for _, f := range []struct {
+		field string
+		value int
+	}{
+		{"RetryBaseMs", config.RetryBaseMs},
+		{"RetryCapMs", config.RetryCapMs},
+		{"MaxRetries", config.MaxRetries},
+		{"MaxGameDurationSeconds", config.MaxGameDurationSeconds},
+		{"CircuitBreakerThreshold", config.CircuitBreakerThreshold},
+		{"CircuitBreakerBackoffMs", config.CircuitBreakerBackoffMs},
+	} {
+		if f.value < 0 {
+			errs = append(errs, fmt.Errorf("%v %d is negative; use 0 for the default", f.field, f.value))
+		}
+	}
+	if config.RetryMultiplier != 0 && config.RetryMultiplier < 1 {
+		errs = append(errs, fmt.Errorf("RetryMultiplier %v would shrink the wait between retries", config.RetryMultiplier))
+	}
+	if config.RetryBaseMs > 0 && config.RetryCapMs > 0 && config.RetryCapMs < config.RetryBaseMs {
+		errs = append(errs, fmt.Errorf("RetryCapMs %d is below RetryBaseMs %d", config.RetryCapMs, config.RetryBaseMs))
+	}
+
+	if config.FCheckLostMsgsThresh > 0 {
+		checkAddr("FCheckHbeatLocalAddr", "udp", config.FCheckHbeatLocalAddr)
+		for _, addr := range config.FCheckServerAddresses {
+			if addr != "" {
+				checkAddr("FCheckServerAddresses", "udp", addr)
+			}
+		}
+	}
+	switch config.Variant {
+	case "", nim.LaskerVariant, nim.WythoffVariant:
+	default:
+		errs = append(errs, fmt.Errorf("Variant %q is not \"lasker\", \"wythoff\" or empty", config.Variant))
+	}
+	if config.BoardProfile != "" && !slices.Contains(nim.BoardProfiles, config.BoardProfile) {
+		errs = append(errs, fmt.Errorf("BoardProfile %q is not one of %v", config.BoardProfile, strings.Join(nim.BoardProfiles, ", ")))
+	}
+	if config.BoardGuarantee != "" && !slices.Contains(nim.BoardGuarantees, config.BoardGuarantee) {
+		errs = append(errs, fmt.Errorf("BoardGuarantee %q is not one of %v", config.BoardGuarantee, strings.Join(nim.BoardGuarantees, ", ")))
+	}
+	if config.BoardProfile == nim.ProfileCustom && config.VerifyInitialBoard {
+		errs = append(errs, errors.New("VerifyInitialBoard is set, but the custom BoardProfile's weights are the server's"))
+	}
+	if config.TimeControlSeconds < 0 || config.TimeControlSeconds > maxTimeControlSeconds {
+		errs = append(errs, fmt.Errorf("TimeControlSeconds %d is outside 0 to %d", config.TimeControlSeconds, maxTimeControlSeconds))
+	}
+	if config.CompressionMode != "" && config.CompressionMode != "rle" {
+		errs = append(errs, fmt.Errorf("CompressionMode %q is not \"rle\" or empty", config.CompressionMode))
+	}
+	switch config.Transport {
+	case "", "udp":
+	case "tcp", "dtls":
+		if config.QuicEnabled {
+			errs = append(errs, fmt.Errorf("Transport %q can't be played with QuicEnabled", config.Transport))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("Transport %q is not \"udp\", \"tcp\", \"dtls\" or empty", config.Transport))
+	}
+	if config.Transport == "dtls" {
+		if config.DTLSCAFile == "" && len(config.DTLSPSK) == 0 {
+			errs = append(errs, errors.New("Transport \"dtls\" needs DTLSCAFile, or a DTLSPSK to derive a key from"))
+		}
+		if config.TCPFallback {
+			errs = append(errs, errors.New("TCPFallback would give up DTLS's encryption; it can't be set with Transport \"dtls\""))
+		}
+	}
+	if config.TracingSampleRate != nil {
+		checkRange("TracingSampleRate", *config.TracingSampleRate, 0, 1)
+	}
+	if config.AutoEscalate {
+		if config.GameResultsFile == "" {
+			errs = append(errs, errors.New("AutoEscalate needs GameResultsFile to remember past games"))
+		}
+		checkRange("EscalationThreshold", config.EscalationThreshold, 0.5, 1)
+	}
+	return nimerr.Wrap(nimerr.ErrConfig, errors.Join(errs...))
+}
@@ -0,0 +1,71 @@
+package client
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"nimgame/pkg/dtlsgame"
+	"nimgame/pkg/nimserver"
+)
+
+// startDTLSServer runs a nim server serving DTLS on a loopback port, with
+// a self-signed certificate, until the test ends, and returns its DTLS
+// address and the certificate's path.
+func startDTLSServer(t *testing.T) (*net.UDPAddr, string) {
+	certPEM, keyPEM, err := dtlsgame.SelfSigned("127.0.0.1")
+	if err != nil {
+		t.Fatalf("making certificate: %v\n", err)
+	}
+	dir := t.TempDir()
+	config := &nimserver.ServerConfig{
+		DTLSAddress:  "127.0.0.1:0",
+		DTLSCertFile: filepath.Join(dir, "cert.pem"),
+		DTLSKeyFile:  filepath.Join(dir, "key.pem"),
+	}
+	os.WriteFile(config.DTLSCertFile, certPEM, 0644)
+	os.WriteFile(config.DTLSKeyFile, keyPEM, 0600)
+	server, err := nimserver.New(nimserver.WithConfig(config), nimserver.WithListenAddress("127.0.0.1:0"))
+	if err != nil {
+		t.Fatalf("starting server: %v\n", err)
+	}
+	done := make(chan error, 1)
+	go func() { done <- server.Run(context.Background()) }()
+	t.Cleanup(func() {
+		server.Shutdown(context.Background())
+		<-done
+	})
+	return server.DTLSAddr().(*net.UDPAddr), config.DTLSCertFile
+}
+
+func TestPlayOverDTLS(t *testing.T) {
+	addr, certFile := startDTLSServer(t)
+	sess := newTestSession(t, &ClientConfig{Transport: "dtls", DTLSCAFile: certFile}, addr)
+
+	result, err := sess.Play(context.Background())
+	if err != nil {
+		t.Fatalf("game over DTLS failed: %v\n", err)
+	}
+	if result.Winner == "" {
+		t.Errorf("game over DTLS ended with %+v, expected a winner\n", result)
+	}
+}
+
+func TestDTLSUntrustedServer(t *testing.T) {
+	addr, _ := startDTLSServer(t)
+	certPEM, _, err := dtlsgame.SelfSigned("127.0.0.1")
+	if err != nil {
+		t.Fatalf("making certificate: %v\n", err)
+	}
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	os.WriteFile(caFile, certPEM, 0644)
+	sess := newTestSession(t, &ClientConfig{Transport: "dtls", DTLSCAFile: caFile}, addr)
+
+	_, err = sess.Play(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "DTLS handshake") {
+		t.Errorf("game against a server with another certificate got %v, expected a failed handshake\n", err)
+	}
+}
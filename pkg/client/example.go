@@ -0,0 +1,104 @@
+package client
+
+// ExampleConfig is the config client -init-config writes. It plays against
+// a server on loopback with tracing and heartbeats off, and documents every
+// setting.
+const ExampleConfig = `// Nim client config, written by client -init-config.
+//
+// Lines starting with // are comments. Any setting can be overridden by a
+// NIM_* environment variable (NIM_SERVER_ADDRESS for NimServerAddresses,
+// NIM_LOG_LEVEL for LogLevel, ...) and some by flags; see client -help.
+{
+    // local UDP address to play from; empty, or port 0, picks a free port
+    "ClientAddress": "",
+    // nim servers, tried in order; seeds are spread across them. These are
+    // IPv6 loopback; write IPv4 ones as "127.0.0.1:41600", with
+    // ClientAddress IPv4 too. IPv6 resolves host names to IPv6 addresses
+    // rather than IPv4
+    "NimServerAddresses": ["[::1]:41600"],
+    "IPv6": false,
+
+    // tracing server to record moves to, as TracingIdentity; Secret must
+    // match the tracing server's. Empty disables tracing.
+    "TracingServerAddress": "",
+    "Secret": "",
+    "TracingIdentity": "client",
+    // fraction (0-1) of actions traced
+    "TracingSampleRate": 1,
+
+    // debug, info, warn or error
+    "LogLevel": "info",
+
+    // give up on a server after this many retransmissions of one move,
+    // waiting RetryBaseMs, then RetryMultiplier times longer each time up
+    // to RetryCapMs
+    "MaxRetries": 10,
+    "RetryBaseMs": 1000,
+    "RetryMultiplier": 2,
+    "RetryCapMs": 8000,
+    // stop sending for CircuitBreakerBackoffMs after this many timeouts or
+    // rejected replies in a row; 0 disables
+    "CircuitBreakerThreshold": 5,
+    "CircuitBreakerBackoffMs": 5000,
+    // abandon games running longer than this; 0 means no limit
+    "MaxGameDurationSeconds": 0,
+
+    // send heartbeats from FCheckHbeatLocalAddr to FCheckServerAddresses[i]
+    // for NimServerAddresses[i], failing over after this many go
+    // unanswered; 0 disables
+    "FCheckLostMsgsThresh": 0,
+    "FCheckHbeatLocalAddr": "[::1]:12346",
+    "FCheckServerAddresses": ["[::1]:41601"],
+
+    // "rle" run-length encodes boards in moves; empty sends them as-is
+    "CompressionMode": "",
+
+    // play over QUIC, which the server must be serving, rather than bare
+    // UDP packets; retransmission is left to QUIC
+    "QuicEnabled": false,
+    // "tcp" plays over TCP, for networks that block UDP, to a server
+    // serving TCP on the same host:port, and "dtls" over DTLS, encrypted;
+    // "udp" or empty plays over UDP. TCPFallback switches a game to TCP
+    // once UDP fails.
+    "Transport": "udp",
+    "TCPFallback": false,
+    // over DTLS, verify the server's certificate against this PEM file;
+    // empty authenticates both sides with a key derived from DTLSPSK, the
+    // server's DTLSPSK, base64 encoded
+    "DTLSCAFile": "",
+    "DTLSPSK": "",
+
+    // append game outcomes to this file; empty disables. AutoEscalate
+    // switches to hard games once the recent win rate exceeds
+    // EscalationThreshold.
+    "GameResultsFile": "",
+    "AutoEscalate": false,
+    "EscalationThreshold": 0.7,
+
+    // check the server's first board is the one generated from the seed;
+    // leave off for servers playing boards from a dataset
+    "VerifyInitialBoard": false,
+
+    // decide each move while waiting for the server, predicting its reply
+    "SpeculativeUpdate": false,
+
+    // whoever takes the last coin loses
+    "MisereMode": false,
+
+    // "lasker" asks the server for Lasker's nim, where a move may split a
+    // heap in two, as the lasker strategy does; "wythoff" for Wythoff's
+    // game, on two heaps, where a move may take as many from both, as the
+    // wythoff strategy does; empty plays nim
+    "Variant": "",
+
+    // ask for boards of one shape: "uniform", "skewed" (one big heap, the
+    // rest tiny), "single" (one heap), "endgame" (heaps of 1 or 2 coins)
+    // or "custom" with the server's weights; empty leaves it to the server
+    "BoardProfile": "",
+
+    // ask for a board won with perfect play by "first", the client,
+    // "second", the server, or "random", either; empty leaves it to the
+    // server
+    "BoardGuarantee": ""
+}
+`
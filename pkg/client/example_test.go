@@ -0,0 +1,41 @@
+package client
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"nimgame/pkg/configfile"
+)
+
+func TestExampleConfigRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "client_config.json")
+	if err := configfile.Write(path, []byte(ExampleConfig), false); err != nil {
+		t.Fatalf("writing example config: %v\n", err)
+	}
+	if err := configfile.Write(path, []byte(ExampleConfig), false); err == nil {
+		t.Errorf("example config overwritten without -force\n")
+	}
+	if err := configfile.Write(path, []byte(ExampleConfig), true); err != nil {
+		t.Errorf("overwriting with -force: %v\n", err)
+	}
+
+	config, err := ReadConfig(path)
+	if err != nil {
+		t.Fatalf("reading example config: %v\n", err)
+	}
+	if err := ValidateConfig(config); err != nil {
+		t.Fatalf("example config is invalid: %v\n", err)
+	}
+	if config.TracingServerAddress != "" {
+		t.Errorf("example config enables tracing at %v\n", config.TracingServerAddress)
+	}
+
+	// newTestSession points the config at the harness
+	h := &harnessServer{Board: []uint8{1, 2, 3}}
+	raddr := h.start(t)
+	config.ClientAddress = "127.0.0.1:0"
+	if result, err := newTestSession(t, config, raddr).Play(context.Background()); err != nil || result.Winner != "client" {
+		t.Errorf("got winner %q, error %v, expected the client to win\n", result.Winner, err)
+	}
+}
@@ -0,0 +1,50 @@
+package client
+
+import (
+	"errors"
+
+	"nimgame/pkg/nimerr"
+)
+
+// Exit statuses for a client run, as returned by ExitCode. ExitError covers
+// failures outside the game, such as a record file that can't be created.
+const (
+	ExitClientWon = 0
+	ExitError     = 1
+	ExitServerWon = 2
+	ExitAborted   = 3 // the game timed out, was canceled or ran out of servers
+	ExitConfig    = 4
+	ExitProtocol  = 5 // the server broke the protocol
+)
+
+// ExitUsage describes the exit statuses for usage output.
+const ExitUsage = `Exit status:
+  0  the client won, or there was no game to play
+  1  an error outside the game
+  2  the server won
+  3  the game was aborted: it timed out, was canceled or every server failed
+  4  the config, flags or environment are invalid
+  5  the server broke the protocol
+`
+
+// ExitCode maps the outcome of a run, as returned by Session.Play or
+// anything before it, to the process exit status. A finished game is
+// reported by its winner even if err says something went wrong afterwards.
+func ExitCode(result Result, err error) int {
+	switch {
+	case result.Winner == "client":
+		return ExitClientWon
+	case result.Winner == "server":
+		return ExitServerWon
+	case err == nil:
+		return ExitClientWon // nothing was played, as with -validate-config
+	case errors.Is(err, nimerr.ErrConfig):
+		return ExitConfig
+	case errors.Is(err, nimerr.ErrProtocol):
+		return ExitProtocol
+	case errors.Is(err, nimerr.ErrTransport), errors.Is(err, nimerr.ErrGameState), errors.Is(err, ErrCanceled):
+		return ExitAborted
+	default:
+		return ExitError
+	}
+}
@@ -0,0 +1,51 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"nimgame/pkg/nim"
+)
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		name    string
+		harness *harnessServer
+		config  ClientConfig
+		code    int
+	}{
+		{"client wins", &harnessServer{Board: []uint8{3, 4, 5, 6}}, ClientConfig{}, ExitClientWon},
+		// the client has to take one coin, leaving the harness the last
+		{"server wins", &harnessServer{Board: []uint8{1, 1}}, ClientConfig{}, ExitServerWon},
+		{"server silent", &harnessServer{Board: []uint8{3, 4, 5}, Silent: true}, ClientConfig{MaxRetries: 2}, ExitAborted},
+		{"server cheats", &harnessServer{Board: []uint8{3, 4, 5}, Cheat: true}, ClientConfig{MaxRetries: 2}, ExitProtocol},
+	}
+	for _, test := range tests {
+		sess := newTestSession(t, &test.config, test.harness.start(t))
+		result, err := sess.Play(context.Background())
+		if code := ExitCode(result, err); code != test.code {
+			t.Errorf("%v: exit code %d, want %d (winner %q, err %v)\n", test.name, code, test.code, result.Winner, err)
+		}
+	}
+
+	_, err := NewSession(ClientConfig{}, nim.Optimal{})
+	if code := ExitCode(Result{}, err); code != ExitConfig {
+		t.Errorf("invalid config: exit code %d, want %d (err %v)\n", code, ExitConfig, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	sess := newTestSession(t, &ClientConfig{}, (&harnessServer{Board: []uint8{3, 4, 5}}).start(t))
+	if result, err := sess.Play(ctx); ExitCode(result, err) != ExitAborted {
+		t.Errorf("canceled game: exit code %d, want %d (err %v)\n", ExitCode(result, err), ExitAborted, err)
+	}
+
+	if code := ExitCode(Result{}, fmt.Errorf("creating game record: %w", errors.ErrUnsupported)); code != ExitError {
+		t.Errorf("error outside the game: exit code %d, want %d\n", code, ExitError)
+	}
+	if code := ExitCode(Result{}, nil); code != 0 {
+		t.Errorf("nothing played: exit code %d, want 0\n", code)
+	}
+}
@@ -0,0 +1,83 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/DistributedClocks/tracing"
+)
+
+// recordingRecorder keeps every recorded action for inspection.
+type recordingRecorder struct {
+	actions []interface{}
+}
+
+func (r *recordingRecorder) RecordAction(record interface{}) {
+	r.actions = append(r.actions, record)
+}
+
+func (r *recordingRecorder) GenerateToken() tracing.TracingToken { return nil }
+
+func (r *recordingRecorder) ReceiveToken(tracing.TracingToken) {}
+
+func (r *recordingRecorder) has(action interface{}) bool {
+	for _, a := range r.actions {
+		if a == action {
+			return true
+		}
+	}
+	return false
+}
+
+func TestFailoverCompletesOnSecondServer(t *testing.T) {
+	board := []uint8{3, 4, 5, 6, 7}
+	first := &harnessServer{Board: board, DieAfter: 3}
+	second := &harnessServer{Board: board}
+	firstAddr, secondAddr := first.start(t), second.start(t)
+
+	sess := newTestSession(t, &ClientConfig{MaxRetries: 2}, firstAddr, secondAddr)
+	trace := &recordingRecorder{}
+	sess.trace = trace
+
+	result, err := sess.Play(context.Background())
+	if err != nil {
+		t.Fatalf("game should complete on the second server: %v\n", err)
+	}
+	if result.Winner != "client" {
+		t.Errorf("expected client to win, result.Winner: %v\n", result.Winner)
+	}
+	if !trace.has(NimServerFailed{firstAddr.String()}) || !trace.has(NewNimServer{secondAddr.String()}) {
+		t.Errorf("missing failover actions: %v\n", trace.actions)
+	}
+	// GameStart plus the two exchanges that completed before the first server died
+	if received, _ := second.counts(); received < 3 {
+		t.Errorf("second server should have seen the replayed game, saw %d packets\n", received)
+	}
+}
+
+func TestAllServersDown(t *testing.T) {
+	first := &harnessServer{Board: []uint8{3, 4, 5}, Silent: true}
+	second := &harnessServer{Board: []uint8{3, 4, 5}, Silent: true}
+	sess := newTestSession(t, &ClientConfig{MaxRetries: 1}, first.start(t), second.start(t))
+	trace := &recordingRecorder{}
+	sess.trace = trace
+
+	_, err := sess.Play(context.Background())
+	if !errors.Is(err, ErrAllServersDown) || !errors.Is(err, ErrNoReply) {
+		t.Fatalf("expected ErrAllServersDown, got %v\n", err)
+	}
+	if !trace.has(AllNimServersDown{}) {
+		t.Errorf("missing AllNimServersDown action: %v\n", trace.actions)
+	}
+}
+
+func TestFailoverDetectsDivergence(t *testing.T) {
+	first := &harnessServer{Board: []uint8{3, 4, 5, 6}, DieAfter: 2}
+	second := &harnessServer{Board: []uint8{6, 5, 4, 3}}
+	sess := newTestSession(t, &ClientConfig{MaxRetries: 1}, first.start(t), second.start(t))
+
+	if _, err := sess.Play(context.Background()); !errors.Is(err, ErrReplayDiverged) {
+		t.Fatalf("expected ErrReplayDiverged, got %v\n", err)
+	}
+}
@@ -0,0 +1,168 @@
+package client
+
+import (
+	"math/rand"
+	"net"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"nimgame/pkg/nim"
+)
+
+// harnessServer is a minimal stand-in for the nim server. It answers GameStart
+// with a fixed board and every other move by taking one coin from the first
+// non-empty row, conceding with {nil, -2, -2} when handed an empty board.
+type harnessServer struct {
+	Board        []uint8
+	Silent       bool          // never reply
+	DieAfter     int           // close the socket after this many replies, if non-zero
+	StallAfter   int           // stop replying, but keep the socket, after this many replies
+	ConcedeAfter int           // answer with {nil, -2, -2} once this many replies are sent, if non-zero
+	Cheat        bool          // answer moves with the client's board unchanged
+	BestMove     bool          // play nim.Optimal, as the server's bestMove, rather than take one coin
+	FromLast     bool          // take one coin from the last row with any, rather than the first
+	Delay        time.Duration // wait this long before each reply
+	Drop         map[int]bool  // ignore these packets, counting from 1
+	Corrupt      map[int]bool  // answer these packets with garbage
+	GameID       string        // sent with every reply, and resumed by, if set
+
+	mu       sync.Mutex
+	conn     *net.UDPConn
+	received int
+	replies  int
+	starts   int              // GameStarts answered
+	resumes  []string         // GameIDs SessionResumes asked for
+	last     StateMoveMessage // the latest reply, resent in answer to Sync
+}
+
+// start serves on a loopback port until the test ends.
+func (h *harnessServer) start(t *testing.T) *net.UDPAddr {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatalf("starting harness server: %v\n", err)
+	}
+	h.conn = conn
+	t.Cleanup(func() { conn.Close() })
+	go h.serve()
+	return conn.LocalAddr().(*net.UDPAddr)
+}
+
+func (h *harnessServer) serve() {
+	buf := make([]byte, 1024)
+	for {
+		n, raddr, err := h.conn.ReadFromUDP(buf)
+		if err != nil {
+			return
+		}
+		move, err := decode(buf, n)
+		if err != nil {
+			continue
+		}
+
+		h.mu.Lock()
+		h.received++
+		silent := h.Silent || (h.StallAfter > 0 && h.replies >= h.StallAfter) || h.Drop[h.received]
+		concede := h.ConcedeAfter > 0 && h.replies >= h.ConcedeAfter
+		corrupt := h.Corrupt[h.received]
+		h.mu.Unlock()
+		if silent {
+			continue
+		}
+		if corrupt {
+			h.conn.WriteToUDP([]byte("garbage"), raddr)
+			continue
+		}
+		time.Sleep(h.Delay)
+
+		var reply StateMoveMessage
+		if move.GameState == nil && move.MoveRow == -1 {
+			board := make([]uint8, len(h.Board))
+			copy(board, h.Board)
+			reply = StateMoveMessage{GameState: board, MoveRow: -1, MoveCount: move.MoveCount}
+			h.mu.Lock()
+			h.starts++
+			h.mu.Unlock()
+		} else if move.GameState == nil && move.MoveRow == syncMoveRow {
+			h.mu.Lock()
+			reply = h.last
+			h.mu.Unlock()
+		} else if move.GameState == nil && move.MoveRow == sessionResumeMoveRow {
+			h.mu.Lock()
+			h.resumes = append(h.resumes, move.GameID)
+			reply = h.last
+			h.mu.Unlock()
+			if move.GameID != h.GameID {
+				continue
+			}
+		} else if concede || isWinState(move.GameState) {
+			reply = StateMoveMessage{GameState: nil, MoveRow: -2, MoveCount: -2}
+		} else if h.Cheat {
+			reply = StateMoveMessage{GameState: move.GameState, MoveRow: 0, MoveCount: 1}
+		} else if h.BestMove {
+			reply, _ = decideMove(nim.Optimal{}, move.GameState)
+		} else if h.FromLast {
+			slices.Reverse(move.GameState)
+			reply = takeOne(move.GameState)
+			slices.Reverse(reply.GameState)
+			reply.MoveRow = int8(len(reply.GameState)) - 1 - reply.MoveRow
+		} else {
+			reply = takeOne(move.GameState)
+		}
+		reply.GameID = h.GameID
+		h.conn.WriteToUDP(encode(&reply), raddr)
+
+		h.mu.Lock()
+		h.replies++
+		h.last = reply
+		die := h.DieAfter > 0 && h.replies >= h.DieAfter
+		h.mu.Unlock()
+		if die {
+			h.conn.Close()
+			return
+		}
+	}
+}
+
+func (h *harnessServer) counts() (received, replies int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.received, h.replies
+}
+
+func takeOne(board []uint8) StateMoveMessage {
+	for i, v := range board {
+		if v > 0 {
+			board[i]--
+			return StateMoveMessage{GameState: board, MoveRow: int8(i), MoveCount: 1}
+		}
+	}
+	return StateMoveMessage{GameState: board, MoveRow: -1, MoveCount: 0}
+}
+
+// newTestSession returns an optimally playing session against raddrs, tried
+// in the order given, with fast retransmissions.
+func newTestSession(t *testing.T, config *ClientConfig, raddrs ...*net.UDPAddr) *Session {
+	if config.RetryBaseMs == 0 {
+		config.RetryBaseMs = 10
+		config.RetryCapMs = 40
+	}
+	if config.ClientAddress == "" {
+		config.ClientAddress = "127.0.0.1:0"
+	}
+	config.NimServerAddresses = nil
+	for _, raddr := range raddrs {
+		config.NimServerAddresses = append(config.NimServerAddresses, raddr.String())
+	}
+	sess, err := NewSession(*config, nim.Optimal{})
+	if err != nil {
+		t.Fatalf("creating session: %v\n", err)
+	}
+	// skip routing by seed and jitter retransmissions predictably
+	sess.servers = config.NimServerAddresses
+	sess.config.FCheckServerAddresses = config.FCheckServerAddresses
+	sess.retry = NewBackoff(config, rand.New(rand.NewSource(1)))
+	t.Cleanup(sess.Close)
+	return sess
+}
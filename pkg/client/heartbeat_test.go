@@ -0,0 +1,71 @@
+package client
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"nimgame/fcheck"
+)
+
+func startResponder(t *testing.T) *fcheck.Responder {
+	r, err := fcheck.StartResponder("127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("starting heartbeat responder: %v\n", err)
+	}
+	t.Cleanup(r.Close)
+	return r
+}
+
+func TestHeartbeatTriggersFailover(t *testing.T) {
+	board := []uint8{3, 4, 5, 6, 7}
+	first := &harnessServer{Board: board, StallAfter: 2}
+	second := &harnessServer{Board: board}
+	firstAddr, secondAddr := first.start(t), second.start(t)
+	firstHbeat, secondHbeat := startResponder(t), startResponder(t)
+	firstHbeat.SetPaused(true)
+
+	// without heartbeats the stalled server would hold the game for minutes
+	config := &ClientConfig{
+		MaxRetries:            10000,
+		FCheckServerAddresses: []string{firstHbeat.Addr(), secondHbeat.Addr()},
+	}
+	sess := newTestSession(t, config, firstAddr, secondAddr)
+	sess.hbeat = fcheck.Config{LostMsgsThresh: 3, InitialRTT: 20 * time.Millisecond, MinInterval: 10 * time.Millisecond}
+	trace := &recordingRecorder{}
+	sess.trace = trace
+
+	start := time.Now()
+	result, err := sess.Play(context.Background())
+	if err != nil {
+		t.Fatalf("game should complete on the second server: %v\n", err)
+	}
+	if result.Winner != "client" {
+		t.Errorf("expected client to win, result.Winner: %v\n", result.Winner)
+	}
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Errorf("failover took %v\n", elapsed)
+	}
+	if !trace.has(NimServerFailed{firstAddr.String()}) {
+		t.Errorf("missing NimServerFailed action: %v\n", trace.actions)
+	}
+}
+
+func TestHeartbeatHealthyServer(t *testing.T) {
+	h := &harnessServer{Board: []uint8{3, 4, 5, 6}}
+	hbeat := startResponder(t)
+	config := &ClientConfig{FCheckServerAddresses: []string{hbeat.Addr()}}
+	sess := newTestSession(t, config, h.start(t))
+	sess.hbeat = fcheck.Config{LostMsgsThresh: 3, InitialRTT: 20 * time.Millisecond, MinInterval: 10 * time.Millisecond}
+	trace := &recordingRecorder{}
+	sess.trace = trace
+
+	if _, err := sess.Play(context.Background()); err != nil {
+		t.Fatalf("game failed: %v\n", err)
+	}
+	for _, a := range trace.actions {
+		if _, ok := a.(NimServerFailed); ok {
+			t.Errorf("healthy server reported failed: %v\n", trace.actions)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package client
+
+import (
+	"math/bits"
+	"time"
+)
+
+// histogramBits sets the precision of latencyHistogram: values are kept to
+// within 1/2^(histogramBits-1), about 1.6%.
+const histogramBits = 7
+
+// latencyHistogram counts durations in buckets that widen with their value,
+// as HDR histograms do, so percentiles keep the same relative precision
+// from microseconds to seconds in a fixed, small amount of memory. The
+// zero value is empty and ready to use.
+type latencyHistogram struct {
+	counts []int // by bucket index, grown as needed
+	n      int
+	total  time.Duration
+	max    time.Duration
+}
+
+// bucket returns the index of the bucket holding v nanoseconds: v itself
+// below 2^histogramBits, and above that v's top histogramBits bits, offset
+// by how far they were shifted down.
+func bucket(v uint64) int {
+	shift := bits.Len64(v) - histogramBits
+	if shift <= 0 {
+		return int(v)
+	}
+	return shift<<(histogramBits-1) + int(v>>shift)
+}
+
+// bucketMax returns the largest value in bucket i.
+func bucketMax(i int) uint64 {
+	const half = 1 << (histogramBits - 1)
+	if i < 2*half {
+		return uint64(i)
+	}
+	shift := i/half - 1
+	mantissa := uint64(i - shift*half)
+	return (mantissa+1)<<shift - 1
+}
+
+func (h *latencyHistogram) record(d time.Duration) {
+	if d < 0 {
+		d = 0
+	}
+	i := bucket(uint64(d))
+	if i >= len(h.counts) {
+		h.counts = append(h.counts, make([]int, i+1-len(h.counts))...)
+	}
+	h.counts[i]++
+	h.n++
+	h.total += d
+	h.max = max(h.max, d)
+}
+
+// mean returns the exact mean, or zero if nothing was recorded.
+func (h *latencyHistogram) mean() time.Duration {
+	if h.n == 0 {
+		return 0
+	}
+	return h.total / time.Duration(h.n)
+}
+
+// percentile returns the nearest-rank pth percentile (0-100), to the
+// histogram's precision, or zero if nothing was recorded.
+func (h *latencyHistogram) percentile(p float64) time.Duration {
+	rank := int(p*float64(h.n)/100 + 0.999999) // ceil, forgiving float error
+	rank = max(rank, 1)
+	seen := 0
+	for i, count := range h.counts {
+		if seen += count; seen >= rank {
+			return min(time.Duration(bucketMax(i)), h.max)
+		}
+	}
+	return 0
+}
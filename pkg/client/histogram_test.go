@@ -0,0 +1,69 @@
+package client
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestLatencyHistogram(t *testing.T) {
+	var h latencyHistogram
+	if h.mean() != 0 || h.percentile(95) != 0 {
+		t.Errorf("empty histogram: mean %v, p95 %v\n", h.mean(), h.percentile(95))
+	}
+	for i := 100; i >= 1; i-- {
+		h.record(time.Duration(i) * time.Millisecond)
+	}
+	tests := []struct {
+		p    float64
+		want time.Duration
+	}{
+		{50, 50 * time.Millisecond},
+		{95, 95 * time.Millisecond},
+		{99, 99 * time.Millisecond},
+		{100, 100 * time.Millisecond},
+		{0, time.Millisecond},
+	}
+	for _, test := range tests {
+		got := h.percentile(test.p)
+		if got < test.want || got > test.want+test.want/50 {
+			t.Errorf("p%v = %v, expected %v to within 2%%\n", test.p, got, test.want)
+		}
+	}
+	if h.mean() != 50500*time.Microsecond || h.max != 100*time.Millisecond {
+		t.Errorf("expected mean 50.5ms and max 100ms, got %v and %v\n", h.mean(), h.max)
+	}
+}
+
+func TestHistogramBuckets(t *testing.T) {
+	// every value lands in a bucket covering it, no more than 1/64 wide
+	for _, v := range []uint64{0, 1, 127, 128, 129, 255, 256, 1000, 123456789, 1 << 40} {
+		i := bucket(v)
+		if hi := bucketMax(i); hi < v || (v >= 128 && hi-v > v/64) {
+			t.Errorf("%d: bucket %d ends at %d\n", v, i, hi)
+		}
+		if i > 0 && bucketMax(i-1) >= v {
+			t.Errorf("%d: bucket %d, but the one before ends at %d\n", v, i, bucketMax(i-1))
+		}
+	}
+}
+
+// TestRTTReflectsDelay plays against a server holding each reply back.
+func TestRTTReflectsDelay(t *testing.T) {
+	const delay = 20 * time.Millisecond
+	h := &harnessServer{Board: []uint8{3, 4, 5}, Delay: delay}
+	// waits long enough that no reply is taken for lost
+	sess := newTestSession(t, &ClientConfig{RetryBaseMs: 500, RetryCapMs: 1000}, h.start(t))
+	result, err := sess.Play(context.Background())
+	if err != nil {
+		t.Fatalf("game failed: %v\n", err)
+	}
+	if result.Retransmissions != 0 {
+		t.Fatalf("expected no retransmissions, got %+v\n", result)
+	}
+	for name, rtt := range map[string]time.Duration{"p50": result.P50RTT, "p95": result.P95RTT, "p99": result.P99RTT, "max": result.MaxRTT} {
+		if rtt < delay || rtt > 5*delay {
+			t.Errorf("%v RTT %v doesn't reflect the %v delay\n", name, rtt, delay)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package client
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"nimgame/pkg/nim"
+	"nimgame/pkg/nimserver"
+)
+
+// splitter splits off a coin from the first heap it can in Lasker's nim,
+// and otherwise plays as nim.Basic.
+type splitter struct {
+	nim.Basic
+}
+
+func (splitter) LaskerMove(board []uint8) []uint8 {
+	for row, coins := range board {
+		if coins > 1 {
+			return nim.Split(board, row, 1)
+		}
+	}
+	row, count := nim.Basic{}.Move(board)
+	after := append([]uint8(nil), board...)
+	after[row] -= count
+	return after
+}
+
+// splitsIn counts the splits the client makes in sess's game, as moves of
+// no coins.
+func splitsIn(sess *Session) *int {
+	splits := new(int)
+	sess.hooks = append(sess.hooks, func(e MoveEvent) {
+		if e.Player == "client" && e.Count == 0 {
+			*splits++
+		}
+	})
+	return splits
+}
+
+func TestPlayLasker(t *testing.T) {
+	server := startServer(t, &nimserver.ServerConfig{LaskerEnabled: true}, "127.0.0.1:0")
+	sess := newTestSession(t, &ClientConfig{Variant: nim.LaskerVariant}, server.Addr().(*net.UDPAddr))
+	sess.strategy = splitter{}
+	splits := splitsIn(sess)
+
+	result, err := sess.Play(context.Background())
+	if err != nil {
+		t.Fatalf("game of Lasker's nim failed: %v\n", err)
+	}
+	if result.Winner == "" || *splits == 0 || sess.variant != nim.LaskerVariant {
+		t.Errorf("game ended with %+v after %d splits, variant %q, expected a winner of Lasker's nim\n", result, *splits, sess.variant)
+	}
+	if stats := server.Stats(); stats.GamesStarted != 1 || stats.InvalidMoves != 0 {
+		t.Errorf("server stats are %+v, expected one game and no invalid moves\n", stats)
+	}
+}
+
+// TestLaskerRefused plays against a server without Lasker's nim, which the
+// client asks for but plays nim with.
+func TestLaskerRefused(t *testing.T) {
+	server := startServer(t, &nimserver.ServerConfig{}, "127.0.0.1:0")
+	sess := newTestSession(t, &ClientConfig{Variant: nim.LaskerVariant}, server.Addr().(*net.UDPAddr))
+	sess.strategy = splitter{}
+	splits := splitsIn(sess)
+
+	result, err := sess.Play(context.Background())
+	if err != nil {
+		t.Fatalf("game failed: %v\n", err)
+	}
+	if result.Winner == "" || *splits != 0 || sess.variant != "" {
+		t.Errorf("game ended with %+v after %d splits, variant %q, expected a winner of nim\n", result, *splits, sess.variant)
+	}
+	if stats := server.Stats(); stats.InvalidMoves != 0 {
+		t.Errorf("server stats are %+v, expected no invalid moves\n", stats)
+	}
+}
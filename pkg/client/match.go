@@ -0,0 +1,124 @@
+package client
+
+import (
+	"context"
+	"fmt"
+
+	"nimgame/pkg/nimerr"
+)
+
+// matchCompleteMoveRow marks the server's message that the game just ended
+// decided the match, carrying the final score. It also answers a Sync sent
+// once the match is over.
+const matchCompleteMoveRow = -14
+
+// MaxMatchGames is the longest match PlayMatch plays, the most games a
+// GameStart can ask for.
+const MaxMatchGames = 127
+
+// ErrNoMatch is returned by PlayMatch when the server doesn't keep the
+// match's score, as servers from before matches don't.
+var ErrNoMatch = nimerr.New(nimerr.ErrProtocol, "server didn't keep the match score")
+
+// MatchScore is a best-of-Games match's score, as the server keeps it.
+type MatchScore struct {
+	Games      int // best of
+	ClientWins int
+	ServerWins int
+}
+
+// Winner returns "client" or "server" once one has won more than half the
+// games, and "" until then.
+func (m MatchScore) Winner() string {
+	switch {
+	case 2*m.ClientWins > m.Games:
+		return "client"
+	case 2*m.ServerWins > m.Games:
+		return "server"
+	}
+	return ""
+}
+
+func (m MatchScore) String() string {
+	return fmt.Sprintf("client %d - server %d", m.ClientWins, m.ServerWins)
+}
+
+// MatchResult is the record of a match: its games, in the order played,
+// and the final score.
+type MatchResult struct {
+	Games []TournamentGame
+	Score MatchScore
+}
+
+// MatchSeed is the seed of game i of a match starting at base: every other
+// seed, wrapping around from 127 to -128, so that every game is played
+// against the server at base's difficulty.
+func MatchSeed(base int8, i int) int8 {
+	return int8(int(base) + 2*i)
+}
+
+// PlayMatch plays a best-of-games match, games odd, game i on seed
+// MatchSeed(base, i) where base is the session's seed, until the server
+// says one side has won more than half of them. The server keeps the score
+// and opens every other game, from the second on. onGame, if not nil, is
+// called after each game with the score so far. The match stops at the
+// first game that fails, whose error is returned. A match is kept by the
+// server it is played on, so a game that fails over to another starts the
+// match over there and is likely to diverge.
+func (s *Session) PlayMatch(ctx context.Context, games int, onGame func(game TournamentGame, score MatchScore)) (MatchResult, error) {
+	if games < 1 || games > MaxMatchGames || games%2 == 0 {
+		return MatchResult{}, nimerr.New(nimerr.ErrConfig, fmt.Sprintf("a match of %d games isn't an odd number from 1 to %d", games, MaxMatchGames))
+	}
+	base := s.seed
+	s.matchGames = int8(games)
+	defer func() { s.matchGames = 0 }()
+
+	var m MatchResult
+	for i := 0; m.Score.Winner() == ""; i++ {
+		if i >= games {
+			return m, fmt.Errorf("%w: %v after %d games of a best of %d", ErrNoMatch, m.Score, i, games)
+		}
+		s.newGame(MatchSeed(base, i))
+		result, err := s.Play(ctx)
+		game := TournamentGame{Seed: s.seed, Result: result, Err: err}
+		m.Games = append(m.Games, game)
+		if err != nil {
+			return m, err
+		}
+		if s.score.Games == 0 {
+			return m, ErrNoMatch
+		}
+		m.Score = s.score
+		if onGame != nil {
+			onGame(game, m.Score)
+		}
+	}
+
+	// the server's word on the final score, which it sent after the last
+	// reply and answers a Sync with
+	sync := StateMoveMessage{GameState: nil, MoveRow: syncMoveRow}
+	var notice StateMoveMessage
+	isNotice := func(move *StateMoveMessage) (bool, error) { return move.MoveRow == matchCompleteMoveRow, nil }
+	if err := s.sendAndAwait(ctx, &sync, &notice, isNotice); err != nil {
+		return m, fmt.Errorf("awaiting the match result: %w", err)
+	}
+	m.Score = s.score
+	s.trace.RecordAction(MatchComplete{
+		Games:      notice.MatchGames,
+		ClientWins: notice.MatchClientWins,
+		ServerWins: notice.MatchServerWins,
+		Winner:     m.Score.Winner(),
+	})
+	return m, nil
+}
+
+// newGame readies the session to play the next game of a match, on seed,
+// over the same connection.
+func (s *Session) newGame(seed int8) {
+	s.seed = seed
+	s.result, s.rtt, s.seq = Result{}, latencyHistogram{}, 0
+	s.initial, s.history, s.variant = nil, nil, ""
+	if s.gameID != "" {
+		s.lastGameID, s.gameID = s.gameID, ""
+	}
+}
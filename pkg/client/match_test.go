@@ -0,0 +1,54 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	"nimgame/pkg/nimerr"
+	"nimgame/pkg/nimserver"
+)
+
+// TestPlayMatch plays a best-of-3 match with the optimal strategy against
+// the server's best moves, on boards the first player wins: the client wins
+// the games it opens, and the server the second, which it opens.
+func TestPlayMatch(t *testing.T) {
+	server := startServer(t, &nimserver.ServerConfig{}, "127.0.0.1:0")
+	sess := newTestSession(t, &ClientConfig{VerifyInitialBoard: true}, server.Addr().(*net.UDPAddr))
+	sess.seed = 3
+
+	var winners []string
+	var scores []MatchScore
+	m, err := sess.PlayMatch(context.Background(), 3, func(game TournamentGame, score MatchScore) {
+		winners = append(winners, game.Result.Winner)
+		scores = append(scores, score)
+	})
+	if err != nil {
+		t.Fatalf("match failed after %+v: %v\n", m, err)
+	}
+	want := []MatchScore{{3, 1, 0}, {3, 1, 1}, {3, 2, 1}}
+	if len(scores) != len(want) || winners[0] != "client" || winners[1] != "server" || winners[2] != "client" {
+		t.Fatalf("games won by %v, at %v, expected client, server, client\n", winners, scores)
+	}
+	for i := range want {
+		if scores[i] != want[i] {
+			t.Errorf("score after game %d is %v, expected %v\n", i+1, scores[i], want[i])
+		}
+	}
+	if m.Score != want[2] || m.Score.Winner() != "client" || len(m.Games) != 3 || m.Games[1].Seed != MatchSeed(3, 1) {
+		t.Errorf("match result is %+v, expected the client to win 2-1\n", m)
+	}
+	if stats := server.Stats(); stats.InvalidMoves != 0 || stats.GamesStarted != 3 {
+		t.Errorf("server stats are %+v, expected three games and no invalid moves\n", stats)
+	}
+}
+
+// TestPlayMatchEven checks PlayMatch refuses a best of an even number of
+// games, which could end in a tie.
+func TestPlayMatchEven(t *testing.T) {
+	sess := newTestSession(t, &ClientConfig{}, startServer(t, &nimserver.ServerConfig{}, "127.0.0.1:0").Addr().(*net.UDPAddr))
+	if _, err := sess.PlayMatch(context.Background(), 4, nil); !errors.Is(err, nimerr.ErrConfig) {
+		t.Errorf("PlayMatch of 4 games returned %v, expected a config error\n", err)
+	}
+}
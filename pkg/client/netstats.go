@@ -0,0 +1,108 @@
+package client
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// Network statistics cover the last statsWindow exchanges and are logged
+// every statsInterval moves.
+const (
+	statsWindow   = 20
+	statsInterval = 10
+)
+
+// NetworkStats summarises round trips to the nim server.
+type NetworkStats struct {
+	MinRTT, MaxRTT, MeanRTT time.Duration
+	LossRate                float64 // retransmissions per send
+}
+
+func (s NetworkStats) String() string {
+	return fmt.Sprintf("min RTT %v, max RTT %v, mean RTT %v, estimated loss %.1f%%",
+		s.MinRTT, s.MaxRTT, s.MeanRTT, 100*s.LossRate)
+}
+
+// CollectNetworkStats computes stats from the times of every transmission,
+// retransmissions included, and of every accepted reply, both in order.
+// Each reply's RTT is measured from the last send before it; every send that
+// didn't draw a reply is counted as lost.
+func CollectNetworkStats(sends, recvs []time.Time) NetworkStats {
+	var stats NetworkStats
+	if len(sends) > 0 && len(recvs) <= len(sends) {
+		stats.LossRate = float64(len(sends)-len(recvs)) / float64(len(sends))
+	}
+	var total time.Duration
+	var n int
+	i := 0
+	for _, recv := range recvs {
+		for i < len(sends) && sends[i].Before(recv) {
+			i++
+		}
+		if i == 0 {
+			continue // no send to pair with
+		}
+		rtt := recv.Sub(sends[i-1])
+		if n == 0 || rtt < stats.MinRTT {
+			stats.MinRTT = rtt
+		}
+		if rtt > stats.MaxRTT {
+			stats.MaxRTT = rtt
+		}
+		total += rtt
+		n++
+	}
+	if n > 0 {
+		stats.MeanRTT = total / time.Duration(n)
+	}
+	return stats
+}
+
+// netStats tracks the sends and replies of the last statsWindow exchanges
+// and logs their stats every statsInterval moves. A nil *netStats tracks
+// nothing, so sessions without WithNetworkStats need no checks.
+type netStats struct {
+	sends, recvs []time.Time
+	perMove      []int // sends made for each reply in recvs
+	pending      int   // sends awaiting a reply
+	moves        int
+	log          *slog.Logger // nil logs to the default logger
+}
+
+func (n *netStats) sent(at time.Time) {
+	if n == nil {
+		return
+	}
+	n.sends = append(n.sends, at)
+	n.pending++
+}
+
+func (n *netStats) received(at time.Time) {
+	if n == nil {
+		return
+	}
+	n.recvs = append(n.recvs, at)
+	n.perMove = append(n.perMove, n.pending)
+	n.pending = 0
+	if len(n.recvs) > statsWindow {
+		// drop the oldest exchange along with its sends
+		n.sends = n.sends[n.perMove[0]:]
+		n.recvs = n.recvs[1:]
+		n.perMove = n.perMove[1:]
+	}
+	n.moves++
+	if n.moves%statsInterval == 0 {
+		stats := n.Stats()
+		logOrDefault(n.log).Info("network stats", "moves", n.moves, "minRTT", stats.MinRTT, "maxRTT", stats.MaxRTT,
+			"meanRTT", stats.MeanRTT, "loss", stats.LossRate)
+	}
+}
+
+// Stats returns the stats over the current window.
+func (n *netStats) Stats() NetworkStats {
+	if n == nil {
+		return NetworkStats{}
+	}
+	return CollectNetworkStats(n.sends, n.recvs)
+}
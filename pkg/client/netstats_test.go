@@ -0,0 +1,53 @@
+package client
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCollectNetworkStats(t *testing.T) {
+	// 20 exchanges with RTTs of 10ms to 200ms; every fifth move is sent twice
+	start := time.Unix(0, 0)
+	var sends, recvs []time.Time
+	now := start
+	for i := 1; i <= 20; i++ {
+		if i%5 == 0 {
+			sends = append(sends, now)
+			now = now.Add(time.Second) // lost, retransmitted after a timeout
+		}
+		sends = append(sends, now)
+		now = now.Add(time.Duration(i) * 10 * time.Millisecond)
+		recvs = append(recvs, now)
+		now = now.Add(time.Millisecond)
+	}
+
+	stats := CollectNetworkStats(sends, recvs)
+	want := NetworkStats{
+		MinRTT:   10 * time.Millisecond,
+		MaxRTT:   200 * time.Millisecond,
+		MeanRTT:  105 * time.Millisecond,
+		LossRate: 4.0 / 24,
+	}
+	if stats != want {
+		t.Errorf("got %+v, expected %+v\n", stats, want)
+	}
+}
+
+func TestNetStatsWindow(t *testing.T) {
+	n := &netStats{}
+	now := time.Unix(0, 0)
+	for i := 1; i <= 30; i++ {
+		n.sent(now)
+		if i <= 10 {
+			// the first ten moves are slow and lossy, then fall out of the window
+			n.sent(now.Add(time.Second))
+			now = now.Add(time.Second)
+		}
+		now = now.Add(time.Duration(i) * time.Millisecond)
+		n.received(now)
+	}
+	stats := n.Stats()
+	if len(n.recvs) != statsWindow || stats.LossRate != 0 || stats.MinRTT != 11*time.Millisecond || stats.MaxRTT != 30*time.Millisecond {
+		t.Errorf("got %+v over %d replies, expected the last %d moves only\n", stats, len(n.recvs), statsWindow)
+	}
+}
@@ -0,0 +1,95 @@
+package client
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"nimgame/pkg/quicstream"
+	"nimgame/pkg/udpnet"
+
+	"github.com/quic-go/quic-go"
+)
+
+// quicDialTimeout bounds the handshake with a server, which a server that
+// is down never answers.
+const quicDialTimeout = 5 * time.Second
+
+// dialQUIC opens a stream to the QUIC server at addr, from laddr if it isn't
+// nil, and returns it as a conn reading and writing one move at a time, as
+// a UDP conn does. ipv6 is ClientConfig.IPv6.
+func dialQUIC(addr string, laddr *net.UDPAddr, ipv6 bool) (net.Conn, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), quicDialTimeout)
+	defer cancel()
+	c := &quicConn{}
+	var err error
+	if laddr == nil {
+		c.conn, err = quic.DialAddr(ctx, addr, quicstream.ClientTLSConfig(), nil)
+	} else {
+		c.conn, err = c.dialFrom(ctx, addr, laddr, ipv6)
+	}
+	if err != nil {
+		c.Close()
+		return nil, err
+	}
+	if c.stream, err = c.conn.OpenStreamSync(ctx); err != nil {
+		c.Close()
+		return nil, err
+	}
+	c.frames = quicstream.NewFrameReader(c.stream)
+	return c, nil
+}
+
+// quicConn is a game's stream to a QUIC server.
+type quicConn struct {
+	transport *quic.Transport // nil when dialed with quic.DialAddr
+	conn      *quic.Conn
+	stream    *quic.Stream
+	frames    *quicstream.FrameReader
+}
+
+func (c *quicConn) dialFrom(ctx context.Context, addr string, laddr *net.UDPAddr, ipv6 bool) (*quic.Conn, error) {
+	network := udpnet.Network(addr, ipv6)
+	raddr, err := net.ResolveUDPAddr(network, addr)
+	if err != nil {
+		return nil, err
+	}
+	udp, err := net.ListenUDP(network, laddr)
+	if err != nil {
+		return nil, err
+	}
+	c.transport = &quic.Transport{Conn: udp}
+	return c.transport.Dial(ctx, raddr, quicstream.ClientTLSConfig(), nil)
+}
+
+func (c *quicConn) Read(b []byte) (int, error) {
+	payload, err := c.frames.ReadFrame()
+	if err != nil {
+		return 0, err
+	}
+	return copy(b, payload), nil
+}
+
+func (c *quicConn) Write(b []byte) (int, error) {
+	if err := quicstream.WriteFrame(c.stream, b); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+func (c *quicConn) Close() error {
+	if c.conn != nil {
+		c.conn.CloseWithError(0, "")
+	}
+	if c.transport != nil {
+		c.transport.Close()
+		c.transport.Conn.Close()
+	}
+	return nil
+}
+
+func (c *quicConn) LocalAddr() net.Addr                { return c.conn.LocalAddr() }
+func (c *quicConn) RemoteAddr() net.Addr               { return c.conn.RemoteAddr() }
+func (c *quicConn) SetDeadline(t time.Time) error      { return c.stream.SetDeadline(t) }
+func (c *quicConn) SetReadDeadline(t time.Time) error  { return c.stream.SetReadDeadline(t) }
+func (c *quicConn) SetWriteDeadline(t time.Time) error { return c.stream.SetWriteDeadline(t) }
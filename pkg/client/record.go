@@ -0,0 +1,123 @@
+package client
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// GameRecorder writes a game in a PGN-like text format:
+//
+//	[Seed "3"]
+//	[Date "2021.04.02"]
+//	[Client "client"]
+//	[Server "127.0.0.1:41600"]
+//	[Board "[3 4 5]"]
+//
+//	1. (0, 2) -> [1 4 5] [nimsum=0]
+//	2. (1, 1) -> [1 3 5] [nimsum=7]
+//	...
+//	Result: client wins
+//
+// Moves alternate between the client, who always moves first, and the server.
+// A nil *GameRecorder records nothing.
+type GameRecorder struct {
+	w      *bufio.Writer
+	header []string
+	moves  int
+}
+
+func NewGameRecorder(w io.Writer, seed int8, client, server string) *GameRecorder {
+	return &GameRecorder{
+		w: bufio.NewWriter(w),
+		header: []string{
+			fmt.Sprintf("[Seed %q]", strconv.Itoa(int(seed))),
+			fmt.Sprintf("[Date %q]", time.Now().Format("2006.01.02")),
+			fmt.Sprintf("[Client %q]", client),
+			fmt.Sprintf("[Server %q]", server),
+		},
+	}
+}
+
+// Start writes the header, ending with the initial board.
+func (g *GameRecorder) Start(board []uint8) {
+	if g == nil {
+		return
+	}
+	for _, line := range g.header {
+		fmt.Fprintln(g.w, line)
+	}
+	fmt.Fprintf(g.w, "[Board %q]\n\n", fmt.Sprint(board))
+}
+
+func (g *GameRecorder) Move(move StateMoveMessage) {
+	if g == nil {
+		return
+	}
+	g.moves++
+	fmt.Fprintf(g.w, "%d. (%d, %d) -> %v [nimsum=%d]\n",
+		g.moves, move.MoveRow, move.MoveCount, move.GameState, boardNimSum(move.GameState))
+}
+
+// Result writes the final line and flushes the record.
+func (g *GameRecorder) Result(winner string) error {
+	if g == nil {
+		return nil
+	}
+	fmt.Fprintf(g.w, "Result: %s wins\n", winner)
+	return g.w.Flush()
+}
+
+var recordMoveLine = regexp.MustCompile(`^(\d+)\. \((-?\d+), (-?\d+)\) -> \[([0-9 ]*)\] \[nimsum=(\d+)\]$`)
+
+// ParseGameRecord reads back the moves of a record written by GameRecorder.
+func ParseGameRecord(r io.Reader) ([]StateMoveMessage, error) {
+	var moves []StateMoveMessage
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "[") || strings.HasPrefix(text, "Result:") {
+			continue
+		}
+		m := recordMoveLine.FindStringSubmatch(text)
+		if m == nil {
+			return nil, fmt.Errorf("line %d: malformed move %q", line, text)
+		}
+		if n, _ := strconv.Atoi(m[1]); n != len(moves)+1 {
+			return nil, fmt.Errorf("line %d: expected move %d, found %d", line, len(moves)+1, n)
+		}
+		row, err := strconv.ParseInt(m[2], 10, 8)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: bad row: %v", line, err)
+		}
+		count, err := strconv.ParseInt(m[3], 10, 8)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: bad count: %v", line, err)
+		}
+		board := []uint8{}
+		for _, f := range strings.Fields(m[4]) {
+			v, err := strconv.ParseUint(f, 10, 8)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: bad board: %v", line, err)
+			}
+			board = append(board, uint8(v))
+		}
+		if sum, _ := strconv.Atoi(m[5]); sum != int(boardNimSum(board)) {
+			return nil, fmt.Errorf("line %d: nimsum %d does not match board %v", line, sum, board)
+		}
+		moves = append(moves, StateMoveMessage{GameState: board, MoveRow: int8(row), MoveCount: int8(count)})
+	}
+	return moves, scanner.Err()
+}
+
+func boardNimSum(board []uint8) uint8 {
+	var sum uint8
+	for _, v := range board {
+		sum ^= v
+	}
+	return sum
+}
@@ -0,0 +1,109 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"nimgame/pkg/nim"
+)
+
+// simulateGame plays decideMove against takeOne from board, returning every
+// move in order and the winner.
+func simulateGame(board []uint8) ([]StateMoveMessage, string) {
+	state := make([]uint8, len(board))
+	copy(state, board)
+	var moves []StateMoveMessage
+	for {
+		move, _ := decideMove(nim.Optimal{}, state)
+		copy(state, move.GameState)
+		moves = append(moves, move)
+		if isWinState(state) {
+			return moves, "client"
+		}
+		next := make([]uint8, len(state))
+		copy(next, state)
+		reply := takeOne(next)
+		copy(state, reply.GameState)
+		moves = append(moves, reply)
+		if isWinState(state) {
+			return moves, "server"
+		}
+	}
+}
+
+func TestGameRecordRoundTrip(t *testing.T) {
+	board := []uint8{3, 4, 5, 6}
+	moves, winner := simulateGame(board)
+
+	var buf bytes.Buffer
+	rec := NewGameRecorder(&buf, 1, "client", "127.0.0.1:41600")
+	rec.Start(board)
+	for _, m := range moves {
+		rec.Move(m)
+	}
+	if err := rec.Result(winner); err != nil {
+		t.Fatalf("writing record: %v\n", err)
+	}
+	t.Logf("Record:\n%s", buf.String())
+
+	if !strings.Contains(buf.String(), "Result: client wins") {
+		t.Errorf("record is missing the result line\n")
+	}
+	parsed, err := ParseGameRecord(&buf)
+	if err != nil {
+		t.Fatalf("parsing record: %v\n", err)
+	}
+	if len(parsed) != len(moves) {
+		t.Fatalf("expected %d moves, parsed %d\n", len(moves), len(parsed))
+	}
+	for i := range moves {
+		if parsed[i].MoveRow != moves[i].MoveRow || parsed[i].MoveCount != moves[i].MoveCount ||
+			!bytes.Equal(parsed[i].GameState, moves[i].GameState) {
+			t.Errorf("move %d: recorded %v, parsed %v\n", i+1, moves[i], parsed[i])
+		}
+	}
+	if !isWinState(parsed[len(parsed)-1].GameState) {
+		t.Errorf("final board should be empty: %v\n", parsed[len(parsed)-1].GameState)
+	}
+}
+
+func TestRecordHarnessGame(t *testing.T) {
+	board := []uint8{3, 4, 5, 6}
+	expected, _ := simulateGame(board)
+
+	h := &harnessServer{Board: board}
+	sess := newTestSession(t, &ClientConfig{}, h.start(t))
+	var buf bytes.Buffer
+	sess.record = NewGameRecorder(&buf, 1, "client", "harness")
+	if _, err := sess.Play(context.Background()); err != nil {
+		t.Fatalf("game failed: %v\n", err)
+	}
+
+	parsed, err := ParseGameRecord(&buf)
+	if err != nil {
+		t.Fatalf("parsing record: %v\n", err)
+	}
+	if len(parsed) != len(expected) {
+		t.Errorf("expected %d moves, parsed %d\n", len(expected), len(parsed))
+	}
+	if final := parsed[len(parsed)-1].GameState; !isWinState(final) {
+		t.Errorf("final board should be empty: %v\n", final)
+	}
+}
+
+func TestParseGameRecordErrors(t *testing.T) {
+	records := []string{
+		"1. (0, 1) -> [2 3] [nimsum=0]\n",                                // wrong nimsum
+		"2. (0, 1) -> [2 3] [nimsum=1]\n",                                // out of sequence
+		"1. 0 1 -> [2 3]\n",                                              // malformed
+		"1. (0, 1) -> [2 300] [nimsum=302]\n",                            // coin count overflow
+		"1. (0, 1) -> [2 3] [nimsum=1]\n2. (1, 1) -> [2 2] [nimsum=3]\n", // bad second nimsum
+	}
+	for _, r := range records {
+		if _, err := ParseGameRecord(strings.NewReader(r)); err == nil {
+			t.Errorf("expected an error parsing %q\n", r)
+		}
+	}
+}
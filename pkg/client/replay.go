@@ -0,0 +1,136 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"nimgame/pkg/nimerr"
+)
+
+// ErrTranscriptDiverged is wrapped by a *ReplayDivergence.
+var ErrTranscriptDiverged = nimerr.New(nimerr.ErrGameState, "server diverged from the recorded game")
+
+// ReplayDivergence is where a replayed game first left its transcript: the
+// board the server was recorded leaving with move number Move, move 0
+// being the initial board, and the one it left this time. Either is nil
+// where that game had ended, by a concession or the last coin.
+type ReplayDivergence struct {
+	Move     int
+	Recorded []int
+	Received []int
+}
+
+func (d *ReplayDivergence) Error() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%v at move %d: recorded %v, got %v", ErrTranscriptDiverged, d.Move, describeBoard(d.Recorded), describeBoard(d.Received))
+	for i := 0; i < max(len(d.Recorded), len(d.Received)); i++ {
+		if i < len(d.Recorded) && i < len(d.Received) && d.Recorded[i] == d.Received[i] {
+			continue
+		}
+		fmt.Fprintf(&sb, "\n  row %d: -%v +%v", i, rowOf(d.Recorded, i), rowOf(d.Received, i))
+	}
+	return sb.String()
+}
+
+func (d *ReplayDivergence) Unwrap() error {
+	return ErrTranscriptDiverged
+}
+
+func describeBoard(board []int) string {
+	if board == nil {
+		return "the end of the game"
+	}
+	return fmt.Sprint(board)
+}
+
+func rowOf(board []int, i int) string {
+	if i >= len(board) {
+		return "none"
+	}
+	return fmt.Sprint(board[i])
+}
+
+// ReadTranscript reads a transcript written by Transcript.WriteFile.
+func ReadTranscript(path string) (*Transcript, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var t Transcript
+	if err := json.Unmarshal(data, &t); err != nil {
+		return nil, fmt.Errorf("reading transcript %s: %w", path, err)
+	}
+	return &t, nil
+}
+
+// Replay plays the client's moves from t against config's servers, move
+// for move, to reproduce the recorded game. Once the server's replies stop
+// matching the recording the game is abandoned and the error is the
+// *ReplayDivergence. opts are applied as for NewSession, after those
+// replaying the game.
+func Replay(ctx context.Context, config ClientConfig, t *Transcript, opts ...Option) (Result, error) {
+	// extra moves asked for in speculation would be taken off the script
+	config.SpeculativeUpdate = false
+	script := &replayStrategy{t: t}
+	opts = append([]Option{WithSeed(t.Seed), WithStrategyName("replay")}, opts...)
+	sess, err := NewSession(config, script, opts...)
+	if err != nil {
+		return Result{}, err
+	}
+	defer sess.Close()
+	result, err := sess.Play(ctx)
+	if script.divergence != nil {
+		return result, script.divergence
+	}
+	if err != nil {
+		return result, err
+	}
+	// the server conceded, or took the last coin, before the recording did
+	if script.next < len(t.Moves) {
+		recorded := t.Moves[script.next-1]
+		return result, &ReplayDivergence{Move: script.next, Recorded: recorded.Board, Received: lastBoardOf(recorded, result)}
+	}
+	return result, nil
+}
+
+// lastBoardOf is what the live game ended on, for reporting: nil after a
+// concession, an empty board otherwise.
+func lastBoardOf(recorded TranscriptMove, result Result) []int {
+	if result.Winner == "client" {
+		return nil
+	}
+	return make([]int, len(recorded.Board))
+}
+
+// replayStrategy plays the client's moves in t in turn. Before each it
+// checks it is asked to move on the recorded board, noting the first
+// board that isn't and then making no move, which ends the game.
+type replayStrategy struct {
+	t          *Transcript
+	next       int // index into t.Moves of the client's next move
+	divergence *ReplayDivergence
+}
+
+func (r *replayStrategy) Move(board []uint8) (int, uint8) {
+	got := boardInts(board)
+	var recorded []int // nil once the recorded game is over
+	switch {
+	case r.next == 0:
+		recorded = r.t.InitialBoard
+	case r.next < len(r.t.Moves):
+		recorded = r.t.Moves[r.next-1].Board
+	}
+	if recorded != nil && r.next < len(r.t.Moves) && r.t.Moves[r.next].Player == "client" && slices.Equal(got, recorded) {
+		move := r.t.Moves[r.next]
+		r.next += 2 // past the server's reply
+		return move.MoveRow, uint8(move.MoveCount)
+	}
+	if r.divergence == nil {
+		r.divergence = &ReplayDivergence{Move: r.next, Recorded: recorded, Received: got}
+	}
+	return -1, 0
+}
@@ -0,0 +1,63 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func readGolden(t *testing.T) *Transcript {
+	transcript, err := ReadTranscript("testdata/golden_transcript.json")
+	if err != nil {
+		t.Fatalf("reading golden transcript: %v\n", err)
+	}
+	return transcript
+}
+
+// replayAgainst replays the golden transcript, recorded against a harness
+// on {3, 4, 5}, against h.
+func replayAgainst(t *testing.T, h *harnessServer) (Result, error) {
+	config := ClientConfig{ClientAddress: "127.0.0.1:0", NimServerAddresses: []string{h.start(t).String()}, RetryBaseMs: 10, RetryCapMs: 40}
+	return Replay(context.Background(), config, readGolden(t))
+}
+
+func TestReplayGolden(t *testing.T) {
+	result, err := replayAgainst(t, &harnessServer{Board: []uint8{3, 4, 5}})
+	if err != nil {
+		t.Fatalf("replay diverged: %v\n", err)
+	}
+	if result.Winner != "client" || result.ClientMoves != 6 || result.ServerMoves != 5 {
+		t.Errorf("replay ended %+v, expected the recorded 6 client moves to 5 and a client win\n", result)
+	}
+}
+
+func TestReplayDivergence(t *testing.T) {
+	tests := []struct {
+		name     string
+		h        *harnessServer
+		move     int
+		recorded []int
+		received []int
+	}{
+		{"initial board", &harnessServer{Board: []uint8{3, 4, 6}}, 0, []int{3, 4, 5}, []int{3, 4, 6}},
+		{"different move", &harnessServer{Board: []uint8{3, 4, 5}, FromLast: true}, 2, []int{0, 4, 5}, []int{1, 4, 4}},
+		{"early concession", &harnessServer{Board: []uint8{3, 4, 5}, ConcedeAfter: 3}, 6, []int{0, 2, 3}, nil},
+	}
+	for _, test := range tests {
+		_, err := replayAgainst(t, test.h)
+		var d *ReplayDivergence
+		if !errors.As(err, &d) || !errors.Is(err, ErrTranscriptDiverged) {
+			t.Errorf("%v: expected a divergence, got %v\n", test.name, err)
+			continue
+		}
+		if d.Move != test.move || !slices.Equal(d.Recorded, test.recorded) || !slices.Equal(d.Received, test.received) {
+			t.Errorf("%v: diverged at move %d from %v to %v, expected move %d from %v to %v\n", test.name,
+				d.Move, d.Recorded, d.Received, test.move, test.recorded, test.received)
+		}
+		if !strings.Contains(err.Error(), "row ") {
+			t.Errorf("%v: no diff of the rows in %q\n", test.name, err)
+		}
+	}
+}
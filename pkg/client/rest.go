@@ -0,0 +1,118 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"nimgame/pkg/nim"
+	"nimgame/pkg/nimerr"
+)
+
+// ErrAPI is returned, wrapped with the server's reason, for a request the
+// REST API server refuses.
+var ErrAPI = nimerr.New(nimerr.ErrProtocol, "API server refused the request")
+
+// NimClient plays games with the REST API server, cmd/apiserver, at
+// BaseURL. The player always moves first, and the server answers each move
+// in its reply.
+type NimClient struct {
+	BaseURL    string
+	HTTPClient *http.Client // nil means http.DefaultClient
+}
+
+// GameState is a game as the REST API server last reported it.
+type GameState struct {
+	GameID string
+	Board  []uint8
+	IsOver bool
+	Winner string // "player" or "server" once the game is over
+}
+
+// apiGameState is the API server's JSON for a game, of which GameState
+// keeps what a player needs.
+type apiGameState struct {
+	GameID string `json:"game_id"`
+	Board  []int  `json:"board"`
+	Winner string `json:"winner,omitempty"`
+}
+
+type apiMove struct {
+	Row   int `json:"row"`
+	Count int `json:"count"`
+}
+
+type apiError struct {
+	Error string `json:"error"`
+}
+
+// NewGame starts a game on seed's board; odd seeds play the server's
+// optimal strategy.
+func (c *NimClient) NewGame(seed int8) (*GameState, error) {
+	return c.do("POST", "/game", struct {
+		Seed int8 `json:"seed"`
+	}{seed})
+}
+
+// MakeMove takes count coins from row in the game gameID, returning the
+// game after the server's answer, if the move didn't end it.
+func (c *NimClient) MakeMove(gameID string, row int8, count int8) (*GameState, error) {
+	return c.do("POST", "/game/"+url.PathEscape(gameID)+"/move", apiMove{Row: int(row), Count: int(count)})
+}
+
+// GetGame returns the game gameID as it stands.
+func (c *NimClient) GetGame(gameID string) (*GameState, error) {
+	return c.do("GET", "/game/"+url.PathEscape(gameID), nil)
+}
+
+// do sends body, if not nil, as JSON to path and decodes the game replied.
+func (c *NimClient) do(method, path string, body interface{}) (*GameState, error) {
+	var buf bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&buf).Encode(body); err != nil {
+			return nil, err
+		}
+	}
+	req, err := http.NewRequest(method, c.BaseURL+path, &buf)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var reason apiError
+		if json.NewDecoder(resp.Body).Decode(&reason) != nil || reason.Error == "" {
+			reason.Error = resp.Status
+		}
+		return nil, fmt.Errorf("%w: %v %v: %v", ErrAPI, method, path, reason.Error)
+	}
+	var state apiGameState
+	if err := json.NewDecoder(resp.Body).Decode(&state); err != nil {
+		return nil, fmt.Errorf("decoding reply to %v %v: %w", method, path, err)
+	}
+	board := make([]uint8, len(state.Board))
+	for i, coins := range state.Board {
+		board[i] = uint8(coins)
+	}
+	return &GameState{GameID: state.GameID, Board: board, IsOver: state.Winner != "", Winner: state.Winner}, nil
+}
+
+// DecideMove returns the row and count of nim.Optimal's move on state's
+// board.
+func DecideMove(state *GameState) (int8, int8) {
+	row, count := nim.Optimal{}.Move(state.Board)
+	return int8(row), int8(count)
+}
@@ -0,0 +1,114 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"nimgame/pkg/nim"
+)
+
+// apiHarness serves the REST API as cmd/apiserver does, playing nim.Basic
+// on each seed's board.
+type apiHarness struct {
+	games map[string][]uint8
+	ends  map[string]string // the winner of each game over
+}
+
+func (h *apiHarness) start(t *testing.T) string {
+	h.games, h.ends = map[string][]uint8{}, map[string]string{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /game", func(w http.ResponseWriter, r *http.Request) {
+		var body struct{ Seed int8 }
+		json.NewDecoder(r.Body).Decode(&body)
+		id := strconv.Itoa(len(h.games) + 1)
+		h.games[id] = nim.GenerateBoard(int64(body.Seed))
+		h.reply(w, id)
+	})
+	mux.HandleFunc("GET /game/{id}", func(w http.ResponseWriter, r *http.Request) {
+		h.reply(w, r.PathValue("id"))
+	})
+	mux.HandleFunc("POST /game/{id}/move", func(w http.ResponseWriter, r *http.Request) {
+		id := r.PathValue("id")
+		var move apiMove
+		json.NewDecoder(r.Body).Decode(&move)
+		board, ok := h.games[id]
+		after := append([]uint8(nil), board...)
+		if ok && move.Row >= 0 && move.Row < len(after) {
+			after[move.Row] -= uint8(move.Count)
+		}
+		if !ok || h.ends[id] != "" || !nim.ValidMove(board, after, move.Row, move.Count) {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(apiError{Error: "illegal move"})
+			return
+		}
+		if isWinState(after) {
+			h.ends[id] = "player"
+		} else {
+			row, count := nim.Basic{}.Move(after)
+			after[row] -= count
+			if isWinState(after) {
+				h.ends[id] = "server"
+			}
+		}
+		h.games[id] = after
+		h.reply(w, id)
+	})
+	ts := httptest.NewServer(mux)
+	t.Cleanup(ts.Close)
+	return ts.URL
+}
+
+func (h *apiHarness) reply(w http.ResponseWriter, id string) {
+	board, ok := h.games[id]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(apiError{Error: "no such game"})
+		return
+	}
+	state := apiGameState{GameID: id, Winner: h.ends[id]}
+	for _, coins := range board {
+		state.Board = append(state.Board, int(coins))
+	}
+	json.NewEncoder(w).Encode(state)
+}
+
+// TestRESTGame plays a game through the REST API by DecideMove, which wins
+// it from a board with a non-zero nim sum.
+func TestRESTGame(t *testing.T) {
+	c := &NimClient{BaseURL: (&apiHarness{}).start(t)}
+	state, err := c.NewGame(4)
+	if err != nil {
+		t.Fatalf("starting a game: %v\n", err)
+	}
+	if !bytes.Equal(state.Board, nim.GenerateBoard(4)) || state.IsOver {
+		t.Fatalf("new game is %+v, expected seed 4's board\n", state)
+	}
+	for moves := 0; !state.IsOver; moves++ {
+		if moves > 100 {
+			t.Fatalf("game still going after %d moves: %+v\n", moves, state)
+		}
+		row, count := DecideMove(state)
+		if state, err = c.MakeMove(state.GameID, row, count); err != nil {
+			t.Fatalf("taking %d from row %d: %v\n", count, row, err)
+		}
+	}
+	if state.Winner != "player" || !isWinState(state.Board) {
+		t.Errorf("game ended as %+v, expected the player to win\n", state)
+	}
+
+	got, err := c.GetGame(state.GameID)
+	if err != nil || got.Winner != "player" || !got.IsOver {
+		t.Errorf("GetGame returned %+v, %v, expected the game won by the player\n", got, err)
+	}
+	if _, err := c.MakeMove(state.GameID, 0, 1); !errors.Is(err, ErrAPI) {
+		t.Errorf("moving in a game over returned %v, expected ErrAPI\n", err)
+	}
+	if _, err := c.GetGame("missing"); !errors.Is(err, ErrAPI) {
+		t.Errorf("getting a missing game returned %v, expected ErrAPI\n", err)
+	}
+}
@@ -0,0 +1,281 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"os"
+	"time"
+
+	"nimgame/pkg/configfile"
+	"nimgame/pkg/nim"
+)
+
+// syncMoveRow marks the message asking the server for its last move, sent
+// in place of GameStart when resuming a game.
+const syncMoveRow = -3
+
+// sessionResumeMoveRow marks the message, carrying the game's GameID,
+// asking the server to carry on the game with the address it is sent from,
+// once the socket the game was played from has failed.
+const sessionResumeMoveRow = -13
+
+// ResumeState is the game so far, saved WithResume after every accepted
+// exchange so a client restarted after a crash can pick the game up where
+// it left off. It is always the client's turn on Board, since it is saved
+// once the server has replied.
+type ResumeState struct {
+	Seed int8 `json:"seed"`
+	// the server knows the game by the client's address, so a resumed
+	// game is played from the same one
+	LocalAddress string           `json:"local_address"`
+	Server       string           `json:"server"`
+	InitialBoard []int            `json:"initial_board"`
+	Board        []int            `json:"board"`
+	History      []resumeExchange `json:"history"`
+	// the history lacks an exchange lost in a crash, so the game can't
+	// fail over to another server
+	Unreplayable bool `json:"unreplayable,omitempty"`
+}
+
+type resumeExchange struct {
+	Move  resumeMove `json:"move"`
+	Reply resumeMove `json:"reply"`
+}
+
+type resumeMove struct {
+	Row   int   `json:"row"`
+	Count int   `json:"count"`
+	Board []int `json:"board"`
+}
+
+// LoadResumeState reads the state saved at path, returning nil if there is
+// none, as when the last game finished.
+func LoadResumeState(path string) (*ResumeState, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var state ResumeState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("reading resume state %s: %w", path, err)
+	}
+	if len(state.Board) == 0 || len(state.Board) != len(state.InitialBoard) {
+		return nil, fmt.Errorf("reading resume state %s: board %v doesn't fit initial board %v", path, state.Board, state.InitialBoard)
+	}
+	return &state, nil
+}
+
+// WithResume saves the game to path after every exchange, removing it once
+// the game ends. If state is not nil, the game it holds is resumed rather
+// than a new one started: it is played for the saved seed from the saved
+// address, and the server is asked for its last move, which is ours to
+// answer.
+func WithResume(path string, state *ResumeState) Option {
+	return func(s *Session) {
+		s.resumePath, s.resume = path, state
+		if state == nil {
+			return
+		}
+		s.seed, s.unreplayable = state.Seed, state.Unreplayable
+		s.initial = boardBytes(state.InitialBoard)
+		s.history = nil
+		for _, ex := range state.History {
+			s.history = append(s.history, exchange{ex.Move.message(), ex.Reply.message()})
+		}
+	}
+}
+
+func (m resumeMove) message() StateMoveMessage {
+	return StateMoveMessage{GameState: boardBytes(m.Board), MoveRow: int8(m.Row), MoveCount: int8(m.Count)}
+}
+
+func newResumeMove(move StateMoveMessage) resumeMove {
+	return resumeMove{Row: int(move.MoveRow), Count: int(move.MoveCount), Board: boardInts(move.GameState)}
+}
+
+func boardBytes(board []int) []uint8 {
+	b := make([]uint8, len(board))
+	for i, v := range board {
+		b[i] = uint8(v)
+	}
+	return b
+}
+
+// resync asks the current server where the resumed game stands and
+// returns the board to move on. If our last move reached the server before
+// the crash the board is a move further on than the one saved, and if it
+// ended the game the winner is returned instead.
+func (s *Session) resync(ctx context.Context) (state []uint8, winner string, err error) {
+	saved := boardBytes(s.resume.Board)
+	sendMove := StateMoveMessage{GameState: nil, MoveRow: syncMoveRow}
+	sendMove.TracingServerAddr = s.config.TracingServerAddress
+	var recvMove StateMoveMessage
+	reachable := func(move *StateMoveMessage) (bool, error) {
+		return isConcession(move) || takenFrom(saved, move.GameState), nil
+	}
+	if err := s.sendAndAwait(ctx, &sendMove, &recvMove, reachable); err != nil {
+		return nil, "", err
+	}
+	switch {
+	case isConcession(&recvMove):
+		return nil, "client", nil
+	case isWinState(recvMove.GameState):
+		return nil, s.emptiedBy("server"), nil
+	}
+	if !bytes.Equal(recvMove.GameState, saved) {
+		// the exchange that got us here is lost, so the game can no
+		// longer be replayed against another server
+		s.logger().Warn("resumed a move past the saved board", "saved", saved, "server", recvMove.GameState)
+		s.unreplayable = true
+	}
+	return append([]uint8(nil), recvMove.GameState...), "", nil
+}
+
+// takenFrom reports whether after is a board of before's rows, each
+// holding no more coins than before, as any later board in the game does.
+func takenFrom(before, after []uint8) bool {
+	if len(after) != len(before) {
+		return false
+	}
+	for i := range before {
+		if after[i] > before[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// saveResume saves the game, on state after the server's latest reply, to
+// the WithResume path. Failing to only loses the chance to resume, so it is
+// logged rather than ending the game.
+func (s *Session) saveResume(state []uint8) {
+	// boards of Lasker's nim don't keep their rows, nor do Wythoff's game's
+	// moves from both heaps have one, as resuming needs
+	if s.resumePath == "" || s.variant != "" {
+		return
+	}
+	saved := ResumeState{
+		Seed:         s.seed,
+		LocalAddress: s.conn.LocalAddr().String(),
+		Server:       s.servers[s.server],
+		InitialBoard: boardInts(s.initial),
+		Board:        boardInts(state),
+		Unreplayable: s.unreplayable,
+	}
+	for _, ex := range s.history {
+		saved.History = append(saved.History, resumeExchange{newResumeMove(ex.move), newResumeMove(ex.reply)})
+	}
+	if err := configfile.WriteJSON(s.resumePath, saved); err != nil {
+		s.logger().Warn("saving resume state", "path", s.resumePath, "err", err)
+	}
+}
+
+// socketFailed reports whether err, from reading the socket, means the
+// socket is broken, as once closed, after an ICMP unreachable or when the
+// server hangs up a TCP connection, rather than that the reply is late or
+// malformed.
+func socketFailed(err error) bool {
+	var opErr *net.OpError
+	return errors.Is(err, net.ErrClosed) || errors.Is(err, io.EOF) || (errors.As(err, &opErr) && !opErr.Timeout())
+}
+
+// reconnect replaces the failed socket to the current server, redialing
+// until it can or the retries run out, and asks the server to carry on the
+// game, if it has started, from the new socket's address. The move awaiting
+// a reply is then retransmitted on it: a server that had it answers with
+// the reply it already made.
+func (s *Session) reconnect(ctx context.Context) error {
+	s.reconnecting = true
+	defer func() { s.reconnecting = false }()
+	s.conn.Close()
+	if err := s.redial(ctx); err != nil {
+		return err
+	}
+	if s.gameID == "" {
+		return nil
+	}
+	from := s.initial
+	if len(s.history) > 0 {
+		from = s.history[len(s.history)-1].reply.GameState
+	}
+	_, err := s.resumeGame(ctx, from)
+	return err
+}
+
+// redial dials the current server, retrying until it can or the retries
+// run out.
+func (s *Session) redial(ctx context.Context) error {
+	addr := s.servers[s.server]
+	for attempt := 0; ; attempt++ {
+		conn, err := s.dial(addr)
+		if err == nil {
+			s.conn = conn
+			break
+		}
+		if attempt >= s.config.maxRetries() {
+			return fmt.Errorf("%w: redialing %v: %w", ErrNoReply, addr, err)
+		}
+		s.logger().Warn("couldn't redial nim server", "server", addr, "err", err)
+		timer := time.NewTimer(s.retry.Next())
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("%w: %w", ErrCanceled, context.Cause(ctx))
+		}
+	}
+	s.watch()
+	return nil
+}
+
+// resumeGame asks the server to carry on s.gameID, last seen on the board
+// from, from the socket's address, and returns where the server says the
+// game stands.
+func (s *Session) resumeGame(ctx context.Context, from []uint8) (StateMoveMessage, error) {
+	sendMove := StateMoveMessage{GameState: nil, MoveRow: sessionResumeMoveRow, GameID: s.gameID}
+	sendMove.TracingServerAddr = s.config.TracingServerAddress
+	var recvMove StateMoveMessage
+	sameGame := func(move *StateMoveMessage) (bool, error) {
+		return move.GameID == s.gameID && (isConcession(move) || takenFrom(from, move.GameState)), nil
+	}
+	if err := s.sendAndAwait(ctx, &sendMove, &recvMove, sameGame); err != nil {
+		return StateMoveMessage{}, err
+	}
+	s.logger().Info("resumed game on a new socket", "game", s.gameID, "local", s.conn.LocalAddr(), "board", recvMove.GameState)
+	return recvMove, nil
+}
+
+// ResumeSession carries on the game gameID, last seen at lastState, once
+// the socket it was played from has failed: it redials config's server
+// from ClientAddress, retrying as a Session does, asks the server to carry
+// on the game from there and sets lastState to where the server says the
+// game stands, a concession if the client has won. The new socket is
+// closed on return; a Session from the same ClientAddress plays on.
+func ResumeSession(config *ClientConfig, lastState *StateMoveMessage, gameID string) error {
+	// the strategy is never asked for a move
+	s, err := NewSession(*config, nim.Optimal{})
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+	s.gameID = gameID
+	s.reconnecting = true // one failed socket is the caller's to handle
+	ctx := context.Background()
+	if err := s.redial(ctx); err != nil {
+		return err
+	}
+	reply, err := s.resumeGame(ctx, lastState.GameState)
+	if err != nil {
+		return err
+	}
+	*lastState = reply
+	return nil
+}
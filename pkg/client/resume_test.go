@@ -0,0 +1,185 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"nimgame/pkg/nim"
+)
+
+// crashAfter plays a game against h, saving it to path, and kills it once
+// the server has made moves moves. It returns the state left behind.
+func crashAfter(t *testing.T, h *harnessServer, path string, moves int) *ResumeState {
+	raddr := h.start(t)
+	sess := newTestSession(t, &ClientConfig{}, raddr)
+	WithResume(path, nil)(sess)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	WithMoveHook(func(e MoveEvent) {
+		if e.Player == "server" {
+			if moves--; moves == 0 {
+				cancel()
+			}
+		}
+	})(sess)
+	if _, err := sess.Play(ctx); !errors.Is(err, ErrCanceled) {
+		t.Fatalf("expected the game to be killed, got %v\n", err)
+	}
+	sess.Close() // frees the address for the resumed game
+	state, err := LoadResumeState(path)
+	if err != nil || state == nil {
+		t.Fatalf("no state to resume: %v\n", err)
+	}
+	return state
+}
+
+// resume plays the game saved in state to the end against h.
+func resume(t *testing.T, h *harnessServer, path string, state *ResumeState) (*Session, Result) {
+	sess := newTestSession(t, &ClientConfig{ClientAddress: state.LocalAddress}, h.conn.LocalAddr().(*net.UDPAddr))
+	WithResume(path, state)(sess)
+	result, err := sess.Play(context.Background())
+	if err != nil {
+		t.Fatalf("resumed game failed: %v\n", err)
+	}
+	return sess, result
+}
+
+func TestResumeAfterCrash(t *testing.T) {
+	h := &harnessServer{Board: []uint8{5, 5, 5}}
+	path := filepath.Join(t.TempDir(), "state.json")
+	state := crashAfter(t, h, path, 2)
+	if len(state.History) != 2 || !slices.Equal(state.Board, state.History[1].Reply.Board) {
+		t.Errorf("saved %d exchanges ending on %v, expected 2 ending on the server's move\n", len(state.History), state.Board)
+	}
+
+	_, result := resume(t, h, path, state)
+	if result.Winner != "client" {
+		t.Errorf("resumed game won by %q, expected the client\n", result.Winner)
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.starts != 1 {
+		t.Errorf("harness saw %d GameStarts, expected the resumed game to sync instead\n", h.starts)
+	}
+	if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("resume state left behind after the game ended: %v\n", err)
+	}
+	if state, err := LoadResumeState(path); state != nil || err != nil {
+		t.Errorf("expected nothing to resume, got %v, %v\n", state, err)
+	}
+}
+
+func TestResumePastSavedBoard(t *testing.T) {
+	h := &harnessServer{Board: []uint8{5, 5, 5}}
+	path := filepath.Join(t.TempDir(), "state.json")
+	state := crashAfter(t, h, path, 2)
+	// as if the client crashed after sending its second move but before
+	// saving the reply
+	state.History = state.History[:1]
+	state.Board = state.History[0].Reply.Board
+
+	sess, result := resume(t, h, path, state)
+	if result.Winner != "client" {
+		t.Errorf("resumed game won by %q, expected the client\n", result.Winner)
+	}
+	if !sess.unreplayable {
+		t.Errorf("game resumed past its saved board can still fail over\n")
+	}
+}
+
+func TestReconnectAfterSocketFailure(t *testing.T) {
+	h := &harnessServer{Board: []uint8{5, 5, 5}, GameID: "game-1"}
+	sess := newTestSession(t, &ClientConfig{}, h.start(t))
+	dials, dial := 0, sess.dial
+	sess.dial = func(addr string) (net.Conn, error) {
+		if dials++; dials == 2 {
+			return nil, errors.New("network is unreachable")
+		}
+		return dial(addr)
+	}
+	var events []MoveEvent
+	WithMoveHook(func(e MoveEvent) {
+		e.Board = slices.Clone(e.Board)
+		events = append(events, e)
+		if len(events) == 4 {
+			// the socket fails after the server's second move
+			sess.conn.Close()
+		}
+	})(sess)
+
+	result, err := sess.Play(context.Background())
+	if err != nil {
+		t.Fatalf("game failed after reconnecting: %v\n", err)
+	}
+	if result.Winner != "client" {
+		t.Errorf("game won by %q, expected the client\n", result.Winner)
+	}
+	if dials != 3 {
+		t.Errorf("dialed %d times, expected a failed redial then one that worked\n", dials)
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if !slices.Equal(h.resumes, []string{"game-1"}) || h.starts != 1 {
+		t.Errorf("harness saw %d GameStarts and resumes %q, expected the game resumed once\n", h.starts, h.resumes)
+	}
+	board := h.Board
+	for _, e := range events {
+		if !nim.ValidMove(board, e.Board, e.Row, e.Count) {
+			t.Fatalf("%v took %d from row %d, from %v to %v\n", e.Player, e.Count, e.Row, board, e.Board)
+		}
+		board = e.Board
+	}
+}
+
+// TestResumeSession resumes a game from an address that can't be dialed
+// from at first, as when the old socket has yet to let it go.
+func TestResumeSession(t *testing.T) {
+	h := &harnessServer{Board: []uint8{5, 5, 5}, GameID: "game-1"}
+	h.last = StateMoveMessage{GameState: []uint8{4, 3, 5}, MoveRow: 1, MoveCount: 2, GameID: "game-1"}
+	raddr := h.start(t)
+	held, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatalf("holding an address: %v\n", err)
+	}
+	config := &ClientConfig{
+		ClientAddress:      held.LocalAddr().String(),
+		NimServerAddresses: []string{raddr.String()},
+		RetryBaseMs:        10,
+		RetryCapMs:         40,
+	}
+	time.AfterFunc(100*time.Millisecond, func() { held.Close() })
+
+	lastState := StateMoveMessage{GameState: []uint8{4, 5, 5}, MoveRow: 0, MoveCount: 1}
+	if err := ResumeSession(config, &lastState, "game-1"); err != nil {
+		t.Fatalf("resuming: %v\n", err)
+	}
+	if !slices.Equal(lastState.GameState, []uint8{4, 3, 5}) || lastState.MoveRow != 1 || lastState.MoveCount != 2 {
+		t.Errorf("expected the server's last move, got %+v\n", lastState)
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if !slices.Equal(h.resumes, []string{"game-1"}) {
+		t.Errorf("harness saw resumes %q, expected game-1 once\n", h.resumes)
+	}
+}
+
+func TestResumeSessionWrongGame(t *testing.T) {
+	h := &harnessServer{Board: []uint8{5, 5, 5}, GameID: "game-1", last: StateMoveMessage{GameState: []uint8{4, 5, 5}}}
+	config := &ClientConfig{
+		ClientAddress:      "127.0.0.1:0",
+		NimServerAddresses: []string{h.start(t).String()},
+		RetryBaseMs:        10,
+		RetryCapMs:         40,
+		MaxRetries:         2,
+	}
+	lastState := StateMoveMessage{GameState: []uint8{5, 5, 5}}
+	if err := ResumeSession(config, &lastState, "game-2"); !errors.Is(err, ErrNoReply) {
+		t.Errorf("expected no reply resuming a game the server doesn't have, got %v\n", err)
+	}
+}
@@ -0,0 +1,78 @@
+package client
+
+import (
+	"hash/fnv"
+	"sort"
+	"strconv"
+)
+
+// virtualNodes is how many points each server gets on the hash ring.
+const virtualNodes = 100
+
+// ConsistentHash picks the server for key from a ring holding virtualNodes
+// points per server, so adding or removing a server only moves the keys on
+// that server's arcs. It returns "" when there are no servers.
+func ConsistentHash(key string, servers []string) string {
+	type point struct {
+		hash   uint64
+		server string
+	}
+	ring := make([]point, 0, len(servers)*virtualNodes)
+	for _, server := range servers {
+		for i := 0; i < virtualNodes; i++ {
+			ring = append(ring, point{ringHash(server + "#" + strconv.Itoa(i)), server})
+		}
+	}
+	if len(ring) == 0 {
+		return ""
+	}
+	sort.Slice(ring, func(i, j int) bool { return ring[i].hash < ring[j].hash })
+
+	h := ringHash(key)
+	i := sort.Search(len(ring), func(i int) bool { return ring[i].hash >= h })
+	if i == len(ring) {
+		i = 0
+	}
+	return ring[i].server
+}
+
+func ringHash(s string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(s))
+	// fnv alone clusters similar strings, so spread the bits out
+	x := h.Sum64()
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	return x
+}
+
+// routeServers orders config's servers, and the heartbeat addresses that go
+// with them, to start at the one key hashes to. Failover then carries on
+// down the list, wrapping around.
+func routeServers(config *ClientConfig, key string) []string {
+	servers := config.ServerAddresses()
+	first := ConsistentHash(key, servers)
+	for i, server := range servers {
+		if server != first {
+			continue
+		}
+		n := len(servers)
+		rotated := make([]string, n)
+		var hbeats []string
+		if len(config.FCheckServerAddresses) > 0 {
+			hbeats = make([]string, n)
+		}
+		for j := range rotated {
+			rotated[j] = servers[(i+j)%n]
+			if k := (i + j) % n; hbeats != nil && k < len(config.FCheckServerAddresses) {
+				hbeats[j] = config.FCheckServerAddresses[k]
+			}
+		}
+		if hbeats != nil {
+			config.FCheckServerAddresses = hbeats
+		}
+		return rotated
+	}
+	return servers
+}
@@ -0,0 +1,74 @@
+package client
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+const ringTestKeys = 3000
+
+func assignments(servers []string) []string {
+	assigned := make([]string, ringTestKeys)
+	for i := range assigned {
+		assigned[i] = ConsistentHash("game-"+strconv.Itoa(i), servers)
+	}
+	return assigned
+}
+
+func TestConsistentHashAddServer(t *testing.T) {
+	before := assignments([]string{"a:1", "b:1", "c:1"})
+	after := assignments([]string{"a:1", "b:1", "c:1", "d:1"})
+	moved := 0
+	for i := range before {
+		if before[i] != after[i] {
+			moved++
+			if after[i] != "d:1" {
+				t.Fatalf("key %d moved from %v to %v rather than the new server\n", i, before[i], after[i])
+			}
+		}
+	}
+	if moved > ringTestKeys/3 {
+		t.Errorf("adding a fourth server moved %d of %d keys\n", moved, ringTestKeys)
+	}
+}
+
+func TestConsistentHashRemoveServer(t *testing.T) {
+	before := assignments([]string{"a:1", "b:1", "c:1"})
+	after := assignments([]string{"a:1", "c:1"})
+	moved := 0
+	for i := range before {
+		if before[i] != after[i] {
+			moved++
+			if before[i] != "b:1" {
+				t.Fatalf("key %d moved from %v though its server remained\n", i, before[i])
+			}
+		}
+	}
+	// only b's keys move, and b held about a third of them
+	if moved > ringTestKeys*2/5 {
+		t.Errorf("removing a server moved %d of %d keys\n", moved, ringTestKeys)
+	}
+}
+
+func TestRouteServers(t *testing.T) {
+	config := &ClientConfig{
+		NimServerAddresses:    []string{"a:1", "b:1", "c:1"},
+		FCheckServerAddresses: []string{"a:2", "b:2"},
+	}
+	for seed := 0; seed < 100; seed++ {
+		key := strconv.Itoa(seed)
+		if ConsistentHash(key, config.NimServerAddresses) != "c:1" {
+			continue
+		}
+		servers := routeServers(config, key)
+		if !reflect.DeepEqual(servers, []string{"c:1", "a:1", "b:1"}) {
+			t.Errorf("expected the list rotated to start at c:1, got %v\n", servers)
+		}
+		if !reflect.DeepEqual(config.FCheckServerAddresses, []string{"", "a:2", "b:2"}) {
+			t.Errorf("heartbeat addresses not rotated with their servers: %v\n", config.FCheckServerAddresses)
+		}
+		return
+	}
+	t.Fatalf("no seed hashed to c:1\n")
+}
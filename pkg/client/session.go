@@ -0,0 +1,928 @@
+package client
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"math/rand"
+	"net"
+	"os"
+	"slices"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"nimgame/fcheck"
+	"nimgame/pkg/dtlsgame"
+	"nimgame/pkg/netcond"
+	"nimgame/pkg/nim"
+	"nimgame/pkg/nimerr"
+	"nimgame/pkg/udpnet"
+
+	"github.com/DistributedClocks/tracing"
+	"github.com/pion/dtls/v3"
+)
+
+const defaultMaxRetries = 10
+
+// cheatThreshold is how many replies in a row to one move may be illegal
+// successors, rather than stale copies of earlier boards, before the
+// server is taken to be cheating.
+const cheatThreshold = 3
+
+// Errors that end a game early, wrapped with the details. Each but
+// ErrCanceled is also one of the nimerr kinds.
+var (
+	ErrNoReply        = nimerr.New(nimerr.ErrTransport, "server stopped responding")
+	ErrGameTimeout    = nimerr.New(nimerr.ErrGameState, "game exceeded its maximum duration")
+	ErrAllServersDown = nimerr.New(nimerr.ErrTransport, "all nim servers are down")
+	ErrReplayDiverged = nimerr.New(nimerr.ErrGameState, "replacement server diverged from the game so far")
+	ErrInvalidReply   = nimerr.New(nimerr.ErrProtocol, "server only sent invalid replies")
+	ErrServerCheating = nimerr.New(nimerr.ErrProtocol, "server kept making illegal moves")
+	ErrBoardMismatch  = nimerr.New(nimerr.ErrProtocol, "initial board doesn't match the seed")
+	ErrCanceled       = errors.New("game canceled")
+)
+
+// Strategy picks the client's moves; see package nim for the built-in ones.
+type Strategy = nim.Strategy
+
+// Result is the outcome of a game and what was seen of the network while
+// playing it; see Summary for the form scripts should read.
+type Result struct {
+	Winner          string // "client" or "server"
+	Moves           int    // moves made by both sides
+	ClientMoves     int
+	ServerMoves     int
+	Retransmissions int // sends repeated after a timeout or unusable reply
+	Timeouts        int // reads that gave up waiting for a reply
+	InvalidPackets  int // replies that couldn't be decoded or weren't valid moves
+
+	// from the first send of each message to its accepted reply, so
+	// retransmissions count against the move; percentiles are nearest-rank,
+	// to within 2%
+	MeanRTT  time.Duration
+	P50RTT   time.Duration
+	P95RTT   time.Duration
+	P99RTT   time.Duration
+	MaxRTT   time.Duration
+	Duration time.Duration
+
+	// with SpeculativeUpdate, how many of the server's replies were
+	// predicted correctly, and the time spent deciding the client's answers
+	// to them before they arrived
+	SpeculativeHits   int
+	SpeculativeMisses int
+	SpeculativeSaved  time.Duration
+
+	// under time control, each side's time for the game and what was left
+	// on its clock as of the server's last reply
+	TimeControl time.Duration
+	ClientClock time.Duration
+	ServerClock time.Duration
+
+	// ForfeitTimeout or ForfeitMoveTTL when the server declared the game
+	// forfeited, won by it; empty for a game played out
+	Forfeit string
+}
+
+// MoveEvent is a move by either side, as passed to WithMoveHook hooks.
+type MoveEvent struct {
+	Player string // "client" or "server"
+	Row    int
+	Count  int
+	Board  []uint8 // after the move; hooks must not modify it
+
+	// under time control, what was left on each side's clock as of the
+	// server's last reply; zero without
+	ClientClock time.Duration
+	ServerClock time.Duration
+}
+
+// exchange is an accepted client move and the server's reply to it.
+type exchange struct {
+	move  StateMoveMessage
+	reply StateMoveMessage
+}
+
+// Session is one game against the configured nim servers. It talks to one
+// server at a time and fails over to the next when the current one stops
+// answering. A Session plays a single game, or with PlayMatch the games of
+// a match, and is not safe for concurrent use.
+type Session struct {
+	config       *ClientConfig
+	seed         int8
+	servers      []string
+	dial         func(addr string) (net.Conn, error)
+	conn         net.Conn // connection to servers[server]
+	tcp          bool     // dial over TCP, as configured or fallen back to
+	server       int
+	tracer       *tracing.Tracer // nil when tracing is disabled
+	trace        actionRecorder
+	strategy     Strategy
+	strategyName string
+	retry        *Backoff
+	breaker      *CircuitBreaker // nil when disabled
+	clk          clock
+	record       *GameRecorder
+	transcript   *Transcript
+	stats        *netStats
+	hooks        []func(MoveEvent)
+	log          *slog.Logger     // nil logs to the default logger
+	result       Result           // counted as the game goes, see Play
+	rtt          latencyHistogram // from the first send to each accepted reply
+
+	// heartbeat monitoring of the current server, if hbeat.LostMsgsThresh is set
+	hbeat        fcheck.Config
+	monitor      *fcheck.Monitor
+	serverFailed atomic.Bool
+
+	// the Seq of the server's last reply under time control, carried back
+	// by our next move
+	seq uint32
+
+	// the server's name for the game, to resume it by from a new socket
+	// once ours fails; reconnecting is set while we do
+	gameID       string
+	reconnecting bool
+
+	deadline  time.Time     // zero when the game may run indefinitely
+	moveDelay time.Duration // WithMoveDelay
+
+	// called, then cleared, by sendAndAwait once the move is first sent, to
+	// start work that can go on while the reply is on its way
+	onSent func()
+
+	// WithNetworkConditions; nil leaves that direction alone
+	condOut, condIn *netcond.Conditioner
+
+	// The game so far, replayed against a replacement server after failover.
+	// Boards and server moves are deterministic given the seed, so a fresh
+	// server fed the same GameStart and client moves ends up in our state.
+	initial []uint8
+	history []exchange
+	variant string // the game's, as the server's first board said
+
+	// resumePath is where the game is saved, WithResume; resume is the game
+	// to resume there, until the server has been synced with it. A game
+	// resumed past its saved board is unreplayable.
+	resumePath   string
+	resume       *ResumeState
+	unreplayable bool
+
+	// the N of the best-of-N match PlayMatch is playing, or zero, and its
+	// score as the server last sent it; lastGameID is the game before this
+	// one of the match
+	matchGames int8
+	score      MatchScore
+	lastGameID string
+}
+
+// Option configures a Session.
+type Option func(*Session)
+
+// WithSeed plays the game for seed. Odd seeds play the hard server.
+func WithSeed(seed int8) Option {
+	return func(s *Session) { s.seed = seed }
+}
+
+// WithStrategyName labels the strategy in the trace.
+func WithStrategyName(name string) Option {
+	return func(s *Session) { s.strategyName = name }
+}
+
+// WithRecorder writes a record of the game to r.
+func WithRecorder(r *GameRecorder) Option {
+	return func(s *Session) { s.record = r }
+}
+
+// WithMoveHook calls hook after every move by either side.
+func WithMoveHook(hook func(MoveEvent)) Option {
+	return func(s *Session) { s.hooks = append(s.hooks, hook) }
+}
+
+// WithLogger logs the game to l rather than the default logger, as when
+// several games share the process.
+func WithLogger(l *slog.Logger) Option {
+	return func(s *Session) { s.log = l }
+}
+
+// logOrDefault is l, or the default logger if l is nil.
+func logOrDefault(l *slog.Logger) *slog.Logger {
+	if l == nil {
+		return slog.Default()
+	}
+	return l
+}
+
+// LevelPacket logs every packet sent and received, below slog.LevelDebug,
+// where retransmissions, timeouts and rejected replies are logged.
+const LevelPacket = slog.LevelDebug - 4
+
+func (s *Session) logger() *slog.Logger {
+	return logOrDefault(s.log)
+}
+
+// WithNetworkStats tracks round trips to the server, logging them every
+// statsInterval moves; see Session.NetworkStats.
+func WithNetworkStats() Option {
+	return func(s *Session) { s.stats = &netStats{} }
+}
+
+// WithMoveDelay waits d before each of our moves, so that a game can be
+// followed as it is played. The wait comes before sending, so it is never
+// taken for a lost reply.
+func WithMoveDelay(d time.Duration) Option {
+	return func(s *Session) { s.moveDelay = d }
+}
+
+// WithNetworkConditions mistreats the packets sent to the server as out
+// says and those received from it as in, to see how the game copes with a
+// poor network. The zero Config leaves that direction alone.
+func WithNetworkConditions(out, in netcond.Config) Option {
+	return func(s *Session) {
+		s.condOut, s.condIn = nil, nil
+		if out.Enabled() {
+			s.condOut = netcond.New(out)
+		}
+		if in.Enabled() {
+			s.condIn = netcond.New(in)
+		}
+	}
+}
+
+// NewSession validates config and prepares a game against its servers,
+// connecting to the tracing server if one is configured. Call Play to play
+// the game and Close once done.
+func NewSession(config ClientConfig, strategy Strategy, opts ...Option) (*Session, error) {
+	if strategy == nil {
+		return nil, nimerr.New(nimerr.ErrConfig, "no strategy given")
+	}
+	if err := ValidateConfig(&config); err != nil {
+		return nil, fmt.Errorf("invalid config:\n%w", err)
+	}
+	var laddr *net.UDPAddr // resolved once the options are applied
+	s := &Session{
+		config:   &config,
+		trace:    nopRecorder{},
+		strategy: strategy,
+		retry:    NewBackoff(&config, rand.New(rand.NewSource(time.Now().UnixNano()))),
+		breaker:  NewCircuitBreaker(&config),
+		clk:      systemClock{},
+		hbeat: fcheck.Config{
+			LocalAddr:      config.FCheckHbeatLocalAddr,
+			LostMsgsThresh: config.FCheckLostMsgsThresh,
+		},
+	}
+	s.tcp = config.Transport == "tcp"
+	var dtlsOpts []dtls.ClientOption // set below for Transport "dtls"
+	s.dial = func(addr string) (net.Conn, error) {
+		if s.tcp {
+			return dialTCP(addr, laddr)
+		}
+		if dtlsOpts != nil {
+			return dtlsgame.Dial(addr, laddr, dtlsOpts)
+		}
+		if s.config.QuicEnabled {
+			return dialQUIC(addr, laddr, s.config.IPv6)
+		}
+		network := udpnet.Network(addr, s.config.IPv6)
+		raddr, err := net.ResolveUDPAddr(network, addr)
+		if err != nil {
+			return nil, err
+		}
+		conn, err := net.DialUDP(network, laddr, raddr)
+		if err != nil || (s.condOut == nil && s.condIn == nil) {
+			return conn, err
+		}
+		return netcond.Conn(conn, s.condOut, s.condIn), nil
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.resume != nil && s.resume.LocalAddress != "" {
+		s.config.ClientAddress = s.resume.LocalAddress
+	}
+	if s.config.ClientAddress != "" {
+		var err error
+		if laddr, err = udpnet.ResolveAddr(s.config.ClientAddress, s.config.IPv6); err != nil {
+			return nil, nimerr.Wrap(nimerr.ErrConfig, fmt.Errorf("resolving ClientAddress: %w", err))
+		}
+	}
+	if config.Transport == "dtls" {
+		var err error
+		if dtlsOpts, err = dtlsgame.ClientOptions(config.DTLSCAFile, config.DTLSPSK); err != nil {
+			return nil, nimerr.Wrap(nimerr.ErrConfig, err)
+		}
+	}
+	if s.breaker != nil {
+		s.breaker.log = s.log
+	}
+	if s.stats != nil {
+		s.stats.log = s.log
+	}
+	s.servers = routeServers(&config, strconv.Itoa(int(s.seed)))
+	if s.resume != nil {
+		// back to the server that knows the game
+		if i := slices.Index(s.servers, s.resume.Server); i > 0 {
+			s.servers = append(append([]string{s.servers[i]}, s.servers[:i]...), s.servers[i+1:]...)
+		}
+	}
+	if config.TracingServerAddress != "" {
+		trace, err := newGameTrace(&config, s.logger())
+		if err != nil {
+			return nil, err
+		}
+		s.tracer, s.trace = trace.tracer, trace
+	}
+	return s, nil
+}
+
+// Play plays the game to the end. It gives up with an error once every
+// server has failed, the game outlasts MaxGameDurationSeconds, or ctx is
+// done, which cuts any pending read short and returns ErrCanceled. The
+// result has a Winner whenever the game finished, even if writing its
+// record failed.
+func (s *Session) Play(ctx context.Context) (Result, error) {
+	start := s.clk.Now()
+	s.trace.RecordAction(GameStart{Seed: s.seed, Strategy: s.strategyName})
+	winner, err := s.play(ctx, s.seed)
+	result := s.result
+	result.Winner, result.Duration = winner, s.clk.Now().Sub(start)
+	result.MeanRTT, result.MaxRTT = s.rtt.mean(), s.rtt.max
+	result.P50RTT, result.P95RTT, result.P99RTT = s.rtt.percentile(50), s.rtt.percentile(95), s.rtt.percentile(99)
+	s.transcript.finish(result, err)
+	if s.resumePath != "" && winner != "" {
+		if err := os.Remove(s.resumePath); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			s.logger().Warn("removing resume state", "path", s.resumePath, "err", err)
+		}
+	}
+	if err != nil {
+		s.trace.RecordAction(GameAborted{Reason: err.Error()})
+		return result, err
+	}
+	s.trace.RecordAction(GameComplete{winner})
+	if err := s.record.Result(winner); err != nil {
+		return result, fmt.Errorf("writing game record: %w", err)
+	}
+	return result, nil
+}
+
+// NetworkStats returns the round trip stats of the latest moves, which are
+// only tracked WithNetworkStats.
+func (s *Session) NetworkStats() NetworkStats {
+	return s.stats.Stats()
+}
+
+// Close releases the connections the session holds.
+func (s *Session) Close() {
+	if s.monitor != nil {
+		s.monitor.Stop()
+	}
+	if s.conn != nil {
+		s.conn.Close()
+	}
+	if s.tracer != nil {
+		s.tracer.Close()
+	}
+}
+
+// maxRetries is MaxRetries, defaulting to defaultMaxRetries.
+func (config *ClientConfig) maxRetries() int {
+	if config.MaxRetries <= 0 {
+		return defaultMaxRetries
+	}
+	return config.MaxRetries
+}
+
+// play runs the game for seed to completion and returns the winner.
+func (s *Session) play(ctx context.Context, seed int8) (string, error) {
+	if s.config.MaxGameDurationSeconds > 0 {
+		s.deadline = s.clk.Now().Add(time.Duration(s.config.MaxGameDurationSeconds) * time.Second)
+	}
+	if s.conn == nil {
+		if err := s.connect(); err != nil {
+			return "", err
+		}
+	}
+	for {
+		winner, err := s.playOn(ctx, seed)
+		if !errors.Is(err, ErrNoReply) {
+			return winner, err
+		}
+		if s.fallBackToTCP(err) {
+			s.conn.Close()
+			s.conn = nil
+			if err := s.connect(); err != nil {
+				return "", err
+			}
+			continue
+		}
+		s.trace.RecordAction(NimServerFailed{NimServerAddress: s.servers[s.server]})
+		s.logger().Warn("nim server failed", "server", s.servers[s.server], "err", err)
+		s.conn.Close()
+		s.conn = nil
+		s.server++
+		if err := s.connect(); err != nil {
+			return "", err
+		}
+	}
+}
+
+// connect dials servers[s.server], moving down the list past any that can't
+// be dialed.
+func (s *Session) connect() error {
+	var lastErr error
+	for ; s.server < len(s.servers); s.server++ {
+		conn, err := s.dial(s.servers[s.server])
+		if err != nil && s.fallBackToTCP(err) {
+			conn, err = s.dial(s.servers[s.server])
+		}
+		if err != nil {
+			s.trace.RecordAction(NimServerFailed{NimServerAddress: s.servers[s.server]})
+			s.logger().Warn("couldn't connect to nim server", "server", s.servers[s.server], "err", err)
+			lastErr = err
+			continue
+		}
+		s.conn = conn
+		if ephemeral(s.config.ClientAddress) {
+			s.logger().Info("playing from a free port", "local", conn.LocalAddr())
+		}
+		s.breaker.Reset()
+		s.trace.RecordAction(NewNimServer{NimServerAddress: s.servers[s.server]})
+		s.watch()
+		return nil
+	}
+	s.trace.RecordAction(AllNimServersDown{})
+	if lastErr != nil {
+		// such as a failed DTLS handshake, which says more than no reply
+		return fmt.Errorf("%w: %w: %w", ErrAllServersDown, ErrNoReply, lastErr)
+	}
+	return fmt.Errorf("%w: %w", ErrAllServersDown, ErrNoReply)
+}
+
+// fallBackToTCP switches the game to TCP, with TCPFallback, if it isn't
+// there already, reporting whether it did. err is why UDP failed.
+func (s *Session) fallBackToTCP(err error) bool {
+	if !s.config.TCPFallback || s.tcp {
+		return false
+	}
+	s.logger().Warn("falling back to TCP", "server", s.servers[s.server], "err", err)
+	s.tcp = true
+	return true
+}
+
+// ephemeral reports whether ClientAddress addr leaves the OS to pick the
+// port, as when it is empty or its port is 0.
+func ephemeral(addr string) bool {
+	_, port, err := net.SplitHostPort(addr)
+	return addr == "" || (err == nil && port == "0")
+}
+
+// watch starts heartbeat monitoring of the current server. Once heartbeats
+// go unanswered the pending read is cut short and sendAndAwait gives up on
+// the server without waiting out its retries.
+func (s *Session) watch() {
+	if s.monitor != nil {
+		s.monitor.Stop()
+		s.monitor = nil
+	}
+	s.serverFailed.Store(false)
+	if s.hbeat.LostMsgsThresh == 0 || s.server >= len(s.config.FCheckServerAddresses) ||
+		s.config.FCheckServerAddresses[s.server] == "" {
+		return
+	}
+
+	config := s.hbeat
+	config.RemoteAddr = s.config.FCheckServerAddresses[s.server]
+	monitor, err := fcheck.StartMonitor(config)
+	if err != nil {
+		s.logger().Warn("couldn't monitor nim server", "addr", config.RemoteAddr, "err", err)
+		return
+	}
+	s.monitor = monitor
+	conn := s.conn
+	go func() {
+		if _, failed := <-monitor.Failed(); failed {
+			s.serverFailed.Store(true)
+			conn.SetReadDeadline(time.Now())
+		}
+	}()
+}
+
+// playOn starts the game on the current server, replays the history, and
+// plays on from there. A game being resumed is instead synced with the
+// server it was last played on.
+func (s *Session) playOn(ctx context.Context, seed int8) (string, error) {
+	if s.resume != nil {
+		state, winner, err := s.resync(ctx)
+		if err != nil || winner != "" {
+			return winner, err
+		}
+		s.resume = nil
+		return s.playFrom(ctx, state)
+	}
+	if s.unreplayable {
+		return "", fmt.Errorf("%w: the resumed game's history is incomplete", ErrReplayDiverged)
+	}
+
+	// get board state
+	sendMove := StateMoveMessage{GameState: nil, MoveRow: -1, MoveCount: seed, Variant: s.config.Variant, BoardProfile: s.config.BoardProfile, BoardGuarantee: s.config.BoardGuarantee, MatchGames: s.matchGames}
+	sendMove.TimeControl = int32(s.config.TimeControlSeconds * 1000)
+	sendMove.TracingServerAddr = s.config.TracingServerAddress
+	var recvMove StateMoveMessage
+	hasBoard := func(move *StateMoveMessage) (bool, error) {
+		if move.GameState == nil && move.MoveRow == drainingMoveRow {
+			// as good as down for a new game, so fail over
+			return false, fmt.Errorf("%w: the server is draining", ErrNoReply)
+		}
+		// a late reply in the match's last game has a board too
+		return len(move.GameState) > 0 && (s.lastGameID == "" || move.GameID != s.lastGameID), nil
+	}
+	if err := s.sendAndAwait(ctx, &sendMove, &recvMove, hasBoard); err != nil {
+		return "", err
+	}
+	if s.variant = recvMove.Variant; s.variant != s.config.Variant {
+		s.logger().Info("server plays nim rather than the variant asked for", "variant", s.config.Variant)
+	}
+	state := make([]uint8, len(recvMove.GameState))
+	copy(state, recvMove.GameState)
+	// in a match, the server opens every other game, answering with its
+	// first move rather than the bare board
+	opened := recvMove.MoveRow >= 0
+	if s.initial == nil {
+		if err := s.verifyInitialBoard(seed, &recvMove); err != nil {
+			return "", err
+		}
+		s.initial = make([]uint8, len(state))
+		copy(s.initial, state)
+		s.record.Start(state)
+		s.transcript.start(state)
+		if opened {
+			s.moved("server", recvMove)
+		}
+	} else if !bytes.Equal(s.initial, state) {
+		return "", fmt.Errorf("%w: initial board %v, expected %v", ErrReplayDiverged, state, s.initial)
+	}
+	if opened && isWinState(state) {
+		return s.emptiedBy("server"), nil
+	}
+
+	validReply := s.replyValidator(&state)
+	// replay the moves made against previous servers
+	for i, ex := range s.history {
+		sendMove = ex.move
+		state = append(state[:0], sendMove.GameState...)
+		if err := s.sendAndAwait(ctx, &sendMove, &recvMove, validReply); err != nil {
+			return "", err
+		}
+		if isConcession(&recvMove) || !bytes.Equal(recvMove.GameState, ex.reply.GameState) {
+			return "", fmt.Errorf("%w: reply %d was %v, expected %v", ErrReplayDiverged, i+1, recvMove.GameState, ex.reply.GameState)
+		}
+		state = append(state[:0], recvMove.GameState...)
+	}
+	return s.playFrom(ctx, state)
+}
+
+// playFrom plays the game on from state, where it is our turn. state is
+// reassigned rather than copied over as it changes, since a split in
+// Lasker's nim lengthens it.
+func (s *Session) playFrom(ctx context.Context, state []uint8) (string, error) {
+	s.saveResume(state)
+	validReply := s.replyValidator(&state)
+	var sendMove, recvMove StateMoveMessage
+	var spec *speculation
+	for {
+		// make move and update state
+		move, err := s.nextMove(state, spec)
+		if err != nil {
+			return "", err
+		}
+		sendMove = move
+		sendMove.TracingServerAddr = s.config.TracingServerAddress
+		sendMove.Seq = s.seq
+		state = append(state[:0], sendMove.GameState...)
+		if err := s.pause(ctx); err != nil {
+			return "", err
+		}
+
+		// if I took the last coin, send the final move and stop
+		if isWinState(state) {
+			if s.matchGames > 0 || s.config.TimeControlSeconds > 0 {
+				// the concession carries the match's score, and mustn't be
+				// left to be read as the answer to the next game's start;
+				// under time control, the move may have come too late
+				if err := s.sendAndAwait(ctx, &sendMove, &recvMove, validReply); err != nil {
+					return "", err
+				}
+				if isForfeit(&recvMove) {
+					return s.forfeited(&recvMove), nil
+				}
+			} else {
+				traceAndSend(&sendMove, s.trace, s.conn, s.config.CompressionMode)
+				s.transcript.sent(sendMove, s.clk.Now())
+			}
+			s.moved("client", sendMove)
+			return s.emptiedBy("client"), nil
+		}
+
+		spec = nil
+		var specs chan *speculation // nil until the move is sent
+		if s.config.SpeculativeUpdate {
+			board := append([]uint8(nil), state...)
+			s.onSent = func() {
+				specs = make(chan *speculation, 1)
+				go func() { specs <- s.speculate(board) }()
+			}
+		}
+		err = s.sendAndAwait(ctx, &sendMove, &recvMove, validReply)
+		s.onSent = nil
+		if specs != nil {
+			spec = <-specs
+		}
+		if err != nil {
+			return "", err
+		}
+		if isForfeit(&recvMove) {
+			return s.forfeited(&recvMove), nil
+		}
+		s.moved("client", sendMove)
+		// the server gives up rather than move on a board it can't win
+		if isConcession(&recvMove) {
+			return "client", nil
+		}
+		s.moved("server", recvMove)
+		s.history = append(s.history, exchange{sendMove, recvMove})
+		state = append(state[:0], recvMove.GameState...)
+		s.saveResume(state)
+		// if the server took the last coin, stop
+		if isWinState(state) {
+			return s.emptiedBy("server"), nil
+		}
+	}
+}
+
+// pause waits out the WithMoveDelay delay, giving up if ctx is done first.
+func (s *Session) pause(ctx context.Context) error {
+	if s.moveDelay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(s.moveDelay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("%w: %w", ErrCanceled, context.Cause(ctx))
+	}
+}
+
+// replyValidator returns the sendAndAwait check of the server's replies to
+// moves on *state, which is read as it changes. It gives up on the server
+// once cheatThreshold illegal replies come in a row.
+func (s *Session) replyValidator(statep *[]uint8) func(*StateMoveMessage) (bool, error) {
+	illegal := 0
+	return func(move *StateMoveMessage) (bool, error) {
+		state := *statep
+		if isConcession(move) || isForfeit(move) || s.validSuccessor(state, move) {
+			illegal = 0
+			return true, nil
+		}
+		if s.seenBoard(move.GameState) {
+			s.logger().Warn("saw invalid/duplicate (but not corrupt) packet", "state", state, "received", move.GameState)
+			return false, nil
+		}
+		s.logger().Warn("server sent an illegal move", "state", state, "received", move.GameState, "row", move.MoveRow, "count", move.MoveCount)
+		if illegal++; illegal < cheatThreshold {
+			return false, nil
+		}
+		s.trace.RecordAction(ServerCheatDetected{
+			Board:     append([]uint8(nil), state...),
+			Received:  move.GameState,
+			MoveRow:   move.MoveRow,
+			MoveCount: move.MoveCount,
+		})
+		return false, fmt.Errorf("%w: %d replies in a row, the last taking %v to %v", ErrServerCheating, illegal, state, move.GameState)
+	}
+}
+
+// validSuccessor is isValidSuccessor in the game's variant: in Lasker's
+// nim and Wythoff's game, moves are judged by the boards alone, as a split
+// has no count and taking from both heaps no one row.
+func (s *Session) validSuccessor(state []uint8, move *StateMoveMessage) bool {
+	switch s.variant {
+	case nim.LaskerVariant:
+		return nim.ValidLaskerMove(state, move.GameState)
+	case nim.WythoffVariant:
+		return nim.ValidWythoffMove(state, move.GameState)
+	}
+	return isValidSuccessor(state, move)
+}
+
+// emptiedBy returns who won the game whose last coin mover took: mover
+// itself, or under MisereMode the other side.
+func (s *Session) emptiedBy(mover string) string {
+	if !s.config.MisereMode {
+		return mover
+	}
+	if mover == "client" {
+		return "server"
+	}
+	return "client"
+}
+
+// verifyInitialBoard checks, if VerifyInitialBoard is set, that the board
+// the server started the game on is the one it should have generated for
+// seed, in the game's variant and the BoardProfile and BoardGuarantee asked
+// for. start is the server's answer to GameStart: the board, or in a match
+// game the server opens, its first move on it.
+func (s *Session) verifyInitialBoard(seed int8, start *StateMoveMessage) error {
+	if !s.config.VerifyInitialBoard {
+		return nil
+	}
+	expected := nim.GenerateBoard(int64(seed))
+	if s.variant == nim.WythoffVariant {
+		expected = nim.GenerateWythoffBoard(int64(seed))
+	} else if s.config.BoardProfile != "" || s.config.BoardGuarantee != "" {
+		profile := cmp.Or(s.config.BoardProfile, nim.ProfileUniform)
+		expected, _ = nim.GenerateProfileBoard(int64(seed), profile, nil, s.config.BoardGuarantee)
+	}
+	board := start.GameState
+	if start.MoveRow >= 0 && s.validSuccessor(expected, start) || start.MoveRow < 0 && bytes.Equal(board, expected) {
+		return nil
+	}
+	s.trace.RecordAction(InitialBoardMismatch{Seed: seed, Expected: expected, Received: append([]uint8(nil), board...)})
+	return fmt.Errorf("%w: seed %d should start on %v, the server sent %v", ErrBoardMismatch, seed, expected, board)
+}
+
+// seenBoard reports whether the server has sent board before this game, so
+// a reply carrying it is a stale duplicate rather than an illegal move.
+func (s *Session) seenBoard(board []uint8) bool {
+	if bytes.Equal(board, s.initial) {
+		return true
+	}
+	for _, ex := range s.history {
+		if bytes.Equal(board, ex.reply.GameState) {
+			return true
+		}
+	}
+	return false
+}
+
+// moved records a move by player, counting it and passing it to the hooks.
+func (s *Session) moved(player string, move StateMoveMessage) {
+	s.record.Move(move)
+	s.transcript.move(player, move)
+	s.result.Moves++
+	if player == "client" {
+		s.result.ClientMoves++
+	} else {
+		s.result.ServerMoves++
+	}
+	for _, hook := range s.hooks {
+		hook(MoveEvent{Player: player, Row: int(move.MoveRow), Count: int(move.MoveCount), Board: move.GameState, ClientClock: s.result.ClientClock, ServerClock: s.result.ServerClock})
+	}
+}
+
+// sendAndAwait sends move and waits for a reply that accept approves of,
+// retransmitting after each timeout or rejected reply, or giving up with the
+// error accept returns. A stale copy of an earlier reply is neither: the
+// wait for this one goes on. Over QUIC, which retransmits for us, move is
+// sent once and the timeouts only count towards giving up. The wait between
+// retransmissions follows s.retry, which is reset whenever a packet arrives.
+// While s.breaker is open nothing is sent, but replies are still read. It
+// gives up once MaxRetries retransmissions go unanswered, the game
+// deadline passes or ctx is done. If every transmission was answered, but
+// never acceptably, the server is breaking the protocol and the error is
+// ErrInvalidReply rather than ErrNoReply.
+func (s *Session) sendAndAwait(ctx context.Context, move *StateMoveMessage, reply *StateMoveMessage, accept func(*StateMoveMessage) (bool, error)) error {
+	maxRetries := s.config.maxRetries()
+	s.retry.Reset()
+	var rejected, unanswered int
+	reconnected := false
+	var firstSent time.Time
+	var stale time.Time // the read deadline a stale reply cut short
+	for attempt := 0; ; {
+		if s.serverFailed.Load() {
+			return fmt.Errorf("%w: heartbeats went unanswered", ErrNoReply)
+		}
+		if ctx.Err() != nil {
+			return fmt.Errorf("%w: %w", ErrCanceled, context.Cause(ctx))
+		}
+		now := s.clk.Now()
+		if !s.deadline.IsZero() && !now.Before(s.deadline) {
+			return ErrGameTimeout
+		}
+
+		var readDeadline time.Time
+		var sent bool
+		if now.Before(stale) {
+			// the move is still in flight, so wait on for its reply
+			readDeadline, sent = stale, true
+		} else if sent = s.breaker.Allow(now); sent {
+			if attempt > maxRetries {
+				if rejected > 0 && unanswered == 0 {
+					return fmt.Errorf("%w: %d replies to %d attempts", ErrInvalidReply, rejected, maxRetries+1)
+				}
+				return fmt.Errorf("%w: no valid reply after %d attempts", ErrNoReply, maxRetries+1)
+			}
+			attempt++
+			switch {
+			case attempt > 1 && (s.config.QuicEnabled || s.tcp):
+				// QUIC and TCP deliver the move themselves, so just wait on
+				s.logger().Debug("still awaiting reply", "attempt", attempt, "row", move.MoveRow, "count", move.MoveCount)
+			default:
+				if attempt > 1 {
+					s.logger().Debug("retransmitting", "attempt", attempt, "row", move.MoveRow, "count", move.MoveCount)
+					s.result.Retransmissions++
+				}
+				traceAndSend(move, s.trace, s.conn, s.config.CompressionMode)
+				s.logger().Log(ctx, LevelPacket, "sent", "board", move.GameState, "row", move.MoveRow, "count", move.MoveCount)
+				s.transcript.sent(*move, now)
+				s.stats.sent(now)
+				if firstSent.IsZero() {
+					firstSent = now
+					if onSent := s.onSent; onSent != nil {
+						s.onSent = nil
+						onSent()
+					}
+				}
+			}
+			readDeadline = now.Add(s.retry.Next())
+		} else {
+			readDeadline = s.breaker.ReopensAt()
+			s.logger().Debug("circuit breaker open, holding off", "until", readDeadline)
+		}
+		stale = time.Time{}
+		if !s.deadline.IsZero() && s.deadline.Before(readDeadline) {
+			readDeadline = s.deadline
+		}
+		if d, ok := ctx.Deadline(); ok && d.Before(readDeadline) {
+			readDeadline = d
+		}
+
+		if err := recvAndTrace(ctx, reply, s.trace, s.conn, readDeadline); err != nil {
+			s.logger().Debug("no reply from server", "deadline", readDeadline, "err", err)
+			if socketFailed(err) && !reconnected && !s.reconnecting {
+				// once per message, so a server that is gone fails over
+				reconnected = true
+				s.logger().Warn("socket failed, reconnecting", "server", s.servers[s.server], "err", err)
+				if err := s.reconnect(ctx); err != nil {
+					return err
+				}
+				continue
+			}
+			if errors.Is(err, errBadMerkleRoot) {
+				s.logger().Warn("rejecting move with a bad Merkle root", "err", err)
+			}
+			if errors.Is(err, nimerr.ErrProtocol) {
+				s.result.InvalidPackets++
+			} else if errors.Is(err, os.ErrDeadlineExceeded) && ctx.Err() == nil {
+				s.result.Timeouts++
+			}
+			if sent {
+				s.breaker.Failure(readDeadline)
+				unanswered++
+			}
+			continue
+		}
+		s.retry.Reset()
+		s.logger().Log(ctx, LevelPacket, "received", "board", reply.GameState, "row", reply.MoveRow, "count", reply.MoveCount)
+		s.transcript.received(*reply, s.clk.Now())
+		ok, err := accept(reply)
+		if err != nil {
+			s.result.InvalidPackets++
+			return err
+		}
+		if ok {
+			if reply.GameID != "" {
+				s.gameID = reply.GameID
+			}
+			if reply.MatchGames > 0 {
+				s.score = MatchScore{Games: int(reply.MatchGames), ClientWins: int(reply.MatchClientWins), ServerWins: int(reply.MatchServerWins)}
+			}
+			s.readClocks(reply)
+			received := s.clk.Now()
+			s.breaker.Success()
+			s.stats.received(received)
+			if !firstSent.IsZero() {
+				s.rtt.record(received.Sub(firstSent))
+			}
+			return nil
+		}
+		s.result.InvalidPackets++
+		if s.seenBoard(reply.GameState) {
+			// a late copy of an earlier reply, which says nothing of whether
+			// this move arrived, so it is neither answer nor cause to resend
+			stale = readDeadline
+			continue
+		}
+		rejected++
+		if sent {
+			s.breaker.Failure(now)
+		}
+	}
+}
@@ -0,0 +1,303 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"sync"
+	"testing"
+	"time"
+
+	"nimgame/pkg/netcond"
+	"nimgame/pkg/nim"
+	"nimgame/pkg/nimerr"
+)
+
+func TestPlayAgainstHarness(t *testing.T) {
+	h := &harnessServer{Board: []uint8{3, 4, 5, 6}}
+	sess := newTestSession(t, &ClientConfig{}, h.start(t))
+
+	result, err := sess.Play(context.Background())
+	if err != nil {
+		t.Fatalf("game failed: %v\n", err)
+	}
+	if result.Winner != "client" {
+		t.Errorf("optimal client should beat the harness, result.Winner: %v\n", result.Winner)
+	}
+}
+
+func TestStrategiesAgainstHarness(t *testing.T) {
+	boards := [][]uint8{{3, 4, 5, 6}, {1, 2, 4}, {9, 9, 2}, {10, 3, 7, 1, 1}}
+	for _, name := range nim.StrategyNames {
+		for _, board := range boards {
+			strategy, err := nim.NewStrategy(name, 7)
+			if err != nil {
+				t.Fatalf("creating %v strategy: %v\n", name, err)
+			}
+			h := &harnessServer{Board: board}
+			sess := newTestSession(t, &ClientConfig{}, h.start(t))
+			sess.strategy = strategy
+
+			result, err := sess.Play(context.Background())
+			if err != nil {
+				t.Fatalf("%v strategy on %v: game failed: %v\n", name, board, err)
+			}
+			if name == "optimal" && result.Winner != "client" {
+				t.Errorf("optimal strategy lost on %v to the basic harness\n", board)
+			}
+		}
+	}
+}
+
+func TestServerConcedes(t *testing.T) {
+	h := &harnessServer{Board: []uint8{3, 4, 5, 6}, ConcedeAfter: 2}
+	sess := newTestSession(t, &ClientConfig{}, h.start(t))
+
+	result, err := sess.Play(context.Background())
+	if err != nil {
+		t.Fatalf("game failed: %v\n", err)
+	}
+	if result.Winner != "client" {
+		t.Errorf("expected the server's concession to hand the client the win, result.Winner: %v\n", result.Winner)
+	}
+	if _, replies := h.counts(); replies != 3 {
+		t.Errorf("expected the game to stop at the concession, server sent %d replies\n", replies)
+	}
+}
+
+func TestServerNeverAnswers(t *testing.T) {
+	h := &harnessServer{Board: []uint8{3, 4, 5}, Silent: true}
+	sess := newTestSession(t, &ClientConfig{MaxRetries: 3}, h.start(t))
+
+	_, err := sess.Play(context.Background())
+	if !errors.Is(err, ErrNoReply) || !errors.Is(err, nimerr.ErrTransport) {
+		t.Fatalf("expected ErrNoReply, got %v\n", err)
+	}
+	if received, _ := h.counts(); received != 4 {
+		t.Errorf("expected 1 transmission and 3 retries, server saw %d\n", received)
+	}
+}
+
+func TestServerDiesMidGame(t *testing.T) {
+	h := &harnessServer{Board: []uint8{3, 4, 5, 6}, DieAfter: 2}
+	sess := newTestSession(t, &ClientConfig{MaxRetries: 2}, h.start(t))
+
+	_, err := sess.Play(context.Background())
+	if !errors.Is(err, ErrNoReply) {
+		t.Fatalf("expected ErrNoReply, got %v\n", err)
+	}
+	if _, replies := h.counts(); replies != 2 {
+		t.Errorf("expected the server to answer twice before dying, got %d\n", replies)
+	}
+}
+
+// tickingClock advances by step on every reading.
+type tickingClock struct {
+	now  time.Time
+	step time.Duration
+}
+
+func (c *tickingClock) Now() time.Time {
+	c.now = c.now.Add(c.step)
+	return c.now
+}
+
+func TestMaxGameDuration(t *testing.T) {
+	config := &ClientConfig{MaxRetries: 1000, MaxGameDurationSeconds: 2}
+	conn := &fakeConn{timeouts: 1000}
+	sess := &Session{
+		config: config,
+		conn:   conn,
+		trace:  nopRecorder{},
+		retry:  NewBackoff(config, rand.New(rand.NewSource(1))),
+		clk:    &tickingClock{now: time.Unix(0, 0), step: 500 * time.Millisecond},
+	}
+
+	_, err := sess.play(context.Background(), 1)
+	if !errors.Is(err, ErrGameTimeout) {
+		t.Fatalf("expected ErrGameTimeout, got %v\n", err)
+	}
+	if conn.writes != 3 {
+		t.Errorf("expected 3 transmissions within the 2s deadline, got %d\n", conn.writes)
+	}
+}
+
+// TestNoMaxGameDuration checks that a zero MaxGameDurationSeconds never
+// times out, however long the game runs.
+func TestNoMaxGameDuration(t *testing.T) {
+	// each reading of the clock is a minute later, so the retries run out
+	// only after hours
+	config := &ClientConfig{MaxRetries: 20}
+	conn := &fakeConn{timeouts: 1000}
+	sess := &Session{
+		config:  config,
+		servers: []string{"127.0.0.1:1"}, // the only one, so giving up on it ends the game
+		conn:    conn,
+		trace:   nopRecorder{},
+		retry:   NewBackoff(config, rand.New(rand.NewSource(1))),
+		clk:     &tickingClock{now: time.Unix(0, 0), step: time.Minute},
+	}
+	if _, err := sess.play(context.Background(), 1); !errors.Is(err, ErrNoReply) {
+		t.Errorf("expected %v, got %v\n", ErrNoReply, err)
+	}
+	if conn.writes != config.MaxRetries+1 {
+		t.Errorf("expected every retry to be sent, got %d transmissions\n", conn.writes)
+	}
+}
+
+func TestPlayWithRLECompression(t *testing.T) {
+	h := &harnessServer{Board: []uint8{5, 5, 5, 2, 2, 7}}
+	sess := newTestSession(t, &ClientConfig{CompressionMode: "rle"}, h.start(t))
+
+	result, err := sess.Play(context.Background())
+	if err != nil {
+		t.Fatalf("game failed: %v\n", err)
+	}
+	if result.Winner != "client" {
+		t.Errorf("optimal client should beat the harness, result.Winner: %v\n", result.Winner)
+	}
+}
+
+func TestCanceledMidMove(t *testing.T) {
+	// long retransmission timeouts, so only cancellation can end the wait
+	h := &harnessServer{Board: []uint8{3, 4, 5, 6}, StallAfter: 1}
+	config := &ClientConfig{MaxRetries: 1000, RetryBaseMs: 5000, RetryCapMs: 10000}
+	sess := newTestSession(t, config, h.start(t))
+
+	// the board arrives, then the client's first move goes unanswered
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+
+	start := time.Now()
+	result, err := sess.Play(ctx)
+	if !errors.Is(err, ErrCanceled) || !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected ErrCanceled wrapping context.Canceled, got %v\n", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Play took %v to notice the cancellation\n", elapsed)
+	}
+	if result.Winner != "" {
+		t.Errorf("canceled game should have no winner, got %v\n", result.Winner)
+	}
+}
+
+func TestMisereMode(t *testing.T) {
+	tests := []struct {
+		board  []uint8
+		misere bool
+		want   string
+	}{
+		{[]uint8{3, 4, 5}, false, "client"}, // the client takes the last coin
+		{[]uint8{3, 4, 5}, true, "server"},
+		{[]uint8{1, 1}, false, "server"}, // the server takes the last coin
+		{[]uint8{1, 1}, true, "client"},
+	}
+	for _, test := range tests {
+		addr := (&harnessServer{Board: test.board}).start(t)
+		sess := newTestSession(t, &ClientConfig{MisereMode: test.misere}, addr)
+		result, err := sess.Play(context.Background())
+		sess.Close()
+		if err != nil {
+			t.Fatalf("%v, misère %v: game failed: %v\n", test.board, test.misere, err)
+		}
+		if result.Winner != test.want {
+			t.Errorf("%v, misère %v: expected the %v to win, got %v\n", test.board, test.misere, test.want, result.Winner)
+		}
+	}
+}
+
+func TestMoveDelay(t *testing.T) {
+	const delay = 40 * time.Millisecond
+	var durations []time.Duration
+	for _, d := range []time.Duration{0, delay, 2 * delay} {
+		sess := newTestSession(t, &ClientConfig{}, (&harnessServer{Board: []uint8{2, 3, 4}}).start(t))
+		WithMoveDelay(d)(sess)
+		result, err := sess.Play(context.Background())
+		sess.Close()
+		if err != nil {
+			t.Fatalf("delay %v: game failed: %v\n", d, err)
+		}
+		if min := time.Duration(result.ClientMoves) * d; result.Duration < min {
+			t.Errorf("delay %v: %d moves took %v, expected at least %v\n", d, result.ClientMoves, result.Duration, min)
+		}
+		durations = append(durations, result.Duration)
+	}
+	if durations[0] >= durations[1] || durations[1] >= durations[2] {
+		t.Errorf("game durations %v don't grow with the delay\n", durations)
+	}
+}
+
+func TestNetworkConditions(t *testing.T) {
+	h := &harnessServer{Board: []uint8{3, 4, 5, 6, 7}}
+	sess := newTestSession(t, &ClientConfig{MaxRetries: 10}, h.start(t))
+	out := netcond.Config{Loss: 0.1, Duplicate: 0.1, Reorder: 0.1, Delay: 5 * time.Millisecond, Seed: 1}
+	in := out
+	in.Seed = 2
+	WithNetworkConditions(out, in)(sess)
+	trace := &recordingRecorder{}
+	sess.trace = trace
+
+	result, err := sess.Play(context.Background())
+	if err != nil {
+		t.Fatalf("game failed on a poor network: %v\n", err)
+	}
+	if result.Winner != "client" {
+		t.Errorf("expected the client to win, result.Winner: %v\n", result.Winner)
+	}
+	for _, action := range trace.actions {
+		switch action.(type) {
+		case NimServerFailed, AllNimServersDown, ServerCheatDetected, GameAborted:
+			t.Errorf("unexpected %T in trace: %+v\n", action, action)
+		}
+	}
+	if last := trace.actions[len(trace.actions)-1]; last != (GameComplete{"client"}) {
+		t.Errorf("trace ends with %+v, expected the client's win\n", last)
+	}
+}
+
+func TestStaleRepliesIgnored(t *testing.T) {
+	// every reply arrives twice, so each move after the first finds a copy
+	// of the last reply waiting for it
+	h := &harnessServer{Board: []uint8{3, 4, 5, 6}}
+	sess := newTestSession(t, &ClientConfig{RetryBaseMs: 200, RetryCapMs: 400}, h.start(t))
+	WithNetworkConditions(netcond.Config{}, netcond.Config{Duplicate: 1})(sess)
+
+	result, err := sess.Play(context.Background())
+	if err != nil {
+		t.Fatalf("game failed: %v\n", err)
+	}
+	if result.Retransmissions != 0 || result.InvalidPackets == 0 {
+		t.Errorf("expected stale replies to be counted but not resent for, got %+v\n", result)
+	}
+}
+
+func TestEmptyClientAddress(t *testing.T) {
+	raddr := (&harnessServer{Board: []uint8{3, 4, 5, 6}}).start(t)
+	config := ClientConfig{NimServerAddresses: []string{raddr.String()}, RetryBaseMs: 10, RetryCapMs: 40}
+	var sessions [2]*Session
+	var results [2]Result
+	var errs [2]error
+	var wg sync.WaitGroup
+	for i := range sessions {
+		sess, err := NewSession(config, nim.Optimal{})
+		if err != nil {
+			t.Fatalf("creating session with no ClientAddress: %v\n", err)
+		}
+		defer sess.Close()
+		sessions[i] = sess
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], errs[i] = sess.Play(context.Background())
+		}()
+	}
+	wg.Wait()
+	for i := range sessions {
+		if errs[i] != nil || results[i].Winner != "client" {
+			t.Errorf("client %d: got winner %q, error %v, expected the client to win\n", i, results[i].Winner, errs[i])
+		}
+	}
+	if a, b := sessions[0].conn.LocalAddr().String(), sessions[1].conn.LocalAddr().String(); a == b {
+		t.Errorf("both clients played from %v\n", a)
+	}
+}
@@ -0,0 +1,59 @@
+package client
+
+import (
+	"bytes"
+	"time"
+
+	"nimgame/pkg/nim"
+)
+
+// speculation is the client's next move, decided while waiting for the
+// server on the board the server was predicted to leave.
+type speculation struct {
+	board []uint8 // the predicted reply
+	move  StateMoveMessage
+	took  time.Duration // deciding move, saved if the prediction holds
+}
+
+// speculate predicts the server's reply to state, taking it to play
+// bestMove, which nim.Optimal mirrors, and decides the client's answer to
+// it. It returns nil if the predicted reply ends the game or the strategy
+// fails on it, leaving the move to be decided once the reply arrives. It
+// runs while the move it follows is on its way, alongside sendAndAwait, so
+// it touches nothing of s but the strategy and the clock.
+func (s *Session) speculate(state []uint8) *speculation {
+	if s.variant != "" {
+		return nil // bestMove plays nim
+	}
+	reply, err := decideMove(nim.Optimal{}, state)
+	if err != nil || isWinState(reply.GameState) {
+		return nil
+	}
+	start := s.clk.Now()
+	move, err := decideMove(s.strategy, reply.GameState)
+	if err != nil {
+		return nil
+	}
+	return &speculation{board: reply.GameState, move: move, took: s.clk.Now().Sub(start)}
+}
+
+// nextMove decides the client's move on state, using spec if it was made
+// for state.
+func (s *Session) nextMove(state []uint8, spec *speculation) (StateMoveMessage, error) {
+	if spec != nil {
+		if bytes.Equal(spec.board, state) {
+			s.result.SpeculativeHits++
+			s.result.SpeculativeSaved += spec.took
+			return spec.move, nil
+		}
+		s.logger().Debug("server's reply wasn't the predicted one", "predicted", spec.board, "received", state)
+		s.result.SpeculativeMisses++
+	}
+	if strategy, ok := s.strategy.(nim.LaskerStrategy); ok && s.variant == nim.LaskerVariant {
+		return decideLaskerMove(strategy, state)
+	}
+	if strategy, ok := s.strategy.(nim.WythoffStrategy); ok && s.variant == nim.WythoffVariant {
+		return decideWythoffMove(strategy, state)
+	}
+	return decideMove(s.strategy, state)
+}
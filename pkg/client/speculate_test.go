@@ -0,0 +1,51 @@
+package client
+
+import (
+	"context"
+	"math/rand"
+	"testing"
+
+	"nimgame/pkg/nim"
+)
+
+// TestSpeculationAgainstBestMove plays random games against a server using
+// bestMove, whose every reply the client should predict.
+func TestSpeculationAgainstBestMove(t *testing.T) {
+	addr := (&harnessServer{Board: []uint8{3, 4, 5, 7}, BestMove: true}).start(t)
+	for seed := int64(0); seed < 10; seed++ {
+		sess := newTestSession(t, &ClientConfig{SpeculativeUpdate: true}, addr)
+		sess.strategy = nim.Random{Rng: rand.New(rand.NewSource(seed))}
+		result, err := sess.Play(context.Background())
+		sess.Close()
+		if err != nil {
+			t.Fatalf("seed %d: game failed: %v\n", seed, err)
+		}
+		if result.SpeculativeMisses != 0 || result.SpeculativeHits == 0 {
+			t.Errorf("seed %d: expected every prediction to hold, got %d hits and %d misses\n",
+				seed, result.SpeculativeHits, result.SpeculativeMisses)
+		}
+	}
+}
+
+// TestSpeculationCorrected plays against a server taking one coin at a
+// time, which the client mispredicts but still beats.
+func TestSpeculationCorrected(t *testing.T) {
+	board := []uint8{3, 4, 5}
+	addr := (&harnessServer{Board: board}).start(t)
+	sess := newTestSession(t, &ClientConfig{SpeculativeUpdate: true}, addr)
+	sess.strategy = nim.Basic{}
+	result, err := sess.Play(context.Background())
+	if err != nil {
+		t.Fatalf("game failed: %v\n", err)
+	}
+	if result.SpeculativeMisses == 0 {
+		t.Errorf("expected mispredictions against a server taking one coin, got %+v\n", result)
+	}
+	want := "server"
+	if nim.SimulateGame(board, nim.Basic{}, nim.Basic{}) {
+		want = "client"
+	}
+	if result.Winner != want {
+		t.Errorf("expected the %v to win, got %+v\n", want, result)
+	}
+}
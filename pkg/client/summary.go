@@ -0,0 +1,43 @@
+package client
+
+import "time"
+
+// Summary is a Result in the JSON form written by -summary-out. The field
+// names and units are kept stable for scripts; durations are in
+// milliseconds, and Winner is empty if the game didn't finish.
+type Summary struct {
+	Winner          string  `json:"winner"`
+	ClientMoves     int     `json:"client_moves"`
+	ServerMoves     int     `json:"server_moves"`
+	Retransmissions int     `json:"retransmissions"`
+	Timeouts        int     `json:"timeouts"`
+	InvalidPackets  int     `json:"invalid_packets"`
+	DurationMs      float64 `json:"duration_ms"`
+	MeanRTTMs       float64 `json:"mean_rtt_ms"`
+	P50RTTMs        float64 `json:"p50_rtt_ms"`
+	P95RTTMs        float64 `json:"p95_rtt_ms"`
+	P99RTTMs        float64 `json:"p99_rtt_ms"`
+	MaxRTTMs        float64 `json:"max_rtt_ms"`
+}
+
+// Summary returns the result in its stable JSON form.
+func (r Result) Summary() Summary {
+	return Summary{
+		Winner:          r.Winner,
+		ClientMoves:     r.ClientMoves,
+		ServerMoves:     r.ServerMoves,
+		Retransmissions: r.Retransmissions,
+		Timeouts:        r.Timeouts,
+		InvalidPackets:  r.InvalidPackets,
+		DurationMs:      milliseconds(r.Duration),
+		MeanRTTMs:       milliseconds(r.MeanRTT),
+		P50RTTMs:        milliseconds(r.P50RTT),
+		P95RTTMs:        milliseconds(r.P95RTT),
+		P99RTTMs:        milliseconds(r.P99RTT),
+		MaxRTTMs:        milliseconds(r.MaxRTT),
+	}
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
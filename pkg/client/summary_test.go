@@ -0,0 +1,64 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestLossyGameSummary(t *testing.T) {
+	// packet 1 is GameStart; the client's first move is lost once and its
+	// second answered with garbage once
+	h := &harnessServer{Board: []uint8{3, 4, 5, 6}, Drop: map[int]bool{2: true}, Corrupt: map[int]bool{4: true}}
+	// waits of at least 100ms, so replies are never mistaken for timeouts
+	sess := newTestSession(t, &ClientConfig{RetryBaseMs: 200, RetryCapMs: 400}, h.start(t))
+	moves := map[string]int{}
+	sess.hooks = append(sess.hooks, func(e MoveEvent) { moves[e.Player]++ })
+
+	result, err := sess.Play(context.Background())
+	if err != nil {
+		t.Fatalf("game failed: %v\n", err)
+	}
+	if result.Winner != "client" || result.ClientMoves != moves["client"] || result.ServerMoves != moves["server"] ||
+		result.Moves != result.ClientMoves+result.ServerMoves {
+		t.Errorf("expected the client to win in %v moves, got %+v\n", moves, result)
+	}
+	if result.Retransmissions != 2 || result.Timeouts != 1 || result.InvalidPackets != 1 {
+		t.Errorf("expected 2 retransmissions, 1 timeout and 1 invalid packet, got %+v\n", result)
+	}
+	if result.MeanRTT <= 0 || result.P95RTT <= 0 || result.Duration < 100*time.Millisecond {
+		t.Errorf("expected RTTs and a duration including the lost packet's wait, got %+v\n", result)
+	}
+	// the lost move's RTT runs from its first send, so includes the wait
+	if result.MaxRTT < 100*time.Millisecond {
+		t.Errorf("expected the lost move's wait in its RTT, got max %v\n", result.MaxRTT)
+	}
+}
+
+func TestSummaryJSON(t *testing.T) {
+	result := Result{Winner: "server", ClientMoves: 3, ServerMoves: 3, Duration: 1500 * time.Millisecond, P95RTT: 250 * time.Microsecond}
+	data, err := json.Marshal(result.Summary())
+	if err != nil {
+		t.Fatalf("marshalling summary: %v\n", err)
+	}
+	var fields map[string]interface{}
+	json.Unmarshal(data, &fields)
+
+	// scripts read these; don't rename them
+	want := []string{"client_moves", "duration_ms", "invalid_packets", "max_rtt_ms", "mean_rtt_ms",
+		"p50_rtt_ms", "p95_rtt_ms", "p99_rtt_ms", "retransmissions", "server_moves", "timeouts", "winner"}
+	var keys []string
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	if !reflect.DeepEqual(keys, want) {
+		t.Errorf("summary fields %v, want %v\n", keys, want)
+	}
+	if fields["winner"] != "server" || fields["duration_ms"] != 1500.0 || fields["p95_rtt_ms"] != 0.25 {
+		t.Errorf("unexpected summary: %s\n", data)
+	}
+}
@@ -0,0 +1,27 @@
+package client
+
+import (
+	"net"
+	"time"
+
+	"nimgame/pkg/tcpframe"
+)
+
+// tcpDialTimeout bounds connecting to a server, which a host dropping our
+// packets never answers.
+const tcpDialTimeout = 5 * time.Second
+
+// dialTCP connects to the server's TCP address addr, from laddr's IP and
+// port if it isn't nil, and returns the connection framed so it reads and
+// writes one move at a time, as a UDP conn does.
+func dialTCP(addr string, laddr *net.UDPAddr) (net.Conn, error) {
+	dialer := net.Dialer{Timeout: tcpDialTimeout}
+	if laddr != nil {
+		dialer.LocalAddr = &net.TCPAddr{IP: laddr.IP, Port: laddr.Port, Zone: laddr.Zone}
+	}
+	conn, err := dialer.Dial("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	return tcpframe.NewConn(conn), nil
+}
@@ -0,0 +1,74 @@
+package client
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"nimgame/pkg/nimserver"
+)
+
+// startServer runs a nim server with config, listening for UDP on addr,
+// until the test ends.
+func startServer(t *testing.T, config *nimserver.ServerConfig, addr string) *nimserver.Server {
+	server, err := nimserver.New(nimserver.WithConfig(config), nimserver.WithListenAddress(addr))
+	if err != nil {
+		t.Fatalf("starting server: %v\n", err)
+	}
+	done := make(chan error, 1)
+	go func() { done <- server.Run(context.Background()) }()
+	t.Cleanup(func() {
+		server.Shutdown(context.Background())
+		<-done
+	})
+	return server
+}
+
+// startTCPServer runs a nim server serving TCP on a loopback port until the
+// test ends, and returns it with its TCP address as a UDPAddr, which no UDP
+// server listens on.
+func startTCPServer(t *testing.T) (*nimserver.Server, *net.UDPAddr) {
+	server := startServer(t, &nimserver.ServerConfig{TCPAddress: "127.0.0.1:0"}, "127.0.0.1:0")
+	addr := server.TCPAddr().(*net.TCPAddr)
+	return server, &net.UDPAddr{IP: addr.IP, Port: addr.Port}
+}
+
+func TestPlayOverTCP(t *testing.T) {
+	server, addr := startTCPServer(t)
+	sess := newTestSession(t, &ClientConfig{Transport: "tcp"}, addr)
+
+	result, err := sess.Play(context.Background())
+	if err != nil {
+		t.Fatalf("game over TCP failed: %v\n", err)
+	}
+	if result.Winner == "" || result.Retransmissions != 0 {
+		t.Errorf("game over TCP ended with %+v, expected a winner and no retransmissions\n", result)
+	}
+	if stats := server.Stats(); stats.GamesStarted != 1 || stats.InvalidMoves != 0 {
+		t.Errorf("server stats are %+v, expected one game and no invalid moves\n", stats)
+	}
+}
+
+func TestTCPFallback(t *testing.T) {
+	server, addr := startTCPServer(t)
+	config := &ClientConfig{TCPFallback: true, MaxRetries: 2}
+	sess := newTestSession(t, config, addr)
+
+	// nothing answers over UDP, so the game is played over TCP
+	result, err := sess.Play(context.Background())
+	if err != nil {
+		t.Fatalf("game falling back to TCP failed: %v\n", err)
+	}
+	if result.Winner == "" || !sess.tcp {
+		t.Errorf("game ended with %+v, over TCP %v, expected a winner over TCP\n", result, sess.tcp)
+	}
+	if stats := server.Stats(); stats.GamesStarted != 1 {
+		t.Errorf("server stats are %+v, expected the one game\n", stats)
+	}
+
+	// without fallback the game is lost with the server
+	config = &ClientConfig{MaxRetries: 2}
+	if _, err := newTestSession(t, config, addr).Play(context.Background()); err == nil {
+		t.Errorf("game without fallback was played\n")
+	}
+}
@@ -0,0 +1,129 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// TournamentGame is one game of a tournament. Err is set when the game
+// didn't finish, in which case Result.Winner is empty.
+type TournamentGame struct {
+	Seed   int8
+	Result Result
+	Err    error
+}
+
+// TournamentResult is the record of a tournament, in the order played.
+type TournamentResult struct {
+	Games  []TournamentGame
+	Wins   int // games the client won
+	Losses int
+	Aborts int // games that didn't finish
+}
+
+// TournamentSeed is the seed of game i of a tournament starting at base:
+// consecutive seeds, wrapping around from 127 to -128, so the difficulty
+// alternates from game to game.
+func TournamentSeed(base int8, i int) int8 {
+	return int8(int(base) + i)
+}
+
+// PlayTournament plays games games one after another, game i with seed
+// TournamentSeed(base, i) in a session from newSession, which is closed
+// once the game is over. Each session has its own trace. A game that fails,
+// or whose session can't be created, is counted as aborted and the next
+// one is played; only ctx being done stops the tournament early, leaving
+// the games not yet started out of the result.
+func PlayTournament(ctx context.Context, games int, base int8, newSession func(game int, seed int8) (*Session, error)) TournamentResult {
+	var t TournamentResult
+	for i := 0; i < games && ctx.Err() == nil; i++ {
+		game, err := playGame(ctx, i, TournamentSeed(base, i), newSession)
+		if errors.Is(err, ErrCanceled) {
+			break
+		}
+		t.add(game)
+	}
+	return t
+}
+
+// PlayParallel plays games games at once, seeded and counted as by
+// PlayTournament. Sessions from newSession must not share a local address
+// or a Strategy that isn't safe for concurrent use. Games canceled by ctx
+// are counted as aborted.
+func PlayParallel(ctx context.Context, games int, base int8, newSession func(game int, seed int8) (*Session, error)) TournamentResult {
+	played := make([]TournamentGame, games)
+	var wg sync.WaitGroup
+	for i := range played {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			played[i], _ = playGame(ctx, i, TournamentSeed(base, i), newSession)
+		}()
+	}
+	wg.Wait()
+
+	var t TournamentResult
+	for _, game := range played {
+		t.add(game)
+	}
+	return t
+}
+
+// playGame plays game in a session from newSession, returning the error
+// that stopped it early, if any, as well as recording it in the game.
+func playGame(ctx context.Context, game int, seed int8, newSession func(game int, seed int8) (*Session, error)) (TournamentGame, error) {
+	played := TournamentGame{Seed: seed}
+	sess, err := newSession(game, seed)
+	if err == nil {
+		played.Result, err = sess.Play(ctx)
+		sess.Close()
+	}
+	// a game with a winner counts even if something failed afterwards
+	if played.Result.Winner == "" {
+		played.Err = err
+	}
+	return played, err
+}
+
+func (t *TournamentResult) add(game TournamentGame) {
+	switch game.Result.Winner {
+	case "client":
+		t.Wins++
+	case "server":
+		t.Losses++
+	default:
+		t.Aborts++
+	}
+	t.Games = append(t.Games, game)
+}
+
+// TournamentSummary is a TournamentResult in the JSON form written by
+// -summary-out with -games or -parallel.
+type TournamentSummary struct {
+	Wins   int                  `json:"wins"`
+	Losses int                  `json:"losses"`
+	Aborts int                  `json:"aborts"`
+	Games  []TournamentGameJSON `json:"games"`
+}
+
+// TournamentGameJSON is a game's Summary with its seed and, if it was
+// aborted, why.
+type TournamentGameJSON struct {
+	Seed int8 `json:"seed"`
+	Summary
+	Error string `json:"error,omitempty"`
+}
+
+// Summary returns the tournament in its stable JSON form.
+func (t TournamentResult) Summary() TournamentSummary {
+	s := TournamentSummary{Wins: t.Wins, Losses: t.Losses, Aborts: t.Aborts, Games: []TournamentGameJSON{}}
+	for _, game := range t.Games {
+		g := TournamentGameJSON{Seed: game.Seed, Summary: game.Result.Summary()}
+		if game.Err != nil {
+			g.Error = game.Err.Error()
+		}
+		s.Games = append(s.Games, g)
+	}
+	return s
+}
@@ -0,0 +1,143 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math/rand"
+	"net"
+	"testing"
+
+	"nimgame/pkg/nim"
+	"nimgame/pkg/nimerr"
+)
+
+// TestTournament plays 10 games back to back from the same local port:
+// wins, losses, a game whose server never answers and one whose session
+// can't be created.
+func TestTournament(t *testing.T) {
+	winnable := (&harnessServer{Board: []uint8{3, 4, 5}}).start(t)
+	losing := (&harnessServer{Board: []uint8{1, 1}}).start(t) // nim sum zero: the server wins
+	silent := (&harnessServer{Board: []uint8{3, 4, 5}, Silent: true}).start(t)
+
+	// find a free port to reuse for every game
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatalf("finding a free port: %v\n", err)
+	}
+	local := conn.LocalAddr().String()
+	conn.Close()
+
+	var seeds []int8
+	newSession := func(game int, seed int8) (*Session, error) {
+		seeds = append(seeds, seed)
+		config := &ClientConfig{ClientAddress: local, MaxRetries: 2}
+		switch {
+		case game == 3:
+			return newTestSession(t, config, silent), nil
+		case game == 5:
+			return nil, nimerr.New(nimerr.ErrConfig, "no session for game 5")
+		case game%2 == 1:
+			return newTestSession(t, config, losing), nil
+		default:
+			return newTestSession(t, config, winnable), nil
+		}
+	}
+
+	result := PlayTournament(context.Background(), 10, 120, newSession)
+	if len(result.Games) != 10 || result.Wins != 5 || result.Losses != 3 || result.Aborts != 2 {
+		t.Fatalf("expected 5 wins, 3 losses and 2 aborts in 10 games, got %+v\n", result)
+	}
+	wantSeeds := []int8{120, 121, 122, 123, 124, 125, 126, 127, -128, -127}
+	for i, game := range result.Games {
+		if game.Seed != wantSeeds[i] || seeds[i] != wantSeeds[i] {
+			t.Errorf("game %d: expected seed %d, played %d and recorded %d\n", i, wantSeeds[i], seeds[i], game.Seed)
+		}
+		aborted := i == 3 || i == 5
+		if aborted != (game.Err != nil) || aborted != (game.Result.Winner == "") {
+			t.Errorf("game %d: unexpected outcome %+v\n", i, game)
+		}
+		if !aborted && game.Result.Duration <= 0 {
+			t.Errorf("game %d: no duration recorded\n", i)
+		}
+	}
+	if !errors.Is(result.Games[3].Err, ErrNoReply) || !errors.Is(result.Games[5].Err, nimerr.ErrConfig) {
+		t.Errorf("unexpected abort reasons: %v, %v\n", result.Games[3].Err, result.Games[5].Err)
+	}
+
+	data, err := json.Marshal(result.Summary())
+	if err != nil {
+		t.Fatalf("marshalling summary: %v\n", err)
+	}
+	var summary struct {
+		Wins  int `json:"wins"`
+		Games []struct {
+			Seed   int8   `json:"seed"`
+			Winner string `json:"winner"`
+			Error  string `json:"error"`
+		} `json:"games"`
+	}
+	json.Unmarshal(data, &summary)
+	if summary.Wins != 5 || len(summary.Games) != 10 || summary.Games[8].Seed != -128 ||
+		summary.Games[0].Winner != "client" || summary.Games[3].Error == "" {
+		t.Errorf("unexpected summary: %s\n", data)
+	}
+}
+
+func TestTournamentCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	addr := (&harnessServer{Board: []uint8{3, 4, 5}}).start(t)
+	played := 0
+	result := PlayTournament(ctx, 5, 0, func(game int, seed int8) (*Session, error) {
+		if played++; played == 3 {
+			cancel()
+		}
+		return newTestSession(t, &ClientConfig{}, addr), nil
+	})
+	if len(result.Games) != 2 || result.Wins != 2 || result.Aborts != 0 {
+		t.Errorf("expected the tournament to stop after 2 games, got %+v\n", result)
+	}
+}
+
+// TestPlayParallel runs 20 games at once against two servers with
+// different strategies and checks each winner against a simulation.
+func TestPlayParallel(t *testing.T) {
+	boards := [][]uint8{{3, 4, 5}, {1, 2, 3}} // the client can force a win on the first only
+	var addrs []string
+	for _, board := range boards {
+		addrs = append(addrs, (&harnessServer{Board: board}).start(t).String())
+	}
+	// each game gets its own instance, so random games can be replayed
+	strategy := func(game int) Strategy {
+		switch game % 3 {
+		case 0:
+			return nim.Optimal{}
+		case 1:
+			return nim.Basic{}
+		}
+		return nim.Random{Rng: rand.New(rand.NewSource(int64(game)))}
+	}
+
+	const games = 20
+	result := PlayParallel(context.Background(), games, 0, func(game int, seed int8) (*Session, error) {
+		config := ClientConfig{
+			ClientAddress:      "127.0.0.1:0",
+			NimServerAddresses: []string{addrs[game%2]},
+			RetryBaseMs:        50,
+			RetryCapMs:         200,
+		}
+		return NewSession(config, strategy(game), WithSeed(seed))
+	})
+	if len(result.Games) != games || result.Aborts != 0 {
+		t.Fatalf("expected %d finished games, got %+v\n", games, result)
+	}
+	for i, game := range result.Games {
+		want := "server"
+		if nim.SimulateGame(boards[i%2], strategy(i), nim.Basic{}) {
+			want = "client"
+		}
+		if game.Seed != int8(i) || game.Result.Winner != want {
+			t.Errorf("game %d: expected seed %d won by the %v, got %+v\n", i, i, want, game)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package client
+
+import (
+	"fmt"
+	"time"
+
+	"nimgame/pkg/configfile"
+)
+
+// Transcript is a machine-readable record of a game: every message sent
+// and received, the moves made, and the result. It is written as JSON by
+// WriteFile; the field names are kept stable for tools that read it.
+// Boards are arrays of coin counts.
+//
+// A nil *Transcript records nothing.
+type Transcript struct {
+	Seed         int8               `json:"seed"`
+	InitialBoard []int              `json:"initial_board"`
+	Packets      []TranscriptPacket `json:"packets"`
+	Moves        []TranscriptMove   `json:"moves"`
+	Result       TranscriptResult   `json:"result"`
+
+	sends int // sends since the last move
+}
+
+// TranscriptPacket is a message sent to or received from the server.
+// Received packets that couldn't be decoded aren't included.
+type TranscriptPacket struct {
+	Time      time.Time `json:"time"`
+	Direction string    `json:"direction"` // "sent" or "received"
+	MoveRow   int       `json:"move_row"`
+	MoveCount int       `json:"move_count"`
+	GameState []int     `json:"game_state"` // null for GameStart and concessions
+}
+
+// TranscriptMove is an accepted move by either side.
+type TranscriptMove struct {
+	Number    int    `json:"number"` // from 1
+	Player    string `json:"player"` // "client" or "server"
+	MoveRow   int    `json:"move_row"`
+	MoveCount int    `json:"move_count"`
+	Board     []int  `json:"board"` // after the move
+	NimSum    int    `json:"nim_sum"`
+	// times the client's move was sent beyond the first, including resends
+	// to a replacement server; always 0 for the server's moves
+	Retransmissions int `json:"retransmissions"`
+}
+
+// TranscriptResult is how the game ended: a Summary of it, and the error
+// that aborted it if it didn't finish.
+type TranscriptResult struct {
+	Summary
+	Error string `json:"error,omitempty"`
+}
+
+// NewTranscript starts the transcript of the game for seed.
+func NewTranscript(seed int8) *Transcript {
+	return &Transcript{Seed: seed, Packets: []TranscriptPacket{}, Moves: []TranscriptMove{}}
+}
+
+// WithTranscript records the game in t.
+func WithTranscript(t *Transcript) Option {
+	return func(s *Session) { s.transcript = t }
+}
+
+func (t *Transcript) start(board []uint8) {
+	if t == nil {
+		return
+	}
+	t.InitialBoard = boardInts(board)
+}
+
+func (t *Transcript) sent(move StateMoveMessage, at time.Time) {
+	if t == nil {
+		return
+	}
+	if move.GameState == nil && move.MoveRow == -1 {
+		t.sends = 0 // GameStart isn't a move
+	} else {
+		t.sends++
+	}
+	t.packet("sent", move, at)
+}
+
+func (t *Transcript) received(move StateMoveMessage, at time.Time) {
+	if t == nil {
+		return
+	}
+	t.packet("received", move, at)
+}
+
+func (t *Transcript) packet(direction string, move StateMoveMessage, at time.Time) {
+	t.Packets = append(t.Packets, TranscriptPacket{
+		Time:      at,
+		Direction: direction,
+		MoveRow:   int(move.MoveRow),
+		MoveCount: int(move.MoveCount),
+		GameState: boardInts(move.GameState),
+	})
+}
+
+func (t *Transcript) move(player string, move StateMoveMessage) {
+	if t == nil {
+		return
+	}
+	m := TranscriptMove{
+		Number:    len(t.Moves) + 1,
+		Player:    player,
+		MoveRow:   int(move.MoveRow),
+		MoveCount: int(move.MoveCount),
+		Board:     boardInts(move.GameState),
+		NimSum:    int(boardNimSum(move.GameState)),
+	}
+	if player == "client" && t.sends > 1 {
+		m.Retransmissions = t.sends - 1
+	}
+	t.sends = 0
+	t.Moves = append(t.Moves, m)
+}
+
+func (t *Transcript) finish(result Result, err error) {
+	if t == nil {
+		return
+	}
+	t.Result = TranscriptResult{Summary: result.Summary()}
+	if err != nil {
+		t.Result.Error = err.Error()
+	}
+}
+
+// WriteFile writes the transcript to path as JSON, replacing it only once
+// the whole transcript is written.
+func (t *Transcript) WriteFile(path string) error {
+	if err := configfile.WriteJSON(path, t); err != nil {
+		return fmt.Errorf("writing transcript: %w", err)
+	}
+	return nil
+}
+
+// boardInts is board as coin counts, which encode as a JSON array rather
+// than base64 like []uint8; nil stays nil.
+func boardInts(board []uint8) []int {
+	if board == nil {
+		return nil
+	}
+	ints := make([]int, len(board))
+	for i, coins := range board {
+		ints[i] = int(coins)
+	}
+	return ints
+}
@@ -0,0 +1,130 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+// TestTranscriptReconstructsGame replays a harness game's transcript from
+// its initial board and checks it arrives at every board the game saw.
+func TestTranscriptReconstructsGame(t *testing.T) {
+	// packet 2, the client's first move, is lost once
+	h := &harnessServer{Board: []uint8{3, 4, 5, 6}, Drop: map[int]bool{2: true}}
+	sess := newTestSession(t, &ClientConfig{}, h.start(t))
+	transcript := NewTranscript(7)
+	sess.transcript = transcript
+	var boards [][]int
+	sess.hooks = append(sess.hooks, func(e MoveEvent) { boards = append(boards, boardInts(e.Board)) })
+
+	result, err := sess.Play(context.Background())
+	if err != nil {
+		t.Fatalf("game failed: %v\n", err)
+	}
+	path := filepath.Join(t.TempDir(), "transcript.json")
+	if err := transcript.WriteFile(path); err != nil {
+		t.Fatalf("writing transcript: %v\n", err)
+	}
+	data, _ := os.ReadFile(path)
+	var read Transcript
+	if err := json.Unmarshal(data, &read); err != nil {
+		t.Fatalf("reading transcript back: %v\n", err)
+	}
+
+	board := append([]int(nil), read.InitialBoard...)
+	if !reflect.DeepEqual(board, []int{3, 4, 5, 6}) || len(read.Moves) != len(boards) {
+		t.Fatalf("expected %d moves from [3 4 5 6], got %v and %d moves\n", len(boards), board, len(read.Moves))
+	}
+	for i, move := range read.Moves {
+		board[move.MoveRow] -= move.MoveCount
+		if !reflect.DeepEqual(board, move.Board) || !reflect.DeepEqual(board, boards[i]) {
+			t.Fatalf("move %d: replayed to %v, transcript has %v, game had %v\n", move.Number, board, move.Board, boards[i])
+		}
+		if want := boardNimSum(toBoard(board)); move.NimSum != int(want) {
+			t.Errorf("move %d: nim sum %d, want %d\n", move.Number, move.NimSum, want)
+		}
+		if want := map[bool]int{true: 1}[i == 0]; move.Retransmissions != want {
+			t.Errorf("move %d: %d retransmissions, want %d\n", move.Number, move.Retransmissions, want)
+		}
+	}
+
+	var sent, received int
+	for _, p := range read.Packets {
+		if p.Time.IsZero() {
+			t.Errorf("packet without a time: %+v\n", p)
+		}
+		if p.Direction == "sent" {
+			sent++
+		} else {
+			received++
+		}
+	}
+	// GameStart, each client move and one retransmission; the board, then
+	// a reply to every move but the winning one
+	if sent != result.ClientMoves+2 || received != result.ServerMoves+1 {
+		t.Errorf("expected %d sent and %d received packets, got %d and %d\n", result.ClientMoves+2, result.ServerMoves+1, sent, received)
+	}
+	if read.Seed != 7 || read.Result.Winner != "client" || read.Result.Retransmissions != 1 || read.Result.Error != "" {
+		t.Errorf("unexpected transcript result: seed %d, %+v\n", read.Seed, read.Result)
+	}
+}
+
+func TestTranscriptJSON(t *testing.T) {
+	transcript := NewTranscript(3)
+	transcript.start([]uint8{1, 2})
+	transcript.sent(StateMoveMessage{GameState: []uint8{0, 2}, MoveRow: 0, MoveCount: 1}, time.Unix(0, 0))
+	transcript.move("client", StateMoveMessage{GameState: []uint8{0, 2}, MoveRow: 0, MoveCount: 1})
+	transcript.finish(Result{}, ErrNoReply)
+	data, err := json.Marshal(transcript)
+	if err != nil {
+		t.Fatalf("marshalling transcript: %v\n", err)
+	}
+	var fields struct {
+		Top     map[string]interface{}
+		Packets []map[string]interface{} `json:"packets"`
+		Moves   []map[string]interface{} `json:"moves"`
+		Result  map[string]interface{}   `json:"result"`
+	}
+	json.Unmarshal(data, &fields.Top)
+	json.Unmarshal(data, &fields)
+
+	// tools read these; don't rename them
+	want := map[string][]string{
+		"transcript": {"initial_board", "moves", "packets", "result", "seed"},
+		"packet":     {"direction", "game_state", "move_count", "move_row", "time"},
+		"move":       {"board", "move_count", "move_row", "nim_sum", "number", "player", "retransmissions"},
+		"result": {"client_moves", "duration_ms", "error", "invalid_packets", "max_rtt_ms", "mean_rtt_ms",
+			"p50_rtt_ms", "p95_rtt_ms", "p99_rtt_ms", "retransmissions", "server_moves", "timeouts", "winner"},
+	}
+	got := map[string][]string{
+		"transcript": keys(fields.Top),
+		"packet":     keys(fields.Packets[0]),
+		"move":       keys(fields.Moves[0]),
+		"result":     keys(fields.Result),
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("transcript fields %v, want %v\n", got, want)
+	}
+}
+
+func keys(m map[string]interface{}) []string {
+	var ks []string
+	for k := range m {
+		ks = append(ks, k)
+	}
+	sort.Strings(ks)
+	return ks
+}
+
+func toBoard(ints []int) []uint8 {
+	board := make([]uint8, len(ints))
+	for i, coins := range ints {
+		board[i] = uint8(coins)
+	}
+	return board
+}
@@ -0,0 +1,90 @@
+package client
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"nimgame/pkg/nimerr"
+)
+
+// validTestConfig passes ValidateConfig.
+func validTestConfig() *ClientConfig {
+	return &ClientConfig{
+		ClientAddress:        "127.0.0.1:0",
+		NimServerAddresses:   []string{"127.0.0.1:1"},
+		TracingServerAddress: "127.0.0.1:2",
+		Secret:               []byte("secret"),
+	}
+}
+
+func TestValidateConfig(t *testing.T) {
+	if err := ValidateConfig(validTestConfig()); err != nil {
+		t.Fatalf("unexpected validation error: %v\n", err)
+	}
+	ephemeral := validTestConfig()
+	ephemeral.ClientAddress = ""
+	if err := ValidateConfig(ephemeral); err != nil {
+		t.Errorf("empty ClientAddress should pick a free port, got %v\n", err)
+	}
+	ipv6 := validTestConfig()
+	ipv6.ClientAddress, ipv6.NimServerAddresses = "[::1]:0", []string{"[::1]:1"}
+	if err := ValidateConfig(ipv6); err != nil {
+		t.Errorf("unexpected validation error for IPv6 addresses: %v\n", err)
+	}
+
+	badRate := -0.5
+	tests := []struct {
+		field string
+		spoil func(*ClientConfig)
+	}{
+		{"ClientAddress", func(c *ClientConfig) { c.ClientAddress = "localhost" }},
+		{"NimServerAddresses", func(c *ClientConfig) { c.NimServerAddresses = nil }},
+		{"NimServerAddresses", func(c *ClientConfig) { c.NimServerAddresses = []string{"127.0.0.1:1", "nowhere"} }},
+		{"NimServerAddresses", func(c *ClientConfig) { c.NimServerAddresses = []string{"[::1]:1"} }},
+		{"ClientAddress", func(c *ClientConfig) { c.IPv6, c.NimServerAddresses = true, []string{"localhost:1"} }},
+		{"TracingServerAddress", func(c *ClientConfig) { c.TracingServerAddress = "x" }},
+		{"Secret", func(c *ClientConfig) { c.Secret = nil }},
+		{"LogLevel", func(c *ClientConfig) { c.LogLevel = "chatty" }},
+		{"RetryBaseMs", func(c *ClientConfig) { c.RetryBaseMs = -1 }},
+		{"RetryCapMs", func(c *ClientConfig) { c.RetryBaseMs, c.RetryCapMs = 100, 10 }},
+		{"RetryMultiplier", func(c *ClientConfig) { c.RetryMultiplier = 0.5 }},
+		{"MaxRetries", func(c *ClientConfig) { c.MaxRetries = -3 }},
+		{"MaxGameDurationSeconds", func(c *ClientConfig) { c.MaxGameDurationSeconds = -1 }},
+		{"FCheckHbeatLocalAddr", func(c *ClientConfig) { c.FCheckLostMsgsThresh, c.FCheckHbeatLocalAddr = 3, "x" }},
+		{"FCheckServerAddresses", func(c *ClientConfig) {
+			c.FCheckLostMsgsThresh, c.FCheckHbeatLocalAddr, c.FCheckServerAddresses = 3, "127.0.0.1:0", []string{"x"}
+		}},
+		{"CompressionMode", func(c *ClientConfig) { c.CompressionMode = "gzip" }},
+		{"BoardProfile", func(c *ClientConfig) { c.BoardProfile = "lumpy" }},
+		{"BoardGuarantee", func(c *ClientConfig) { c.BoardGuarantee = "me" }},
+		{"VerifyInitialBoard", func(c *ClientConfig) { c.BoardProfile, c.VerifyInitialBoard = "custom", true }},
+		{"TracingSampleRate", func(c *ClientConfig) { c.TracingSampleRate = &badRate }},
+		{"Transport", func(c *ClientConfig) { c.Transport = "sctp" }},
+		{"Transport", func(c *ClientConfig) { c.Transport, c.QuicEnabled = "tcp", true }},
+		{"DTLSCAFile", func(c *ClientConfig) { c.Transport = "dtls" }},
+		{"TCPFallback", func(c *ClientConfig) { c.Transport, c.TCPFallback = "dtls", true }},
+		{"GameResultsFile", func(c *ClientConfig) { c.AutoEscalate, c.EscalationThreshold = true, 0.7 }},
+		{"EscalationThreshold", func(c *ClientConfig) { c.AutoEscalate, c.GameResultsFile = true, "results" }},
+	}
+	for _, test := range tests {
+		config := validTestConfig()
+		test.spoil(config)
+		err := ValidateConfig(config)
+		if err == nil || !strings.Contains(err.Error(), test.field) {
+			t.Errorf("expected a problem with %v, got %v\n", test.field, err)
+		} else if !errors.Is(err, nimerr.ErrConfig) {
+			t.Errorf("problem with %v is not a config error: %v\n", test.field, err)
+		}
+	}
+}
+
+func TestReadConfigUnknownField(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "client_config.json")
+	os.WriteFile(path, []byte(`{"ClientAdress": "127.0.0.1:1"}`), 0644)
+	if _, err := ReadConfig(path); err == nil || !strings.Contains(err.Error(), "ClientAdress") {
+		t.Errorf("expected the misspelt field to be reported, got %v\n", err)
+	}
+}
@@ -0,0 +1,143 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"nimgame/internal/wiredump"
+	"nimgame/pkg/nim"
+	"nimgame/pkg/nimserver"
+)
+
+// wireDumps is the directory of the server's tests' dumps of canonical
+// packets for the current wire version; see wireVersion there.
+var wireDumps = filepath.Join("..", "nimserver", "testdata", "wire", "current")
+
+// readWireDump reads the canonical packet name from dir.
+func readWireDump(t *testing.T, dir, name string) []byte {
+	packet, err := wiredump.Read(filepath.Join(dir, name+".hex"))
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	return packet
+}
+
+// TestWireCompatibility checks the client writes the canonical packets it
+// sends byte for byte as the server does, and reads those the server sends.
+func TestWireCompatibility(t *testing.T) {
+	dir := wireDumps
+	sent := map[string]StateMoveMessage{
+		"game_start":  {GameState: nil, MoveRow: -1, MoveCount: 5},
+		"client_move": {GameState: []uint8{2, 3, 4}, MoveRow: 0, MoveCount: 1},
+		"client_move_traced": {
+			GameState:         []uint8{2, 3, 4},
+			MoveRow:           0,
+			MoveCount:         1,
+			TracingServerAddr: "127.0.0.1:6000",
+			Token:             []byte{0x01, 0x02, 0x03, 0x04},
+		},
+		"sync":            {GameState: nil, MoveRow: syncMoveRow},
+		"session_resume":  {GameState: nil, MoveRow: sessionResumeMoveRow, GameID: "6f3a9c01d2e4b587"},
+		"lasker_start":    {GameState: nil, MoveRow: -1, MoveCount: 5, Variant: nim.LaskerVariant},
+		"wythoff_start":   {GameState: nil, MoveRow: -1, MoveCount: 5, Variant: nim.WythoffVariant},
+		"profile_start":   {GameState: nil, MoveRow: -1, MoveCount: 5, BoardProfile: nim.ProfileSkewed},
+		"guarantee_start": {GameState: nil, MoveRow: -1, MoveCount: 5, BoardGuarantee: nim.GuaranteeSecond},
+		"match_start":     {GameState: nil, MoveRow: -1, MoveCount: 5, MatchGames: 3},
+		"timed_start":     {GameState: nil, MoveRow: -1, MoveCount: 5, TimeControl: 60000},
+		"timed_move":      {GameState: []uint8{1, 3, 3}, MoveRow: 2, MoveCount: 1, Seq: 2},
+	}
+	for name, move := range sent {
+		if got, want := encode(&move), readWireDump(t, dir, name); !bytes.Equal(got, want) {
+			t.Errorf("%v: encoded as %x, expected %x\n", name, got, want)
+		}
+	}
+
+	board := []uint8{1, 3, 4}
+	received := map[string]StateMoveMessage{
+		"server_board": {GameState: []uint8{3, 3, 4}, MoveRow: -1, MoveCount: 5, GameID: "6f3a9c01d2e4b587"},
+		"server_move": {
+			GameState:  board,
+			MoveRow:    0,
+			MoveCount:  1,
+			MerkleRoot: nim.ComputeMerkleRoot(board),
+			GameID:     "6f3a9c01d2e4b587",
+		},
+		"server_move_rle": {GameState: []uint8{5, 5, 5, 5, 0, 0, 7}, MoveRow: 6, MoveCount: 2},
+		"concede":         {GameState: nil, MoveRow: -2, MoveCount: -2},
+		"forfeit":         {MoveRow: -12, MoveCount: -12, TracingServerAddr: "127.0.0.1:6000"},
+		"lasker_split":    {GameState: []uint8{3, 1, 2, 4}, MoveRow: 1, GameID: "6f3a9c01d2e4b587", Variant: nim.LaskerVariant},
+		"wythoff_both":    {GameState: []uint8{1, 2}, MoveRow: nim.WythoffBothRows, MoveCount: 3, GameID: "6f3a9c01d2e4b587", Variant: nim.WythoffVariant},
+		"match_opening":   {GameState: []uint8{1, 3, 4}, MoveRow: 0, MoveCount: 2, GameID: "6f3a9c01d2e4b587", MatchGames: 3, MatchClientWins: 1},
+		"match_complete":  {MoveRow: matchCompleteMoveRow, MoveCount: matchCompleteMoveRow, GameID: "6f3a9c01d2e4b587", MatchGames: 3, MatchClientWins: 2, MatchServerWins: 1},
+		"draining":        {MoveRow: drainingMoveRow, MoveCount: drainingMoveRow},
+		"timed_reply":     {GameState: []uint8{1, 3, 4}, MoveRow: 0, MoveCount: 1, GameID: "6f3a9c01d2e4b587", TimeControl: 60000, ClientClock: 50001, ServerClock: 59999, Seq: 2},
+		"dry_run_board":   {GameState: []uint8{3, 3, 4}, MoveRow: -1, MoveCount: 5, DryRun: true},
+		"timeout":         {MoveRow: forfeitMoveRow, MoveCount: forfeitMoveRow, TimeControl: 60000, ServerClock: 59998, Seq: 5},
+	}
+	for name, want := range received {
+		packet := readWireDump(t, dir, name)
+		got, err := decode(packet, len(packet))
+		if err != nil {
+			t.Errorf("%v: decoding: %v\n", name, err)
+		} else if !reflect.DeepEqual(got, want) {
+			t.Errorf("%v decoded to %+v, expected %+v\n", name, got, want)
+		}
+	}
+}
+
+// TestPlayAgainstServer plays a game over UDP with the nim server itself,
+// rather than the harness.
+func TestPlayAgainstServer(t *testing.T) {
+	server := startServer(t, &nimserver.ServerConfig{}, "127.0.0.1:0")
+	sess := newTestSession(t, &ClientConfig{}, server.Addr().(*net.UDPAddr))
+
+	result, err := sess.Play(context.Background())
+	if err != nil {
+		t.Fatalf("game failed: %v\n", err)
+	}
+	if result.Winner == "" {
+		t.Errorf("game ended with %+v, expected a winner\n", result)
+	}
+	if stats := server.Stats(); stats.GamesStarted != 1 || stats.InvalidMoves != 0 {
+		t.Errorf("server stats are %+v, expected one game and no invalid moves\n", stats)
+	}
+}
+
+func TestPlayOverIPv6(t *testing.T) {
+	server := startServer(t, &nimserver.ServerConfig{}, "[::1]:0")
+	addr := server.Addr().(*net.UDPAddr)
+	if addr.IP.To4() != nil {
+		t.Fatalf("server listens on %v, expected IPv6 loopback\n", addr)
+	}
+	sess := newTestSession(t, &ClientConfig{ClientAddress: "[::1]:0"}, addr)
+
+	result, err := sess.Play(context.Background())
+	if err != nil {
+		t.Fatalf("game over IPv6 failed: %v\n", err)
+	}
+	if result.Winner == "" {
+		t.Errorf("game over IPv6 ended with %+v, expected a winner\n", result)
+	}
+	if stats := server.Stats(); stats.GamesStarted != 1 || stats.InvalidMoves != 0 {
+		t.Errorf("server stats are %+v, expected one game and no invalid moves\n", stats)
+	}
+}
+
+// TestPlayBoardProfile asks the server for a board of a single heap, and
+// checks it is the one the profile makes for the seed.
+func TestPlayBoardProfile(t *testing.T) {
+	server := startServer(t, &nimserver.ServerConfig{}, "127.0.0.1:0")
+	sess := newTestSession(t, &ClientConfig{BoardProfile: nim.ProfileSingle, VerifyInitialBoard: true}, server.Addr().(*net.UDPAddr))
+
+	result, err := sess.Play(context.Background())
+	if err != nil {
+		t.Fatalf("game on a single heap failed: %v\n", err)
+	}
+	if len(sess.initial) != 1 || result.Winner == "" {
+		t.Errorf("game started on %v and ended with %+v, expected a winner on a single heap\n", sess.initial, result)
+	}
+}
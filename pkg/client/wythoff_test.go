@@ -0,0 +1,60 @@
+package client
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"nimgame/pkg/nim"
+	"nimgame/pkg/nimserver"
+)
+
+// bothHeaps counts the moves the client takes from both heaps in sess's
+// game.
+func bothHeaps(sess *Session) *int {
+	both := new(int)
+	sess.hooks = append(sess.hooks, func(e MoveEvent) {
+		if e.Player == "client" && e.Row == nim.WythoffBothRows {
+			*both++
+		}
+	})
+	return both
+}
+
+func TestPlayWythoff(t *testing.T) {
+	server := startServer(t, &nimserver.ServerConfig{WythoffEnabled: true}, "127.0.0.1:0")
+	sess := newTestSession(t, &ClientConfig{Variant: nim.WythoffVariant}, server.Addr().(*net.UDPAddr))
+	sess.strategy = nim.WythoffOptimal{}
+	both := bothHeaps(sess)
+
+	result, err := sess.Play(context.Background())
+	if err != nil {
+		t.Fatalf("game of Wythoff's game failed: %v\n", err)
+	}
+	if result.Winner != "client" || sess.variant != nim.WythoffVariant {
+		t.Errorf("game ended with %+v, variant %q, expected the client to win Wythoff's game\n", result, sess.variant)
+	}
+	if stats := server.Stats(); stats.GamesStarted != 1 || stats.InvalidMoves != 0 {
+		t.Errorf("server stats are %+v after %d moves from both heaps, expected one game and no invalid moves\n", stats, *both)
+	}
+}
+
+// TestWythoffRefused plays against a server without Wythoff's game, which
+// the client asks for but plays nim with.
+func TestWythoffRefused(t *testing.T) {
+	server := startServer(t, &nimserver.ServerConfig{}, "127.0.0.1:0")
+	sess := newTestSession(t, &ClientConfig{Variant: nim.WythoffVariant}, server.Addr().(*net.UDPAddr))
+	sess.strategy = nim.WythoffOptimal{}
+	both := bothHeaps(sess)
+
+	result, err := sess.Play(context.Background())
+	if err != nil {
+		t.Fatalf("game failed: %v\n", err)
+	}
+	if result.Winner == "" || *both != 0 || sess.variant != "" {
+		t.Errorf("game ended with %+v after %d moves from both heaps, variant %q, expected a winner of nim\n", result, *both, sess.variant)
+	}
+	if stats := server.Stats(); stats.InvalidMoves != 0 {
+		t.Errorf("server stats are %+v, expected no invalid moves\n", stats)
+	}
+}
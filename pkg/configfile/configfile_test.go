@@ -0,0 +1,92 @@
+package configfile
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type testConfig struct {
+	Address string
+	Retries int
+}
+
+func TestReadComments(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	data := `// top comment
+{
+    // where to listen
+    "Address": "127.0.0.1:1000",
+	  // indented comment
+    "Retries": 3
+}
+`
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	var cfg testConfig
+	if err := Read(path, &cfg); err != nil {
+		t.Fatalf("reading config: %v\n", err)
+	}
+	if cfg != (testConfig{"127.0.0.1:1000", 3}) {
+		t.Errorf("got %+v\n", cfg)
+	}
+}
+
+func TestReadErrors(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		name, data, want string
+	}{
+		{"unknown field", `{"Adress": "x"}`, `unknown field "Adress"`},
+		{"bad json", `{"Retries": "three"}`, "cannot unmarshal"},
+	}
+	for _, test := range tests {
+		path := filepath.Join(dir, strings.ReplaceAll(test.name, " ", "_"))
+		os.WriteFile(path, []byte(test.data), 0644)
+		err := Read(path, new(testConfig))
+		if err == nil || !strings.Contains(err.Error(), test.want) {
+			t.Errorf("%v: got error %v, expected it to mention %q\n", test.name, err, test.want)
+		}
+	}
+	if err := Read(filepath.Join(dir, "missing.json"), new(testConfig)); err == nil {
+		t.Errorf("reading a missing file succeeded\n")
+	}
+}
+
+func TestWriteForce(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := Write(path, []byte("one"), false); err != nil {
+		t.Fatalf("writing new file: %v\n", err)
+	}
+	if err := Write(path, []byte("two"), false); err == nil || !strings.Contains(err.Error(), "-force") {
+		t.Errorf("overwrite without force: got error %v\n", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "one" {
+		t.Errorf("file changed to %q without force\n", data)
+	}
+	if err := Write(path, []byte("two"), true); err != nil {
+		t.Fatalf("overwrite with force: %v\n", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "two" {
+		t.Errorf("got %q after forced overwrite\n", data)
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "state.json")
+	for _, want := range []testConfig{{"127.0.0.1:1", 1}, {"127.0.0.1:2", 2}} {
+		if err := WriteJSON(path, want); err != nil {
+			t.Fatalf("writing %+v: %v\n", want, err)
+		}
+		var got testConfig
+		if err := Read(path, &got); err != nil || got != want {
+			t.Errorf("read back %+v, %v, expected %+v\n", got, err, want)
+		}
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("temporary files left behind: %v\n", entries)
+	}
+}
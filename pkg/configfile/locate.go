@@ -0,0 +1,93 @@
+package configfile
+
+import (
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// EnvVar names the environment variable that points at a config file.
+const EnvVar = "NIM_CONFIG"
+
+// userDir is the directory under os.UserConfigDir holding config files.
+const userDir = "nimgame"
+
+// Overridden by tests.
+var (
+	executable    = os.Executable
+	userConfigDir = os.UserConfigDir
+)
+
+// Locate returns the path of the config file named name, such as
+// "server_config.json", logging where it was found. It looks, in order:
+//
+//   - at flagPath, if the -config flag was given
+//   - at $NIM_CONFIG, if set
+//   - in the current directory
+//   - in the directory holding the executable
+//   - in nimgame under os.UserConfigDir, e.g. ~/.config/nimgame
+//
+// A file named by flagPath or $NIM_CONFIG must exist. In the current and
+// executable directories name is also looked for in config and ../config,
+// where the repository keeps it.
+func Locate(flagPath, name string, getenv func(string) string) (string, error) {
+	if flagPath != "" {
+		return found(flagPath, "-config flag")
+	}
+	if path := getenv(EnvVar); path != "" {
+		return found(path, "$"+EnvVar)
+	}
+
+	var tried []string
+	try := func(dir, source string) (string, bool) {
+		for _, path := range []string{
+			filepath.Join(dir, name),
+			filepath.Join(dir, "config", name),
+			filepath.Join(dir, "..", "config", name),
+		} {
+			tried = append(tried, path)
+			if isFile(path) {
+				slog.Info("using config file", "path", path, "from", source)
+				return path, true
+			}
+		}
+		return "", false
+	}
+	if wd, err := os.Getwd(); err == nil {
+		if path, ok := try(wd, "current directory"); ok {
+			return path, nil
+		}
+	}
+	if exe, err := executable(); err == nil {
+		if path, ok := try(filepath.Dir(exe), "executable directory"); ok {
+			return path, nil
+		}
+	}
+	if dir, err := userConfigDir(); err == nil {
+		path := filepath.Join(dir, userDir, name)
+		tried = append(tried, path)
+		if isFile(path) {
+			slog.Info("using config file", "path", path, "from", "user config directory")
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("%w: no %v in %v; name one with -config or $%v",
+		fs.ErrNotExist, name, strings.Join(tried, ", "), EnvVar)
+}
+
+// found checks that the explicitly named path exists.
+func found(path, source string) (string, error) {
+	if _, err := os.Stat(path); err != nil {
+		return "", fmt.Errorf("config file from %v: %w", source, err)
+	}
+	slog.Info("using config file", "path", path, "from", source)
+	return path, nil
+}
+
+func isFile(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
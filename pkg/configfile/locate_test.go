@@ -0,0 +1,128 @@
+package configfile
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// locateDirs sets up a temp dir each for the working directory, the
+// executable and the user config dir, and points Locate at them.
+func locateDirs(t *testing.T) (wd, exe, user string) {
+	root := t.TempDir()
+	wd, exe, user = filepath.Join(root, "wd"), filepath.Join(root, "bin"), filepath.Join(root, "user")
+	for _, dir := range []string{wd, exe, filepath.Join(user, userDir)} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Chdir(wd)
+	oldExe, oldUser := executable, userConfigDir
+	executable = func() (string, error) { return filepath.Join(exe, "server"), nil }
+	userConfigDir = func() (string, error) { return user, nil }
+	t.Cleanup(func() { executable, userConfigDir = oldExe, oldUser })
+	return wd, exe, user
+}
+
+func touch(t *testing.T, path string) string {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLocateOrder(t *testing.T) {
+	const name = "server_config.json"
+	tests := []struct {
+		name   string
+		levels int // how many of the levels below have a config file
+	}{
+		{"user config dir", 1},
+		{"executable dir", 2},
+		{"current dir", 3},
+		{"$NIM_CONFIG", 4},
+		{"-config flag", 5},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			wd, exe, user := locateDirs(t)
+			explicit := filepath.Join(t.TempDir(), "flag.json")
+			fromEnv := filepath.Join(t.TempDir(), "env.json")
+			// from the lowest level up
+			levels := []string{
+				filepath.Join(user, userDir, name),
+				filepath.Join(exe, name),
+				filepath.Join(wd, name),
+				fromEnv,
+				explicit,
+			}
+			for _, path := range levels[:test.levels] {
+				touch(t, path)
+			}
+			flagPath, env := "", ""
+			if test.levels >= 4 {
+				env = fromEnv
+			}
+			if test.levels >= 5 {
+				flagPath = explicit
+			}
+
+			path, err := Locate(flagPath, name, func(k string) string {
+				if k == EnvVar {
+					return env
+				}
+				return ""
+			})
+			if err != nil || path != levels[test.levels-1] {
+				t.Errorf("got %v, %v, expected %v\n", path, err, levels[test.levels-1])
+			}
+		})
+	}
+}
+
+func TestLocateRepoLayout(t *testing.T) {
+	const name = "client_config.json"
+	tests := []struct {
+		name string
+		file func(wd, exe string) string
+	}{
+		{"config dir under current dir", func(wd, exe string) string { return filepath.Join(wd, "config", name) }},
+		{"config dir beside current dir", func(wd, exe string) string { return filepath.Join(wd, "..", "config", name) }},
+		{"config dir beside executable dir", func(wd, exe string) string { return filepath.Join(exe, "..", "config", name) }},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			wd, exe, _ := locateDirs(t)
+			want := touch(t, test.file(wd, exe))
+			path, err := Locate("", name, func(string) string { return "" })
+			if err != nil || path != want {
+				t.Errorf("got %v, %v, expected %v\n", path, err, want)
+			}
+		})
+	}
+}
+
+func TestLocateMissing(t *testing.T) {
+	locateDirs(t)
+	missing := filepath.Join(t.TempDir(), "missing.json")
+	tests := []struct {
+		name     string
+		flagPath string
+		env      string
+	}{
+		{"nothing anywhere", "", ""},
+		{"missing -config", missing, ""},
+		{"missing $NIM_CONFIG", "", missing},
+	}
+	for _, test := range tests {
+		_, err := Locate(test.flagPath, "server_config.json", func(string) string { return test.env })
+		if !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("%v: got error %v, expected it not to exist\n", test.name, err)
+		}
+	}
+}
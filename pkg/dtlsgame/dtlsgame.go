@@ -0,0 +1,226 @@
+// Package dtlsgame carries nim moves over DTLS, for games played across
+// the open internet. A move is sent as it is over UDP, gob encoded, one to
+// a record. The server proves who it is with its certificate or, without
+// one, both sides with a key derived from the pre-shared key they share.
+package dtlsgame
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"time"
+
+	"nimgame/pkg/udpnet"
+
+	"github.com/pion/dtls/v3"
+	dtlsnet "github.com/pion/dtls/v3/pkg/net"
+	"github.com/pion/dtls/v3/pkg/protocol"
+	"github.com/pion/dtls/v3/pkg/protocol/recordlayer"
+	"github.com/pion/transport/v5/udp"
+)
+
+// handshakeTimeout bounds a handshake, which a peer that is down, isn't
+// speaking DTLS or holds another key never finishes.
+var handshakeTimeout = 5 * time.Second
+
+// pskIdentity is the identity hint sent with a pre-shared key; there is only
+// the one key, so it is never looked at.
+const pskIdentity = "nim"
+
+// PSK derives the key client and server authenticate each other with from
+// the pre-shared key psk.
+func PSK(psk []byte) []byte {
+	key := sha256.Sum256(append([]byte("nim dtls psk\x00"), psk...))
+	return key[:]
+}
+
+// pskOptions authenticate both sides by the key derived from psk.
+func pskOptions(psk []byte) []dtls.Option {
+	key := PSK(psk)
+	return []dtls.Option{
+		dtls.WithPSK(func([]byte) ([]byte, error) { return key, nil }),
+		dtls.WithPSKIdentityHint([]byte(pskIdentity)),
+		dtls.WithCipherSuites(dtls.TLS_PSK_WITH_AES_128_GCM_SHA256),
+	}
+}
+
+// ServerOptions configures a server with the PEM certificate and key at
+// certFile and keyFile or, when they are empty, the key derived from psk.
+func ServerOptions(certFile, keyFile string, psk []byte) ([]dtls.ServerOption, error) {
+	if certFile == "" {
+		var opts []dtls.ServerOption
+		for _, opt := range pskOptions(psk) {
+			opts = append(opts, opt)
+		}
+		return opts, nil
+	}
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("loading DTLS certificate: %w", err)
+	}
+	return []dtls.ServerOption{
+		dtls.WithCertificates(cert),
+		dtls.WithExtendedMasterSecret(dtls.RequireExtendedMasterSecret),
+	}, nil
+}
+
+// ClientOptions configures a client to verify the server's certificate
+// against the PEM certificates in caFile, for the host Dial is given, or,
+// when caFile is empty, to authenticate both sides with the key derived
+// from psk.
+func ClientOptions(caFile string, psk []byte) ([]dtls.ClientOption, error) {
+	if caFile == "" {
+		var opts []dtls.ClientOption
+		for _, opt := range pskOptions(psk) {
+			opts = append(opts, opt)
+		}
+		return opts, nil
+	}
+	certs, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, fmt.Errorf("reading DTLS CA file: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(certs) {
+		return nil, fmt.Errorf("no certificates in DTLS CA file %v", caFile)
+	}
+	return []dtls.ClientOption{
+		dtls.WithRootCAs(pool),
+		dtls.WithExtendedMasterSecret(dtls.RequireExtendedMasterSecret),
+	}, nil
+}
+
+// Listen serves DTLS on addr, returning connections whose handshakes are
+// yet to run. A packet from a new address that doesn't start a handshake is
+// dropped, and dropped, if not nil, is called.
+func Listen(addr string, opts []dtls.ServerOption, dropped func()) (net.Listener, error) {
+	network := udpnet.Network(addr, false)
+	laddr, err := net.ResolveUDPAddr(network, addr)
+	if err != nil {
+		return nil, err
+	}
+	config := udp.ListenConfig{
+		AcceptFilter: func(packet []byte) bool {
+			if isHandshake(packet) {
+				return true
+			}
+			if dropped != nil {
+				dropped()
+			}
+			return false
+		},
+	}
+	parent, err := config.Listen(network, laddr)
+	if err != nil {
+		return nil, err
+	}
+	lis, err := dtls.NewListenerWithOptions(dtlsnet.PacketListenerFromListener(parent), opts...)
+	if err != nil {
+		parent.Close()
+		return nil, err
+	}
+	return lis, nil
+}
+
+// isHandshake reports whether packet begins with a DTLS handshake record.
+func isHandshake(packet []byte) bool {
+	records, err := recordlayer.UnpackDatagram(packet)
+	if err != nil || len(records) == 0 {
+		return false
+	}
+	var header recordlayer.Header
+	return header.Unmarshal(records[0]) == nil && header.ContentType == protocol.ContentTypeHandshake
+}
+
+// Handshake runs conn's handshake, if conn is a DTLS connection that hasn't,
+// giving up after handshakeTimeout.
+func Handshake(conn net.Conn) error {
+	c, ok := conn.(*dtls.Conn)
+	if !ok {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), handshakeTimeout)
+	defer cancel()
+	if err := c.HandshakeContext(ctx); err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			err = fmt.Errorf("unfinished after %v; is the peer serving DTLS, with the same Secret?", handshakeTimeout)
+		}
+		return fmt.Errorf("DTLS handshake with %v failed: %w", conn.RemoteAddr(), err)
+	}
+	return nil
+}
+
+// Dial connects to the DTLS server at addr, from laddr if it isn't nil, and
+// runs the handshake.
+func Dial(addr string, laddr *net.UDPAddr, opts []dtls.ClientOption) (net.Conn, error) {
+	network := udpnet.Network(addr, false)
+	raddr, err := net.ResolveUDPAddr(network, addr)
+	if err != nil {
+		return nil, err
+	}
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		opts = append(opts[:len(opts):len(opts)], dtls.WithServerName(host))
+	}
+	pconn, err := net.ListenUDP(network, laddr)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := dtls.ClientWithOptions(pconn, raddr, opts...)
+	if err != nil {
+		pconn.Close()
+		return nil, err
+	}
+	if err := Handshake(conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+// SelfSigned makes up a certificate for hosts, names or IP addresses, and
+// its key, PEM encoded, for trying DTLS out without a CA: the certificate
+// is its own CA file for clients.
+func SelfSigned(hosts ...string) (certPEM, keyPEM []byte, err error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "nim"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(365 * 24 * time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	for _, host := range hosts {
+		if ip := net.ParseIP(host); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, host)
+		}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, nil, err
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), nil
+}
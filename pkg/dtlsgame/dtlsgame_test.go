@@ -0,0 +1,132 @@
+package dtlsgame
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// writeSelfSigned writes a certificate for 127.0.0.1 and its key to the
+// test's temporary directory, returning their paths.
+func writeSelfSigned(t *testing.T) (string, string) {
+	certPEM, keyPEM, err := SelfSigned("127.0.0.1")
+	if err != nil {
+		t.Fatalf("making certificate: %v\n", err)
+	}
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	os.WriteFile(certFile, certPEM, 0644)
+	os.WriteFile(keyFile, keyPEM, 0600)
+	return certFile, keyFile
+}
+
+// echo serves lis until the test ends, sending back each packet read.
+func echo(t *testing.T, lis net.Listener) {
+	t.Cleanup(func() { lis.Close() })
+	go func() {
+		for {
+			conn, err := lis.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				buf := make([]byte, 1024)
+				for {
+					n, err := conn.Read(buf)
+					if err != nil {
+						return
+					}
+					conn.Write(buf[:n])
+				}
+			}()
+		}
+	}()
+}
+
+// roundTrip sends ping over conn and checks it comes back.
+func roundTrip(t *testing.T, conn net.Conn) {
+	defer conn.Close()
+	if _, err := conn.Write([]byte("ping")); err != nil {
+		t.Fatalf("writing: %v\n", err)
+	}
+	buf := make([]byte, 16)
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if n, err := conn.Read(buf); err != nil || string(buf[:n]) != "ping" {
+		t.Errorf("read %q, %v, expected the ping back\n", buf[:n], err)
+	}
+}
+
+func TestCertificate(t *testing.T) {
+	certFile, keyFile := writeSelfSigned(t)
+	serverOpts, err := ServerOptions(certFile, keyFile, nil)
+	if err != nil {
+		t.Fatalf("server options: %v\n", err)
+	}
+	lis, err := Listen("127.0.0.1:0", serverOpts, nil)
+	if err != nil {
+		t.Fatalf("listening: %v\n", err)
+	}
+	echo(t, lis)
+
+	clientOpts, err := ClientOptions(certFile, nil)
+	if err != nil {
+		t.Fatalf("client options: %v\n", err)
+	}
+	conn, err := Dial(lis.Addr().String(), nil, clientOpts)
+	if err != nil {
+		t.Fatalf("dialing: %v\n", err)
+	}
+	roundTrip(t, conn)
+
+	// a certificate the client doesn't trust fails the handshake
+	otherCert, _ := writeSelfSigned(t)
+	clientOpts, _ = ClientOptions(otherCert, nil)
+	if _, err := Dial(lis.Addr().String(), nil, clientOpts); err == nil || !strings.Contains(err.Error(), "DTLS handshake") {
+		t.Errorf("dialing with the wrong CA got %v, expected a failed handshake\n", err)
+	}
+}
+
+func TestPSK(t *testing.T) {
+	serverOpts, _ := ServerOptions("", "", []byte("secret"))
+	var dropped atomic.Int32
+	lis, err := Listen("127.0.0.1:0", serverOpts, func() { dropped.Add(1) })
+	if err != nil {
+		t.Fatalf("listening: %v\n", err)
+	}
+	echo(t, lis)
+
+	clientOpts, _ := ClientOptions("", []byte("secret"))
+	conn, err := Dial(lis.Addr().String(), nil, clientOpts)
+	if err != nil {
+		t.Fatalf("dialing: %v\n", err)
+	}
+	roundTrip(t, conn)
+
+	// the server can't tell the client its key is wrong, so it waits out
+	// the handshake
+	defer func(timeout time.Duration) { handshakeTimeout = timeout }(handshakeTimeout)
+	handshakeTimeout = 200 * time.Millisecond
+	wrongOpts, _ := ClientOptions("", []byte("guess"))
+	if _, err := Dial(lis.Addr().String(), nil, wrongOpts); err == nil || !strings.Contains(err.Error(), "DTLS handshake") {
+		t.Errorf("dialing with the wrong secret got %v, expected a failed handshake\n", err)
+	}
+
+	// a plain UDP packet never makes a connection
+	plain, err := net.Dial("udp", lis.Addr().String())
+	if err != nil {
+		t.Fatalf("dialing over UDP: %v\n", err)
+	}
+	defer plain.Close()
+	plain.Write([]byte("not a handshake"))
+	for deadline := time.Now().Add(time.Second); dropped.Load() == 0 && time.Now().Before(deadline); {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if got := dropped.Load(); got != 1 {
+		t.Errorf("%d packets dropped, expected the plain UDP one\n", got)
+	}
+}
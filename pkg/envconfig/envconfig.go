@@ -0,0 +1,142 @@
+// Package envconfig overrides config struct fields from environment
+// variables.
+//
+// Each exported field is read from prefix + its name in upper snake case,
+// so ClientAddress is NIM_CLIENT_ADDRESS under the "NIM_" prefix. An
+// `env:"NAME"` tag renames the variable (still prefixed) and `env:"-"`
+// skips the field. Unset or empty variables leave fields alone.
+//
+// Supported field types are strings, bools, integers, floats, pointers to
+// any of those, []string (comma separated) and []byte (base64).
+package envconfig
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Apply overrides the fields of the struct cfg points to with the
+// variables getenv returns, reporting every variable that couldn't be
+// parsed.
+func Apply(cfg interface{}, prefix string, getenv func(string) string) error {
+	v := reflect.ValueOf(cfg)
+	if v.Kind() != reflect.Pointer || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("envconfig: need a pointer to a struct, got %T", cfg)
+	}
+	v = v.Elem()
+
+	var errs []error
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		name, ok := VarName(field, prefix)
+		if !ok {
+			continue
+		}
+		value := getenv(name)
+		if value == "" {
+			continue
+		}
+		if err := set(v.Field(i), value); err != nil {
+			errs = append(errs, fmt.Errorf("%s=%q: %w", name, value, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// VarName is the variable that sets field, or false if none does.
+func VarName(field reflect.StructField, prefix string) (string, bool) {
+	if !field.IsExported() {
+		return "", false
+	}
+	name := field.Tag.Get("env")
+	if name == "-" {
+		return "", false
+	}
+	if name == "" {
+		name = upperSnake(field.Name)
+	}
+	return prefix + name, true
+}
+
+func set(field reflect.Value, value string) error {
+	if field.Kind() == reflect.Pointer {
+		elem := reflect.New(field.Type().Elem())
+		if err := set(elem.Elem(), value); err != nil {
+			return err
+		}
+		field.Set(elem)
+		return nil
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return errors.New("not true or false")
+		}
+		field.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, field.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("not an integer that fits in %v", field.Type())
+		}
+		field.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(value, 10, field.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("not an integer that fits in %v", field.Type())
+		}
+		field.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(value, field.Type().Bits())
+		if err != nil {
+			return errors.New("not a number")
+		}
+		field.SetFloat(f)
+	case reflect.Slice:
+		switch field.Type().Elem().Kind() {
+		case reflect.Uint8:
+			b, err := base64.StdEncoding.DecodeString(value)
+			if err != nil {
+				return errors.New("not valid base64")
+			}
+			field.SetBytes(b)
+		case reflect.String:
+			parts := strings.Split(value, ",")
+			for i := range parts {
+				parts[i] = strings.TrimSpace(parts[i])
+			}
+			field.Set(reflect.ValueOf(parts))
+		default:
+			return fmt.Errorf("unsupported field type %v", field.Type())
+		}
+	default:
+		return fmt.Errorf("unsupported field type %v", field.Type())
+	}
+	return nil
+}
+
+// upperSnake turns a Go identifier into UPPER_SNAKE_CASE, keeping acronyms
+// together: GRPCAddress becomes GRPC_ADDRESS.
+func upperSnake(name string) string {
+	runes := []rune(name)
+	var sb strings.Builder
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) {
+			prev := runes[i-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+				sb.WriteByte('_')
+			}
+		}
+		sb.WriteRune(unicode.ToUpper(r))
+	}
+	return sb.String()
+}
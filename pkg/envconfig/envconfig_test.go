@@ -0,0 +1,78 @@
+package envconfig
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type testConfig struct {
+	ServerAddress string `env:"ADDR"`
+	GRPCAddress   string
+	Secret        []byte
+	Peers         []string
+	Retries       int
+	Thresh        uint8
+	Rate          *float64
+	Verbose       bool
+	Skipped       string `env:"-"`
+	hidden        string
+}
+
+func env(vars map[string]string) func(string) string {
+	return func(k string) string { return vars[k] }
+}
+
+func TestApply(t *testing.T) {
+	cfg := testConfig{ServerAddress: "file", Retries: 3, Skipped: "file"}
+	err := Apply(&cfg, "NIM_", env(map[string]string{
+		"NIM_ADDR":         "env",
+		"NIM_GRPC_ADDRESS": "127.0.0.1:1",
+		"NIM_SECRET":       "c2VjcmV0",
+		"NIM_PEERS":        "a:1, b:2",
+		"NIM_THRESH":       "7",
+		"NIM_RATE":         "0.5",
+		"NIM_VERBOSE":      "true",
+		"NIM_SKIPPED":      "env",
+	}))
+	if err != nil {
+		t.Fatalf("Apply: %v\n", err)
+	}
+	if cfg.ServerAddress != "env" || cfg.GRPCAddress != "127.0.0.1:1" || !bytes.Equal(cfg.Secret, []byte("secret")) ||
+		!reflect.DeepEqual(cfg.Peers, []string{"a:1", "b:2"}) || cfg.Retries != 3 || cfg.Thresh != 7 ||
+		cfg.Rate == nil || *cfg.Rate != 0.5 || !cfg.Verbose || cfg.Skipped != "file" {
+		t.Errorf("unexpected config %+v\n", cfg)
+	}
+}
+
+func TestApplyErrors(t *testing.T) {
+	var cfg testConfig
+	err := Apply(&cfg, "NIM_", env(map[string]string{
+		"NIM_RETRIES": "many",
+		"NIM_THRESH":  "300",
+		"NIM_SECRET":  "not base64!",
+	}))
+	if err == nil {
+		t.Fatalf("expected errors\n")
+	}
+	for _, name := range []string{"NIM_RETRIES", "NIM_THRESH", "NIM_SECRET"} {
+		if !strings.Contains(err.Error(), name) {
+			t.Errorf("expected %v to be named in:\n%v\n", name, err)
+		}
+	}
+}
+
+func TestUpperSnake(t *testing.T) {
+	for in, want := range map[string]string{
+		"NimServerAddress":     "NIM_SERVER_ADDRESS",
+		"GRPCAddress":          "GRPC_ADDRESS",
+		"FCheckLostMsgsThresh": "F_CHECK_LOST_MSGS_THRESH",
+		"RetryBaseMs":          "RETRY_BASE_MS",
+		"Secret":               "SECRET",
+	} {
+		if got := upperSnake(in); got != want {
+			t.Errorf("upperSnake(%q) = %q, want %q\n", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package netcond
+
+import (
+	"net"
+	"os"
+	"sync"
+	"time"
+)
+
+// inQueue is how many conditioned incoming packets wait to be read before
+// more are dropped, as a full socket buffer would.
+const inQueue = 64
+
+// Conn wraps conn, a connected packet conn such as a *net.UDPConn, so that
+// what is written to it passes through out and what is read from it through
+// in. Either may be nil to leave that direction alone.
+func Conn(conn net.Conn, out, in *Conditioner) net.Conn {
+	c := &condConn{Conn: conn, out: out, in: in}
+	if in != nil {
+		c.incoming = make(chan []byte, inQueue)
+		c.done = make(chan struct{})
+		c.deadlineSet = make(chan struct{})
+		go c.receive()
+	}
+	return c
+}
+
+type condConn struct {
+	net.Conn
+	out, in *Conditioner
+
+	// With an in conditioner, receive reads conn for Read, which takes its
+	// packets from incoming and keeps its own deadline.
+	incoming  chan []byte
+	done      chan struct{} // closed by Close
+	closeOnce sync.Once
+
+	mu          sync.Mutex
+	deadline    time.Time
+	deadlineSet chan struct{} // closed, and replaced, when deadline changes
+}
+
+func (c *condConn) Write(b []byte) (int, error) {
+	if c.out == nil {
+		return c.Conn.Write(b)
+	}
+	// as if it went out, like any datagram that is lost on the way
+	c.out.Send(b, func(p []byte) { c.Conn.Write(p) })
+	return len(b), nil
+}
+
+// receive passes packets read from conn through the in conditioner to
+// incoming until conn is closed.
+func (c *condConn) receive() {
+	for {
+		buf := make([]byte, 64*1024)
+		n, err := c.Conn.Read(buf)
+		if err != nil {
+			select {
+			case <-c.done:
+				return
+			default:
+				continue // a bad datagram, such as a refused one
+			}
+		}
+		c.in.Send(buf[:n], c.queue)
+	}
+}
+
+func (c *condConn) queue(p []byte) {
+	select {
+	case <-c.done:
+	case c.incoming <- p:
+	default:
+	}
+}
+
+func (c *condConn) Read(b []byte) (int, error) {
+	if c.in == nil {
+		return c.Conn.Read(b)
+	}
+	for {
+		c.mu.Lock()
+		deadline, deadlineSet := c.deadline, c.deadlineSet
+		c.mu.Unlock()
+		var timeout <-chan time.Time // nil, so never fires, without a deadline
+		if !deadline.IsZero() {
+			wait := time.Until(deadline)
+			if wait <= 0 {
+				return 0, os.ErrDeadlineExceeded
+			}
+			timer := time.NewTimer(wait)
+			timeout = timer.C
+			defer timer.Stop()
+		}
+		select {
+		case p := <-c.incoming:
+			return copy(b, p), nil
+		case <-timeout:
+			return 0, os.ErrDeadlineExceeded
+		case <-c.done:
+			return 0, net.ErrClosed
+		case <-deadlineSet:
+			// wait again for the new deadline
+		}
+	}
+}
+
+func (c *condConn) SetReadDeadline(t time.Time) error {
+	if c.in == nil {
+		return c.Conn.SetReadDeadline(t)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.deadline = t
+	close(c.deadlineSet)
+	c.deadlineSet = make(chan struct{})
+	return nil
+}
+
+func (c *condConn) SetDeadline(t time.Time) error {
+	if err := c.SetReadDeadline(t); err != nil {
+		return err
+	}
+	return c.Conn.SetWriteDeadline(t)
+}
+
+func (c *condConn) Close() error {
+	if c.in != nil {
+		c.closeOnce.Do(func() { close(c.done) })
+	}
+	return c.Conn.Close()
+}
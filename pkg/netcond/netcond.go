@@ -0,0 +1,125 @@
+// Package netcond makes a network worse on purpose, dropping, duplicating,
+// delaying and reordering packets, so that the nim binaries can be tried
+// against a poor one. Which packets are affected is drawn from a seeded
+// source, so a run's faults follow from its seed and the order packets are
+// sent in.
+package netcond
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// maxHold is the longest a packet held back for reordering waits for one
+// to overtake it before it is sent anyway.
+const maxHold = 100 * time.Millisecond
+
+// Config is how badly a Conditioner treats packets. The zero Config passes
+// them through untouched.
+type Config struct {
+	Loss      float64       // chance a packet is dropped
+	Duplicate float64       // chance a packet is delivered twice
+	Reorder   float64       // chance a packet is held back until the next has gone
+	Delay     time.Duration // each packet is delayed by up to this long
+	Seed      int64
+
+	// or, with DelayMean, by a normally distributed time with that mean
+	// and DelayStddev, never less than none
+	DelayMean   time.Duration
+	DelayStddev time.Duration
+}
+
+// Enabled reports whether c does anything to packets.
+func (c Config) Enabled() bool {
+	return c.Loss > 0 || c.Duplicate > 0 || c.Reorder > 0 || c.Delay > 0 || c.DelayMean > 0 || c.DelayStddev > 0
+}
+
+// Validate checks the chances are between 0 and 1, the delays aren't
+// negative and only one way of delaying packets is set.
+func (c Config) Validate() error {
+	for _, p := range []struct {
+		name   string
+		chance float64
+	}{{"loss", c.Loss}, {"duplicate", c.Duplicate}, {"reorder", c.Reorder}} {
+		if p.chance < 0 || p.chance > 1 {
+			return fmt.Errorf("%v rate %v is not between 0 and 1", p.name, p.chance)
+		}
+	}
+	for _, d := range []struct {
+		name  string
+		delay time.Duration
+	}{{"delay", c.Delay}, {"delay mean", c.DelayMean}, {"delay stddev", c.DelayStddev}} {
+		if d.delay < 0 {
+			return fmt.Errorf("%v %v is negative", d.name, d.delay)
+		}
+	}
+	if c.Delay > 0 && (c.DelayMean > 0 || c.DelayStddev > 0) {
+		return fmt.Errorf("delay %v can't be set with a delay mean or stddev", c.Delay)
+	}
+	return nil
+}
+
+// Conditioner passes packets on, or not, as its Config says. It is safe for
+// concurrent use.
+type Conditioner struct {
+	cfg Config
+
+	mu    sync.Mutex
+	rng   *rand.Rand
+	held  *time.Timer // sends the packet held back for reordering
+	queue delayQueue  // sends delayed packets
+}
+
+// New returns a Conditioner treating packets as cfg says.
+func New(cfg Config) *Conditioner {
+	return &Conditioner{cfg: cfg, rng: rand.New(rand.NewSource(cfg.Seed))}
+}
+
+// Send passes packet through the conditions, calling deliver with each copy
+// that survives them, now or later from another goroutine. deliver is given
+// a copy of packet, which it must not modify, as duplicates share it.
+func (c *Conditioner) Send(packet []byte, deliver func([]byte)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// every chance is drawn for every packet, so that one setting doesn't
+	// change which packets the others pick
+	lost := c.rng.Float64() < c.cfg.Loss
+	copies := 1
+	if c.rng.Float64() < c.cfg.Duplicate {
+		copies = 2
+	}
+	hold := c.rng.Float64() < c.cfg.Reorder
+	var delay time.Duration
+	if c.cfg.Delay > 0 {
+		delay = time.Duration(c.rng.Int63n(int64(c.cfg.Delay) + 1))
+	} else if c.cfg.DelayMean > 0 || c.cfg.DelayStddev > 0 {
+		delay = max(c.cfg.DelayMean+time.Duration(c.rng.NormFloat64()*float64(c.cfg.DelayStddev)), 0)
+	}
+	if lost {
+		return
+	}
+	packet = bytes.Clone(packet)
+	send := func() {
+		for i := 0; i < copies; i++ {
+			deliver(packet)
+		}
+	}
+	if held := c.held; held != nil {
+		// the held packet goes just after this one, which is never held
+		// itself so that it does overtake it
+		if held.Stop() {
+			defer held.Reset(delay + time.Millisecond)
+		}
+		c.held, hold = nil, false
+	}
+	if hold {
+		c.held = time.AfterFunc(delay+maxHold, send)
+	} else if delay > 0 {
+		c.queue.push(time.Now().Add(delay), send)
+	} else {
+		send()
+	}
+}
@@ -0,0 +1,150 @@
+package netcond
+
+import (
+	"net"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// collect sends packets 0 to n-1 through c and returns those delivered
+// within wait, in the order they were.
+func collect(c *Conditioner, n int, wait time.Duration) []byte {
+	var mu sync.Mutex
+	var got []byte
+	for i := 0; i < n; i++ {
+		c.Send([]byte{byte(i)}, func(p []byte) {
+			mu.Lock()
+			defer mu.Unlock()
+			got = append(got, p[0])
+		})
+	}
+	time.Sleep(wait)
+	mu.Lock()
+	defer mu.Unlock()
+	return got
+}
+
+func TestConditions(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      Config
+		expected []byte
+	}{
+		{"none", Config{}, []byte{0, 1}},
+		{"loss", Config{Loss: 1}, nil},
+		{"duplicate", Config{Duplicate: 1}, []byte{0, 0, 1, 1}},
+		{"reorder", Config{Reorder: 1}, []byte{1, 0}},
+	}
+	for _, test := range tests {
+		got := collect(New(test.cfg), 2, 20*time.Millisecond)
+		if !slices.Equal(got, test.expected) {
+			t.Errorf("%v: delivered %v, expected %v\n", test.name, got, test.expected)
+		}
+	}
+}
+
+func TestHeldPacketSentAlone(t *testing.T) {
+	got := collect(New(Config{Reorder: 1}), 1, maxHold+50*time.Millisecond)
+	if !slices.Equal(got, []byte{0}) {
+		t.Errorf("delivered %v, expected the held packet once nothing overtook it\n", got)
+	}
+}
+
+func TestSameSeedSameFaults(t *testing.T) {
+	cfg := Config{Loss: 0.3, Duplicate: 0.3, Seed: 7}
+	first, second := collect(New(cfg), 50, 0), collect(New(cfg), 50, 0)
+	if !slices.Equal(first, second) {
+		t.Errorf("seed %d delivered %v, then %v\n", cfg.Seed, first, second)
+	}
+	if len(first) == 50 {
+		t.Errorf("no packet was lost or duplicated\n")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	for _, cfg := range []Config{{Loss: -0.1}, {Duplicate: 1.5}, {Reorder: 2}, {Delay: -time.Second},
+		{DelayStddev: -time.Second}, {Delay: time.Second, DelayMean: time.Second}} {
+		if cfg.Validate() == nil {
+			t.Errorf("%+v passed validation\n", cfg)
+		}
+	}
+	if err := (Config{Loss: 1, Delay: time.Second}).Validate(); err != nil {
+		t.Errorf("valid config failed: %v\n", err)
+	}
+}
+
+func TestConnDeadline(t *testing.T) {
+	server, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatalf("listening: %v\n", err)
+	}
+	defer server.Close()
+	raw, err := net.DialUDP("udp", nil, server.LocalAddr().(*net.UDPAddr))
+	if err != nil {
+		t.Fatalf("dialing: %v\n", err)
+	}
+	conn := Conn(raw, New(Config{}), New(Config{Delay: 10 * time.Millisecond}))
+	defer conn.Close()
+
+	conn.SetReadDeadline(time.Now().Add(20 * time.Millisecond))
+	if _, err := conn.Read(make([]byte, 8)); !isTimeout(err) {
+		t.Errorf("read with nothing sent returned %v, expected a timeout\n", err)
+	}
+
+	conn.Write([]byte("ping"))
+	buf := make([]byte, 8)
+	n, raddr, err := server.ReadFromUDP(buf)
+	if err != nil || string(buf[:n]) != "ping" {
+		t.Fatalf("server read %q, %v\n", buf[:n], err)
+	}
+	server.WriteToUDP([]byte("pong"), raddr)
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	if n, err := conn.Read(buf); err != nil || string(buf[:n]) != "pong" {
+		t.Errorf("read %q, %v, expected pong\n", buf[:n], err)
+	}
+}
+
+func isTimeout(err error) bool {
+	netErr, ok := err.(net.Error)
+	return ok && netErr.Timeout()
+}
+
+func TestDelay(t *testing.T) {
+	const delay = 50 * time.Millisecond
+	c := New(Config{DelayMean: delay, Duplicate: 1})
+	arrived := make(chan time.Time, 2)
+	sent := time.Now()
+	c.Send([]byte{0}, func([]byte) { arrived <- time.Now() })
+	for i := 0; i < 2; i++ {
+		select {
+		case at := <-arrived:
+			if at.Sub(sent) < delay {
+				t.Errorf("copy %d delivered after %v, expected at least %v\n", i, at.Sub(sent), delay)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("copy %d never delivered\n", i)
+		}
+	}
+}
+
+func TestDelayQueueOrder(t *testing.T) {
+	var q delayQueue
+	var mu sync.Mutex
+	var got []int
+	start := time.Now()
+	for i, after := range []time.Duration{30, 10, 20, 10} {
+		q.push(start.Add(after*time.Millisecond), func() {
+			mu.Lock()
+			defer mu.Unlock()
+			got = append(got, i)
+		})
+	}
+	time.Sleep(60 * time.Millisecond)
+	mu.Lock()
+	defer mu.Unlock()
+	if !slices.Equal(got, []int{1, 3, 2, 0}) {
+		t.Errorf("sent %v, expected packets in the order they were due\n", got)
+	}
+}
@@ -0,0 +1,98 @@
+package netcond
+
+import (
+	"container/heap"
+	"sync"
+	"time"
+)
+
+// delayQueue sends delayed packets once their time comes, in the order of
+// those times, and of sending for packets due at once. A goroutine drains
+// it while it has packets waiting; the zero delayQueue is empty and ready
+// to use.
+type delayQueue struct {
+	mu       sync.Mutex
+	packets  delayHeap
+	sent     uint64        // packets pushed so far, numbering them
+	draining bool          // drain is running
+	wake     chan struct{} // tells drain an earlier packet was pushed
+}
+
+// delayed is a packet waiting in a delayQueue.
+type delayed struct {
+	at   time.Time
+	n    uint64 // its place in sending order, breaking ties in at
+	send func()
+}
+
+// push has send called at at, from the queue's goroutine.
+func (q *delayQueue) push(at time.Time, send func()) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.sent++
+	heap.Push(&q.packets, delayed{at: at, n: q.sent, send: send})
+	if !q.draining {
+		q.draining = true
+		q.wake = make(chan struct{}, 1)
+		go q.drain(q.wake)
+		return
+	}
+	if q.packets[0].n == q.sent {
+		select {
+		case q.wake <- struct{}{}:
+		default: // already woken
+		}
+	}
+}
+
+// drain sends each packet as its time comes, returning once none are left.
+func (q *delayQueue) drain(wake chan struct{}) {
+	for {
+		q.mu.Lock()
+		if len(q.packets) == 0 {
+			q.draining = false
+			q.mu.Unlock()
+			return
+		}
+		next := q.packets[0]
+		wait := time.Until(next.at)
+		if wait <= 0 {
+			heap.Pop(&q.packets)
+		}
+		q.mu.Unlock()
+
+		if wait <= 0 {
+			next.send()
+			continue
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-wake:
+			timer.Stop()
+		}
+	}
+}
+
+// delayHeap is a min-heap of delayed packets by when they are due.
+type delayHeap []delayed
+
+func (h delayHeap) Len() int { return len(h) }
+
+func (h delayHeap) Less(i, j int) bool {
+	if h[i].at.Equal(h[j].at) {
+		return h[i].n < h[j].n
+	}
+	return h[i].at.Before(h[j].at)
+}
+
+func (h delayHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (h *delayHeap) Push(x any) { *h = append(*h, x.(delayed)) }
+
+func (h *delayHeap) Pop() any {
+	old := *h
+	last := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return last
+}
@@ -0,0 +1,120 @@
+package nim
+
+import (
+	"bytes"
+	"math"
+	"math/rand"
+	"slices"
+)
+
+// GenerateBoard returns the board for seed: 3 to 16 rows of 1 to 10 coins,
+// with a non-zero nim sum so the first player can always win.
+func GenerateBoard(seed int64) []uint8 {
+	return generateBoard(seed, 10, func(rng *rand.Rand) int { return rng.Intn(10) + 1 }, GuaranteeFirst)
+}
+
+// GenerateStochasticBoard returns a board for seed shaped like
+// GenerateBoard's, but with a row of k coins weighted by k^-alpha rather
+// than all sizes equally likely, so that for positive alpha most rows are
+// short and the odd one long.
+func GenerateStochasticBoard(seed int64, alpha float64) []uint8 {
+	weights := StochasticWeights(alpha)
+	return generateBoard(seed, 10, func(rng *rand.Rand) int { return WeightedRandom(weights, rng) + 1 }, GuaranteeFirst)
+}
+
+// StochasticWeights are GenerateStochasticBoard's weights for rows of 1 to
+// 10 coins, k^-alpha for k coins; as the custom profile's weights they make
+// the same boards.
+func StochasticWeights(alpha float64) []float64 {
+	weights := make([]float64, 10)
+	for i := range weights {
+		weights[i] = math.Pow(float64(i+1), -alpha)
+	}
+	return weights
+}
+
+// generateBoard makes a board for seed of 3 to 16 rows, each of coins(rng)
+// coins, from 1 to maxCoins, adjusted to make good guarantee.
+func generateBoard(seed int64, maxCoins uint8, coins func(rng *rand.Rand) int, guarantee string) []uint8 {
+	rng := rand.New(rand.NewSource(seed))
+	numRows := rng.Intn(14) + 3
+	board := make([]uint8, numRows)
+	for i := 0; i < numRows; i++ {
+		board[i] = uint8(coins(rng))
+	}
+	return guaranteeBoard(board, guarantee, numRows-1, maxCoins)
+}
+
+// WeightedRandom picks an index into weights with probability in
+// proportion to its weight. Negative weights count as zero; with no
+// positive weight it returns -1.
+func WeightedRandom(weights []float64, rng *rand.Rand) int {
+	var total float64
+	for _, w := range weights {
+		total += max(w, 0)
+	}
+	if total <= 0 {
+		return -1
+	}
+	r := rng.Float64() * total
+	last := -1
+	for i, w := range weights {
+		if w <= 0 {
+			continue
+		}
+		if r < w {
+			return i
+		}
+		r -= w
+		last = i
+	}
+	return last // r survived rounding
+}
+
+// ValidMove reports whether after is before with count coins taken from
+// row, a move either player may make. Malformed moves (a different number
+// of rows, a row out of range, a non-positive count or more coins than the
+// row has) are invalid.
+func ValidMove(before, after []uint8, row, count int) bool {
+	if len(after) != len(before) || row < 0 || row >= len(before) ||
+		count <= 0 || count > int(before[row]) {
+		return false
+	}
+	for i, coins := range before {
+		if i == row {
+			coins -= uint8(count)
+		}
+		if coins != after[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// BoardEquivalent reports whether a and b have the same rows in any order.
+// Rows can't be swapped in a game, but boards that are permutations of one
+// another have the same value, so analysis can treat them as one position.
+func BoardEquivalent(a, b []uint8) bool {
+	return len(a) == len(b) && bytes.Equal(sortedBoard(a), sortedBoard(b))
+}
+
+// BoardSubset reports whether every row of a appears in b, counting rows of
+// the same size separately: a multiset subset, whatever the order.
+func BoardSubset(a, b []uint8) bool {
+	var counts [256]int
+	for _, coins := range b {
+		counts[coins]++
+	}
+	for _, coins := range a {
+		if counts[coins]--; counts[coins] < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func sortedBoard(board []uint8) []uint8 {
+	sorted := slices.Clone(board)
+	slices.Sort(sorted)
+	return sorted
+}
@@ -0,0 +1,118 @@
+package nim
+
+import (
+	"bytes"
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+// meanCoins is the mean coins per row of n boards from generate.
+func meanCoins(n int, generate func(seed int64) []uint8) float64 {
+	var coins, rows int
+	for seed := int64(0); seed < int64(n); seed++ {
+		board := generate(seed)
+		for _, c := range board {
+			coins += int(c)
+		}
+		rows += len(board)
+	}
+	return float64(coins) / float64(rows)
+}
+
+func TestStochasticBoards(t *testing.T) {
+	stochastic := func(seed int64) []uint8 { return GenerateStochasticBoard(seed, 1.5) }
+	for seed := int64(0); seed < 1000; seed++ {
+		b := stochastic(seed)
+		if len(b) < 3 || len(b) > 16 || NimSum(b) == 0 || bytes.IndexByte(b, 0) >= 0 || slices.Max(b) > 10 {
+			t.Fatalf("seed %d: invalid stochastic board %v\n", seed, b)
+		}
+	}
+	uniformMean, stochasticMean := meanCoins(1000, GenerateBoard), meanCoins(1000, stochastic)
+	if stochasticMean >= uniformMean {
+		t.Errorf("stochastic boards average %.2f coins a row, uniform ones %.2f\n", stochasticMean, uniformMean)
+	}
+}
+
+func TestWeightedRandom(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	counts := make([]int, 4)
+	for i := 0; i < 10000; i++ {
+		counts[WeightedRandom([]float64{1, 0, 3, -2}, rng)]++
+	}
+	if counts[1] != 0 || counts[3] != 0 {
+		t.Errorf("indices without positive weight were picked: %v\n", counts)
+	}
+	if ratio := float64(counts[2]) / float64(counts[0]); ratio < 2.5 || ratio > 3.5 {
+		t.Errorf("picked %v, expected index 2 about 3 times as often as 0\n", counts)
+	}
+	if i := WeightedRandom([]float64{0, -1}, rng); i != -1 {
+		t.Errorf("picked %d with no positive weight, expected -1\n", i)
+	}
+}
+
+func TestValidMove(t *testing.T) {
+	before := []uint8{3, 4, 5}
+	tests := []struct {
+		after      []uint8
+		row, count int
+		want       bool
+	}{
+		{[]uint8{3, 1, 5}, 1, 3, true},
+		{[]uint8{0, 4, 5}, 0, 3, true},
+		{[]uint8{3, 1, 5}, 1, 2, false},    // count doesn't match the board
+		{[]uint8{3, 4, 4, 0}, 2, 1, false}, // extra row
+		{[]uint8{3, 4, 5}, 0, 0, false},
+		{[]uint8{3, 4, 5}, 3, 1, false},
+		{[]uint8{3, 4, 250}, 2, 11, false}, // more coins than the row has
+	}
+	for _, test := range tests {
+		if got := ValidMove(before, test.after, test.row, test.count); got != test.want {
+			t.Errorf("ValidMove(%v, %v, %d, %d) = %v, expected %v\n", before, test.after, test.row, test.count, got, test.want)
+		}
+	}
+}
+
+func TestBoardEquivalent(t *testing.T) {
+	tests := []struct {
+		a, b []uint8
+		want bool
+	}{
+		{[]uint8{3, 4, 5}, []uint8{3, 4, 5}, true},
+		{[]uint8{3, 4, 5}, []uint8{5, 3, 4}, true},
+		{[]uint8{1, 1, 2}, []uint8{1, 2, 1}, true},
+		{[]uint8{1, 1, 2}, []uint8{1, 2, 2}, false},
+		{[]uint8{3, 4}, []uint8{3, 4, 0}, false},
+		{[]uint8{}, nil, true},
+	}
+	for _, test := range tests {
+		if got := BoardEquivalent(test.a, test.b); got != test.want {
+			t.Errorf("BoardEquivalent(%v, %v) = %v, expected %v\n", test.a, test.b, got, test.want)
+		}
+	}
+	a := []uint8{5, 3, 4}
+	BoardEquivalent(a, []uint8{3, 4, 5})
+	if !bytes.Equal(a, []uint8{5, 3, 4}) {
+		t.Errorf("BoardEquivalent reordered its argument: %v\n", a)
+	}
+}
+
+func TestBoardSubset(t *testing.T) {
+	tests := []struct {
+		a, b []uint8
+		want bool
+	}{
+		{[]uint8{3, 4}, []uint8{5, 4, 3}, true},
+		{[]uint8{4, 3, 5}, []uint8{5, 4, 3}, true},
+		{[]uint8{2, 2}, []uint8{2, 7, 2}, true},
+		{[]uint8{2, 2}, []uint8{2, 7}, false},
+		{[]uint8{6}, []uint8{5, 4, 3}, false},
+		{nil, []uint8{1}, true},
+		{[]uint8{1}, nil, false},
+	}
+	for _, test := range tests {
+		if got := BoardSubset(test.a, test.b); got != test.want {
+			t.Errorf("BoardSubset(%v, %v) = %v, expected %v\n", test.a, test.b, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package nim
+
+import (
+	"math/bits"
+	"slices"
+)
+
+// Board guarantees say which player a generated board is won by with
+// perfect play: the first to move, who can win exactly when the nim sum is
+// non-zero, or the second, or either, leaving the board as drawn.
+const (
+	GuaranteeFirst  = "first"  // a non-zero nim sum, as GenerateBoard makes
+	GuaranteeSecond = "second" // a zero nim sum, so whoever moves first loses
+	GuaranteeRandom = "random" // no adjustment
+)
+
+// BoardGuarantees lists the guarantees GenerateProfileBoard knows.
+var BoardGuarantees = []string{GuaranteeFirst, GuaranteeSecond, GuaranteeRandom}
+
+// guaranteeBoard adjusts board, of rows of 1 to maxCoins coins, to make
+// good guarantee, an empty one meaning GuaranteeFirst. For that, a zero nim
+// sum is made non-zero by changing row by a coin; for GuaranteeSecond, a
+// non-zero one is made zero by changing the last row that can be changed
+// to do it or, failing that, adding rows. Either way the board stays
+// within maxCoins a row.
+func guaranteeBoard(board []uint8, guarantee string, row int, maxCoins uint8) []uint8 {
+	sum := NimSum(board)
+	switch guarantee {
+	case GuaranteeRandom:
+		return board
+	case GuaranteeSecond:
+		if sum == 0 {
+			return board
+		}
+		for i := len(board) - 1; i >= 0; i-- {
+			if coins := board[i] ^ sum; coins >= 1 && coins <= maxCoins {
+				board[i] = coins
+				return board
+			}
+		}
+		if sum <= maxCoins {
+			return append(board, sum)
+		}
+		// sum's top bit is no more than maxCoins's, whose rows made it
+		top := uint8(1) << (7 - bits.LeadingZeros8(sum))
+		return append(board, top, sum^top)
+	}
+	if sum != 0 {
+		return board
+	}
+	switch coins := board[row]; {
+	case coins < maxCoins:
+		board[row]++
+	case coins > 1:
+		board[row]--
+	default:
+		// an even number of rows of the one coin allowed, at least 4
+		board = slices.Delete(board, row, row+1)
+	}
+	return board
+}
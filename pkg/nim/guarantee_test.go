@@ -0,0 +1,66 @@
+package nim
+
+import (
+	"bytes"
+	"slices"
+	"testing"
+)
+
+// TestBoardGuarantees checks the nim sum of every profile's boards over
+// 5000 seeds: non-zero for GuaranteeFirst, zero for GuaranteeSecond, and
+// as drawn for GuaranteeRandom, which the others only change when they
+// must. Rows stay within the profile's sizes throughout.
+func TestBoardGuarantees(t *testing.T) {
+	weights := []float64{1, 0, 2, 1}
+	maxCoins := map[string]uint8{ProfileUniform: 10, ProfileSkewed: 10, ProfileSingle: 10, ProfileEndgame: 2, ProfileCustom: 4}
+	for _, profile := range BoardProfiles {
+		zeroes := 0
+		for seed := int64(0); seed < 5000; seed++ {
+			boards := map[string][]uint8{}
+			for _, guarantee := range BoardGuarantees {
+				board, err := GenerateProfileBoard(seed, profile, weights, guarantee)
+				if err != nil {
+					t.Fatalf("%v %v board for seed %d: %v\n", profile, guarantee, seed, err)
+				}
+				if len(board) == 0 || bytes.IndexByte(board, 0) >= 0 || slices.Max(board) > maxCoins[profile] {
+					t.Fatalf("%v %v board for seed %d is %v\n", profile, guarantee, seed, board)
+				}
+				again, _ := GenerateProfileBoard(seed, profile, weights, guarantee)
+				if !bytes.Equal(board, again) {
+					t.Fatalf("%v %v boards for seed %d differ: %v and %v\n", profile, guarantee, seed, board, again)
+				}
+				boards[guarantee] = board
+			}
+			drawn := boards[GuaranteeRandom]
+			if NimSum(drawn) == 0 {
+				zeroes++
+			}
+			if NimSum(boards[GuaranteeFirst]) == 0 || NimSum(drawn) != 0 && !bytes.Equal(boards[GuaranteeFirst], drawn) {
+				t.Fatalf("%v board for seed %d guaranteeing the first player is %v, drawn as %v\n", profile, seed, boards[GuaranteeFirst], drawn)
+			}
+			if NimSum(boards[GuaranteeSecond]) != 0 || NimSum(drawn) == 0 && !bytes.Equal(boards[GuaranteeSecond], drawn) {
+				t.Fatalf("%v board for seed %d guaranteeing the second player is %v, drawn as %v\n", profile, seed, boards[GuaranteeSecond], drawn)
+			}
+		}
+		// a single heap never has a zero nim sum, nor a skewed board, whose
+		// big heap has a bit the small ones lack
+		if zeroes == 0 && profile != ProfileSingle && profile != ProfileSkewed {
+			t.Errorf("no %v board drawn with a zero nim sum, so the guarantees went untested\n", profile)
+		}
+	}
+	if board, err := GenerateProfileBoard(1, ProfileUniform, nil, ""); err != nil || !bytes.Equal(board, GenerateBoard(1)) {
+		t.Errorf("no guarantee made %v, %v, expected GenerateBoard's %v\n", board, err, GenerateBoard(1))
+	}
+	if _, err := GenerateProfileBoard(1, ProfileUniform, nil, "nobody"); err == nil {
+		t.Errorf("an unknown guarantee made a board\n")
+	}
+}
+
+func TestStochasticWeights(t *testing.T) {
+	for seed := int64(0); seed < 1000; seed++ {
+		custom, _ := GenerateProfileBoard(seed, ProfileCustom, StochasticWeights(1.5), GuaranteeFirst)
+		if stochastic := GenerateStochasticBoard(seed, 1.5); !bytes.Equal(custom, stochastic) {
+			t.Fatalf("seed %d: custom board %v with the stochastic weights, stochastic board %v\n", seed, custom, stochastic)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package nim
+
+// Lasker's nim is nim in which a move may, rather than take coins, split a
+// heap into two non-empty heaps. The board then grows a row: the split heap
+// keeps its row with one part, and the other part is inserted right after
+// it. See https://en.wikipedia.org/wiki/Nim#Lasker's_nim.
+
+// LaskerVariant is the name client and server give Lasker's nim when
+// agreeing at GameStart to play it.
+const LaskerVariant = "lasker"
+
+// LaskerGrundy is the Grundy value of a heap of n coins in Lasker's nim:
+// n, but for heaps of 4k+3 and 4k+4 coins, whose values are swapped. A
+// board is lost for the player to move when its heaps' values XOR to zero.
+func LaskerGrundy(n uint8) int {
+	switch n % 4 {
+	case 3:
+		return int(n) + 1
+	case 0:
+		if n > 0 {
+			return int(n) - 1
+		}
+	}
+	return int(n)
+}
+
+// LaskerGrundySum is the XOR of the Grundy values of board's heaps.
+func LaskerGrundySum(board []uint8) int {
+	sum := 0
+	for _, coins := range board {
+		sum ^= LaskerGrundy(coins)
+	}
+	return sum
+}
+
+// Split returns board with row split into heaps of first and the rest of
+// its coins, leaving board untouched.
+func Split(board []uint8, row int, first uint8) []uint8 {
+	after := make([]uint8, 0, len(board)+1)
+	after = append(after, board[:row+1]...)
+	after[row] = first
+	after = append(after, board[row]-first)
+	return append(after, board[row+1:]...)
+}
+
+// ValidLaskerMove reports whether after is before with coins taken from one
+// row, or with one row split into two non-empty heaps as Split does. It
+// goes by the boards alone: a split has no row and count to check.
+func ValidLaskerMove(before, after []uint8) bool {
+	row := 0
+	for row < len(before) && row < len(after) && before[row] == after[row] {
+		row++
+	}
+	switch {
+	case len(after) == len(before):
+		return row < len(before) && ValidMove(before, after, row, int(before[row])-int(after[row]))
+	case len(after) == len(before)+1 && row < len(before):
+		first, second := after[row], after[row+1]
+		if first == 0 || second == 0 || int(first)+int(second) != int(before[row]) {
+			return false
+		}
+		for i := row + 1; i < len(before); i++ {
+			if after[i+1] != before[i] {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// LaskerStrategy picks moves in Lasker's nim. A Strategy without LaskerMove
+// plays Lasker's nim by its Move, only ever taking coins, which is as legal
+// there as in nim.
+type LaskerStrategy interface {
+	// LaskerMove returns the board after its move on board, which has at
+	// least one coin left and is left untouched.
+	LaskerMove(board []uint8) []uint8
+}
+
+// LaskerOptimal plays Lasker's nim by Grundy values, leaving boards whose
+// values XOR to zero whenever it can and taking a single coin otherwise. In
+// nim it plays as Optimal does.
+type LaskerOptimal struct{}
+
+func (LaskerOptimal) Move(board []uint8) (int, uint8) {
+	return Optimal{}.Move(board)
+}
+
+func (LaskerOptimal) LaskerMove(board []uint8) []uint8 {
+	sum := LaskerGrundySum(board)
+	if sum != 0 {
+		for row, coins := range board {
+			target := sum ^ LaskerGrundy(coins)
+			for left := uint8(0); left < coins; left++ {
+				if LaskerGrundy(left) == target {
+					after := append([]uint8(nil), board...)
+					after[row] = left
+					return after
+				}
+			}
+			for first := uint8(1); first <= coins/2; first++ {
+				if LaskerGrundy(first)^LaskerGrundy(coins-first) == target {
+					return Split(board, row, first)
+				}
+			}
+		}
+	}
+	row, count := Basic{}.Move(board)
+	after := append([]uint8(nil), board...)
+	after[row] -= count
+	return after
+}
+
+// LaskerStep describes the move from before to after, a ValidLaskerMove:
+// the row taken from and the coins taken or, for a split, the row split and
+// a count of zero.
+func LaskerStep(before, after []uint8) (row int, count uint8) {
+	for row < len(before) && before[row] == after[row] {
+		row++
+	}
+	if len(after) > len(before) {
+		return row, 0
+	}
+	return row, before[row] - after[row]
+}
@@ -0,0 +1,131 @@
+package nim
+
+import (
+	"bytes"
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+// TestLaskerGrundyPublished checks the first Grundy values of Lasker's nim
+// against those published for it in Winning Ways and on Wikipedia.
+func TestLaskerGrundyPublished(t *testing.T) {
+	want := []int{0, 1, 2, 4, 3, 5, 6, 8, 7, 9, 10, 12, 11, 13, 14, 16, 15, 17, 18, 20, 19, 21, 22, 24, 23}
+	for n, g := range want {
+		if got := LaskerGrundy(uint8(n)); got != g {
+			t.Errorf("LaskerGrundy(%d) = %d, expected %d\n", n, got, g)
+		}
+	}
+}
+
+// TestLaskerGrundyMex works the Grundy values out from the rules, as the
+// least value no move from a heap reaches, for every heap size.
+func TestLaskerGrundyMex(t *testing.T) {
+	grundy := make([]int, 256)
+	for n := 1; n < 256; n++ {
+		reached := map[int]bool{}
+		for left := 0; left < n; left++ {
+			reached[grundy[left]] = true
+		}
+		for first := 1; first <= n/2; first++ {
+			reached[grundy[first]^grundy[n-first]] = true
+		}
+		for reached[grundy[n]] {
+			grundy[n]++
+		}
+		if got := LaskerGrundy(uint8(n)); got != grundy[n] {
+			t.Errorf("LaskerGrundy(%d) = %d, expected %d\n", n, got, grundy[n])
+		}
+	}
+}
+
+func TestValidLaskerMove(t *testing.T) {
+	before := []uint8{3, 5, 2}
+	tests := []struct {
+		after []uint8
+		want  bool
+	}{
+		{[]uint8{3, 1, 2}, true},        // take 4
+		{[]uint8{0, 5, 2}, true},        // take the row
+		{[]uint8{3, 2, 3, 2}, true},     // split 5 into 2 and 3
+		{[]uint8{3, 5, 1, 1}, true},     // split the last row
+		{[]uint8{3, 5, 2}, false},       // no move
+		{[]uint8{3, 6, 2}, false},       // adds coins
+		{[]uint8{2, 4, 2}, false},       // two rows
+		{[]uint8{3, 0, 5, 2}, false},    // an empty heap
+		{[]uint8{3, 2, 2, 2}, false},    // coins lost in the split
+		{[]uint8{3, 2, 3, 1}, false},    // splits and takes
+		{[]uint8{3, 5, 2, 1}, false},    // a heap from nowhere
+		{[]uint8{1, 2, 2, 5, 2}, false}, // two rows more
+		{[]uint8{3, 5}, false},          // a row less
+	}
+	for _, test := range tests {
+		if got := ValidLaskerMove(before, test.after); got != test.want {
+			t.Errorf("ValidLaskerMove(%v, %v) = %v, expected %v\n", before, test.after, got, test.want)
+		}
+	}
+}
+
+func TestSplit(t *testing.T) {
+	board := []uint8{3, 5, 2}
+	if got := Split(board, 1, 2); !bytes.Equal(got, []uint8{3, 2, 3, 2}) {
+		t.Errorf("splitting 2 off row 1 of %v gave %v\n", board, got)
+	}
+	if got := Split(board, 2, 1); !bytes.Equal(got, []uint8{3, 5, 1, 1}) {
+		t.Errorf("splitting 1 off row 2 of %v gave %v\n", board, got)
+	}
+	if !bytes.Equal(board, []uint8{3, 5, 2}) {
+		t.Errorf("Split modified the board: %v\n", board)
+	}
+	if row, count := LaskerStep(board, Split(board, 1, 2)); row != 1 || count != 0 {
+		t.Errorf("LaskerStep described the split as %d, %d\n", row, count)
+	}
+	if row, count := LaskerStep(board, []uint8{3, 1, 2}); row != 1 || count != 4 {
+		t.Errorf("LaskerStep described taking 4 from row 1 as %d, %d\n", row, count)
+	}
+}
+
+// TestLaskerOptimal plays LaskerOptimal against random Lasker's nim moves:
+// every move it makes is legal and, from a board with a non-zero Grundy
+// sum, leaves a zero one, so it wins every game it starts on such a board.
+func TestLaskerOptimal(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for game := 0; game < 200; game++ {
+		board := make([]uint8, 1+rng.Intn(5))
+		for i := range board {
+			board[i] = uint8(1 + rng.Intn(12))
+		}
+		if LaskerGrundySum(board) == 0 {
+			continue
+		}
+		for turn := 0; slices.Max(board) > 0; turn++ {
+			var after []uint8
+			if turn%2 == 0 {
+				after = LaskerOptimal{}.LaskerMove(board)
+				if LaskerGrundySum(after) != 0 {
+					t.Fatalf("LaskerOptimal moved %v to %v, with Grundy sum %d\n", board, after, LaskerGrundySum(after))
+				}
+			} else {
+				after = randomLaskerMove(rng, board)
+			}
+			if !ValidLaskerMove(board, after) {
+				t.Fatalf("illegal move from %v to %v\n", board, after)
+			}
+			board = after
+			if slices.Max(board) == 0 && turn%2 == 1 {
+				t.Fatalf("LaskerOptimal lost\n")
+			}
+		}
+	}
+}
+
+// randomLaskerMove takes coins from, or splits, a random non-empty heap.
+func randomLaskerMove(rng *rand.Rand, board []uint8) []uint8 {
+	row, count := Random{Rng: rng}.Move(board)
+	if board[row] > 1 && rng.Intn(2) == 0 {
+		return Split(board, row, uint8(1+rng.Intn(int(board[row])-1)))
+	}
+	after := append([]uint8(nil), board...)
+	after[row] -= count
+	return after
+}
@@ -0,0 +1,75 @@
+package nim
+
+import "slices"
+
+// BestMoveNPly returns the move on board that leaves the opponent worst
+// off, looking n plies ahead by minimax with alpha-beta pruning. Games
+// still in play after the last ply are scored neither won nor lost, so
+// the search only sees wins within n plies, taking the first such move by
+// row then count; when it sees none, the move is the one Optimal, nim's
+// closed form, plays. Either way the move is Optimal's for every n. n
+// below 1 is taken as 1. On an empty board Row is -1.
+func BestMoveNPly(board []uint8, n int) Move {
+	if n < 1 {
+		n = 1
+	}
+	state := append([]uint8(nil), board...)
+	best, bestScore := Move{Row: -1}, lossScore
+	alpha := lossScore
+	for row, coins := range state {
+		for count := uint8(1); count <= coins; count++ {
+			state[row] -= count
+			score := -negamax(state, n-1, -winScore, -alpha)
+			state[row] += count
+			if best.Row < 0 || score > bestScore {
+				best, bestScore = Move{Row: row, Count: count}, score
+			}
+			if score > alpha {
+				alpha = score
+			}
+			if alpha >= winScore {
+				return best
+			}
+		}
+	}
+	if bestScore < winScore && best.Row >= 0 {
+		// no win within n plies: play nim's closed form
+		row, count := Optimal{}.Move(state)
+		return Move{Row: row, Count: count}
+	}
+	return best
+}
+
+// Scores of a position for the player to move.
+const (
+	lossScore    = -1
+	unknownScore = 0 // the game goes on past the search
+	winScore     = 1
+)
+
+// negamax scores board for the player to move, looking depth plies ahead,
+// within the window alpha to beta: a score at or beyond either bound only
+// says the move leading here won't be chosen.
+func negamax(board []uint8, depth, alpha, beta int) int {
+	if slices.Max(board) == 0 {
+		// the opponent took the last coin
+		return lossScore
+	}
+	if depth == 0 {
+		return unknownScore
+	}
+	for row, coins := range board {
+		for count := uint8(1); count <= coins; count++ {
+			board[row] -= count
+			score := -negamax(board, depth-1, -beta, -alpha)
+			board[row] += count
+			if score > alpha {
+				alpha = score
+			}
+			if alpha >= beta {
+				return alpha
+			}
+		}
+	}
+	return alpha
+}
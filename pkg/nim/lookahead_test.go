@@ -0,0 +1,89 @@
+package nim
+
+import (
+	"fmt"
+	"testing"
+)
+
+// wins reports whether the player to move on board can force a win, by
+// searching every line of play to the end.
+func wins(board []uint8) bool {
+	for row, coins := range board {
+		for count := uint8(1); count <= coins; count++ {
+			board[row] -= count
+			lost := !wins(board)
+			board[row] += count
+			if lost {
+				return true
+			}
+		}
+	}
+	// no move, or none leaving the opponent lost
+	return false
+}
+
+// TestBestMoveNPlyExhaustive checks a search deep enough to reach the end
+// of the game against wins on every board of up to three rows of three
+// coins.
+func TestBestMoveNPlyExhaustive(t *testing.T) {
+	for code := 1; code < 64; code++ {
+		board := []uint8{uint8(code & 3), uint8(code >> 2 & 3), uint8(code >> 4)}
+		n := int(board[0] + board[1] + board[2])
+		move := BestMoveNPly(board, n)
+		if move.Row < 0 || move.Count == 0 || move.Count > board[move.Row] {
+			t.Fatalf("%d plies on %v: illegal move %+v\n", n, board, move)
+		}
+		after := append([]uint8(nil), board...)
+		after[move.Row] -= move.Count
+		if wins(append([]uint8(nil), board...)) && wins(after) {
+			t.Errorf("%d plies on %v: took %d from row %d, leaving %v won for the opponent\n", n, board, move.Count, move.Row, after)
+		}
+		row, count := Optimal{}.Move(append([]uint8(nil), board...))
+		if move != (Move{row, count}) {
+			t.Errorf("%d plies on %v: got %+v, Optimal %+v\n", n, board, move, Move{row, count})
+		}
+	}
+}
+
+// TestBestMoveNPlyShallow checks a search plays wins within its plies, and
+// Optimal's move when it sees none.
+func TestBestMoveNPlyShallow(t *testing.T) {
+	tests := []struct {
+		board []uint8
+		n     int
+		want  Move
+	}{
+		{[]uint8{0, 0, 5}, 1, Move{2, 5}}, // takes the last coins
+		{[]uint8{1, 1, 1}, 3, Move{0, 1}}, // wins on the third ply
+		// leaving {2, 2} wins, but not within a ply or three
+		{[]uint8{2, 3}, 1, Move{1, 1}},
+		{[]uint8{2, 3}, 3, Move{1, 1}},
+		{[]uint8{4, 1}, 1, Move{0, 3}},
+		{[]uint8{2, 3}, 5, Move{1, 1}},
+		// every move loses: one coin from the first row
+		{[]uint8{2, 2}, 1, Move{0, 1}},
+	}
+	for _, test := range tests {
+		if got := BestMoveNPly(test.board, test.n); got != test.want {
+			t.Errorf("%d plies on %v: got %+v, expected %+v\n", test.n, test.board, got, test.want)
+		}
+	}
+}
+
+func TestBestMoveNPlyEmptyBoard(t *testing.T) {
+	if got := BestMoveNPly([]uint8{0, 0}, 2); got.Row != -1 {
+		t.Errorf("expected no move on an empty board, got %+v\n", got)
+	}
+}
+
+func BenchmarkBestMoveNPly(b *testing.B) {
+	// a zero nim-sum, so no winning move cuts the search short at the root
+	board := []uint8{1, 2, 3, 4, 5, 6, 7}
+	for n := 1; n <= 3; n++ {
+		b.Run(fmt.Sprintf("n=%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				BestMoveNPly(board, n)
+			}
+		})
+	}
+}
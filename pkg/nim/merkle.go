@@ -0,0 +1,41 @@
+package nim
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+)
+
+// ComputeMerkleRoot returns the root of a binary Merkle tree over the rows
+// of board. Each leaf is sha256(rowIndex || rowValue), with the index as a
+// big-endian uint32, and each parent the sha256 of its two children
+// concatenated; a node left without a sibling moves up a level unchanged.
+// An empty board has the zero root.
+func ComputeMerkleRoot(board []uint8) [32]byte {
+	if len(board) == 0 {
+		return [32]byte{}
+	}
+	level := make([][32]byte, len(board))
+	var leaf [5]byte
+	for row, coins := range board {
+		binary.BigEndian.PutUint32(leaf[:4], uint32(row))
+		leaf[4] = coins
+		level[row] = sha256.Sum256(leaf[:])
+	}
+	for len(level) > 1 {
+		next := level[:0]
+		for i := 0; i < len(level); i += 2 {
+			if i+1 == len(level) {
+				next = append(next, level[i])
+				break
+			}
+			next = append(next, sha256.Sum256(append(level[i][:], level[i+1][:]...)))
+		}
+		level = next
+	}
+	return level[0]
+}
+
+// VerifyMerkleRoot reports whether root is the Merkle root of board.
+func VerifyMerkleRoot(board []uint8, root [32]byte) bool {
+	return ComputeMerkleRoot(board) == root
+}
@@ -0,0 +1,42 @@
+package nim
+
+import (
+	"crypto/sha256"
+	"testing"
+)
+
+func TestMerkleRootTwoRows(t *testing.T) {
+	left := sha256.Sum256([]byte{0, 0, 0, 0, 3})
+	right := sha256.Sum256([]byte{0, 0, 0, 1, 5})
+	want := sha256.Sum256(append(left[:], right[:]...))
+	if got := ComputeMerkleRoot([]uint8{3, 5}); got != want {
+		t.Errorf("got root %x, expected %x\n", got, want)
+	}
+	if got := ComputeMerkleRoot([]uint8{7}); got != sha256.Sum256([]byte{0, 0, 0, 0, 7}) {
+		t.Errorf("single row root %x is not its leaf\n", got)
+	}
+	if got := ComputeMerkleRoot(nil); got != ([32]byte{}) {
+		t.Errorf("empty board root %x is not zero\n", got)
+	}
+}
+
+func TestMerkleRootTampered(t *testing.T) {
+	for _, board := range [][]uint8{{1, 2, 3}, {5, 5, 5, 2, 2, 7}, {0, 0, 0, 0, 0, 0, 0, 1}} {
+		root := ComputeMerkleRoot(board)
+		if !VerifyMerkleRoot(board, root) {
+			t.Errorf("%v: root doesn't verify\n", board)
+		}
+		for row := range board {
+			tampered := append([]uint8(nil), board...)
+			tampered[row] ^= 1
+			if VerifyMerkleRoot(tampered, root) {
+				t.Errorf("%v: root still verifies with row %d changed to %v\n", board, row, tampered[row])
+			}
+		}
+		// swapping rows changes the leaves' indices
+		swapped := append([]uint8{board[1], board[0]}, board[2:]...)
+		if board[0] != board[1] && VerifyMerkleRoot(swapped, root) {
+			t.Errorf("%v: root still verifies with rows swapped\n", board)
+		}
+	}
+}
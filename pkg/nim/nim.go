@@ -0,0 +1,2 @@
+// Package nim holds the game logic shared by the nim server and clients.
+package nim
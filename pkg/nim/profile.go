@@ -0,0 +1,79 @@
+package nim
+
+import (
+	"fmt"
+	"math/rand"
+	"slices"
+)
+
+// Board profiles name the shapes of board GenerateProfileBoard makes, for
+// trying strategies out on boards of one kind.
+const (
+	ProfileUniform = "uniform" // GenerateBoard's
+	ProfileSkewed  = "skewed"  // one heap of 6 to 10 coins, the rest of 1 or 2
+	ProfileSingle  = "single"  // one heap of 1 to 10 coins
+	ProfileEndgame = "endgame" // heaps of 1 or 2 coins, where misère play diverges
+	ProfileCustom  = "custom"  // heaps of k coins weighted by the (k-1)th weight
+)
+
+// BoardProfiles lists the profiles GenerateProfileBoard knows.
+var BoardProfiles = []string{ProfileUniform, ProfileSkewed, ProfileSingle, ProfileEndgame, ProfileCustom}
+
+// GenerateProfileBoard returns the board for seed shaped as profile says,
+// weights being the ProfileCustom weights and ignored by the others, and
+// adjusted to make good guarantee, one of BoardGuarantees, an empty one
+// meaning GuaranteeFirst. The board is the same for the same seed every
+// time.
+func GenerateProfileBoard(seed int64, profile string, weights []float64, guarantee string) ([]uint8, error) {
+	if guarantee != "" && !slices.Contains(BoardGuarantees, guarantee) {
+		return nil, fmt.Errorf("unknown board guarantee %q", guarantee)
+	}
+	switch profile {
+	case ProfileUniform:
+		return generateBoard(seed, 10, func(rng *rand.Rand) int { return rng.Intn(10) + 1 }, guarantee), nil
+	case ProfileSkewed:
+		return generateSkewedBoard(seed, guarantee), nil
+	case ProfileSingle:
+		rng := rand.New(rand.NewSource(seed))
+		return guaranteeBoard([]uint8{uint8(rng.Intn(10) + 1)}, guarantee, 0, 10), nil
+	case ProfileEndgame:
+		return generateBoard(seed, 2, func(rng *rand.Rand) int { return rng.Intn(2) + 1 }, guarantee), nil
+	case ProfileCustom:
+		if err := ValidProfileWeights(weights); err != nil {
+			return nil, err
+		}
+		// the adjustment keeps rows no longer than the weights allow
+		maxCoins := len(weights)
+		for weights[maxCoins-1] <= 0 {
+			maxCoins--
+		}
+		return generateBoard(seed, uint8(maxCoins), func(rng *rand.Rand) int { return WeightedRandom(weights, rng) + 1 }, guarantee), nil
+	}
+	return nil, fmt.Errorf("unknown board profile %q", profile)
+}
+
+// ValidProfileWeights reports why weights can't be ProfileCustom's weights,
+// or nil if they can: at most 255, with at least one positive.
+func ValidProfileWeights(weights []float64) error {
+	if len(weights) > 255 {
+		return fmt.Errorf("%d custom profile weights, more than the 255 coins a row holds", len(weights))
+	}
+	if !slices.ContainsFunc(weights, func(w float64) bool { return w > 0 }) {
+		return fmt.Errorf("custom profile weights %v have none positive", weights)
+	}
+	return nil
+}
+
+// generateSkewedBoard makes a ProfileSkewed board for seed: 3 to 16 rows,
+// one of 6 to 10 coins and the others of 1 or 2. The big heap is the one
+// changed to make the nim sum non-zero for guarantee.
+func generateSkewedBoard(seed int64, guarantee string) []uint8 {
+	rng := rand.New(rand.NewSource(seed))
+	board := make([]uint8, rng.Intn(14)+3)
+	big := rng.Intn(len(board))
+	for i := range board {
+		board[i] = uint8(rng.Intn(2) + 1)
+	}
+	board[big] = uint8(rng.Intn(5) + 6)
+	return guaranteeBoard(board, guarantee, big, 10)
+}
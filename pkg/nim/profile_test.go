@@ -0,0 +1,109 @@
+package nim
+
+import (
+	"bytes"
+	"slices"
+	"testing"
+)
+
+// profileBoards generates the boards of n seeds in profile.
+func profileBoards(t *testing.T, n int, profile string, weights []float64) [][]uint8 {
+	boards := make([][]uint8, n)
+	for seed := range boards {
+		board, err := GenerateProfileBoard(int64(seed), profile, weights, "")
+		if err != nil {
+			t.Fatalf("%v board for seed %d: %v\n", profile, seed, err)
+		}
+		again, _ := GenerateProfileBoard(int64(seed), profile, weights, "")
+		if !bytes.Equal(board, again) {
+			t.Fatalf("%v boards for seed %d differ: %v and %v\n", profile, seed, board, again)
+		}
+		if len(board) == 0 || bytes.IndexByte(board, 0) >= 0 || NimSum(board) == 0 {
+			t.Fatalf("%v board for seed %d is %v, expected non-empty rows and a non-zero nim sum\n", profile, seed, board)
+		}
+		boards[seed] = board
+	}
+	return boards
+}
+
+// rowsOf counts the rows of boards, and those of which keep says true.
+func rowsOf(boards [][]uint8, keep func(coins uint8) bool) (kept, rows int) {
+	for _, board := range boards {
+		for _, coins := range board {
+			if keep(coins) {
+				kept++
+			}
+			rows++
+		}
+	}
+	return kept, rows
+}
+
+func TestUniformProfile(t *testing.T) {
+	boards := profileBoards(t, 1000, ProfileUniform, nil)
+	for seed, board := range boards {
+		if !bytes.Equal(board, GenerateBoard(int64(seed))) {
+			t.Fatalf("uniform board for seed %d is %v, not GenerateBoard's\n", seed, board)
+		}
+	}
+	if mean := meanCoins(1000, GenerateBoard); mean < 5 || mean > 6 {
+		t.Errorf("uniform boards average %.2f coins a row, expected about 5.5\n", mean)
+	}
+}
+
+func TestSkewedProfile(t *testing.T) {
+	var bigCoins int
+	for seed, board := range profileBoards(t, 1000, ProfileSkewed, nil) {
+		big := slices.Max(board)
+		if len(board) < 3 || len(board) > 16 || big < 6 || big > 10 {
+			t.Fatalf("skewed board for seed %d is %v, expected 3 to 16 rows with one of 6 to 10 coins\n", seed, board)
+		}
+		if tiny, _ := rowsOf([][]uint8{board}, func(coins uint8) bool { return coins <= 2 }); tiny != len(board)-1 {
+			t.Fatalf("skewed board for seed %d is %v, expected every row but one of 1 or 2 coins\n", seed, board)
+		}
+		bigCoins += int(big)
+	}
+	if mean := float64(bigCoins) / 1000; mean < 7.5 || mean > 8.5 {
+		t.Errorf("skewed boards' big heaps average %.2f coins, expected about 8\n", mean)
+	}
+}
+
+func TestSingleProfile(t *testing.T) {
+	var coins int
+	for seed, board := range profileBoards(t, 1000, ProfileSingle, nil) {
+		if len(board) != 1 || board[0] > 10 {
+			t.Fatalf("single board for seed %d is %v, expected one heap of 1 to 10 coins\n", seed, board)
+		}
+		coins += int(board[0])
+	}
+	if mean := float64(coins) / 1000; mean < 5 || mean > 6 {
+		t.Errorf("single heaps average %.2f coins, expected about 5.5\n", mean)
+	}
+}
+
+func TestEndgameProfile(t *testing.T) {
+	boards := profileBoards(t, 1000, ProfileEndgame, nil)
+	if big, _ := rowsOf(boards, func(coins uint8) bool { return coins > 2 }); big != 0 {
+		t.Fatalf("%d endgame rows have more than 2 coins\n", big)
+	}
+	if twos, rows := rowsOf(boards, func(coins uint8) bool { return coins == 2 }); twos < rows*2/5 || twos > rows*3/5 {
+		t.Errorf("%d of %d endgame rows have 2 coins, expected about half\n", twos, rows)
+	}
+}
+
+func TestCustomProfile(t *testing.T) {
+	weights := []float64{1, 0, 0, 0, 0, 0, 0, 0, 0, 3}
+	boards := profileBoards(t, 1000, ProfileCustom, weights)
+	if tens, rows := rowsOf(boards, func(coins uint8) bool { return coins == 10 }); tens < rows*7/10 || tens > rows*8/10 {
+		t.Errorf("%d of %d custom rows have 10 coins, expected about three quarters\n", tens, rows)
+	}
+	// only rows of one coin: a board with an even number of them loses one
+	profileBoards(t, 1000, ProfileCustom, []float64{1})
+
+	if _, err := GenerateProfileBoard(1, ProfileCustom, []float64{0, -1}, ""); err == nil {
+		t.Errorf("custom weights with none positive made a board\n")
+	}
+	if _, err := GenerateProfileBoard(1, "lumpy", nil, ""); err == nil {
+		t.Errorf("an unknown profile made a board\n")
+	}
+}
@@ -0,0 +1,68 @@
+package nim
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Move takes Count coins from row Row.
+type Move struct {
+	Row   int
+	Count uint8
+}
+
+// maxRenderCoins is the widest heap RenderBoard draws in full; wider heaps
+// are cut short and labelled with their size.
+const maxRenderCoins = 20
+
+// RenderBoard draws board one heap per line, labelled with its index:
+//
+//	0 ●●●
+//	1 ●●○○
+//	2
+//
+// Coins taken by lastMove, if given, are drawn hollow on their row. Heaps
+// wider than maxRenderCoins are truncated with an ellipsis and their size.
+func RenderBoard(board []uint8, lastMove *Move) string {
+	width := len(fmt.Sprint(len(board) - 1))
+	var sb strings.Builder
+	for i, coins := range board {
+		var removed int
+		if lastMove != nil && lastMove.Row == i {
+			removed = int(lastMove.Count)
+		}
+		line := fmt.Sprintf("%*d %s", width, i, renderHeap(coins, removed))
+		sb.WriteString(strings.TrimRight(line, " "))
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// RenderBoardLine draws board on one line, heaps in order between bars,
+// empty ones as a dot, for following a game move by move:
+//
+//	●●● | ●● | · | ●
+//
+// Heaps are truncated as by RenderBoard.
+func RenderBoardLine(board []uint8) string {
+	heaps := make([]string, len(board))
+	for i, coins := range board {
+		heaps[i] = "·"
+		if coins > 0 {
+			heaps[i] = renderHeap(coins, 0)
+		}
+	}
+	return strings.Join(heaps, " | ")
+}
+
+// renderHeap draws a heap of coins with removed hollow coins after them.
+func renderHeap(coins uint8, removed int) string {
+	row := strings.Repeat("●", int(coins)) + strings.Repeat("○", removed)
+	if total := int(coins) + removed; total > maxRenderCoins {
+		row = string([]rune(row)[:maxRenderCoins]) + fmt.Sprintf("… %d", coins)
+		if removed > 0 {
+			row += fmt.Sprintf(" (-%d)", removed)
+		}
+	}
+	return row
+}
@@ -0,0 +1,62 @@
+package nim
+
+import "testing"
+
+func TestRenderBoard(t *testing.T) {
+	tests := []struct {
+		board    []uint8
+		lastMove *Move
+		want     string
+	}{
+		{[]uint8{3, 5, 7}, nil, "" +
+			"0 ●●●\n" +
+			"1 ●●●●●\n" +
+			"2 ●●●●●●●\n"},
+		{[]uint8{3, 2, 7}, &Move{Row: 1, Count: 3}, "" +
+			"0 ●●●\n" +
+			"1 ●●○○○\n" +
+			"2 ●●●●●●●\n"},
+		{[]uint8{0, 1, 0}, &Move{Row: 0, Count: 2}, "" +
+			"0 ○○\n" +
+			"1 ●\n" +
+			"2\n"},
+		{[]uint8{255, 18}, &Move{Row: 1, Count: 4}, "" +
+			"0 ●●●●●●●●●●●●●●●●●●●●… 255\n" +
+			"1 ●●●●●●●●●●●●●●●●●●○○… 18 (-4)\n"},
+		{[]uint8{1, 0, 0, 0, 0, 0, 0, 0, 0, 0, 2}, nil, "" +
+			" 0 ●\n" +
+			" 1\n" +
+			" 2\n" +
+			" 3\n" +
+			" 4\n" +
+			" 5\n" +
+			" 6\n" +
+			" 7\n" +
+			" 8\n" +
+			" 9\n" +
+			"10 ●●\n"},
+		{[]uint8{}, nil, ""},
+	}
+	for _, test := range tests {
+		if got := RenderBoard(test.board, test.lastMove); got != test.want {
+			t.Errorf("RenderBoard(%v, %v) =\n%s\nwant\n%s\n", test.board, test.lastMove, got, test.want)
+		}
+	}
+}
+
+func TestRenderBoardLine(t *testing.T) {
+	tests := []struct {
+		board []uint8
+		want  string
+	}{
+		{[]uint8{3, 2, 0, 1}, "●●● | ●● | · | ●"},
+		{[]uint8{0}, "·"},
+		{[]uint8{21, 1}, "●●●●●●●●●●●●●●●●●●●●… 21 | ●"},
+		{[]uint8{}, ""},
+	}
+	for _, test := range tests {
+		if got := RenderBoardLine(test.board); got != test.want {
+			t.Errorf("RenderBoardLine(%v) = %q, want %q\n", test.board, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package nim
+
+import "fmt"
+
+// RLEEncode run-length encodes board as [count, value, count, value, ...].
+// Runs longer than 255 rows are split.
+func RLEEncode(board []uint8) []uint8 {
+	encoded := []uint8{}
+	for i := 0; i < len(board); {
+		run := 1
+		for i+run < len(board) && board[i+run] == board[i] && run < 255 {
+			run++
+		}
+		encoded = append(encoded, uint8(run), board[i])
+		i += run
+	}
+	return encoded
+}
+
+// RLEDecode expands a board produced by RLEEncode, which mustn't have more
+// than maxRows rows, so a short message can't claim a huge board.
+func RLEDecode(encoded []uint8, maxRows int) ([]uint8, error) {
+	if len(encoded)%2 != 0 {
+		return nil, fmt.Errorf("rle: odd length %d", len(encoded))
+	}
+	rows := 0
+	for i := 0; i < len(encoded); i += 2 {
+		if encoded[i] == 0 {
+			return nil, fmt.Errorf("rle: zero-length run at %d", i)
+		}
+		if rows += int(encoded[i]); rows > maxRows {
+			return nil, fmt.Errorf("rle: more than %d rows", maxRows)
+		}
+	}
+	board := make([]uint8, 0, rows)
+	for i := 0; i < len(encoded); i += 2 {
+		for n := 0; n < int(encoded[i]); n++ {
+			board = append(board, encoded[i+1])
+		}
+	}
+	return board, nil
+}
@@ -0,0 +1,49 @@
+package nim
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestRLEEncode(t *testing.T) {
+	encoded := RLEEncode([]uint8{3, 3, 3, 5, 5})
+	if !bytes.Equal(encoded, []uint8{3, 3, 2, 5}) {
+		t.Errorf("expected [3 3 2 5], got %v\n", encoded)
+	}
+}
+
+func TestRLERoundTrip(t *testing.T) {
+	long := make([]uint8, 600)
+	for i := range long {
+		long[i] = 7
+	}
+	boards := [][]uint8{
+		{},
+		{1},
+		{3, 3, 3, 5, 5},
+		{1, 2, 3, 4, 5},
+		{0, 0, 9, 0, 0},
+		long,
+	}
+	for _, b := range boards {
+		encoded := RLEEncode(b)
+		decoded, err := RLEDecode(encoded, len(long))
+		if err != nil {
+			t.Errorf("decoding %v: %v\n", encoded, err)
+		}
+		if !bytes.Equal(decoded, b) {
+			t.Errorf("round trip of %v gave %v\n", b, decoded)
+		}
+	}
+	if n := len(RLEEncode(long)); n != 6 {
+		t.Errorf("600-row run should split into 3 pairs, got %d bytes\n", n)
+	}
+}
+
+func TestRLEDecodeErrors(t *testing.T) {
+	for _, encoded := range [][]uint8{{3}, {0, 5}, {2, 1, 0, 1}, {255, 1, 255, 1}} {
+		if _, err := RLEDecode(encoded, 300); err == nil {
+			t.Errorf("expected an error decoding %v\n", encoded)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package nim
+
+import (
+	"fmt"
+	"math/rand"
+)
+
+// Strategy picks a player's next move. Move is only called on boards with at
+// least one coin left and returns the row to take from and how many coins to
+// take from it.
+type Strategy interface {
+	Move(board []uint8) (row int, count uint8)
+}
+
+// Basic takes one coin from the first non-empty row.
+type Basic struct{}
+
+func (Basic) Move(board []uint8) (int, uint8) {
+	for i, coins := range board {
+		if coins > 0 {
+			return i, 1
+		}
+	}
+	return -1, 0
+}
+
+// Optimal plays the winning nim strategy described by
+// https://en.wikipedia.org/wiki/Nim, leaving a zero nim-sum whenever it can
+// and taking a single coin otherwise.
+type Optimal struct{}
+
+func (Optimal) Move(board []uint8) (int, uint8) {
+	sum := NimSum(board)
+	if sum == 0 {
+		return Basic{}.Move(board)
+	}
+	for i, coins := range board {
+		if coins^sum < coins {
+			return i, coins - (coins ^ sum)
+		}
+	}
+	return -1, 0
+}
+
+// Random takes a random number of coins from a random non-empty row.
+type Random struct {
+	Rng *rand.Rand
+}
+
+func (s Random) Move(board []uint8) (int, uint8) {
+	var rows []int
+	for i, coins := range board {
+		if coins > 0 {
+			rows = append(rows, i)
+		}
+	}
+	if len(rows) == 0 {
+		return -1, 0
+	}
+	row := rows[s.Rng.Intn(len(rows))]
+	return row, uint8(s.Rng.Intn(int(board[row]))) + 1
+}
+
+// StrategyNames lists the strategies NewStrategy knows, in display order.
+var StrategyNames = []string{"basic", "optimal", "random", "lasker", "wythoff"}
+
+// NewStrategy returns the strategy called name. seed seeds the random
+// strategy and is ignored by the others.
+func NewStrategy(name string, seed int64) (Strategy, error) {
+	switch name {
+	case "basic":
+		return Basic{}, nil
+	case "optimal":
+		return Optimal{}, nil
+	case "random":
+		return Random{Rng: rand.New(rand.NewSource(seed))}, nil
+	case "lasker":
+		return LaskerOptimal{}, nil
+	case "wythoff":
+		return WythoffOptimal{}, nil
+	}
+	return nil, fmt.Errorf("unknown strategy %q", name)
+}
+
+// NimSum is the XOR of every row of board. The player to move can force a
+// win exactly when it is non-zero.
+func NimSum(board []uint8) uint8 {
+	var sum uint8
+	for _, coins := range board {
+		sum ^= coins
+	}
+	return sum
+}
+
+// GameLength plays first against second from board, as SimulateGame does,
+// and returns the number of moves the game lasts. board is left untouched.
+func GameLength(board []uint8, first, second Strategy) int {
+	state := append([]uint8(nil), board...)
+	players := [2]Strategy{first, second}
+	for turn := 0; ; turn++ {
+		row, count := players[turn%2].Move(state)
+		if row < 0 {
+			return turn
+		}
+		state[row] -= count
+	}
+}
+
+// SimulateGame plays first against second from board, first moving first,
+// and reports whether first takes the last coin. board is left untouched.
+func SimulateGame(board []uint8, first, second Strategy) bool {
+	state := append([]uint8(nil), board...)
+	players := [2]Strategy{first, second}
+	for turn := 0; ; turn++ {
+		row, count := players[turn%2].Move(state)
+		if row < 0 {
+			// nothing left to take: the previous player took the last coin
+			return turn%2 == 1
+		}
+		state[row] -= count
+	}
+}
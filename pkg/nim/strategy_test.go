@@ -0,0 +1,117 @@
+package nim
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestBasicMove(t *testing.T) {
+	row, count := Basic{}.Move([]uint8{0, 0, 4, 2})
+	if row != 2 || count != 1 {
+		t.Errorf("expected (2, 1), got (%d, %d)\n", row, count)
+	}
+}
+
+func TestOptimalLeavesZeroNimSum(t *testing.T) {
+	boards := [][]uint8{
+		{3, 4, 5},
+		{1, 2, 4, 8},
+		{7, 0, 0, 1},
+		{10, 10, 1},
+	}
+	for _, b := range boards {
+		row, count := Optimal{}.Move(b)
+		if count == 0 || count > b[row] {
+			t.Fatalf("illegal move (%d, %d) on %v\n", row, count, b)
+		}
+		next := append([]uint8(nil), b...)
+		next[row] -= count
+		if NimSum(next) != 0 {
+			t.Errorf("move (%d, %d) on %v left nim-sum %d\n", row, count, b, NimSum(next))
+		}
+	}
+}
+
+func TestRandomIsReproducible(t *testing.T) {
+	play := func(seed int64) [][2]int {
+		s, err := NewStrategy("random", seed)
+		if err != nil {
+			t.Fatalf("creating random strategy: %v\n", err)
+		}
+		board := []uint8{5, 6, 7, 8}
+		var moves [][2]int
+		for board[0]+board[1]+board[2]+board[3] > 0 {
+			row, count := s.Move(board)
+			if count == 0 || count > board[row] {
+				t.Fatalf("illegal move (%d, %d) on %v\n", row, count, board)
+			}
+			board[row] -= count
+			moves = append(moves, [2]int{row, int(count)})
+		}
+		return moves
+	}
+	if a, b := play(42), play(42); !reflect.DeepEqual(a, b) {
+		t.Errorf("same seed gave different games:\n%v\n%v\n", a, b)
+	}
+}
+
+func TestNewStrategyUnknown(t *testing.T) {
+	if _, err := NewStrategy("greedy", 0); err == nil {
+		t.Errorf("expected an error for an unknown strategy\n")
+	}
+}
+
+func TestSimulateGame(t *testing.T) {
+	board := []uint8{3, 4, 5}
+	if !SimulateGame(board, Optimal{}, Basic{}) {
+		t.Errorf("optimal should win %v moving first\n", board)
+	}
+	if SimulateGame([]uint8{1, 2, 3}, Basic{}, Optimal{}) {
+		t.Errorf("basic should lose a zero nim-sum board to optimal\n")
+	}
+	if board[0] != 3 || board[1] != 4 || board[2] != 5 {
+		t.Errorf("SimulateGame modified the board: %v\n", board)
+	}
+}
+
+func TestGameLength(t *testing.T) {
+	board := []uint8{3, 4, 5}
+	if got := GameLength(board, Basic{}, Basic{}); got != 12 {
+		t.Errorf("basic against basic took %d moves on %v, expected one a coin\n", got, board)
+	}
+	if got := GameLength(board, Optimal{}, Optimal{}); got < 3 || got > 12 {
+		t.Errorf("optimal against optimal took %d moves on %v\n", got, board)
+	}
+	if got := GameLength(nil, Basic{}, Basic{}); got != 0 {
+		t.Errorf("an empty board took %d moves\n", got)
+	}
+	if board[0] != 3 || board[1] != 4 || board[2] != 5 {
+		t.Errorf("GameLength modified the board: %v\n", board)
+	}
+}
+
+// TestAllSeedsOptimalOutcome guards the game logic as a whole: every board
+// a game can start on is a win for the player moving first with Optimal,
+// and the same board with its nim sum cleared, which GenerateBoard never
+// deals, is a loss for whoever moves first, however well they play.
+func TestAllSeedsOptimalOutcome(t *testing.T) {
+	for seed := math.MinInt8; seed <= math.MaxInt8; seed++ {
+		board := GenerateBoard(int64(seed))
+		if NimSum(board) == 0 {
+			t.Fatalf("seed %d: board nim sum should be non-zero: %v\n", seed, board)
+		}
+		if !SimulateGame(board, Optimal{}, Basic{}) {
+			t.Errorf("seed %d: optimal should win %v moving first against basic\n", seed, board)
+		}
+
+		last := len(board) - 1
+		board[last] ^= NimSum(board)
+		if NimSum(board) != 0 {
+			t.Fatalf("seed %d: clearing the nim sum left %v\n", seed, board)
+		}
+		if SimulateGame(board, Optimal{}, Optimal{}) {
+			t.Errorf("seed %d: moving first on zero nim-sum board %v should lose\n", seed, board)
+		}
+	}
+}
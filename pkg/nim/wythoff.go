@@ -0,0 +1,108 @@
+package nim
+
+import (
+	"math"
+	"math/rand"
+)
+
+// Wythoff's game is played on exactly two heaps, and a move takes any
+// number of coins from one heap or the same number from both. See
+// https://en.wikipedia.org/wiki/Wythoff%27s_game.
+
+// WythoffVariant is the name client and server give Wythoff's game when
+// agreeing at GameStart to play it.
+const WythoffVariant = "wythoff"
+
+// WythoffBothRows is the row a move taking coins from both heaps of
+// Wythoff's game is sent with, its count being the coins taken from each.
+const WythoffBothRows = 2
+
+// phi is the golden ratio, which the cold positions are spaced by.
+var phi = (1 + math.Sqrt(5)) / 2
+
+// IsWythoffCold reports whether a board of heaps a and b, in either order,
+// is lost for the player to move: the kth such board, counting from zero,
+// has heaps of floor(k*phi) and floor(k*phi^2) coins.
+func IsWythoffCold(a, b uint8) bool {
+	if a > b {
+		a, b = b, a
+	}
+	k := float64(b - a)
+	return int(a) == int(math.Floor(k*phi))
+}
+
+// GenerateWythoffBoard returns the board of Wythoff's game for seed: two
+// heaps of 1 to 10 coins that aren't a cold position, so the first player
+// can always win.
+func GenerateWythoffBoard(seed int64) []uint8 {
+	rng := rand.New(rand.NewSource(seed))
+	board := []uint8{uint8(rng.Intn(10) + 1), uint8(rng.Intn(10) + 1)}
+	// every heap size is in one cold position, so changing the other heap
+	// by a coin leaves it
+	if IsWythoffCold(board[0], board[1]) {
+		if board[1] < 10 {
+			board[1]++
+		} else {
+			board[1]--
+		}
+	}
+	return board
+}
+
+// ValidWythoffMove reports whether after is before, a board of two heaps,
+// with coins taken from one heap or as many taken from both.
+func ValidWythoffMove(before, after []uint8) bool {
+	if len(before) != 2 || len(after) != 2 || after[0] > before[0] || after[1] > before[1] {
+		return false
+	}
+	first, second := before[0]-after[0], before[1]-after[1]
+	return first > 0 && (second == 0 || second == first) || first == 0 && second > 0
+}
+
+// WythoffStrategy picks moves in Wythoff's game. A Strategy without
+// WythoffMove plays Wythoff's game by its Move, only ever taking from one
+// heap, which is as legal there as in nim.
+type WythoffStrategy interface {
+	// WythoffMove returns the board after its move on board, two heaps
+	// with at least one coin left, which is left untouched.
+	WythoffMove(board []uint8) []uint8
+}
+
+// WythoffOptimal plays Wythoff's game by moving to a cold position whenever
+// it can, and taking a single coin otherwise. In nim it plays as Optimal
+// does.
+type WythoffOptimal struct{}
+
+func (WythoffOptimal) Move(board []uint8) (int, uint8) {
+	return Optimal{}.Move(board)
+}
+
+func (WythoffOptimal) WythoffMove(board []uint8) []uint8 {
+	a, b := board[0], board[1]
+	for n := uint8(1); n <= max(a, b); n++ {
+		switch {
+		case n <= a && IsWythoffCold(a-n, b):
+			return []uint8{a - n, b}
+		case n <= b && IsWythoffCold(a, b-n):
+			return []uint8{a, b - n}
+		case n <= a && n <= b && IsWythoffCold(a-n, b-n):
+			return []uint8{a - n, b - n}
+		}
+	}
+	row, count := Basic{}.Move(board)
+	after := append([]uint8(nil), board...)
+	after[row] -= count
+	return after
+}
+
+// WythoffStep describes the move from before to after, a ValidWythoffMove:
+// the heap taken from, or WythoffBothRows, and the coins taken from it.
+func WythoffStep(before, after []uint8) (row int, count uint8) {
+	switch {
+	case before[0] == after[0]:
+		return 1, before[1] - after[1]
+	case before[1] == after[1]:
+		return 0, before[0] - after[0]
+	}
+	return WythoffBothRows, before[0] - after[0]
+}
@@ -0,0 +1,133 @@
+package nim
+
+import (
+	"bytes"
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+// TestWythoffColdPublished checks the first cold positions of Wythoff's
+// game, as published for it, are cold, and the boards a coin away aren't.
+func TestWythoffColdPublished(t *testing.T) {
+	cold := [][2]uint8{{0, 0}, {1, 2}, {3, 5}, {4, 7}, {6, 10}, {8, 13}, {9, 15}, {11, 18}, {12, 20}, {14, 23}}
+	for _, p := range cold {
+		if !IsWythoffCold(p[0], p[1]) || !IsWythoffCold(p[1], p[0]) {
+			t.Errorf("(%d, %d) isn't cold\n", p[0], p[1])
+		}
+		if IsWythoffCold(p[0], p[1]+1) {
+			t.Errorf("(%d, %d) is cold\n", p[0], p[1]+1)
+		}
+	}
+}
+
+// TestWythoffColdFromRules works out which boards are lost from the rules,
+// as those with no move to a lost board, for heaps of up to 60 coins.
+func TestWythoffColdFromRules(t *testing.T) {
+	const size = 61
+	var lost [size][size]bool
+	for a := 0; a < size; a++ {
+		for b := 0; b < size; b++ {
+			lost[a][b] = true
+			for n := 1; n <= max(a, b) && lost[a][b]; n++ {
+				if n <= a && lost[a-n][b] || n <= b && lost[a][b-n] || n <= a && n <= b && lost[a-n][b-n] {
+					lost[a][b] = false
+				}
+			}
+			if got := IsWythoffCold(uint8(a), uint8(b)); got != lost[a][b] {
+				t.Errorf("IsWythoffCold(%d, %d) = %v, expected %v\n", a, b, got, lost[a][b])
+			}
+		}
+	}
+}
+
+func TestValidWythoffMove(t *testing.T) {
+	before := []uint8{4, 7}
+	tests := []struct {
+		after []uint8
+		want  bool
+	}{
+		{[]uint8{1, 7}, true},     // take 3 from the first
+		{[]uint8{4, 0}, true},     // take the second
+		{[]uint8{2, 5}, true},     // take 2 from both
+		{[]uint8{0, 3}, true},     // empty the first, taking from both
+		{[]uint8{4, 7}, false},    // no move
+		{[]uint8{3, 5}, false},    // unequal amounts from both
+		{[]uint8{5, 7}, false},    // adds coins
+		{[]uint8{5, 6}, false},    // adds to one, takes from the other
+		{[]uint8{4, 7, 0}, false}, // a heap from nowhere
+		{[]uint8{4}, false},       // a heap less
+	}
+	for _, test := range tests {
+		if got := ValidWythoffMove(before, test.after); got != test.want {
+			t.Errorf("ValidWythoffMove(%v, %v) = %v, expected %v\n", before, test.after, got, test.want)
+		}
+	}
+	if row, count := WythoffStep(before, []uint8{2, 5}); row != WythoffBothRows || count != 2 {
+		t.Errorf("WythoffStep described taking 2 from both as %d, %d\n", row, count)
+	}
+	if row, count := WythoffStep(before, []uint8{4, 1}); row != 1 || count != 6 {
+		t.Errorf("WythoffStep described taking 6 from row 1 as %d, %d\n", row, count)
+	}
+}
+
+func TestGenerateWythoffBoard(t *testing.T) {
+	for seed := int64(0); seed < 200; seed++ {
+		board := GenerateWythoffBoard(seed)
+		if len(board) != 2 || board[0] < 1 || board[0] > 10 || board[1] < 1 || board[1] > 10 {
+			t.Fatalf("seed %d generated %v, expected two heaps of 1 to 10 coins\n", seed, board)
+		}
+		if IsWythoffCold(board[0], board[1]) {
+			t.Errorf("seed %d generated the cold position %v\n", seed, board)
+		}
+		if !bytes.Equal(board, GenerateWythoffBoard(seed)) {
+			t.Errorf("seed %d generated different boards\n", seed)
+		}
+	}
+}
+
+// TestWythoffOptimal plays WythoffOptimal against random moves: every move
+// it makes is legal and, from a board that isn't cold, leaves a cold one,
+// so it wins every game it starts on such a board.
+func TestWythoffOptimal(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for game := 0; game < 200; game++ {
+		board := []uint8{uint8(rng.Intn(40)), uint8(rng.Intn(40))}
+		if IsWythoffCold(board[0], board[1]) {
+			continue
+		}
+		for turn := 0; slices.Max(board) > 0; turn++ {
+			var after []uint8
+			if turn%2 == 0 {
+				after = WythoffOptimal{}.WythoffMove(board)
+				if !IsWythoffCold(after[0], after[1]) {
+					t.Fatalf("WythoffOptimal moved %v to %v, which isn't cold\n", board, after)
+				}
+			} else {
+				after = randomWythoffMove(rng, board)
+			}
+			if !ValidWythoffMove(board, after) {
+				t.Fatalf("illegal move from %v to %v\n", board, after)
+			}
+			board = after
+			if slices.Max(board) == 0 && turn%2 == 1 {
+				t.Fatalf("WythoffOptimal lost\n")
+			}
+		}
+	}
+}
+
+// randomWythoffMove takes coins from a random non-empty heap or, as often
+// when both have coins, from both.
+func randomWythoffMove(rng *rand.Rand, board []uint8) []uint8 {
+	after := append([]uint8(nil), board...)
+	if both := min(board[0], board[1]); both > 0 && rng.Intn(2) == 0 {
+		n := uint8(1 + rng.Intn(int(both)))
+		after[0] -= n
+		after[1] -= n
+		return after
+	}
+	row, count := Random{Rng: rng}.Move(board)
+	after[row] -= count
+	return after
+}
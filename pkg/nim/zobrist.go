@@ -0,0 +1,63 @@
+package nim
+
+import (
+	"math/rand"
+	"sync"
+	"sync/atomic"
+)
+
+// zobristTable holds a random value for every (row, coins) pair of the
+// longest board hashed yet, grown a row at a time from a fixed seed so
+// hashes are stable across runs however long the boards hashed first.
+var zobristTable struct {
+	rows atomic.Pointer[[][256]uint64]
+	mu   sync.Mutex // held while growing rows
+	rng  *rand.Rand
+}
+
+// zobristRows returns the table with at least n rows.
+func zobristRows(n int) [][256]uint64 {
+	if rows := zobristTable.rows.Load(); rows != nil && len(*rows) >= n {
+		return *rows
+	}
+	zobristTable.mu.Lock()
+	defer zobristTable.mu.Unlock()
+	var rows [][256]uint64
+	if p := zobristTable.rows.Load(); p != nil {
+		rows = *p
+	} else {
+		zobristTable.rng = rand.New(rand.NewSource(0x6e696d))
+	}
+	if len(rows) >= n {
+		return rows
+	}
+	// readers keep the old slice, so copy rather than append in place
+	grown := make([][256]uint64, len(rows), max(n, 2*len(rows)))
+	copy(grown, rows)
+	for len(grown) < cap(grown) {
+		var keys [256]uint64
+		for coins := range keys {
+			keys[coins] = zobristTable.rng.Uint64()
+		}
+		grown = append(grown, keys)
+	}
+	zobristTable.rows.Store(&grown)
+	return grown
+}
+
+// ZobristHash hashes board, of any length, for transposition table lookups.
+func ZobristHash(board []uint8) uint64 {
+	table := zobristRows(len(board))
+	var hash uint64
+	for row, coins := range board {
+		hash ^= table[row][coins]
+	}
+	return hash
+}
+
+// IncrementalHash updates prev, the hash of a board, for row changing from
+// oldVal to newVal coins.
+func IncrementalHash(prev uint64, row int, oldVal, newVal uint8) uint64 {
+	table := zobristRows(row + 1)
+	return prev ^ table[row][oldVal] ^ table[row][newVal]
+}
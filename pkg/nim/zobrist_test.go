@@ -0,0 +1,55 @@
+package nim
+
+import "testing"
+
+func TestIncrementalHash(t *testing.T) {
+	board := []uint8{3, 4, 5, 0, 9}
+	hash := ZobristHash(board)
+	for _, move := range []Move{{0, 2}, {4, 9}, {2, 1}, {1, 4}} {
+		old := board[move.Row]
+		board[move.Row] -= move.Count
+		hash = IncrementalHash(hash, move.Row, old, board[move.Row])
+		if full := ZobristHash(board); hash != full {
+			t.Errorf("after %v: incremental hash %x, full hash %x\n", move, hash, full)
+		}
+	}
+}
+
+func TestZobristHashDistinguishesRows(t *testing.T) {
+	if ZobristHash([]uint8{1, 2}) == ZobristHash([]uint8{2, 1}) {
+		t.Errorf("permuted boards hash the same\n")
+	}
+}
+
+func TestZobristHashLongBoard(t *testing.T) {
+	small := ZobristHash([]uint8{1, 2})
+	long := make([]uint8, 300)
+	long[299] = 1
+	if ZobristHash(long) == ZobristHash(long[:299]) {
+		t.Errorf("a coin in row 299 didn't change the hash\n")
+	}
+	if after := ZobristHash([]uint8{1, 2}); after != small {
+		t.Errorf("hashing a long board changed a short board's hash from %x to %x\n", small, after)
+	}
+}
+
+var benchBoard = []uint8{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 1, 2, 3, 4, 5, 6}
+
+func BenchmarkZobristHash(b *testing.B) {
+	var hash uint64
+	for i := 0; i < b.N; i++ {
+		benchBoard[i%16] ^= 1
+		hash = ZobristHash(benchBoard)
+	}
+	_ = hash
+}
+
+func BenchmarkIncrementalHash(b *testing.B) {
+	hash := ZobristHash(benchBoard)
+	for i := 0; i < b.N; i++ {
+		row := i % 16
+		old := benchBoard[row]
+		benchBoard[row] ^= 1
+		hash = IncrementalHash(hash, row, old, benchBoard[row])
+	}
+}
@@ -0,0 +1,51 @@
+// Package nimerr defines the kinds of failure the nim binaries tell apart,
+// so that only main decides what they mean for the exit status:
+//
+//	if errors.Is(err, nimerr.ErrConfig) {
+//		os.Exit(2)
+//	}
+//
+// Errors are marked with a kind by New and Wrap, which leave their messages
+// alone.
+package nimerr
+
+import "errors"
+
+var (
+	// ErrConfig is a config file, flag or environment variable that can't
+	// be used.
+	ErrConfig = errors.New("config error")
+	// ErrTransport is a connection that couldn't be made or stopped working.
+	ErrTransport = errors.New("transport error")
+	// ErrProtocol is a message from a peer that couldn't be understood.
+	ErrProtocol = errors.New("protocol error")
+	// ErrGameState is a game that can't go on from the state it is in.
+	ErrGameState = errors.New("game state error")
+)
+
+// New returns an error with message text that is of the given kind.
+func New(kind error, text string) error {
+	return &kindError{kind: kind, err: errors.New(text)}
+}
+
+// Wrap marks err as being of the given kind without changing its message.
+// It returns nil if err is nil.
+func Wrap(kind, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &kindError{kind: kind, err: err}
+}
+
+type kindError struct {
+	kind error
+	err  error
+}
+
+func (e *kindError) Error() string {
+	return e.err.Error()
+}
+
+func (e *kindError) Unwrap() []error {
+	return []error{e.err, e.kind}
+}
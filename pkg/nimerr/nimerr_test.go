@@ -0,0 +1,34 @@
+package nimerr
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"testing"
+)
+
+func TestKinds(t *testing.T) {
+	errNoReply := New(ErrTransport, "server stopped responding")
+	wrapped := fmt.Errorf("playing: %w", errNoReply)
+	if !errors.Is(wrapped, errNoReply) || !errors.Is(wrapped, ErrTransport) {
+		t.Errorf("%v lost its kind when wrapped\n", wrapped)
+	}
+	if errors.Is(wrapped, ErrProtocol) {
+		t.Errorf("%v is a transport error, not a protocol error\n", wrapped)
+	}
+	if errNoReply.Error() != "server stopped responding" {
+		t.Errorf("New changed the message to %q\n", errNoReply.Error())
+	}
+
+	missing := fmt.Errorf("reading config: %w", fs.ErrNotExist)
+	err := Wrap(ErrConfig, missing)
+	if !errors.Is(err, ErrConfig) || !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Wrap should keep both the kind and the cause: %v\n", err)
+	}
+	if err.Error() != missing.Error() {
+		t.Errorf("Wrap changed the message from %q to %q\n", missing.Error(), err.Error())
+	}
+	if Wrap(ErrConfig, nil) != nil {
+		t.Errorf("Wrap of nil should be nil\n")
+	}
+}
@@ -0,0 +1,340 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        (unknown)
+// source: nim.proto
+
+package nimpb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type StartGameRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Seed          int32                  `protobuf:"varint,1,opt,name=seed,proto3" json:"seed,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StartGameRequest) Reset() {
+	*x = StartGameRequest{}
+	mi := &file_nim_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StartGameRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StartGameRequest) ProtoMessage() {}
+
+func (x *StartGameRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_nim_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StartGameRequest.ProtoReflect.Descriptor instead.
+func (*StartGameRequest) Descriptor() ([]byte, []int) {
+	return file_nim_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *StartGameRequest) GetSeed() int32 {
+	if x != nil {
+		return x.Seed
+	}
+	return 0
+}
+
+type MoveRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	GameId        string                 `protobuf:"bytes,1,opt,name=game_id,json=gameId,proto3" json:"game_id,omitempty"`
+	GameState     []byte                 `protobuf:"bytes,2,opt,name=game_state,json=gameState,proto3" json:"game_state,omitempty"`
+	MoveRow       int32                  `protobuf:"varint,3,opt,name=move_row,json=moveRow,proto3" json:"move_row,omitempty"`
+	MoveCount     int32                  `protobuf:"varint,4,opt,name=move_count,json=moveCount,proto3" json:"move_count,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MoveRequest) Reset() {
+	*x = MoveRequest{}
+	mi := &file_nim_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MoveRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MoveRequest) ProtoMessage() {}
+
+func (x *MoveRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_nim_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MoveRequest.ProtoReflect.Descriptor instead.
+func (*MoveRequest) Descriptor() ([]byte, []int) {
+	return file_nim_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *MoveRequest) GetGameId() string {
+	if x != nil {
+		return x.GameId
+	}
+	return ""
+}
+
+func (x *MoveRequest) GetGameState() []byte {
+	if x != nil {
+		return x.GameState
+	}
+	return nil
+}
+
+func (x *MoveRequest) GetMoveRow() int32 {
+	if x != nil {
+		return x.MoveRow
+	}
+	return 0
+}
+
+func (x *MoveRequest) GetMoveCount() int32 {
+	if x != nil {
+		return x.MoveCount
+	}
+	return 0
+}
+
+type GetStateRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	GameId        string                 `protobuf:"bytes,1,opt,name=game_id,json=gameId,proto3" json:"game_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetStateRequest) Reset() {
+	*x = GetStateRequest{}
+	mi := &file_nim_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetStateRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetStateRequest) ProtoMessage() {}
+
+func (x *GetStateRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_nim_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetStateRequest.ProtoReflect.Descriptor instead.
+func (*GetStateRequest) Descriptor() ([]byte, []int) {
+	return file_nim_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *GetStateRequest) GetGameId() string {
+	if x != nil {
+		return x.GameId
+	}
+	return ""
+}
+
+type GameState struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	GameId        string                 `protobuf:"bytes,1,opt,name=game_id,json=gameId,proto3" json:"game_id,omitempty"`
+	GameState     []byte                 `protobuf:"bytes,2,opt,name=game_state,json=gameState,proto3" json:"game_state,omitempty"`
+	MoveRow       int32                  `protobuf:"varint,3,opt,name=move_row,json=moveRow,proto3" json:"move_row,omitempty"`
+	MoveCount     int32                  `protobuf:"varint,4,opt,name=move_count,json=moveCount,proto3" json:"move_count,omitempty"`
+	Winner        string                 `protobuf:"bytes,5,opt,name=winner,proto3" json:"winner,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GameState) Reset() {
+	*x = GameState{}
+	mi := &file_nim_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GameState) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GameState) ProtoMessage() {}
+
+func (x *GameState) ProtoReflect() protoreflect.Message {
+	mi := &file_nim_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GameState.ProtoReflect.Descriptor instead.
+func (*GameState) Descriptor() ([]byte, []int) {
+	return file_nim_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *GameState) GetGameId() string {
+	if x != nil {
+		return x.GameId
+	}
+	return ""
+}
+
+func (x *GameState) GetGameState() []byte {
+	if x != nil {
+		return x.GameState
+	}
+	return nil
+}
+
+func (x *GameState) GetMoveRow() int32 {
+	if x != nil {
+		return x.MoveRow
+	}
+	return 0
+}
+
+func (x *GameState) GetMoveCount() int32 {
+	if x != nil {
+		return x.MoveCount
+	}
+	return 0
+}
+
+func (x *GameState) GetWinner() string {
+	if x != nil {
+		return x.Winner
+	}
+	return ""
+}
+
+var File_nim_proto protoreflect.FileDescriptor
+
+const file_nim_proto_rawDesc = "" +
+	"\n" +
+	"\tnim.proto\x12\animgame\"&\n" +
+	"\x10StartGameRequest\x12\x12\n" +
+	"\x04seed\x18\x01 \x01(\x05R\x04seed\"\x7f\n" +
+	"\vMoveRequest\x12\x17\n" +
+	"\agame_id\x18\x01 \x01(\tR\x06gameId\x12\x1d\n" +
+	"\n" +
+	"game_state\x18\x02 \x01(\fR\tgameState\x12\x19\n" +
+	"\bmove_row\x18\x03 \x01(\x05R\amoveRow\x12\x1d\n" +
+	"\n" +
+	"move_count\x18\x04 \x01(\x05R\tmoveCount\"*\n" +
+	"\x0fGetStateRequest\x12\x17\n" +
+	"\agame_id\x18\x01 \x01(\tR\x06gameId\"\x95\x01\n" +
+	"\tGameState\x12\x17\n" +
+	"\agame_id\x18\x01 \x01(\tR\x06gameId\x12\x1d\n" +
+	"\n" +
+	"game_state\x18\x02 \x01(\fR\tgameState\x12\x19\n" +
+	"\bmove_row\x18\x03 \x01(\x05R\amoveRow\x12\x1d\n" +
+	"\n" +
+	"move_count\x18\x04 \x01(\x05R\tmoveCount\x12\x16\n" +
+	"\x06winner\x18\x05 \x01(\tR\x06winner2\xb5\x01\n" +
+	"\aNimGame\x12:\n" +
+	"\tStartGame\x12\x19.nimgame.StartGameRequest\x1a\x12.nimgame.GameState\x124\n" +
+	"\bMakeMove\x12\x14.nimgame.MoveRequest\x1a\x12.nimgame.GameState\x128\n" +
+	"\bGetState\x12\x18.nimgame.GetStateRequest\x1a\x12.nimgame.GameStateB\x13Z\x11nimgame/pkg/nimpbb\x06proto3"
+
+var (
+	file_nim_proto_rawDescOnce sync.Once
+	file_nim_proto_rawDescData []byte
+)
+
+func file_nim_proto_rawDescGZIP() []byte {
+	file_nim_proto_rawDescOnce.Do(func() {
+		file_nim_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_nim_proto_rawDesc), len(file_nim_proto_rawDesc)))
+	})
+	return file_nim_proto_rawDescData
+}
+
+var file_nim_proto_msgTypes = make([]protoimpl.MessageInfo, 4)
+var file_nim_proto_goTypes = []any{
+	(*StartGameRequest)(nil), // 0: nimgame.StartGameRequest
+	(*MoveRequest)(nil),      // 1: nimgame.MoveRequest
+	(*GetStateRequest)(nil),  // 2: nimgame.GetStateRequest
+	(*GameState)(nil),        // 3: nimgame.GameState
+}
+var file_nim_proto_depIdxs = []int32{
+	0, // 0: nimgame.NimGame.StartGame:input_type -> nimgame.StartGameRequest
+	1, // 1: nimgame.NimGame.MakeMove:input_type -> nimgame.MoveRequest
+	2, // 2: nimgame.NimGame.GetState:input_type -> nimgame.GetStateRequest
+	3, // 3: nimgame.NimGame.StartGame:output_type -> nimgame.GameState
+	3, // 4: nimgame.NimGame.MakeMove:output_type -> nimgame.GameState
+	3, // 5: nimgame.NimGame.GetState:output_type -> nimgame.GameState
+	3, // [3:6] is the sub-list for method output_type
+	0, // [0:3] is the sub-list for method input_type
+	0, // [0:0] is the sub-list for extension type_name
+	0, // [0:0] is the sub-list for extension extendee
+	0, // [0:0] is the sub-list for field type_name
+}
+
+func init() { file_nim_proto_init() }
+func file_nim_proto_init() {
+	if File_nim_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_nim_proto_rawDesc), len(file_nim_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   4,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_nim_proto_goTypes,
+		DependencyIndexes: file_nim_proto_depIdxs,
+		MessageInfos:      file_nim_proto_msgTypes,
+	}.Build()
+	File_nim_proto = out.File
+	file_nim_proto_goTypes = nil
+	file_nim_proto_depIdxs = nil
+}
@@ -0,0 +1,197 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: nim.proto
+
+package nimpb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	NimGame_StartGame_FullMethodName = "/nimgame.NimGame/StartGame"
+	NimGame_MakeMove_FullMethodName  = "/nimgame.NimGame/MakeMove"
+	NimGame_GetState_FullMethodName  = "/nimgame.NimGame/GetState"
+)
+
+// NimGameClient is the client API for NimGame service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type NimGameClient interface {
+	StartGame(ctx context.Context, in *StartGameRequest, opts ...grpc.CallOption) (*GameState, error)
+	MakeMove(ctx context.Context, in *MoveRequest, opts ...grpc.CallOption) (*GameState, error)
+	GetState(ctx context.Context, in *GetStateRequest, opts ...grpc.CallOption) (*GameState, error)
+}
+
+type nimGameClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewNimGameClient(cc grpc.ClientConnInterface) NimGameClient {
+	return &nimGameClient{cc}
+}
+
+func (c *nimGameClient) StartGame(ctx context.Context, in *StartGameRequest, opts ...grpc.CallOption) (*GameState, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GameState)
+	err := c.cc.Invoke(ctx, NimGame_StartGame_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *nimGameClient) MakeMove(ctx context.Context, in *MoveRequest, opts ...grpc.CallOption) (*GameState, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GameState)
+	err := c.cc.Invoke(ctx, NimGame_MakeMove_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *nimGameClient) GetState(ctx context.Context, in *GetStateRequest, opts ...grpc.CallOption) (*GameState, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GameState)
+	err := c.cc.Invoke(ctx, NimGame_GetState_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// NimGameServer is the server API for NimGame service.
+// All implementations must embed UnimplementedNimGameServer
+// for forward compatibility.
+type NimGameServer interface {
+	StartGame(context.Context, *StartGameRequest) (*GameState, error)
+	MakeMove(context.Context, *MoveRequest) (*GameState, error)
+	GetState(context.Context, *GetStateRequest) (*GameState, error)
+	mustEmbedUnimplementedNimGameServer()
+}
+
+// UnimplementedNimGameServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedNimGameServer struct{}
+
+func (UnimplementedNimGameServer) StartGame(context.Context, *StartGameRequest) (*GameState, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method StartGame not implemented")
+}
+func (UnimplementedNimGameServer) MakeMove(context.Context, *MoveRequest) (*GameState, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method MakeMove not implemented")
+}
+func (UnimplementedNimGameServer) GetState(context.Context, *GetStateRequest) (*GameState, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetState not implemented")
+}
+func (UnimplementedNimGameServer) mustEmbedUnimplementedNimGameServer() {}
+func (UnimplementedNimGameServer) testEmbeddedByValue()                 {}
+
+// UnsafeNimGameServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to NimGameServer will
+// result in compilation errors.
+type UnsafeNimGameServer interface {
+	mustEmbedUnimplementedNimGameServer()
+}
+
+func RegisterNimGameServer(s grpc.ServiceRegistrar, srv NimGameServer) {
+	// If the following call pancis, it indicates UnimplementedNimGameServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&NimGame_ServiceDesc, srv)
+}
+
+func _NimGame_StartGame_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(StartGameRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NimGameServer).StartGame(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: NimGame_StartGame_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NimGameServer).StartGame(ctx, req.(*StartGameRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _NimGame_MakeMove_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MoveRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NimGameServer).MakeMove(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: NimGame_MakeMove_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NimGameServer).MakeMove(ctx, req.(*MoveRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _NimGame_GetState_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetStateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NimGameServer).GetState(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: NimGame_GetState_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NimGameServer).GetState(ctx, req.(*GetStateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// NimGame_ServiceDesc is the grpc.ServiceDesc for NimGame service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var NimGame_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "nimgame.NimGame",
+	HandlerType: (*NimGameServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "StartGame",
+			Handler:    _NimGame_StartGame_Handler,
+		},
+		{
+			MethodName: "MakeMove",
+			Handler:    _NimGame_MakeMove_Handler,
+		},
+		{
+			MethodName: "GetState",
+			Handler:    _NimGame_GetState_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "nim.proto",
+}
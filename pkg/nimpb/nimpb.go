@@ -0,0 +1,4 @@
+// Package nimpb is the gRPC NimGame service, generated from nim.proto.
+package nimpb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative nim.proto
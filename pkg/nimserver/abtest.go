@@ -0,0 +1,45 @@
+package nimserver
+
+import "math/rand"
+
+// The server's strategies, as labelled in GameSession.Strategy and the
+// nim_wins_total and nim_losses_total metrics.
+const (
+	strategyBest   = "best"
+	strategyNormal = "normal"
+)
+
+// chooseDifficulty picks how a new game for seed is played: as WithStrategy
+// chooses, by the seed's parity, or with ABTestEnabled by chance, bestMove
+// in an ABTestRatio of games. A/B games can't be replayed from their seed,
+// so a client failing over to another server mid-game may see its moves
+// rejected.
+func (s *Server) chooseDifficulty(seed int8) int8 {
+	if s.choose != nil {
+		return s.choose(seed)
+	}
+	if !s.config.ABTestEnabled {
+		return seed & 1
+	}
+	if rand.Float64() < s.config.ABTestRatio {
+		return 1
+	}
+	return 0
+}
+
+// strategyName names the strategy Play uses at difficulty.
+func strategyName(difficulty int8) string {
+	if difficulty == 1 {
+		return strategyBest
+	}
+	return strategyNormal
+}
+
+// countOutcome counts a finished game against the strategy that played it.
+func countOutcome(strategy, winner string) {
+	if winner == "server" {
+		serverWins.WithLabelValues(strategy).Inc()
+	} else {
+		serverLosses.WithLabelValues(strategy).Inc()
+	}
+}
@@ -0,0 +1,60 @@
+package nimserver
+
+import (
+	"math/rand"
+	"testing"
+
+	"nimgame/pkg/nim"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// TestABTestStrategies plays 100 games against a randomly moving client,
+// half of them expected on each strategy, and checks both win counters move.
+func TestABTestStrategies(t *testing.T) {
+	server, raddr := serveOnLoopback(t, &ServerConfig{ABTestEnabled: true, ABTestRatio: 0.5}, nil)
+	client := nim.Random{Rng: rand.New(rand.NewSource(1))}
+
+	wins := map[string]float64{}
+	for _, strategy := range []string{strategyBest, strategyNormal} {
+		wins[strategy] = testutil.ToFloat64(serverWins.WithLabelValues(strategy))
+	}
+	assigned := map[string]int{}
+	for game := 0; game < 100; game++ {
+		player := newTestClient(t, raddr, nil)
+		local := player.conn.LocalAddr().String()
+
+		// seed 0 alone would always play normalMove
+		player.exchange(StateMoveMessage{GameState: nil, MoveRow: -1, MoveCount: 0})
+		sess := sessionOf(server, local)
+		if sess.Strategy != strategyName(sess.Difficulty) {
+			t.Fatalf("game %d: strategy %q doesn't match difficulty %d\n", game, sess.Strategy, sess.Difficulty)
+		}
+		assigned[sess.Strategy]++
+		for sess.Playing {
+			board := append([]uint8(nil), sess.LastMove.GameState...)
+			row, count := client.Move(board)
+			board[row] -= count
+			player.exchange(StateMoveMessage{GameState: board, MoveRow: int8(row), MoveCount: int8(count)})
+			sess = sessionOf(server, local)
+		}
+	}
+
+	if assigned[strategyBest] == 0 || assigned[strategyNormal] == 0 {
+		t.Errorf("expected games on both strategies, got %v\n", assigned)
+	}
+	for strategy, before := range wins {
+		if won := testutil.ToFloat64(serverWins.WithLabelValues(strategy)) - before; won == 0 {
+			t.Errorf("no wins counted for %v over %d games\n", strategy, assigned[strategy])
+		}
+	}
+}
+
+func TestChooseDifficultyBySeed(t *testing.T) {
+	server := newServer(&ServerConfig{}, nil, nil)
+	for _, seed := range []int8{-3, -2, 0, 1, 127} {
+		if got := server.chooseDifficulty(seed); got != seed&1 {
+			t.Errorf("seed %d played at difficulty %d without A/B testing\n", seed, got)
+		}
+	}
+}
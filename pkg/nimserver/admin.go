@@ -0,0 +1,47 @@
+package nimserver
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"golang.org/x/net/websocket"
+)
+
+// serveAdmin serves the operator endpoints on lis until the returned server
+// is closed.
+func (s *Server) serveAdmin(lis net.Listener) *http.Server {
+	admin := &http.Server{Handler: s.adminHandler()}
+	go func() {
+		if err := admin.Serve(lis); !errors.Is(err, http.ErrServerClosed) {
+			fmt.Fprintf(os.Stderr, "Error serving admin endpoints: %v\n", err)
+		}
+	}()
+	return admin
+}
+
+// adminHandler routes the operator endpoints, and the game endpoints if
+// AdminGamesEnabled.
+func (s *Server) adminHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	if s.seedCache != nil {
+		mux.Handle("GET /query/seed/{seed}", seedQueryHandler(s.seedCache))
+	}
+	mux.HandleFunc("GET /games", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(s.ActiveGames())
+	})
+	s.handleDebug(mux)
+	if s.config.AdminGamesEnabled {
+		s.handleGames(mux)
+		// websocket.Server, unlike websocket.Handler, takes any Origin,
+		// including the "null" of a page opened from a file
+		mux.Handle("/ws", websocket.Server{Handler: s.serveWebSocket})
+	}
+	return mux
+}
@@ -0,0 +1,100 @@
+package nimserver
+
+import (
+	"math"
+	"time"
+)
+
+// reasonTimeout is the GameComplete reason of a game the client lost on
+// time, its clock having run out.
+const reasonTimeout = "timeout"
+
+// Clock is a game's chess clock, for a client that asked for time control
+// at GameStart: each side has Budget for the whole game. The client's runs
+// from the first send of a reply to the arrival of its move answering it,
+// and the server's while it decides its moves. The client loses the game
+// once its clock runs out.
+//
+// Every send to the client, resends included, is numbered with the next
+// Seq, and the client's move carries back the Seq of the copy it answers.
+// A lost reply, or a Sync, gets the reply resent without restarting the
+// client's clock; the Seq only tells a retransmitted copy of a move already
+// played, which answers an earlier reply, from the move answering this one.
+type Clock struct {
+	Budget  time.Duration
+	Client  time.Duration // left on the client's clock
+	Server  time.Duration // left on the server's clock
+	Seq     uint32        // of the latest send
+	MoveSeq uint32        // of the first send of the reply the client is to answer
+
+	// when the reply the client is to answer was first sent; not saved, so
+	// a restored game's clock resumes from its next send
+	sentAt time.Time
+}
+
+// newClock returns a clock giving each side budget milliseconds, or nil for
+// a game without time control, when budget isn't positive.
+func newClock(budget int32) *Clock {
+	if budget <= 0 {
+		return nil
+	}
+	d := time.Duration(budget) * time.Millisecond
+	return &Clock{Budget: d, Client: d, Server: d}
+}
+
+// stamp numbers move, a send to the client at now, and copies the clocks
+// into it. The client's clock starts on the first send of a reply, and
+// runs on through its resends.
+func (c *Clock) stamp(move *StateMoveMessage, now time.Time) {
+	c.Seq++
+	if c.MoveSeq == 0 {
+		c.MoveSeq = c.Seq
+	}
+	if c.sentAt.IsZero() {
+		c.sentAt = now
+	}
+	move.Seq = c.Seq
+	move.TimeControl = milliseconds(c.Budget)
+	move.ClientClock = milliseconds(c.Client)
+	move.ServerClock = milliseconds(c.Server)
+}
+
+// replayed reports whether a move carrying back ack answers a reply sent
+// before the one the client is to answer, so is a copy of a move already
+// played. Clients that don't carry Seq back send 0, which never is.
+func (c *Clock) replayed(ack uint32) bool {
+	return ack != 0 && ack < c.MoveSeq
+}
+
+// charge stops the client's clock for a move answering the reply it is to
+// answer, read at receivedAt, reporting whether any time was left.
+func (c *Clock) charge(receivedAt time.Time) bool {
+	if !c.sentAt.IsZero() && receivedAt.After(c.sentAt) {
+		c.Client -= receivedAt.Sub(c.sentAt)
+	}
+	c.MoveSeq, c.sentAt = 0, time.Time{}
+	return c.Client > 0
+}
+
+// left is the time on the client's clock at now, which is running while
+// the client is to answer a reply.
+func (c *Clock) left(now time.Time) time.Duration {
+	if c.sentAt.IsZero() || !now.After(c.sentAt) {
+		return c.Client
+	}
+	return c.Client - now.Sub(c.sentAt)
+}
+
+// think charges the server's clock for deciding a move from receivedAt
+// until now.
+func (c *Clock) think(receivedAt, now time.Time) {
+	if now.After(receivedAt) {
+		c.Server -= now.Sub(receivedAt)
+	}
+}
+
+// milliseconds is d in whole milliseconds, as the clocks are sent; time
+// run out is 0, not negative.
+func milliseconds(d time.Duration) int32 {
+	return int32(min(max(d.Milliseconds(), 0), math.MaxInt32))
+}
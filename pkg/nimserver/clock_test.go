@@ -0,0 +1,153 @@
+package nimserver
+
+import (
+	"encoding/json"
+	"net"
+	"testing"
+	"time"
+)
+
+// TestClockFlagFall plays a game under time control on a fake clock: a
+// reply lost on its way is charged from its first send, not the copy
+// resent, and the move after the client's clock runs out loses it the game.
+func TestClockFlagFall(t *testing.T) {
+	tracingAddr, output := startTracingServer(t)
+	config := &ServerConfig{TracingServerAddress: tracingAddr}
+	udp := &MockUDPConn{}
+	server := newServer(config, newTestTracer(t, tracingAddr, "server"), udp, WithDataset(map[int8][]uint8{4: {9, 9, 9}}))
+	t.Cleanup(server.stopMoveTimers)
+
+	// every reply is written a millisecond after its move is read
+	var receivedAt time.Time
+	server.now = func() time.Time { return receivedAt.Add(time.Millisecond) }
+	raddr := &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 9}
+	exchange := func(move StateMoveMessage, at time.Duration) StateMoveMessage {
+		t.Helper()
+		packet, err := Marshal(move)
+		if err != nil {
+			t.Fatalf("marshalling %+v: %v\n", move, err)
+		}
+		receivedAt = time.Unix(1000, 0).Add(at)
+		sent := len(udp.OutPackets)
+		server.handleMove(packet, raddr, receivedAt)
+		if len(udp.OutPackets) == sent {
+			t.Fatalf("no reply to %+v\n", move)
+		}
+		var reply StateMoveMessage
+		if err := UnmarshalMove(udp.OutPackets[len(udp.OutPackets)-1], &reply, defaultMaxBoardRows); err != nil {
+			t.Fatalf("unmarshalling reply: %v\n", err)
+		}
+		return reply
+	}
+	expectClocks := func(reply StateMoveMessage, seq uint32, client, server int32) {
+		t.Helper()
+		if reply.TimeControl != 60000 || reply.Seq != seq || reply.ClientClock != client || reply.ServerClock != server {
+			t.Errorf("reply %+v, expected Seq %d with %dms left to the client and %dms to the server\n", reply, seq, client, server)
+		}
+	}
+
+	start := exchange(StateMoveMessage{GameState: nil, MoveRow: -1, MoveCount: 4, TimeControl: 60000}, 0)
+	expectClocks(start, 1, 60000, 60000)
+
+	// sent at 1ms, answered at 10s
+	first, _ := normalMove(append([]uint8(nil), start.GameState...))
+	first.Seq = start.Seq
+	reply := exchange(*first, 10*time.Second)
+	expectClocks(reply, 2, 50001, 59999)
+
+	// the reply, sent at 10.001s, is lost, and the move retransmitted at 40s
+	// gets it resent, which is answered at 45s
+	resent := exchange(*first, 40*time.Second)
+	expectClocks(resent, 3, 50001, 59999)
+	second, _ := normalMove(append([]uint8(nil), resent.GameState...))
+	second.Seq = resent.Seq
+	reply = exchange(*second, 45*time.Second)
+	expectClocks(reply, 4, 15002, 59998)
+
+	// sent at 45.001s, answered 45.002s later
+	third, _ := normalMove(append([]uint8(nil), reply.GameState...))
+	third.Seq = reply.Seq
+	notice := exchange(*third, 90003*time.Millisecond)
+	if notice.MoveRow != forfeitMoveRow || notice.ClientClock != 0 {
+		t.Errorf("late move answered with %+v, expected a forfeit with no time left\n", notice)
+	}
+	if sess := server.session(raddr.String()); sess != nil {
+		t.Errorf("session still kept after the client ran out of time: %+v\n", sess)
+	}
+	if stats := server.Stats(); stats.ServerWins != 1 || stats.InvalidMoves != 0 {
+		t.Errorf("expected the server to win, with the retransmitted move not counted invalid, got %+v\n", stats)
+	}
+
+	var traced []GameComplete
+	for _, r := range readTraceRecords(t, output) {
+		if r.TracerIdentity == "server" && r.Tag == "GameComplete" {
+			var action GameComplete
+			if err := json.Unmarshal(r.Body, &action); err != nil {
+				t.Fatalf("decoding %s: %v\n", r.Body, err)
+			}
+			traced = append(traced, action)
+		}
+	}
+	if want := (GameComplete{Winner: "server", Reason: reasonTimeout}); len(traced) != 1 || traced[0] != want {
+		t.Errorf("traced %+v, expected one %+v\n", traced, want)
+	}
+}
+
+// TestClockTimer starts a game with a 300ms clock and never moves.
+func TestClockTimer(t *testing.T) {
+	server, raddr := serveOnLoopback(t, &ServerConfig{}, nil)
+	client := newTestClient(t, raddr, nil)
+	start := client.exchange(StateMoveMessage{GameState: nil, MoveRow: -1, MoveCount: 4, TimeControl: 300})
+	if start.TimeControl != 300 || start.ClientClock != 300 {
+		t.Fatalf("game started with %+v, expected 300ms on the client's clock\n", start)
+	}
+
+	client.conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	n, err := client.conn.Read(client.buf)
+	if err != nil {
+		t.Fatalf("no forfeit notice: %v\n", err)
+	}
+	var notice StateMoveMessage
+	if err := UnmarshalMove(client.buf[:n], &notice, defaultMaxBoardRows); err != nil || notice.MoveRow != forfeitMoveRow || notice.ClientClock != 0 {
+		t.Errorf("expected a forfeit notice with no time left, got %+v (%v)\n", notice, err)
+	}
+	if server.session(client.conn.LocalAddr().String()) != nil {
+		t.Errorf("session still kept after the client ran out of time\n")
+	}
+}
+
+// TestClockSyncFlagFall starts a game with a 300ms clock and only ever
+// Syncs, whose resends mustn't restart the client's clock.
+func TestClockSyncFlagFall(t *testing.T) {
+	server, raddr := serveOnLoopback(t, &ServerConfig{}, nil)
+	client := newTestClient(t, raddr, nil)
+	client.exchange(StateMoveMessage{GameState: nil, MoveRow: -1, MoveCount: 4, TimeControl: 300})
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		packet, err := Marshal(StateMoveMessage{GameState: nil, MoveRow: syncMoveRow})
+		if err != nil {
+			t.Fatalf("marshalling Sync: %v\n", err)
+		}
+		if _, err := client.conn.Write(packet); err != nil {
+			t.Fatalf("sending Sync: %v\n", err)
+		}
+		client.conn.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+		n, err := client.conn.Read(client.buf)
+		if err != nil {
+			continue // Syncs after the game is over go unanswered
+		}
+		var reply StateMoveMessage
+		if err := UnmarshalMove(client.buf[:n], &reply, defaultMaxBoardRows); err != nil {
+			t.Fatalf("unmarshalling reply: %v\n", err)
+		}
+		if reply.MoveRow == forfeitMoveRow {
+			if server.session(client.conn.LocalAddr().String()) != nil {
+				t.Errorf("session still kept after the client ran out of time\n")
+			}
+			return
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	t.Errorf("client Syncing every 50ms never ran out of its 300ms\n")
+}
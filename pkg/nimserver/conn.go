@@ -0,0 +1,78 @@
+package nimserver
+
+import (
+	"fmt"
+	"net"
+)
+
+// serveConn passes the packets read from conn, a connection to one client
+// reading a move at a time, on to answer until either side closes it. The
+// game is kept under prefix and the client's address, its session
+// outliving the connection while the game is in progress so it can be
+// resumed from another.
+func (s *Server) serveConn(conn net.Conn, prefix string) {
+	if !s.trackConn(conn) {
+		conn.Close()
+		return
+	}
+	key := prefix + conn.RemoteAddr().String()
+	defer func() {
+		s.untrackConn(conn)
+		conn.Close()
+		s.gameMu.Lock()
+		defer s.gameMu.Unlock()
+		if sess := s.session(key); sess != nil && !sess.Playing {
+			s.sessionsMu.Lock()
+			delete(s.sessions, key)
+			s.sessionsMu.Unlock()
+			s.persist(key)
+		}
+	}()
+
+	send := func(reply []byte) {
+		if _, err := conn.Write(reply); err != nil {
+			fmt.Printf("Error sending to remote address: %v\n", conn.RemoteAddr())
+		}
+	}
+	packet := make([]byte, 64*1024)
+	for {
+		n, err := conn.Read(packet)
+		if err != nil {
+			s.logger().Debug("connection ended", "client", conn.RemoteAddr(), "key", key, "err", err)
+			return
+		}
+		receivedAt := s.now()
+		s.gameMu.Lock()
+		s.answer(packet[:n], key, receivedAt, send)
+		s.gameMu.Unlock()
+	}
+}
+
+// trackConn adds conn to the connections closed by release, reporting
+// false if they already have been.
+func (s *Server) trackConn(conn net.Conn) bool {
+	s.connsMu.Lock()
+	defer s.connsMu.Unlock()
+	if s.conns == nil {
+		return false
+	}
+	s.conns[conn] = true
+	return true
+}
+
+func (s *Server) untrackConn(conn net.Conn) {
+	s.connsMu.Lock()
+	defer s.connsMu.Unlock()
+	delete(s.conns, conn)
+}
+
+// closeConns closes the TCP and DTLS connections of games being played,
+// and any accepted later.
+func (s *Server) closeConns() {
+	s.connsMu.Lock()
+	defer s.connsMu.Unlock()
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.conns = nil
+}
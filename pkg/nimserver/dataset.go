@@ -0,0 +1,234 @@
+package nimserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+
+	"nimgame/pkg/nim"
+)
+
+// datasetEntry is one board of a dataset file, which holds a JSON list of
+// them. Rows are read as ints since []uint8 would be expected in base64.
+type datasetEntry struct {
+	Seed  *int8 `json:"seed"`
+	Board []int `json:"board"`
+}
+
+// LoadDataset reads the boards to play for particular seeds from the
+// dataset at path, in place of those GenerateBoard would make. Every board
+// must have at least one row, and every row 1 to 255 coins.
+func LoadDataset(path string) (map[int8][]uint8, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var entries []datasetEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("parsing dataset %v: %w", path, err)
+	}
+	boards := make(map[int8][]uint8, len(entries))
+	for i, entry := range entries {
+		if entry.Seed == nil {
+			return nil, fmt.Errorf("dataset %v: entry %d has no seed", path, i)
+		}
+		seed := *entry.Seed
+		if _, ok := boards[seed]; ok {
+			return nil, fmt.Errorf("dataset %v: seed %d appears more than once", path, seed)
+		}
+		if len(entry.Board) == 0 {
+			return nil, fmt.Errorf("dataset %v: seed %d has an empty board", path, seed)
+		}
+		board := make([]uint8, len(entry.Board))
+		for row, coins := range entry.Board {
+			if coins < 1 || coins > 255 {
+				return nil, fmt.Errorf("dataset %v: seed %d has %d coins in row %d, not 1 to 255", path, seed, coins, row)
+			}
+			board[row] = uint8(coins)
+		}
+		boards[seed] = board
+	}
+	return boards, nil
+}
+
+// checkDataset reports dataset boards too large for CheckMove to accept
+// moves on.
+func checkDataset(config *ServerConfig, boards map[int8][]uint8) error {
+	for seed, board := range boards {
+		if len(board) > config.maxBoardRows() {
+			return fmt.Errorf("seed %d has %d rows, more than MaxBoardRows %d", seed, len(board), config.maxBoardRows())
+		}
+		for row, coins := range board {
+			if coins > config.maxCoinsPerRow() {
+				return fmt.Errorf("seed %d has %d coins in row %d, more than MaxCoinsPerRow %d", seed, coins, row, config.maxCoinsPerRow())
+			}
+		}
+	}
+	return nil
+}
+
+// WithDataset plays the given boards for their seeds; see LoadDataset.
+func WithDataset(boards map[int8][]uint8) Option {
+	return func(s *Server) { s.dataset = boards }
+}
+
+// BoardConfig is what decides the board a game is played on from its seed.
+type BoardConfig struct {
+	Dataset         map[int8][]uint8 // boards by seed, played in place of generated ones
+	StochasticMode  bool
+	StochasticAlpha float64 // zero means defaultStochasticAlpha
+
+	// MinGameLength rejects generated boards that a game between two naive
+	// players, each taking a coin at a time, finishes in fewer moves, trying
+	// the following seeds instead, up to MaxSeedRetries of them (zero means
+	// defaultMaxSeedRetries). Clients verifying the initial board will find
+	// a replaced one isn't the seed's.
+	MinGameLength  int
+	MaxSeedRetries int
+
+	// TwoHeaps generates boards of Wythoff's game, two heaps of
+	// GenerateWythoffBoard's, in place of the dataset's and the others
+	TwoHeaps bool
+
+	// Profile generates boards with nim.GenerateProfileBoard, in place of
+	// GenerateBoard's and StochasticMode's; ProfileWeights are the custom
+	// profile's
+	Profile        string
+	ProfileWeights []float64
+
+	// Guarantee, one of nim.BoardGuarantees, says who generated boards are
+	// won by with perfect play; empty means nim.GuaranteeFirst
+	Guarantee string
+
+	log *slog.Logger // where rejected seeds are logged; nil means slog.Default()
+}
+
+// boardConfig is the server's BoardConfig.
+func (s *Server) boardConfig() *BoardConfig {
+	return &BoardConfig{
+		Dataset:         s.dataset,
+		StochasticMode:  s.config.StochasticMode,
+		StochasticAlpha: s.config.StochasticAlpha,
+		MinGameLength:   s.config.MinGameLength,
+		MaxSeedRetries:  s.config.MaxSeedRetries,
+		Profile:         s.config.BoardProfile,
+		ProfileWeights:  s.config.BoardProfileWeights,
+		Guarantee:       s.config.BoardGuarantee,
+		log:             s.logger(),
+	}
+}
+
+// board returns the board for a new game with seed: in TwoHeaps mode
+// GenerateWythoffBoard's, or else the dataset's if it has one, or else
+// GenerateBoard's, or GenerateStochasticBoard's in StochasticMode, or
+// Profile's, adjusted for Guarantee, for the first seed from seed on whose
+// board lasts MinGameLength moves. A nil cfg
+// always generates the seed's board.
+func (cfg *BoardConfig) board(seed int8) []uint8 {
+	if cfg == nil {
+		return nim.GenerateBoard(int64(seed))
+	}
+	if cfg.TwoHeaps {
+		return nim.GenerateWythoffBoard(int64(seed))
+	}
+	if board, ok := cfg.Dataset[seed]; ok {
+		return append([]uint8(nil), board...)
+	}
+	first := cfg.generate(int64(seed))
+	if cfg.MinGameLength == 0 {
+		return first
+	}
+	board := first
+	for try := int64(0); ; try++ {
+		length := nim.GameLength(board, nim.Basic{}, nim.Basic{})
+		if length >= cfg.MinGameLength {
+			return board
+		}
+		cfg.logger().Debug("rejecting seed", "seed", int64(seed)+try, "moves", length, "min_game_length", cfg.MinGameLength)
+		if try == int64(cfg.maxSeedRetries()) {
+			break
+		}
+		board = cfg.generate(int64(seed) + try + 1)
+	}
+	cfg.logger().Warn("no board lasts MinGameLength moves; playing the seed's own", "seed", seed, "retries", cfg.maxSeedRetries())
+	return first
+}
+
+// generate makes the board for seed, in Profile, in StochasticMode or
+// neither, making good Guarantee.
+func (cfg *BoardConfig) generate(seed int64) []uint8 {
+	profile, weights := cfg.Profile, cfg.ProfileWeights
+	if profile == "" {
+		profile = nim.ProfileUniform
+		if cfg.StochasticMode {
+			profile, weights = nim.ProfileCustom, nim.StochasticWeights(cfg.stochasticAlpha())
+		}
+	}
+	board, err := nim.GenerateProfileBoard(seed, profile, weights, cfg.Guarantee)
+	if err != nil {
+		cfg.logger().Warn("can't generate board; playing the seed's usual one", "profile", profile, "guarantee", cfg.Guarantee, "err", err)
+		return nim.GenerateBoard(seed)
+	}
+	return board
+}
+
+const defaultMaxSeedRetries = 100
+
+// maxSeedRetries is MaxSeedRetries, defaulting to defaultMaxSeedRetries.
+func (cfg *BoardConfig) maxSeedRetries() int {
+	if cfg.MaxSeedRetries == 0 {
+		return defaultMaxSeedRetries
+	}
+	return cfg.MaxSeedRetries
+}
+
+// logger is log, defaulting to slog.Default().
+func (cfg *BoardConfig) logger() *slog.Logger {
+	if cfg.log == nil {
+		return slog.Default()
+	}
+	return cfg.log
+}
+
+// stochasticAlpha is StochasticAlpha, defaulting to defaultStochasticAlpha.
+func (cfg *BoardConfig) stochasticAlpha() float64 {
+	if cfg.StochasticAlpha == 0 {
+		return defaultStochasticAlpha
+	}
+	return cfg.StochasticAlpha
+}
+
+// newBoard returns the board for a new game of variant with seed, in
+// profile and making good guarantee, as the server's BoardConfig says.
+func (s *Server) newBoard(seed int8, variant, profile, guarantee string) []uint8 {
+	cfg := s.boardConfig()
+	cfg.TwoHeaps = variant == nim.WythoffVariant
+	cfg.Profile, cfg.Guarantee = profile, guarantee
+	return cfg.board(seed)
+}
+
+// boardProfile is the profile to generate the board of a game in for a
+// client asking for asked at GameStart: asked, if it is one of
+// nim.BoardProfiles, but for "custom" without BoardProfileWeights, and
+// BoardProfile otherwise.
+func (s *Server) boardProfile(asked string) string {
+	if !slices.Contains(nim.BoardProfiles, asked) || asked == nim.ProfileCustom && len(s.config.BoardProfileWeights) == 0 {
+		return s.config.BoardProfile
+	}
+	return asked
+}
+
+// boardGuarantee is the guarantee to make good for a client asking for
+// asked at GameStart: asked, if it is one of nim.BoardGuarantees, and
+// otherwise BoardGuarantee, or nim.GuaranteeFirst if that is empty.
+func (s *Server) boardGuarantee(asked string) string {
+	switch {
+	case slices.Contains(nim.BoardGuarantees, asked):
+		return asked
+	case s.config.BoardGuarantee != "":
+		return s.config.BoardGuarantee
+	}
+	return nim.GuaranteeFirst
+}
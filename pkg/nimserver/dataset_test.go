@@ -0,0 +1,193 @@
+package nimserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"nimgame/pkg/nim"
+)
+
+func writeDataset(t *testing.T, data string) string {
+	path := filepath.Join(t.TempDir(), "dataset.json")
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatalf("writing dataset: %v\n", err)
+	}
+	return path
+}
+
+func TestLoadDataset(t *testing.T) {
+	path := writeDataset(t, `[
+		{"seed": 42, "board": [3, 5, 7, 2]},
+		{"seed": -7, "board": [1]},
+		{"seed": 0, "board": [10, 10, 10]},
+		{"seed": 127, "board": [1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 1, 2, 3, 4, 5, 6]},
+		{"seed": -128, "board": [255, 1]}
+	]`)
+	dataset, err := LoadDataset(path)
+	if err != nil {
+		t.Fatalf("loading dataset: %v\n", err)
+	}
+	want := map[int8][]uint8{
+		42:   {3, 5, 7, 2},
+		-7:   {1},
+		0:    {10, 10, 10},
+		127:  {1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 1, 2, 3, 4, 5, 6},
+		-128: {255, 1},
+	}
+	server := newServer(&ServerConfig{}, nil, nil, WithDataset(dataset))
+	for seed, board := range want {
+		if got := server.newBoard(seed, "", "", ""); !bytes.Equal(got, board) {
+			t.Errorf("seed %d: expected board %v, got %v\n", seed, board, got)
+		}
+	}
+	if got := server.newBoard(1, "", "", ""); !bytes.Equal(got, nim.GenerateBoard(1)) {
+		t.Errorf("seed missing from the dataset: expected the generated board, got %v\n", got)
+	}
+
+	// games mustn't change the dataset's boards
+	server.newBoard(42, "", "", "")[0] = 0
+	if got := server.newBoard(42, "", "", ""); got[0] != 3 {
+		t.Errorf("dataset board was modified: %v\n", got)
+	}
+	if err := checkDataset(&ServerConfig{}, dataset); err == nil {
+		t.Errorf("expected a row of 255 coins to exceed the default MaxCoinsPerRow\n")
+	}
+}
+
+func TestLoadDatasetInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"not JSON", `{"seed": 1`},
+		{"no seed", `[{"board": [1, 2]}]`},
+		{"empty board", `[{"seed": 1, "board": []}]`},
+		{"empty row", `[{"seed": 1, "board": [1, 0, 2]}]`},
+		{"too many coins", `[{"seed": 1, "board": [256]}]`},
+		{"seed out of range", `[{"seed": 200, "board": [1]}]`},
+		{"duplicate seed", `[{"seed": 1, "board": [1]}, {"seed": 1, "board": [2]}]`},
+	}
+	for _, test := range tests {
+		if _, err := LoadDataset(writeDataset(t, test.data)); err == nil {
+			t.Errorf("%s: expected an error\n", test.name)
+		}
+	}
+}
+
+func TestGameStartFromDataset(t *testing.T) {
+	_, raddr := serveOnLoopback(t, &ServerConfig{}, nil, WithDataset(map[int8][]uint8{5: {2, 2, 3}}))
+	reply := newTestClient(t, raddr, nil).exchange(StateMoveMessage{GameState: nil, MoveRow: -1, MoveCount: 5})
+	if !bytes.Equal(reply.GameState, []uint8{2, 2, 3}) {
+		t.Errorf("expected the game to start on the dataset's board, got %v\n", reply.GameState)
+	}
+}
+
+func TestMinGameLength(t *testing.T) {
+	cfg := &BoardConfig{MinGameLength: 10, Dataset: map[int8][]uint8{3: {1, 1}}}
+	replaced := 0
+	for seed := -128; seed <= 127; seed++ {
+		board := cfg.board(int8(seed))
+		if seed == 3 {
+			if !bytes.Equal(board, []uint8{1, 1}) {
+				t.Errorf("seed 3: expected the dataset's board, got %v\n", board)
+			}
+			continue
+		}
+		if moves := nim.GameLength(board, nim.Basic{}, nim.Basic{}); moves < 10 {
+			t.Errorf("seed %d: board %v lasts %d moves under naive play, expected at least 10\n", seed, board, moves)
+		}
+		if !bytes.Equal(board, nim.GenerateBoard(int64(seed))) {
+			replaced++
+		}
+	}
+	if replaced == 0 {
+		t.Errorf("expected some seeds' boards to be too short and replaced\n")
+	}
+
+	// with no seed in reach lasting long enough, the seed's own board is played
+	cfg = &BoardConfig{MinGameLength: 1000, MaxSeedRetries: 3}
+	if got := cfg.board(7); !bytes.Equal(got, nim.GenerateBoard(7)) {
+		t.Errorf("expected seed 7's own board once retries ran out, got %v\n", got)
+	}
+}
+
+// TestBoardProfile checks a GameStart asking for a profile is played on its
+// board, and one asking for none, or for custom boards the server has no
+// weights for, on the BoardProfile's.
+func TestBoardProfile(t *testing.T) {
+	_, raddr := serveOnLoopback(t, &ServerConfig{BoardProfile: nim.ProfileEndgame}, nil)
+	c := newTestClient(t, raddr, nil)
+
+	tests := []struct {
+		asked, played string
+	}{
+		{nim.ProfileSingle, nim.ProfileSingle},
+		{nim.ProfileSkewed, nim.ProfileSkewed},
+		{"", nim.ProfileEndgame},
+		{"lumpy", nim.ProfileEndgame},
+		{nim.ProfileCustom, nim.ProfileEndgame},
+	}
+	for _, test := range tests {
+		reply := c.exchange(StateMoveMessage{GameState: nil, MoveRow: -1, MoveCount: 9, BoardProfile: test.asked})
+		if want, _ := nim.GenerateProfileBoard(9, test.played, nil, ""); !bytes.Equal(reply.GameState, want) {
+			t.Errorf("asking for %q started on %v, expected %v, the %v board\n", test.asked, reply.GameState, want, test.played)
+		}
+	}
+
+	// with weights, custom boards are played as asked
+	_, raddr = serveOnLoopback(t, &ServerConfig{BoardProfileWeights: []float64{0, 0, 0, 1}}, nil)
+	c = newTestClient(t, raddr, nil)
+	reply := c.exchange(StateMoveMessage{GameState: nil, MoveRow: -1, MoveCount: 9, BoardProfile: nim.ProfileCustom})
+	if want, _ := nim.GenerateProfileBoard(9, nim.ProfileCustom, []float64{0, 0, 0, 1}, ""); !bytes.Equal(reply.GameState, want) {
+		t.Errorf("asking for custom boards started on %v, expected %v\n", reply.GameState, want)
+	}
+}
+
+// TestBoardGuarantee checks games start on boards with the nim sum their
+// guarantee asks for, the server's BoardGuarantee unless the GameStart asks
+// for another, and that each is traced.
+func TestBoardGuarantee(t *testing.T) {
+	tracingAddr, output := startTracingServer(t)
+	_, raddr := startServer(t, &ServerConfig{TracingServerAddress: tracingAddr, BoardGuarantee: nim.GuaranteeSecond})
+	c := newTestClient(t, raddr, nil)
+
+	tests := []struct {
+		asked, made string
+	}{
+		{"", nim.GuaranteeSecond},
+		{nim.GuaranteeFirst, nim.GuaranteeFirst},
+		{"nobody", nim.GuaranteeSecond},
+		{nim.GuaranteeRandom, nim.GuaranteeRandom},
+	}
+	var made []string
+	for _, test := range tests {
+		for seed := int8(0); seed < 20; seed++ {
+			reply := c.exchange(StateMoveMessage{GameState: nil, MoveRow: -1, MoveCount: seed, BoardGuarantee: test.asked})
+			if want, _ := nim.GenerateProfileBoard(int64(seed), nim.ProfileUniform, nil, test.made); !bytes.Equal(reply.GameState, want) {
+				t.Errorf("seed %d asking for %q started on %v, expected %v\n", seed, test.asked, reply.GameState, want)
+			}
+			if sum := nim.NimSum(reply.GameState); test.made == nim.GuaranteeFirst && sum == 0 || test.made == nim.GuaranteeSecond && sum != 0 {
+				t.Errorf("seed %d asking for %q started on %v, with nim sum %d\n", seed, test.asked, reply.GameState, sum)
+			}
+			made = append(made, test.made)
+		}
+	}
+
+	var traced []string
+	for _, r := range readTraceRecords(t, output) {
+		if r.TracerIdentity == "server" && r.Tag == "BoardGuarantee" {
+			var action BoardGuarantee
+			if err := json.Unmarshal(r.Body, &action); err != nil {
+				t.Fatalf("decoding %s: %v\n", r.Body, err)
+			}
+			traced = append(traced, action.Guarantee)
+		}
+	}
+	if !slices.Equal(traced, made) {
+		t.Errorf("traced guarantees %v, expected %v\n", traced, made)
+	}
+}
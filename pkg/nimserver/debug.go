@@ -0,0 +1,80 @@
+package nimserver
+
+import (
+	"net/http"
+	"net/http/pprof"
+	"slices"
+	"strings"
+	"time"
+
+	"nimgame/pkg/nim"
+)
+
+// debugGame is a session as the /debug/games endpoints show it.
+type debugGame struct {
+	Raddr     string    `json:"raddr"` // the client's UDP address, or the session's key over other transports
+	GameID    string    `json:"game_id"`
+	Board     []int     `json:"board"`
+	Rendered  string    `json:"rendered"` // nim.RenderBoard's drawing of Board
+	MoveCount int       `json:"move_count"`
+	Strategy  string    `json:"strategy"`
+	Playing   bool      `json:"playing"`
+	LastSeen  time.Time `json:"last_seen"`
+}
+
+// handleDebug routes the endpoints for looking into the running server:
+// pprof's, and /debug/games, listing every session, finished or not, and
+// /debug/games/{id}, a single game.
+func (s *Server) handleDebug(mux *http.ServeMux) {
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.HandleFunc("GET /debug/games", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, s.debugGames())
+	})
+	mux.HandleFunc("GET /debug/games/{id}", func(w http.ResponseWriter, r *http.Request) {
+		s.gameMu.Lock()
+		defer s.gameMu.Unlock()
+		key := s.gameKey("", r.PathValue("id"))
+		if key == "" {
+			writeError(w, http.StatusNotFound, errNoGame)
+			return
+		}
+		writeJSON(w, http.StatusOK, newDebugGame(key, s.session(key)))
+	})
+}
+
+// debugGames is every session, by key.
+func (s *Server) debugGames() []debugGame {
+	s.gameMu.Lock()
+	defer s.gameMu.Unlock()
+	s.sessionsMu.Lock()
+	defer s.sessionsMu.Unlock()
+	games := []debugGame{} // an empty list, not null, without sessions
+	for raddr, sess := range s.sessions {
+		games = append(games, newDebugGame(raddr, sess))
+	}
+	slices.SortFunc(games, func(a, b debugGame) int { return strings.Compare(a.Raddr, b.Raddr) })
+	return games
+}
+
+// newDebugGame describes sess, kept at raddr. The caller holds gameMu.
+func newDebugGame(raddr string, sess *GameSession) debugGame {
+	board := sess.LastMove.GameState
+	game := debugGame{
+		Raddr:     raddr,
+		GameID:    sess.GameID,
+		Board:     make([]int, len(board)),
+		Rendered:  nim.RenderBoard(board, nil),
+		MoveCount: sess.MoveCount,
+		Strategy:  sess.Strategy,
+		Playing:   sess.Playing,
+		LastSeen:  sess.LastSeen,
+	}
+	for i, coins := range board {
+		game.Board[i] = int(coins)
+	}
+	return game
+}
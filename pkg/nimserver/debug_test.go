@@ -0,0 +1,57 @@
+package nimserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// getDebug decodes the JSON server's admin listener serves at path into v,
+// returning the status.
+func getDebug(t *testing.T, server *Server, path string, v interface{}) int {
+	resp, err := http.Get("http://" + server.AdminAddr().String() + path)
+	if err != nil {
+		t.Fatalf("getting %v: %v\n", path, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusOK {
+		if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+			t.Fatalf("decoding %v: %v\n", path, err)
+		}
+	}
+	return resp.StatusCode
+}
+
+func TestDebugGames(t *testing.T) {
+	server, raddr := serveOnLoopback(t, &ServerConfig{AdminAddress: "127.0.0.1:0"}, nil)
+	first, second := newTestClient(t, raddr, nil), newTestClient(t, raddr, nil)
+	replies := map[string]StateMoveMessage{}
+	for _, c := range []*testClient{first, second} {
+		reply := c.exchange(StateMoveMessage{GameState: nil, MoveRow: -1, MoveCount: 4})
+		replies[c.conn.LocalAddr().String()] = reply
+	}
+
+	var games []debugGame
+	if status := getDebug(t, server, "/debug/games", &games); status != http.StatusOK || len(games) != 2 {
+		t.Fatalf("/debug/games answered %v with %+v, expected both games\n", status, games)
+	}
+	for _, game := range games {
+		reply, ok := replies[game.Raddr]
+		if !ok || game.GameID != reply.GameID || len(game.Board) != len(reply.GameState) || !game.Playing {
+			t.Errorf("/debug/games listed %+v, expected one of %v\n", game, replies)
+		}
+		if strings.Count(game.Rendered, "\n") != len(reply.GameState) {
+			t.Errorf("game %v rendered as %q, expected a line a heap\n", game.GameID, game.Rendered)
+		}
+	}
+
+	var game debugGame
+	id := replies[first.conn.LocalAddr().String()].GameID
+	if status := getDebug(t, server, "/debug/games/"+id, &game); status != http.StatusOK || game.GameID != id {
+		t.Errorf("/debug/games/%v answered %v with %+v\n", id, status, game)
+	}
+	if status := getDebug(t, server, "/debug/games/nope", &game); status != http.StatusNotFound {
+		t.Errorf("unknown game answered %v, expected 404\n", status)
+	}
+}
@@ -0,0 +1,59 @@
+package nimserver
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"time"
+)
+
+const (
+	dedupMaxSize = 1000
+	dedupTTL     = 10 * time.Second
+)
+
+// dedupCache remembers the hashes of packets recently seen, from any
+// address, so exact duplicates can be dropped. It keeps at most maxSize
+// hashes, forgetting the least recently seen first, and each for ttl.
+type dedupCache struct {
+	maxSize int
+	ttl     time.Duration
+	order   *list.List // of *dedupEntry, most recently seen first
+	entries map[[sha256.Size]byte]*list.Element
+}
+
+type dedupEntry struct {
+	key       [sha256.Size]byte
+	firstSeen time.Time
+}
+
+func newDedupCache(maxSize int, ttl time.Duration) *dedupCache {
+	return &dedupCache{
+		maxSize: maxSize,
+		ttl:     ttl,
+		order:   list.New(),
+		entries: make(map[[sha256.Size]byte]*list.Element),
+	}
+}
+
+// seen records packet as seen at now and reports whether it had already
+// been seen, from any address, within the TTL.
+func (c *dedupCache) seen(packet []byte, now time.Time) bool {
+	key := sha256.Sum256(packet)
+	if elem, ok := c.entries[key]; ok {
+		c.order.MoveToFront(elem)
+		entry := elem.Value.(*dedupEntry)
+		if now.Sub(entry.firstSeen) < c.ttl {
+			return true
+		}
+		entry.firstSeen = now
+		return false
+	}
+
+	c.entries[key] = c.order.PushFront(&dedupEntry{key: key, firstSeen: now})
+	for c.order.Len() > c.maxSize {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*dedupEntry).key)
+	}
+	return false
+}
@@ -0,0 +1,29 @@
+package nimserver
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestDedupCache(t *testing.T) {
+	c := newDedupCache(3, 10*time.Second)
+	start := time.Unix(0, 0)
+	if c.seen([]byte("a"), start) {
+		t.Errorf("first sighting reported as a duplicate\n")
+	}
+	if !c.seen([]byte("a"), start.Add(9*time.Second)) {
+		t.Errorf("repeat within the TTL not reported as a duplicate\n")
+	}
+	if c.seen([]byte("a"), start.Add(10*time.Second)) {
+		t.Errorf("repeat after the TTL reported as a duplicate\n")
+	}
+
+	// "a" is the least recently seen once three more packets arrive
+	for i := 0; i < 3; i++ {
+		c.seen([]byte(strconv.Itoa(i)), start.Add(11*time.Second))
+	}
+	if c.order.Len() != 3 || c.seen([]byte("a"), start.Add(12*time.Second)) {
+		t.Errorf("least recently seen entry not evicted at capacity\n")
+	}
+}
@@ -0,0 +1,66 @@
+package nimserver
+
+import (
+	"errors"
+	"time"
+)
+
+// drainingMoveRow marks a draining server's refusal of a GameStart: the
+// games in progress play on, but no more are started.
+const drainingMoveRow = -4
+
+// drainPollInterval is how often a draining server checks for the end of
+// the games in progress.
+const drainPollInterval = 100 * time.Millisecond
+
+var errDraining = errors.New("server is draining: no new games")
+
+// refuseGame is the reply to a GameStart from raddr while draining, which
+// starts no session.
+func (s *Server) refuseGame(raddr string) *moveResult {
+	s.logger().Info("refusing game while draining", "client", raddr)
+	return &moveResult{
+		reply: StateMoveMessage{
+			MoveRow:           drainingMoveRow,
+			MoveCount:         drainingMoveRow,
+			TracingServerAddr: s.config.TracingServerAddress,
+		},
+		refused: true,
+	}
+}
+
+// drain refuses new games, once Serve's context is done, until the games
+// in progress have ended, DrainTimeout seconds have passed or done is
+// closed. Without a DrainTimeout it returns at once.
+func (s *Server) drain(done <-chan struct{}) {
+	if s.config.DrainTimeout <= 0 {
+		return
+	}
+	s.draining.Store(true)
+	drainingGauge.Set(1)
+	defer drainingGauge.Set(0)
+	s.updateHealth()
+
+	timeout := time.NewTimer(time.Duration(s.config.DrainTimeout) * time.Second)
+	defer timeout.Stop()
+	tick := time.NewTicker(drainPollInterval)
+	defer tick.Stop()
+	for games := s.inProgress(); games > 0; games = s.inProgress() {
+		s.logger().Debug("draining", "games", games)
+		select {
+		case <-tick.C:
+		case <-timeout.C:
+			s.logger().Warn("drain timed out", "games", games, "timeout", s.config.DrainTimeout)
+			return
+		case <-done:
+			return
+		}
+	}
+}
+
+// inProgress returns the number of games being played, taking gameMu.
+func (s *Server) inProgress() int {
+	s.gameMu.Lock()
+	defer s.gameMu.Unlock()
+	return s.playing()
+}
@@ -0,0 +1,124 @@
+package nimserver
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// TestDrain stops the server during a game, by cancelling Run or calling
+// Shutdown: the server refuses a new game while it drains, but plays the
+// one in progress to its end, and only then stops.
+func TestDrain(t *testing.T) {
+	t.Run("cancel", func(t *testing.T) {
+		testDrain(t, func(cancel context.CancelFunc, server *Server) <-chan error {
+			cancel()
+			return nil
+		})
+	})
+	t.Run("Shutdown", func(t *testing.T) {
+		testDrain(t, func(cancel context.CancelFunc, server *Server) <-chan error {
+			stopped := make(chan error, 1)
+			go func() { stopped <- server.Shutdown(context.Background()) }()
+			return stopped
+		})
+	})
+}
+
+// testDrain plays TestDrain's game, stopping the server with stop, which
+// returns a channel Shutdown's error is sent on, if it called it.
+func testDrain(t *testing.T, stop func(context.CancelFunc, *Server) <-chan error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	server, err := New(WithConfig(&ServerConfig{DrainTimeout: 10}), WithListenAddress("127.0.0.1:0"))
+	if err != nil {
+		t.Fatalf("starting server: %v\n", err)
+	}
+	done := make(chan error, 1)
+	go func() { done <- server.Run(ctx) }()
+
+	// play seed 3's board, which the first player wins, up to the
+	// client's winning move
+	c := newTestClient(t, server.Addr().(*net.UDPAddr), nil)
+	reply := c.exchange(StateMoveMessage{GameState: nil, MoveRow: -1, MoveCount: 3})
+	last := bestMove(append([]uint8(nil), reply.GameState...))
+	for !emptyBoard(last.GameState) {
+		if reply = c.exchange(last); reply.MoveRow == -2 || emptyBoard(reply.GameState) {
+			t.Fatalf("game ended early with %+v\n", reply)
+		}
+		last = bestMove(append([]uint8(nil), reply.GameState...))
+	}
+
+	shutdown := stop(cancel, server)
+	for deadline := time.Now().Add(time.Second); !server.draining.Load(); time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("server not draining a second after being stopped\n")
+		}
+	}
+	if g := testutil.ToFloat64(drainingGauge); g != 1 {
+		t.Errorf("nim_draining is %v while draining, expected 1\n", g)
+	}
+	other := newTestClient(t, server.Addr().(*net.UDPAddr), nil)
+	if refusal := other.exchange(StateMoveMessage{GameState: nil, MoveRow: -1, MoveCount: 5}); refusal.MoveRow != drainingMoveRow || refusal.GameState != nil {
+		t.Errorf("GameStart while draining answered with %+v, expected a refusal\n", refusal)
+	}
+	select {
+	case err := <-done:
+		t.Fatalf("Run returned %v with a game in progress\n", err)
+	default:
+	}
+
+	if reply := c.exchange(last); reply.MoveRow != -2 {
+		t.Errorf("winning move answered with %+v, expected a concession\n", reply)
+	}
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("expected Run to stop cleanly once drained, got %v\n", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("Run still running two seconds after the last game ended\n")
+	}
+	if shutdown != nil {
+		if err := <-shutdown; err != nil {
+			t.Errorf("Shutdown returned %v, expected nil once drained\n", err)
+		}
+	}
+	if g := testutil.ToFloat64(drainingGauge); g != 0 {
+		t.Errorf("nim_draining is %v after stopping, expected 0\n", g)
+	}
+	if stats := server.Stats(); stats.GamesStarted != 1 || stats.ClientWins != 1 {
+		t.Errorf("expected the one game started, won by the client, got %+v\n", stats)
+	}
+}
+
+// TestShutdownTimeout calls Shutdown during a game with a context that
+// runs out before the drain does, which closes the server at once.
+func TestShutdownTimeout(t *testing.T) {
+	server, err := New(WithConfig(&ServerConfig{DrainTimeout: 10}), WithListenAddress("127.0.0.1:0"))
+	if err != nil {
+		t.Fatalf("starting server: %v\n", err)
+	}
+	done := make(chan error, 1)
+	go func() { done <- server.Run(context.Background()) }()
+	c := newTestClient(t, server.Addr().(*net.UDPAddr), nil)
+	c.exchange(StateMoveMessage{GameState: nil, MoveRow: -1, MoveCount: 3})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	if err := server.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Shutdown returned %v with a game in progress, expected the deadline exceeded\n", err)
+	}
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("expected Run to stop cleanly, got %v\n", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("Run still running two seconds after Shutdown gave up waiting\n")
+	}
+}
@@ -0,0 +1,71 @@
+package nimserver
+
+import "nimgame/pkg/nim"
+
+// dryRunSeed is the seed chooseDifficulty picks a dry run's moves'
+// difficulty from, there being no game to take one from: odd, so the hard
+// server without an A/B test or a WithStrategy.
+const dryRunSeed int8 = 1
+
+// dryRun answers clientMove from raddr under DryRun: a GameStart gets its
+// board, and a move is answered by the server's move at the difficulty
+// chooseDifficulty picks for dryRunSeed, but no session is kept, nor
+// anything counted, saved or notified. There being no game, a move isn't
+// checked against one: it is only checked against the board movedFrom
+// rebuilds from the move itself, so only rows out of range are rejected.
+// Messages that need a game, such as Sync, are ignored, and Lasker's nim's
+// splits, which don't say what they split, are refused. The caller holds
+// gameMu.
+func (s *Server) dryRun(raddr string, clientMove StateMoveMessage) *moveResult {
+	var reply StateMoveMessage
+	var rejected bool
+	var invalid int8
+	switch {
+	case clientMove.GameState == nil && clientMove.MoveRow == -1:
+		seed := clientMove.MoveCount
+		variant := s.variant(clientMove.Variant)
+		reply = StateMoveMessage{
+			GameState: s.newBoard(seed, variant, s.boardProfile(clientMove.BoardProfile), s.boardGuarantee(clientMove.BoardGuarantee)),
+			MoveRow:   -1,
+			MoveCount: seed,
+			Variant:   variant,
+		}
+	case clientMove.GameState == nil:
+		return nil
+	default:
+		last := movedFrom(clientMove)
+		ok, err := CheckMove(clientMove, last, s.config)
+		if !ok {
+			s.logger().Warn("dry run: rejected move", "client", raddr, "err", err)
+			// the board the move should have followed from, to move on
+			reply = StateMoveMessage{GameState: last.GameState, MoveRow: -1, Variant: last.Variant}
+			rejected, invalid = true, InvalidMoveIllegal
+			break
+		}
+		clientMove.Variant = last.Variant
+		// Play moves on the board in place
+		clientMove.GameState = append([]uint8(nil), clientMove.GameState...)
+		reply = s.play(clientMove, s.chooseDifficulty(dryRunSeed))
+		reply.Variant = last.Variant
+	}
+	reply.TracingServerAddr = s.config.TracingServerAddress
+	reply.DryRun = true
+	return &moveResult{reply: reply, rejected: rejected, invalid: invalid}
+}
+
+// movedFrom returns the board move says it was made on, the board it
+// leaves with the coins it took put back. Taking from both heaps of a two
+// heap board is Wythoff's game's move.
+func movedFrom(move StateMoveMessage) StateMoveMessage {
+	before := append([]uint8(nil), move.GameState...)
+	last := StateMoveMessage{GameState: before, Variant: move.Variant}
+	switch row := int(move.MoveRow); {
+	case len(before) == 2 && row == nim.WythoffBothRows:
+		before[0] += uint8(move.MoveCount)
+		before[1] += uint8(move.MoveCount)
+		last.Variant = nim.WythoffVariant
+	case row >= 0 && row < len(before):
+		before[row] += uint8(move.MoveCount)
+	}
+	return last
+}
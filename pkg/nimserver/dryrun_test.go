@@ -0,0 +1,63 @@
+package nimserver
+
+import (
+	"bytes"
+	"testing"
+
+	"nimgame/pkg/nim"
+)
+
+// TestDryRun plays a game with a server in DryRun, which answers every move
+// with a marked reply but never keeps a session.
+func TestDryRun(t *testing.T) {
+	server, raddr := serveOnLoopback(t, &ServerConfig{DryRun: true}, nil)
+	c := newTestClient(t, raddr, nil)
+	noSessions := func(after string) {
+		t.Helper()
+		server.sessionsMu.Lock()
+		defer server.sessionsMu.Unlock()
+		if len(server.sessions) != 0 {
+			t.Fatalf("%d sessions kept after %v\n", len(server.sessions), after)
+		}
+	}
+
+	reply := c.exchange(StateMoveMessage{GameState: nil, MoveRow: -1, MoveCount: 3})
+	if !reply.DryRun || reply.MoveRow != -1 || !bytes.Equal(reply.GameState, nim.GenerateBoard(3)) {
+		t.Fatalf("GameStart answered %+v, expected seed 3's board marked DryRun\n", reply)
+	}
+	noSessions("GameStart")
+	for moves := 0; reply.MoveRow != -2; moves++ {
+		if moves > 50 {
+			t.Fatalf("game still going after %d moves\n", moves)
+		}
+		move := bestMove(append([]uint8(nil), reply.GameState...))
+		reply = c.exchange(move)
+		if !reply.DryRun || reply.MoveRow != -2 && !nim.ValidMove(move.GameState, reply.GameState, int(reply.MoveRow), int(reply.MoveCount)) {
+			t.Fatalf("%+v answered with %+v\n", move, reply)
+		}
+		noSessions("a move")
+	}
+
+	// answered with the board the move should have followed from
+	illegal := c.exchange(StateMoveMessage{GameState: []uint8{2, 2}, MoveRow: 5, MoveCount: 1})
+	if !illegal.DryRun || illegal.MoveRow != -1 || !bytes.Equal(illegal.GameState, []uint8{2, 2}) {
+		t.Errorf("illegal move answered with %+v\n", illegal)
+	}
+	noSessions("an illegal move")
+	if stats := server.Stats(); stats != (Stats{}) {
+		t.Errorf("a dry run counted %+v\n", stats)
+	}
+}
+
+// TestDryRunStrategy checks a dry run's moves are played as the server's
+// strategy would play them, rather than always as the hard server.
+func TestDryRunStrategy(t *testing.T) {
+	_, raddr := serveOnLoopback(t, &ServerConfig{DryRun: true}, nil, WithStrategy(func(int8) int8 { return 0 }))
+	c := newTestClient(t, raddr, nil)
+
+	move := StateMoveMessage{GameState: []uint8{1, 2}, MoveRow: 1, MoveCount: 1}
+	want, _ := normalMove([]uint8{1, 2})
+	if reply := c.exchange(move); !reply.DryRun || !bytes.Equal(reply.GameState, want.GameState) {
+		t.Errorf("%+v answered with %+v, expected the easy server's %v\n", move, reply, want.GameState)
+	}
+}
@@ -0,0 +1,27 @@
+package nimserver
+
+import (
+	"net"
+
+	"nimgame/pkg/dtlsgame"
+)
+
+// serveDTLS plays the games of clients connecting to lis until lis is
+// closed, each client's moves coming, a packet to a record, over its own
+// DTLS connection. A client whose handshake fails is told nothing more.
+func (s *Server) serveDTLS(lis net.Listener) {
+	for {
+		conn, err := lis.Accept()
+		if err != nil {
+			return // closed
+		}
+		go func() {
+			if err := dtlsgame.Handshake(conn); err != nil {
+				s.logger().Warn("dropping DTLS client", "client", conn.RemoteAddr(), "err", err)
+				conn.Close()
+				return
+			}
+			s.serveConn(conn, dtlsKeyPrefix)
+		}()
+	}
+}
@@ -0,0 +1,73 @@
+package nimserver
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"nimgame/pkg/dtlsgame"
+)
+
+// serveDTLSOnLoopback serves games over DTLS, with a self-signed
+// certificate, as well as UDP, until the test ends, returning the server
+// and the certificate's path.
+func serveDTLSOnLoopback(t *testing.T) (*Server, string) {
+	certPEM, keyPEM, err := dtlsgame.SelfSigned("127.0.0.1")
+	if err != nil {
+		t.Fatalf("making certificate: %v\n", err)
+	}
+	dir := t.TempDir()
+	config := &ServerConfig{
+		DTLSAddress:  "127.0.0.1:0",
+		DTLSCertFile: filepath.Join(dir, "cert.pem"),
+		DTLSKeyFile:  filepath.Join(dir, "key.pem"),
+	}
+	os.WriteFile(config.DTLSCertFile, certPEM, 0644)
+	os.WriteFile(config.DTLSKeyFile, keyPEM, 0600)
+	server, _ := serveOnLoopback(t, config, nil)
+	return server, config.DTLSCertFile
+}
+
+func TestDTLSGame(t *testing.T) {
+	server, certFile := serveDTLSOnLoopback(t)
+	opts, err := dtlsgame.ClientOptions(certFile, nil)
+	if err != nil {
+		t.Fatalf("client options: %v\n", err)
+	}
+	conn, err := dtlsgame.Dial(server.DTLSAddr().String(), &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)}, opts)
+	if err != nil {
+		t.Fatalf("dialing over DTLS: %v\n", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	client := &testClient{t: t, conn: conn, buf: make([]byte, 1024)}
+
+	reply := client.exchange(StateMoveMessage{GameState: nil, MoveRow: -1, MoveCount: 4})
+	games := server.ActiveGames()
+	if len(games) != 1 || games[0].Transport != "dtls" || games[0].Client != conn.LocalAddr().String() || games[0].GameID != reply.GameID {
+		t.Errorf("active games are %+v, expected game %v over DTLS from %v\n", games, reply.GameID, conn.LocalAddr())
+	}
+	if winner, _ := client.playGame(4); winner != "client" {
+		t.Errorf("game over DTLS won by %v, expected the client\n", winner)
+	}
+}
+
+func TestDTLSDropsPlainPackets(t *testing.T) {
+	server, _ := serveDTLSOnLoopback(t)
+	udp, err := net.Dial("udp", server.DTLSAddr().String())
+	if err != nil {
+		t.Fatalf("dialing over UDP: %v\n", err)
+	}
+	defer udp.Close()
+	packet, _ := Marshal(StateMoveMessage{GameState: nil, MoveRow: -1, MoveCount: 4})
+	udp.Write(packet)
+
+	udp.SetReadDeadline(time.Now().Add(200 * time.Millisecond))
+	if n, err := udp.Read(make([]byte, 1024)); err == nil {
+		t.Errorf("plain UDP move on the DTLS port answered with %d bytes\n", n)
+	}
+	if stats := server.Stats(); stats.DTLSDropped != 1 || stats.GamesStarted != 0 {
+		t.Errorf("stats are %+v, expected the packet dropped and no game\n", stats)
+	}
+}
@@ -0,0 +1,116 @@
+package nimserver
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// forfeitMoveRow marks the message telling a client it forfeited the game
+// by not moving within MoveTTL.
+const forfeitMoveRow = -12
+
+// startMoveTimer gives the client whose game is kept under raddr MoveTTL to
+// answer the move just sent for sess, after which it forfeits, told so by
+// send.
+func (s *Server) startMoveTimer(raddr string, sess *GameSession, send func([]byte)) {
+	if s.config.MoveTTL <= 0 {
+		return
+	}
+	sess.stopMoveTimer()
+	gameID, moves := sess.GameID, sess.MoveCount
+	sess.moveTimer = time.AfterFunc(time.Duration(s.config.MoveTTL)*time.Second, func() {
+		s.forfeit(raddr, gameID, moves, "", send)
+	})
+}
+
+// startFlagTimer forfeits sess's game, kept under raddr, telling the client
+// so with send, if the client's clock runs out before it answers the reply
+// it is to answer. Games without time control, or over, have no timer.
+func (s *Server) startFlagTimer(raddr string, sess *GameSession, send func([]byte)) {
+	if sess.Clock == nil || !sess.Playing {
+		return
+	}
+	if sess.flagTimer != nil {
+		sess.flagTimer.Stop()
+	}
+	gameID, moves := sess.GameID, sess.MoveCount
+	sess.flagTimer = time.AfterFunc(sess.Clock.left(s.now()), func() {
+		s.forfeit(raddr, gameID, moves, reasonTimeout, send)
+	})
+}
+
+// stopMoveTimer stops the session's MoveTTL and clock timers.
+func (sess *GameSession) stopMoveTimer() {
+	if sess.moveTimer != nil {
+		sess.moveTimer.Stop()
+		sess.moveTimer = nil
+	}
+	if sess.flagTimer != nil {
+		sess.flagTimer.Stop()
+		sess.flagTimer = nil
+	}
+}
+
+// stopMoveTimers stops every session's timer, once no more moves will be
+// handled.
+func (s *Server) stopMoveTimers() {
+	s.gameMu.Lock()
+	defer s.gameMu.Unlock()
+	s.sessionsMu.Lock()
+	defer s.sessionsMu.Unlock()
+	for _, sess := range s.sessions {
+		sess.stopMoveTimer()
+	}
+}
+
+// forfeit ends the game gameID at raddr, won by the server, forgets the
+// session and sends the client a notice with send. reason is reasonTimeout
+// when the client's clock ran out, and empty when MoveTTL did. A timer can
+// fire just as the client's move is handled, so nothing is done unless the
+// game is still waiting at moves moves.
+func (s *Server) forfeit(raddr string, gameID string, moves int, reason string, send func([]byte)) {
+	s.gameMu.Lock()
+	defer s.gameMu.Unlock()
+	sess := s.session(raddr)
+	if sess == nil || !sess.Playing || sess.GameID != gameID || sess.MoveCount != moves {
+		return
+	}
+	if reason == reasonTimeout {
+		s.logger().Info("client ran out of time", "client", raddr, "game", gameID, "budget", sess.Clock.Budget)
+		sess.Clock.Client = 0
+	} else {
+		s.logger().Info("client forfeited", "client", raddr, "game", gameID, "ttl", s.config.MoveTTL)
+	}
+	sess.stopMoveTimer()
+	s.resign(raddr, sess, reason)
+
+	notice := StateMoveMessage{
+		MoveRow:           forfeitMoveRow,
+		MoveCount:         forfeitMoveRow,
+		TracingServerAddr: s.config.TracingServerAddress,
+	}
+	if sess.Clock != nil {
+		sess.Clock.stamp(&notice, s.now())
+	}
+	bufOut, err := MarshalMove(notice, s.config.CompressionMode)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error marshalling forfeit to %v: %v\n", raddr, err)
+		return
+	}
+	send(bufOut)
+}
+
+// resign ends sess's game, kept under raddr, won by the server for reason,
+// as GameComplete has it, and forgets the session. The caller holds gameMu.
+func (s *Server) resign(raddr string, sess *GameSession, reason string) {
+	sess.moveTimer = nil
+	if s.trace != nil {
+		s.trace.RecordAction(GameComplete{Winner: "server", Reason: reason})
+	}
+	s.endGame(raddr, sess, "server")
+	s.sessionsMu.Lock()
+	delete(s.sessions, raddr)
+	s.sessionsMu.Unlock()
+	s.persist(raddr)
+}
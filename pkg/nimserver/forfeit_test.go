@@ -0,0 +1,62 @@
+package nimserver
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestMoveTTLForfeit starts a game and never answers the server's move.
+func TestMoveTTLForfeit(t *testing.T) {
+	recorder := &recordingPlugin{}
+	server, raddr := serveOnLoopback(t, &ServerConfig{MoveTTL: 1}, nil, WithPlugins(recorder))
+	client := newTestClient(t, raddr, nil)
+	client.exchange(StateMoveMessage{GameState: nil, MoveRow: -1, MoveCount: 4})
+	local := client.conn.LocalAddr().String()
+	if server.session(local) == nil {
+		t.Fatalf("no session for %v after GameStart\n", local)
+	}
+
+	time.Sleep(2 * time.Second)
+	if server.session(local) != nil {
+		t.Errorf("session still kept after MoveTTL\n")
+	}
+	recorder.mu.Lock()
+	events := strings.Join(recorder.events, "\n")
+	recorder.mu.Unlock()
+	if !strings.HasSuffix(events, "end server\ndisconnect") {
+		t.Errorf("expected the game to end won by the server, got hook calls:\n%v\n", events)
+	}
+	if server.playing() != 0 {
+		t.Errorf("forfeited game still counted as playing\n")
+	}
+
+	client.conn.SetReadDeadline(time.Now().Add(time.Second))
+	n, err := client.conn.Read(client.buf)
+	if err != nil {
+		t.Fatalf("no forfeit notice: %v\n", err)
+	}
+	var notice StateMoveMessage
+	if err := UnmarshalMove(client.buf[:n], &notice, defaultMaxBoardRows); err != nil || notice.MoveRow != forfeitMoveRow {
+		t.Errorf("expected a forfeit notice, got %+v (%v)\n", notice, err)
+	}
+}
+
+// TestMoveTTLAnswered checks a move made in time keeps the game going.
+func TestMoveTTLAnswered(t *testing.T) {
+	dataset := map[int8][]uint8{4: {5, 5, 5}} // too many coins to finish in 3 moves each
+	server, raddr := serveOnLoopback(t, &ServerConfig{MoveTTL: 1}, nil, WithDataset(dataset))
+	client := newTestClient(t, raddr, nil)
+	reply := client.exchange(StateMoveMessage{GameState: nil, MoveRow: -1, MoveCount: 4})
+	for i := 0; i < 3; i++ {
+		time.Sleep(600 * time.Millisecond)
+		move, err := normalMove(append([]uint8(nil), reply.GameState...))
+		if err != nil {
+			t.Fatalf("no move on %v\n", reply.GameState)
+		}
+		reply = client.exchange(*move)
+	}
+	if server.session(client.conn.LocalAddr().String()) == nil || reply.MoveRow < 0 {
+		t.Errorf("game ended while the client kept moving within MoveTTL\n")
+	}
+}
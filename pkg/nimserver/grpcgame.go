@@ -0,0 +1,87 @@
+package nimserver
+
+import (
+	"context"
+	"math"
+
+	"nimgame/pkg/nimpb"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// nimGameServer serves the NimGame service, playing games in the same
+// sessions, with the same strategies, as the games over UDP.
+type nimGameServer struct {
+	nimpb.UnimplementedNimGameServer
+	s *Server
+}
+
+func (g nimGameServer) StartGame(ctx context.Context, req *nimpb.StartGameRequest) (*nimpb.GameState, error) {
+	if req.Seed < math.MinInt8 || req.Seed > math.MaxInt8 {
+		return nil, status.Errorf(codes.InvalidArgument, "seed %v out of range", req.Seed)
+	}
+	s := g.s
+	s.gameMu.Lock()
+	defer s.gameMu.Unlock()
+	res := s.respondTraced(grpcKeyPrefix+newGameID(), StateMoveMessage{
+		MoveRow:   -1,
+		MoveCount: int8(req.Seed),
+	})
+	if res.refused {
+		return nil, status.Error(codes.Unavailable, errDraining.Error())
+	}
+	return gameState(res.sess), nil
+}
+
+func (g nimGameServer) MakeMove(ctx context.Context, req *nimpb.MoveRequest) (*nimpb.GameState, error) {
+	if req.MoveRow < math.MinInt8 || req.MoveRow > math.MaxInt8 ||
+		req.MoveCount < math.MinInt8 || req.MoveCount > math.MaxInt8 {
+		return nil, status.Errorf(codes.InvalidArgument, "move %v/%v out of range", req.MoveRow, req.MoveCount)
+	}
+	s := g.s
+	s.gameMu.Lock()
+	defer s.gameMu.Unlock()
+	key := s.gameKey(grpcKeyPrefix, req.GameId)
+	if key == "" {
+		return nil, status.Errorf(codes.NotFound, "no game %q", req.GameId)
+	}
+	// a nil board would ask for a new game or a resend
+	board := append([]uint8{}, req.GameState...)
+	res := s.respondTraced(key, StateMoveMessage{
+		GameState: board,
+		MoveRow:   int8(req.MoveRow),
+		MoveCount: int8(req.MoveCount),
+		GameID:    req.GameId,
+	})
+	if res.rejected {
+		return nil, status.Errorf(codes.InvalidArgument, "illegal move %v/%v", req.MoveRow, req.MoveCount)
+	}
+	return gameState(res.sess), nil
+}
+
+func (g nimGameServer) GetState(ctx context.Context, req *nimpb.GetStateRequest) (*nimpb.GameState, error) {
+	s := g.s
+	s.gameMu.Lock()
+	defer s.gameMu.Unlock()
+	key := s.gameKey(grpcKeyPrefix, req.GameId)
+	if key == "" {
+		return nil, status.Errorf(codes.NotFound, "no game %q", req.GameId)
+	}
+	return gameState(s.session(key)), nil
+}
+
+// gameState is where sess's game stands, as the server's last reply left it.
+func gameState(sess *GameSession) *nimpb.GameState {
+	last := sess.LastMove
+	state := &nimpb.GameState{
+		GameId:    sess.GameID,
+		GameState: append([]byte(nil), last.GameState...),
+		MoveRow:   int32(last.MoveRow),
+		MoveCount: int32(last.MoveCount),
+	}
+	if !sess.Playing {
+		state.Winner = gameWinner(last)
+	}
+	return state
+}
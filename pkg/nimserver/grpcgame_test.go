@@ -0,0 +1,175 @@
+package nimserver
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"nimgame/pkg/nimpb"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+)
+
+// dialNimGame returns a NimGame client connected to server's gRPC address.
+func dialNimGame(t *testing.T, server *Server) nimpb.NimGameClient {
+	conn, err := grpc.NewClient(server.GRPCAddr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("dialing gRPC: %v\n", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return nimpb.NewNimGameClient(conn)
+}
+
+// grpcMove makes the move bestMove picks on state's board.
+func grpcMove(ctx context.Context, client nimpb.NimGameClient, state *nimpb.GameState) (*nimpb.GameState, error) {
+	move := bestMove(append([]uint8(nil), state.GameState...))
+	return client.MakeMove(ctx, &nimpb.MoveRequest{
+		GameId:    state.GameId,
+		GameState: move.GameState,
+		MoveRow:   int32(move.MoveRow),
+		MoveCount: int32(move.MoveCount),
+	})
+}
+
+func TestGRPCGame(t *testing.T) {
+	server, _ := serveOnLoopback(t, &ServerConfig{GRPCAddress: "127.0.0.1:0"}, nil)
+	client := dialNimGame(t, server)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	state, err := client.StartGame(ctx, &nimpb.StartGameRequest{Seed: 4})
+	if err != nil {
+		t.Fatalf("starting game: %v\n", err)
+	}
+	if state.GameId == "" || state.MoveRow != -1 || state.MoveCount != 4 {
+		t.Fatalf("game started as %v, expected a GameID and move -1/4\n", state)
+	}
+
+	// an illegal move leaves the game as it was
+	_, err = client.MakeMove(ctx, &nimpb.MoveRequest{GameId: state.GameId, GameState: state.GameState, MoveRow: 99, MoveCount: 1})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("illegal move got %v, expected InvalidArgument\n", err)
+	}
+	if _, err = client.GetState(ctx, &nimpb.GetStateRequest{GameId: "nope"}); status.Code(err) != codes.NotFound {
+		t.Errorf("unknown game got %v, expected NotFound\n", err)
+	}
+
+	for state.Winner == "" {
+		if state, err = grpcMove(ctx, client, state); err != nil {
+			t.Fatalf("making move: %v\n", err)
+		}
+	}
+	if state.Winner != "client" || state.MoveRow != -2 {
+		t.Errorf("game ended with %v, expected the server to concede\n", state)
+	}
+	got, err := client.GetState(ctx, &nimpb.GetStateRequest{GameId: state.GameId})
+	if err != nil {
+		t.Fatalf("getting state: %v\n", err)
+	}
+	if got.Winner != "client" || got.MoveRow != -2 {
+		t.Errorf("GetState returned %v, expected the final reply %v\n", got, state)
+	}
+	if stats := server.Stats(); stats.GamesStarted != 1 || stats.ClientWins != 1 || stats.InvalidMoves != 1 {
+		t.Errorf("stats are %+v, expected one game won by the client and one invalid move\n", stats)
+	}
+}
+
+// TestGRPCFinishedGameForgotten checks a finished game over gRPC is kept
+// for GetState only until finishedGameTTL has passed.
+func TestGRPCFinishedGameForgotten(t *testing.T) {
+	clock := &testClock{at: time.Unix(1000, 0)}
+	server, _ := serveOnLoopback(t, &ServerConfig{GRPCAddress: "127.0.0.1:0"}, nil, withClock(clock))
+	client := dialNimGame(t, server)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	state, err := client.StartGame(ctx, &nimpb.StartGameRequest{Seed: 4})
+	for err == nil && state.Winner == "" {
+		state, err = grpcMove(ctx, client, state)
+	}
+	if err != nil {
+		t.Fatalf("playing: %v\n", err)
+	}
+	clock.advance(finishedGameTTL)
+	if _, err := client.GetState(ctx, &nimpb.GetStateRequest{GameId: state.GameId}); err != nil {
+		t.Errorf("finished game gone before finishedGameTTL: %v\n", err)
+	}
+	clock.advance(time.Second)
+	if _, err := client.GetState(ctx, &nimpb.GetStateRequest{GameId: state.GameId}); status.Code(err) != codes.NotFound {
+		t.Errorf("finished game got %v after finishedGameTTL, expected NotFound\n", err)
+	}
+	server.sessionsMu.Lock()
+	defer server.sessionsMu.Unlock()
+	if len(server.sessions) != 0 {
+		t.Errorf("expected no sessions kept, got %v\n", server.sessions)
+	}
+}
+
+func TestGRPCAndUDPGames(t *testing.T) {
+	server, raddr := serveOnLoopback(t, &ServerConfig{GRPCAddress: "127.0.0.1:0", AdminAddress: "127.0.0.1:0"}, nil)
+	client := dialNimGame(t, server)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	udp := newTestClient(t, raddr, nil)
+	udpReply := udp.exchange(StateMoveMessage{GameState: nil, MoveRow: -1, MoveCount: 6})
+	state, err := client.StartGame(ctx, &nimpb.StartGameRequest{Seed: 6})
+	if err != nil {
+		t.Fatalf("starting game: %v\n", err)
+	}
+
+	resp, err := http.Get("http://" + server.AdminAddr().String() + "/games")
+	if err != nil {
+		t.Fatalf("listing games: %v\n", err)
+	}
+	defer resp.Body.Close()
+	var games []GameInfo
+	if err := json.NewDecoder(resp.Body).Decode(&games); err != nil {
+		t.Fatalf("decoding games: %v\n", err)
+	}
+	if len(games) != 2 {
+		t.Fatalf("admin listed %v, expected both games\n", games)
+	}
+	if games[0].Transport != "grpc" || games[0].GameID != state.GameId {
+		t.Errorf("admin listed %+v first, expected gRPC game %v\n", games[0], state.GameId)
+	}
+	if games[1].Transport != "udp" || games[1].GameID != udpReply.GameID || games[1].Client != udp.conn.LocalAddr().String() {
+		t.Errorf("admin listed %+v second, expected UDP game %v\n", games[1], udpReply.GameID)
+	}
+
+	// moves in either game leave the other where it was
+	if state, err = grpcMove(ctx, client, state); err != nil {
+		t.Fatalf("making gRPC move: %v\n", err)
+	}
+	udpReply = udp.exchange(bestMove(append([]uint8(nil), udpReply.GameState...)))
+	if udpReply.MoveRow < 0 {
+		t.Errorf("UDP move got %v, expected the server's move\n", udpReply)
+	}
+	got, err := client.GetState(ctx, &nimpb.GetStateRequest{GameId: state.GameId})
+	if err != nil {
+		t.Fatalf("getting state: %v\n", err)
+	}
+	if string(got.GameState) != string(state.GameState) || got.MoveRow != state.MoveRow {
+		t.Errorf("gRPC game is at %v after a UDP move, expected %v\n", got, state)
+	}
+
+	// a UDP client can't take over the gRPC game: the resume is ignored,
+	// and the client is still playing its own
+	packet, _ := Marshal(StateMoveMessage{MoveRow: sessionResumeMoveRow, GameID: state.GameId})
+	udp.conn.Write(packet)
+	reply := udp.exchange(StateMoveMessage{MoveRow: syncMoveRow})
+	if reply.GameID != udpReply.GameID {
+		t.Errorf("syncing after resuming the gRPC game over UDP got %v, expected the UDP game\n", reply)
+	}
+	if _, err := client.GetState(ctx, &nimpb.GetStateRequest{GameId: state.GameId}); err != nil {
+		t.Errorf("gRPC game lost after a UDP client resumed it: %v\n", err)
+	}
+	if stats := server.Stats(); stats.GamesStarted != 2 || stats.InvalidMoves != 0 {
+		t.Errorf("stats are %+v, expected two games and no invalid moves\n", stats)
+	}
+}
@@ -0,0 +1,261 @@
+package nimserver
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"nimgame/pkg/tcpframe"
+
+	"github.com/DistributedClocks/tracing"
+)
+
+// startTracingServer runs a tracing server on a loopback port for the rest of
+// the test and returns its address and the path of its JSON output.
+func startTracingServer(t *testing.T) (string, string) {
+	dir := t.TempDir()
+	output := filepath.Join(dir, "trace_output.log")
+	ts := tracing.NewTracingServer(tracing.TracingServerConfig{
+		ServerBind:       "127.0.0.1:0",
+		OutputFile:       output,
+		ShivizOutputFile: filepath.Join(dir, "shiviz_output.log"),
+	})
+	if err := ts.Open(); err != nil {
+		t.Fatalf("opening tracing server: %v\n", err)
+	}
+	go ts.Accept()
+	t.Cleanup(func() { ts.Close() })
+	return ts.Listener.Addr().String(), output
+}
+
+func newTestTracer(t *testing.T, addr, identity string) *tracing.Tracer {
+	tracer := tracing.NewTracer(tracing.TracerConfig{
+		ServerAddress:  addr,
+		TracerIdentity: identity,
+	})
+	tracer.SetShouldPrint(false)
+	t.Cleanup(func() { tracer.Close() })
+	return tracer
+}
+
+// startServer runs a nim server on a loopback port until the test ends.
+// A tracing server is started too unless config already names one.
+func startServer(t *testing.T, config *ServerConfig, opts ...Option) (*Server, *net.UDPAddr) {
+	if config.TracingServerAddress == "" {
+		config.TracingServerAddress, _ = startTracingServer(t)
+	}
+	config.TracingIdentity = "server"
+	config.Secret = []byte("secret")
+	return serveOnLoopback(t, config, newTestTracer(t, config.TracingServerAddress, "server"), opts...)
+}
+
+// testClock stands in for the server's clock, moving only when advanced.
+type testClock struct {
+	mu sync.Mutex
+	at time.Time
+}
+
+// withClock has the server tell the time by clock.
+func withClock(clock *testClock) Option {
+	return func(s *Server) { s.now = clock.now }
+}
+
+func (c *testClock) now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.at
+}
+
+func (c *testClock) advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.at = c.at.Add(d)
+}
+
+// MockUDPConn is a UDPInterface with no socket behind it. Reads return
+// InPackets in turn, all from RemoteAddr, then net.ErrClosed, which ends
+// Serve; writes are appended to OutPackets.
+type MockUDPConn struct {
+	InPackets  [][]byte
+	OutPackets [][]byte
+	RemoteAddr *net.UDPAddr
+	pos        int
+}
+
+func (c *MockUDPConn) ReadFrom() ([]byte, *net.UDPAddr, error) {
+	if c.pos == len(c.InPackets) {
+		return nil, nil, net.ErrClosed
+	}
+	c.pos++
+	return c.InPackets[c.pos-1], c.RemoteAddr, nil
+}
+
+func (c *MockUDPConn) WriteTo(packet []byte, raddr *net.UDPAddr) {
+	c.OutPackets = append(c.OutPackets, append([]byte(nil), packet...))
+}
+
+func (c *MockUDPConn) SetReadDeadline(time.Time) error { return nil }
+
+func (c *MockUDPConn) Close() {}
+
+// listenLoopback points config at a loopback port and listens on it until
+// the test ends.
+func listenLoopback(t *testing.T, config *ServerConfig) *UDPConnection {
+	config.NimServerAddress = "127.0.0.1:0"
+	udp, err := startListenUDP(config)
+	if err != nil {
+		t.Fatalf("listening: %v\n", err)
+	}
+	t.Cleanup(udp.Close)
+	return udp
+}
+
+// serveOnLoopback serves config on a loopback port with tracer, which may be
+// nil to disable tracing, until the test ends.
+func serveOnLoopback(t *testing.T, config *ServerConfig, tracer *tracing.Tracer, opts ...Option) (*Server, *net.UDPAddr) {
+	opts = append([]Option{WithConfig(config), WithListenAddress("127.0.0.1:0")}, opts...)
+	if tracer != nil {
+		opts = append(opts, WithTracer(tracer))
+	}
+	server, err := New(opts...)
+	if err != nil {
+		t.Fatalf("starting server: %v\n", err)
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- server.Run(context.Background()) }()
+	t.Cleanup(func() {
+		server.Shutdown(context.Background())
+		if err := <-done; err != nil {
+			t.Errorf("Run: %v\n", err)
+		}
+	})
+	return server, server.Addr().(*net.UDPAddr)
+}
+
+// sessionOf returns a copy of the session the server keeps under raddr, or
+// nil, taken once the server is done with the move it last answered.
+func sessionOf(server *Server, raddr string) *GameSession {
+	server.gameMu.Lock()
+	defer server.gameMu.Unlock()
+	sess := server.session(raddr)
+	if sess == nil {
+		return nil
+	}
+	c := *sess
+	return &c
+}
+
+// testClient speaks the client side of the protocol, playing with the
+// server's own bestMove so it always wins generated boards.
+type testClient struct {
+	t     *testing.T
+	conn  net.Conn // a UDP socket, a tcpframe.Conn or a DTLS connection
+	trace *tracing.Trace
+	buf   []byte
+}
+
+func newTestClient(t *testing.T, raddr *net.UDPAddr, trace *tracing.Trace) *testClient {
+	conn, err := net.DialUDP("udp", nil, raddr)
+	if err != nil {
+		t.Fatalf("dialing server: %v\n", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return &testClient{t: t, conn: conn, trace: trace, buf: make([]byte, 1024)}
+}
+
+// newTCPTestClient is a testClient playing over TCP, its moves framed by
+// tcpframe.
+func newTCPTestClient(t *testing.T, addr net.Addr) *testClient {
+	conn, err := net.Dial("tcp", addr.String())
+	if err != nil {
+		t.Fatalf("dialing server over TCP: %v\n", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return &testClient{t: t, conn: tcpframe.NewConn(conn), buf: make([]byte, 1024)}
+}
+
+// redial points the client at raddr from the address it has been playing
+// from, as after the server restarted on another port.
+func (c *testClient) redial(raddr *net.UDPAddr) {
+	laddr := c.conn.LocalAddr().(*net.UDPAddr)
+	c.conn.Close()
+	conn, err := net.DialUDP("udp", laddr, raddr)
+	if err != nil {
+		c.t.Fatalf("redialing server: %v\n", err)
+	}
+	c.t.Cleanup(func() { conn.Close() })
+	c.conn = conn
+}
+
+// exchange sends move and returns the server's reply.
+func (c *testClient) exchange(move StateMoveMessage) StateMoveMessage {
+	if c.trace != nil {
+		move.Token = c.trace.GenerateToken()
+	}
+	packet, err := Marshal(move)
+	if err != nil {
+		c.t.Fatalf("marshalling move: %v\n", err)
+	}
+	if _, err = c.conn.Write(packet); err != nil {
+		c.t.Fatalf("sending move: %v\n", err)
+	}
+	c.conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	n, err := c.conn.Read(c.buf)
+	if err != nil {
+		c.t.Fatalf("no reply to %v: %v\n", move, err)
+	}
+	var reply StateMoveMessage
+	if err = UnmarshalMove(c.buf[:n], &reply, defaultMaxBoardRows); err != nil {
+		c.t.Fatalf("unmarshalling reply: %v\n", err)
+	}
+	if c.trace != nil && reply.Token != nil {
+		c.trace = c.trace.Tracer.ReceiveToken(reply.Token)
+	}
+	return reply
+}
+
+// playGame plays seed to completion and returns the winner along with every
+// reply the server sent.
+func (c *testClient) playGame(seed int8) (string, []StateMoveMessage) {
+	reply := c.exchange(StateMoveMessage{GameState: nil, MoveRow: -1, MoveCount: seed})
+	replies := []StateMoveMessage{reply}
+	for {
+		board := make([]uint8, len(reply.GameState))
+		copy(board, reply.GameState)
+		move := bestMove(board)
+		reply = c.exchange(move)
+		replies = append(replies, reply)
+		if reply.MoveRow == -2 {
+			return "client", replies
+		}
+		if emptyBoard(reply.GameState) {
+			return "server", replies
+		}
+	}
+}
+
+// readTraceRecords loads every record the tracing server has written so far.
+func readTraceRecords(t *testing.T, output string) []tracing.TraceRecord {
+	f, err := os.Open(output)
+	if err != nil {
+		t.Fatalf("opening trace output: %v\n", err)
+	}
+	defer f.Close()
+
+	var records []tracing.TraceRecord
+	dec := json.NewDecoder(f)
+	for dec.More() {
+		var r tracing.TraceRecord
+		if err := dec.Decode(&r); err != nil {
+			t.Fatalf("decoding trace output: %v\n", err)
+		}
+		records = append(records, r)
+	}
+	return records
+}
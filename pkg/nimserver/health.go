@@ -0,0 +1,48 @@
+package nimserver
+
+import (
+	"fmt"
+	"net"
+	"os"
+
+	"nimgame/pkg/nimpb"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// nimServiceName is the gRPC service whose health the server reports.
+const nimServiceName = "nimgame.NimService"
+
+// updateHealth reports the nim service as serving while there is room for
+// another game, that is while fewer than MaxClients games are in progress.
+func (s *Server) updateHealth() {
+	status := healthpb.HealthCheckResponse_SERVING
+	if s.draining.Load() || s.config.MaxClients > 0 && s.playing() >= s.config.MaxClients {
+		status = healthpb.HealthCheckResponse_NOT_SERVING
+	}
+	s.health.SetServingStatus(nimServiceName, status)
+}
+
+// serveGRPC serves the server's gRPC services on lis until the returned
+// server is stopped.
+func serveGRPC(lis net.Listener, s *Server) *grpc.Server {
+	srv := grpc.NewServer()
+	healthpb.RegisterHealthServer(srv, s.health)
+	nimpb.RegisterNimGameServer(srv, nimGameServer{s: s})
+	go func() {
+		if err := srv.Serve(lis); err != nil {
+			fmt.Fprintf(os.Stderr, "Error serving gRPC: %v\n", err)
+		}
+	}()
+	return srv
+}
+
+// newHealthServer returns the health service reported over gRPC, with the
+// nim service serving until games fill it up.
+func newHealthServer() *health.Server {
+	srv := health.NewServer()
+	srv.SetServingStatus(nimServiceName, healthpb.HealthCheckResponse_SERVING)
+	return srv
+}
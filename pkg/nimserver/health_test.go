@@ -0,0 +1,74 @@
+package nimserver
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// startGRPC serves server's gRPC services on a loopback port and returns a
+// health client connected to them, as grpc_health_probe would be.
+func startGRPC(t *testing.T, server *Server) healthpb.HealthClient {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listening for gRPC: %v\n", err)
+	}
+	srv := serveGRPC(lis, server)
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient(lis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("dialing gRPC: %v\n", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return healthpb.NewHealthClient(conn)
+}
+
+func TestHealthCheck(t *testing.T) {
+	server, _ := startServer(t, &ServerConfig{MaxClients: 1})
+	client := startGRPC(t, server)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	resp, err := client.Check(ctx, &healthpb.HealthCheckRequest{Service: nimServiceName})
+	if err != nil {
+		t.Fatalf("health check failed: %v\n", err)
+	}
+	if resp.Status != healthpb.HealthCheckResponse_SERVING {
+		t.Errorf("idle server reported %v, expected SERVING\n", resp.Status)
+	}
+}
+
+func TestHealthWatchOverloaded(t *testing.T) {
+	server, raddr := startServer(t, &ServerConfig{MaxClients: 1})
+	client := startGRPC(t, server)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	watch, err := client.Watch(ctx, &healthpb.HealthCheckRequest{Service: nimServiceName})
+	if err != nil {
+		t.Fatalf("watching health: %v\n", err)
+	}
+	resp, err := watch.Recv()
+	if err != nil {
+		t.Fatalf("receiving health: %v\n", err)
+	}
+	if resp.Status != healthpb.HealthCheckResponse_SERVING {
+		t.Errorf("idle server reported %v, expected SERVING\n", resp.Status)
+	}
+
+	// one game fills the server up
+	newTestClient(t, raddr, nil).exchange(StateMoveMessage{GameState: nil, MoveRow: -1, MoveCount: 1})
+	resp, err = watch.Recv()
+	if err != nil {
+		t.Fatalf("receiving health: %v\n", err)
+	}
+	if resp.Status != healthpb.HealthCheckResponse_NOT_SERVING {
+		t.Errorf("full server reported %v, expected NOT_SERVING\n", resp.Status)
+	}
+}
@@ -0,0 +1,165 @@
+package nimserver
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"net/http"
+)
+
+// httpGameState is the body of every successful game endpoint response.
+type httpGameState struct {
+	GameID     string    `json:"game_id"`
+	Board      []int     `json:"board"` // []uint8 would encode as base64
+	Moves      int       `json:"moves"`
+	Winner     string    `json:"winner,omitempty"` // "client" or "server" once the game is over
+	ServerMove *httpMove `json:"server_move,omitempty"`
+}
+
+type httpMove struct {
+	Row   int `json:"row"`
+	Count int `json:"count"`
+}
+
+type newHTTPGameBody struct {
+	Seed       *int8 `json:"seed"`       // random if unset
+	Difficulty *int8 `json:"difficulty"` // 1 plays bestMove, 0 takes one coin; the seed's choice if unset
+}
+
+type httpErrorBody struct {
+	Error string `json:"error"`
+}
+
+var (
+	errNoGame   = errors.New("no such game")
+	errGameOver = errors.New("game is over")
+)
+
+// handleGames routes the game endpoints, which play games over HTTP in the
+// same sessions, with the same strategies and tracing, as the games over
+// UDP:
+//
+//	POST /games               {"seed": n, "difficulty": d} (both optional) -> the new game
+//	POST /games/{id}/moves    {"row": r, "count": c} -> the board after the server's reply
+//	GET  /games/{id}          -> where the game stands
+//
+// A finished game is forgotten finishedGameTTL after its last move.
+func (s *Server) handleGames(mux *http.ServeMux) {
+	mux.HandleFunc("POST /games", s.createHTTPGame)
+	mux.HandleFunc("GET /games/{id}", s.getHTTPGame)
+	mux.HandleFunc("POST /games/{id}/moves", s.moveHTTPGame)
+}
+
+func (s *Server) createHTTPGame(w http.ResponseWriter, r *http.Request) {
+	var body newHTTPGameBody
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("parsing body: %w", err))
+			return
+		}
+	}
+	if body.Difficulty != nil && *body.Difficulty != 0 && *body.Difficulty != 1 {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("difficulty %d is not 0 or 1", *body.Difficulty))
+		return
+	}
+	seed := int8(rand.Intn(256) - 128)
+	if body.Seed != nil {
+		seed = *body.Seed
+	}
+
+	s.gameMu.Lock()
+	defer s.gameMu.Unlock()
+	key := httpKeyPrefix + newGameID()
+	res := s.respondTraced(key, StateMoveMessage{GameState: nil, MoveRow: -1, MoveCount: seed})
+	if res.refused {
+		writeError(w, http.StatusServiceUnavailable, errDraining)
+		return
+	}
+	if body.Difficulty != nil {
+		res.sess.Difficulty = *body.Difficulty
+		res.sess.Strategy = strategyName(*body.Difficulty)
+		s.persist(key)
+	}
+	writeJSON(w, http.StatusOK, httpState(res.sess))
+}
+
+func (s *Server) getHTTPGame(w http.ResponseWriter, r *http.Request) {
+	s.gameMu.Lock()
+	defer s.gameMu.Unlock()
+	key := s.gameKey(httpKeyPrefix, r.PathValue("id"))
+	if key == "" {
+		writeError(w, http.StatusNotFound, errNoGame)
+		return
+	}
+	writeJSON(w, http.StatusOK, httpState(s.session(key)))
+}
+
+func (s *Server) moveHTTPGame(w http.ResponseWriter, r *http.Request) {
+	var body httpMove
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("parsing body: %w", err))
+		return
+	}
+
+	s.gameMu.Lock()
+	defer s.gameMu.Unlock()
+	key := s.gameKey(httpKeyPrefix, r.PathValue("id"))
+	if key == "" {
+		writeError(w, http.StatusNotFound, errNoGame)
+		return
+	}
+	sess := s.session(key)
+	// the server answers every move at once, so it is the client's turn
+	// until the game is over
+	if !sess.Playing {
+		writeError(w, http.StatusConflict, errGameOver)
+		return
+	}
+	// the client sends only its move, so the board it leaves is worked
+	// out here, and CheckMove then judges it as it would a UDP client's
+	board := append([]uint8{}, sess.LastMove.GameState...)
+	if body.Row < 0 || body.Row >= len(board) || body.Count < 1 || body.Count > math.MaxInt8 || body.Count > int(board[body.Row]) {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("illegal move: %d from row %d", body.Count, body.Row))
+		return
+	}
+	board[body.Row] -= uint8(body.Count)
+	res := s.respondTraced(key, StateMoveMessage{
+		GameState: board,
+		MoveRow:   int8(body.Row),
+		MoveCount: int8(body.Count),
+		GameID:    sess.GameID,
+	})
+	if res.rejected {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("illegal move: %d from row %d", body.Count, body.Row))
+		return
+	}
+	writeJSON(w, http.StatusOK, httpState(res.sess))
+}
+
+// httpState is where sess's game stands, as the server's last reply left it.
+func httpState(sess *GameSession) httpGameState {
+	last := sess.LastMove
+	state := httpGameState{GameID: sess.GameID, Board: []int{}, Moves: sess.MoveCount}
+	for _, coins := range last.GameState {
+		state.Board = append(state.Board, int(coins))
+	}
+	if last.MoveRow >= 0 {
+		state.ServerMove = &httpMove{Row: int(last.MoveRow), Count: int(last.MoveCount)}
+	}
+	if !sess.Playing {
+		state.Winner = gameWinner(last)
+	}
+	return state
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, httpErrorBody{Error: err.Error()})
+}
@@ -0,0 +1,120 @@
+package nimserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// do sends body as JSON and decodes the reply into out, returning the status.
+func do(t *testing.T, method, url string, body, out interface{}) int {
+	t.Helper()
+	var buf bytes.Buffer
+	if body != nil {
+		json.NewEncoder(&buf).Encode(body)
+	}
+	req, err := http.NewRequest(method, url, &buf)
+	if err != nil {
+		t.Fatalf("building request: %v\n", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s: %v\n", method, url, err)
+	}
+	defer resp.Body.Close()
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		t.Fatalf("decoding reply to %s %s: %v\n", method, url, err)
+	}
+	return resp.StatusCode
+}
+
+func TestHTTPGame(t *testing.T) {
+	server := newServer(&ServerConfig{AdminGamesEnabled: true}, nil, nil)
+	ts := httptest.NewServer(server.adminHandler())
+	defer ts.Close()
+
+	// seed 5 would play bestMove, which the client can't beat
+	var state httpGameState
+	seed, difficulty := int8(5), int8(0)
+	if status := do(t, "POST", ts.URL+"/games", newHTTPGameBody{Seed: &seed, Difficulty: &difficulty}, &state); status != http.StatusOK {
+		t.Fatalf("creating a game returned %d\n", status)
+	}
+	if state.GameID == "" || len(state.Board) == 0 || state.Winner != "" {
+		t.Fatalf("unexpected new game %+v\n", state)
+	}
+	gameURL := ts.URL + "/games/" + state.GameID
+
+	var fail httpErrorBody
+	if status := do(t, "POST", gameURL+"/moves", httpMove{Row: len(state.Board), Count: 1}, &fail); status != http.StatusBadRequest {
+		t.Errorf("move off the board returned %d, expected 400\n", status)
+	}
+	if status := do(t, "GET", ts.URL+"/games/nope", nil, &fail); status != http.StatusNotFound {
+		t.Errorf("unknown game returned %d, expected 404\n", status)
+	}
+
+	for state.Winner == "" {
+		board := make([]uint8, len(state.Board))
+		for i, coins := range state.Board {
+			board[i] = uint8(coins)
+		}
+		move := bestMove(board)
+		if status := do(t, "POST", gameURL+"/moves", httpMove{Row: int(move.MoveRow), Count: int(move.MoveCount)}, &state); status != http.StatusOK {
+			t.Fatalf("move %v returned %d\n", move, status)
+		}
+	}
+	if state.Winner != "client" {
+		t.Errorf("game ended with %+v, expected the client to beat difficulty 0\n", state)
+	}
+
+	var got httpGameState
+	if status := do(t, "GET", gameURL, nil, &got); status != http.StatusOK || got.Winner != "client" || got.Moves != state.Moves {
+		t.Errorf("GET returned %d %+v, expected %+v\n", status, got, state)
+	}
+	if status := do(t, "POST", gameURL+"/moves", httpMove{Row: 0, Count: 1}, &fail); status != http.StatusConflict {
+		t.Errorf("move after the game returned %d, expected 409\n", status)
+	}
+	if stats := server.Stats(); stats.GamesStarted != 1 || stats.ClientWins != 1 {
+		t.Errorf("stats are %+v, expected one game won by the client\n", stats)
+	}
+}
+
+// TestHTTPFinishedGameForgotten plays a game over HTTP and checks its
+// session is gone once finishedGameTTL has passed.
+func TestHTTPFinishedGameForgotten(t *testing.T) {
+	server := newServer(&ServerConfig{AdminGamesEnabled: true}, nil, nil)
+	clock := &testClock{at: time.Unix(1000, 0)}
+	server.now = clock.now
+	ts := httptest.NewServer(server.adminHandler())
+	defer ts.Close()
+
+	var state httpGameState
+	seed, difficulty := int8(5), int8(0)
+	if status := do(t, "POST", ts.URL+"/games", newHTTPGameBody{Seed: &seed, Difficulty: &difficulty}, &state); status != http.StatusOK {
+		t.Fatalf("creating a game returned %d\n", status)
+	}
+	gameURL := ts.URL + "/games/" + state.GameID
+	for state.Winner == "" {
+		board := make([]uint8, len(state.Board))
+		for i, coins := range state.Board {
+			board[i] = uint8(coins)
+		}
+		move := bestMove(board)
+		if status := do(t, "POST", gameURL+"/moves", httpMove{Row: int(move.MoveRow), Count: int(move.MoveCount)}, &state); status != http.StatusOK {
+			t.Fatalf("move %v returned %d\n", move, status)
+		}
+	}
+
+	clock.advance(finishedGameTTL + time.Second)
+	var fail httpErrorBody
+	if status := do(t, "GET", gameURL, nil, &fail); status != http.StatusNotFound {
+		t.Errorf("finished game returned %d after finishedGameTTL, expected 404\n", status)
+	}
+	server.sessionsMu.Lock()
+	defer server.sessionsMu.Unlock()
+	if len(server.sessions) != 0 {
+		t.Errorf("expected no sessions kept, got %v\n", server.sessions)
+	}
+}
@@ -0,0 +1,88 @@
+package nimserver
+
+import (
+	"bytes"
+	"testing"
+
+	"nimgame/pkg/nim"
+)
+
+// TestLaskerGame plays Lasker's nim through to the end, splitting the first
+// heap that can be split every move, and checks the server's moves are
+// Lasker's nim's and win it the game.
+func TestLaskerGame(t *testing.T) {
+	server, raddr := serveOnLoopback(t, &ServerConfig{LaskerEnabled: true}, nil)
+	c := newTestClient(t, raddr, nil)
+
+	// an odd seed plays the server's best moves
+	reply := c.exchange(StateMoveMessage{GameState: nil, MoveRow: -1, MoveCount: 3, Variant: nim.LaskerVariant})
+	if reply.Variant != nim.LaskerVariant {
+		t.Fatalf("GameStart answered %+v, expected Lasker's nim\n", reply)
+	}
+	splits := 0
+	for !emptyBoard(reply.GameState) {
+		board := reply.GameState
+		move := StateMoveMessage{GameState: nim.LaskerOptimal{}.LaskerMove(board)}
+		for row, coins := range board {
+			if coins > 1 {
+				move.GameState, move.MoveRow = nim.Split(board, row, 1), int8(row)
+				splits++
+				break
+			}
+		}
+		if emptyBoard(move.GameState) {
+			c.exchange(move)
+			t.Fatalf("the client won against the server's best moves\n")
+		}
+		reply = c.exchange(move)
+		if reply.Variant != nim.LaskerVariant || !nim.ValidLaskerMove(move.GameState, reply.GameState) {
+			t.Fatalf("the server answered %v with %+v\n", move.GameState, reply)
+		}
+	}
+	if stats := server.Stats(); splits == 0 || stats.InvalidMoves != 0 || stats.ServerWins != 1 {
+		t.Errorf("%d splits, server stats %+v, expected the server to accept splits and win\n", splits, stats)
+	}
+}
+
+// TestLaskerNotEnabled checks a server without LaskerEnabled plays nim
+// with a client asking for Lasker's nim, and refuses its splits.
+func TestLaskerNotEnabled(t *testing.T) {
+	server, raddr := serveOnLoopback(t, &ServerConfig{}, nil)
+	c := newTestClient(t, raddr, nil)
+
+	reply := c.exchange(StateMoveMessage{GameState: nil, MoveRow: -1, MoveCount: 3, Variant: nim.LaskerVariant})
+	if reply.Variant != "" {
+		t.Fatalf("GameStart answered %+v, expected nim\n", reply)
+	}
+	board := reply.GameState
+	row := bytes.IndexFunc(board, func(coins rune) bool { return coins > 1 })
+	c.exchange(StateMoveMessage{GameState: nim.Split(board, row, 1), MoveRow: int8(row)})
+	if stats := server.Stats(); stats.InvalidMoves != 1 {
+		t.Errorf("server stats are %+v, expected the split refused\n", stats)
+	}
+}
+
+func TestCheckMoveLasker(t *testing.T) {
+	config := &ServerConfig{MaxBoardRows: 3}
+	last := StateMoveMessage{GameState: []uint8{3, 5, 2}, Variant: nim.LaskerVariant}
+	tests := []struct {
+		move StateMoveMessage
+		want bool
+	}{
+		{StateMoveMessage{GameState: []uint8{3, 2, 3, 2}, MoveRow: 1}, true}, // past MaxBoardRows
+		{StateMoveMessage{GameState: []uint8{3, 1, 2}, MoveRow: 1, MoveCount: 4}, true},
+		{StateMoveMessage{GameState: []uint8{3, 1, 2}, MoveRow: 0, MoveCount: 1}, true}, // judged by the boards
+		{StateMoveMessage{GameState: []uint8{3, 2, 2, 2}, MoveRow: 1}, false},
+		{StateMoveMessage{GameState: []uint8{3, 5, 2}, MoveRow: 1}, false},
+	}
+	for _, test := range tests {
+		if got, err := CheckMove(test.move, last, config); got != test.want || err != nil {
+			t.Errorf("CheckMove(%+v) = %v, %v, expected %v\n", test.move, got, err, test.want)
+		}
+	}
+	// without the variant a split is refused
+	last.Variant = ""
+	if got, _ := CheckMove(tests[0].move, last, config); got {
+		t.Errorf("CheckMove accepted a split in nim\n")
+	}
+}
@@ -0,0 +1,39 @@
+package nimserver
+
+import (
+	"sort"
+	"time"
+)
+
+// LatencyStats summarises the server's move latencies over a game.
+type LatencyStats struct {
+	Min, Max, Mean, P99 time.Duration
+}
+
+// SessionLatencyStats summarises the latencies of the replies sent in
+// sess's game.
+func SessionLatencyStats(sess *GameSession) LatencyStats {
+	return ComputeLatencyStats(sess.Stats.Latencies)
+}
+
+// ComputeLatencyStats summarises latencies; P99 uses the nearest-rank method.
+func ComputeLatencyStats(latencies []time.Duration) LatencyStats {
+	if len(latencies) == 0 {
+		return LatencyStats{}
+	}
+	sorted := make([]time.Duration, len(latencies))
+	copy(sorted, latencies)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	var total time.Duration
+	for _, l := range sorted {
+		total += l
+	}
+	rank := (99*len(sorted) + 99) / 100 // ceil(0.99 * n)
+	return LatencyStats{
+		Min:  sorted[0],
+		Max:  sorted[len(sorted)-1],
+		Mean: total / time.Duration(len(sorted)),
+		P99:  sorted[rank-1],
+	}
+}
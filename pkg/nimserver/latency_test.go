@@ -0,0 +1,119 @@
+package nimserver
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestComputeLatencyStats(t *testing.T) {
+	if stats := ComputeLatencyStats(nil); stats != (LatencyStats{}) {
+		t.Errorf("stats of no latencies: %+v\n", stats)
+	}
+
+	latencies := []time.Duration{3 * time.Millisecond, time.Millisecond, 2 * time.Millisecond}
+	want := LatencyStats{Min: time.Millisecond, Max: 3 * time.Millisecond, Mean: 2 * time.Millisecond, P99: 3 * time.Millisecond}
+	if stats := ComputeLatencyStats(latencies); stats != want {
+		t.Errorf("ComputeLatencyStats(%v) = %+v, want %+v\n", latencies, stats, want)
+	}
+}
+
+// TestMoveLatencyTracking plays the server 100 moves whose replies are
+// written 1ms, 2ms, ... 100ms after they were read, on a fake clock.
+func TestMoveLatencyTracking(t *testing.T) {
+	// a move's read and its reply's write are the clock's only reads, each
+	// a step on from the one before
+	var clockMu sync.Mutex
+	var now time.Time
+	var step time.Duration
+	fakeClock := func(s *Server) {
+		s.now = func() time.Time {
+			clockMu.Lock()
+			defer clockMu.Unlock()
+			now = now.Add(step)
+			return now
+		}
+	}
+	server, raddr := startServer(t, &ServerConfig{}, fakeClock)
+	client := newTestClient(t, raddr, nil)
+	local := client.conn.LocalAddr().String()
+
+	for i := 1; i <= 100; i++ {
+		clockMu.Lock()
+		step = time.Duration(i) * time.Millisecond
+		clockMu.Unlock()
+		if i == 1 {
+			client.exchange(StateMoveMessage{GameState: nil, MoveRow: -1, MoveCount: 5})
+		} else {
+			// an illegal move only gets the last reply resent, so the game
+			// never ends
+			client.exchange(StateMoveMessage{GameState: []uint8{}, MoveRow: 0, MoveCount: 0})
+		}
+		// the reply's latency is tracked once it is sent
+		for deadline := time.Now().Add(time.Second); len(sessionOf(server, local).Stats.Latencies) < i; time.Sleep(time.Millisecond) {
+			if time.Now().After(deadline) {
+				t.Fatalf("move %d: latency not tracked\n", i)
+			}
+		}
+	}
+
+	latencies := sessionOf(server, local).Stats.Latencies
+	if len(latencies) != 100 {
+		t.Fatalf("tracked %v latencies, want 100\n", len(latencies))
+	}
+	stats := ComputeLatencyStats(latencies)
+	want := LatencyStats{Min: time.Millisecond, Max: 100 * time.Millisecond, Mean: 50500 * time.Microsecond, P99: 99 * time.Millisecond}
+	if stats != want {
+		t.Errorf("latency stats = %+v, want %+v\n", stats, want)
+	}
+}
+
+// TestSessionLatencyStats records 10 replies, the last the slowest, and
+// checks the session's p99 is that largest latency.
+func TestSessionLatencyStats(t *testing.T) {
+	sess := &GameSession{}
+	start := time.Unix(1700000000, 0)
+	for i := 1; i <= 10; i++ {
+		receivedAt := start.Add(time.Duration(i) * time.Second)
+		sentAt := receivedAt.Add(time.Duration(i) * time.Millisecond)
+		if latency := sess.recordReply(receivedAt, sentAt); latency != time.Duration(i)*time.Millisecond {
+			t.Errorf("move %d: latency %v, want %v\n", i, latency, time.Duration(i)*time.Millisecond)
+		}
+		if !sess.ReceivedAt.Equal(receivedAt) || !sess.SentAt.Equal(sentAt) {
+			t.Errorf("move %d: session has ReceivedAt %v, SentAt %v\n", i, sess.ReceivedAt, sess.SentAt)
+		}
+	}
+
+	stats := SessionLatencyStats(sess)
+	want := LatencyStats{Min: time.Millisecond, Max: 10 * time.Millisecond, Mean: 5500 * time.Microsecond, P99: 10 * time.Millisecond}
+	if stats != want {
+		t.Errorf("SessionLatencyStats = %+v, want %+v\n", stats, want)
+	}
+	if stats.P99 != stats.Max {
+		t.Errorf("p99 %v is not the largest latency %v\n", stats.P99, stats.Max)
+	}
+}
+
+// TestMoveLatencyQuantile checks move latency is exported with its 0.99
+// quantile.
+func TestMoveLatencyQuantile(t *testing.T) {
+	moveLatency.Observe(0.001)
+	families, err := prometheus.DefaultGatherer.Gather()
+	if err != nil {
+		t.Fatalf("gathering metrics: %v\n", err)
+	}
+	for _, family := range families {
+		if family.GetName() != "nim_move_latency_seconds" {
+			continue
+		}
+		for _, q := range family.GetMetric()[0].GetSummary().GetQuantile() {
+			if q.GetQuantile() == 0.99 {
+				return
+			}
+		}
+		t.Fatalf("nim_move_latency_seconds has no 0.99 quantile\n")
+	}
+	t.Fatalf("nim_move_latency_seconds is not exported\n")
+}
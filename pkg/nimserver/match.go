@@ -0,0 +1,124 @@
+package nimserver
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/DistributedClocks/tracing"
+)
+
+// matchCompleteMoveRow marks the MatchComplete message, telling a client
+// the game just ended decided its match. It carries the final score, and
+// answers any Sync sent once the match is over.
+const matchCompleteMoveRow = -14
+
+// Match is a best-of-Games match a client plays as consecutive games from
+// one session, whose score the server keeps. The client opens the first
+// game, and the server the next, in turn.
+type Match struct {
+	ID         string
+	Games      int8 // best of; an even number plays on past a tie
+	ClientWins int8
+	ServerWins int8
+}
+
+// joinMatch returns the match a GameStart asking for a best-of-games match
+// plays its game in: sess's, if it is still undecided, or a new one. It is
+// nil for a single game, when games isn't positive.
+func joinMatch(sess *GameSession, games int8) *Match {
+	if games <= 0 {
+		return nil
+	}
+	if m := sess.Match; m != nil && !m.decided() {
+		return m
+	}
+	return &Match{ID: newGameID(), Games: games}
+}
+
+// serverOpens reports whether the server makes the first move of the
+// match's next game: every other one, from the second on.
+func (m *Match) serverOpens() bool {
+	return (m.ClientWins+m.ServerWins)%2 == 1
+}
+
+// decided reports whether either side has won more than half the games.
+func (m *Match) decided() bool {
+	return 2*int(m.ClientWins) > int(m.Games) || 2*int(m.ServerWins) > int(m.Games)
+}
+
+// winner returns "client" or "server" once the match is decided, and ""
+// before.
+func (m *Match) winner() string {
+	switch {
+	case 2*int(m.ClientWins) > int(m.Games):
+		return "client"
+	case 2*int(m.ServerWins) > int(m.Games):
+		return "server"
+	}
+	return ""
+}
+
+// record counts a game of the match won by winner.
+func (m *Match) record(winner string) {
+	if winner == "server" {
+		m.ServerWins++
+	} else {
+		m.ClientWins++
+	}
+}
+
+// score copies the match's length and score into move, a reply in one of
+// its games.
+func (m *Match) score(move *StateMoveMessage) {
+	move.MatchGames, move.MatchClientWins, move.MatchServerWins = m.Games, m.ClientWins, m.ServerWins
+}
+
+// openGame makes the server's first move of a match game, kept under raddr,
+// on board, the game's new board, and returns it as the reply to the
+// GameStart.
+func (s *Server) openGame(raddr string, sess *GameSession, board []uint8) StateMoveMessage {
+	// Play moves on the board in place
+	move := s.play(StateMoveMessage{GameState: append([]uint8(nil), board...), Variant: sess.Variant}, sess.Difficulty)
+	s.notifyMove(raddr, sess.GameID, move)
+	sess.MoveCount++
+	sess.record(move)
+	s.count(func(st *Stats) { st.Moves++ })
+	return move
+}
+
+// completeMatch traces the end of the match the game gameID, kept under
+// raddr, decided, and sends the client the MatchComplete message with send.
+// The caller holds gameMu.
+func (s *Server) completeMatch(raddr, gameID string, m *Match, trace *tracing.Trace, send func(reply []byte)) {
+	s.logger().Info("match complete", "client", raddr, "match", m.ID, "winner", m.winner(), "client_wins", m.ClientWins, "server_wins", m.ServerWins)
+	if trace != nil {
+		trace.RecordAction(matchComplete(m))
+	}
+	if send == nil {
+		return
+	}
+	notice := StateMoveMessage{
+		MoveRow:           matchCompleteMoveRow,
+		MoveCount:         matchCompleteMoveRow,
+		TracingServerAddr: s.config.TracingServerAddress,
+		GameID:            gameID,
+	}
+	m.score(&notice)
+	bufOut, err := MarshalMove(notice, s.config.CompressionMode)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error marshalling match result to %v: %v\n", raddr, err)
+		return
+	}
+	send(bufOut)
+}
+
+// matchComplete is the MatchComplete action recording m's final score.
+func matchComplete(m *Match) MatchComplete {
+	return MatchComplete{
+		MatchID:    m.ID,
+		Games:      m.Games,
+		ClientWins: m.ClientWins,
+		ServerWins: m.ServerWins,
+		Winner:     m.winner(),
+	}
+}
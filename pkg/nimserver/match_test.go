@@ -0,0 +1,105 @@
+package nimserver
+
+import (
+	"encoding/json"
+	"testing"
+
+	"nimgame/pkg/nim"
+)
+
+// playOut plays a game on from reply, the server's answer to its GameStart,
+// by bestMove, returning the server's last reply.
+func (c *testClient) playOut(reply StateMoveMessage) StateMoveMessage {
+	for reply.MoveRow != -2 && !emptyBoard(reply.GameState) {
+		reply = c.exchange(bestMove(append([]uint8(nil), reply.GameState...)))
+	}
+	return reply
+}
+
+// TestMatch plays a best-of-3 match by bestMove on boards the first player
+// wins, against the server's best moves: the client wins the games it
+// opens, the first and third, and the server the second, which it opens.
+func TestMatch(t *testing.T) {
+	tracingAddr, output := startTracingServer(t)
+	server, raddr := startServer(t, &ServerConfig{TracingServerAddress: tracingAddr})
+	c := newTestClient(t, raddr, nil)
+
+	winners := []string{"client", "server", "client"}
+	var clientWins, serverWins int8
+	for game, winner := range winners {
+		// odd seeds, for the server's best moves, and each its own, or the
+		// GameStart would be dropped as a duplicate
+		seed := int8(3 + 2*game)
+		start := c.exchange(StateMoveMessage{GameState: nil, MoveRow: -1, MoveCount: seed, MatchGames: 3})
+		board := nim.GenerateBoard(int64(seed))
+		if opened := start.MoveRow >= 0; opened != (game == 1) || opened && !nim.ValidMove(board, start.GameState, int(start.MoveRow), int(start.MoveCount)) {
+			t.Fatalf("game %d started with %+v, from %v\n", game+1, start, board)
+		}
+		if start.MatchGames != 3 || start.MatchClientWins != clientWins || start.MatchServerWins != serverWins {
+			t.Errorf("game %d started at %d-%d of %d, expected %d-%d of 3\n", game+1, start.MatchClientWins, start.MatchServerWins, start.MatchGames, clientWins, serverWins)
+		}
+		if winner == "client" {
+			clientWins++
+		} else {
+			serverWins++
+		}
+		last := c.playOut(start)
+		if got := gameWinner(last); got != winner || last.MatchClientWins != clientWins || last.MatchServerWins != serverWins {
+			t.Fatalf("game %d ended with %+v, expected %v to win, at %d-%d\n", game+1, last, winner, clientWins, serverWins)
+		}
+	}
+
+	// sent after the last reply, and again in answer to Sync
+	notice := c.exchange(StateMoveMessage{GameState: nil, MoveRow: syncMoveRow})
+	if notice.MoveRow != matchCompleteMoveRow || notice.MatchGames != 3 || notice.MatchClientWins != 2 || notice.MatchServerWins != 1 {
+		t.Errorf("match ended with %+v, expected MatchComplete at 2-1\n", notice)
+	}
+	if stats := server.Stats(); stats.ClientWins != 2 || stats.ServerWins != 1 || stats.InvalidMoves != 0 {
+		t.Errorf("server stats are %+v, expected the client to win two games to one\n", stats)
+	}
+
+	var traced []MatchComplete
+	for _, r := range readTraceRecords(t, output) {
+		if r.TracerIdentity == "server" && r.Tag == "MatchComplete" {
+			var action MatchComplete
+			if err := json.Unmarshal(r.Body, &action); err != nil {
+				t.Fatalf("decoding %s: %v\n", r.Body, err)
+			}
+			traced = append(traced, action)
+		}
+	}
+	want := MatchComplete{Games: 3, ClientWins: 2, ServerWins: 1, Winner: "client"}
+	if len(traced) != 1 || traced[0].MatchID == "" || traced[0].Games != want.Games || traced[0].ClientWins != want.ClientWins ||
+		traced[0].ServerWins != want.ServerWins || traced[0].Winner != want.Winner {
+		t.Errorf("traced %+v, expected one %+v\n", traced, want)
+	}
+}
+
+func TestMatchScore(t *testing.T) {
+	m := &Match{Games: 3}
+	for _, winner := range []string{"server", "client"} {
+		m.record(winner)
+		if m.decided() {
+			t.Fatalf("%+v is decided\n", m)
+		}
+	}
+	if m.serverOpens() {
+		t.Errorf("%+v has the server open the third game\n", m)
+	}
+	m.record("server")
+	if !m.decided() || m.winner() != "server" {
+		t.Errorf("%+v is won by %q, expected the server\n", m, m.winner())
+	}
+	// an undecided match carries on, and a decided one starts afresh
+	sess := &GameSession{Match: m}
+	if next := joinMatch(sess, 3); next == m || next.ClientWins+next.ServerWins != 0 {
+		t.Errorf("joining after %+v gave %+v, expected a new match\n", m, next)
+	}
+	sess.Match = &Match{Games: 5, ClientWins: 2}
+	if next := joinMatch(sess, 3); next != sess.Match {
+		t.Errorf("joining %+v gave %+v\n", sess.Match, next)
+	}
+	if joinMatch(sess, 0) != nil {
+		t.Errorf("a GameStart without MatchGames joined a match\n")
+	}
+}
@@ -0,0 +1,48 @@
+package nimserver
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Prometheus metrics, served on the admin listener at /metrics.
+var (
+	moveLatency = promauto.NewSummary(prometheus.SummaryOpts{
+		Name:       "nim_move_latency_seconds",
+		Help:       "Time from receiving a client move to sending the server's reply.",
+		Objectives: map[float64]float64{0.5: 0.05, 0.9: 0.01, 0.99: 0.001},
+	})
+	queueDepth = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "nim_queue_depth",
+		Help: "Client moves read off the socket and waiting to be handled.",
+	})
+	drainingGauge = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "nim_draining",
+		Help: "1 while the server is draining, refusing new games until those in progress end, else 0.",
+	})
+	droppedMoves = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "nim_dropped_total",
+		Help: "Client moves dropped because the move queue was full.",
+	})
+	dedupDrops = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "nim_dedup_drops_total",
+		Help: "Packets dropped as exact duplicates of one seen in the last 10 seconds.",
+	})
+	dtlsDrops = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "nim_dtls_drops_total",
+		Help: "Packets on the DTLS address dropped for not starting a DTLS handshake.",
+	})
+	moveComputeDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "nim_move_compute_duration_seconds",
+		Help:    "Time Play takes to pick the server's move, when MoveTimingEnabled.",
+		Buckets: prometheus.ExponentialBuckets(0.000001, 4, 10),
+	})
+	serverWins = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "nim_wins_total",
+		Help: "Games won by the server, by the strategy it played.",
+	}, []string{"strategy"})
+	serverLosses = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "nim_losses_total",
+		Help: "Games lost by the server, by the strategy it played.",
+	}, []string{"strategy"})
+)
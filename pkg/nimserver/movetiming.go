@@ -0,0 +1,26 @@
+package nimserver
+
+import (
+	"fmt"
+	"time"
+)
+
+// play is Play, timed when MoveTimingEnabled. Moves taking longer than
+// MaxMoveComputeMs are logged with their board for profiling.
+func (s *Server) play(move StateMoveMessage, mode int8) StateMoveMessage {
+	if !s.config.MoveTimingEnabled {
+		return Play(move, mode)
+	}
+	// Play moves on the board in place
+	board := append([]uint8(nil), move.GameState...)
+	start := s.now()
+	reply := Play(move, mode)
+	elapsed := s.now().Sub(start)
+	moveComputeDuration.Observe(elapsed.Seconds())
+
+	limit := time.Duration(s.config.MaxMoveComputeMs) * time.Millisecond
+	if limit > 0 && elapsed > limit {
+		s.logger().Warn("slow move computation", "elapsed", elapsed, "limit", limit, "difficulty", mode, "board", fmt.Sprint(board))
+	}
+	return reply
+}
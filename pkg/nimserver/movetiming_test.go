@@ -0,0 +1,62 @@
+package nimserver
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestPlayWorstCaseBoard times Play on the largest board a move can
+// address, where only the last row can zero the nim sum.
+func TestPlayWorstCaseBoard(t *testing.T) {
+	board := make([]uint8, 127)
+	for i := range board {
+		board[i] = 1
+	}
+	board[len(board)-1] = 200 // 126 ones cancel out, leaving a nim sum of 200
+
+	const runs = 1000
+	var total time.Duration
+	for i := 0; i < runs; i++ {
+		move := StateMoveMessage{GameState: append([]uint8(nil), board...)}
+		start := time.Now()
+		reply := Play(move, 1)
+		total += time.Since(start)
+		if reply.MoveRow != int8(len(board)-1) || nimSum(reply.GameState) != 0 {
+			t.Fatalf("expected the last row to be taken down to a zero nim sum, got %v\n", reply)
+		}
+	}
+	if mean := total / runs; mean > time.Millisecond {
+		t.Errorf("Play took %v on a %d row board, expected under 1ms\n", mean, len(board))
+	}
+}
+
+func TestSlowMoveWarning(t *testing.T) {
+	var logs bytes.Buffer
+	defer slog.SetDefault(slog.Default())
+	slog.SetDefault(slog.New(slog.NewTextHandler(&logs, nil)))
+
+	config := &ServerConfig{MoveTimingEnabled: true, MaxMoveComputeMs: 1}
+	server := newServer(config, nil, nil)
+	now := time.Unix(0, 0)
+	var step time.Duration
+	server.now = func() time.Time {
+		now = now.Add(step)
+		return now
+	}
+
+	board := []uint8{3, 4, 5}
+	step = time.Millisecond / 2
+	server.play(StateMoveMessage{GameState: append([]uint8(nil), board...)}, 1)
+	if logs.Len() != 0 {
+		t.Errorf("a move within MaxMoveComputeMs was logged: %s\n", logs.String())
+	}
+
+	step = 2 * time.Millisecond
+	server.play(StateMoveMessage{GameState: append([]uint8(nil), board...)}, 1)
+	if !strings.Contains(logs.String(), "slow move computation") || !strings.Contains(logs.String(), "board=\"[3 4 5]\"") {
+		t.Errorf("expected a warning with the board before the move, got: %s\n", logs.String())
+	}
+}
@@ -0,0 +1,391 @@
+// Package nimserver serves nim games to clients over UDP, or QUIC, playing
+// the server's side of each. A Server is made by New, as its options say,
+// served with Run and stopped with Shutdown, so it can be embedded in
+// other programs and tests as well as run by the server command.
+package nimserver
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"slices"
+	"strings"
+	"time"
+
+	"nimgame/fcheck"
+	"nimgame/pkg/dtlsgame"
+	"nimgame/pkg/netcond"
+	"nimgame/pkg/nimerr"
+
+	"github.com/DistributedClocks/tracing"
+)
+
+// Option configures optional Server behaviour.
+type Option func(*Server)
+
+// WithConfig serves as config says. It replaces the whole config, so it
+// goes before any option changing part of it.
+func WithConfig(config *ServerConfig) Option {
+	return func(s *Server) {
+		c := *config
+		s.config = &c
+	}
+}
+
+// WithListenAddress serves games on addr, overriding NimServerAddress. A
+// port of 0 picks a free one; see Server.Addr.
+func WithListenAddress(addr string) Option {
+	return func(s *Server) { s.config.NimServerAddress = addr }
+}
+
+// WithStore saves the sessions to the directory path as they change, and
+// their games' histories beside it, and restores them on start, overriding
+// PersistPath.
+func WithStore(path string) Option {
+	return func(s *Server) { s.config.PersistPath = path }
+}
+
+// WithStrategy has choose pick how each new game is played from its seed,
+// in place of the seed's parity or the A/B test: 1 plays bestMove, 0 takes
+// one coin.
+func WithStrategy(choose func(seed int8) int8) Option {
+	return func(s *Server) { s.choose = choose }
+}
+
+// WithConditioner sends replies through c, mistreating them as its Config
+// says. It applies to games over UDP only.
+func WithConditioner(c *netcond.Conditioner) Option {
+	return func(s *Server) { s.conds = c }
+}
+
+// WithTracer traces moves with tracer rather than connecting to the
+// config's TracingServerAddress. The tracer is left open for the caller to
+// close.
+func WithTracer(tracer *tracing.Tracer) Option {
+	return func(s *Server) { s.tracer = tracer }
+}
+
+// WithLogger logs to l rather than the default logger.
+func WithLogger(l *slog.Logger) Option {
+	return func(s *Server) { s.log = l }
+}
+
+// ErrServerClosed is returned by Run once the server has run or been shut
+// down.
+var ErrServerClosed = errors.New("server closed")
+
+// New validates the config opts make and prepares a server for it: it
+// loads the dataset, seed cache and saved sessions the config names, checks
+// the boards with RunSelfTest, connects to the tracing server and listens
+// for games and on the TCP, DTLS, admin and gRPC addresses. Errors are
+// nimerr.ErrConfig or nimerr.ErrTransport. Call Run to serve games, and
+// Shutdown to stop.
+func New(opts ...Option) (*Server, error) {
+	s := newServer(new(ServerConfig), nil, nil, opts...)
+	if err := s.open(); err != nil {
+		s.release()
+		return nil, err
+	}
+	return s, nil
+}
+
+// open does New's work once the options are applied.
+func (s *Server) open() error {
+	config := s.config
+	if err := ValidateConfig(config); err != nil {
+		return fmt.Errorf("invalid config:\n%w", err)
+	}
+	if s.conds != nil && config.QuicEnabled {
+		return nimerr.New(nimerr.ErrConfig, "a conditioner can't be used with QuicEnabled")
+	}
+	if s.tracer == nil {
+		tracer, err := initTracer(config, s.logger())
+		if err != nil {
+			return err
+		}
+		if tracer != nil {
+			s.tracer, s.trace, s.ownTracer = tracer, tracer.CreateTrace(), true
+		}
+	}
+
+	var err error
+	if config.SeedCacheFile != "" {
+		if s.seedCache, err = loadSeedCache(config.SeedCacheFile); err != nil {
+			return nimerr.Wrap(nimerr.ErrConfig, fmt.Errorf("loading seed cache: %w", err))
+		}
+	}
+	if config.DatasetFile != "" && s.dataset == nil {
+		dataset, err := LoadDataset(config.DatasetFile)
+		if err == nil {
+			err = checkDataset(config, dataset)
+		}
+		if err != nil {
+			return nimerr.Wrap(nimerr.ErrConfig, fmt.Errorf("loading dataset: %w", err))
+		}
+		s.logger().Info("loaded board dataset", "path", config.DatasetFile, "boards", len(dataset))
+		s.dataset = dataset
+	}
+	s.RunSelfTest()
+	if config.PersistPath != "" {
+		sessions, err := loadSessions(config.PersistPath)
+		if err != nil {
+			return nimerr.Wrap(nimerr.ErrConfig, fmt.Errorf("restoring sessions: %w", err))
+		}
+		s.logger().Info("restored sessions", "path", config.PersistPath, "sessions", len(sessions))
+		WithSessions(sessions)(s)
+		s.saver.restore(sessions)
+		s.updateHealth()
+	}
+
+	if s.udp, s.addr, err = listen(config, s.logger()); err != nil {
+		return err
+	}
+	if udp, ok := s.udp.(*UDPConnection); ok {
+		udp.Conds = s.conds
+	}
+	if config.AdminAddress != "" {
+		if s.adminLis, err = net.Listen("tcp", config.AdminAddress); err != nil {
+			return nimerr.Wrap(nimerr.ErrTransport, fmt.Errorf("listening for admin endpoints: %w", err))
+		}
+	}
+	if config.GRPCAddress != "" {
+		if s.grpcLis, err = net.Listen("tcp", config.GRPCAddress); err != nil {
+			return nimerr.Wrap(nimerr.ErrTransport, fmt.Errorf("listening for gRPC: %w", err))
+		}
+	}
+	if config.TCPAddress != "" {
+		if s.tcpLis, err = net.Listen("tcp", config.TCPAddress); err != nil {
+			return nimerr.Wrap(nimerr.ErrTransport, fmt.Errorf("listening for TCP: %w", err))
+		}
+		s.logger().Info("serving games over TCP", "addr", s.tcpLis.Addr())
+	}
+	if config.DTLSAddress != "" {
+		opts, err := dtlsgame.ServerOptions(config.DTLSCertFile, config.DTLSKeyFile, config.DTLSPSK)
+		if err != nil {
+			return nimerr.Wrap(nimerr.ErrConfig, err)
+		}
+		dropped := func() {
+			dtlsDrops.Inc()
+			s.count(func(st *Stats) { st.DTLSDropped++ })
+		}
+		if s.dtlsLis, err = dtlsgame.Listen(config.DTLSAddress, opts, dropped); err != nil {
+			return nimerr.Wrap(nimerr.ErrTransport, fmt.Errorf("listening for DTLS: %w", err))
+		}
+		s.logger().Info("serving games over DTLS", "addr", s.dtlsLis.Addr())
+	}
+	return nil
+}
+
+// Run serves games, and the heartbeat, admin and gRPC endpoints the config
+// asks for, including games over gRPC, until ctx is done or Shutdown is called. The moves already
+// read are handled before it returns nil. Everything New opened is closed
+// once it returns, so a Server runs only once.
+func (s *Server) Run(ctx context.Context) error {
+	s.runMu.Lock()
+	ran := s.ran
+	s.ran = true
+	if !ran {
+		ctx, s.stopRun = context.WithCancelCause(ctx)
+		defer s.stopRun(nil)
+	}
+	s.runMu.Unlock()
+	if ran {
+		return ErrServerClosed
+	}
+	defer close(s.finished)
+	defer s.release()
+
+	// answer heartbeats from clients monitoring us
+	if s.config.FCheckAckLocalAddr != "" {
+		responder, err := fcheck.StartResponder(s.config.FCheckAckLocalAddr)
+		if err != nil {
+			return nimerr.Wrap(nimerr.ErrTransport, fmt.Errorf("starting heartbeat responder: %w", err))
+		}
+		defer responder.Close()
+	}
+	if s.adminLis != nil {
+		defer s.serveAdmin(s.adminLis).Close()
+	}
+	if s.grpcLis != nil {
+		defer serveGRPC(s.grpcLis, s).Stop()
+	}
+	if s.tcpLis != nil {
+		go s.serveTCP(s.tcpLis)
+	}
+	if s.dtlsLis != nil {
+		go s.serveDTLS(s.dtlsLis)
+	}
+	s.restartTimers()
+	if err := s.Serve(ctx); !errors.Is(err, ErrCanceled) {
+		return err
+	}
+	return nil
+}
+
+// Shutdown stops Run as though its context were done, draining for up to
+// DrainTimeout, and waits for it to finish the moves already read and
+// return. Once ctx is done it stops waiting for the drain: the socket is
+// closed, and ctx's error returned. A server that never ran is closed at
+// once.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.runMu.Lock()
+	ran, stopRun := s.ran, s.stopRun
+	s.ran = true
+	s.runMu.Unlock()
+	if !ran {
+		s.release()
+		close(s.finished)
+		return nil
+	}
+	stopRun(ErrServerClosed)
+	select {
+	case <-s.finished:
+		return nil
+	case <-ctx.Done():
+		s.udp.Close() // Serve returns once the read loop sees it closed
+		return ctx.Err()
+	}
+}
+
+// release closes the sockets, the notifiers and a tracer New connected.
+func (s *Server) release() {
+	if s.udp != nil {
+		s.udp.Close()
+	}
+	for _, lis := range []net.Listener{s.adminLis, s.grpcLis, s.tcpLis, s.dtlsLis} {
+		if lis != nil {
+			lis.Close()
+		}
+	}
+	s.closeConns()
+	// moves over TCP or DTLS may have been answered after Serve stopped the
+	// timers
+	s.stopMoveTimers()
+	s.webhooks.close()
+	s.saver.close()
+	if closer, ok := s.notifier.(io.Closer); ok {
+		closer.Close()
+	}
+	if s.ownTracer {
+		s.tracer.Close()
+	}
+}
+
+// Addr is the address games are served on.
+func (s *Server) Addr() net.Addr {
+	return s.addr
+}
+
+// AdminAddr is the address the admin endpoints are served on, or nil
+// without an AdminAddress.
+func (s *Server) AdminAddr() net.Addr {
+	if s.adminLis == nil {
+		return nil
+	}
+	return s.adminLis.Addr()
+}
+
+// DTLSAddr is the address games are served on over DTLS, or nil without a
+// DTLSAddress.
+func (s *Server) DTLSAddr() net.Addr {
+	if s.dtlsLis == nil {
+		return nil
+	}
+	return s.dtlsLis.Addr()
+}
+
+// TCPAddr is the address games are served on over TCP, or nil without a
+// TCPAddress.
+func (s *Server) TCPAddr() net.Addr {
+	if s.tcpLis == nil {
+		return nil
+	}
+	return s.tcpLis.Addr()
+}
+
+// GRPCAddr is the address the gRPC services are served on, or nil without
+// a GRPCAddress.
+func (s *Server) GRPCAddr() net.Addr {
+	if s.grpcLis == nil {
+		return nil
+	}
+	return s.grpcLis.Addr()
+}
+
+// GameInfo describes a game in progress.
+type GameInfo struct {
+	GameID    string
+	Client    string  // the client's address; empty for games over gRPC, HTTP or WebSocket
+	Transport string  // "udp", "quic", "tcp", "dtls", "grpc", "http" or "ws"
+	Board     []uint8 // as the server's last reply left it
+	Moves     int     // valid moves by either side
+	Strategy  string  // "best" or "normal"
+	LastSeen  time.Time
+}
+
+// ActiveGames returns the games in progress, by client address, the games
+// over gRPC, HTTP and WebSocket first, by GameID.
+func (s *Server) ActiveGames() []GameInfo {
+	s.gameMu.Lock()
+	defer s.gameMu.Unlock()
+	s.sessionsMu.Lock()
+	defer s.sessionsMu.Unlock()
+	var games []GameInfo
+	for raddr, sess := range s.sessions {
+		if !sess.Playing {
+			continue
+		}
+		client, transport := raddr, keyTransport(raddr)
+		if transport == "tcp" || transport == "dtls" {
+			client = strings.TrimPrefix(raddr, transport+":")
+		} else if transport != "" {
+			client = ""
+		} else if s.config.QuicEnabled {
+			transport = "quic"
+		} else {
+			transport = "udp"
+		}
+		games = append(games, GameInfo{
+			GameID:    sess.GameID,
+			Client:    client,
+			Transport: transport,
+			Board:     append([]uint8(nil), sess.LastMove.GameState...),
+			Moves:     sess.MoveCount,
+			Strategy:  sess.Strategy,
+			LastSeen:  sess.LastSeen,
+		})
+	}
+	slices.SortFunc(games, func(a, b GameInfo) int {
+		return cmp.Or(strings.Compare(a.Client, b.Client), strings.Compare(a.GameID, b.GameID))
+	})
+	return games
+}
+
+// Stats are what a server has counted since it was made.
+type Stats struct {
+	GamesStarted int
+	ClientWins   int
+	ServerWins   int // including games forfeited by the client
+	Moves        int // valid moves by either side
+	InvalidMoves int // client moves rejected, and answered with the last reply
+	Dropped      int // packets dropped as duplicates or with the move queue full
+	DTLSDropped  int // packets on DTLSAddress dropped for not starting a handshake
+}
+
+// Stats returns the server's counts so far.
+func (s *Server) Stats() Stats {
+	s.statsMu.Lock()
+	defer s.statsMu.Unlock()
+	return s.stats
+}
+
+// count updates the stats with f.
+func (s *Server) count(f func(*Stats)) {
+	s.statsMu.Lock()
+	defer s.statsMu.Unlock()
+	f(&s.stats)
+}
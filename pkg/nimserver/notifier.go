@@ -0,0 +1,100 @@
+package nimserver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// GameNotifier pushes game events to an external system.
+type GameNotifier interface {
+	NotifyGameStart(gameID, raddr string, board []uint8)
+	NotifyGameEnd(gameID, winner string, moves int)
+}
+
+// WithNotifier sends game events to n instead of the notifier the config
+// describes.
+func WithNotifier(n GameNotifier) Option {
+	return func(s *Server) {
+		s.notifier = n
+	}
+}
+
+// newNotifier returns the notifier config describes: Kafka when
+// KafkaBootstrapServers is set, otherwise a NullNotifier.
+func newNotifier(config *ServerConfig) GameNotifier {
+	if config.KafkaBootstrapServers == "" {
+		return NullNotifier{}
+	}
+	return NewKafkaNotifier(config.KafkaBootstrapServers, config.KafkaTopic)
+}
+
+// NullNotifier discards every event.
+type NullNotifier struct{}
+
+func (NullNotifier) NotifyGameStart(gameID, raddr string, board []uint8) {}
+
+func (NullNotifier) NotifyGameEnd(gameID, winner string, moves int) {}
+
+// KafkaNotifier publishes events as JSON messages keyed by game ID, so each
+// game's events stay in order on one partition. Messages are written in the
+// background and failures are logged rather than retried by the caller.
+type KafkaNotifier struct {
+	writer *kafka.Writer
+}
+
+// kafkaEvent is the JSON value of a KafkaNotifier message.
+type kafkaEvent struct {
+	Event     string    `json:"event"`
+	GameID    string    `json:"game_id"`
+	Raddr     string    `json:"raddr,omitempty"`
+	Board     []uint8   `json:"board,omitempty"`
+	Winner    string    `json:"winner,omitempty"`
+	Moves     int       `json:"moves,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// NewKafkaNotifier publishes to topic on the comma-separated brokers.
+func NewKafkaNotifier(bootstrapServers, topic string) *KafkaNotifier {
+	return &KafkaNotifier{writer: &kafka.Writer{
+		Addr:     kafka.TCP(strings.Split(bootstrapServers, ",")...),
+		Topic:    topic,
+		Balancer: &kafka.Hash{},
+		Async:    true,
+		Completion: func(messages []kafka.Message, err error) {
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error publishing %d game events to Kafka: %v\n", len(messages), err)
+			}
+		},
+	}}
+}
+
+func (k *KafkaNotifier) NotifyGameStart(gameID, raddr string, board []uint8) {
+	// board is copied since Play updates boards in place
+	k.publish(kafkaEvent{Event: EventGameStart, GameID: gameID, Raddr: raddr, Board: append([]uint8(nil), board...)})
+}
+
+func (k *KafkaNotifier) NotifyGameEnd(gameID, winner string, moves int) {
+	k.publish(kafkaEvent{Event: EventGameEnd, GameID: gameID, Winner: winner, Moves: moves})
+}
+
+func (k *KafkaNotifier) publish(event kafkaEvent) {
+	event.Timestamp = time.Now()
+	value, err := json.Marshal(event)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error encoding game event: %v\n", err)
+		return
+	}
+	// an async writer only fails here once closed
+	k.writer.WriteMessages(context.Background(), kafka.Message{Key: []byte(event.GameID), Value: value})
+}
+
+// Close flushes pending events.
+func (k *KafkaNotifier) Close() error {
+	return k.writer.Close()
+}
@@ -0,0 +1,60 @@
+package nimserver
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+
+	"nimgame/pkg/nim"
+)
+
+// RecordingNotifier captures every notification.
+type RecordingNotifier struct {
+	mu     sync.Mutex
+	starts []recordedStart
+	ends   []recordedEnd
+}
+
+type recordedStart struct {
+	gameID, raddr string
+	board         []uint8
+}
+
+type recordedEnd struct {
+	gameID, winner string
+	moves          int
+}
+
+func (n *RecordingNotifier) NotifyGameStart(gameID, raddr string, board []uint8) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.starts = append(n.starts, recordedStart{gameID, raddr, append([]uint8(nil), board...)})
+}
+
+func (n *RecordingNotifier) NotifyGameEnd(gameID, winner string, moves int) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.ends = append(n.ends, recordedEnd{gameID, winner, moves})
+}
+
+func TestNotifierGameEvents(t *testing.T) {
+	notifier := &RecordingNotifier{}
+	_, raddr := startServer(t, &ServerConfig{}, WithNotifier(notifier))
+	client := newTestClient(t, raddr, nil)
+	winner, replies := client.playGame(5)
+
+	notifier.mu.Lock()
+	defer notifier.mu.Unlock()
+	if len(notifier.starts) != 1 || len(notifier.ends) != 1 {
+		t.Fatalf("expected one start and one end, got %v and %v\n", notifier.starts, notifier.ends)
+	}
+	start, end := notifier.starts[0], notifier.ends[0]
+	if start.gameID == "" || start.raddr != client.conn.LocalAddr().String() || !bytes.Equal(start.board, nim.GenerateBoard(5)) {
+		t.Errorf("unexpected game start %+v\n", start)
+	}
+	// every client move but the last is answered by a server move
+	moves := 2*(len(replies)-1) - 1
+	if end.gameID != start.gameID || end.winner != winner || end.moves != moves {
+		t.Errorf("unexpected game end %+v, expected winner %v after %d moves\n", end, winner, moves)
+	}
+}
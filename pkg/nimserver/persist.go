@@ -0,0 +1,385 @@
+package nimserver
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"nimgame/pkg/configfile"
+	"nimgame/pkg/nim"
+)
+
+// persistedSession is the part of a GameSession saved to PersistPath, with
+// the key it is kept under. Its game's history so far is saved with every
+// other game's, in the file historyPath names.
+type persistedSession struct {
+	Raddr      string
+	GameID     string
+	LastMove   StateMoveMessage
+	Difficulty int8
+	Strategy   string
+	MoveCount  int
+	Playing    bool
+	Match      *Match
+	Clock      *Clock
+	History    []StateMoveMessage `json:"-"`
+}
+
+// historyPath is where the move histories of the sessions saved to dir are
+// kept: beside it, with ".history" appended.
+func historyPath(dir string) string {
+	return filepath.Clean(dir) + ".history"
+}
+
+// sessionFile is where the session kept under raddr is saved in dir.
+func sessionFile(dir, raddr string) string {
+	return filepath.Join(dir, base64.RawURLEncoding.EncodeToString([]byte(raddr))+".json")
+}
+
+// readSessions reads every session saved to dir. A missing dir is a first
+// start, with nothing saved.
+func readSessions(dir string) ([]persistedSession, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var saved []persistedSession
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		var p persistedSession
+		if err := json.Unmarshal(data, &p); err != nil {
+			return nil, fmt.Errorf("parsing session %v: %w", path, err)
+		}
+		saved = append(saved, p)
+	}
+	return saved, nil
+}
+
+// LoadGameHistory reads move histories saved by the server, by game ID,
+// from path: PersistPath with ".history" appended. Each history is a
+// game's board followed by every move made on it; see RebuildState.
+func LoadGameHistory(path string) (map[string][]StateMoveMessage, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var history map[string][]StateMoveMessage
+	if err := json.Unmarshal(data, &history); err != nil {
+		return nil, fmt.Errorf("parsing game history %v: %w", path, err)
+	}
+	return history, nil
+}
+
+// RebuildState replays history, a game's board followed by every move made
+// on it, and returns the board it leaves. Moves are judged by the rules of
+// the variant the history's board is marked with, and those that don't
+// follow from the board before them are an error.
+func RebuildState(history []StateMoveMessage) ([]uint8, error) {
+	if len(history) == 0 {
+		return nil, errors.New("empty history")
+	}
+	variant := history[0].Variant
+	board := append([]uint8(nil), history[0].GameState...)
+	for i, move := range history[1:] {
+		var legal bool
+		switch variant {
+		case nim.LaskerVariant:
+			// a split has no row and count, so take the board it leaves
+			legal = nim.ValidLaskerMove(board, move.GameState)
+		case nim.WythoffVariant:
+			// taking from both heaps has no one row
+			legal = nim.ValidWythoffMove(board, move.GameState)
+		default:
+			legal = nim.ValidMove(board, move.GameState, int(move.MoveRow), int(move.MoveCount))
+		}
+		if !legal {
+			return nil, fmt.Errorf("move %d takes %v to %v, which isn't a legal move", i+1, board, move.GameState)
+		}
+		board = append(board[:0], move.GameState...)
+	}
+	return board, nil
+}
+
+// loadSessions reads the sessions saved to dir, by the key each is kept
+// under, and their histories.
+func loadSessions(dir string) (map[string]*GameSession, error) {
+	saved, err := readSessions(dir)
+	if err != nil {
+		return nil, err
+	}
+	history, err := LoadGameHistory(historyPath(dir))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	sessions := make(map[string]*GameSession, len(saved))
+	for _, p := range saved {
+		sessions[p.Raddr] = &GameSession{
+			GameID:     p.GameID,
+			LastMove:   p.LastMove,
+			Variant:    p.LastMove.Variant,
+			Difficulty: p.Difficulty,
+			Strategy:   p.Strategy,
+			MoveCount:  p.MoveCount,
+			Playing:    p.Playing,
+			Match:      p.Match,
+			Clock:      p.Clock,
+			History:    history[p.GameID],
+		}
+	}
+	return sessions, nil
+}
+
+// WithSessions resumes the given sessions, as restored by loadSessions.
+func WithSessions(sessions map[string]*GameSession) Option {
+	return func(s *Server) {
+		for raddr, sess := range sessions {
+			s.sessions[raddr] = sess
+		}
+	}
+}
+
+// restartTimers gives the restored games in progress their MoveTTL and
+// clock timers again, as though the server's last move had just been sent.
+func (s *Server) restartTimers() {
+	s.gameMu.Lock()
+	defer s.gameMu.Unlock()
+	s.sessionsMu.Lock()
+	defer s.sessionsMu.Unlock()
+	for raddr, sess := range s.sessions {
+		if sess.Playing {
+			send := s.resumedSend(raddr)
+			s.startMoveTimer(raddr, sess, send)
+			s.startFlagTimer(raddr, sess, send)
+		}
+	}
+}
+
+// resumedSend sends a restored game's forfeit notice to the client at
+// raddr. Games over a connection lost it in the restart, so theirs go
+// nowhere.
+func (s *Server) resumedSend(raddr string) func([]byte) {
+	if keyTransport(raddr) != "" {
+		return func([]byte) {}
+	}
+	return func(reply []byte) {
+		addr, err := net.ResolveUDPAddr("udp", raddr)
+		if err != nil {
+			s.logger().Warn("sending to restored client", "client", raddr, "err", err)
+			return
+		}
+		s.udp.WriteTo(reply, addr)
+	}
+}
+
+// persist saves the session kept under raddr to PersistPath, if set, or
+// forgets it there once the session is gone. The caller holds gameMu; the
+// session is copied and written on the saver's goroutine.
+func (s *Server) persist(raddr string) {
+	if s.saver == nil {
+		return
+	}
+	s.sessionsMu.Lock()
+	sess := s.sessions[raddr]
+	s.sessionsMu.Unlock()
+	if sess == nil {
+		s.saver.save(raddr, nil)
+		return
+	}
+	p := &persistedSession{
+		Raddr:      raddr,
+		GameID:     sess.GameID,
+		LastMove:   sess.LastMove,
+		Difficulty: sess.Difficulty,
+		Strategy:   sess.Strategy,
+		MoveCount:  sess.MoveCount,
+		Playing:    sess.Playing,
+		// the history is only appended to, and its boards are copies
+		History: sess.History,
+	}
+	// Play updates boards in place
+	p.LastMove.GameState = append([]uint8(nil), sess.LastMove.GameState...)
+	if sess.Match != nil {
+		m := *sess.Match
+		p.Match = &m
+	}
+	if sess.Clock != nil {
+		c := *sess.Clock
+		p.Clock = &c
+	}
+	s.saver.save(raddr, p)
+}
+
+// sessionSaver writes sessions to a directory, a file each, on its own
+// goroutine so a slow disk never holds up play. Only the latest state
+// saved for each session is written, and with each batch the histories of
+// every session's game, to the file historyPath names.
+type sessionSaver struct {
+	dir string
+	log *slog.Logger
+
+	mu      sync.Mutex
+	pending map[string]*persistedSession // by key; nil removes the file
+	writing bool
+	closed  bool
+	idle    *sync.Cond // signalled when a batch has been written
+	wake    chan struct{}
+
+	writeMu   sync.Mutex                    // held while a batch is written
+	games     map[string]string             // the game ID of each session saved, by key
+	histories map[string][]StateMoveMessage // by game ID
+}
+
+// newSessionSaver returns nil when no PersistPath is configured.
+func newSessionSaver(config *ServerConfig, log *slog.Logger) *sessionSaver {
+	if config.PersistPath == "" {
+		return nil
+	}
+	w := &sessionSaver{
+		dir:       config.PersistPath,
+		log:       log,
+		pending:   make(map[string]*persistedSession),
+		wake:      make(chan struct{}, 1),
+		games:     make(map[string]string),
+		histories: make(map[string][]StateMoveMessage),
+	}
+	w.idle = sync.NewCond(&w.mu)
+	go w.run()
+	return w
+}
+
+// restore has the saver start from sessions, as restored by loadSessions,
+// whose histories are kept until they change or end.
+func (w *sessionSaver) restore(sessions map[string]*GameSession) {
+	if w == nil {
+		return
+	}
+	w.writeMu.Lock()
+	defer w.writeMu.Unlock()
+	for raddr, sess := range sessions {
+		w.games[raddr] = sess.GameID
+		w.histories[sess.GameID] = sess.History
+	}
+}
+
+// save queues p to be written as the session kept under raddr, replacing
+// any state queued for it before, or the session's file to be removed if p
+// is nil. Once the saver is closed it writes at once.
+func (w *sessionSaver) save(raddr string, p *persistedSession) {
+	if w == nil {
+		return
+	}
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		w.write(map[string]*persistedSession{raddr: p})
+		return
+	}
+	w.pending[raddr] = p
+	select {
+	case w.wake <- struct{}{}:
+	default: // already woken
+	}
+	w.mu.Unlock()
+}
+
+func (w *sessionSaver) run() {
+	for range w.wake {
+		w.mu.Lock()
+		batch := w.pending
+		w.pending = make(map[string]*persistedSession)
+		w.writing = true
+		w.mu.Unlock()
+		w.write(batch)
+		w.mu.Lock()
+		w.writing = false
+		w.idle.Broadcast()
+		w.mu.Unlock()
+	}
+}
+
+// write saves batch, each state as the session kept under its key or, if
+// nil, the session's file removed, after the histories of the games saved
+// then, so a session never names a game they don't have. A failure is
+// logged and play carries on unsaved.
+func (w *sessionSaver) write(batch map[string]*persistedSession) {
+	w.writeMu.Lock()
+	defer w.writeMu.Unlock()
+	for raddr, p := range batch {
+		if gameID, ok := w.games[raddr]; ok && (p == nil || p.GameID != gameID) {
+			delete(w.histories, gameID)
+			delete(w.games, raddr)
+		}
+		if p != nil {
+			w.games[raddr] = p.GameID
+			w.histories[p.GameID] = p.History
+		}
+	}
+	if err := os.MkdirAll(w.dir, 0755); err != nil {
+		w.log.Error("saving sessions", "path", w.dir, "err", err)
+		return
+	}
+	if err := configfile.WriteJSON(historyPath(w.dir), w.histories); err != nil {
+		w.log.Error("saving game history", "path", historyPath(w.dir), "err", err)
+		return
+	}
+	for raddr, p := range batch {
+		path := sessionFile(w.dir, raddr)
+		var err error
+		if p == nil {
+			if err = os.Remove(path); errors.Is(err, fs.ErrNotExist) {
+				err = nil
+			}
+		} else {
+			err = configfile.WriteJSON(path, p)
+		}
+		if err != nil {
+			w.log.Error("saving session", "client", raddr, "path", path, "err", err)
+		}
+	}
+}
+
+// flush waits for everything saved so far to be written.
+func (w *sessionSaver) flush() {
+	if w == nil {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for len(w.pending) > 0 || w.writing {
+		w.idle.Wait()
+	}
+}
+
+// close writes what is queued and stops the goroutine; sessions saved
+// after it are written at once.
+func (w *sessionSaver) close() {
+	if w == nil {
+		return
+	}
+	w.flush()
+	w.mu.Lock()
+	w.closed = true
+	close(w.wake)
+	batch := w.pending // saved since the flush
+	w.pending = nil
+	w.mu.Unlock()
+	if len(batch) > 0 {
+		w.write(batch)
+	}
+}
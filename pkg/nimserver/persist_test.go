@@ -0,0 +1,175 @@
+package nimserver
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"nimgame/pkg/nim"
+)
+
+// TestPersistSessions plays part of a game, restarts the server from the
+// saved sessions and finishes the game on the new server.
+func TestPersistSessions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sessions")
+	server, raddr := serveOnLoopback(t, &ServerConfig{}, nil, WithStore(path))
+	client := newTestClient(t, raddr, nil)
+	local := client.conn.LocalAddr().String()
+	reply := client.exchange(StateMoveMessage{GameState: nil, MoveRow: -1, MoveCount: 4})
+	for i := 0; i < 2; i++ {
+		reply = client.exchange(bestMove(append([]uint8(nil), reply.GameState...)))
+	}
+	before := sessionOf(server, local)
+	server.Shutdown(context.Background())
+
+	// restart
+	history, err := LoadGameHistory(historyPath(path))
+	if err != nil {
+		t.Fatalf("loading history: %v\n", err)
+	}
+	restarted, raddr := serveOnLoopback(t, &ServerConfig{}, nil, WithStore(path))
+	after := sessionOf(restarted, local)
+	if after == nil || after.GameID != before.GameID || !after.Playing || after.MoveCount != before.MoveCount {
+		t.Fatalf("expected %+v restored, got %+v\n", before, after)
+	}
+	if len(after.History) != len(before.History) {
+		t.Errorf("expected %d moves of history restored, got %v\n", len(before.History), after.History)
+	}
+	if len(history[before.GameID]) != 1+before.MoveCount {
+		t.Errorf("expected the board and %d moves in the history, got %v\n", before.MoveCount, history[before.GameID])
+	}
+	board, err := RebuildState(history[before.GameID])
+	if err != nil {
+		t.Fatalf("rebuilding state: %v\n", err)
+	}
+	if !bytes.Equal(board, after.LastMove.GameState) {
+		t.Errorf("history rebuilds %v, but the saved state is %v\n", board, after.LastMove.GameState)
+	}
+
+	// the game carries on where it left off
+	client.redial(raddr)
+	for i := 0; i < 100 && reply.MoveRow != -2 && !emptyBoard(reply.GameState); i++ {
+		reply = client.exchange(bestMove(append([]uint8(nil), reply.GameState...)))
+	}
+	if stats := restarted.Stats(); stats.InvalidMoves != 0 {
+		t.Errorf("moves rejected after restart: %+v\n", stats)
+	}
+	if sessionOf(restarted, local).Playing {
+		t.Errorf("game didn't finish after restart\n")
+	}
+}
+
+// TestRestoredGameForfeits restarts a server mid-game and checks the
+// restored game is forfeited once MoveTTL passes without a move.
+func TestRestoredGameForfeits(t *testing.T) {
+	path := t.TempDir()
+	server, raddr := serveOnLoopback(t, &ServerConfig{}, nil, WithStore(path))
+	client := newTestClient(t, raddr, nil)
+	client.exchange(StateMoveMessage{GameState: nil, MoveRow: -1, MoveCount: 4})
+	server.Shutdown(context.Background())
+
+	restarted, raddr := serveOnLoopback(t, &ServerConfig{MoveTTL: 1}, nil, WithStore(path))
+	client.redial(raddr)
+	client.conn.SetReadDeadline(time.Now().Add(3 * time.Second))
+	n, err := client.conn.Read(client.buf)
+	if err != nil {
+		t.Fatalf("no forfeit notice: %v\n", err)
+	}
+	var notice StateMoveMessage
+	if err := UnmarshalMove(client.buf[:n], &notice, defaultMaxBoardRows); err != nil || notice.MoveRow != forfeitMoveRow {
+		t.Errorf("expected a forfeit notice, got %+v (%v)\n", notice, err)
+	}
+	if sess := sessionOf(restarted, client.conn.LocalAddr().String()); sess != nil {
+		t.Errorf("forfeited game still kept: %+v\n", sess)
+	}
+	restarted.Shutdown(context.Background())
+	if sessions, err := loadSessions(path); err != nil || len(sessions) != 0 {
+		t.Errorf("expected the forfeited game forgotten, got %v (%v)\n", sessions, err)
+	}
+	if history, err := LoadGameHistory(historyPath(path)); err != nil || len(history) != 0 {
+		t.Errorf("expected the forfeited game's history forgotten, got %v (%v)\n", history, err)
+	}
+}
+
+// TestPersistLaskerHistory saves a game of Lasker's nim in which the client
+// split a heap, and rebuilds its board from the saved history.
+func TestPersistLaskerHistory(t *testing.T) {
+	path := t.TempDir()
+	server, raddr := serveOnLoopback(t, &ServerConfig{LaskerEnabled: true}, nil, WithStore(path))
+	client := newTestClient(t, raddr, nil)
+	reply := client.exchange(StateMoveMessage{GameState: nil, MoveRow: -1, MoveCount: 3, Variant: nim.LaskerVariant})
+	row := bytes.IndexFunc(reply.GameState, func(coins rune) bool { return coins > 1 })
+	reply = client.exchange(StateMoveMessage{GameState: nim.Split(reply.GameState, row, 1), MoveRow: int8(row)})
+	sess := sessionOf(server, client.conn.LocalAddr().String())
+	server.Shutdown(context.Background())
+	if stats := server.Stats(); stats.InvalidMoves != 0 {
+		t.Fatalf("split rejected: %+v\n", stats)
+	}
+
+	history, err := LoadGameHistory(historyPath(path))
+	if err != nil {
+		t.Fatalf("loading history: %v\n", err)
+	}
+	board, err := RebuildState(history[sess.GameID])
+	if err != nil {
+		t.Fatalf("rebuilding state: %v\n", err)
+	}
+	if !bytes.Equal(board, reply.GameState) {
+		t.Errorf("history rebuilds %v, but the game stands at %v\n", board, reply.GameState)
+	}
+}
+
+// TestPersistWythoffHistory saves a game of Wythoff's game in which the
+// client took from both heaps, and rebuilds its board from the saved
+// history.
+func TestPersistWythoffHistory(t *testing.T) {
+	path := t.TempDir()
+	server, raddr := serveOnLoopback(t, &ServerConfig{WythoffEnabled: true}, nil, WithStore(path))
+	client := newTestClient(t, raddr, nil)
+	reply := client.exchange(StateMoveMessage{GameState: nil, MoveRow: -1, MoveCount: 3, Variant: nim.WythoffVariant})
+	both := []uint8{reply.GameState[0] - 1, reply.GameState[1] - 1}
+	reply = client.exchange(StateMoveMessage{GameState: both, MoveRow: nim.WythoffBothRows, MoveCount: 1})
+	sess := sessionOf(server, client.conn.LocalAddr().String())
+	server.Shutdown(context.Background())
+	if stats := server.Stats(); stats.InvalidMoves != 0 {
+		t.Fatalf("taking from both heaps rejected: %+v\n", stats)
+	}
+
+	history, err := LoadGameHistory(historyPath(path))
+	if err != nil {
+		t.Fatalf("loading history: %v\n", err)
+	}
+	board, err := RebuildState(history[sess.GameID])
+	if err != nil {
+		t.Fatalf("rebuilding state: %v\n", err)
+	}
+	if !bytes.Equal(board, reply.GameState) {
+		t.Errorf("history rebuilds %v, but the game stands at %v\n", board, reply.GameState)
+	}
+}
+
+func TestRebuildStateRejectsIllegalMoves(t *testing.T) {
+	history := []StateMoveMessage{
+		{GameState: []uint8{3, 4, 5}, MoveRow: -1, MoveCount: 2},
+		{GameState: []uint8{3, 1, 5}, MoveRow: 1, MoveCount: 3},
+		{GameState: []uint8{3, 1, 6}, MoveRow: 2, MoveCount: 1},
+	}
+	if board, err := RebuildState(history[:2]); err != nil || !bytes.Equal(board, []uint8{3, 1, 5}) {
+		t.Errorf("expected [3 1 5], got %v (%v)\n", board, err)
+	}
+	if _, err := RebuildState(history); err == nil {
+		t.Errorf("expected an error for a move adding coins\n")
+	}
+	if _, err := RebuildState(nil); err == nil {
+		t.Errorf("expected an error for an empty history\n")
+	}
+}
+
+func TestLoadSessionsFirstStart(t *testing.T) {
+	sessions, err := loadSessions(filepath.Join(t.TempDir(), "missing"))
+	if err != nil || len(sessions) != 0 {
+		t.Errorf("expected no sessions on first start, got %v (%v)\n", sessions, err)
+	}
+}
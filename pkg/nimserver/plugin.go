@@ -0,0 +1,105 @@
+package nimserver
+
+import (
+	"fmt"
+	"io"
+	"sync"
+)
+
+// Plugin receives the server's connection and game events. Hooks run on the
+// main loop, so they should return quickly.
+//
+// UDP has no connections, so a client "connects" when it starts a game while
+// it has none in progress and "disconnects" when that game ends.
+type Plugin interface {
+	OnConnect(raddr string)
+	OnGameStart(raddr, gameID string, board []uint8)
+	// OnMove is called for every move played: the client's valid move and
+	// then the server's reply.
+	OnMove(raddr, gameID string, move StateMoveMessage)
+	OnGameEnd(raddr, gameID, winner string)
+	OnDisconnect(raddr string)
+}
+
+// WithPlugins registers plugins to be called, in order, on each event.
+func WithPlugins(plugins ...Plugin) Option {
+	return func(s *Server) {
+		s.plugins = append(s.plugins, plugins...)
+	}
+}
+
+// LoggingPlugin writes a line per event to Out.
+type LoggingPlugin struct {
+	Out io.Writer
+}
+
+func (p LoggingPlugin) OnConnect(raddr string) {
+	fmt.Fprintf(p.Out, "%v connected\n", raddr)
+}
+
+func (p LoggingPlugin) OnGameStart(raddr, gameID string, board []uint8) {
+	fmt.Fprintf(p.Out, "%v started game %v on board %v\n", raddr, gameID, board)
+}
+
+func (p LoggingPlugin) OnMove(raddr, gameID string, move StateMoveMessage) {
+	fmt.Fprintf(p.Out, "game %v: took %d from row %d, board now %v\n", gameID, move.MoveCount, move.MoveRow, move.GameState)
+}
+
+func (p LoggingPlugin) OnGameEnd(raddr, gameID, winner string) {
+	fmt.Fprintf(p.Out, "game %v with %v won by %v\n", gameID, raddr, winner)
+}
+
+func (p LoggingPlugin) OnDisconnect(raddr string) {
+	fmt.Fprintf(p.Out, "%v disconnected\n", raddr)
+}
+
+// PluginStats are the totals counted by StatsPlugin.
+type PluginStats struct {
+	Connections  int
+	GamesStarted int
+	Moves        int
+	ClientWins   int
+	ServerWins   int
+}
+
+// StatsPlugin counts events; it is safe to read Stats while the server runs.
+type StatsPlugin struct {
+	mu    sync.Mutex
+	stats PluginStats
+}
+
+func (p *StatsPlugin) Stats() PluginStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.stats
+}
+
+func (p *StatsPlugin) OnConnect(raddr string) {
+	p.mu.Lock()
+	p.stats.Connections++
+	p.mu.Unlock()
+}
+
+func (p *StatsPlugin) OnGameStart(raddr, gameID string, board []uint8) {
+	p.mu.Lock()
+	p.stats.GamesStarted++
+	p.mu.Unlock()
+}
+
+func (p *StatsPlugin) OnMove(raddr, gameID string, move StateMoveMessage) {
+	p.mu.Lock()
+	p.stats.Moves++
+	p.mu.Unlock()
+}
+
+func (p *StatsPlugin) OnGameEnd(raddr, gameID, winner string) {
+	p.mu.Lock()
+	if winner == "client" {
+		p.stats.ClientWins++
+	} else {
+		p.stats.ServerWins++
+	}
+	p.mu.Unlock()
+}
+
+func (p *StatsPlugin) OnDisconnect(raddr string) {}
@@ -0,0 +1,79 @@
+package nimserver
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// recordingPlugin logs every hook call as a string.
+type recordingPlugin struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (p *recordingPlugin) add(format string, args ...interface{}) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.events = append(p.events, fmt.Sprintf(format, args...))
+}
+
+func (p *recordingPlugin) OnConnect(raddr string) {
+	p.add("connect")
+}
+
+func (p *recordingPlugin) OnGameStart(raddr, gameID string, board []uint8) {
+	p.add("start %v", board)
+}
+
+func (p *recordingPlugin) OnMove(raddr, gameID string, move StateMoveMessage) {
+	p.add("move %v", move.GameState)
+}
+
+func (p *recordingPlugin) OnGameEnd(raddr, gameID, winner string) {
+	p.add("end %v", winner)
+}
+
+func (p *recordingPlugin) OnDisconnect(raddr string) {
+	p.add("disconnect")
+}
+
+func TestPluginHooks(t *testing.T) {
+	recorder := &recordingPlugin{}
+	stats := &StatsPlugin{}
+	var logs bytes.Buffer
+	_, raddr := startServer(t, &ServerConfig{}, WithPlugins(recorder, stats, LoggingPlugin{&logs}))
+
+	client := newTestClient(t, raddr, nil)
+	winner, replies := client.playGame(4)
+
+	// expected: every board the game passed through, client and server alternating
+	expected := []string{"connect", fmt.Sprintf("start %v", replies[0].GameState)}
+	board := append([]uint8{}, replies[0].GameState...)
+	for _, reply := range replies[1:] {
+		bestMove(board)
+		expected = append(expected, fmt.Sprintf("move %v", board))
+		if reply.MoveRow >= 0 {
+			expected = append(expected, fmt.Sprintf("move %v", reply.GameState))
+			copy(board, reply.GameState)
+		}
+	}
+	expected = append(expected, "end "+winner, "disconnect")
+
+	recorder.mu.Lock()
+	events := recorder.events
+	recorder.mu.Unlock()
+	if strings.Join(events, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("unexpected hook calls:\n%v\nexpected:\n%v\n", strings.Join(events, "\n"), strings.Join(expected, "\n"))
+	}
+
+	got := stats.Stats()
+	if got.Connections != 1 || got.GamesStarted != 1 || got.ClientWins != 1 || got.Moves != len(expected)-4 {
+		t.Errorf("unexpected stats: %+v\n", got)
+	}
+	if !strings.Contains(logs.String(), "won by client") {
+		t.Errorf("logging plugin missed the game end:\n%v", logs.String())
+	}
+}
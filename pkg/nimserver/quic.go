@@ -0,0 +1,177 @@
+package nimserver
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"nimgame/pkg/nimerr"
+	"nimgame/pkg/quicstream"
+	"nimgame/pkg/udpnet"
+
+	"github.com/quic-go/quic-go"
+)
+
+// QUICListener serves games over QUIC as a UDPInterface, so that they are
+// played as over UDP: each frame a client sends on its stream is a packet
+// from the client's address, and what is written to that address is
+// framed onto the stream.
+type QUICListener struct {
+	ln      *quic.Listener
+	packets chan quicPacket
+	done    chan struct{} // closed by Close
+	close   sync.Once
+
+	mu          sync.Mutex
+	streams     map[string]*quic.Stream // by the client's address
+	deadline    time.Time
+	deadlineSet chan struct{} // closed, and replaced, when deadline changes
+}
+
+type quicPacket struct {
+	packet []byte
+	raddr  *net.UDPAddr
+}
+
+// startListenQUIC listens for QUIC connections on config.NimServerAddress.
+func startListenQUIC(config *ServerConfig) (*QUICListener, error) {
+	tlsConf, err := quicstream.ServerTLSConfig()
+	if err != nil {
+		return nil, nimerr.Wrap(nimerr.ErrTransport, fmt.Errorf("making a TLS certificate: %w", err))
+	}
+	addr, err := udpnet.ResolveAddr(config.NimServerAddress, config.IPv6)
+	if err != nil {
+		return nil, nimerr.Wrap(nimerr.ErrConfig, fmt.Errorf("resolving NimServerAddress: %w", err))
+	}
+	ln, err := quic.ListenAddr(addr.String(), tlsConf, nil)
+	if err != nil {
+		return nil, nimerr.Wrap(nimerr.ErrTransport, fmt.Errorf("listening for QUIC on %v: %w", addr, err))
+	}
+	q := &QUICListener{
+		ln:          ln,
+		packets:     make(chan quicPacket),
+		done:        make(chan struct{}),
+		streams:     make(map[string]*quic.Stream),
+		deadlineSet: make(chan struct{}),
+	}
+	go q.accept()
+	return q, nil
+}
+
+// Addr is the address the listener is listening on.
+func (q *QUICListener) Addr() net.Addr {
+	return q.ln.Addr()
+}
+
+func (q *QUICListener) accept() {
+	for {
+		conn, err := q.ln.Accept(context.Background())
+		if err != nil {
+			return // closed
+		}
+		go q.serveConn(conn)
+	}
+}
+
+// serveConn passes the frames of conn's first stream on to ReadFrom until
+// either is closed.
+func (q *QUICListener) serveConn(conn *quic.Conn) {
+	stream, err := conn.AcceptStream(conn.Context())
+	if err != nil {
+		return
+	}
+	raddr, _ := conn.RemoteAddr().(*net.UDPAddr)
+	if raddr == nil {
+		conn.CloseWithError(0, "not a UDP address")
+		return
+	}
+	key := raddr.String()
+	q.mu.Lock()
+	q.streams[key] = stream
+	q.mu.Unlock()
+	defer func() {
+		q.mu.Lock()
+		if q.streams[key] == stream {
+			delete(q.streams, key)
+		}
+		q.mu.Unlock()
+		conn.CloseWithError(0, "")
+	}()
+
+	frames := quicstream.NewFrameReader(stream)
+	for {
+		packet, err := frames.ReadFrame()
+		if err != nil {
+			slog.Debug("QUIC stream ended", "client", key, "err", err)
+			return
+		}
+		select {
+		case q.packets <- quicPacket{packet, raddr}:
+		case <-q.done:
+			return
+		}
+	}
+}
+
+func (q *QUICListener) ReadFrom() ([]byte, *net.UDPAddr, error) {
+	for {
+		q.mu.Lock()
+		deadline, deadlineSet := q.deadline, q.deadlineSet
+		q.mu.Unlock()
+		var timeout <-chan time.Time // nil, so never fires, without a deadline
+		if !deadline.IsZero() {
+			wait := time.Until(deadline)
+			if wait <= 0 {
+				return nil, nil, os.ErrDeadlineExceeded
+			}
+			timer := time.NewTimer(wait)
+			timeout = timer.C
+			defer timer.Stop()
+		}
+		select {
+		case p := <-q.packets:
+			return p.packet, p.raddr, nil
+		case <-timeout:
+			return nil, nil, os.ErrDeadlineExceeded
+		case <-q.done:
+			return nil, nil, net.ErrClosed
+		case <-deadlineSet:
+			// wait again for the new deadline
+		}
+	}
+}
+
+// WriteTo frames packet onto raddr's stream. Packets for clients whose
+// stream has gone are dropped, as UDP would drop them.
+func (q *QUICListener) WriteTo(packet []byte, raddr *net.UDPAddr) {
+	q.mu.Lock()
+	stream := q.streams[raddr.String()]
+	q.mu.Unlock()
+	if stream == nil {
+		slog.Debug("no QUIC stream to reply on", "client", raddr)
+		return
+	}
+	if err := quicstream.WriteFrame(stream, packet); err != nil {
+		fmt.Printf("Error sending QUIC frame to remote address: %v\n", raddr)
+	}
+}
+
+func (q *QUICListener) SetReadDeadline(t time.Time) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.deadline = t
+	close(q.deadlineSet)
+	q.deadlineSet = make(chan struct{})
+	return nil
+}
+
+func (q *QUICListener) Close() {
+	q.close.Do(func() {
+		close(q.done)
+		q.ln.Close()
+	})
+}
@@ -0,0 +1,31 @@
+package nimserver
+
+import (
+	"context"
+	"testing"
+
+	"nimgame/pkg/client"
+	"nimgame/pkg/nim"
+)
+
+func TestPlayOverQUIC(t *testing.T) {
+	_, addr := serveOnLoopback(t, &ServerConfig{QuicEnabled: true}, nil)
+	sess, err := client.NewSession(client.ClientConfig{
+		NimServerAddresses: []string{addr.String()},
+		QuicEnabled:        true,
+	}, nim.Optimal{}, client.WithSeed(4))
+	if err != nil {
+		t.Fatalf("creating session: %v\n", err)
+	}
+	defer sess.Close()
+	result, err := sess.Play(context.Background())
+	if err != nil {
+		t.Fatalf("game over QUIC failed: %v\n", err)
+	}
+	if result.Winner != "client" {
+		t.Errorf("got winner %v, expected client\n", result.Winner)
+	}
+	if result.Retransmissions != 0 {
+		t.Errorf("%d retransmissions over QUIC, expected none\n", result.Retransmissions)
+	}
+}
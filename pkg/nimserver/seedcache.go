@@ -0,0 +1,76 @@
+package nimserver
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+
+	"nimgame/pkg/nim"
+)
+
+// seedCacheGames is how many games PrecomputeSeedCache simulates per seed.
+const seedCacheGames = 100
+
+// PrecomputeSeedCache returns, for every seed, the rate at which an optimal
+// first player beats the basic strategy on the seed's board.
+func PrecomputeSeedCache() map[int8]float64 {
+	cache := make(map[int8]float64, 256)
+	for seed := math.MinInt8; seed <= math.MaxInt8; seed++ {
+		board := nim.GenerateBoard(int64(seed))
+		wins := 0
+		for i := 0; i < seedCacheGames; i++ {
+			if nim.SimulateGame(board, nim.Optimal{}, nim.Basic{}) {
+				wins++
+			}
+		}
+		cache[int8(seed)] = float64(wins) / seedCacheGames
+	}
+	return cache
+}
+
+// loadSeedCache reads the cache saved at path, computing and saving it first
+// if the file doesn't exist yet.
+func loadSeedCache(path string) (map[int8]float64, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		cache := PrecomputeSeedCache()
+		if data, err = json.Marshal(cache); err != nil {
+			return nil, err
+		}
+		return cache, os.WriteFile(path, data, 0644)
+	} else if err != nil {
+		return nil, err
+	}
+	var cache map[int8]float64
+	if err := json.Unmarshal(data, &cache); err != nil {
+		return nil, fmt.Errorf("parsing seed cache %v: %w", path, err)
+	}
+	return cache, nil
+}
+
+// seedQueryHandler answers /query/seed/{seed} with the seed's cached win
+// probability.
+func seedQueryHandler(cache map[int8]float64) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		seed, err := strconv.ParseInt(r.PathValue("seed"), 10, 8)
+		if err != nil {
+			http.Error(w, "seed must be an integer between -128 and 127", http.StatusBadRequest)
+			return
+		}
+		p, ok := cache[int8(seed)]
+		if !ok {
+			http.Error(w, "seed not cached", http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"seed":            seed,
+			"win_probability": p,
+		})
+	}
+}
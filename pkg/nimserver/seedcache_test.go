@@ -0,0 +1,62 @@
+package nimserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+)
+
+func TestSeedCacheDeterministic(t *testing.T) {
+	cache := PrecomputeSeedCache()
+	if len(cache) != 256 {
+		t.Errorf("expected all 256 seeds cached, got %d\n", len(cache))
+	}
+	if p := cache[0]; p != 0 && p != 1 {
+		t.Errorf("optimal play is deterministic, yet seed 0 has win probability %v\n", p)
+	}
+}
+
+func TestLoadSeedCache(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "seed_cache.json")
+	computed, err := loadSeedCache(path)
+	if err != nil {
+		t.Fatalf("computing seed cache: %v\n", err)
+	}
+	loaded, err := loadSeedCache(path)
+	if err != nil {
+		t.Fatalf("loading seed cache: %v\n", err)
+	}
+	for seed, p := range computed {
+		if loaded[seed] != p {
+			t.Errorf("seed %d: loaded %v, computed %v\n", seed, loaded[seed], p)
+		}
+	}
+}
+
+func TestSeedQuery(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.Handle("GET /query/seed/{seed}", seedQueryHandler(map[int8]float64{-3: 1}))
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest("GET", "/query/seed/-3", nil))
+	var resp struct {
+		Seed           int8    `json:"seed"`
+		WinProbability float64 `json:"win_probability"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decoding response: %v\n", err)
+	}
+	if resp.Seed != -3 || resp.WinProbability != 1 {
+		t.Errorf("unexpected response %+v\n", resp)
+	}
+
+	for _, path := range []string{"/query/seed/200", "/query/seed/x", "/query/seed/4"} {
+		rec = httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+		if rec.Code == http.StatusOK {
+			t.Errorf("%v: expected an error status\n", path)
+		}
+	}
+}
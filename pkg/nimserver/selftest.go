@@ -0,0 +1,41 @@
+package nimserver
+
+import "nimgame/pkg/nim"
+
+// ValidateBoardCorpus returns the seeds, of seeds, whose board as cfg makes
+// it the client can't win by moving first: one with a zero nim-sum, which
+// GenerateBoard's correction must never leave. A nil cfg checks the
+// generated boards.
+func ValidateBoardCorpus(seeds []int8, cfg *BoardConfig) []int8 {
+	var failing []int8
+	for _, seed := range seeds {
+		board := cfg.board(seed)
+		if nim.NimSum(board) == 0 {
+			failing = append(failing, seed)
+		}
+	}
+	return failing
+}
+
+// selfTestSeeds are the seeds RunSelfTest checks the boards of.
+func selfTestSeeds() []int8 {
+	seeds := make([]int8, 0, 128)
+	for seed := 0; seed <= 127; seed++ {
+		seeds = append(seeds, int8(seed))
+	}
+	return seeds
+}
+
+// RunSelfTest checks the boards of seeds 0 to 127 with ValidateBoardCorpus
+// before any game is served, logging every seed the client can't win from
+// and returning them. New runs it once the dataset is loaded.
+func (s *Server) RunSelfTest() []int8 {
+	if g := s.config.BoardGuarantee; g != "" && g != nim.GuaranteeFirst {
+		return nil // boards meant to be lost, or left as drawn
+	}
+	failing := ValidateBoardCorpus(selfTestSeeds(), s.boardConfig())
+	for _, seed := range failing {
+		s.logger().Warn("board has no winning first move", "seed", seed, "board", s.newBoard(seed, "", s.config.BoardProfile, ""))
+	}
+	return failing
+}
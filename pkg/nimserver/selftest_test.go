@@ -0,0 +1,32 @@
+package nimserver
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestValidateBoardCorpusDefaults(t *testing.T) {
+	if failing := ValidateBoardCorpus(selfTestSeeds(), nil); len(failing) != 0 {
+		t.Errorf("generated boards for seeds %v have no winning first move\n", failing)
+	}
+	stochastic := &BoardConfig{StochasticMode: true}
+	if failing := ValidateBoardCorpus(selfTestSeeds(), stochastic); len(failing) != 0 {
+		t.Errorf("stochastic boards for seeds %v have no winning first move\n", failing)
+	}
+}
+
+func TestValidateBoardCorpusDataset(t *testing.T) {
+	cfg := &BoardConfig{Dataset: map[int8][]uint8{
+		1: {3, 5, 6},    // nim-sum 0
+		2: {1, 1, 1, 1}, // even ones
+		3: {1, 1, 1},
+	}}
+	if failing := ValidateBoardCorpus([]int8{0, 1, 2, 3}, cfg); !slices.Equal(failing, []int8{1, 2}) {
+		t.Errorf("failing seeds %v, expected [1 2]\n", failing)
+	}
+
+	server := newServer(&ServerConfig{}, nil, nil, WithDataset(cfg.Dataset))
+	if failing := server.RunSelfTest(); !slices.Equal(failing, []int8{1, 2}) {
+		t.Errorf("self test failed seeds %v, expected [1 2]\n", failing)
+	}
+}
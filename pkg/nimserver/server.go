@@ -0,0 +1,1171 @@
+package nimserver
+
+import (
+	"bytes"
+	"context"
+	crand "crypto/rand"
+	"encoding/gob"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"net"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"nimgame/pkg/netcond"
+	"nimgame/pkg/nim"
+	"nimgame/pkg/nimerr"
+	"nimgame/pkg/udpnet"
+
+	"github.com/DistributedClocks/tracing"
+	"google.golang.org/grpc/health"
+)
+
+/** Config struct **/
+
+type ServerConfig struct {
+	// an address in IPv6 notation, "[::1]:41600" say, is listened on over
+	// IPv6; IPv6 does so for host names too, rather than IPv4, and for an
+	// empty host, rather than both
+	NimServerAddress string `env:"SERVER_ADDRESS"`
+	IPv6             bool
+
+	// moves are traced to TracingServerAddress as TracingIdentity when set;
+	// empty disables tracing
+	TracingServerAddress string
+	Secret               []byte
+	TracingIdentity      string
+
+	// game events are POSTed to WebhookURL when set; an empty WebhookEvents
+	// list subscribes to every event
+	WebhookURL    string
+	WebhookEvents []string
+
+	// clients monitoring this server send heartbeats here; empty disables
+	FCheckAckLocalAddr string
+
+	// "rle" run-length encodes boards in replies; empty sends them as-is
+	CompressionMode string
+
+	// operator HTTP endpoints (/metrics, ...) listen here; empty disables
+	AdminAddress string
+	// games can be played over HTTP and WebSocket on AdminAddress too, for
+	// trying the server out with curl or from a browser
+	AdminGamesEnabled bool
+	// a WebSocket client dropping mid-game resigns it after this many
+	// seconds unless it resumes the game; zero means defaultWebSocketGrace
+	WebSocketGrace int
+
+	// debug, info, warn or error; empty means info
+	LogLevel string
+
+	// moves waiting to be handled beyond this many are dropped; zero means
+	// defaultQueueDepth
+	QueueDepth int
+
+	// gRPC services, health checks and the NimGame service, listen here;
+	// empty disables
+	GRPCAddress string
+
+	// the server reports itself unhealthy while this many games are in
+	// progress; zero means no limit
+	MaxClients int
+
+	// per-seed win probabilities are loaded from here, or computed and saved
+	// here on first start, and served at /query/seed/{seed}; empty disables
+	SeedCacheFile string
+
+	// game starts and ends are published to KafkaTopic on these
+	// comma-separated brokers when set
+	KafkaBootstrapServers string
+	KafkaTopic            string
+
+	// fraction (0-1) of moves whose handling is traced; unset traces every
+	// move
+	TracingSampleRate *float64
+
+	// time every server move, warning about those taking longer than
+	// MaxMoveComputeMs; zero never warns
+	MoveTimingEnabled bool
+	MaxMoveComputeMs  int
+
+	// moves carrying a board with more rows, or a row with more coins, are
+	// rejected; zero means defaultMaxBoardRows and defaultMaxCoinsPerRow
+	MaxBoardRows   int
+	MaxCoinsPerRow uint8
+
+	// when enabled each new game plays bestMove with probability
+	// ABTestRatio (0-1) and normalMove otherwise, whatever its seed
+	ABTestEnabled bool
+	ABTestRatio   float64
+
+	// boards for particular seeds are loaded from here (see LoadDataset);
+	// other seeds, or all when empty, get generated boards
+	DatasetFile string
+
+	// a directory each session is saved to as it changes, and restored
+	// from on start, with the move histories of their games saved beside
+	// it, with ".history" appended (see LoadGameHistory); empty disables
+	PersistPath string
+
+	// a client that hasn't answered the server's move after MoveTTL seconds
+	// forfeits the game; zero waits forever
+	MoveTTL int
+
+	// once Run's context is done, on SIGTERM say, the server drains for up
+	// to DrainTimeout seconds: it refuses new games but plays on until the
+	// games in progress end; zero stops at once
+	DrainTimeout int
+
+	// games are played over QUIC streams, rather than bare UDP packets, on
+	// NimServerAddress
+	QuicEnabled bool
+
+	// games are also played over TCP here, for clients whose network
+	// blocks UDP, one game to a connection; empty disables
+	TCPAddress string
+
+	// games are also played over DTLS here, encrypted, for playing across
+	// the open internet; empty disables. The server is authenticated by
+	// the PEM certificate and key in DTLSCertFile and DTLSKeyFile or,
+	// without them, both sides by a key derived from DTLSPSK, which
+	// clients must share.
+	DTLSAddress  string
+	DTLSCertFile string
+	DTLSKeyFile  string
+	DTLSPSK      []byte
+
+	// StochasticMode generates boards with rows of k coins weighted by
+	// k^-StochasticAlpha, mostly short rows, rather than the seed's usual
+	// board; zero alpha means defaultStochasticAlpha. Clients verifying the
+	// initial board will find it isn't the seed's.
+	StochasticMode  bool
+	StochasticAlpha float64
+
+	// LaskerEnabled plays Lasker's nim, in which a move may split a heap in
+	// two, with clients asking for it at GameStart; they play nim otherwise
+	LaskerEnabled bool
+
+	// WythoffEnabled plays Wythoff's game, on two heaps, taking from one or
+	// as many from both, with clients asking for it at GameStart
+	WythoffEnabled bool
+
+	// DryRun answers every message without keeping any game: moves are
+	// only checked against the board they say they were made on, not a real
+	// game's, and answered, every reply marked DryRun, for trying out clients; games over gRPC,
+	// HTTP and WebSocket, which need a game kept, can't be played
+	DryRun bool
+
+	// GameVariant is the game played with clients that don't ask for an
+	// enabled variant at GameStart: "nim", the default, or "wythoff" for
+	// Wythoff's game, which clients that ignore a reply's Variant can't play
+	GameVariant string
+
+	// MinGameLength replaces generated boards that two naive players, taking
+	// a coin a move, would finish in fewer moves with the board of the
+	// next seed that lasts, trying up to MaxSeedRetries (0 means 100); a
+	// MinGameLength of 0 plays every seed's board.
+	MinGameLength  int
+	MaxSeedRetries int
+
+	// BoardProfile generates boards of one shape, one of nim.BoardProfiles,
+	// in place of the seed's usual board and StochasticMode's, for games
+	// whose GameStart doesn't ask for another; BoardProfileWeights are the
+	// "custom" profile's, weighting rows of 1, 2, ... coins. Clients asking
+	// for "custom" get the usual board without them.
+	BoardProfile        string
+	BoardProfileWeights []float64
+
+	// BoardGuarantee, one of nim.BoardGuarantees, says who generated boards
+	// are won by with perfect play, for games whose GameStart doesn't ask:
+	// "first", the client, as by default, "second", the server, for
+	// positions meant to be lost, or "random", leaving boards as drawn
+	BoardGuarantee string
+}
+
+// sampleRate is TracingSampleRate, defaulting to 1.
+func (config *ServerConfig) sampleRate() float64 {
+	if config.TracingSampleRate == nil {
+		return 1
+	}
+	return *config.TracingSampleRate
+}
+
+const defaultQueueDepth = 64
+
+const defaultStochasticAlpha = 1.5
+
+const defaultWebSocketGrace = 30
+
+// webSocketGrace is WebSocketGrace, defaulting to defaultWebSocketGrace.
+func (config *ServerConfig) webSocketGrace() time.Duration {
+	if config.WebSocketGrace == 0 {
+		return defaultWebSocketGrace * time.Second
+	}
+	return time.Duration(config.WebSocketGrace) * time.Second
+}
+
+// The largest board nim.GenerateBoard makes.
+const (
+	defaultMaxBoardRows   = 16
+	defaultMaxCoinsPerRow = 10
+)
+
+// maxBoardRows is MaxBoardRows, defaulting to defaultMaxBoardRows.
+func (config *ServerConfig) maxBoardRows() int {
+	if config.MaxBoardRows <= 0 {
+		return defaultMaxBoardRows
+	}
+	return config.MaxBoardRows
+}
+
+// maxCoinsPerRow is MaxCoinsPerRow, defaulting to defaultMaxCoinsPerRow.
+func (config *ServerConfig) maxCoinsPerRow() uint8 {
+	if config.MaxCoinsPerRow == 0 {
+		return defaultMaxCoinsPerRow
+	}
+	return config.MaxCoinsPerRow
+}
+
+/** Tracing structs **/
+
+type ClientMoveReceive StateMoveMessage
+
+type ServerMove StateMoveMessage
+
+// InvalidMove records a client move that failed validation, answered with
+// the last reply again. ErrorCode says why: InvalidMoveIllegal or
+// InvalidMoveTooLarge.
+type InvalidMove struct {
+	GameState  []uint8
+	MoveRow    int8
+	MoveCount  int8
+	ErrorCode  int8
+	ClientAddr string
+}
+
+// BoardGuarantee records the guarantee a new game's board was generated to
+// make good, and its nim sum: non-zero when the first player can win.
+type BoardGuarantee struct {
+	GameID    string
+	Guarantee string
+	NimSum    uint8
+}
+
+// InvalidMove error codes.
+const (
+	InvalidMoveIllegal  int8 = 1 // not a legal move from the last board sent
+	InvalidMoveTooLarge int8 = 2 // the board is larger than the config allows; see ErrBoardTooLarge
+)
+
+// GameComplete records the end of a game the client didn't play out, won
+// by the server. Reason is reasonTimeout for a client out of time, and
+// empty for one that forfeited or left.
+type GameComplete struct {
+	Winner string
+	Reason string
+}
+
+// MatchComplete records the final score of a best-of-Games match, once the
+// game deciding it ends.
+type MatchComplete struct {
+	MatchID    string
+	Games      int8
+	ClientWins int8
+	ServerWins int8
+	Winner     string
+}
+
+/** Message structs **/
+
+type StateMoveMessage struct {
+	GameState         []uint8
+	MoveRow           int8
+	MoveCount         int8
+	TracingServerAddr string
+	Token             tracing.TracingToken
+	RLEEncoded        bool     // GameState is run-length encoded, see nim.RLEEncode
+	MerkleRoot        [32]byte // nim.ComputeMerkleRoot of the decoded GameState; zero if not sent
+	// the game a reply belongs to, which a client sends back to resume it
+	// from a new address; see sessionResumeMoveRow
+	GameID string
+	// nim.LaskerVariant or nim.WythoffVariant in a GameStart asking for
+	// Lasker's nim or Wythoff's game, and in every reply of a game the
+	// server plays it in; empty for nim
+	Variant string
+	// one of nim.BoardProfiles in a GameStart asking for boards of its
+	// shape; empty for the server's
+	BoardProfile string
+	// one of nim.BoardGuarantees in a GameStart asking for a board won by
+	// that player; empty for the server's
+	BoardGuarantee string
+	// in a GameStart, the N of a best-of-N match to play, or carry on, from
+	// the client's address; zero plays a single game. Every reply in a
+	// match carries its N and the games each side has won so far, as does
+	// the MatchComplete message, with the final score; see Match
+	MatchGames      int8
+	MatchClientWins int8
+	MatchServerWins int8
+	// in a GameStart, each side's time for the game in milliseconds, asking
+	// for time control; zero plays without clocks. Every reply in such a
+	// game carries it, the milliseconds left on each side's clock, and its
+	// Seq, which the client's move answering it carries back; see Clock
+	TimeControl int32
+	ClientClock int32
+	ServerClock int32
+	Seq         uint32
+	// set in every reply of a server in DryRun, which keeps nothing of
+	// the game
+	DryRun bool
+}
+
+// UDPInterface is the socket the server plays over: a *UDPConnection, or
+// a MockUDPConn in tests.
+type UDPInterface interface {
+	// ReadFrom reads the next packet, which is only valid until the next
+	// call.
+	ReadFrom() (packet []byte, raddr *net.UDPAddr, err error)
+	WriteTo(packet []byte, raddr *net.UDPAddr)
+	// SetReadDeadline cuts a pending ReadFrom short at t.
+	SetReadDeadline(t time.Time) error
+	Close()
+}
+
+type UDPConnection struct {
+	Conds *netcond.Conditioner // what replies go through; nil sends them untouched
+	Conn  *net.UDPConn
+	BufIn []byte
+}
+
+func (udp *UDPConnection) Close() {
+	udp.Conn.Close()
+}
+
+// ReadFrom reads the next packet into BufIn, returning the part of it read.
+func (udp *UDPConnection) ReadFrom() (packet []byte, raddr *net.UDPAddr, err error) {
+	n, raddr, err := udp.Conn.ReadFromUDP(udp.BufIn)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error receiving connection: %v\n", err)
+	}
+	return udp.BufIn[:n], raddr, err
+}
+
+func (udp *UDPConnection) SetReadDeadline(t time.Time) error {
+	return udp.Conn.SetReadDeadline(t)
+}
+
+func (udp *UDPConnection) WriteTo(packet []byte, raddr *net.UDPAddr) {
+	if udp.Conds != nil {
+		udp.Conds.Send(packet, func(p []byte) { udp.writeTo(p, raddr) })
+		return
+	}
+	udp.writeTo(packet, raddr)
+}
+
+func (udp *UDPConnection) writeTo(packet []byte, raddr *net.UDPAddr) {
+	_, err := udp.Conn.WriteToUDP(packet, raddr)
+	if err != nil {
+		fmt.Printf("Error sending UDP packet to remote address: %v\n", raddr)
+	}
+}
+
+func UDPAdapter(conn *net.UDPConn, bufsize int) *UDPConnection {
+	buf := make([]byte, bufsize)
+	return &UDPConnection{nil, conn, buf}
+}
+
+type Server struct {
+	config *ServerConfig
+	tracer *tracing.Tracer // nil when tracing is disabled
+	trace  *tracing.Trace  // used for messages that arrive without a token
+	udp    UDPInterface
+	addr   net.Addr // where udp listens, once New has listened
+	log    *slog.Logger
+
+	webhooks *webhookNotifier
+	saver    *sessionSaver // nil without a PersistPath
+	notifier GameNotifier
+	plugins  []Plugin
+	dataset  map[int8][]uint8 // boards by seed, used in place of generated ones
+	choose   func(seed int8) int8
+	conds    *netcond.Conditioner
+	now      func() time.Time
+	health   *health.Server
+
+	// what New opened for Run to serve, and close when it is done
+	ownTracer bool // the tracer was connected by New rather than given
+	seedCache map[int8]float64
+	adminLis  net.Listener // nil without an AdminAddress
+	grpcLis   net.Listener // nil without a GRPCAddress
+	tcpLis    net.Listener // nil without a TCPAddress
+	dtlsLis   net.Listener // nil without a DTLSAddress
+	connsMu   sync.Mutex
+	conns     map[net.Conn]bool // games being played over TCP and DTLS; nil once closed
+	runMu     sync.Mutex
+	ran       bool                    // Run has been called, or Shutdown called first
+	stopRun   context.CancelCauseFunc // cancels Run's context, for Shutdown
+	finished  chan struct{}           // closed once Run returns
+	draining  atomic.Bool             // GameStarts are refused, see drain
+
+	// packets read off the socket, waiting for the worker
+	incomingMoves chan incomingPacket
+	recent        *dedupCache // only touched while holding gameMu
+
+	// every client's game, by raddr, or for games over TCP, gRPC, HTTP and
+	// WebSocket by a key beginning with their prefix (see keyTransport);
+	// sessions are only modified while holding gameMu, by the worker or a
+	// forfeit, sessionsMu guards the map itself
+	sessions   map[string]*GameSession
+	sessionsMu sync.Mutex
+	gameMu     sync.Mutex
+
+	statsMu sync.Mutex
+	stats   Stats
+}
+
+// incomingPacket is a packet read from raddr at receivedAt.
+type incomingPacket struct {
+	packet     []byte
+	raddr      *net.UDPAddr
+	receivedAt time.Time
+}
+
+// newServer returns a server for config playing over udp, traced by tracer
+// unless it is nil. New builds on it, connecting and listening as the
+// config says.
+func newServer(config *ServerConfig, tracer *tracing.Tracer, udp UDPInterface, opts ...Option) *Server {
+	s := &Server{
+		config:   config,
+		tracer:   tracer,
+		udp:      udp,
+		sessions: make(map[string]*GameSession),
+		now:      time.Now,
+		recent:   newDedupCache(dedupMaxSize, dedupTTL),
+		health:   newHealthServer(),
+		finished: make(chan struct{}),
+		conns:    make(map[net.Conn]bool),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	queueDepth := s.config.QueueDepth
+	if queueDepth <= 0 {
+		queueDepth = defaultQueueDepth
+	}
+	s.incomingMoves = make(chan incomingPacket, queueDepth)
+	s.webhooks = newWebhookNotifier(s.config)
+	s.saver = newSessionSaver(s.config, s.logger())
+	if s.notifier == nil {
+		s.notifier = newNotifier(s.config)
+	}
+	if s.tracer != nil {
+		s.trace = s.tracer.CreateTrace()
+	}
+	s.updateHealth() // restored games count against MaxClients
+	return s
+}
+
+func (s *Server) logger() *slog.Logger {
+	if s.log == nil {
+		return slog.Default()
+	}
+	return s.log
+}
+
+// ErrCanceled is returned by Serve when its context is done.
+var ErrCanceled = errors.New("server canceled")
+
+// Serve handles incoming moves until the UDP connection is closed, when it
+// returns nil, or ctx is done, when the pending read is cut short and it
+// returns ErrCanceled. Packets are queued for a worker goroutine as they are
+// read; once the queue is full further packets are dropped, leaving the
+// client to retransmit. Moves already queued are handled before Serve
+// returns.
+func (s *Server) Serve(ctx context.Context) error {
+	// once ctx is done, the read loop plays on while the server drains,
+	// until cut is closed
+	served := make(chan struct{})
+	defer close(served)
+	cut := make(chan struct{})
+	stop := context.AfterFunc(ctx, func() {
+		s.drain(served)
+		close(cut)
+		s.udp.SetReadDeadline(time.Now())
+	})
+	defer stop()
+
+	// a single worker, since game state is not safe for concurrent use
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for in := range s.incomingMoves {
+			queueDepth.Set(float64(len(s.incomingMoves)))
+			s.handleMove(in.packet, in.raddr, in.receivedAt)
+		}
+	}()
+	defer func() {
+		close(s.incomingMoves)
+		<-done
+		s.stopMoveTimers()
+	}()
+
+	for {
+		// remember to have a timeout on this
+		packet, raddr, err := s.udp.ReadFrom()
+		select {
+		case <-cut:
+			return fmt.Errorf("%w: %w", ErrCanceled, context.Cause(ctx))
+		default:
+		}
+		if errors.Is(err, net.ErrClosed) {
+			return nil
+		} else if err != nil {
+			continue
+		}
+		in := incomingPacket{
+			packet:     append([]byte(nil), packet...),
+			raddr:      raddr,
+			receivedAt: s.now(),
+		}
+		select {
+		case s.incomingMoves <- in:
+			queueDepth.Set(float64(len(s.incomingMoves)))
+		default:
+			fmt.Fprintf(os.Stderr, "DROP packet from %v: move queue full\n", raddr)
+			droppedMoves.Inc()
+			s.count(func(st *Stats) { st.Dropped++ })
+		}
+	}
+}
+
+// syncMoveRow marks a client's request for the server's last move, sent by
+// a client resuming its game after a crash in place of GameStart.
+const syncMoveRow = -3
+
+// sessionResumeMoveRow marks a client's request, carrying the GameID of
+// its game, to carry on the game from the address it is sent from, after
+// losing its socket. It is answered as Sync is.
+const sessionResumeMoveRow = -13
+
+// handleMove processes one packet from raddr, which was read at receivedAt.
+func (s *Server) handleMove(packet []byte, raddr *net.UDPAddr, receivedAt time.Time) {
+	s.gameMu.Lock()
+	defer s.gameMu.Unlock()
+	s.answer(packet, raddr.String(), receivedAt, func(reply []byte) { s.udp.WriteTo(reply, raddr) })
+}
+
+// answer plays packet, read at receivedAt from the client whose game is
+// kept under raddr, and sends the reply with send, which also sends any
+// forfeit notice. The caller holds gameMu.
+func (s *Server) answer(packet []byte, raddr string, receivedAt time.Time, send func(reply []byte)) {
+	s.logger().Debug("packet received", "raddr", raddr)
+	clientMove := StateMoveMessage{}
+	err := UnmarshalMove(packet, &clientMove, s.config.maxBoardRows())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error unmarshalling message from connection: %v\n", err)
+		return
+	}
+	// clients put a fresh token in every send, so an exact copy of a recent
+	// packet carrying one, from any address, is a replay rather than a
+	// retransmission or another client's identical move, which packets
+	// without one can't be told from
+	if clientMove.Token != nil && s.recent.seen(packet, receivedAt) {
+		s.logger().Debug("dropping duplicate packet", "raddr", raddr)
+		dedupDrops.Inc()
+		s.count(func(st *Stats) { st.Dropped++ })
+		return
+	}
+
+	// continue the client's trace if it sent us a token; moves left out of
+	// the sample, or every move when tracing is disabled, aren't traced at
+	// all and the reply carries no token
+	sampled := s.tracer != nil && rand.Float64() < s.config.sampleRate()
+	trace := s.trace
+	if sampled && clientMove.Token != nil {
+		trace = s.tracer.ReceiveToken(clientMove.Token)
+	}
+	if sampled {
+		trace.RecordAction(ClientMoveReceive(clientMove))
+	}
+
+	res := s.respond(raddr, clientMove, receivedAt)
+	if res == nil {
+		return
+	}
+	servMove, sess := res.reply, res.sess
+	if sampled {
+		if res.rejected {
+			trace.RecordAction(invalidMove(clientMove, res.invalid, raddr))
+		}
+		if res.guarantee != "" {
+			trace.RecordAction(BoardGuarantee{GameID: servMove.GameID, Guarantee: res.guarantee, NimSum: nim.NimSum(servMove.GameState)})
+		}
+		trace.RecordAction(ServerMove(servMove))
+		servMove.Token = trace.GenerateToken()
+	}
+
+	if servMove.GameState != nil {
+		servMove.MerkleRoot = nim.ComputeMerkleRoot(servMove.GameState)
+	}
+	var bufOut []byte
+	bufOut, err = MarshalMove(servMove, s.config.CompressionMode)
+	if err != nil {
+		// the client retransmits, and gets the saved move resent
+		fmt.Fprintf(os.Stderr, "Error marshalling reply to %v: %v\n", raddr, err)
+		return
+	}
+
+	// At this point buf contains a reply that we send back to the raddr.
+	send(bufOut)
+	if sess == nil {
+		// refused while draining, or answered in a dry run
+		return
+	}
+	// resends leave the timers running
+	if res.awaitMove {
+		s.startMoveTimer(raddr, sess, send)
+		s.startFlagTimer(raddr, sess, send)
+	}
+	if res.match != nil {
+		s.completeMatch(raddr, servMove.GameID, res.match, trace, send)
+	}
+
+	latency := sess.recordReply(receivedAt, s.now())
+	moveLatency.Observe(latency.Seconds())
+	if res.winner != "" {
+		stats := SessionLatencyStats(sess)
+		s.logger().Info("game move latency", "game", sess.GameID, "min", stats.Min, "max", stats.Max, "mean", stats.Mean, "p99", stats.P99)
+	}
+}
+
+// moveResult is what a message from a client came to.
+type moveResult struct {
+	reply     StateMoveMessage // saved as the session's LastMove
+	sess      *GameSession
+	rejected  bool   // the client's move was illegal, so the last reply is sent again
+	invalid   int8   // why it was rejected, as an InvalidMove error code
+	guarantee string // the new board's nim.BoardGuarantees, for a GameStart
+	awaitMove bool   // the reply is a move the client has MoveTTL to answer
+	winner    string // set when the message ended the game
+	match     *Match // set when the game that ended decided its match
+	refused   bool   // a GameStart refused while draining, so sess is nil
+}
+
+// respond plays clientMove, read at receivedAt from the client at raddr,
+// and saves the reply, returning nil for a message to ignore. raddr is
+// the client's UDP address, or for a game over TCP, gRPC, HTTP or
+// WebSocket a key of its own. The caller holds gameMu.
+func (s *Server) respond(raddr string, clientMove StateMoveMessage, receivedAt time.Time) *moveResult {
+	if clientMove.GameState == nil && clientMove.MoveRow == -1 && s.draining.Load() {
+		return s.refuseGame(raddr)
+	}
+	if s.config.DryRun {
+		return s.dryRun(raddr, clientMove)
+	}
+	// check if there's an ongoing game for the sender
+	sess := s.session(raddr)
+	resuming := clientMove.GameState == nil && clientMove.MoveRow == sessionResumeMoveRow
+	if resuming {
+		sess = s.moveSession(clientMove.GameID, raddr)
+	}
+	var servMove StateMoveMessage
+	var gameID, winner string
+	awaitMove := false // the reply is a move the client has MoveTTL to answer
+	changed := false   // the game moved on, so the session is saved again
+	rejected := false
+	var invalid int8
+	var guarantee string // the new game's board's, at GameStart
+	// GameStart message
+	if clientMove.GameState == nil && clientMove.MoveRow == -1 {
+		// new game
+		seed := clientMove.MoveCount
+		variant := s.variant(clientMove.Variant)
+		guarantee = s.boardGuarantee(clientMove.BoardGuarantee)
+		newGameState := s.newBoard(seed, variant, s.boardProfile(clientMove.BoardProfile), guarantee)
+		servMove = StateMoveMessage{
+			GameState: newGameState,
+			MoveRow:   -1,
+			MoveCount: seed,
+		}
+		gameID = newGameID()
+		s.count(func(st *Stats) { st.GamesStarted++ })
+		sess = s.startSession(raddr, gameID, s.chooseDifficulty(seed))
+		sess.Variant = variant
+		sess.Match = joinMatch(sess, clientMove.MatchGames)
+		sess.Clock = newClock(clientMove.TimeControl)
+		sess.record(servMove)
+		awaitMove = true
+		changed = true
+		if !sess.Playing {
+			sess.Playing = true
+			s.updateHealth()
+			for _, p := range s.plugins {
+				p.OnConnect(raddr)
+			}
+		}
+		for _, p := range s.plugins {
+			p.OnGameStart(raddr, gameID, newGameState)
+		}
+		s.notifier.NotifyGameStart(gameID, raddr, newGameState)
+		s.webhooks.notify(EventGameStart, gameID, raddr, map[string]interface{}{
+			"seed":  seed,
+			"board": newGameState,
+		})
+		if sess.Match != nil && sess.Match.serverOpens() {
+			servMove = s.openGame(raddr, sess, newGameState)
+			if sess.Clock != nil {
+				sess.Clock.think(receivedAt, s.now())
+			}
+			if winner = gameWinner(servMove); winner != "" {
+				s.endGame(raddr, sess, winner)
+				awaitMove = false
+			}
+		}
+	} else if sess == nil {
+		// not a GameStart message and no ongoing games
+		// ignore the ill-formed message
+		return nil
+	} else if clientMove.GameState == nil && (clientMove.MoveRow == syncMoveRow || resuming) {
+		// resend where the game stands, leaving it as it is
+		gameID = sess.GameID
+		servMove = sess.LastMove
+		// or, once its match is over, the MatchComplete message, which may
+		// have been lost
+		if m := sess.Match; m != nil && m.decided() && !sess.Playing && !resuming {
+			servMove = StateMoveMessage{MoveRow: matchCompleteMoveRow, MoveCount: matchCompleteMoveRow}
+		}
+		// a resumed game's move timer forfeits it at the old address
+		awaitMove = resuming && sess.Playing
+		changed = resuming
+	} else if sess.Clock != nil && sess.Clock.replayed(clientMove.Seq) {
+		// a retransmitted copy of a move already played, whose reply was
+		// lost: resend it
+		gameID = sess.GameID
+		servMove = sess.LastMove
+	} else {
+		gameID = sess.GameID
+		ver, err := CheckMove(clientMove, sess.LastMove, s.config)
+		if err != nil {
+			s.logger().Warn("rejected move", "client", raddr, "game", gameID, "err", err)
+		}
+		if !ver {
+			servMove = sess.LastMove
+			rejected = true
+			invalid = InvalidMoveIllegal
+			if errors.Is(err, ErrBoardTooLarge) {
+				invalid = InvalidMoveTooLarge
+			}
+			s.count(func(st *Stats) { st.InvalidMoves++ })
+			s.webhooks.notify(EventInvalidMove, gameID, raddr, map[string]interface{}{
+				"move":  clientMove,
+				"board": sess.LastMove.GameState,
+			})
+		} else if sess.Clock != nil && !sess.Clock.charge(receivedAt) {
+			// the move came too late, the client's clock having run out
+			s.logger().Info("client ran out of time", "client", raddr, "game", gameID, "budget", sess.Clock.Budget)
+			sess.stopMoveTimer()
+			s.resign(raddr, sess, reasonTimeout)
+			servMove = StateMoveMessage{MoveRow: forfeitMoveRow, MoveCount: forfeitMoveRow}
+			winner = "server"
+		} else {
+			sess.stopMoveTimer()
+			s.notifyMove(raddr, gameID, clientMove)
+			sess.MoveCount++
+			sess.record(clientMove)
+			clientMove.Variant = sess.Variant
+			servMove = s.play(clientMove, sess.Difficulty)
+			if sess.Clock != nil {
+				sess.Clock.think(receivedAt, s.now())
+			}
+			moves := 1
+			if servMove.MoveRow >= 0 {
+				s.notifyMove(raddr, gameID, servMove)
+				sess.MoveCount++
+				sess.record(servMove)
+				moves++
+			}
+			s.count(func(st *Stats) { st.Moves += moves })
+			changed = true
+			if winner = gameWinner(servMove); winner != "" {
+				s.endGame(raddr, sess, winner)
+			} else {
+				awaitMove = true
+			}
+		}
+	}
+	sess.LastSeen = receivedAt
+
+	// save the game
+	servMove.TracingServerAddr = s.config.TracingServerAddress
+	servMove.GameID = gameID
+	servMove.Variant = sess.Variant
+	var decided *Match
+	if m := sess.Match; m != nil {
+		m.score(&servMove)
+		if winner != "" && m.decided() {
+			decided = m
+		}
+	}
+	if sess.Clock != nil {
+		sess.Clock.stamp(&servMove, s.now())
+	}
+	sess.LastMove = servMove
+	if changed {
+		s.persist(raddr)
+	}
+	return &moveResult{reply: servMove, sess: sess, rejected: rejected, invalid: invalid, guarantee: guarantee, awaitMove: awaitMove, winner: winner, match: decided}
+}
+
+// respondTraced responds to move in the game kept under key, a game over
+// gRPC, HTTP or WebSocket, tracing it as handleMove would a move with no token. The
+// caller holds gameMu.
+func (s *Server) respondTraced(key string, move StateMoveMessage) *moveResult {
+	sampled := s.tracer != nil && rand.Float64() < s.config.sampleRate()
+	if sampled {
+		s.trace.RecordAction(ClientMoveReceive(move))
+	}
+	res := s.respond(key, move, s.now())
+	if sampled && res != nil {
+		if res.rejected {
+			s.trace.RecordAction(invalidMove(move, res.invalid, key))
+		}
+		if res.guarantee != "" {
+			s.trace.RecordAction(BoardGuarantee{GameID: res.reply.GameID, Guarantee: res.guarantee, NimSum: nim.NimSum(res.reply.GameState)})
+		}
+		s.trace.RecordAction(ServerMove(res.reply))
+	}
+	if res != nil && res.match != nil {
+		s.completeMatch(key, res.reply.GameID, res.match, s.trace, nil)
+	}
+	return res
+}
+
+// invalidMove is the InvalidMove action recording move, rejected with code,
+// from the client at addr.
+func invalidMove(move StateMoveMessage, code int8, addr string) InvalidMove {
+	return InvalidMove{
+		GameState:  move.GameState,
+		MoveRow:    move.MoveRow,
+		MoveCount:  move.MoveCount,
+		ErrorCode:  code,
+		ClientAddr: addr,
+	}
+}
+
+// Given a board game state, calculate a next move to return
+func Play(move StateMoveMessage, mode int8) StateMoveMessage {
+	board := move.GameState
+
+	// all rows empty, should not happen
+	// should this value be encountered, it is to be considered an admission of defeat -- not required to show
+	if emptyBoard(board) {
+		return StateMoveMessage{
+			GameState: nil,
+			MoveRow:   -2,
+			MoveCount: -2,
+		}
+	}
+
+	if move.Variant == nim.LaskerVariant && mode == 1 {
+		return laskerBestMove(board)
+	}
+	if move.Variant == nim.WythoffVariant && mode == 1 {
+		return wythoffBestMove(board)
+	}
+	if mode == 1 {
+		// advanced strategy:
+		// calculate the nimsum, and make it equal 0
+		// if nimsum is already 0, make a normal move
+		return bestMove(board)
+	}
+
+	// basic strategy: find the first non-empty row, and take one piece from it.
+	nextMove, err := normalMove(board)
+	if err != nil {
+		fmt.Println(err)
+	}
+
+	return *nextMove
+}
+
+// notifyMove hands plugins a copy of move, since Play updates boards in place.
+func (s *Server) notifyMove(raddr, gameID string, move StateMoveMessage) {
+	if len(s.plugins) == 0 {
+		return
+	}
+	board := make([]uint8, len(move.GameState))
+	copy(board, move.GameState)
+	move.GameState = board
+	for _, p := range s.plugins {
+		p.OnMove(raddr, gameID, move)
+	}
+}
+
+func (s *Server) endGame(raddr string, sess *GameSession, winner string) {
+	countOutcome(sess.Strategy, winner)
+	s.count(func(st *Stats) {
+		if winner == "server" {
+			st.ServerWins++
+		} else {
+			st.ClientWins++
+		}
+	})
+	for _, p := range s.plugins {
+		p.OnGameEnd(raddr, sess.GameID, winner)
+	}
+	if sess.Match != nil {
+		sess.Match.record(winner)
+	}
+	s.notifier.NotifyGameEnd(sess.GameID, winner, sess.MoveCount)
+	s.webhooks.notify(EventGameEnd, sess.GameID, raddr, map[string]interface{}{
+		"winner": winner,
+	})
+	sess.Playing = false
+	s.updateHealth()
+	for _, p := range s.plugins {
+		p.OnDisconnect(raddr)
+	}
+}
+
+// gameWinner reports who won if servMove, the server's reply to a valid client
+// move, ends the game, and "" otherwise.
+func gameWinner(servMove StateMoveMessage) string {
+	if servMove.MoveRow == -2 {
+		// the client emptied the board, so the server conceded
+		return "client"
+	} else if emptyBoard(servMove.GameState) {
+		return "server"
+	}
+	return ""
+}
+
+// newGameID returns a random identifier for a new game.
+func newGameID() string {
+	id := make([]byte, 8)
+	crand.Read(id)
+	return hex.EncodeToString(id)
+}
+
+// check if the board is empty
+func emptyBoard(board []uint8) bool {
+	isEmpty := true
+	for _, v := range board {
+		if v != 0 {
+			isEmpty = false
+			break
+		}
+	}
+	return isEmpty
+}
+
+// calculate the nimsum of a board
+func nimSum(board []uint8) uint8 {
+	sum := uint8(0)
+	for _, v := range board {
+		sum ^= v
+	}
+	return sum
+}
+
+// naive gameplay
+func normalMove(board []uint8) (*StateMoveMessage, error) {
+	for i := 0; i < len(board); i++ {
+		if board[i] > 0 {
+			board[i] -= 1
+			return &StateMoveMessage{
+				GameState: board,
+				MoveRow:   int8(i),
+				MoveCount: 1,
+			}, nil
+		}
+	}
+	return nil, errors.New("no move to make")
+}
+
+// advanced gameplay
+// always try to make the nimsum be zero
+func bestMove(board []uint8) StateMoveMessage {
+	sum := nimSum(board)
+	if sum != 0 {
+		for i, v := range board {
+			tmp := sum ^ v
+			if tmp <= v {
+				board[i] = tmp
+				return StateMoveMessage{
+					GameState: board,
+					MoveRow:   int8(i),
+					MoveCount: int8(v - tmp),
+				}
+			}
+		}
+	}
+	move, err := normalMove(board)
+	if err != nil {
+		// the board is empty, which Play concedes before asking us
+		return StateMoveMessage{GameState: nil, MoveRow: -2, MoveCount: -2}
+	}
+	return *move
+}
+
+// ErrBoardTooLarge is returned by CheckMove for a board with more rows or
+// coins than the config allows.
+var ErrBoardTooLarge = nimerr.New(nimerr.ErrProtocol, "board too large")
+
+// lastmove is the last move server sent to a client
+// incmove is the normal move received for that client
+// check that this move is valid, and return whether it is; the error is
+// ErrBoardTooLarge when the board is refused for its size
+func CheckMove(incmove StateMoveMessage, lastmove StateMoveMessage, config *ServerConfig) (bool, error) {
+	lastboard := lastmove.GameState
+	incboard := incmove.GameState
+	// splits grow boards a row at a time, past any the server hands out
+	lasker := lastmove.Variant == nim.LaskerVariant
+
+	// Refuse boards larger than any the server hands out before looking
+	// at them further
+	if !lasker && len(incboard) > config.maxBoardRows() {
+		return false, fmt.Errorf("%w: %d rows, more than %d", ErrBoardTooLarge, len(incboard), config.maxBoardRows())
+	}
+	for i, coins := range incboard {
+		if coins > config.maxCoinsPerRow() {
+			return false, fmt.Errorf("%w: row %d has %d coins, more than %d", ErrBoardTooLarge, i, coins, config.maxCoinsPerRow())
+		}
+	}
+	if lasker {
+		// judged by how the board changed, as a split has no row and count
+		return nim.ValidLaskerMove(lastboard, incboard), nil
+	}
+	if lastmove.Variant == nim.WythoffVariant {
+		// judged by the boards too, as taking from both heaps has no one row
+		return nim.ValidWythoffMove(lastboard, incboard), nil
+	}
+
+	// Sanity checks
+	// 1. borad length should not change
+	// 2. MoveRow should be valid (0 <= MoveRow < len(board))
+	if len(lastboard) != len(incboard) ||
+		incmove.MoveRow < 0 ||
+		int(incmove.MoveRow) >= len(incboard) {
+		return false, nil
+	}
+	// Check the validity of the move
+	// 1. row counts should not change for rows not moved
+	// 2. the row count for the moved row should be correctly updated
+	for i := 0; i < len(incboard); i++ {
+		if incboard[i] == lastboard[i] {
+			continue
+		} else if i == int(incmove.MoveRow) &&
+			incmove.MoveCount > 0 &&
+			incmove.MoveCount <= int8(lastboard[i]) &&
+			incboard[i] == lastboard[i]-uint8(incmove.MoveCount) {
+			continue
+		}
+		return false, nil
+	}
+
+	return true, nil
+}
+
+// tracingDialTimeout bounds the check that the tracing server is up.
+const tracingDialTimeout = 5 * time.Second
+
+// initTracer connects to the tracing server, returning nil when no
+// TracingServerAddress is set.
+func initTracer(config *ServerConfig, log *slog.Logger) (*tracing.Tracer, error) {
+	if config.TracingServerAddress == "" {
+		log.Info("tracing disabled")
+		return nil, nil
+	}
+	if rate := config.sampleRate(); rate < 1 {
+		log.Warn("tracing only some moves", "TracingSampleRate", rate)
+	}
+	// tracing.NewTracer exits the program if it can't connect, so check
+	// the server is there first
+	conn, err := net.DialTimeout("tcp", config.TracingServerAddress, tracingDialTimeout)
+	if err != nil {
+		return nil, nimerr.Wrap(nimerr.ErrTransport, fmt.Errorf("connecting to tracing server: %w", err))
+	}
+	conn.Close()
+	return tracing.NewTracer(tracing.TracerConfig{
+		ServerAddress:  config.TracingServerAddress,
+		TracerIdentity: config.TracingIdentity,
+		Secret:         config.Secret,
+	}), nil
+}
+
+// listen listens on config.NimServerAddress for games over QUIC, if
+// QuicEnabled, or else UDP, returning the address it listens on.
+func listen(config *ServerConfig, log *slog.Logger) (UDPInterface, net.Addr, error) {
+	if config.QuicEnabled {
+		log.Info("serving games over QUIC", "addr", config.NimServerAddress)
+		q, err := startListenQUIC(config)
+		if err != nil {
+			return nil, nil, err
+		}
+		return q, q.Addr(), nil
+	}
+	udp, err := startListenUDP(config)
+	if err != nil {
+		return nil, nil, err
+	}
+	return udp, udp.Conn.LocalAddr(), nil
+}
+
+// startListenUDP listens on config.NimServerAddress.
+func startListenUDP(config *ServerConfig) (*UDPConnection, error) {
+	network := udpnet.Network(config.NimServerAddress, config.IPv6)
+	addr, err := net.ResolveUDPAddr(network, config.NimServerAddress)
+	if err != nil {
+		return nil, nimerr.Wrap(nimerr.ErrConfig, fmt.Errorf("resolving NimServerAddress: %w", err))
+	}
+	conn, err := net.ListenUDP(network, addr)
+	if err != nil {
+		return nil, nimerr.Wrap(nimerr.ErrTransport, fmt.Errorf("listening on %v: %w", addr, err))
+	}
+	return UDPAdapter(conn, 1024), nil
+}
+
+// Gets the byte array representation of a move, so it can be put onto the wire.
+func Marshal(move interface{}) ([]byte, error) {
+	var network bytes.Buffer
+	enc := gob.NewEncoder(&network)
+	err := enc.Encode(move)
+	return network.Bytes(), err
+}
+
+func Unmarshal(input []byte, move interface{}) error {
+	network := bytes.NewBuffer(input)
+	dec := gob.NewDecoder(network)
+	err := dec.Decode(move)
+	return err
+}
+
+// MarshalMove marshals move for the wire, compressing its board as mode asks.
+func MarshalMove(move StateMoveMessage, mode string) ([]byte, error) {
+	if mode == "rle" && move.GameState != nil {
+		move.GameState = nim.RLEEncode(move.GameState)
+		move.RLEEncoded = true
+	}
+	return Marshal(move)
+}
+
+// UnmarshalMove is the inverse of MarshalMove, refusing a compressed board
+// of more than maxRows rows.
+func UnmarshalMove(input []byte, move *StateMoveMessage, maxRows int) error {
+	if err := Unmarshal(input, move); err != nil {
+		return err
+	}
+	if move.RLEEncoded {
+		board, err := nim.RLEDecode(move.GameState, maxRows)
+		if err != nil {
+			return err
+		}
+		move.GameState = board
+		move.RLEEncoded = false
+	}
+	return nil
+}
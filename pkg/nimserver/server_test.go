@@ -0,0 +1,691 @@
+package nimserver
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"math/rand"
+	"net"
+	"testing"
+	"time"
+
+	"nimgame/pkg/netcond"
+	"nimgame/pkg/nim"
+	"nimgame/pkg/nimerr"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func genEmptyBoards(n int) [][]uint8 {
+	var boards [][]uint8
+	for i := 0; i < n; i++ {
+		rows := rand.Intn(14) + 3
+		b := make([]uint8, rows)
+		for i := 0; i < rows; i++ {
+			b[i] = uint8(0)
+		}
+		boards = append(boards, b)
+	}
+	return boards
+}
+
+func genBoards(n int) [][]uint8 {
+	var boards [][]uint8
+	for i := 0; i < n; i++ {
+		b := nim.GenerateBoard(int64(i))
+		boards = append(boards, b)
+	}
+	return boards
+}
+
+func TestEmptyBoard(t *testing.T) {
+	// empty boards should all be empty
+	emptyBoards := genEmptyBoards(15)
+	t.Logf("Boards: %v\n", emptyBoards)
+	for _, b := range emptyBoards {
+		isEmpty := emptyBoard(b)
+		if !isEmpty {
+			t.Errorf("board should be empty: %v\n", b)
+		}
+	}
+
+	// non-empty boards should all be non-empty
+	nonEmptyBoards := genBoards(15)
+	t.Logf("Boards: %v\n", nonEmptyBoards)
+	for _, b := range nonEmptyBoards {
+		isEmpty := emptyBoard(b)
+		if isEmpty {
+			t.Errorf("board should not be empty: %v\n", b)
+		}
+	}
+}
+
+func TestNormalMove(t *testing.T) {
+	// a normal move is to take one from the first non-zero row
+	boards := genBoards(15)
+	for _, b := range boards {
+		t.Logf("Board: %v\n", b)
+		// record the first element before move
+		prev0 := b[0]
+		st, err := normalMove(b)
+		t.Logf("after move: %v\n", st.GameState)
+		// All boards are non-empty, so should not error
+		if err != nil {
+			t.Errorf("a normal move should be made on board: %v\n", b)
+		}
+		// the board after move
+		b2 := st.GameState
+		// since the board in non-empty in all rows, we should always remove 1 item from row 0
+		if (prev0-b2[0]) != 1 || st.MoveRow != 0 || st.MoveCount != 1 {
+			t.Errorf("made a wrong move: %v\n", st)
+		}
+	}
+
+	board := []uint8{1, 9, 1, 5}
+	t.Logf("Board: %v\n", board)
+	st, _ := normalMove(board)
+	t.Logf("after move: %v\n", st)
+	if st.GameState[0] != 0 || st.MoveRow != 0 || st.MoveCount != 1 {
+		t.Errorf("made a wrong move: %v\n", st)
+	}
+}
+
+func TestBoardGen(t *testing.T) {
+	boards := genBoards(15)
+	for _, b := range boards {
+		sum := nimSum(b)
+		if sum == 0 {
+			t.Errorf("board nim sum should be non-zero: %v\n", b)
+		}
+	}
+}
+
+func TestAllSeedsValid(t *testing.T) {
+	// clients send an int8 seed, so this covers every board a game can start on
+	for seed := math.MinInt8; seed <= math.MaxInt8; seed++ {
+		b := nim.GenerateBoard(int64(seed))
+		if b == nil {
+			t.Fatalf("seed %d: no board generated\n", seed)
+		}
+		if len(b) < 3 {
+			t.Errorf("seed %d: board should have at least 3 rows: %v\n", seed, b)
+		}
+		for i, coins := range b {
+			if coins < 1 {
+				t.Errorf("seed %d: row %d of board %v is empty\n", seed, i, b)
+			}
+		}
+		if nimSum(b) == 0 {
+			t.Fatalf("seed %d: board nim sum should be non-zero: %v\n", seed, b)
+		}
+	}
+}
+
+func TestGenerateBoardDeterministic(t *testing.T) {
+	for seed := int64(math.MinInt8); seed <= math.MaxInt8; seed++ {
+		first, second := nim.GenerateBoard(seed), nim.GenerateBoard(seed)
+		if !bytes.Equal(first, second) {
+			t.Errorf("seed %d generated different boards: %v and %v\n", seed, first, second)
+		}
+	}
+}
+
+func TestBestMove(t *testing.T) {
+	boards := genBoards(15)
+	for _, b := range boards {
+		t.Logf("Board: %v\n", b)
+		st := bestMove(b)
+		t.Logf("after move: %v\n", st.GameState)
+		sum := nimSum(st.GameState)
+		// the generated Boards are guaranteed to have non-zero nim sum
+		// therefore it's always possible to make nim-sum zero
+		if sum != 0 {
+			t.Errorf("nim sum should be zero after best move: %v\n", st)
+		}
+	}
+}
+
+// TestBestMoveNPly checks a search one and three plies deep agrees with
+// bestMove, which nim's closed form makes exact, on random boards, losing
+// ones included.
+func TestBestMoveNPly(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 50; i++ {
+		board := make([]uint8, rng.Intn(6)+1)
+		for row := range board {
+			board[row] = uint8(rng.Intn(8))
+		}
+		board[rng.Intn(len(board))]++ // never empty
+		want := bestMove(append([]uint8(nil), board...))
+		for _, n := range []int{1, 3} {
+			if got := nim.BestMoveNPly(board, n); got.Row != int(want.MoveRow) || int8(got.Count) != want.MoveCount {
+				t.Errorf("on %v: %d plies took %d from row %d, bestMove %d from row %d\n", board, n, got.Count, got.Row, want.MoveCount, want.MoveRow)
+			}
+		}
+	}
+}
+
+func TestCheckMoveBoardTooLarge(t *testing.T) {
+	config := &ServerConfig{}
+	huge := make([]uint8, 100)
+	for i := range huge {
+		huge[i] = 1
+	}
+	last := StateMoveMessage{GameState: append([]uint8(nil), huge...)}
+	huge[0] = 0
+	ok, err := CheckMove(StateMoveMessage{GameState: huge, MoveRow: 0, MoveCount: 1}, last, config)
+	if ok || !errors.Is(err, ErrBoardTooLarge) {
+		t.Errorf("expected a 100 row board to be refused with ErrBoardTooLarge, got %v, %v\n", ok, err)
+	}
+
+	config.MaxCoinsPerRow = 5
+	ok, err = CheckMove(StateMoveMessage{GameState: []uint8{3, 6}, MoveRow: 1, MoveCount: 1}, StateMoveMessage{GameState: []uint8{3, 7}}, config)
+	if ok || !errors.Is(err, ErrBoardTooLarge) {
+		t.Errorf("expected a row of 6 coins to be refused with ErrBoardTooLarge, got %v, %v\n", ok, err)
+	}
+
+	board := nim.GenerateBoard(1)
+	next := bestMove(append([]uint8(nil), board...))
+	config.MaxCoinsPerRow = 0
+	if ok, err := CheckMove(next, StateMoveMessage{GameState: board}, config); !ok || err != nil {
+		t.Errorf("expected a generated board to be within the defaults, got %v, %v\n", ok, err)
+	}
+}
+
+func TestTokenContinuity(t *testing.T) {
+	tracingAddr, output := startTracingServer(t)
+	_, raddr := startServer(t, &ServerConfig{TracingServerAddress: tracingAddr})
+
+	trace := newTestTracer(t, tracingAddr, "client").CreateTrace()
+	client := newTestClient(t, raddr, trace)
+	winner, replies := client.playGame(3)
+	if winner != "client" {
+		t.Errorf("client should win with best moves, winner: %v\n", winner)
+	}
+	for i, reply := range replies {
+		if reply.Token == nil {
+			t.Errorf("reply %d carried no token: %v\n", i, reply)
+		}
+	}
+	if client.trace.ID != trace.ID {
+		t.Errorf("trace changed from %d to %d over the game\n", trace.ID, client.trace.ID)
+	}
+
+	// every action the server recorded for this game belongs to the client's trace
+	records := readTraceRecords(t, output)
+	serverMoves := 0
+	for _, r := range records {
+		if r.TracerIdentity != "server" || r.Tag == "CreateTrace" {
+			continue
+		}
+		if r.TraceID != trace.ID {
+			t.Errorf("server recorded %v under trace %d, want %d\n", r.Tag, r.TraceID, trace.ID)
+		}
+		if r.Tag == "ServerMove" {
+			serverMoves++
+		}
+	}
+	if serverMoves != len(replies) {
+		t.Errorf("expected %d ServerMove records, found %d\n", len(replies), serverMoves)
+	}
+}
+
+func TestTracingSampledOut(t *testing.T) {
+	tracingAddr, output := startTracingServer(t)
+	rate := 0.0
+	_, raddr := startServer(t, &ServerConfig{TracingServerAddress: tracingAddr, TracingSampleRate: &rate})
+
+	trace := newTestTracer(t, tracingAddr, "client").CreateTrace()
+	client := newTestClient(t, raddr, trace)
+	moves := 0
+	for seed := int8(1); moves < 100; seed += 2 {
+		_, replies := client.playGame(seed)
+		moves += len(replies)
+		for _, reply := range replies {
+			if reply.Token != nil {
+				t.Fatalf("unsampled reply carried a token: %v\n", reply)
+			}
+		}
+	}
+
+	for _, r := range readTraceRecords(t, output) {
+		if r.TracerIdentity == "server" && r.Tag != "CreateTrace" {
+			t.Errorf("server recorded %v with tracing sampled out\n", r.Tag)
+		}
+	}
+}
+
+func TestRLECompressedGame(t *testing.T) {
+	_, raddr := startServer(t, &ServerConfig{CompressionMode: "rle"})
+	client := newTestClient(t, raddr, nil)
+
+	// the raw reply carries the encoded board
+	packet, _ := Marshal(StateMoveMessage{GameState: nil, MoveRow: -1, MoveCount: 9})
+	client.conn.Write(packet)
+	n, err := client.conn.Read(client.buf)
+	if err != nil {
+		t.Fatalf("no reply to GameStart: %v\n", err)
+	}
+	var raw StateMoveMessage
+	Unmarshal(client.buf[:n], &raw)
+	if !raw.RLEEncoded || !bytes.Equal(raw.GameState, nim.RLEEncode(nim.GenerateBoard(9))) {
+		t.Errorf("expected an RLE board, got %v\n", raw)
+	}
+
+	winner, replies := client.playGame(9)
+	if winner != "client" {
+		t.Errorf("client should win with best moves, winner: %v\n", winner)
+	}
+	if !bytes.Equal(replies[0].GameState, nim.GenerateBoard(9)) {
+		t.Errorf("decoded board %v, expected %v\n", replies[0].GameState, nim.GenerateBoard(9))
+	}
+}
+
+func TestRepliesCarryMerkleRoot(t *testing.T) {
+	_, raddr := startServer(t, &ServerConfig{CompressionMode: "rle"})
+	_, replies := newTestClient(t, raddr, nil).playGame(6)
+	for i, reply := range replies {
+		if reply.GameState == nil {
+			if reply.MerkleRoot != ([32]byte{}) {
+				t.Errorf("reply %d: concession carries root %x\n", i, reply.MerkleRoot)
+			}
+			continue
+		}
+		if !nim.VerifyMerkleRoot(reply.GameState, reply.MerkleRoot) {
+			t.Errorf("reply %d: root %x doesn't match board %v\n", i, reply.MerkleRoot, reply.GameState)
+		}
+	}
+}
+
+// stallingPlugin holds up the server's worker in OnGameStart until released.
+type stallingPlugin struct {
+	recordingPlugin
+	release chan struct{}
+}
+
+func (p *stallingPlugin) OnGameStart(raddr, gameID string, board []uint8) {
+	<-p.release
+}
+
+func TestQueueFullDropsMoves(t *testing.T) {
+	stall := &stallingPlugin{release: make(chan struct{})}
+	_, raddr := startServer(t, &ServerConfig{QueueDepth: 1}, WithPlugins(stall))
+	t.Cleanup(func() { close(stall.release) })
+	client := newTestClient(t, raddr, nil)
+
+	dropped := testutil.ToFloat64(droppedMoves)
+	packet, _ := Marshal(StateMoveMessage{GameState: nil, MoveRow: -1, MoveCount: 1})
+	for i := 0; i < 3; i++ {
+		client.conn.Write(packet)
+	}
+
+	// one packet is stuck with the worker and one fills the queue
+	deadline := time.Now().Add(2 * time.Second)
+	for testutil.ToFloat64(droppedMoves) < dropped+1 {
+		if time.Now().After(deadline) {
+			t.Fatalf("no packets dropped with a full queue\n")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// TestDuplicatePacketDropped sends the same packet from two addresses, and
+// checks only the first is answered.
+func TestDuplicatePacketDropped(t *testing.T) {
+	_, raddr := serveOnLoopback(t, &ServerConfig{}, nil)
+	first := newTestClient(t, raddr, nil)
+	second := newTestClient(t, raddr, nil)
+
+	drops := testutil.ToFloat64(dedupDrops)
+	move := StateMoveMessage{GameState: nil, MoveRow: -1, MoveCount: 4, Token: []byte("token")}
+	first.exchange(move)
+
+	packet, _ := Marshal(move)
+	second.conn.Write(packet)
+	second.conn.SetReadDeadline(time.Now().Add(200 * time.Millisecond))
+	if _, err := second.conn.Read(second.buf); err == nil {
+		t.Errorf("duplicate packet from another address was answered\n")
+	}
+	if got := testutil.ToFloat64(dedupDrops); got != drops+1 {
+		t.Errorf("nim_dedup_drops_total went from %v to %v, expected one drop\n", drops, got)
+	}
+
+	// without a token, which clients make fresh for every send, a copy may
+	// be a retransmission, or another client's identical GameStart
+	move.Token = nil
+	first.exchange(move)
+	second.exchange(move)
+}
+
+func TestSyncResendsLastMove(t *testing.T) {
+	_, raddr := startServer(t, &ServerConfig{})
+	client := newTestClient(t, raddr, nil)
+	reply := client.exchange(StateMoveMessage{GameState: nil, MoveRow: -1, MoveCount: 4})
+	reply = client.exchange(bestMove(append([]uint8(nil), reply.GameState...)))
+
+	synced := client.exchange(StateMoveMessage{GameState: nil, MoveRow: syncMoveRow})
+	if !bytes.Equal(synced.GameState, reply.GameState) || synced.MoveRow != reply.MoveRow || synced.MoveCount != reply.MoveCount {
+		t.Errorf("Sync answered with %v, expected the last move %v\n", synced, reply)
+	}
+	// the game carries on from the synced board, which a rejected move
+	// would be answered with again
+	if next := client.exchange(bestMove(append([]uint8(nil), synced.GameState...))); bytes.Equal(next.GameState, synced.GameState) {
+		t.Errorf("move after Sync was rejected with %v\n", next)
+	}
+}
+
+func TestSessionResumeFromNewAddress(t *testing.T) {
+	server, raddr := startServer(t, &ServerConfig{})
+	first := newTestClient(t, raddr, nil)
+	reply := first.exchange(StateMoveMessage{GameState: nil, MoveRow: -1, MoveCount: 4})
+	reply = first.exchange(bestMove(append([]uint8(nil), reply.GameState...)))
+	if reply.GameID == "" {
+		t.Fatalf("reply %v carries no GameID\n", reply)
+	}
+
+	// the client's socket is gone; it carries on from another
+	second := newTestClient(t, raddr, nil)
+	resumed := second.exchange(StateMoveMessage{GameState: nil, MoveRow: sessionResumeMoveRow, GameID: reply.GameID})
+	if !bytes.Equal(resumed.GameState, reply.GameState) || resumed.GameID != reply.GameID {
+		t.Errorf("resume answered with %v, expected the last move %v\n", resumed, reply)
+	}
+	if next := second.exchange(bestMove(append([]uint8(nil), resumed.GameState...))); bytes.Equal(next.GameState, resumed.GameState) {
+		t.Errorf("move after resuming was rejected with %v\n", next)
+	}
+	server.sessionsMu.Lock()
+	defer server.sessionsMu.Unlock()
+	if _, ok := server.sessions[first.conn.LocalAddr().String()]; ok {
+		t.Errorf("session left at the old address\n")
+	}
+}
+
+func TestStochasticMode(t *testing.T) {
+	_, raddr := serveOnLoopback(t, &ServerConfig{StochasticMode: true, StochasticAlpha: 2}, nil)
+	winner, replies := newTestClient(t, raddr, nil).playGame(6)
+	if !bytes.Equal(replies[0].GameState, nim.GenerateStochasticBoard(6, 2)) {
+		t.Errorf("started on %v, expected the stochastic board %v\n", replies[0].GameState, nim.GenerateStochasticBoard(6, 2))
+	}
+	if winner != "client" {
+		t.Errorf("got winner %v, expected client\n", winner)
+	}
+}
+
+func TestConditionedReplies(t *testing.T) {
+	_, raddr := serveOnLoopback(t, &ServerConfig{}, nil, WithConditioner(netcond.New(netcond.Config{Duplicate: 1})))
+	client := newTestClient(t, raddr, nil)
+	reply := client.exchange(StateMoveMessage{GameState: nil, MoveRow: -1, MoveCount: 4})
+
+	client.conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	n, err := client.conn.Read(client.buf)
+	if err != nil {
+		t.Fatalf("no duplicate of the reply: %v\n", err)
+	}
+	var dup StateMoveMessage
+	if err := UnmarshalMove(client.buf[:n], &dup, defaultMaxBoardRows); err != nil || !bytes.Equal(dup.GameState, reply.GameState) {
+		t.Errorf("duplicate %v, %v doesn't match the reply %v\n", dup, err, reply)
+	}
+}
+
+// TestServeGame plays a few moves, one of them illegal, and checks the
+// replies and the game's history.
+func TestServeGame(t *testing.T) {
+	clone := func(board []uint8) []uint8 { return append([]uint8(nil), board...) }
+	board := nim.GenerateBoard(4) // even, so the server plays normalMove
+	move1 := bestMove(clone(board))
+	reply1 := Play(StateMoveMessage{GameState: clone(move1.GameState)}, 0)
+	added := clone(reply1.GameState)
+	added[0]++
+	illegal := StateMoveMessage{GameState: added, MoveRow: 0, MoveCount: 1}
+	move2 := bestMove(clone(reply1.GameState))
+	reply2 := Play(StateMoveMessage{GameState: clone(move2.GameState)}, 0)
+
+	server, raddr := serveOnLoopback(t, &ServerConfig{}, nil)
+	client := newTestClient(t, raddr, nil)
+	// the illegal move is answered with the last reply again
+	want := [][]uint8{board, reply1.GameState, reply1.GameState, reply2.GameState}
+	for i, move := range []StateMoveMessage{{GameState: nil, MoveRow: -1, MoveCount: 4}, move1, illegal, move2} {
+		if reply := client.exchange(move); !bytes.Equal(reply.GameState, want[i]) {
+			t.Errorf("reply %d: board %v, expected %v\n", i, reply.GameState, want[i])
+		}
+	}
+	if sess := sessionOf(server, client.conn.LocalAddr().String()); sess == nil || len(sess.History) != 5 {
+		t.Errorf("expected a session recording the board and 4 moves, got %+v\n", sess)
+	}
+}
+
+// TestInvalidMoveTraced sends a move taking one coin more than its board
+// shows and checks the server traces it as an InvalidMove.
+func TestInvalidMoveTraced(t *testing.T) {
+	tracingAddr, output := startTracingServer(t)
+	board := nim.GenerateBoard(4)
+	wrong := StateMoveMessage{GameState: append([]uint8(nil), board...), MoveRow: 0, MoveCount: 2}
+	wrong.GameState[0]--
+
+	server, raddr := startServer(t, &ServerConfig{TracingServerAddress: tracingAddr})
+	client := newTestClient(t, raddr, nil)
+	client.exchange(StateMoveMessage{GameState: nil, MoveRow: -1, MoveCount: 4})
+	client.exchange(wrong)
+	if stats := server.Stats(); stats.InvalidMoves != 1 {
+		t.Fatalf("server stats are %+v, expected the move refused\n", stats)
+	}
+
+	var invalid []InvalidMove
+	for _, r := range readTraceRecords(t, output) {
+		if r.TracerIdentity == "server" && r.Tag == "InvalidMove" {
+			var action InvalidMove
+			if err := json.Unmarshal(r.Body, &action); err != nil {
+				t.Fatalf("decoding %s: %v\n", r.Body, err)
+			}
+			invalid = append(invalid, action)
+		}
+	}
+	want := InvalidMove{GameState: wrong.GameState, MoveRow: 0, MoveCount: 2, ErrorCode: InvalidMoveIllegal, ClientAddr: client.conn.LocalAddr().String()}
+	if len(invalid) != 1 || !bytes.Equal(invalid[0].GameState, want.GameState) || invalid[0].MoveRow != want.MoveRow ||
+		invalid[0].MoveCount != want.MoveCount || invalid[0].ErrorCode != want.ErrorCode || invalid[0].ClientAddr != want.ClientAddr {
+		t.Errorf("traced %+v, expected one %+v\n", invalid, want)
+	}
+}
+
+func TestRunCanceledMidGame(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	server, err := New(WithListenAddress("127.0.0.1:0"))
+	if err != nil {
+		t.Fatalf("starting server: %v\n", err)
+	}
+	done := make(chan error, 1)
+	go func() { done <- server.Run(ctx) }()
+
+	client := newTestClient(t, server.Addr().(*net.UDPAddr), nil)
+	reply := client.exchange(StateMoveMessage{GameState: nil, MoveRow: -1, MoveCount: 3})
+	client.exchange(bestMove(reply.GameState))
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("expected Run to stop cleanly when canceled, got %v\n", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("Run still running a second after cancellation\n")
+	}
+	if err := server.Run(context.Background()); !errors.Is(err, ErrServerClosed) {
+		t.Errorf("second Run returned %v, expected ErrServerClosed\n", err)
+	}
+}
+
+func TestActiveGamesAndStats(t *testing.T) {
+	server, raddr := serveOnLoopback(t, &ServerConfig{}, nil)
+	client := newTestClient(t, raddr, nil)
+	reply := client.exchange(StateMoveMessage{GameState: nil, MoveRow: -1, MoveCount: 6})
+	client.exchange(StateMoveMessage{GameState: []uint8{}, MoveRow: 0, MoveCount: 0})
+
+	games := server.ActiveGames()
+	if len(games) != 1 || games[0].Client != client.conn.LocalAddr().String() || games[0].GameID != reply.GameID ||
+		!bytes.Equal(games[0].Board, reply.GameState) || games[0].Moves != 0 {
+		t.Errorf("expected the game started from %v on %v, got %+v\n", client.conn.LocalAddr(), reply.GameState, games)
+	}
+
+	winner, replies := client.playGame(4)
+	if games := server.ActiveGames(); len(games) != 0 {
+		t.Errorf("finished games still active: %+v\n", games)
+	}
+	stats := server.Stats()
+	// the second game's moves, bar its start, each answered with a move
+	// except the last, which the server concedes
+	want := Stats{GamesStarted: 2, ClientWins: 1, Moves: 2*(len(replies)-1) - 1, InvalidMoves: 1}
+	if winner != "client" || stats != want {
+		t.Errorf("expected stats %+v, got %+v\n", want, stats)
+	}
+}
+
+func TestStartupErrors(t *testing.T) {
+	if _, err := New(); !errors.Is(err, nimerr.ErrConfig) {
+		t.Errorf("no listen address: expected a config error, got %v\n", err)
+	}
+
+	taken := listenLoopback(t, &ServerConfig{})
+	_, err := New(WithListenAddress(taken.Conn.LocalAddr().String()))
+	if !errors.Is(err, nimerr.ErrTransport) {
+		t.Errorf("address in use: expected a transport error, got %v\n", err)
+	}
+
+	// nothing listens on the tracing port once its listener is closed
+	lis, _ := net.Listen("tcp", "127.0.0.1:0")
+	lis.Close()
+	_, err = New(WithConfig(&ServerConfig{
+		NimServerAddress:     "127.0.0.1:0",
+		TracingServerAddress: lis.Addr().String(),
+		Secret:               []byte("secret"),
+		TracingIdentity:      "server",
+	}))
+	if !errors.Is(err, nimerr.ErrTransport) {
+		t.Errorf("tracing server down: expected a transport error, got %v\n", err)
+	}
+}
+
+// simulateGame plays board out between a client and the server, each
+// choosing moves with Play in its own mode, checking every client move
+// with CheckMove as handleMove does. It returns the winner, or "" if a
+// client move was rejected.
+func simulateGame(board []uint8, clientMode, serverMode int8, config *ServerConfig) string {
+	last := StateMoveMessage{GameState: append([]uint8(nil), board...), MoveRow: -1}
+	for {
+		move := Play(StateMoveMessage{GameState: append([]uint8(nil), last.GameState...)}, clientMode)
+		if ok, _ := CheckMove(move, last, config); !ok {
+			return ""
+		}
+		if emptyBoard(move.GameState) {
+			return "client"
+		}
+		last = Play(move, serverMode)
+		if emptyBoard(last.GameState) {
+			return "server"
+		}
+	}
+}
+
+func TestSimulateGame(t *testing.T) {
+	strategies := map[int8]nim.Strategy{0: nim.Basic{}, 1: nim.Optimal{}}
+	config := &ServerConfig{}
+	for i, board := range genBoards(50) {
+		for clientMode := int8(0); clientMode <= 1; clientMode++ {
+			for serverMode := int8(0); serverMode <= 1; serverMode++ {
+				want := "server"
+				if nim.SimulateGame(board, strategies[clientMode], strategies[serverMode]) {
+					want = "client"
+				}
+				if got := simulateGame(board, clientMode, serverMode, config); got != want {
+					t.Errorf("board %d, modes %d vs %d: expected the %v to win, got %q\n", i, clientMode, serverMode, want, got)
+				}
+			}
+		}
+	}
+}
+
+// FuzzServerHandler hands the server two arbitrary packets from one client,
+// as a GameStart and a move would be, checking it doesn't panic, keeps no
+// more sessions than MaxClients and only ever replies with packets that
+// decode. Run it with go test -fuzz=FuzzServerHandler -fuzztime=60s.
+func FuzzServerHandler(f *testing.F) {
+	board := nim.GenerateBoard(4)
+	start, _ := Marshal(StateMoveMessage{GameState: nil, MoveRow: -1, MoveCount: 4})
+	move, _ := Marshal(bestMove(append([]uint8(nil), board...)))
+	sync, _ := Marshal(StateMoveMessage{GameState: nil, MoveRow: syncMoveRow})
+	f.Add(start, move)
+	f.Add(start, sync)
+	f.Add(move, start)
+	f.Add([]byte{}, []byte{0})
+
+	f.Fuzz(func(t *testing.T, first, second []byte) {
+		config := &ServerConfig{MaxClients: 1}
+		conn := &MockUDPConn{RemoteAddr: &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 5000}}
+		server := newServer(config, nil, conn)
+		defer server.stopMoveTimers()
+		for _, packet := range [][]byte{first, second} {
+			server.handleMove(packet, conn.RemoteAddr, time.Now())
+			server.sessionsMu.Lock()
+			n := len(server.sessions)
+			server.sessionsMu.Unlock()
+			if n > config.MaxClients {
+				t.Fatalf("%d sessions for one client\n", n)
+			}
+		}
+		for i, packet := range conn.OutPackets {
+			var reply StateMoveMessage
+			if err := UnmarshalMove(packet, &reply, defaultMaxBoardRows); err != nil {
+				t.Fatalf("reply %d doesn't decode: %v\n", i, err)
+			}
+		}
+	})
+}
+
+const benchGames = 10000
+
+// benchmarkPlay times Play in mode on benchGames boards, each copied
+// afresh since Play moves on the board in place.
+func benchmarkPlay(b *testing.B, mode int8) {
+	boards := genBoards(benchGames)
+	scratch := make([][]uint8, len(boards))
+	for i, board := range boards {
+		scratch[i] = make([]uint8, len(board))
+	}
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		for i, board := range boards {
+			copy(scratch[i], board)
+			Play(StateMoveMessage{GameState: scratch[i]}, mode)
+		}
+	}
+}
+
+func BenchmarkNormalMoveAI(b *testing.B) {
+	benchmarkPlay(b, 0)
+}
+
+func BenchmarkBestMoveAI(b *testing.B) {
+	benchmarkPlay(b, 1)
+}
+
+// benchmarkFullGames plays benchGames whole games with simulateGame, the
+// server in serverMode against a client playing its best.
+func benchmarkFullGames(b *testing.B, serverMode int8) {
+	boards := genBoards(benchGames)
+	config := &ServerConfig{}
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		for _, board := range boards {
+			simulateGame(board, 1, serverMode, config)
+		}
+	}
+	b.ReportMetric(float64(b.N*benchGames)/float64(b.Elapsed().Nanoseconds()), "games/ns")
+}
+
+func BenchmarkSimulateFullGameNormal(b *testing.B) {
+	benchmarkFullGames(b, 0)
+}
+
+func BenchmarkSimulateFullGameBest(b *testing.B) {
+	benchmarkFullGames(b, 1)
+}
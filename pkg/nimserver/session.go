@@ -0,0 +1,180 @@
+package nimserver
+
+import (
+	"strings"
+	"time"
+)
+
+// GameSession is the server's state for one client, kept by remote address.
+// It outlives the client's game so that retransmissions after the end are
+// still answered with the last reply.
+type GameSession struct {
+	GameID     string
+	LastMove   StateMoveMessage // the last reply sent, resent for invalid moves
+	Difficulty int8             // 1 plays bestMove, 0 takes one coin
+	Strategy   string           // strategyBest or strategyNormal, following Difficulty
+	Variant    string           // nim.LaskerVariant or nim.WythoffVariant, empty for nim
+	MoveCount  int              // valid moves by either side this game
+	Stats      GameStats
+	Playing    bool      // a game is in progress, counted against MaxClients
+	LastSeen   time.Time // when the client's latest packet was read
+
+	// the match the game is played in, if the client asked for one; unlike
+	// the rest, it is kept from one game to the next
+	Match *Match
+
+	// the game's chess clock, if the client asked for time control
+	Clock *Clock
+
+	// when the latest message answered was read off the socket, and when
+	// the reply was written; each reply's latency is kept in Stats
+	ReceivedAt time.Time
+	SentAt     time.Time
+
+	// the game's board followed by every valid move by either side, each
+	// with its own copy of the board and marked with the game's variant
+	History []StateMoveMessage
+
+	moveTimer *time.Timer // forfeits the game if the client doesn't move, see MoveTTL
+	flagTimer *time.Timer // forfeits the game once the client's clock runs out
+}
+
+// GameStats is what the server measures over a game.
+type GameStats struct {
+	Latencies []time.Duration // read-to-write time of each reply
+}
+
+// recordReply records the reply to a message read at receivedAt as sent at
+// sentAt, returning its latency.
+func (sess *GameSession) recordReply(receivedAt, sentAt time.Time) time.Duration {
+	sess.ReceivedAt, sess.SentAt = receivedAt, sentAt
+	latency := sentAt.Sub(receivedAt)
+	sess.Stats.Latencies = append(sess.Stats.Latencies, latency)
+	return latency
+}
+
+// session returns the session for raddr, or nil if raddr never started a
+// game.
+func (s *Server) session(raddr string) *GameSession {
+	s.sessionsMu.Lock()
+	defer s.sessionsMu.Unlock()
+	return s.sessions[raddr]
+}
+
+// startSession resets raddr's session for a new game, creating it if needed.
+func (s *Server) startSession(raddr, gameID string, difficulty int8) *GameSession {
+	s.sessionsMu.Lock()
+	defer s.sessionsMu.Unlock()
+	sess := s.sessions[raddr]
+	if sess == nil {
+		sess = &GameSession{}
+		s.sessions[raddr] = sess
+	}
+	sess.stopMoveTimer()
+	sess.GameID = gameID
+	sess.Difficulty = difficulty
+	sess.Strategy = strategyName(difficulty)
+	sess.MoveCount = 0
+	sess.Stats = GameStats{}
+	sess.History = nil
+	return sess
+}
+
+// moveSession moves the session playing gameID to raddr, replacing any
+// session there, and returns it, or nil if no session has the game. Games
+// over gRPC are never moved to a UDP address, nor the other way round.
+func (s *Server) moveSession(gameID, raddr string) *GameSession {
+	s.sessionsMu.Lock()
+	defer s.sessionsMu.Unlock()
+	if gameID == "" {
+		return nil
+	}
+	for from, sess := range s.sessions {
+		if sess.GameID != gameID || keyTransport(from) != keyTransport(raddr) {
+			continue
+		}
+		if from != raddr {
+			if old := s.sessions[raddr]; old != nil {
+				old.stopMoveTimer()
+			}
+			delete(s.sessions, from)
+			s.sessions[raddr] = sess
+			s.saver.save(from, nil) // the caller saves it under raddr
+		}
+		return sess
+	}
+	return nil
+}
+
+// record appends move to the game's history, copying its board since Play
+// updates boards in place, and marks it with the game's variant, by whose
+// rules RebuildState replays it.
+func (sess *GameSession) record(move StateMoveMessage) {
+	sess.History = append(sess.History, StateMoveMessage{
+		GameState: append([]uint8(nil), move.GameState...),
+		MoveRow:   move.MoveRow,
+		MoveCount: move.MoveCount,
+		Variant:   sess.Variant,
+	})
+}
+
+// playing returns how many games are in progress.
+func (s *Server) playing() int {
+	s.sessionsMu.Lock()
+	defer s.sessionsMu.Unlock()
+	n := 0
+	for _, sess := range s.sessions {
+		if sess.Playing {
+			n++
+		}
+	}
+	return n
+}
+
+// The session keys of games played other than over UDP begin with one of
+// these, so they never share a session with a UDP client. Those of games
+// over TCP and DTLS go on to give the client's address; the others have
+// none.
+const (
+	tcpKeyPrefix  = "tcp:"
+	dtlsKeyPrefix = "dtls:"
+	grpcKeyPrefix = "grpc:"
+	httpKeyPrefix = "http:"
+	wsKeyPrefix   = "ws:"
+)
+
+// keyTransport returns "tcp", "dtls", "grpc", "http" or "ws" for the
+// session key of a game played over one of those, and "" for a UDP
+// client's address.
+func keyTransport(raddr string) string {
+	for _, prefix := range []string{tcpKeyPrefix, dtlsKeyPrefix, grpcKeyPrefix, httpKeyPrefix, wsKeyPrefix} {
+		if strings.HasPrefix(raddr, prefix) {
+			return strings.TrimSuffix(prefix, ":")
+		}
+	}
+	return ""
+}
+
+// finishedGameTTL is how long a game over gRPC or HTTP is kept once
+// finished, for its client to look up. Each of those games is played under a key of its
+// own, so nothing is played there again.
+const finishedGameTTL = time.Minute
+
+// gameKey returns the session key, beginning prefix, of the game gameID,
+// or "" if there is none. Games over gRPC or HTTP finished for
+// finishedGameTTL are forgotten on the way.
+func (s *Server) gameKey(prefix, gameID string) string {
+	s.sessionsMu.Lock()
+	defer s.sessionsMu.Unlock()
+	now, key := s.now(), ""
+	for raddr, sess := range s.sessions {
+		transport := keyTransport(raddr)
+		if (transport == "grpc" || transport == "http") && !sess.Playing && now.Sub(sess.LastSeen) > finishedGameTTL {
+			delete(s.sessions, raddr)
+			s.saver.save(raddr, nil)
+		} else if gameID != "" && strings.HasPrefix(raddr, prefix) && sess.GameID == gameID {
+			key = raddr
+		}
+	}
+	return key
+}
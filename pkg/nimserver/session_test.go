@@ -0,0 +1,53 @@
+package nimserver
+
+import (
+	"testing"
+)
+
+// TestGameSessionLifecycle plays a game, then starts another from the same
+// address.
+func TestGameSessionLifecycle(t *testing.T) {
+	server, raddr := serveOnLoopback(t, &ServerConfig{}, nil)
+	client := newTestClient(t, raddr, nil)
+	local := client.conn.LocalAddr().String()
+
+	if sess := sessionOf(server, local); sess != nil {
+		t.Fatalf("session exists before any game: %+v\n", sess)
+	}
+	client.exchange(StateMoveMessage{GameState: nil, MoveRow: -1, MoveCount: 3})
+	sess := sessionOf(server, local)
+	if sess == nil || !sess.Playing || sess.Difficulty != 1 || sess.GameID == "" {
+		t.Fatalf("expected a hard game in progress, got %+v\n", sess)
+	}
+	firstID, first := sess.GameID, server.session(local)
+
+	replies := 1
+	for sess.Playing {
+		board := make([]uint8, len(sess.LastMove.GameState))
+		copy(board, sess.LastMove.GameState)
+		client.exchange(bestMove(board))
+		sess = sessionOf(server, local)
+		replies++
+	}
+	if sess.LastMove.MoveRow != -2 {
+		t.Errorf("expected the server's last reply to concede, got %v\n", sess.LastMove)
+	}
+	if want := 2*(replies-1) - 1; sess.MoveCount != want {
+		t.Errorf("expected %d moves, counted %d\n", want, sess.MoveCount)
+	}
+	if len(sess.Stats.Latencies) != replies {
+		t.Errorf("expected %d latencies, tracked %d\n", replies, len(sess.Stats.Latencies))
+	}
+	if server.playing() != 0 {
+		t.Errorf("finished game still counted as playing\n")
+	}
+
+	client.exchange(StateMoveMessage{GameState: nil, MoveRow: -1, MoveCount: 4})
+	if server.session(local) != first {
+		t.Errorf("new game from the same address got a new session\n")
+	}
+	sess = sessionOf(server, local)
+	if !sess.Playing || sess.Difficulty != 0 || sess.GameID == firstID || sess.MoveCount != 0 || len(sess.Stats.Latencies) != 1 {
+		t.Errorf("session not reset for the new game: %+v\n", sess)
+	}
+}
@@ -0,0 +1,21 @@
+package nimserver
+
+import (
+	"net"
+
+	"nimgame/pkg/tcpframe"
+)
+
+// serveTCP plays the games of clients connecting to lis, one game to a
+// connection, until lis is closed. Each frame a client sends is handled as
+// a packet would be over UDP, in the same sessions, but with no need to
+// drop duplicates: TCP delivers every move once and in order.
+func (s *Server) serveTCP(lis net.Listener) {
+	for {
+		conn, err := lis.Accept()
+		if err != nil {
+			return // closed
+		}
+		go s.serveConn(tcpframe.NewConn(conn), tcpKeyPrefix)
+	}
+}
@@ -0,0 +1,82 @@
+package nimserver
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestTCPGame(t *testing.T) {
+	server, _ := serveOnLoopback(t, &ServerConfig{TCPAddress: "127.0.0.1:0"}, nil)
+	client := newTCPTestClient(t, server.TCPAddr())
+
+	// moves are still checked over TCP
+	reply := client.exchange(StateMoveMessage{GameState: nil, MoveRow: -1, MoveCount: 4})
+	illegal := append(append([]uint8(nil), reply.GameState...), 1)
+	if got := client.exchange(StateMoveMessage{GameState: illegal, MoveRow: 0, MoveCount: 1}); !bytes.Equal(got.GameState, reply.GameState) {
+		t.Errorf("illegal move over TCP answered with %v, expected the last move %v\n", got, reply)
+	}
+
+	winner, _ := client.playGame(4)
+	if winner != "client" {
+		t.Errorf("game over TCP won by %v, expected the client\n", winner)
+	}
+	if stats := server.Stats(); stats.GamesStarted != 2 || stats.ClientWins != 1 || stats.InvalidMoves != 1 {
+		t.Errorf("stats are %+v, expected two games, one won by the client, and one invalid move\n", stats)
+	}
+}
+
+func TestTCPSessionResume(t *testing.T) {
+	server, _ := serveOnLoopback(t, &ServerConfig{TCPAddress: "127.0.0.1:0"}, nil)
+	first := newTCPTestClient(t, server.TCPAddr())
+	reply := first.exchange(StateMoveMessage{GameState: nil, MoveRow: -1, MoveCount: 4})
+	reply = first.exchange(bestMove(append([]uint8(nil), reply.GameState...)))
+	first.conn.Close()
+
+	// the game outlives its connection, and carries on over another
+	second := newTCPTestClient(t, server.TCPAddr())
+	resumed := second.exchange(StateMoveMessage{MoveRow: sessionResumeMoveRow, GameID: reply.GameID})
+	if !bytes.Equal(resumed.GameState, reply.GameState) || resumed.GameID != reply.GameID {
+		t.Errorf("resume answered with %v, expected the last move %v\n", resumed, reply)
+	}
+	if games := server.ActiveGames(); len(games) != 1 || games[0].Client != second.conn.LocalAddr().String() {
+		t.Errorf("active games are %+v, expected the game at %v\n", games, second.conn.LocalAddr())
+	}
+}
+
+func TestTCPAndUDPGames(t *testing.T) {
+	server, raddr := serveOnLoopback(t, &ServerConfig{TCPAddress: "127.0.0.1:0"}, nil)
+	udp := newTestClient(t, raddr, nil)
+	tcp := newTCPTestClient(t, server.TCPAddr())
+	udpReply := udp.exchange(StateMoveMessage{GameState: nil, MoveRow: -1, MoveCount: 6})
+	tcpReply := tcp.exchange(StateMoveMessage{GameState: nil, MoveRow: -1, MoveCount: 6})
+
+	transports := map[string]string{}
+	for _, game := range server.ActiveGames() {
+		transports[game.GameID] = game.Transport
+	}
+	if len(transports) != 2 || transports[udpReply.GameID] != "udp" || transports[tcpReply.GameID] != "tcp" {
+		t.Errorf("active games are over %v, expected one over UDP and one over TCP\n", transports)
+	}
+
+	// the games are played alongside each other to the end
+	done := make(chan string)
+	go func() {
+		winner, _ := tcp.playGame(4)
+		done <- winner
+	}()
+	if winner, _ := udp.playGame(4); winner != "client" {
+		t.Errorf("UDP game won by %v, expected the client\n", winner)
+	}
+	select {
+	case winner := <-done:
+		if winner != "client" {
+			t.Errorf("TCP game won by %v, expected the client\n", winner)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("TCP game never finished\n")
+	}
+	if stats := server.Stats(); stats.GamesStarted != 4 || stats.ClientWins != 2 {
+		t.Errorf("stats are %+v, expected four games and two client wins\n", stats)
+	}
+}
@@ -0,0 +1,145 @@
+package nimserver
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+
+	"nimgame/pkg/configfile"
+	"nimgame/pkg/nim"
+	"nimgame/pkg/nimerr"
+	"nimgame/pkg/udpnet"
+)
+
+// ReadConfig reads the config file at path, rejecting fields ServerConfig
+// doesn't have so typos don't go unnoticed. Errors are nimerr.ErrConfig.
+func ReadConfig(path string) (*ServerConfig, error) {
+	config := new(ServerConfig)
+	if err := configfile.Read(path, config); err != nil {
+		return nil, nimerr.Wrap(nimerr.ErrConfig, err)
+	}
+	return config, nil
+}
+
+// ValidateConfig reports every setting in config that would stop the
+// server from starting, naming the field and its value. The error is
+// nimerr.ErrConfig.
+func ValidateConfig(config *ServerConfig) error {
+	var errs []error
+	checkAddr := func(field, network, addr string, required bool) {
+		if addr == "" {
+			if required {
+				errs = append(errs, fmt.Errorf("%v is empty; set it in the config file or with a flag", field))
+			}
+			return
+		}
+		var err error
+		if network == "udp" {
+			_, err = udpnet.ResolveAddr(addr, config.IPv6)
+		} else {
+			_, err = net.ResolveTCPAddr(network, addr)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%v %q is not a valid host:port address: %v", field, addr, err))
+		}
+	}
+	checkAddr("NimServerAddress", "udp", config.NimServerAddress, true)
+	checkAddr("TracingServerAddress", "tcp", config.TracingServerAddress, false)
+	checkAddr("AdminAddress", "tcp", config.AdminAddress, false)
+	checkAddr("GRPCAddress", "tcp", config.GRPCAddress, false)
+	checkAddr("TCPAddress", "tcp", config.TCPAddress, false)
+	checkAddr("DTLSAddress", "udp", config.DTLSAddress, false)
+	checkAddr("FCheckAckLocalAddr", "udp", config.FCheckAckLocalAddr, false)
+
+	if (config.DTLSCertFile == "") != (config.DTLSKeyFile == "") {
+		errs = append(errs, errors.New("DTLSCertFile and DTLSKeyFile must be set together"))
+	}
+	if config.DTLSAddress != "" && config.DTLSCertFile == "" && len(config.DTLSPSK) == 0 {
+		errs = append(errs, errors.New("DTLSAddress needs DTLSCertFile and DTLSKeyFile, or a DTLSPSK to derive a key from"))
+	}
+	if config.DryRun && (config.GRPCAddress != "" || config.AdminGamesEnabled) {
+		errs = append(errs, errors.New("DryRun keeps no games, which GRPCAddress and AdminGamesEnabled play; leave them unset"))
+	}
+	if config.AdminGamesEnabled && config.AdminAddress == "" {
+		errs = append(errs, errors.New("AdminGamesEnabled is set but AdminAddress is empty"))
+	}
+	if config.TracingServerAddress != "" && len(config.Secret) == 0 {
+		errs = append(errs, errors.New("Secret is empty; it must match the tracing server's Secret"))
+	}
+	if config.TracingServerAddress != "" && config.TracingIdentity == "" {
+		errs = append(errs, errors.New("TracingIdentity is empty; name this server in the traces"))
+	}
+	if config.LogLevel != "" {
+		var level slog.Level
+		if err := level.UnmarshalText([]byte(config.LogLevel)); err != nil {
+			errs = append(errs, fmt.Errorf("LogLevel %q is not one of debug, info, warn or error", config.LogLevel))
+		}
+	}
+	if config.QueueDepth < 0 {
+		errs = append(errs, fmt.Errorf("QueueDepth %d is negative", config.QueueDepth))
+	}
+	if config.MaxClients < 0 {
+		errs = append(errs, fmt.Errorf("MaxClients %d is negative", config.MaxClients))
+	}
+	if config.MaxMoveComputeMs < 0 {
+		errs = append(errs, fmt.Errorf("MaxMoveComputeMs %d is negative", config.MaxMoveComputeMs))
+	}
+	if config.WebSocketGrace < 0 {
+		errs = append(errs, fmt.Errorf("WebSocketGrace %d is negative", config.WebSocketGrace))
+	}
+	if config.MoveTTL < 0 {
+		errs = append(errs, fmt.Errorf("MoveTTL %d is negative", config.MoveTTL))
+	}
+	if config.DrainTimeout < 0 {
+		errs = append(errs, fmt.Errorf("DrainTimeout %d is negative", config.DrainTimeout))
+	}
+	if config.MinGameLength < 0 {
+		errs = append(errs, fmt.Errorf("MinGameLength %d is negative", config.MinGameLength))
+	}
+	if config.MaxSeedRetries < 0 {
+		errs = append(errs, fmt.Errorf("MaxSeedRetries %d is negative", config.MaxSeedRetries))
+	}
+	if config.BoardProfile != "" && !slices.Contains(nim.BoardProfiles, config.BoardProfile) {
+		errs = append(errs, fmt.Errorf("BoardProfile %q is not one of %v", config.BoardProfile, strings.Join(nim.BoardProfiles, ", ")))
+	}
+	if len(config.BoardProfileWeights) > 0 || config.BoardProfile == nim.ProfileCustom {
+		if err := nim.ValidProfileWeights(config.BoardProfileWeights); err != nil {
+			errs = append(errs, fmt.Errorf("BoardProfileWeights: %w", err))
+		} else if len(config.BoardProfileWeights) > int(config.maxCoinsPerRow()) {
+			errs = append(errs, fmt.Errorf("BoardProfileWeights has %d weights, for rows of more than MaxCoinsPerRow %d coins", len(config.BoardProfileWeights), config.maxCoinsPerRow()))
+		}
+	}
+	switch config.GameVariant {
+	case "", "nim", nim.WythoffVariant:
+	default:
+		errs = append(errs, fmt.Errorf("GameVariant %q is not \"nim\" or \"wythoff\"", config.GameVariant))
+	}
+	if config.BoardGuarantee != "" && !slices.Contains(nim.BoardGuarantees, config.BoardGuarantee) {
+		errs = append(errs, fmt.Errorf("BoardGuarantee %q is not one of %v", config.BoardGuarantee, strings.Join(nim.BoardGuarantees, ", ")))
+	}
+	if config.MaxBoardRows < 0 {
+		errs = append(errs, fmt.Errorf("MaxBoardRows %d is negative", config.MaxBoardRows))
+	}
+	if rate := config.sampleRate(); rate < 0 || rate > 1 {
+		errs = append(errs, fmt.Errorf("TracingSampleRate %v is outside 0 to 1", strconv.FormatFloat(rate, 'g', -1, 64)))
+	}
+	if config.ABTestRatio < 0 || config.ABTestRatio > 1 {
+		errs = append(errs, fmt.Errorf("ABTestRatio %v is outside 0 to 1", strconv.FormatFloat(config.ABTestRatio, 'g', -1, 64)))
+	}
+	if config.CompressionMode != "" && config.CompressionMode != "rle" {
+		errs = append(errs, fmt.Errorf("CompressionMode %q is not \"rle\" or empty", config.CompressionMode))
+	}
+	for _, event := range config.WebhookEvents {
+		if event != EventGameStart && event != EventGameEnd && event != EventInvalidMove {
+			errs = append(errs, fmt.Errorf("WebhookEvents has unknown event %q", event))
+		}
+	}
+	if config.KafkaBootstrapServers != "" && config.KafkaTopic == "" {
+		errs = append(errs, errors.New("KafkaTopic is empty; name the topic to publish game events to"))
+	}
+	return nimerr.Wrap(nimerr.ErrConfig, errors.Join(errs...))
+}
@@ -0,0 +1,96 @@
+package nimserver
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"nimgame/pkg/nimerr"
+)
+
+// validTestConfig passes ValidateConfig.
+func validTestConfig() *ServerConfig {
+	return &ServerConfig{
+		NimServerAddress:     "127.0.0.1:0",
+		TracingServerAddress: "127.0.0.1:1",
+		Secret:               []byte("secret"),
+		TracingIdentity:      "server",
+	}
+}
+
+func TestValidateConfig(t *testing.T) {
+	if err := ValidateConfig(validTestConfig()); err != nil {
+		t.Fatalf("unexpected validation error: %v\n", err)
+	}
+
+	badRate := 2.0
+	tests := []struct {
+		field string
+		spoil func(*ServerConfig)
+	}{
+		{"NimServerAddress", func(c *ServerConfig) { c.NimServerAddress = "" }},
+		{"NimServerAddress", func(c *ServerConfig) { c.NimServerAddress = "127.0.0.1" }},
+		{"TracingServerAddress", func(c *ServerConfig) { c.TracingServerAddress = "nowhere" }},
+		{"AdminAddress", func(c *ServerConfig) { c.AdminAddress = "127.0.0.1:http:x" }},
+		{"GRPCAddress", func(c *ServerConfig) { c.GRPCAddress = ":-1" }},
+		{"TCPAddress", func(c *ServerConfig) { c.TCPAddress = "tcp" }},
+		{"DTLSAddress", func(c *ServerConfig) { c.DTLSAddress = "dtls" }},
+		{"DTLSKeyFile", func(c *ServerConfig) { c.DTLSCertFile = "cert.pem" }},
+		{"DTLSAddress", func(c *ServerConfig) { c.DTLSAddress = "127.0.0.1:0" }},
+		{"AdminGamesEnabled", func(c *ServerConfig) { c.AdminGamesEnabled = true }},
+		{"FCheckAckLocalAddr", func(c *ServerConfig) { c.FCheckAckLocalAddr = "x" }},
+		{"Secret", func(c *ServerConfig) { c.Secret = nil }},
+		{"TracingIdentity", func(c *ServerConfig) { c.TracingIdentity = "" }},
+		{"LogLevel", func(c *ServerConfig) { c.LogLevel = "loud" }},
+		{"QueueDepth", func(c *ServerConfig) { c.QueueDepth = -1 }},
+		{"MaxClients", func(c *ServerConfig) { c.MaxClients = -1 }},
+		{"MaxMoveComputeMs", func(c *ServerConfig) { c.MaxMoveComputeMs = -1 }},
+		{"MoveTTL", func(c *ServerConfig) { c.MoveTTL = -1 }},
+		{"WebSocketGrace", func(c *ServerConfig) { c.WebSocketGrace = -1 }},
+		{"MinGameLength", func(c *ServerConfig) { c.MinGameLength = -1 }},
+		{"MaxSeedRetries", func(c *ServerConfig) { c.MaxSeedRetries = -1 }},
+		{"BoardProfile", func(c *ServerConfig) { c.BoardProfile = "lumpy" }},
+		{"BoardProfileWeights", func(c *ServerConfig) { c.BoardProfile = "custom" }},
+		{"BoardProfileWeights", func(c *ServerConfig) { c.BoardProfileWeights = make([]float64, 300) }},
+		{"MaxCoinsPerRow", func(c *ServerConfig) { c.BoardProfileWeights, c.MaxCoinsPerRow = []float64{1, 1, 1}, 2 }},
+		{"BoardGuarantee", func(c *ServerConfig) { c.BoardGuarantee = "client" }},
+		{"GameVariant", func(c *ServerConfig) { c.GameVariant = "lasker" }},
+		{"DryRun", func(c *ServerConfig) { c.DryRun, c.GRPCAddress = true, "127.0.0.1:0" }},
+		{"MaxBoardRows", func(c *ServerConfig) { c.MaxBoardRows = -1 }},
+		{"TracingSampleRate", func(c *ServerConfig) { c.TracingSampleRate = &badRate }},
+		{"ABTestRatio", func(c *ServerConfig) { c.ABTestRatio = 1.5 }},
+		{"CompressionMode", func(c *ServerConfig) { c.CompressionMode = "zip" }},
+		{"WebhookEvents", func(c *ServerConfig) { c.WebhookEvents = []string{"game_over"} }},
+	}
+	for _, test := range tests {
+		config := validTestConfig()
+		test.spoil(config)
+		if err := ValidateConfig(config); err == nil || !strings.Contains(err.Error(), test.field) {
+			t.Errorf("expected a problem with %v, got %v\n", test.field, err)
+		}
+	}
+}
+
+func TestValidateConfigReportsAll(t *testing.T) {
+	config := validTestConfig()
+	config.NimServerAddress = ""
+	config.QueueDepth = -1
+	config.LogLevel = "loud"
+	err := ValidateConfig(config)
+	if err == nil || strings.Count(err.Error(), "\n") != 2 {
+		t.Errorf("expected three problems reported together, got %v\n", err)
+	}
+}
+
+func TestReadConfigUnknownField(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "server_config.json")
+	if err := os.WriteFile(path, []byte(`{"NimServerAdress": "127.0.0.1:1"}`), 0644); err != nil {
+		t.Fatalf("writing config: %v\n", err)
+	}
+	_, err := ReadConfig(path)
+	if !errors.Is(err, nimerr.ErrConfig) || !strings.Contains(err.Error(), "NimServerAdress") {
+		t.Errorf("expected the misspelt field to be reported, got %v\n", err)
+	}
+}
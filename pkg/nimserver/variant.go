@@ -0,0 +1,36 @@
+package nimserver
+
+import "nimgame/pkg/nim"
+
+// variant is the variant of nim to play for a client asking for asked at
+// GameStart: Lasker's nim or Wythoff's game if it asked for one and the
+// config enables it, and the GameVariant otherwise, whatever it asked for.
+func (s *Server) variant(asked string) string {
+	switch {
+	case asked == nim.LaskerVariant && s.config.LaskerEnabled:
+		return nim.LaskerVariant
+	case asked == nim.WythoffVariant && s.config.WythoffEnabled:
+		return nim.WythoffVariant
+	case s.config.GameVariant == nim.WythoffVariant:
+		return nim.WythoffVariant
+	}
+	return ""
+}
+
+// laskerBestMove is bestMove for Lasker's nim, which it plays by
+// nim.LaskerOptimal. A split is sent with the row split and a count of
+// zero.
+func laskerBestMove(board []uint8) StateMoveMessage {
+	after := nim.LaskerOptimal{}.LaskerMove(board)
+	row, count := nim.LaskerStep(board, after)
+	return StateMoveMessage{GameState: after, MoveRow: int8(row), MoveCount: int8(count)}
+}
+
+// wythoffBestMove is bestMove for Wythoff's game, which it plays by
+// nim.WythoffOptimal, moving to the cold positions. Coins taken from both
+// heaps are sent with nim.WythoffBothRows as the row.
+func wythoffBestMove(board []uint8) StateMoveMessage {
+	after := nim.WythoffOptimal{}.WythoffMove(board)
+	row, count := nim.WythoffStep(board, after)
+	return StateMoveMessage{GameState: after, MoveRow: int8(row), MoveCount: int8(count)}
+}
@@ -0,0 +1,118 @@
+package nimserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+)
+
+// Webhook event names, as listed in ServerConfig.WebhookEvents.
+const (
+	EventGameStart   = "game_start"
+	EventGameEnd     = "game_end"
+	EventInvalidMove = "invalid_move"
+)
+
+const (
+	webhookQueueSize = 64
+	webhookRetries   = 3
+	webhookRetryWait = 100 * time.Millisecond
+)
+
+// WebhookEvent is the JSON body POSTed to ServerConfig.WebhookURL.
+type WebhookEvent struct {
+	Event     string      `json:"event"`
+	GameID    string      `json:"game_id"`
+	Raddr     string      `json:"raddr"`
+	Timestamp time.Time   `json:"timestamp"`
+	Data      interface{} `json:"data"`
+}
+
+// webhookNotifier delivers events from a buffered queue on its own goroutine
+// so a slow endpoint never holds up the main loop.
+type webhookNotifier struct {
+	url    string
+	events map[string]bool // nil means every event
+	queue  chan WebhookEvent
+	client *http.Client
+	done   chan struct{}
+}
+
+// newWebhookNotifier returns nil when no WebhookURL is configured.
+func newWebhookNotifier(config *ServerConfig) *webhookNotifier {
+	if config.WebhookURL == "" {
+		return nil
+	}
+	w := &webhookNotifier{
+		url:    config.WebhookURL,
+		queue:  make(chan WebhookEvent, webhookQueueSize),
+		client: &http.Client{Timeout: 5 * time.Second},
+		done:   make(chan struct{}),
+	}
+	if len(config.WebhookEvents) > 0 {
+		w.events = make(map[string]bool)
+		for _, e := range config.WebhookEvents {
+			w.events[e] = true
+		}
+	}
+	go w.run()
+	return w
+}
+
+// notify queues an event for delivery, dropping it if the queue is full.
+func (w *webhookNotifier) notify(event, gameID, raddr string, data interface{}) {
+	if w == nil || (w.events != nil && !w.events[event]) {
+		return
+	}
+	select {
+	case w.queue <- WebhookEvent{event, gameID, raddr, time.Now(), data}:
+	default:
+		fmt.Fprintf(os.Stderr, "Webhook queue full, dropping %v event for game %v\n", event, gameID)
+	}
+}
+
+// close stops accepting events and waits for the queued ones to be delivered.
+func (w *webhookNotifier) close() {
+	if w == nil {
+		return
+	}
+	close(w.queue)
+	<-w.done
+}
+
+func (w *webhookNotifier) run() {
+	defer close(w.done)
+	for ev := range w.queue {
+		var err error
+		for attempt := 0; attempt <= webhookRetries; attempt++ {
+			if attempt > 0 {
+				time.Sleep(time.Duration(attempt) * webhookRetryWait)
+			}
+			if err = w.deliver(ev); err == nil {
+				break
+			}
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error delivering %v webhook for game %v: %v\n", ev.Event, ev.GameID, err)
+		}
+	}
+}
+
+func (w *webhookNotifier) deliver(ev WebhookEvent) error {
+	body, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	resp, err := w.client.Post(w.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("unexpected status %v", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package nimserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// webhookTarget collects the events POSTed to it, failing the first `fail` requests.
+func webhookTarget(t *testing.T, fail int) (*httptest.Server, chan WebhookEvent) {
+	events := make(chan WebhookEvent, 16)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if fail > 0 {
+			fail--
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		var ev WebhookEvent
+		if err := json.NewDecoder(r.Body).Decode(&ev); err != nil {
+			t.Errorf("bad webhook body: %v\n", err)
+		}
+		events <- ev
+	}))
+	t.Cleanup(srv.Close)
+	return srv, events
+}
+
+func nextEvent(t *testing.T, events chan WebhookEvent) WebhookEvent {
+	select {
+	case ev := <-events:
+		return ev
+	case <-time.After(3 * time.Second):
+		t.Fatalf("timed out waiting for webhook\n")
+	}
+	return WebhookEvent{}
+}
+
+func TestWebhookGameEvents(t *testing.T) {
+	target, events := webhookTarget(t, 0)
+	_, raddr := startServer(t, &ServerConfig{
+		WebhookURL:    target.URL,
+		WebhookEvents: []string{EventGameStart, EventGameEnd},
+	})
+
+	client := newTestClient(t, raddr, nil)
+	winner, _ := client.playGame(5)
+
+	start := nextEvent(t, events)
+	end := nextEvent(t, events)
+	if start.Event != EventGameStart || end.Event != EventGameEnd {
+		t.Fatalf("expected game_start then game_end, got %v then %v\n", start.Event, end.Event)
+	}
+	if start.GameID == "" || start.GameID != end.GameID {
+		t.Errorf("events should share a game id: %q, %q\n", start.GameID, end.GameID)
+	}
+	if start.Raddr != client.conn.LocalAddr().String() {
+		t.Errorf("expected raddr %v, got %v\n", client.conn.LocalAddr(), start.Raddr)
+	}
+	if data, ok := end.Data.(map[string]interface{}); !ok || data["winner"] != winner {
+		t.Errorf("expected winner %v in game_end data, got %v\n", winner, end.Data)
+	}
+}
+
+func TestWebhookRetries(t *testing.T) {
+	target, events := webhookTarget(t, webhookRetries)
+	w := newWebhookNotifier(&ServerConfig{WebhookURL: target.URL})
+	w.notify(EventGameStart, "abc", "127.0.0.1:1", nil)
+
+	if ev := nextEvent(t, events); ev.GameID != "abc" {
+		t.Errorf("unexpected event: %v\n", ev)
+	}
+	w.close()
+}
+
+func TestWebhookEventFilter(t *testing.T) {
+	target, events := webhookTarget(t, 0)
+	w := newWebhookNotifier(&ServerConfig{WebhookURL: target.URL, WebhookEvents: []string{EventGameEnd}})
+	w.notify(EventInvalidMove, "abc", "127.0.0.1:1", nil)
+	w.close()
+
+	select {
+	case ev := <-events:
+		t.Errorf("unsubscribed event delivered: %v\n", ev)
+	default:
+	}
+}
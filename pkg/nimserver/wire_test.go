@@ -0,0 +1,175 @@
+package nimserver
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"nimgame/internal/wiredump"
+	"nimgame/pkg/nim"
+)
+
+// wireVersion numbers the encoding of moves the server writes today, whose
+// canonical packets are dumped in testdata/wire/current. The packets of
+// version 1, from before any field was added, are frozen in
+// testdata/wire/legacy, and must still decode.
+//
+// A change to StateMoveMessage that changes the bytes of any canonical
+// packet is a protocol change: bump wireVersion and run
+//
+//	go test ./pkg/nimserver -run TestWireEncoding -update-wire
+//
+// to dump the new version over the current one. Each dump's comment starts
+// with the version it is of, and -update-wire refuses to change the bytes
+// of a dump without a bump.
+const wireVersion = 7
+
+var updateWire = flag.Bool("update-wire", false, "write the canonical packets to testdata/wire/current")
+
+// wireCase is a canonical packet: msg, marshalled by MarshalMove in mode.
+// Legacy packets are sent by the original client, client.go in the
+// repository's root, from its own three field StateMoveMessage under the
+// name ClientMove; the server only reads them, so they are only dumped in
+// testdata/wire/legacy.
+type wireCase struct {
+	name   string
+	msg    StateMoveMessage
+	mode   string
+	legacy bool
+}
+
+var merkleBoard = []uint8{1, 3, 4}
+
+var wireCases = []wireCase{
+	{name: "game_start", msg: StateMoveMessage{GameState: nil, MoveRow: -1, MoveCount: 5}},
+	{name: "client_move", msg: StateMoveMessage{GameState: []uint8{2, 3, 4}, MoveRow: 0, MoveCount: 1}},
+	{name: "client_move_traced", msg: StateMoveMessage{
+		GameState:         []uint8{2, 3, 4},
+		MoveRow:           0,
+		MoveCount:         1,
+		TracingServerAddr: "127.0.0.1:6000",
+		Token:             []byte{0x01, 0x02, 0x03, 0x04},
+	}},
+	{name: "sync", msg: StateMoveMessage{GameState: nil, MoveRow: syncMoveRow}},
+	{name: "session_resume", msg: StateMoveMessage{GameState: nil, MoveRow: sessionResumeMoveRow, GameID: "6f3a9c01d2e4b587"}},
+	{name: "server_board", msg: StateMoveMessage{GameState: []uint8{3, 3, 4}, MoveRow: -1, MoveCount: 5, GameID: "6f3a9c01d2e4b587"}},
+	{name: "server_move", msg: StateMoveMessage{
+		GameState:  merkleBoard,
+		MoveRow:    0,
+		MoveCount:  1,
+		MerkleRoot: nim.ComputeMerkleRoot(merkleBoard),
+		GameID:     "6f3a9c01d2e4b587",
+	}},
+	{name: "server_move_rle", mode: "rle", msg: StateMoveMessage{GameState: []uint8{5, 5, 5, 5, 0, 0, 7}, MoveRow: 6, MoveCount: 2}},
+	{name: "concede", msg: StateMoveMessage{GameState: nil, MoveRow: -2, MoveCount: -2}},
+	{name: "forfeit", msg: StateMoveMessage{MoveRow: forfeitMoveRow, MoveCount: forfeitMoveRow, TracingServerAddr: "127.0.0.1:6000"}},
+	{name: "lasker_start", msg: StateMoveMessage{GameState: nil, MoveRow: -1, MoveCount: 5, Variant: nim.LaskerVariant}},
+	{name: "lasker_split", msg: StateMoveMessage{GameState: []uint8{3, 1, 2, 4}, MoveRow: 1, MoveCount: 0, GameID: "6f3a9c01d2e4b587", Variant: nim.LaskerVariant}},
+	{name: "wythoff_start", msg: StateMoveMessage{GameState: nil, MoveRow: -1, MoveCount: 5, Variant: nim.WythoffVariant}},
+	{name: "wythoff_both", msg: StateMoveMessage{GameState: []uint8{1, 2}, MoveRow: nim.WythoffBothRows, MoveCount: 3, GameID: "6f3a9c01d2e4b587", Variant: nim.WythoffVariant}},
+	{name: "profile_start", msg: StateMoveMessage{GameState: nil, MoveRow: -1, MoveCount: 5, BoardProfile: nim.ProfileSkewed}},
+	{name: "guarantee_start", msg: StateMoveMessage{GameState: nil, MoveRow: -1, MoveCount: 5, BoardGuarantee: nim.GuaranteeSecond}},
+	{name: "match_start", msg: StateMoveMessage{GameState: nil, MoveRow: -1, MoveCount: 5, MatchGames: 3}},
+	{name: "match_opening", msg: StateMoveMessage{GameState: []uint8{1, 3, 4}, MoveRow: 0, MoveCount: 2, GameID: "6f3a9c01d2e4b587", MatchGames: 3, MatchClientWins: 1}},
+	{name: "match_complete", msg: StateMoveMessage{MoveRow: matchCompleteMoveRow, MoveCount: matchCompleteMoveRow, GameID: "6f3a9c01d2e4b587", MatchGames: 3, MatchClientWins: 2, MatchServerWins: 1}},
+	{name: "draining", msg: StateMoveMessage{MoveRow: drainingMoveRow, MoveCount: drainingMoveRow}},
+	{name: "timed_start", msg: StateMoveMessage{GameState: nil, MoveRow: -1, MoveCount: 5, TimeControl: 60000}},
+	{name: "timed_reply", msg: StateMoveMessage{GameState: []uint8{1, 3, 4}, MoveRow: 0, MoveCount: 1, GameID: "6f3a9c01d2e4b587", TimeControl: 60000, ClientClock: 50001, ServerClock: 59999, Seq: 2}},
+	{name: "timed_move", msg: StateMoveMessage{GameState: []uint8{1, 3, 3}, MoveRow: 2, MoveCount: 1, Seq: 2}},
+	{name: "dry_run_board", msg: StateMoveMessage{GameState: []uint8{3, 3, 4}, MoveRow: -1, MoveCount: 5, DryRun: true}},
+	{name: "timeout", msg: StateMoveMessage{MoveRow: forfeitMoveRow, MoveCount: forfeitMoveRow, TimeControl: 60000, ServerClock: 59998, Seq: 5}},
+	{name: "legacy_game_start", legacy: true, msg: StateMoveMessage{GameState: nil, MoveRow: -1, MoveCount: 5}},
+	{name: "legacy_move", legacy: true, msg: StateMoveMessage{GameState: []uint8{2, 3, 4}, MoveRow: 0, MoveCount: 1}},
+}
+
+// wireDump is the path of the dump of the canonical packet name in dir,
+// current or legacy.
+func wireDump(dir, name string) string {
+	return filepath.Join("testdata", "wire", dir, name+".hex")
+}
+
+// readWireDump reads the packet dumped at path.
+func readWireDump(t *testing.T, path string) []byte {
+	packet, err := wiredump.Read(path)
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	return packet
+}
+
+// TestWireEncoding guards against changing the protocol by accident: every
+// canonical message must marshal to exactly the bytes dumped for the
+// current wireVersion.
+func TestWireEncoding(t *testing.T) {
+	for _, c := range wireCases {
+		if c.legacy {
+			continue
+		}
+		packet, err := MarshalMove(c.msg, c.mode)
+		if err != nil {
+			t.Fatalf("%v: marshalling %+v: %v\n", c.name, c.msg, err)
+		}
+		path := wireDump("current", c.name)
+		if *updateWire {
+			// the dumps of a version are never rewritten with other bytes
+			if want, err := wiredump.Read(path); err == nil && !bytes.Equal(packet, want) && dumpVersion(t, path) == wireVersion {
+				t.Fatalf("%v: encoded as\n%x\nnot as dumped for version %d\n%x\nbump wireVersion to dump a new version\n", c.name, packet, wireVersion, want)
+			}
+			if err := wiredump.Write(path, fmt.Sprintf("v%d: %+v", wireVersion, c.msg), packet); err != nil {
+				t.Fatalf("%v\n", err)
+			}
+			continue
+		}
+		if want := readWireDump(t, path); !bytes.Equal(packet, want) {
+			t.Errorf("%v: encoded as\n%x\nnot as dumped in %v\n%x\nif the protocol is meant to change, bump wireVersion\n", c.name, packet, path, want)
+		}
+	}
+}
+
+// dumpVersion returns the wire version the dump at path is of, as the v<N>
+// its comment starts with has it.
+func dumpVersion(t *testing.T, path string) int {
+	comment, err := wiredump.Comment(path)
+	if err != nil {
+		t.Fatalf("%v\n", err)
+	}
+	var version int
+	if _, err := fmt.Sscanf(comment, "v%d:", &version); err != nil {
+		t.Fatalf("%v: comment %q doesn't start with the wire version: %v\n", path, comment, err)
+	}
+	return version
+}
+
+// TestWireDecoding checks the packets dumped for this wire version, which
+// must be marked as such, and the legacy ones, still decode to their
+// canonical messages.
+func TestWireDecoding(t *testing.T) {
+	for _, dir := range []string{"current", "legacy"} {
+		for _, c := range wireCases {
+			if c.legacy && dir == "current" {
+				continue
+			}
+			path := wireDump(dir, c.name)
+			if _, err := os.Stat(path); os.IsNotExist(err) && dir == "legacy" {
+				continue // added after version 1
+			}
+			if dir == "current" {
+				if version := dumpVersion(t, path); version != wireVersion {
+					t.Errorf("%v is dumped for version %d, but wireVersion is %d; run -update-wire\n", path, version, wireVersion)
+				}
+			}
+			var got StateMoveMessage
+			if err := UnmarshalMove(readWireDump(t, path), &got, defaultMaxBoardRows); err != nil {
+				t.Errorf("%v: decoding: %v\n", path, err)
+				continue
+			}
+			if !reflect.DeepEqual(got, c.msg) {
+				t.Errorf("%v decoded to %+v, expected %+v\n", path, got, c.msg)
+			}
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package nimserver
+
+import (
+	"errors"
+	"io"
+	"math"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+// wsMessage is a JSON frame on /ws, a StateMoveMessage as a browser can
+// read and write it. Clients send a GameStart, a move, or a Sync or
+// SessionResume, as they would over UDP; the server answers with frames
+// naming an Event.
+type wsMessage struct {
+	Event     string `json:"event,omitempty"` // in frames from the server
+	GameID    string `json:"game_id,omitempty"`
+	GameState []int  `json:"game_state"` // []uint8 would encode as base64
+	MoveRow   int    `json:"move_row"`
+	MoveCount int    `json:"move_count"`
+	Winner    string `json:"winner,omitempty"` // with gameComplete
+	Error     string `json:"error,omitempty"`  // with error
+}
+
+// The events of frames from the server.
+const (
+	wsGameStarted  = "gameStarted"  // answers a GameStart
+	wsGameResumed  = "gameResumed"  // answers a Sync or SessionResume with the last reply
+	wsServerMoved  = "serverMoved"  // the server's reply to a valid move
+	wsInvalidMove  = "invalidMove"  // the move was rejected; the frame is the last reply again
+	wsGameComplete = "gameComplete" // follows the move that ended the game
+	wsError        = "error"        // the frame couldn't be played
+)
+
+// serveWebSocket plays the games of one WebSocket client, one at a time,
+// in a session of the connection's own. Should the client drop mid-game,
+// it has WebSocketGrace to resume the game from a new connection before
+// resigning it.
+func (s *Server) serveWebSocket(ws *websocket.Conn) {
+	key := wsKeyPrefix + newGameID()
+	defer s.wsDisconnected(key)
+	for {
+		var msg wsMessage
+		if err := websocket.JSON.Receive(ws, &msg); err != nil {
+			if !errors.Is(err, io.EOF) {
+				s.logger().Debug("websocket closed", "err", err)
+			}
+			return
+		}
+		for _, frame := range s.wsRespond(key, msg) {
+			if err := websocket.JSON.Send(ws, frame); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// wsRespond plays msg in the game kept under key and returns the frames
+// answering it.
+func (s *Server) wsRespond(key string, msg wsMessage) []wsMessage {
+	move := StateMoveMessage{GameID: msg.GameID}
+	if msg.MoveRow < math.MinInt8 || msg.MoveRow > math.MaxInt8 || msg.MoveCount < math.MinInt8 || msg.MoveCount > math.MaxInt8 {
+		return []wsMessage{{Event: wsError, Error: "move_row and move_count must be between -128 and 127"}}
+	}
+	move.MoveRow, move.MoveCount = int8(msg.MoveRow), int8(msg.MoveCount)
+	if msg.GameState != nil {
+		move.GameState = []uint8{}
+		for _, coins := range msg.GameState {
+			if coins < 0 || coins > math.MaxUint8 {
+				return []wsMessage{{Event: wsError, Error: "game_state rows must be between 0 and 255"}}
+			}
+			move.GameState = append(move.GameState, uint8(coins))
+		}
+	}
+
+	s.gameMu.Lock()
+	defer s.gameMu.Unlock()
+	res := s.respondTraced(key, move)
+	if res == nil {
+		return []wsMessage{{Event: wsError, Error: "no game in progress"}}
+	}
+	if res.refused {
+		return []wsMessage{{Event: wsError, Error: errDraining.Error()}}
+	}
+	reply := wsFrame(res.reply)
+	switch {
+	case move.GameState == nil && move.MoveRow == -1:
+		reply.Event = wsGameStarted
+	case move.GameState == nil:
+		// a resumed game brings along the timer of the connection it
+		// dropped from
+		res.sess.stopMoveTimer()
+		reply.Event = wsGameResumed
+	case res.rejected:
+		reply.Event = wsInvalidMove
+	case res.reply.MoveRow >= 0:
+		reply.Event = wsServerMoved
+	}
+	frames := []wsMessage{reply}
+	if reply.Event == "" {
+		// the server conceded, so the game is complete and there's no
+		// move to report
+		frames = nil
+	}
+	if res.winner != "" {
+		complete := wsFrame(res.reply)
+		complete.Event, complete.Winner = wsGameComplete, res.winner
+		frames = append(frames, complete)
+	}
+	return frames
+}
+
+// wsFrame is reply as a frame.
+func wsFrame(reply StateMoveMessage) wsMessage {
+	frame := wsMessage{GameID: reply.GameID, GameState: []int{}, MoveRow: int(reply.MoveRow), MoveCount: int(reply.MoveCount)}
+	for _, coins := range reply.GameState {
+		frame.GameState = append(frame.GameState, int(coins))
+	}
+	return frame
+}
+
+// wsDisconnected forgets the finished game of the connection kept under
+// key, or gives its game in progress WebSocketGrace to be resumed before
+// resigning it.
+func (s *Server) wsDisconnected(key string) {
+	s.gameMu.Lock()
+	defer s.gameMu.Unlock()
+	sess := s.session(key)
+	if sess == nil {
+		return
+	}
+	select {
+	case <-s.finished:
+		// no moves are handled any more, so nor is a resign
+		return
+	default:
+	}
+	if !sess.Playing {
+		s.sessionsMu.Lock()
+		delete(s.sessions, key)
+		s.sessionsMu.Unlock()
+		s.persist(key)
+		return
+	}
+	gameID, grace := sess.GameID, s.config.webSocketGrace()
+	sess.stopMoveTimer()
+	sess.moveTimer = time.AfterFunc(grace, func() {
+		s.gameMu.Lock()
+		defer s.gameMu.Unlock()
+		// a resumed game has moved on to another connection's key
+		sess := s.session(key)
+		if sess == nil || !sess.Playing || sess.GameID != gameID {
+			return
+		}
+		s.logger().Info("websocket client resigned", "game", gameID, "grace", grace)
+		s.resign(key, sess, "")
+	})
+}
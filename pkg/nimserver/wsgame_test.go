@@ -0,0 +1,117 @@
+package nimserver
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+// dialWebSocket connects to the /ws endpoint of ts.
+func dialWebSocket(t *testing.T, ts *httptest.Server) *websocket.Conn {
+	url := "ws" + strings.TrimPrefix(ts.URL, "http") + "/ws"
+	ws, err := websocket.Dial(url, "", ts.URL)
+	if err != nil {
+		t.Fatalf("dialing %v: %v\n", url, err)
+	}
+	t.Cleanup(func() { ws.Close() })
+	return ws
+}
+
+// wsExchange sends msg and returns the next frame.
+func wsExchange(t *testing.T, ws *websocket.Conn, msg wsMessage) wsMessage {
+	t.Helper()
+	if err := websocket.JSON.Send(ws, msg); err != nil {
+		t.Fatalf("sending %v: %v\n", msg, err)
+	}
+	return wsReceive(t, ws)
+}
+
+func wsReceive(t *testing.T, ws *websocket.Conn) wsMessage {
+	t.Helper()
+	ws.SetReadDeadline(time.Now().Add(2 * time.Second))
+	var frame wsMessage
+	if err := websocket.JSON.Receive(ws, &frame); err != nil {
+		t.Fatalf("receiving frame: %v\n", err)
+	}
+	return frame
+}
+
+// wsMove is the move bestMove picks on frame's board.
+func wsMove(frame wsMessage) wsMessage {
+	board := make([]uint8, len(frame.GameState))
+	for i, coins := range frame.GameState {
+		board[i] = uint8(coins)
+	}
+	move := wsFrame(bestMove(board))
+	move.GameID = frame.GameID
+	return move
+}
+
+func TestWebSocketGame(t *testing.T) {
+	server := newServer(&ServerConfig{AdminGamesEnabled: true}, nil, nil)
+	ts := httptest.NewServer(server.adminHandler())
+	defer ts.Close()
+	ws := dialWebSocket(t, ts)
+
+	frame := wsExchange(t, ws, wsMessage{GameState: nil, MoveRow: -1, MoveCount: 4})
+	if frame.Event != wsGameStarted || frame.GameID == "" || len(frame.GameState) == 0 {
+		t.Fatalf("GameStart answered with %+v\n", frame)
+	}
+	added := append([]int(nil), frame.GameState...)
+	added[0]++
+	illegal := wsMessage{GameID: frame.GameID, GameState: added, MoveRow: 0, MoveCount: 1}
+	if got := wsExchange(t, ws, illegal); got.Event != wsInvalidMove {
+		t.Errorf("coin added answered with %+v, expected invalidMove\n", got)
+	}
+
+	for frame.Event != wsGameComplete {
+		frame = wsExchange(t, ws, wsMove(frame))
+		if frame.Event != wsServerMoved && frame.Event != wsGameComplete {
+			t.Fatalf("move answered with %+v\n", frame)
+		}
+	}
+	if frame.Winner != "client" || frame.MoveRow != -2 {
+		t.Errorf("game completed with %+v, expected the server to concede\n", frame)
+	}
+	if stats := server.Stats(); stats.GamesStarted != 1 || stats.ClientWins != 1 || stats.InvalidMoves != 1 {
+		t.Errorf("stats are %+v, expected one game won by the client and one invalid move\n", stats)
+	}
+}
+
+func TestWebSocketDropResigns(t *testing.T) {
+	server := newServer(&ServerConfig{AdminGamesEnabled: true, WebSocketGrace: 1}, nil, nil)
+	ts := httptest.NewServer(server.adminHandler())
+	defer ts.Close()
+
+	// a game resumed within the grace period carries on
+	first := dialWebSocket(t, ts)
+	frame := wsExchange(t, first, wsMessage{GameState: nil, MoveRow: -1, MoveCount: 6})
+	first.Close()
+	time.Sleep(100 * time.Millisecond)
+	second := dialWebSocket(t, ts)
+	resumed := wsExchange(t, second, wsMessage{MoveRow: sessionResumeMoveRow, GameID: frame.GameID})
+	if resumed.Event != wsGameResumed || resumed.GameID != frame.GameID {
+		t.Fatalf("resume answered with %+v\n", resumed)
+	}
+	time.Sleep(1500 * time.Millisecond)
+	if got := wsExchange(t, second, wsMove(resumed)); got.Event != wsServerMoved && got.Event != wsGameComplete {
+		t.Errorf("move after resuming answered with %+v\n", got)
+	}
+
+	// one left dropped resigns
+	second.Close()
+	time.Sleep(100 * time.Millisecond)
+	if len(server.ActiveGames()) != 1 {
+		t.Fatalf("dropped game not kept for the grace period\n")
+	}
+	time.Sleep(1500 * time.Millisecond)
+	if games := server.ActiveGames(); len(games) != 0 {
+		t.Errorf("games %+v still in progress after the grace period\n", games)
+	}
+	if stats := server.Stats(); stats.ServerWins != 1 {
+		t.Errorf("stats are %+v, expected the dropped game won by the server\n", stats)
+	}
+}
@@ -0,0 +1,125 @@
+package nimserver
+
+import (
+	"testing"
+
+	"nimgame/pkg/nim"
+)
+
+// TestWythoffGame plays Wythoff's game through to the end, taking a coin
+// from both heaps whenever it can, and checks the server's moves are
+// Wythoff's game's, move to cold positions where there is one, and win it
+// the game.
+func TestWythoffGame(t *testing.T) {
+	server, raddr := serveOnLoopback(t, &ServerConfig{WythoffEnabled: true}, nil)
+	c := newTestClient(t, raddr, nil)
+
+	// an odd seed plays the server's best moves
+	reply := c.exchange(StateMoveMessage{GameState: nil, MoveRow: -1, MoveCount: 3, Variant: nim.WythoffVariant})
+	if reply.Variant != nim.WythoffVariant || len(reply.GameState) != 2 {
+		t.Fatalf("GameStart answered %+v, expected two heaps of Wythoff's game\n", reply)
+	}
+	both := 0
+	for !emptyBoard(reply.GameState) {
+		board := reply.GameState
+		move := StateMoveMessage{GameState: []uint8{board[0], board[1]}}
+		if board[0] > 0 && board[1] > 0 {
+			move.GameState[0]--
+			move.GameState[1]--
+			move.MoveRow, move.MoveCount = nim.WythoffBothRows, 1
+			both++
+		} else {
+			row, count := nim.Basic{}.Move(board)
+			move.GameState[row] -= count
+			move.MoveRow, move.MoveCount = int8(row), int8(count)
+		}
+		if emptyBoard(move.GameState) {
+			c.exchange(move)
+			t.Fatalf("the client won against the server's best moves\n")
+		}
+		reply = c.exchange(move)
+		if reply.Variant != nim.WythoffVariant || !nim.ValidWythoffMove(move.GameState, reply.GameState) {
+			t.Fatalf("the server answered %v with %+v\n", move.GameState, reply)
+		}
+		if !nim.IsWythoffCold(move.GameState[0], move.GameState[1]) && !nim.IsWythoffCold(reply.GameState[0], reply.GameState[1]) {
+			t.Errorf("the server moved %v to %v, which isn't cold\n", move.GameState, reply.GameState)
+		}
+	}
+	if stats := server.Stats(); both == 0 || stats.InvalidMoves != 0 || stats.ServerWins != 1 {
+		t.Errorf("%d moves from both heaps, server stats %+v, expected the server to accept them and win\n", both, stats)
+	}
+}
+
+// TestWythoffNotEnabled checks a server without WythoffEnabled plays nim on
+// its usual boards with a client asking for Wythoff's game.
+func TestWythoffNotEnabled(t *testing.T) {
+	_, raddr := serveOnLoopback(t, &ServerConfig{LaskerEnabled: true}, nil)
+	c := newTestClient(t, raddr, nil)
+
+	reply := c.exchange(StateMoveMessage{GameState: nil, MoveRow: -1, MoveCount: 3, Variant: nim.WythoffVariant})
+	if reply.Variant != "" || len(reply.GameState) < 3 {
+		t.Fatalf("GameStart answered %+v, expected nim\n", reply)
+	}
+}
+
+// TestWythoffGameVariant checks a server with GameVariant "wythoff" plays
+// Wythoff's game with a client that doesn't ask for it.
+func TestWythoffGameVariant(t *testing.T) {
+	_, raddr := serveOnLoopback(t, &ServerConfig{GameVariant: nim.WythoffVariant}, nil)
+	c := newTestClient(t, raddr, nil)
+
+	reply := c.exchange(StateMoveMessage{GameState: nil, MoveRow: -1, MoveCount: 3})
+	if reply.Variant != nim.WythoffVariant || len(reply.GameState) != 2 {
+		t.Fatalf("GameStart answered %+v, expected two heaps of Wythoff's game\n", reply)
+	}
+	move := StateMoveMessage{GameState: []uint8{reply.GameState[0] - 1, reply.GameState[1] - 1}, MoveRow: nim.WythoffBothRows, MoveCount: 1}
+	if next := c.exchange(move); !nim.ValidWythoffMove(move.GameState, next.GameState) {
+		t.Errorf("the server answered %v with %+v\n", move.GameState, next)
+	}
+}
+
+// TestWythoffBestMove checks wythoffBestMove moves every warm board of
+// heaps up to 40 to a cold one, by a move CheckMove accepts.
+func TestWythoffBestMove(t *testing.T) {
+	for a := 0; a <= 40; a++ {
+		for b := 0; b <= 40; b++ {
+			if nim.IsWythoffCold(uint8(a), uint8(b)) {
+				continue
+			}
+			board := []uint8{uint8(a), uint8(b)}
+			move := wythoffBestMove(append([]uint8(nil), board...))
+			if len(move.GameState) != 2 || !nim.IsWythoffCold(move.GameState[0], move.GameState[1]) {
+				t.Fatalf("wythoffBestMove(%v) = %+v, which isn't cold\n", board, move)
+			}
+			last := StateMoveMessage{GameState: board, Variant: nim.WythoffVariant}
+			if ok, err := CheckMove(move, last, &ServerConfig{MaxCoinsPerRow: 40}); !ok || err != nil {
+				t.Fatalf("wythoffBestMove(%v) = %+v, which CheckMove refuses: %v\n", board, move, err)
+			}
+		}
+	}
+}
+
+func TestCheckMoveWythoff(t *testing.T) {
+	config := &ServerConfig{}
+	last := StateMoveMessage{GameState: []uint8{4, 7}, Variant: nim.WythoffVariant}
+	tests := []struct {
+		move StateMoveMessage
+		want bool
+	}{
+		{StateMoveMessage{GameState: []uint8{3, 5}, MoveRow: nim.WythoffBothRows, MoveCount: 1}, false},
+		{StateMoveMessage{GameState: []uint8{3, 6}, MoveRow: nim.WythoffBothRows, MoveCount: 1}, true},
+		{StateMoveMessage{GameState: []uint8{4, 5}, MoveRow: 1, MoveCount: 2}, true},
+		{StateMoveMessage{GameState: []uint8{0, 3}, MoveRow: nim.WythoffBothRows, MoveCount: 4}, true},
+		{StateMoveMessage{GameState: []uint8{4, 7, 1}, MoveRow: 2, MoveCount: 1}, false},
+	}
+	for _, test := range tests {
+		if got, err := CheckMove(test.move, last, config); got != test.want || err != nil {
+			t.Errorf("CheckMove(%+v) = %v, %v, expected %v\n", test.move, got, err, test.want)
+		}
+	}
+	// without the variant taking from both is refused
+	last.Variant = ""
+	if got, _ := CheckMove(tests[1].move, last, config); got {
+		t.Errorf("CheckMove accepted taking from both rows in nim\n")
+	}
+}
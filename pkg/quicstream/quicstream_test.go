@@ -0,0 +1,68 @@
+package quicstream
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"testing"
+	"testing/iotest"
+)
+
+// flakyReader returns a deadline error once, after the first n bytes.
+type flakyReader struct {
+	r     *bytes.Reader
+	n     int
+	fired bool
+}
+
+func (f *flakyReader) Read(p []byte) (int, error) {
+	if !f.fired && f.n == 0 {
+		f.fired = true
+		return 0, os.ErrDeadlineExceeded
+	}
+	if !f.fired && len(p) > f.n {
+		p = p[:f.n]
+	}
+	n, err := f.r.Read(p)
+	f.n -= n
+	return n, err
+}
+
+func TestFrames(t *testing.T) {
+	var stream bytes.Buffer
+	payloads := [][]byte{[]byte("first"), {}, []byte("third move")}
+	for _, p := range payloads {
+		if err := WriteFrame(&stream, p); err != nil {
+			t.Fatalf("writing frame %q: %v\n", p, err)
+		}
+	}
+
+	for _, r := range []struct {
+		name   string
+		frames *FrameReader
+	}{
+		{"whole", NewFrameReader(bytes.NewReader(stream.Bytes()))},
+		{"a byte at a time", NewFrameReader(iotest.OneByteReader(bytes.NewReader(stream.Bytes())))},
+		{"cut short mid-frame", NewFrameReader(&flakyReader{r: bytes.NewReader(stream.Bytes()), n: 6})},
+	} {
+		for i, want := range payloads {
+			got, err := r.frames.ReadFrame()
+			if errors.Is(err, os.ErrDeadlineExceeded) {
+				got, err = r.frames.ReadFrame()
+			}
+			if err != nil || !bytes.Equal(got, want) {
+				t.Errorf("%v: frame %d is %q, %v, expected %q\n", r.name, i, got, err, want)
+			}
+		}
+	}
+}
+
+func TestOversizedFrame(t *testing.T) {
+	if err := WriteFrame(&bytes.Buffer{}, make([]byte, MaxFrame+1)); err == nil {
+		t.Errorf("wrote a frame over MaxFrame\n")
+	}
+	header := []byte{0xff, 0xff, 0xff, 0xff}
+	if _, err := NewFrameReader(bytes.NewReader(header)).ReadFrame(); err == nil {
+		t.Errorf("read a frame over MaxFrame\n")
+	}
+}
@@ -0,0 +1,109 @@
+// Package tcpframe carries nim moves over TCP, for networks that block UDP,
+// each game over a connection of its own. A move is sent as it is over
+// UDP, gob encoded, framed by a 2-byte big-endian length.
+package tcpframe
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+)
+
+// MaxFrame is the largest payload a frame's length can give, well beyond
+// any move.
+const MaxFrame = 1<<16 - 1
+
+// WriteFrame writes payload to w as one frame.
+func WriteFrame(w io.Writer, payload []byte) error {
+	if len(payload) > MaxFrame {
+		return fmt.Errorf("frame of %d bytes is over %d", len(payload), MaxFrame)
+	}
+	frame := make([]byte, 2+len(payload))
+	binary.BigEndian.PutUint16(frame, uint16(len(payload)))
+	copy(frame[2:], payload)
+	_, err := w.Write(frame)
+	return err
+}
+
+// FrameReader reads frames from a connection. A read cut short, by a
+// deadline say, leaves what was read of the frame to be finished by the
+// next call.
+type FrameReader struct {
+	r       io.Reader
+	pending []byte // read but not yet returned
+	buf     []byte
+}
+
+// NewFrameReader returns a FrameReader reading from r.
+func NewFrameReader(r io.Reader) *FrameReader {
+	return &FrameReader{r: r, buf: make([]byte, 4096)}
+}
+
+// ReadFrame reads the next frame and returns its payload.
+func (f *FrameReader) ReadFrame() ([]byte, error) {
+	for {
+		if payload := f.next(); payload != nil {
+			return payload, nil
+		}
+		n, err := f.r.Read(f.buf)
+		f.pending = append(f.pending, f.buf[:n]...)
+		if err != nil {
+			// a frame completed by the failed read is still returned, the
+			// error, if it lasts, coming with the next read
+			if payload := f.next(); payload != nil {
+				return payload, nil
+			}
+			return nil, err
+		}
+	}
+}
+
+// next takes the first frame off pending, returning nil if it isn't all
+// there yet.
+func (f *FrameReader) next() []byte {
+	if len(f.pending) < 2 {
+		return nil
+	}
+	end := 2 + int(binary.BigEndian.Uint16(f.pending))
+	if len(f.pending) < end {
+		return nil
+	}
+	payload := append([]byte{}, f.pending[2:end]...)
+	f.pending = f.pending[end:]
+	return payload
+}
+
+// Conn is a TCP connection that reads and writes whole frames, so it can
+// stand in for a connected UDP socket: each Write sends one frame, and each
+// Read returns the payload of one.
+type Conn struct {
+	net.Conn
+	frames *FrameReader
+}
+
+// NewConn returns conn framed as a Conn.
+func NewConn(conn net.Conn) *Conn {
+	return &Conn{Conn: conn, frames: NewFrameReader(conn)}
+}
+
+// Read reads the next frame into p, failing with io.ErrShortBuffer if p
+// can't hold it.
+func (c *Conn) Read(p []byte) (int, error) {
+	payload, err := c.frames.ReadFrame()
+	if err != nil {
+		return 0, err
+	}
+	if len(payload) > len(p) {
+		return 0, io.ErrShortBuffer
+	}
+	return copy(p, payload), nil
+}
+
+// Write sends p as one frame.
+func (c *Conn) Write(p []byte) (int, error) {
+	if err := WriteFrame(c.Conn, p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
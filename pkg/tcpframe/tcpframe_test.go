@@ -0,0 +1,85 @@
+package tcpframe
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"testing"
+	"testing/iotest"
+)
+
+// flakyReader returns a deadline error once, after the first n bytes.
+type flakyReader struct {
+	r     *bytes.Reader
+	n     int
+	fired bool
+}
+
+func (f *flakyReader) Read(p []byte) (int, error) {
+	if !f.fired && f.n == 0 {
+		f.fired = true
+		return 0, os.ErrDeadlineExceeded
+	}
+	if !f.fired && len(p) > f.n {
+		p = p[:f.n]
+	}
+	n, err := f.r.Read(p)
+	f.n -= n
+	return n, err
+}
+
+func TestFrames(t *testing.T) {
+	var conn bytes.Buffer
+	payloads := [][]byte{[]byte("first"), {}, []byte("third move"), make([]byte, MaxFrame)}
+	for _, p := range payloads {
+		if err := WriteFrame(&conn, p); err != nil {
+			t.Fatalf("writing frame %q: %v\n", p, err)
+		}
+	}
+
+	for _, r := range []struct {
+		name   string
+		frames *FrameReader
+	}{
+		{"whole", NewFrameReader(bytes.NewReader(conn.Bytes()))},
+		{"a byte at a time", NewFrameReader(iotest.OneByteReader(bytes.NewReader(conn.Bytes())))},
+		{"cut short mid-frame", NewFrameReader(&flakyReader{r: bytes.NewReader(conn.Bytes()), n: 4})},
+	} {
+		for i, want := range payloads {
+			got, err := r.frames.ReadFrame()
+			if errors.Is(err, os.ErrDeadlineExceeded) {
+				got, err = r.frames.ReadFrame()
+			}
+			if err != nil || !bytes.Equal(got, want) {
+				t.Errorf("%v: frame %d is %d bytes, %v, expected %d\n", r.name, i, len(got), err, len(want))
+			}
+		}
+	}
+}
+
+func TestOversizedFrame(t *testing.T) {
+	if err := WriteFrame(&bytes.Buffer{}, make([]byte, MaxFrame+1)); err == nil {
+		t.Errorf("wrote a frame over MaxFrame\n")
+	}
+}
+
+func TestConn(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+	c, s := NewConn(client), NewConn(server)
+	go func() {
+		c.Write([]byte("move"))
+		c.Write([]byte("a move too long"))
+	}()
+
+	buf := make([]byte, 8)
+	if n, err := s.Read(buf); err != nil || string(buf[:n]) != "move" {
+		t.Errorf("read %q, %v, expected \"move\"\n", buf[:n], err)
+	}
+	if _, err := s.Read(buf); !errors.Is(err, io.ErrShortBuffer) {
+		t.Errorf("reading a frame too long for the buffer got %v, expected io.ErrShortBuffer\n", err)
+	}
+}
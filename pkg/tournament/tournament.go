@@ -0,0 +1,152 @@
+// Package tournament runs round-robin competitions between nim players,
+// every player meeting every other on each of a list of seeds' boards, and
+// ranks them by the matches they win. Games are played out in memory with
+// nim.SimulateGame; no server is involved.
+package tournament
+
+import (
+	"cmp"
+	"slices"
+
+	"nimgame/pkg/nim"
+	"nimgame/pkg/nimserver"
+)
+
+// PlayerFunc picks a player's move on board, which has at least one coin
+// left, returning the board after it as the reply to a client would carry
+// it. It may change board.
+type PlayerFunc func(board []uint8) nimserver.StateMoveMessage
+
+// NaivePlayer takes one coin from the first non-empty row, as the server's
+// normal strategy does.
+func NaivePlayer(board []uint8) nimserver.StateMoveMessage {
+	return nimserver.Play(nimserver.StateMoveMessage{GameState: board}, 0)
+}
+
+// OptimalPlayer leaves a zero nim-sum whenever it can, as the server's best
+// strategy does.
+func OptimalPlayer(board []uint8) nimserver.StateMoveMessage {
+	return nimserver.Play(nimserver.StateMoveMessage{GameState: board}, 1)
+}
+
+// Tournament is a round robin between Players on the board of each of
+// Seeds, as nim.GenerateBoard makes it.
+type Tournament struct {
+	Seeds   []int8
+	Players []PlayerFunc
+	// games in every match, the first player moving first in the even
+	// ones; zero means 1. An odd number always finds a winner.
+	BestOf int
+}
+
+// Match is a meeting of two players on one seed's board.
+type Match struct {
+	Seed    int8
+	Players [2]int // indices into Tournament.Players
+	Wins    [2]int // games won by each of Players
+	Winner  int    // index into Tournament.Players, or -1 for a draw
+}
+
+// Standing is one player's record in a tournament.
+type Standing struct {
+	Player int // index into Tournament.Players
+	Wins   int // matches won
+	Losses int
+	Draws  int
+	Games  int // games won, across every match
+}
+
+// TournamentResult is the record of a tournament.
+type TournamentResult struct {
+	Matches []Match // in the order played
+	// every player, by matches won, then games won, then index
+	Standings []Standing
+}
+
+// Run plays every player against every other on each seed, Players[i]
+// meeting Players[j] for each i != j, so every pair meets twice a seed
+// with each player going first in the match's first game.
+func (t *Tournament) Run() TournamentResult {
+	bestOf := t.BestOf
+	if bestOf <= 0 {
+		bestOf = 1
+	}
+	result := TournamentResult{Standings: make([]Standing, len(t.Players))}
+	for i := range result.Standings {
+		result.Standings[i].Player = i
+	}
+	for _, seed := range t.Seeds {
+		board := nim.GenerateBoard(int64(seed))
+		for i := range t.Players {
+			for j := range t.Players {
+				if i == j {
+					continue
+				}
+				match := t.play(board, seed, i, j, bestOf)
+				result.Matches = append(result.Matches, match)
+				result.record(match)
+			}
+		}
+	}
+	slices.SortFunc(result.Standings, func(a, b Standing) int {
+		return cmp.Or(b.Wins-a.Wins, b.Games-a.Games, a.Player-b.Player)
+	})
+	return result
+}
+
+// play plays the match between Players[i] and Players[j] on board, ending
+// it once either has won more than half of bestOf games.
+func (t *Tournament) play(board []uint8, seed int8, i, j, bestOf int) Match {
+	match := Match{Seed: seed, Players: [2]int{i, j}, Winner: -1}
+	first := playerStrategy(t.Players[i])
+	second := playerStrategy(t.Players[j])
+	for game := 0; game < bestOf && max(match.Wins[0], match.Wins[1])*2 <= bestOf; game++ {
+		if game%2 == 0 {
+			if nim.SimulateGame(board, first, second) {
+				match.Wins[0]++
+			} else {
+				match.Wins[1]++
+			}
+		} else {
+			if nim.SimulateGame(board, second, first) {
+				match.Wins[1]++
+			} else {
+				match.Wins[0]++
+			}
+		}
+	}
+	if match.Wins[0] > match.Wins[1] {
+		match.Winner = i
+	} else if match.Wins[1] > match.Wins[0] {
+		match.Winner = j
+	}
+	return match
+}
+
+// record adds match to the standings, which are still by player.
+func (r *TournamentResult) record(match Match) {
+	for k, player := range match.Players {
+		st := &r.Standings[player]
+		st.Games += match.Wins[k]
+		switch match.Winner {
+		case -1:
+			st.Draws++
+		case player:
+			st.Wins++
+		default:
+			st.Losses++
+		}
+	}
+}
+
+// playerStrategy is a PlayerFunc as the nim.Strategy SimulateGame plays.
+type playerStrategy PlayerFunc
+
+func (p playerStrategy) Move(board []uint8) (int, uint8) {
+	// Play gives up on an empty board with a negative row
+	move := p(append([]uint8(nil), board...))
+	if move.MoveRow < 0 {
+		return -1, 0
+	}
+	return int(move.MoveRow), uint8(move.MoveCount)
+}
@@ -0,0 +1,51 @@
+package tournament
+
+import (
+	"testing"
+
+	"nimgame/pkg/nimserver"
+)
+
+// greedyPlayer empties the first non-empty row.
+func greedyPlayer(board []uint8) nimserver.StateMoveMessage {
+	for row, coins := range board {
+		if coins > 0 {
+			board[row] = 0
+			return nimserver.StateMoveMessage{GameState: board, MoveRow: int8(row), MoveCount: int8(coins)}
+		}
+	}
+	return nimserver.StateMoveMessage{MoveRow: -2, MoveCount: -2}
+}
+
+func TestOptimalBeatsNaive(t *testing.T) {
+	const naive, optimal, greedy = 0, 1, 2
+	tour := &Tournament{
+		Seeds:   []int8{1, 2, 3, 4, 5, 6, 7, 8},
+		Players: []PlayerFunc{NaivePlayer, OptimalPlayer, greedyPlayer},
+		BestOf:  3,
+	}
+	result := tour.Run()
+
+	// 6 ordered pairs of players meet on each seed
+	if len(result.Matches) != 6*len(tour.Seeds) {
+		t.Fatalf("%d matches played, expected %d\n", len(result.Matches), 6*len(tour.Seeds))
+	}
+	for _, m := range result.Matches {
+		if (m.Players[0] == optimal && m.Players[1] == naive) || (m.Players[0] == naive && m.Players[1] == optimal) {
+			if m.Winner != optimal {
+				t.Errorf("seed %d: naive beat optimal %v\n", m.Seed, m)
+			}
+		}
+		if m.Wins[0]+m.Wins[1] > tour.BestOf || max(m.Wins[0], m.Wins[1])*2 <= tour.BestOf {
+			t.Errorf("seed %d: match %v isn't best of %d\n", m.Seed, m, tour.BestOf)
+		}
+	}
+	if top := result.Standings[0]; top.Player != optimal || top.Losses != 0 {
+		t.Errorf("standings %+v, expected optimal unbeaten on top\n", result.Standings)
+	}
+	for _, st := range result.Standings {
+		if st.Wins+st.Losses+st.Draws != 4*len(tour.Seeds) {
+			t.Errorf("player %d played %d matches, expected %d\n", st.Player, st.Wins+st.Losses+st.Draws, 4*len(tour.Seeds))
+		}
+	}
+}
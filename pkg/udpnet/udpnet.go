@@ -0,0 +1,34 @@
+// Package udpnet picks the UDP network, IPv4 or IPv6, an address is
+// resolved and listened on in, rather than leaving it to "udp", which
+// resolves host names to IPv4 addresses first and listens on both.
+package udpnet
+
+import (
+	"net"
+	"strings"
+)
+
+// Network is "udp6" for addr, a host:port, if its host is in IPv6
+// notation, as in "[::1]:41600", or if ipv6 is set and its host is a
+// name or empty. An empty host is otherwise left to "udp", to listen on
+// both, as is an address that isn't host:port, to fail resolving as it
+// always has; anything else is "udp4".
+func Network(addr string, ipv6 bool) string {
+	host, _, err := net.SplitHostPort(addr)
+	switch {
+	case err != nil:
+		return "udp"
+	case strings.Contains(host, ":"):
+		return "udp6"
+	case ipv6 && net.ParseIP(host) == nil:
+		return "udp6"
+	case host == "":
+		return "udp"
+	}
+	return "udp4"
+}
+
+// ResolveAddr resolves addr in the network Network picks for it.
+func ResolveAddr(addr string, ipv6 bool) (*net.UDPAddr, error) {
+	return net.ResolveUDPAddr(Network(addr, ipv6), addr)
+}
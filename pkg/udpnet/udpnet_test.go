@@ -0,0 +1,38 @@
+package udpnet
+
+import "testing"
+
+func TestNetwork(t *testing.T) {
+	tests := []struct {
+		addr string
+		ipv6 bool
+		want string
+	}{
+		{"127.0.0.1:41600", false, "udp4"},
+		{"127.0.0.1:41600", true, "udp4"}, // an IPv4 address stays one
+		{"[::1]:41600", false, "udp6"},
+		{"[fe80::1%lo]:41600", false, "udp6"},
+		{"[::]:0", false, "udp6"},
+		{"localhost:41600", false, "udp4"},
+		{"localhost:41600", true, "udp6"},
+		{":41600", false, "udp"}, // both
+		{":41600", true, "udp6"},
+		{"nonsense", true, "udp"},
+	}
+	for _, test := range tests {
+		if got := Network(test.addr, test.ipv6); got != test.want {
+			t.Errorf("Network(%q, %v) = %q, expected %q\n", test.addr, test.ipv6, got, test.want)
+		}
+	}
+}
+
+func TestResolveAddr(t *testing.T) {
+	addr, err := ResolveAddr("[::1]:41600", false)
+	if err != nil || addr.IP.To4() != nil || addr.Port != 41600 {
+		t.Errorf("resolved [::1]:41600 to %v, %v\n", addr, err)
+	}
+	addr, err = ResolveAddr("localhost:41600", false)
+	if err != nil || addr.IP.To4() == nil {
+		t.Errorf("resolved localhost:41600 to %v, %v, expected an IPv4 address\n", addr, err)
+	}
+}
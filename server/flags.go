@@ -0,0 +1,148 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+
+	"nimgame/pkg/configfile"
+	"nimgame/pkg/envconfig"
+	"nimgame/pkg/nimerr"
+	"nimgame/pkg/nimserver"
+)
+
+// configName is the config file looked for when -config isn't given; see
+// configfile.Locate.
+const configName = "server_config.json"
+
+// serverFlags is the parsed command line. Settings that also live in
+// ServerConfig override the config file only when given.
+type serverFlags struct {
+	configPath   string
+	listen       string
+	port         int
+	tracing      string
+	logLevel     string
+	admin        string
+	validateOnly bool
+	initConfig   string
+	force        bool
+	set          map[string]bool // flags given on the command line
+}
+
+// parseFlags parses args, which exclude the program name. For
+// compatibility the listen address may also be given positionally, as
+// "port" (listening on every interface) or "host port".
+func parseFlags(args []string, output io.Writer) (*serverFlags, error) {
+	f := &serverFlags{set: make(map[string]bool)}
+	fs := flag.NewFlagSet("server", flag.ContinueOnError)
+	fs.SetOutput(output)
+	fs.StringVar(&f.configPath, "config", "", "read the server config from `path` rather than searching for "+configName)
+	fs.StringVar(&f.listen, "listen", "", "UDP `address` to serve games on, overriding NimServerAddress")
+	fs.IntVar(&f.port, "port", 0, "UDP `port` to serve games on, keeping NimServerAddress's host")
+	fs.StringVar(&f.tracing, "tracing", "", "tracing server `address`, overriding TracingServerAddress")
+	fs.StringVar(&f.logLevel, "log-level", "", "debug, info, warn or error, overriding LogLevel")
+	fs.BoolVar(&f.validateOnly, "validate-config", false, "check the config and exit")
+	fs.StringVar(&f.initConfig, "init-config", "", "write an example config to `path` and exit")
+	fs.BoolVar(&f.force, "force", false, "let -init-config overwrite an existing file")
+	fs.StringVar(&f.admin, "admin", "", "HTTP `address` for /metrics and other operator endpoints, overriding AdminAddress")
+	fs.Usage = func() {
+		fmt.Fprintln(output, "Usage: server [flags] [[host] port]")
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	fs.Visit(func(fl *flag.Flag) { f.set[fl.Name] = true })
+
+	usageErr := func(format string, args ...interface{}) error {
+		err := fmt.Errorf(format, args...)
+		fmt.Fprintln(output, err)
+		fs.Usage()
+		return err
+	}
+	if fs.NArg() > 0 && f.set["listen"] {
+		return nil, usageErr("listen address given both as -listen and as arguments")
+	}
+	if f.set["port"] {
+		switch {
+		case f.set["listen"] || fs.NArg() > 0:
+			return nil, usageErr("-port can't be combined with a listen address")
+		case f.port < 1 || f.port > 65535:
+			return nil, usageErr("-port %d is out of range 1 to 65535", f.port)
+		}
+	}
+	switch fs.NArg() {
+	case 0:
+	case 1:
+		f.listen = net.JoinHostPort("0.0.0.0", fs.Arg(0))
+		f.set["listen"] = true
+	case 2:
+		f.listen = net.JoinHostPort(fs.Arg(0), fs.Arg(1))
+		f.set["listen"] = true
+	default:
+		return nil, usageErr("too many arguments: %v", fs.Args())
+	}
+	return f, nil
+}
+
+// apply overrides config with the flags that were given.
+func (f *serverFlags) apply(config *nimserver.ServerConfig) {
+	if f.set["listen"] {
+		config.NimServerAddress = f.listen
+	}
+	if f.set["port"] {
+		// an unparseable address is left for validation to report
+		if host, _, err := net.SplitHostPort(config.NimServerAddress); err == nil {
+			config.NimServerAddress = net.JoinHostPort(host, strconv.Itoa(f.port))
+		}
+	}
+	if f.set["tracing"] {
+		config.TracingServerAddress = f.tracing
+	}
+	if f.set["log-level"] {
+		config.LogLevel = f.logLevel
+	}
+	if f.set["admin"] {
+		config.AdminAddress = f.admin
+	}
+}
+
+// loadConfig parses the command line and builds the config from the file it
+// names or finds, the NIM_* environment variables (see package envconfig),
+// then the flags, each overriding the last, and validates the result.
+// Errors are nimerr.ErrConfig. With -init-config there is no config to load
+// and it is nil.
+func loadConfig(args []string, output io.Writer, getenv func(string) string) (*serverFlags, *nimserver.ServerConfig, error) {
+	f, config, err := readConfig(args, output, getenv)
+	return f, config, nimerr.Wrap(nimerr.ErrConfig, err)
+}
+
+func readConfig(args []string, output io.Writer, getenv func(string) string) (*serverFlags, *nimserver.ServerConfig, error) {
+	f, err := parseFlags(args, output)
+	if err != nil {
+		return nil, nil, err
+	}
+	if f.initConfig != "" {
+		return f, nil, nil
+	}
+	path, err := configfile.Locate(f.configPath, configName, getenv)
+	if err != nil {
+		return nil, nil, err
+	}
+	config, err := nimserver.ReadConfig(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := envconfig.Apply(config, "NIM_", getenv); err != nil {
+		return nil, nil, fmt.Errorf("invalid environment:\n%w", err)
+	}
+	f.apply(config)
+	if err := nimserver.ValidateConfig(config); err != nil {
+		return nil, nil, fmt.Errorf("invalid config %v:\n%w", path, err)
+	}
+	return f, config, nil
+}
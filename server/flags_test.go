@@ -0,0 +1,90 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeTestConfig(t *testing.T, config string) string {
+	path := filepath.Join(t.TempDir(), "server_config.json")
+	if err := os.WriteFile(path, []byte(config), 0644); err != nil {
+		t.Fatalf("writing config: %v\n", err)
+	}
+	return path
+}
+
+func TestServerConfigPrecedence(t *testing.T) {
+	path := writeTestConfig(t, `{
+		"NimServerAddress": "127.0.0.1:1000",
+		"TracingServerAddress": "127.0.0.1:2000",
+		"AdminAddress": "127.0.0.1:3000",
+		"LogLevel": "warn",
+		"Secret": "c2VjcmV0",
+		"TracingIdentity": "server"
+	}`)
+	env := map[string]string{"NIM_SERVER_ADDRESS": "127.0.0.1:1004", "NIM_LOG_LEVEL": "error"}
+	tests := []struct {
+		name   string
+		args   []string
+		env    map[string]string
+		listen string
+		trace  string
+		admin  string
+		level  string
+	}{
+		{"file", []string{"-config", path}, nil,
+			"127.0.0.1:1000", "127.0.0.1:2000", "127.0.0.1:3000", "warn"},
+		{"port argument", []string{"-config", path, "1001"}, nil,
+			"0.0.0.0:1001", "127.0.0.1:2000", "127.0.0.1:3000", "warn"},
+		{"host and port arguments", []string{"-config", path, "localhost", "1002"}, nil,
+			"localhost:1002", "127.0.0.1:2000", "127.0.0.1:3000", "warn"},
+		{"flags", []string{"-config", path, "-listen", "127.0.0.1:1003", "-tracing", "127.0.0.1:2003", "-admin", "127.0.0.1:3003", "-log-level", "debug"}, nil,
+			"127.0.0.1:1003", "127.0.0.1:2003", "127.0.0.1:3003", "debug"},
+		{"port flag", []string{"-config", path, "-port", "1006"}, nil,
+			"127.0.0.1:1006", "127.0.0.1:2000", "127.0.0.1:3000", "warn"},
+		{"empty admin flag disables", []string{"-config", path, "-admin", ""}, nil,
+			"127.0.0.1:1000", "127.0.0.1:2000", "", "warn"},
+		{"env over file", []string{"-config", path}, env,
+			"127.0.0.1:1004", "127.0.0.1:2000", "127.0.0.1:3000", "error"},
+		{"flags over env", []string{"-config", path, "-log-level", "info", "1005"}, env,
+			"0.0.0.0:1005", "127.0.0.1:2000", "127.0.0.1:3000", "info"},
+	}
+	for _, test := range tests {
+		_, config, err := loadConfig(test.args, io.Discard, func(k string) string { return test.env[k] })
+		if err != nil {
+			t.Fatalf("%v: %v\n", test.name, err)
+		}
+		if config.NimServerAddress != test.listen || config.TracingServerAddress != test.trace ||
+			config.AdminAddress != test.admin || config.LogLevel != test.level {
+			t.Errorf("%v: got listen %v, tracing %v, admin %v, level %v\n", test.name,
+				config.NimServerAddress, config.TracingServerAddress, config.AdminAddress, config.LogLevel)
+		}
+	}
+}
+
+func TestServerEnvErrors(t *testing.T) {
+	path := writeTestConfig(t, `{"NimServerAddress": "127.0.0.1:1000", "TracingServerAddress": "127.0.0.1:2000", "Secret": "c2VjcmV0", "TracingIdentity": "server"}`)
+	env := map[string]string{"NIM_QUEUE_DEPTH": "deep", "NIM_SECRET": "%%%"}
+	_, _, err := loadConfig([]string{"-config", path}, io.Discard, func(k string) string { return env[k] })
+	if err == nil || !strings.Contains(err.Error(), "NIM_QUEUE_DEPTH") || !strings.Contains(err.Error(), "NIM_SECRET") {
+		t.Errorf("expected errors naming NIM_QUEUE_DEPTH and NIM_SECRET, got %v\n", err)
+	}
+}
+
+func TestServerFlagErrors(t *testing.T) {
+	for _, args := range [][]string{
+		{"-listen", "127.0.0.1:1", "2"},
+		{"a", "b", "c"},
+		{"-bogus"},
+		{"-port", "1", "-listen", "127.0.0.1:2"},
+		{"-port", "1", "2"},
+		{"-port", "70000"},
+	} {
+		if _, err := parseFlags(args, io.Discard); err == nil {
+			t.Errorf("parseFlags(%v) should fail\n", args)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package main
+
+// exampleConfig is written by -init-config. It listens on loopback, leaves
+// tracing and the optional endpoints off, and documents every setting.
+const exampleConfig = `// Nim server config, written by server -init-config.
+//
+// Lines starting with // are comments. Any setting can be overridden by a
+// NIM_* environment variable (NIM_SERVER_ADDRESS for NimServerAddress,
+// NIM_LOG_LEVEL for LogLevel, ...) and some by flags; see server -help.
+{
+    // UDP address clients send their moves to, on IPv6 loopback;
+    // "127.0.0.1:41600" for IPv4. An empty host listens on both, and IPv6
+    // listens over IPv6 only on it, and on a host name
+    "NimServerAddress": "[::1]:41600",
+    "IPv6": false,
+
+    // tracing server to record moves to, as TracingIdentity; Secret must
+    // match the tracing server's. Empty disables tracing.
+    "TracingServerAddress": "",
+    "Secret": "",
+    "TracingIdentity": "server",
+    // fraction (0-1) of moves traced
+    "TracingSampleRate": 1,
+
+    // play games over QUIC streams on NimServerAddress rather than bare UDP
+    // packets; clients must set QuicEnabled too
+    "QuicEnabled": false,
+
+    // debug, info, warn or error
+    "LogLevel": "info",
+
+    // address answering client heartbeats; empty disables
+    "FCheckAckLocalAddr": "[::1]:41601",
+
+    // "rle" run-length encodes boards in replies; empty sends them as-is
+    "CompressionMode": "",
+
+    // HTTP address for /metrics, /query/seed/{seed}, /games and /debug/;
+    // empty disables
+    "AdminAddress": "",
+    // play games over HTTP on AdminAddress too, under /games and /ws
+    "AdminGamesEnabled": false,
+    // seconds a WebSocket client that dropped mid-game has to resume it
+    "WebSocketGrace": 30,
+    // gRPC address for health checks and the NimGame service; empty disables
+    "GRPCAddress": "",
+    // play games over TCP here too, for clients whose network blocks UDP;
+    // use NimServerAddress's port so clients find it. Empty disables.
+    "TCPAddress": "",
+    // play games over DTLS here too, encrypted, authenticated by the PEM
+    // certificate and key files or, without them, a key derived from
+    // DTLSPSK, base64 encoded, which clients must share. Empty disables.
+    "DTLSAddress": "",
+    "DTLSCertFile": "",
+    "DTLSKeyFile": "",
+    "DTLSPSK": "",
+
+    // moves waiting to be handled beyond this many are dropped
+    "QueueDepth": 64,
+    // report unhealthy while this many games are in progress; 0 means no limit
+    "MaxClients": 0,
+
+    // time every server move, warning about any taking over
+    // MaxMoveComputeMs; 0 never warns
+    "MoveTimingEnabled": false,
+    "MaxMoveComputeMs": 0,
+
+    // moves with a board of more rows, or a row of more coins, are
+    // rejected; 0 allows the largest board the server generates
+    "MaxBoardRows": 16,
+    "MaxCoinsPerRow": 10,
+
+    // play bestMove in a random ABTestRatio of games and normalMove in the
+    // rest, whatever the seed, comparing them in nim_wins_total
+    "ABTestEnabled": false,
+    "ABTestRatio": 0.5,
+
+    // boards to play for particular seeds, as a JSON list of
+    // {"seed": 42, "board": [3, 5, 7, 2]}; empty generates every board
+    "DatasetFile": "",
+
+    // generate boards with a row of k coins weighted by
+    // k^-StochasticAlpha, mostly short rows, rather than each seed's usual
+    // board, which clients verifying boards will reject
+    "StochasticMode": false,
+    "StochasticAlpha": 1.5,
+
+    // play Lasker's nim, where a move may split a heap in two, with
+    // clients asking for it at GameStart; others play nim
+    "LaskerEnabled": false,
+
+    // play Wythoff's game, on two heaps, taking from one or the same from
+    // both, with clients asking for it at GameStart; others play nim
+    "WythoffEnabled": false,
+
+    // the game played with clients that don't ask for one of those: "nim"
+    // or "wythoff", for Wythoff's game with them all
+    "GameVariant": "nim",
+
+    // answer every message without keeping any game, marking the replies,
+    // to try clients out; needs GRPCAddress and AdminGamesEnabled unset
+    "DryRun": false,
+
+    // replace boards two players taking a coin a move would finish in
+    // fewer moves than this with the next seed's that lasts, trying up to
+    // MaxSeedRetries seeds (0 means 100); 0 disables
+    "MinGameLength": 0,
+    "MaxSeedRetries": 0,
+
+    // generate boards of one shape: "uniform", "skewed" (one big heap,
+    // the rest tiny), "single" (one heap), "endgame" (heaps of 1 or 2
+    // coins) or "custom", weighting rows of 1, 2, ... coins by
+    // BoardProfileWeights; clients may ask for another at GameStart
+    "BoardProfile": "",
+    "BoardProfileWeights": [],
+
+    // who generated boards are won by with perfect play: "first", the
+    // client, "second", the server, or "random", either; clients may ask
+    // for another at GameStart
+    "BoardGuarantee": "first",
+
+    // a client that hasn't answered the server's move after this many
+    // seconds forfeits; 0 waits forever
+    "MoveTTL": 0,
+
+    // on SIGTERM, refuse new games but play on for up to this many
+    // seconds, until those in progress end; 0 stops at once
+    "DrainTimeout": 0,
+
+    // save each session to this directory as it changes, and their move
+    // histories to it with ".history" appended, and resume them on start;
+    // empty disables
+    "PersistPath": "",
+
+    // per-seed win probabilities are computed on first start and cached
+    // here; empty disables
+    "SeedCacheFile": "",
+
+    // POST game_start, game_end and invalid_move events to WebhookURL;
+    // an empty list subscribes to every event. Empty URL disables.
+    "WebhookURL": "",
+    "WebhookEvents": [],
+
+    // publish game starts and ends to KafkaTopic on these comma-separated
+    // brokers; empty disables
+    "KafkaBootstrapServers": "",
+    "KafkaTopic": ""
+}
+`
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"io"
+	"path/filepath"
+	"testing"
+
+	"nimgame/pkg/client"
+	"nimgame/pkg/configfile"
+	"nimgame/pkg/nim"
+	"nimgame/pkg/nimserver"
+)
+
+func TestExampleConfigRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "server_config.json")
+	f, config, err := loadConfig([]string{"-init-config", path}, io.Discard, func(string) string { return "" })
+	if err != nil || config != nil || f.initConfig != path {
+		t.Fatalf("parsing -init-config: got %v, %v\n", config, err)
+	}
+	if err := configfile.Write(path, []byte(exampleConfig), false); err != nil {
+		t.Fatalf("writing example config: %v\n", err)
+	}
+	if err := configfile.Write(path, []byte(exampleConfig), false); err == nil {
+		t.Errorf("example config overwritten without -force\n")
+	}
+
+	_, config, err = loadConfig([]string{"-config", path}, io.Discard, func(string) string { return "" })
+	if err != nil {
+		t.Fatalf("loading example config: %v\n", err)
+	}
+	if config.TracingServerAddress != "" {
+		t.Errorf("example config enables tracing at %v\n", config.TracingServerAddress)
+	}
+
+	// the example config serves games with tracing off, here from free
+	// ports rather than its own
+	config.FCheckAckLocalAddr = "127.0.0.1:0"
+	server, err := nimserver.New(nimserver.WithConfig(config), nimserver.WithListenAddress("127.0.0.1:0"))
+	if err != nil {
+		t.Fatalf("starting server: %v\n", err)
+	}
+	done := make(chan error, 1)
+	go func() { done <- server.Run(context.Background()) }()
+	defer func() {
+		server.Shutdown(context.Background())
+		if err := <-done; err != nil {
+			t.Errorf("Run: %v\n", err)
+		}
+	}()
+
+	sess, err := client.NewSession(client.ClientConfig{
+		NimServerAddresses: []string{server.Addr().String()},
+	}, nim.Optimal{}, client.WithSeed(4))
+	if err != nil {
+		t.Fatalf("creating session: %v\n", err)
+	}
+	defer sess.Close()
+	if result, err := sess.Play(context.Background()); err != nil || result.Winner != "client" {
+		t.Errorf("got winner %v, %v, expected client\n", result.Winner, err)
+	}
+}
@@ -0,0 +1,77 @@
+// Command server serves nim games as its config file, environment and flags
+// say; see package nimserver, which does the serving.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"nimgame/pkg/configfile"
+	"nimgame/pkg/nimerr"
+	"nimgame/pkg/nimserver"
+)
+
+func main() {
+	err := run(os.Args[1:])
+	if err != nil && !errors.Is(err, flag.ErrHelp) {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+	}
+	os.Exit(exitCode(err))
+}
+
+// exitCode is the status to exit with after err, which may be nil: 2 for
+// config errors and 1 for anything else.
+func exitCode(err error) int {
+	switch {
+	case err == nil, errors.Is(err, flag.ErrHelp):
+		return 0
+	case errors.Is(err, nimerr.ErrConfig):
+		return 2
+	default:
+		return 1
+	}
+}
+
+// run serves games as args ask until interrupted.
+func run(args []string) error {
+	flags, config, err := loadConfig(args, os.Stderr, os.Getenv)
+	if err != nil {
+		return err
+	}
+	if flags.initConfig != "" {
+		if err := configfile.Write(flags.initConfig, []byte(exampleConfig), flags.force); err != nil {
+			return fmt.Errorf("writing config: %w", err)
+		}
+		fmt.Printf("wrote example config to %v\n", flags.initConfig)
+		return nil
+	}
+	if flags.validateOnly {
+		fmt.Println("config OK")
+		return nil
+	}
+	initLogger(config)
+
+	server, err := nimserver.New(nimserver.WithConfig(config))
+	if err != nil {
+		return err
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if err := server.Run(ctx); err != nil {
+		return err
+	}
+	slog.Info("shutting down")
+	return nil
+}
+
+func initLogger(config *nimserver.ServerConfig) {
+	var level slog.Level
+	level.UnmarshalText([]byte(config.LogLevel)) // checked by ValidateConfig
+	slog.SetDefault(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: level})))
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"nimgame/pkg/nimerr"
+	"nimgame/pkg/nimserver"
+)
+
+func TestStartupErrorKinds(t *testing.T) {
+	noEnv := func(string) string { return "" }
+	_, _, err := loadConfig([]string{"-config", "missing.json"}, io.Discard, noEnv)
+	if !errors.Is(err, nimerr.ErrConfig) || exitCode(err) != 2 {
+		t.Errorf("missing config: expected a config error, got %v\n", err)
+	}
+
+	taken, err := nimserver.New(nimserver.WithListenAddress("127.0.0.1:0"))
+	if err != nil {
+		t.Fatalf("starting server: %v\n", err)
+	}
+	defer taken.Shutdown(context.Background())
+	_, err = nimserver.New(nimserver.WithListenAddress(taken.Addr().String()))
+	if !errors.Is(err, nimerr.ErrTransport) || exitCode(err) != 1 {
+		t.Errorf("address in use: expected a transport error, got %v\n", err)
+	}
+}
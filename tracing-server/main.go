@@ -8,6 +8,9 @@ import (
 
 func main() {
 	tracingServer := tracing.NewTracingServerFromFile("../config/tracing_server_config.json")
+	if len(tracingServer.Config.Secret) == 0 {
+		log.Fatal("Secret is empty; set it in tracing_server_config.json, and the same Secret in the client and server configs")
+	}
 
 	err := tracingServer.Open()
 	if err != nil {